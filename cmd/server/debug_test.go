@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/handlers"
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+)
+
+func TestDebugServer_SeparateFromMainListener(t *testing.T) {
+	cfg := &config.Config{
+		PushoverUserKey:  "test_user",
+		PushoverAPIToken: "test_token",
+		PushoverURL:      "https://api.pushover.net/1/messages.json",
+		Port:             "127.0.0.1:0",
+		BearerToken:      "Bearer test_token",
+		DebugAddr:        "127.0.0.1:0",
+	}
+	logger := &MockLogger{}
+
+	deps, err := handlers.CreateServerDependencies(context.Background(), cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create dependencies: %v", err)
+	}
+
+	srv := server.NewServer(cfg, handlers.CreateRouter(deps), logger)
+	srv.EnableDebug(cfg.DebugAddr, server.NewDebugHandler(deps.DebugVars()))
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			t.Errorf("Failed to shut down: %v", err)
+		}
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for srv.Addr() == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if srv.Addr() == nil || srv.DebugAddr() == nil {
+		t.Fatal("Listeners did not start")
+	}
+
+	head := func(addr fmt.Stringer, path string) *http.Response {
+		t.Helper()
+		resp, err := http.Head("http://" + addr.String() + path)
+		if err != nil {
+			t.Fatalf("HEAD %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := head(srv.DebugAddr(), "/debug/pprof/heap")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 from debug listener, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/octet-stream" {
+		t.Errorf("Expected heap profile content type, got %q", ct)
+	}
+
+	if resp := head(srv.Addr(), "/debug/pprof/heap"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 from main listener, got %d", resp.StatusCode)
+	}
+
+	vars, err := http.Get("http://" + srv.DebugAddr().String() + "/debug/vars")
+	if err != nil {
+		t.Fatalf("GET /debug/vars failed: %v", err)
+	}
+	defer vars.Body.Close()
+
+	var values map[string]json.RawMessage
+	if err := json.NewDecoder(vars.Body).Decode(&values); err != nil {
+		t.Fatalf("Invalid /debug/vars JSON: %v", err)
+	}
+	for _, name := range []string{"memstats", "forward_queue_depth", "idempotency_keys", "metrics"} {
+		if _, ok := values[name]; !ok {
+			t.Errorf("Expected %s in /debug/vars", name)
+		}
+	}
+}
+
+func TestDebugServer_Disabled(t *testing.T) {
+	cfg := &config.Config{Port: "127.0.0.1:0"}
+	srv := server.NewServer(cfg, http.NotFoundHandler(), &MockLogger{})
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+
+	if srv.DebugAddr() != nil {
+		t.Errorf("Expected no debug listener, got %s", srv.DebugAddr())
+	}
+}
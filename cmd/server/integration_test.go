@@ -45,12 +45,12 @@ func TestCreateWebhookHandler_FullCoverage(t *testing.T) {
 		expectedStatus int
 	}{
 		{
-			name:           "OPTIONS request",
+			name:           "OPTIONS request without ALLOWED_ORIGINS",
 			method:         http.MethodOptions,
 			authHeader:     "",
 			body:           "",
 			testMode:       false,
-			expectedStatus: http.StatusOK,
+			expectedStatus: http.StatusMethodNotAllowed,
 		},
 		{
 			name:           "GET request",
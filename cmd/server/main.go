@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/zhorvath83/flux-provider-pushover/internal/config"
 	"github.com/zhorvath83/flux-provider-pushover/internal/handlers"
+	"github.com/zhorvath83/flux-provider-pushover/internal/pushover"
 	"github.com/zhorvath83/flux-provider-pushover/internal/server"
 )
 
@@ -20,18 +26,66 @@ func (d DefaultLogger) Println(v ...interface{}) {
 	log.Println(v...)
 }
 
-// RunApp runs the application with dependency injection (testable)
+// RunApp runs the application with dependency injection (testable). Startup
+// failures are returned before anything is served. Background work still
+// running after the shutdown drain is cancelled when RunApp returns.
 func RunApp(configLoader config.ConfigLoader, logger server.Logger) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv, err := startApp(ctx, configLoader, logger)
+	if err != nil {
+		return err
+	}
+
+	// Wait for shutdown signal
+	return srv.WaitForShutdown()
+}
+
+// startApp loads the configuration, creates the dependencies, runs the
+// startup checks and binds the listeners. Readiness is reported last, once
+// requests are actually accepted. Cancelling ctx ends the background work
+// of the dependencies.
+func startApp(ctx context.Context, configLoader config.ConfigLoader, logger server.Logger) (*server.Server, error) {
 	// Load and validate configuration
 	cfg, err := config.WithValidation(configLoader, config.ValidateConfig)()
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	deprecated := make([]string, 0, len(cfg.DeprecatedEnv))
+	for name := range cfg.DeprecatedEnv {
+		deprecated = append(deprecated, name)
+	}
+	sort.Strings(deprecated)
+	for _, name := range deprecated {
+		logger.Printf("WARNING: %s is deprecated, set %s instead", name, cfg.DeprecatedEnv[name])
+	}
+	if cfg.AcceptsAPIToken() {
+		logger.Println("WARNING: the webhook accepts PUSHOVER_API_TOKEN as bearer token, which is deprecated; set WEBHOOK_TOKEN so a leaked webhook secret does not expose the Pushover application")
 	}
 
-	// Create dependencies
-	deps, err := handlers.CreateServerDependencies(cfg, logger)
+	// Create dependencies, message templates are compiled here
+	deps, err := handlers.CreateServerDependencies(ctx, cfg, logger)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to create dependencies: %w", err)
+	}
+
+	// Background work of the dependencies is stopped when startup fails
+	fail := func(err error) (*server.Server, error) {
+		drainCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if drainErr := deps.Drain(drainCtx); drainErr != nil {
+			logger.Printf("Failed to stop dependencies: %v", drainErr)
+		}
+		return nil, err
+	}
+
+	if cfg.ValidateCredentials {
+		client := pushover.NewPushoverClientWithEndpoints(pushover.NewHTTPClient(cfg.SendTimeout(), pushover.TransportOptions{}), pushover.ResolveEndpoints(cfg.PushoverBaseURL, cfg.PushoverURL))
+		if err := validateCredentials(ctx, cfg, client); err != nil {
+			return fail(err)
+		}
+		logger.Println("Pushover credentials validated")
 	}
 
 	// Create router
@@ -39,18 +93,51 @@ func RunApp(configLoader config.ConfigLoader, logger server.Logger) error {
 
 	// Create and start server
 	srv := server.NewServer(cfg, router, logger)
+	if cfg.DebugAddr != "" {
+		srv.EnableDebug(cfg.DebugAddr, server.NewDebugHandler(deps.DebugVars()))
+	}
+	srv.RegisterShutdownHook(deps.Drain)
+	srv.RegisterDiagnostics(deps.DumpDiagnostics)
+	srv.RegisterHealthState(deps.Health)
 	if err := srv.Start(); err != nil {
-		return err
+		return fail(err)
 	}
+	deps.Start()
+	deps.MarkStarted()
 
-	// Wait for shutdown signal
-	return srv.WaitForShutdown()
+	return srv, nil
+}
+
+// credentialValidator checks Pushover credentials with the API
+type credentialValidator interface {
+	ValidateUser(ctx context.Context, token, user string) error
+}
+
+// validateCredentials checks the configured token and user key
+func validateCredentials(ctx context.Context, cfg *config.Config, validator credentialValidator) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if err := validator.ValidateUser(ctx, cfg.PushoverAPIToken, cfg.PushoverUserKey); err != nil {
+		return fmt.Errorf("invalid Pushover credentials: %w", err)
+	}
+	return nil
+}
+
+// healthCheckURL is the local /health URL, under BASE_PATH unless
+// HEALTH_AT_ROOT is set
+func healthCheckURL(getEnv func(string) string) string {
+	base := config.NormalizePath(getEnv("BASE_PATH"))
+	if atRoot, err := strconv.ParseBool(getEnv("HEALTH_AT_ROOT")); err == nil && atRoot {
+		base = ""
+	}
+	return "http://localhost:8080" + base + "/health"
 }
 
 func main() {
 	// Handle health check mode for Docker HEALTHCHECK
 	if len(os.Args) > 1 && os.Args[1] == "-health" {
-		if err := server.HealthCheck("http://localhost:8080/health"); err != nil {
+		if err := server.HealthCheck(healthCheckURL(os.Getenv)); err != nil {
 			os.Exit(1)
 		}
 		os.Exit(0)
@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -235,3 +241,240 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+// validConfig returns a config loader for a server on a random local port
+func validConfig(modify func(cfg *config.Config)) config.ConfigLoader {
+	return func() (*config.Config, error) {
+		cfg := config.NewConfig()
+		cfg.PushoverUserKey = "test_user"
+		cfg.PushoverAPIToken = "test_token"
+		cfg.BearerToken = "Bearer test_token"
+		cfg.Port = "127.0.0.1:0"
+		if modify != nil {
+			modify(cfg)
+		}
+		return cfg, nil
+	}
+}
+
+func TestRunApp_BindFailure(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer taken.Close()
+
+	err = RunApp(validConfig(func(cfg *config.Config) { cfg.Port = taken.Addr().String() }), &MockLogger{})
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to start server: ") {
+		t.Errorf("Expected a bind error from RunApp, got %v", err)
+	}
+}
+
+func TestRunApp_CredentialValidationFailure(t *testing.T) {
+	pushoverAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/1/users/validate.json" {
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"token":"invalid","errors":["application token is invalid"],"status":0}`))
+	}))
+	defer pushoverAPI.Close()
+
+	err := RunApp(validConfig(func(cfg *config.Config) {
+		cfg.PushoverURL = pushoverAPI.URL + "/1/messages.json"
+		cfg.ValidateCredentials = true
+	}), &MockLogger{})
+
+	expected := "invalid Pushover credentials: pushover API returned status 400: application token is invalid"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+}
+
+func TestStartApp_ReadyOnceListening(t *testing.T) {
+	var validated bool
+	pushoverAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		validated = true
+		w.Write([]byte(`{"status":1}`))
+	}))
+	defer pushoverAPI.Close()
+
+	srv, err := startApp(context.Background(), validConfig(func(cfg *config.Config) {
+		cfg.PushoverURL = pushoverAPI.URL + "/1/messages.json"
+		cfg.ValidateCredentials = true
+	}), &MockLogger{})
+	if err != nil {
+		t.Fatalf("Unexpected startup error: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+
+	if !validated {
+		t.Error("Expected the credentials to be validated before serving")
+	}
+
+	// Readiness is reported as soon as startApp returns, no wait needed
+	resp, err := http.Get("http://" + srv.Addr().String() + "/ready")
+	if err != nil {
+		t.Fatalf("Expected the listener to be bound, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected ready after startup, got %d", resp.StatusCode)
+	}
+}
+
+func TestStartApp_APITokenDeprecation(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *config.Config)
+		expected bool
+	}{
+		{"legacy API token", nil, true},
+		{"dedicated webhook token", func(cfg *config.Config) {
+			cfg.WebhookToken = "webhook_token"
+			cfg.BearerToken = "Bearer webhook_token"
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &MockLoggerForRun{}
+			srv, err := startApp(context.Background(), validConfig(tt.modify), logger)
+			if err != nil {
+				t.Fatalf("Unexpected startup error: %v", err)
+			}
+			defer srv.Shutdown(context.Background())
+
+			warned := false
+			for _, message := range logger.Messages {
+				warned = warned || strings.Contains(message, "PUSHOVER_API_TOKEN as bearer token, which is deprecated")
+			}
+			if warned != tt.expected {
+				t.Errorf("Expected the deprecation warning %v, got %v", tt.expected, logger.Messages)
+			}
+		})
+	}
+}
+
+func TestStartApp_DeprecatedEnv(t *testing.T) {
+	logger := &MockLoggerForRun{}
+	srv, err := startApp(context.Background(), validConfig(func(cfg *config.Config) {
+		cfg.DeprecatedEnv = map[string]string{"HTTP_MAX_IDLE_CONNS": "PUSHOVER_MAX_IDLE_CONNS"}
+	}), logger)
+	if err != nil {
+		t.Fatalf("Unexpected startup error: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+
+	warned := false
+	for _, message := range logger.Messages {
+		warned = warned || message == "WARNING: HTTP_MAX_IDLE_CONNS is deprecated, set PUSHOVER_MAX_IDLE_CONNS instead"
+	}
+	if !warned {
+		t.Errorf("Expected the deprecation warning, got %v", logger.Messages)
+	}
+}
+
+func TestValidateCredentials(t *testing.T) {
+	validator := &MockCredentialValidator{err: errors.New("user key is invalid")}
+	cfg := &config.Config{PushoverAPIToken: "token", PushoverUserKey: "user"}
+
+	if err := validateCredentials(context.Background(), cfg, validator); err == nil || err.Error() != "invalid Pushover credentials: user key is invalid" {
+		t.Errorf("Expected wrapped validation error, got %v", err)
+	}
+	if validator.token != "token" || validator.user != "user" {
+		t.Errorf("Expected the configured credentials, got %q %q", validator.token, validator.user)
+	}
+
+	validator.err = nil
+	if err := validateCredentials(context.Background(), cfg, validator); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestHealthCheckURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{"default", nil, "http://localhost:8080/health"},
+		{"base path", map[string]string{"BASE_PATH": "/hooks/pushover/"}, "http://localhost:8080/hooks/pushover/health"},
+		{"health at root", map[string]string{"BASE_PATH": "/hooks/pushover", "HEALTH_AT_ROOT": "true"}, "http://localhost:8080/health"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if url := healthCheckURL(func(key string) string { return tt.env[key] }); url != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, url)
+			}
+		})
+	}
+}
+
+// MockCredentialValidator records the validated credentials
+type MockCredentialValidator struct {
+	token, user string
+	err         error
+}
+
+func (m *MockCredentialValidator) ValidateUser(ctx context.Context, token, user string) error {
+	m.token, m.user = token, user
+	return m.err
+}
+
+func TestStartApp_NoGoroutinesAfterShutdown(t *testing.T) {
+	// The forward target never answers, forwards are only ended by the
+	// root context
+	release := make(chan struct{})
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Reading the body lets the server notice the client going away
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer target.Close()
+	defer close(release)
+
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	srv, err := startApp(ctx, validConfig(func(cfg *config.Config) {
+		cfg.PushoverAPIToken = "test_api_token"
+		cfg.BearerToken = "Bearer test_api_token"
+		cfg.ForwardURL = target.URL
+		cfg.CoalesceWindow = time.Hour
+		cfg.AuditLogPath = filepath.Join(t.TempDir(), "audit.log")
+	}), &MockLogger{})
+	if err != nil {
+		t.Fatalf("Unexpected startup error: %v", err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	req, _ := http.NewRequest(http.MethodPost, "http://"+srv.Addr().String()+"/webhook",
+		strings.NewReader(`{"severity":"info","message":"m","involvedObject":{"kind":"Kustomization","name":"apps"}}`))
+	req.Header.Set("Authorization", "Bearer test_api_token")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to post alert: %v", err)
+	}
+	resp.Body.Close()
+
+	// The drain gives up on the hanging forward, cancelling the root
+	// context ends it
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer shutdownCancel()
+	_ = srv.Shutdown(shutdownCtx)
+	cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		buf := make([]byte, 1<<16)
+		t.Errorf("Expected at most %d goroutines after shutdown, got %d:\n%s", before, after, buf[:runtime.Stack(buf, true)])
+	}
+}
@@ -1,3 +1,11 @@
 module github.com/zhorvath83/flux-provider-pushover
 
 go 1.22
+
+require (
+	github.com/alicebob/miniredis/v2 v2.39.0
+	github.com/robfig/cron/v3 v3.0.1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require github.com/yuin/gopher-lua v1.1.1 // indirect
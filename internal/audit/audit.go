@@ -0,0 +1,147 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+)
+
+// DefaultFlushInterval is how often buffered entries are written to disk
+const DefaultFlushInterval = time.Second
+
+// Entry is one line of the audit log: a processed alert and what became of it
+type Entry struct {
+	Time              time.Time `json:"time"`
+	RequestID         string    `json:"request_id"`
+	Kind              string    `json:"kind"`
+	Namespace         string    `json:"namespace"`
+	Name              string    `json:"name"`
+	Severity          string    `json:"severity"`
+	Reason            string    `json:"reason"`
+	Message           string    `json:"message"`
+	Revision          string    `json:"revision,omitempty"`
+	Outcome           string    `json:"outcome"`
+	Rule              string    `json:"rule,omitempty"`
+	Error             string    `json:"error,omitempty"`
+	PushoverRequestID string    `json:"pushover_request_id,omitempty"`
+}
+
+// Writer is implemented by RotatingFile
+type Writer interface {
+	Write(p []byte) (int, error)
+	Flush() error
+	Close() error
+}
+
+// Logger appends entries as JSON lines to a buffered writer, flushed every
+// flush interval and on Close. Write failures never reach the caller, they
+// are logged and counted. A nil Logger records nothing.
+type Logger struct {
+	w      Writer
+	logger server.Logger
+
+	mu     sync.Mutex // Serializes writes and flushes
+	stop   chan struct{}
+	done   chan struct{}
+	closed bool
+
+	failures *metrics.Counter
+}
+
+// NewLogger creates a logger writing to w and starts its periodic flush
+func NewLogger(w Writer, flushInterval time.Duration, logger server.Logger, registry *metrics.Registry) *Logger {
+	l := &Logger{
+		w:        w,
+		logger:   logger,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		failures: registry.Counter("audit_write_failures_total", "Audit log entries or flushes that could not be written"),
+	}
+	go l.flushLoop(flushInterval)
+	return l
+}
+
+// Record appends entry to the audit log
+func (l *Logger) Record(entry Entry) {
+	if l == nil {
+		return
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		l.fail("encode", err)
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+	if _, err := l.w.Write(line); err != nil {
+		l.fail("write", err)
+	}
+}
+
+// Close stops the periodic flush, then flushes and closes the writer
+func (l *Logger) Close(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	close(l.stop)
+	l.mu.Unlock()
+
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Close()
+}
+
+// flushLoop flushes the buffered entries until Close
+func (l *Logger) flushLoop(interval time.Duration) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.flush()
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// flush writes the buffered entries to disk
+func (l *Logger) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.w.Flush(); err != nil {
+		l.fail("flush", err)
+	}
+}
+
+// fail counts and logs a failure to write the audit log
+func (l *Logger) fail(op string, err error) {
+	l.failures.Inc()
+	l.logger.Printf("Warning: failed to %s audit log entry: %v", op, err)
+}
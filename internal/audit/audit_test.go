@@ -0,0 +1,147 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+)
+
+// MockLogger for testing (thread-safe)
+type MockLogger struct {
+	mu       sync.Mutex
+	Messages []string
+}
+
+func (m *MockLogger) Printf(format string, v ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Messages = append(m.Messages, fmt.Sprintf(format, v...))
+}
+
+func (m *MockLogger) Println(v ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Messages = append(m.Messages, fmt.Sprint(v...))
+}
+
+// FailingWriter fails every write and flush
+type FailingWriter struct{}
+
+func (FailingWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+func (FailingWriter) Flush() error                { return errors.New("disk full") }
+func (FailingWriter) Close() error                { return nil }
+
+func TestLogger_WritesJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	file, err := OpenRotatingFile(path, 300, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	logger := NewLogger(file, time.Hour, &MockLogger{}, nil)
+
+	for i := 0; i < 10; i++ {
+		logger.Record(Entry{
+			Time:      time.Unix(1700000000, 0).UTC(),
+			RequestID: fmt.Sprintf("req-%d", i),
+			Kind:      "Kustomization",
+			Namespace: "apps",
+			Name:      "apps",
+			Severity:  "error",
+			Reason:    "HealthCheckFailed",
+			Message:   "line one\nline \"two\"",
+			Outcome:   "sent",
+		})
+	}
+	if err := logger.Close(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Entries are spread over rotated files, each a valid JSON line
+	var ids []string
+	for _, name := range []string{path + ".3", path + ".2", path + ".1", path} {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatalf("Expected rotation to create %s: %v", filepath.Base(name), err)
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var entry Entry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				t.Errorf("Invalid JSON line in %s: %q", filepath.Base(name), scanner.Text())
+			}
+			if entry.Message != "line one\nline \"two\"" || entry.Outcome != "sent" {
+				t.Errorf("Unexpected entry %+v", entry)
+			}
+			ids = append(ids, entry.RequestID)
+		}
+		f.Close()
+	}
+
+	// The oldest entries were rotated out of AUDIT_LOG_MAX_FILES
+	if len(ids) == 0 || len(ids) >= 10 || ids[len(ids)-1] != "req-9" {
+		t.Errorf("Expected the latest entries in order, got %v", ids)
+	}
+}
+
+func TestLogger_PeriodicFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	file, err := OpenRotatingFile(path, 1<<20, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	logger := NewLogger(file, 10*time.Millisecond, &MockLogger{}, nil)
+	defer logger.Close(context.Background())
+
+	logger.Record(Entry{RequestID: "req-1", Outcome: "sent"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, _ := os.ReadFile(path); strings.Contains(string(data), `"request_id":"req-1"`) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("Expected the entry to be flushed without closing the logger")
+}
+
+func TestLogger_FailuresAreCounted(t *testing.T) {
+	registry := metrics.NewRegistry()
+	messages := &MockLogger{}
+	logger := NewLogger(FailingWriter{}, time.Hour, messages, registry)
+
+	logger.Record(Entry{Outcome: "sent"})
+	if err := logger.Close(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if failures := registry.Counter("audit_write_failures_total", "").Value(); failures != 1 {
+		t.Errorf("Expected 1 failure, got %d", failures)
+	}
+	if len(messages.Messages) != 1 || !strings.Contains(messages.Messages[0], "disk full") {
+		t.Errorf("Expected a warning, got %v", messages.Messages)
+	}
+
+	// Entries after close are dropped silently
+	logger.Record(Entry{Outcome: "sent"})
+	if failures := registry.Counter("audit_write_failures_total", "").Value(); failures != 1 {
+		t.Errorf("Expected no write after close, got %d failures", failures)
+	}
+}
+
+func TestLogger_Nil(t *testing.T) {
+	var logger *Logger
+	logger.Record(Entry{})
+	if err := logger.Close(context.Background()); err != nil {
+		t.Errorf("Expected nil logger to close cleanly, got %v", err)
+	}
+}
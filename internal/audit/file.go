@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingFile is a buffered append-only file rotated by size. Every Write
+// is kept whole: when it would grow the file past maxSize, the file is first
+// renamed to path.1, older files shift to path.2 and so on, and the oldest
+// beyond maxFiles is removed.
+type RotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int // Rotated files kept next to the active one
+
+	mu   sync.Mutex
+	file *os.File
+	buf  *bufio.Writer
+	size int64 // Written and buffered bytes of the active file
+}
+
+// OpenRotatingFile opens path for appending, creating it if needed
+func OpenRotatingFile(path string, maxSize int64, maxFiles int) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the active file and picks up its size
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+
+	f.file = file
+	f.buf = bufio.NewWriter(file)
+	f.size = info.Size()
+	return nil
+}
+
+// Write buffers p, rotating first if p would not fit in the active file
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.buf.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Flush writes the buffered data to the file
+func (f *RotatingFile) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	return f.buf.Flush()
+}
+
+// Close flushes and closes the file
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := errors.Join(f.buf.Flush(), f.file.Close())
+	f.file = nil
+	return err
+}
+
+// rotate shifts the rotated files by one and starts an empty active file
+func (f *RotatingFile) rotate() error {
+	if err := errors.Join(f.buf.Flush(), f.file.Close()); err != nil {
+		return fmt.Errorf("failed to close audit log for rotation: %w", err)
+	}
+	f.file = nil
+
+	if f.maxFiles > 0 {
+		for i := f.maxFiles - 1; i > 0; i-- {
+			if err := os.Rename(rotatedName(f.path, i), rotatedName(f.path, i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to rotate audit log: %w", err)
+			}
+		}
+		if err := os.Rename(f.path, rotatedName(f.path, 1)); err != nil {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	} else if err := os.Remove(f.path); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+
+	return f.open()
+}
+
+// rotatedName returns the name of the i-th rotated file (pure function)
+func rotatedName(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
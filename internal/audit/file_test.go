@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestRotatingFile_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	f, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "eeee\n", "ffff\n", "gggg\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		path:        "gggg\n",
+		path + ".1": "eeee\nffff\n",
+		path + ".2": "cccc\ndddd\n",
+	}
+	for name, content := range expected {
+		if got := readFile(t, name); got != content {
+			t.Errorf("Expected %s to hold %q, got %q", filepath.Base(name), content, got)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected files beyond AUDIT_LOG_MAX_FILES to be removed, got %v", err)
+	}
+}
+
+func TestRotatingFile_NoRotatedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	f, err := OpenRotatingFile(path, 10, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	f.Write([]byte("aaaaaaaa\n"))
+	f.Write([]byte("bbbbbbbb\n"))
+	f.Close()
+
+	if got := readFile(t, path); got != "bbbbbbbb\n" {
+		t.Errorf("Expected the file to start over, got %q", got)
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Errorf("Expected no rotated file, got %v", err)
+	}
+}
+
+func TestRotatingFile_AppendsToExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if err := os.WriteFile(path, []byte("old entry\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenRotatingFile(path, 15, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	f.Write([]byte("new entry\n"))
+	f.Close()
+
+	// The existing size counts towards the limit
+	if got := readFile(t, path+".1"); got != "old entry\n" {
+		t.Errorf("Expected the existing file to be rotated, got %q", got)
+	}
+	if got := readFile(t, path); got != "new entry\n" {
+		t.Errorf("Expected the new entry in a fresh file, got %q", got)
+	}
+}
+
+func TestRotatingFile_Buffered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	f, err := OpenRotatingFile(path, 1<<20, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer f.Close()
+
+	f.Write([]byte("entry\n"))
+	if got := readFile(t, path); got != "" {
+		t.Errorf("Expected the entry to be buffered, got %q", got)
+	}
+
+	if err := f.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := readFile(t, path); got != "entry\n" {
+		t.Errorf("Expected the entry after flush, got %q", got)
+	}
+}
+
+func TestRotatingFile_Errors(t *testing.T) {
+	if _, err := OpenRotatingFile(filepath.Join(t.TempDir(), "missing", "audit.log"), 10, 1); err == nil ||
+		!strings.Contains(err.Error(), "failed to open audit log") {
+		t.Errorf("Expected open error, got %v", err)
+	}
+
+	f, err := OpenRotatingFile(filepath.Join(t.TempDir(), "audit.log"), 10, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	f.Close()
+	if _, err := f.Write([]byte("late\n")); err == nil {
+		t.Error("Expected writes after close to fail")
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("Expected a second close to be a no-op, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+// Package clock lets time-based features run on a fake clock in tests
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits for durations to pass
+type Clock interface {
+	Now() time.Time
+	// After sends the time on the returned channel once d has passed
+	After(d time.Duration) <-chan time.Time
+}
+
+// Real is the system clock
+type Real struct{}
+
+// Now returns the current time
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// After waits for d to pass
+func (Real) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// fakeWaiter is a channel returned by Fake.After, due at deadline
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// Fake is a manually advanced clock for tests
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+// NewFake creates a fake clock stopped at now
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the fake time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// After returns a channel sent the fake time once Advance reaches d from now
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- f.now
+		return ch
+	}
+	f.waiters = append(f.waiters, fakeWaiter{deadline: f.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the fake time forward by d, firing the waiters now due
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = f.now.Add(d)
+	waiting := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.deadline.After(f.now) {
+			waiting = append(waiting, w)
+			continue
+		}
+		w.ch <- f.now
+	}
+	f.waiters = waiting
+}
+
+// Waiters returns the number of After channels not fired yet, for tests to
+// wait until the code under test is waiting
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
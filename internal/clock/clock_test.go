@@ -0,0 +1,63 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	fake := NewFake(start)
+
+	soon := fake.After(time.Second)
+	later := fake.After(time.Minute)
+	select {
+	case <-fake.After(0):
+	default:
+		t.Error("Expected After(0) to fire immediately")
+	}
+	if fake.Waiters() != 2 {
+		t.Fatalf("Expected 2 waiters, got %d", fake.Waiters())
+	}
+
+	fake.Advance(time.Second)
+	if !fake.Now().Equal(start.Add(time.Second)) {
+		t.Errorf("Expected the time advanced by a second, got %v", fake.Now())
+	}
+	select {
+	case fired := <-soon:
+		if !fired.Equal(start.Add(time.Second)) {
+			t.Errorf("Expected the advanced time sent, got %v", fired)
+		}
+	default:
+		t.Error("Expected the due waiter to fire")
+	}
+	select {
+	case <-later:
+		t.Error("Expected the later waiter to keep waiting")
+	default:
+	}
+
+	fake.Advance(time.Hour)
+	select {
+	case <-later:
+	default:
+		t.Error("Expected the later waiter to fire")
+	}
+	if fake.Waiters() != 0 {
+		t.Errorf("Expected no waiters, got %d", fake.Waiters())
+	}
+}
+
+func TestReal(t *testing.T) {
+	var clock Clock = Real{}
+	before := time.Now()
+	if now := clock.Now(); now.Before(before) {
+		t.Errorf("Expected the current time, got %v before %v", now, before)
+	}
+	select {
+	case <-clock.After(time.Millisecond):
+	case <-time.After(time.Second):
+		t.Error("Expected After to fire")
+	}
+}
@@ -0,0 +1,153 @@
+// Package conditions tracks the state of components as Kubernetes-style
+// conditions, so that tooling reading the conditions of Kubernetes objects
+// can read the provider's too
+package conditions
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+)
+
+// Status of a condition, as in Kubernetes
+type Status string
+
+// Condition statuses
+const (
+	StatusTrue    Status = "True"
+	StatusFalse   Status = "False"
+	StatusUnknown Status = "Unknown"
+)
+
+// Condition types of the provider
+const (
+	Ready             = "Ready"
+	PushoverReachable = "PushoverReachable"
+	CredentialsValid  = "CredentialsValid"
+	QueueHealthy      = "QueueHealthy"
+)
+
+// ReasonUnknown is the reason of conditions nothing was observed for yet
+const ReasonUnknown = "NotObserved"
+
+// Condition is the latest observation of an aspect of the provider
+type Condition struct {
+	Type               string    `json:"type" doc:"Aspect observed, e.g. Ready"`
+	Status             Status    `json:"status" doc:"True, False or Unknown"`
+	Reason             string    `json:"reason" doc:"CamelCase reason of the status"`
+	Message            string    `json:"message,omitempty" doc:"Human-readable details"`
+	LastTransitionTime time.Time `json:"lastTransitionTime" doc:"When the status last changed"`
+}
+
+// Set holds conditions in the order their types were declared. Setting a
+// condition keeps its lastTransitionTime unless the status changed. A nil
+// Set ignores updates and holds nothing.
+type Set struct {
+	clock clock.Clock
+
+	mu         sync.Mutex
+	conditions []Condition
+	onChange   func(condType string)
+}
+
+// NewSet creates a set of the given types, all Unknown until observed
+func NewSet(types ...string) *Set {
+	return NewSetWithClock(clock.Real{}, types...)
+}
+
+// NewSetWithClock creates a set telling transition times by clk
+func NewSetWithClock(clk clock.Clock, types ...string) *Set {
+	now := clk.Now().UTC().Truncate(time.Second)
+	s := &Set{clock: clk, conditions: make([]Condition, len(types))}
+	for i, t := range types {
+		s.conditions[i] = Condition{Type: t, Status: StatusUnknown, Reason: ReasonUnknown, LastTransitionTime: now}
+	}
+	return s
+}
+
+// OnChange registers fn to be called with the type of every condition whose
+// status changes, after the set is updated. Conditions derived from others
+// are kept current this way.
+func (s *Set) OnChange(fn func(condType string)) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChange = fn
+}
+
+// Set records the status of condType with its reason and message, reporting
+// whether the status changed. Types the set was not created with are added.
+func (s *Set) Set(condType string, status Status, reason, message string) bool {
+	if s == nil {
+		return false
+	}
+
+	changed, onChange := s.set(condType, status, reason, message)
+	if changed && onChange != nil {
+		onChange(condType)
+	}
+	return changed
+}
+
+// set updates condType, returning whether its status changed and the
+// OnChange function to call
+func (s *Set) set(condType string, status Status, reason, message string) (bool, func(string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now().UTC().Truncate(time.Second)
+	for i := range s.conditions {
+		condition := &s.conditions[i]
+		if condition.Type != condType {
+			continue
+		}
+		changed := condition.Status != status
+		if changed {
+			condition.LastTransitionTime = now
+		}
+		condition.Status, condition.Reason, condition.Message = status, reason, message
+		return changed, s.onChange
+	}
+
+	s.conditions = append(s.conditions, Condition{Type: condType, Status: status, Reason: reason, Message: message, LastTransitionTime: now})
+	return true, s.onChange
+}
+
+// Get returns the condition of condType
+func (s *Set) Get(condType string) (Condition, bool) {
+	if s == nil {
+		return Condition{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, condition := range s.conditions {
+		if condition.Type == condType {
+			return condition, true
+		}
+	}
+	return Condition{}, false
+}
+
+// List returns a copy of the conditions
+func (s *Set) List() []Condition {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Condition(nil), s.conditions...)
+}
+
+// FromBool returns StatusTrue or StatusFalse (pure function)
+func FromBool(ok bool) Status {
+	if ok {
+		return StatusTrue
+	}
+	return StatusFalse
+}
@@ -0,0 +1,142 @@
+package conditions
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+)
+
+func TestSet_Transitions(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)
+	clk := clock.NewFake(start)
+	set := NewSetWithClock(clk, Ready, QueueHealthy)
+
+	ready, ok := set.Get(Ready)
+	if !ok || ready.Status != StatusUnknown || ready.Reason != ReasonUnknown || !ready.LastTransitionTime.Equal(start.Truncate(time.Second)) {
+		t.Fatalf("Expected Ready to start Unknown, got %+v", ready)
+	}
+
+	tests := []struct {
+		name       string
+		advance    time.Duration
+		status     Status
+		reason     string
+		message    string
+		changed    bool
+		transition time.Duration // Since start, of the expected lastTransitionTime
+	}{
+		{name: "first observation", advance: time.Minute, status: StatusFalse, reason: "SendFailed", message: "timeout", changed: true, transition: time.Minute},
+		{name: "same status, new reason", advance: time.Minute, status: StatusFalse, reason: "QueueBackedUp", changed: false, transition: time.Minute},
+		{name: "recovery", advance: time.Minute, status: StatusTrue, reason: "Ready", changed: true, transition: 3 * time.Minute},
+		{name: "still ready", advance: time.Hour, status: StatusTrue, reason: "Ready", changed: false, transition: 3 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk.Advance(tt.advance)
+			if changed := set.Set(Ready, tt.status, tt.reason, tt.message); changed != tt.changed {
+				t.Errorf("Expected changed=%v, got %v", tt.changed, changed)
+			}
+
+			ready, _ := set.Get(Ready)
+			want := Condition{
+				Type:               Ready,
+				Status:             tt.status,
+				Reason:             tt.reason,
+				Message:            tt.message,
+				LastTransitionTime: start.Add(tt.transition).Truncate(time.Second),
+			}
+			if ready != want {
+				t.Errorf("Expected %+v, got %+v", want, ready)
+			}
+		})
+	}
+
+	// Other conditions keep their own transition times
+	if queue, _ := set.Get(QueueHealthy); queue.Status != StatusUnknown || !queue.LastTransitionTime.Equal(start.Truncate(time.Second)) {
+		t.Errorf("Expected QueueHealthy untouched, got %+v", queue)
+	}
+}
+
+func TestSet_List(t *testing.T) {
+	set := NewSet(Ready, PushoverReachable)
+	set.Set(CredentialsValid, StatusTrue, "Accepted", "")
+
+	list := set.List()
+	var types []string
+	for _, condition := range list {
+		types = append(types, condition.Type)
+	}
+	if fmt.Sprint(types) != "[Ready PushoverReachable CredentialsValid]" {
+		t.Errorf("Expected declared types first, then added ones, got %v", types)
+	}
+
+	// The list is a copy
+	list[0].Status = StatusTrue
+	if ready, _ := set.Get(Ready); ready.Status != StatusUnknown {
+		t.Errorf("Expected the set unaffected by changes to its list, got %+v", ready)
+	}
+}
+
+func TestSet_OnChange(t *testing.T) {
+	set := NewSet(Ready, CredentialsValid)
+
+	// Derive Ready from CredentialsValid, as the handlers do
+	var changes []string
+	set.OnChange(func(condType string) {
+		changes = append(changes, condType)
+		if condType == CredentialsValid {
+			valid, _ := set.Get(CredentialsValid)
+			set.Set(Ready, valid.Status, valid.Reason, "")
+		}
+	})
+
+	set.Set(CredentialsValid, StatusFalse, "Rejected", "invalid token")
+	set.Set(CredentialsValid, StatusFalse, "Rejected", "invalid user")
+	set.Set(CredentialsValid, StatusTrue, "Accepted", "")
+
+	if fmt.Sprint(changes) != "[CredentialsValid Ready CredentialsValid Ready]" {
+		t.Errorf("Expected status changes only, got %v", changes)
+	}
+	if ready, _ := set.Get(Ready); ready.Status != StatusTrue || ready.Reason != "Accepted" {
+		t.Errorf("Expected Ready derived from CredentialsValid, got %+v", ready)
+	}
+}
+
+func TestSet_Concurrent(t *testing.T) {
+	set := NewSet(Ready)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				set.Set(Ready, FromBool((i+j)%2 == 0), "Flapping", "")
+				set.List()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if ready, ok := set.Get(Ready); !ok || ready.Status == StatusUnknown {
+		t.Errorf("Expected Ready observed, got %+v", ready)
+	}
+}
+
+func TestSet_Nil(t *testing.T) {
+	var set *Set
+	if set.Set(Ready, StatusTrue, "Ready", "") {
+		t.Error("Expected nil set to ignore updates")
+	}
+	if _, ok := set.Get(Ready); ok {
+		t.Error("Expected nil set to hold nothing")
+	}
+	if set.List() != nil {
+		t.Error("Expected nil set to list nothing")
+	}
+	set.OnChange(func(string) { t.Error("Expected nil set to report no changes") })
+}
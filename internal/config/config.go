@@ -2,18 +2,346 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
 	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/maintenance"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
 )
 
 // Config holds application configuration
 type Config struct {
 	PushoverUserKey  string
 	PushoverAPIToken string
-	BearerToken      string // Pre-computed Bearer token
-	Port             string
-	PushoverURL      string // Make it configurable for testing
+	BearerToken      string   // Pre-computed Bearer token
+	WebhookToken     string   // Webhook bearer token, the API token is accepted when empty
+	WebhookTokens    []string // Accepted webhook bearer tokens, replacing the API token when set
+
+	// Cluster of the alerts posted with the WebhookTokens entry at the same
+	// index, from its ;cluster= and ;allow_cluster_header options
+	WebhookTokenClusters []WebhookTokenCluster
+
+	// Pushover application and user of each normalized severity, overriding
+	// PushoverAPIToken and PushoverUserKey
+	SeverityCredentials map[string]PushoverCredentials
+
+	// HTTP Basic credentials accepted on the webhook, empty disables them
+	WebhookBasicUser     string
+	WebhookBasicPassword string
+	Port                 string
+	PushoverURL          string // Messages endpoint, PUSHOVER_URL overrides the one below the base URL
+	PushoverBaseURL      string // API base URL of self-hosted relays, empty finds the other endpoints next to PushoverURL
+
+	// Resolution of the Pushover host, hardened against cluster DNS outages
+	PushoverResolveOverride string        // IP:port dialed instead of resolving the host
+	PushoverDNSCacheTTL     time.Duration // Zero disables the cache
+	PushoverDNSTimeout      time.Duration // Limit on a lookup, separate from the dial timeout
+
+	// Optional response overrides, empty means built-in default
+	ResponseContentType          string
+	ResponseOKBody               string
+	ResponseUnauthorizedBody     string
+	ResponseInvalidJSONBody      string
+	ResponseMethodNotAllowedBody string
+	SuccessStatus                int // 200 or 202 for accepted webhooks
+
+	// Notification providers
+	Providers            []string // Active senders, in failover order
+	ProvidersMode        string   // "fanout" or "failover"
+	NtfyURL              string
+	NtfyTopic            string
+	NtfyToken            string
+	OutgoingWebhookURL   string
+	OutgoingWebhookToken string
+	SlackWebhookURL      string
+
+	// Mirroring of raw events to a secondary endpoint
+	ForwardURL   string
+	ForwardToken string
+	// Sign mirrored events with HMAC-SHA256 of FORWARD_HMAC_SECRET
+	ForwardSign       bool
+	ForwardHMACSecret string
+
+	// Record delivery failures as Kubernetes Events when running in a cluster
+	EmitK8sEvents bool
+
+	// Message formatting
+	PreserveKindCase bool   // Keep involvedObject.kind casing instead of lowercasing
+	ClusterName      string // Footer identifying the cluster, empty omits it
+	MessagePrefix    string // Starts every message body, e.g. "[PROD]"
+	RevisionFormat   string // "full", "short" or "branch-short"
+	ShortenRevision  bool   // Show only the 7 character commit SHA of revisions
+	ShowUID          bool   // Add the involvedObject.uid to messages for correlation
+	MaxTitleLength   int    // Titles are shortened to this many characters, 0 is the Pushover limit
+	TitleError       string // Title of error notifications, empty uses the app title
+	TitleInfo        string // Title of info notifications, empty uses the app title
+	MaxMessageLines  int    // Metadata lines rendered below the message, 0 renders all
+	MetadataPrefix   string // Alert eventMetadata keys with this prefix override Pushover options
+	TemplatesFile    string // JSON or YAML message templates selected by severity and kind, empty uses the built-in message
+	MessageFallback  bool   // Send a minimal message instead of failing when building one panics
+
+	// Attach the full event message to Pushover messages it overflowed,
+	// cut to AttachmentMaxBytes
+	AttachOverflow     bool
+	AttachmentMaxBytes int
+
+	// Template yielding the URL of an image fetched and attached to each
+	// Pushover message, empty or an empty result attaches none
+	ImageURLTemplate  string
+	ImageMaxBytes     int
+	ImageFetchTimeout time.Duration
+
+	// Pushover sounds by lowercased alert reason, taking precedence over the
+	// sounds by severity, e.g. "imagepullbackoff" to "siren"
+	ReasonSounds   map[string]string
+	SeveritySounds map[string]string
+
+	// Daily window, e.g. "22:00-07:00", in which only error alerts notify
+	// normally, empty disables it
+	QuietHours         string
+	QuietHoursTimezone string // IANA time zone of the window
+	QuietHoursMode     string // "silent" or "suppress", what happens to other alerts
+
+	// Devices delete messages sent silently during quiet hours after it, 0 keeps them
+	QuietTTL time.Duration
+
+	// Recurring maintenance windows, e.g. "0 2 * * 6 4h namespace=apps",
+	// during which matching alerts are dropped or sent silently
+	MaintenanceWindows  string
+	MaintenanceTimezone string // IANA time zone of the schedules
+	MaintenanceMode     string // "suppress" or "downgrade"
+
+	// Pushover Glances widget updates: "off", "alongside" or "instead" of messages
+	Glances string
+
+	// Show the number of objects currently in error state on the Glances
+	// widget instead
+	EnableGlances bool
+
+	// Externally reachable base URL, e.g. https://flux-pushover.example.com,
+	// for Pushover's acknowledgement callbacks. Empty disables them.
+	PublicURL string
+
+	// How often the receipts of unacknowledged emergency messages are
+	// polled, zero disables polling
+	ReceiptPollInterval time.Duration
+
+	// Cancel the unacknowledged emergency messages about an object once an
+	// info alert reports its recovery
+	AutoCancelEmergency bool
+
+	// Leader election between replicas, in-cluster only
+	EnableLeaderElection bool
+	LeaderElectionMode   string // "standby" or "proxy", what non-leaders do with webhooks
+	LeaderElectionLease  string
+
+	// Deduplication of repeated alerts, optionally shared between replicas through Redis
+	DedupWindow   time.Duration // Zero disables deduplication
+	RedisAddr     string
+	RedisPassword string
+
+	// Delivery only when an object's state changes, kept in the deduplication store
+	NotifyOnChangeOnly bool
+	ChangeDetection    string        // "message" or "reason", whether the message is part of the state
+	StateTTL           time.Duration // Objects quiet for this long alert again
+
+	// Delivery only when an object's severity changes, e.g. on recovery,
+	// remembered in memory by object uid for StateTTL
+	StatefulNotify bool
+
+	// Replay protection for webhooks retried by notification-controller,
+	// opt-in since identical alerts within the TTL are not sent again
+	IdempotencyTTL     time.Duration // Zero disables it
+	IdempotencyMaxKeys int
+
+	// Rejection of replayed webhooks by their event timestamp
+	MaxEventAge           time.Duration // Zero accepts events of any age
+	EventClockSkew        time.Duration // Tolerance for events dated in the future
+	RequireEventTimestamp bool
+
+	// Involved object kinds notified about, lowercased. A non-empty
+	// IncludeKinds drops every other kind and ExcludeKinds is ignored.
+	IncludeKinds []string
+	ExcludeKinds []string
+
+	// Alerts a minute each namespace may send, zero disables the limit
+	PerNamespaceRate float64
+
+	// Load shedding: deliveries sent at once, zero disables it, and the
+	// alerts waiting for one above which non-error alerts are shed
+	ShedMaxConcurrent int
+	ShedHighWater     int
+
+	// Merging of events for the same object into one notification
+	CoalesceWindow       time.Duration // Zero disables coalescing
+	CoalesceBypassErrors bool          // Deliver error alerts immediately
+	DispatchWorkers      int           // Workers delivering coalesced alerts, each object on one
+
+	// Deliveries waiting in the dispatch and load shedding queues above
+	// which the readiness check fails, zero disables the check
+	QueueUnhealthyDepth int
+
+	// How long the readiness check fails after a failed send, unless a
+	// successful send clears it first, zero disables the check
+	SendFailureUnreadyWindow time.Duration
+
+	// Pushover retries
+	RetryMaxAttempts int     // Total attempts per message, 1 disables retries
+	RetryBudgetRatio float64 // Tokens earned per success in the shared retry budget
+	RetryOnTimeout   bool    // Retry timeouts after the request was written, risking duplicates
+
+	// Retry-After of 503 responses, zero omits the header
+	RetryAfterSeconds int
+
+	// Time limits of webhooks and the Pushover sends they make
+	PushoverTimeout time.Duration // Limit on each send, zero is DefaultPushoverTimeout
+	RequestDeadline time.Duration // Limit on handling a webhook, zero disables it
+
+	// Connection pool of the outgoing HTTP client
+	HTTPMaxIdleConns        int // PUSHOVER_MAX_IDLE_CONNS
+	HTTPMaxIdleConnsPerHost int // PUSHOVER_MAX_IDLE_CONNS_PER_HOST
+	HTTPIdleConnTimeout     time.Duration
+	PushoverMaxInFlight     int // Concurrent Pushover requests, 0 is unlimited
+
+	// Protocol settings of the outgoing HTTP client
+	HTTPTLSHandshakeTimeout   time.Duration
+	HTTPExpectContinueTimeout time.Duration
+	HTTP2                     bool // Negotiate HTTP/2 over TLS
+	HTTPCompression           bool // Ask for gzip responses
+
+	// Listener for pprof and expvar, empty disables it
+	DebugAddr string
+
+	// Answer 200 instead of 400 on "/" for load balancer probes
+	RootOK bool
+
+	// Routing behind a reverse proxy that forwards a path prefix
+	BasePath            string // Prefix of every route, e.g. "/hooks/pushover", empty serves at the root
+	WebhookPath         string // Route receiving Flux alerts, below BasePath
+	HealthAtRoot        bool   // Keep /health and /ready at the root for kubelet probes
+	RedirectLegacyPaths bool   // Redirect the unprefixed routes with 308 instead of 404
+
+	// Log every request with its status and duration
+	AccessLog bool
+
+	// Reverse proxies whose X-Forwarded-Proto is believed
+	TrustedProxies []netip.Prefix
+	// Refuse requests that did not reach a trusted proxy over HTTPS
+	RequireForwardedHTTPS bool
+
+	// Lowercased origins, e.g. "https://dashboard.example.com", allowed to
+	// call the webhook from a browser, "*" allows any. Empty disables CORS.
+	AllowedOrigins []string
+
+	// Notify the Pushover user of recovered panics, at most once per cool-down
+	PanicNotify         bool
+	PanicNotifyCooldown time.Duration
+
+	// Watchdog thresholds for notifying about the provider itself being
+	// degraded, zero disables each
+	WatchdogFailureStreak int           // Consecutive failed Pushover sends
+	WatchdogSaturation    time.Duration // Time the delivery queue stays at SHED_HIGH_WATER
+	WatchdogFlaps         int           // Readiness changes within WatchdogFlapWindow
+	WatchdogFlapWindow    time.Duration
+
+	// Check the Pushover token and user key with the API before serving
+	ValidateCredentials bool
+	// Validate credentials Pushover rejected again this often, 0 never does
+	CredentialsRecheckInterval time.Duration
+
+	// Failing /health before shutdown so load balancers deregister the pod
+	PreShutdownDelay time.Duration
+	// Bounds the shutdown, including the final flush of queued deliveries
+	ShutdownTimeout time.Duration
+
+	// Repeated identical delivery failures are logged in full only the first
+	// LogSampleBurst times per window, zero window logs every failure
+	LogSampleWindow time.Duration
+	LogSampleBurst  int
+
+	// JSON lines audit log of every processed alert, empty path disables it
+	AuditLogPath      string
+	AuditLogMaxSizeMB int // Size at which the file is rotated
+	AuditLogMaxFiles  int // Rotated files kept
+
+	// Require severity and involvedObject.kind, which Flux itself may omit
+	StrictAlerts bool
+
+	// Whether alerts without a message or reason or with fields over their
+	// length caps are rejected ("strict") or logged and delivered ("lenient"),
+	// and the severities accepted besides info, warning and error
+	ValidationMode  string
+	ExtraSeverities []string
+
+	// Top-level payload keys of non-Flux forwarders, lower case, mapped to
+	// the alert field paths they stand for, e.g. "msg" to "message"
+	FieldAliases map[string]string
+
+	// Limits checked before decoding a webhook payload, zero disables them
+	MaxJSONDepth  int
+	MaxJSONTokens int
+
+	// Log the body of webhooks that fail to decode, cut to the given size
+	DebugLogInvalidPayloads bool
+	DebugPayloadLogBytes    int
+
+	// Deprecated environment variables that were set, mapped to the
+	// variables replacing them, warned about at startup
+	DeprecatedEnv map[string]string
 }
 
+// DefaultPushoverBaseURL is the Pushover API the endpoints are found below
+const DefaultPushoverBaseURL = "https://api.pushover.net/1"
+
+// MinReceiptPollInterval is the shortest interval Pushover allows polling a
+// receipt at
+const MinReceiptPollInterval = 5 * time.Second
+
+// DefaultPushoverTimeout limits each send to Pushover unless PUSHOVER_TIMEOUT is set
+const DefaultPushoverTimeout = 10 * time.Second
+
+// Supported notification providers and dispatch modes
+const (
+	ProviderPushover = "pushover"
+	ProviderNtfy     = "ntfy"
+	ProviderWebhook  = "webhook"
+	ProviderSlack    = "slack"
+
+	ProvidersModeFanOut   = "fanout"
+	ProvidersModeFailover = "failover"
+
+	LeaderElectionModeStandby = "standby"
+	LeaderElectionModeProxy   = "proxy"
+
+	ChangeDetectionMessage = "message"
+	ChangeDetectionReason  = "reason"
+
+	ValidationModeStrict  = "strict"
+	ValidationModeLenient = "lenient"
+
+	RevisionFormatFull        = "full"
+	RevisionFormatShort       = "short"
+	RevisionFormatBranchShort = "branch-short"
+
+	QuietHoursSilent   = "silent"
+	QuietHoursSuppress = "suppress"
+
+	MaintenanceSuppress  = "suppress"
+	MaintenanceDowngrade = "downgrade"
+
+	GlancesOff       = "off"
+	GlancesAlongside = "alongside"
+	GlancesInstead   = "instead"
+)
+
 // ConfigValidator is a functional type for config validation
 type ConfigValidator func(*Config) error
 
@@ -23,9 +351,248 @@ type ConfigLoader func() (*Config, error)
 // NewConfig creates a new configuration with default values
 func NewConfig() *Config {
 	return &Config{
-		Port:        ":8080",
-		PushoverURL: "https://api.pushover.net/1/messages.json",
+		Port:          ":8080",
+		PushoverURL:   DefaultPushoverBaseURL + "/messages.json",
+		Providers:     []string{ProviderPushover},
+		ProvidersMode: ProvidersModeFanOut,
+		NtfyURL:       "https://ntfy.sh",
+		SuccessStatus: http.StatusOK,
+		WebhookPath:   "/webhook",
+
+		RevisionFormat: RevisionFormatFull,
+		MetadataPrefix: "pushover.",
+		Glances:        GlancesOff,
+
+		QuietHoursTimezone: "UTC",
+		QuietHoursMode:     QuietHoursSilent,
+
+		MaintenanceTimezone: "UTC",
+		MaintenanceMode:     MaintenanceSuppress,
+
+		LeaderElectionMode:  LeaderElectionModeStandby,
+		LeaderElectionLease: "flux-provider-pushover",
+
+		ChangeDetection: ChangeDetectionMessage,
+		StateTTL:        24 * time.Hour,
+
+		ValidationMode: ValidationModeLenient,
+
+		IdempotencyMaxKeys: 10000,
+
+		EventClockSkew: 30 * time.Second,
+
+		ShedHighWater: 100,
+
+		DispatchWorkers: 4,
+
+		SendFailureUnreadyWindow: time.Minute,
+
+		ShutdownTimeout: time.Duration(types.ShutdownTimeout) * time.Second,
+
+		CredentialsRecheckInterval: 5 * time.Minute,
+
+		RetryMaxAttempts: 1,
+		RetryBudgetRatio: 0.1,
+
+		RetryAfterSeconds: 30,
+
+		PushoverTimeout: DefaultPushoverTimeout,
+		RequestDeadline: 20 * time.Second,
+
+		HTTPMaxIdleConns:        10,
+		HTTPMaxIdleConnsPerHost: 2,
+		HTTPIdleConnTimeout:     90 * time.Second,
+
+		HTTPTLSHandshakeTimeout:   10 * time.Second,
+		HTTPExpectContinueTimeout: time.Second,
+		HTTP2:                     true,
+
+		PushoverDNSCacheTTL: 5 * time.Minute,
+		PushoverDNSTimeout:  2 * time.Second,
+
+		MaxJSONDepth:  32,
+		MaxJSONTokens: 10000,
+
+		DebugPayloadLogBytes: 4096,
+
+		MaxTitleLength: types.MaxTitleLength,
+
+		AttachmentMaxBytes: types.DefaultAttachmentBytes,
+
+		ImageMaxBytes:     types.MaxAttachmentBytes,
+		ImageFetchTimeout: 5 * time.Second,
+
+		PanicNotifyCooldown: 15 * time.Minute,
+
+		WatchdogFailureStreak: 25,
+		WatchdogFlapWindow:    10 * time.Minute,
+
+		LogSampleWindow: time.Minute,
+		LogSampleBurst:  10,
+
+		AuditLogMaxSizeMB: 100,
+		AuditLogMaxFiles:  5,
+	}
+}
+
+// PushoverCredentials are the Pushover application token and user key
+// messages are sent with, empty fields falling back to the defaults
+type PushoverCredentials struct {
+	Token string
+	User  string
+}
+
+// WebhookTokenCluster is the cluster of the alerts posted with a webhook token
+type WebhookTokenCluster struct {
+	Name        string // Static cluster label, empty falls back to CLUSTER_NAME
+	AllowHeader bool   // Whether the X-Cluster-Name header may name the cluster
+}
+
+// maxClusterNameLength bounds cluster names, which end up in metric labels
+const maxClusterNameLength = 63
+
+// ValidClusterName reports whether name is 1-63 letters, digits, '.', '_'
+// or '-', safe for notifications, logs and metric labels (pure function)
+func ValidClusterName(name string) bool {
+	if name == "" || len(name) > maxClusterNameLength {
+		return false
+	}
+	for _, r := range name {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '.' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseWebhookToken splits a WEBHOOK_TOKENS entry of the form
+// "<token>[;cluster=<name>][;allow_cluster_header]" into the token and its
+// cluster. Errors never contain the token (pure function).
+func parseWebhookToken(entry string, index int) (string, WebhookTokenCluster, error) {
+	var cluster WebhookTokenCluster
+	token, options, _ := strings.Cut(entry, ";")
+	for _, option := range strings.Split(options, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "":
+		case "cluster":
+			cluster.Name = strings.TrimSpace(value)
+		case "allow_cluster_header":
+			cluster.AllowHeader = true
+		default:
+			return "", cluster, fmt.Errorf("WEBHOOK_TOKENS entry %d: unknown option %q", index+1, key)
+		}
+	}
+	if token = strings.TrimSpace(token); token == "" {
+		return "", cluster, fmt.Errorf("WEBHOOK_TOKENS entry %d: token is empty", index+1)
+	}
+	return token, cluster, nil
+}
+
+// alertFieldPaths are the JSON paths of the alert fields, e.g. "involvedObject.kind"
+var alertFieldPaths = jsonPaths(reflect.TypeOf(types.FluxAlert{}), "")
+
+// jsonPaths lists the JSON paths of the fields of struct t, its nested
+// structs included (pure function)
+func jsonPaths(t reflect.Type, prefix string) []string {
+	var paths []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		paths = append(paths, prefix+name)
+		if t.Field(i).Type.Kind() == reflect.Struct {
+			paths = append(paths, jsonPaths(t.Field(i).Type, prefix+name+".")...)
+		}
+	}
+	return paths
+}
+
+// AlertFieldPath returns the alert field path matching path case
+// insensitively, e.g. "involvedObject.kind" for "involvedobject.kind" (pure function)
+func AlertFieldPath(path string) (string, bool) {
+	for _, known := range alertFieldPaths {
+		if strings.EqualFold(known, path) {
+			return known, true
+		}
+	}
+	return "", false
+}
+
+// validateFieldAliases checks that FIELD_ALIASES maps top-level keys to
+// alert fields (pure function)
+func validateFieldAliases(aliases map[string]string) error {
+	for alias, field := range aliases {
+		if strings.Contains(alias, ".") {
+			return fmt.Errorf("FIELD_ALIASES alias %q must be a top-level key", alias)
+		}
+		if known, ok := AlertFieldPath(field); !ok || known != field {
+			return fmt.Errorf("FIELD_ALIASES alias %q maps to unknown alert field %q", alias, field)
+		}
+	}
+	return nil
+}
+
+// credentialSeverities are the severities with their own credentials,
+// suffixing PUSHOVER_API_TOKEN_ and PUSHOVER_USER_KEY_ upper-cased
+var credentialSeverities = []string{types.SeverityError, types.SeverityWarning, types.SeverityInfo}
+
+// Credentials returns the Pushover credentials of severity, falling back to
+// PUSHOVER_API_TOKEN and PUSHOVER_USER_KEY field by field (pure function)
+func (c *Config) Credentials(severity string) PushoverCredentials {
+	credentials := c.SeverityCredentials[strings.ToLower(severity)]
+	if credentials.Token == "" {
+		credentials.Token = c.PushoverAPIToken
+	}
+	if credentials.User == "" {
+		credentials.User = c.PushoverUserKey
+	}
+	return credentials
+}
+
+// Title returns the notification title of severity, PUSHOVER_TITLE_ERROR or
+// PUSHOVER_TITLE_INFO when set, else the app title (pure function)
+func (c *Config) Title(severity string) string {
+	title := ""
+	switch strings.ToLower(severity) {
+	case types.SeverityError:
+		title = c.TitleError
+	case types.SeverityInfo:
+		title = c.TitleInfo
+	}
+	if title == "" {
+		return types.AppTitle
+	}
+	return title
+}
+
+// AcceptsAPIToken reports whether the webhook accepts the Pushover API token
+// as bearer token, the deprecated fallback while neither WEBHOOK_TOKEN nor
+// WEBHOOK_TOKENS are set (pure function)
+func (c *Config) AcceptsAPIToken() bool {
+	return c.WebhookToken == "" && len(c.WebhookTokens) == 0 && c.BearerToken != ""
+}
+
+// SendTimeout returns the limit on each send to Pushover
+func (c *Config) SendTimeout() time.Duration {
+	if c.PushoverTimeout == 0 {
+		return DefaultPushoverTimeout
+	}
+	return c.PushoverTimeout
+}
+
+// envAlias returns key, or its deprecated alias when only the alias is set.
+// A used alias is recorded in DeprecatedEnv, to be warned about.
+func (c *Config) envAlias(getEnv func(string) string, key, alias string) string {
+	if getEnv(key) != "" || getEnv(alias) == "" {
+		return key
+	}
+	if c.DeprecatedEnv == nil {
+		c.DeprecatedEnv = make(map[string]string)
 	}
+	c.DeprecatedEnv[alias] = key
+	return alias
 }
 
 // LoadFromEnv loads configuration from environment variables (pure function)
@@ -33,44 +600,1241 @@ func LoadFromEnv(getEnv func(string) string) ConfigLoader {
 	return func() (*Config, error) {
 		cfg := NewConfig()
 
-		cfg.PushoverUserKey = getEnv("PUSHOVER_USER_KEY")
-		cfg.PushoverAPIToken = getEnv("PUSHOVER_API_TOKEN")
+		// Stray whitespace, e.g. from YAML block scalars, is never part of a credential
+		cfg.PushoverUserKey = strings.TrimSpace(getEnv("PUSHOVER_USER_KEY"))
+		cfg.PushoverAPIToken = strings.TrimSpace(getEnv("PUSHOVER_API_TOKEN"))
+		for _, severity := range credentialSeverities {
+			suffix := "_" + strings.ToUpper(severity)
+			credentials := PushoverCredentials{
+				Token: strings.TrimSpace(getEnv("PUSHOVER_API_TOKEN" + suffix)),
+				User:  strings.TrimSpace(getEnv("PUSHOVER_USER_KEY" + suffix)),
+			}
+			if credentials != (PushoverCredentials{}) {
+				if cfg.SeverityCredentials == nil {
+					cfg.SeverityCredentials = make(map[string]PushoverCredentials)
+				}
+				cfg.SeverityCredentials[severity] = credentials
+			}
+		}
 
 		if port := getEnv("PORT"); port != "" {
 			cfg.Port = ":" + port
 		}
 
+		if baseURL := strings.TrimRight(strings.TrimSpace(getEnv("PUSHOVER_BASE_URL")), "/"); baseURL != "" {
+			cfg.PushoverBaseURL = baseURL
+			cfg.PushoverURL = baseURL + "/messages.json"
+		}
 		if pushoverURL := getEnv("PUSHOVER_URL"); pushoverURL != "" {
 			cfg.PushoverURL = pushoverURL
 		}
 
-		// Pre-compute Bearer token
-		if cfg.PushoverAPIToken != "" {
-			cfg.BearerToken = "Bearer " + cfg.PushoverAPIToken
+		cfg.ResponseContentType = getEnv("RESPONSE_CONTENT_TYPE")
+		cfg.ResponseOKBody = getEnv("RESPONSE_OK_BODY")
+		cfg.ResponseUnauthorizedBody = getEnv("RESPONSE_UNAUTHORIZED_BODY")
+		cfg.ResponseInvalidJSONBody = getEnv("RESPONSE_INVALID_JSON_BODY")
+		cfg.ResponseMethodNotAllowedBody = getEnv("RESPONSE_METHOD_NOT_ALLOWED_BODY")
+		successStatus, err := parseInt(getEnv, "SUCCESS_STATUS", cfg.SuccessStatus)
+		if err != nil {
+			return nil, err
 		}
+		cfg.SuccessStatus = successStatus
 
-		return cfg, nil
-	}
-}
+		cfg.PushoverResolveOverride = strings.TrimSpace(getEnv("PUSHOVER_RESOLVE_OVERRIDE"))
+		if cfg.PushoverDNSCacheTTL, err = parseDuration(getEnv, "PUSHOVER_DNS_CACHE_TTL", cfg.PushoverDNSCacheTTL); err != nil {
+			return nil, err
+		}
+		if cfg.PushoverDNSTimeout, err = parseDuration(getEnv, "PUSHOVER_DNS_TIMEOUT", cfg.PushoverDNSTimeout); err != nil {
+			return nil, err
+		}
 
-// DefaultConfigLoader loads config from os.Getenv
-var DefaultConfigLoader = LoadFromEnv(os.Getenv)
+		if providers := splitList(getEnv("PROVIDERS")); len(providers) > 0 {
+			cfg.Providers = providers
+		}
 
-// ValidateConfig validates the configuration (pure function)
-func ValidateConfig(cfg *Config) error {
-	if cfg == nil {
-		return fmt.Errorf("config is nil")
-	}
+		if mode := getEnv("PROVIDERS_MODE"); mode != "" {
+			cfg.ProvidersMode = strings.ToLower(mode)
+		}
 
-	if cfg.PushoverUserKey == "" {
-		return fmt.Errorf("PUSHOVER_USER_KEY is required")
-	}
+		if ntfyURL := getEnv("NTFY_URL"); ntfyURL != "" {
+			cfg.NtfyURL = ntfyURL
+		}
+		cfg.NtfyTopic = getEnv("NTFY_TOPIC")
+		cfg.NtfyToken = getEnv("NTFY_TOKEN")
+		cfg.OutgoingWebhookURL = getEnv("OUTGOING_WEBHOOK_URL")
+		cfg.OutgoingWebhookToken = getEnv("OUTGOING_WEBHOOK_TOKEN")
+		cfg.SlackWebhookURL = strings.TrimSpace(getEnv("SLACK_WEBHOOK_URL"))
 
-	if cfg.PushoverAPIToken == "" {
-		return fmt.Errorf("PUSHOVER_API_TOKEN is required")
-	}
+		cfg.ForwardURL = getEnv("FORWARD_URL")
+		cfg.ForwardToken = getEnv("FORWARD_TOKEN")
+		if cfg.ForwardSign, err = parseBool(getEnv, "FORWARD_SIGN"); err != nil {
+			return nil, err
+		}
+		cfg.ForwardHMACSecret = getEnv("FORWARD_HMAC_SECRET")
 
-	return nil
+		emitEvents, err := parseBool(getEnv, "EMIT_K8S_EVENTS")
+		if err != nil {
+			return nil, err
+		}
+		cfg.EmitK8sEvents = emitEvents
+
+		if cfg.PreserveKindCase, err = parseBool(getEnv, "PRESERVE_KIND_CASE"); err != nil {
+			return nil, err
+		}
+
+		if cfg.ShowUID, err = parseBool(getEnv, "SHOW_UID"); err != nil {
+			return nil, err
+		}
+
+		if cfg.MaxTitleLength, err = parseInt(getEnv, "MAX_TITLE_LENGTH", cfg.MaxTitleLength); err != nil {
+			return nil, err
+		}
+		cfg.TitleError = strings.TrimSpace(getEnv("PUSHOVER_TITLE_ERROR"))
+		cfg.TitleInfo = strings.TrimSpace(getEnv("PUSHOVER_TITLE_INFO"))
+		if cfg.MaxMessageLines, err = parseInt(getEnv, "MAX_MESSAGE_LINES", 0); err != nil {
+			return nil, err
+		}
+		if cfg.AttachOverflow, err = parseBool(getEnv, "ATTACH_OVERFLOW"); err != nil {
+			return nil, err
+		}
+		if cfg.AttachmentMaxBytes, err = parseInt(getEnv, "ATTACHMENT_MAX_BYTES", cfg.AttachmentMaxBytes); err != nil {
+			return nil, err
+		}
+		cfg.ImageURLTemplate = strings.TrimSpace(getEnv("IMAGE_URL_TEMPLATE"))
+		if cfg.ImageMaxBytes, err = parseInt(getEnv, "IMAGE_MAX_BYTES", cfg.ImageMaxBytes); err != nil {
+			return nil, err
+		}
+		if cfg.ImageFetchTimeout, err = parseDuration(getEnv, "IMAGE_FETCH_TIMEOUT", cfg.ImageFetchTimeout); err != nil {
+			return nil, err
+		}
+		cfg.ClusterName = strings.TrimSpace(getEnv("CLUSTER_NAME"))
+		cfg.MessagePrefix = strings.TrimSpace(getEnv("MESSAGE_PREFIX"))
+		cfg.TemplatesFile = strings.TrimSpace(getEnv("MESSAGE_TEMPLATES_FILE"))
+		if cfg.MessageFallback, err = parseBool(getEnv, "MESSAGE_FALLBACK"); err != nil {
+			return nil, err
+		}
+		if prefix := strings.TrimSpace(getEnv("PUSHOVER_METADATA_PREFIX")); prefix != "" {
+			cfg.MetadataPrefix = prefix
+		}
+		if cfg.ReasonSounds, err = parseMapping(getEnv, "REASON_SOUNDS"); err != nil {
+			return nil, err
+		}
+		if cfg.SeveritySounds, err = parseMapping(getEnv, "SEVERITY_SOUNDS"); err != nil {
+			return nil, err
+		}
+		if format := getEnv("REVISION_FORMAT"); format != "" {
+			cfg.RevisionFormat = strings.ToLower(strings.TrimSpace(format))
+		}
+		if cfg.ShortenRevision, err = parseBool(getEnv, "SHORTEN_REVISION"); err != nil {
+			return nil, err
+		}
+
+		cfg.QuietHours = strings.TrimSpace(getEnv("QUIET_HOURS"))
+		if timezone := strings.TrimSpace(getEnv("QUIET_HOURS_TIMEZONE")); timezone != "" {
+			cfg.QuietHoursTimezone = timezone
+		}
+		if mode := getEnv("QUIET_HOURS_MODE"); mode != "" {
+			cfg.QuietHoursMode = strings.ToLower(strings.TrimSpace(mode))
+		}
+		if cfg.QuietTTL, err = parseDuration(getEnv, "QUIET_TTL", 0); err != nil {
+			return nil, err
+		}
+
+		cfg.MaintenanceWindows = strings.TrimSpace(getEnv("MAINTENANCE_WINDOWS"))
+		if timezone := strings.TrimSpace(getEnv("MAINTENANCE_TIMEZONE")); timezone != "" {
+			cfg.MaintenanceTimezone = timezone
+		}
+		if mode := getEnv("MAINTENANCE_MODE"); mode != "" {
+			cfg.MaintenanceMode = strings.ToLower(strings.TrimSpace(mode))
+		}
+
+		cfg.PublicURL = strings.TrimRight(strings.TrimSpace(getEnv("PUBLIC_URL")), "/")
+		if cfg.ReceiptPollInterval, err = parseDuration(getEnv, "RECEIPT_POLL_INTERVAL", cfg.ReceiptPollInterval); err != nil {
+			return nil, err
+		}
+		if cfg.AutoCancelEmergency, err = parseBool(getEnv, "AUTO_CANCEL_EMERGENCY"); err != nil {
+			return nil, err
+		}
+
+		if glances := getEnv("GLANCES"); glances != "" {
+			cfg.Glances = strings.ToLower(strings.TrimSpace(glances))
+		}
+		if cfg.EnableGlances, err = parseBool(getEnv, "ENABLE_GLANCES"); err != nil {
+			return nil, err
+		}
+
+		if cfg.RootOK, err = parseBool(getEnv, "ROOT_OK"); err != nil {
+			return nil, err
+		}
+
+		cfg.BasePath = NormalizePath(getEnv("BASE_PATH"))
+		if webhookPath := getEnv("WEBHOOK_PATH"); webhookPath != "" {
+			// "/" is kept to be refused, the root is not a webhook route
+			if cfg.WebhookPath = NormalizePath(webhookPath); cfg.WebhookPath == "" {
+				cfg.WebhookPath = "/"
+			}
+		}
+		if cfg.HealthAtRoot, err = parseBool(getEnv, "HEALTH_AT_ROOT"); err != nil {
+			return nil, err
+		}
+		if cfg.RedirectLegacyPaths, err = parseBool(getEnv, "REDIRECT_LEGACY_PATHS"); err != nil {
+			return nil, err
+		}
+
+		if cfg.AccessLog, err = parseBool(getEnv, "ACCESS_LOG"); err != nil {
+			return nil, err
+		}
+
+		if cfg.TrustedProxies, err = parsePrefixes(getEnv, "TRUSTED_PROXIES"); err != nil {
+			return nil, err
+		}
+		if cfg.RequireForwardedHTTPS, err = parseBool(getEnv, "REQUIRE_FORWARDED_HTTPS"); err != nil {
+			return nil, err
+		}
+		cfg.AllowedOrigins = splitList(getEnv("ALLOWED_ORIGINS"))
+
+		if cfg.ValidateCredentials, err = parseBool(getEnv, "VALIDATE_CREDENTIALS"); err != nil {
+			return nil, err
+		}
+
+		if cfg.PanicNotify, err = parseBool(getEnv, "PANIC_NOTIFY"); err != nil {
+			return nil, err
+		}
+		if cfg.PanicNotifyCooldown, err = parseDuration(getEnv, "PANIC_NOTIFY_COOLDOWN", cfg.PanicNotifyCooldown); err != nil {
+			return nil, err
+		}
+
+		if cfg.WatchdogFailureStreak, err = parseInt(getEnv, "WATCHDOG_FAILURE_STREAK", cfg.WatchdogFailureStreak); err != nil {
+			return nil, err
+		}
+		if cfg.WatchdogSaturation, err = parseDuration(getEnv, "WATCHDOG_SATURATION", 0); err != nil {
+			return nil, err
+		}
+		if cfg.WatchdogFlaps, err = parseInt(getEnv, "WATCHDOG_READINESS_FLAPS", 0); err != nil {
+			return nil, err
+		}
+		if cfg.WatchdogFlapWindow, err = parseDuration(getEnv, "WATCHDOG_FLAP_WINDOW", cfg.WatchdogFlapWindow); err != nil {
+			return nil, err
+		}
+
+		if cfg.CredentialsRecheckInterval, err = parseDuration(getEnv, "CREDENTIALS_RECHECK_INTERVAL", cfg.CredentialsRecheckInterval); err != nil {
+			return nil, err
+		}
+
+		if cfg.PreShutdownDelay, err = parseDuration(getEnv, "PRESHUTDOWN_DELAY", 0); err != nil {
+			return nil, err
+		}
+		if cfg.ShutdownTimeout, err = parseDuration(getEnv, "SHUTDOWN_TIMEOUT", cfg.ShutdownTimeout); err != nil {
+			return nil, err
+		}
+
+		if cfg.LogSampleWindow, err = parseDuration(getEnv, "LOG_SAMPLE_WINDOW", cfg.LogSampleWindow); err != nil {
+			return nil, err
+		}
+		if cfg.LogSampleBurst, err = parseInt(getEnv, "LOG_SAMPLE_BURST", cfg.LogSampleBurst); err != nil {
+			return nil, err
+		}
+
+		cfg.AuditLogPath = strings.TrimSpace(getEnv("AUDIT_LOG_PATH"))
+		if cfg.AuditLogMaxSizeMB, err = parseInt(getEnv, "AUDIT_LOG_MAX_SIZE_MB", cfg.AuditLogMaxSizeMB); err != nil {
+			return nil, err
+		}
+		if cfg.AuditLogMaxFiles, err = parseInt(getEnv, "AUDIT_LOG_MAX_FILES", cfg.AuditLogMaxFiles); err != nil {
+			return nil, err
+		}
+
+		if cfg.EnableLeaderElection, err = parseBool(getEnv, "ENABLE_LEADER_ELECTION"); err != nil {
+			return nil, err
+		}
+
+		if mode := getEnv("LEADER_ELECTION_MODE"); mode != "" {
+			cfg.LeaderElectionMode = strings.ToLower(mode)
+		}
+
+		if lease := getEnv("LEADER_ELECTION_LEASE_NAME"); lease != "" {
+			cfg.LeaderElectionLease = lease
+		}
+
+		if cfg.DedupWindow, err = parseDuration(getEnv, "DEDUP_WINDOW", 0); err != nil {
+			return nil, err
+		}
+		cfg.RedisAddr = getEnv("REDIS_ADDR")
+		cfg.RedisPassword = getEnv("REDIS_PASSWORD")
+
+		if cfg.NotifyOnChangeOnly, err = parseBool(getEnv, "NOTIFY_ON_CHANGE_ONLY"); err != nil {
+			return nil, err
+		}
+		if detection := getEnv("CHANGE_DETECTION"); detection != "" {
+			cfg.ChangeDetection = strings.ToLower(strings.TrimSpace(detection))
+		}
+		if cfg.StateTTL, err = parseDuration(getEnv, "STATE_TTL", cfg.StateTTL); err != nil {
+			return nil, err
+		}
+		if cfg.StatefulNotify, err = parseBool(getEnv, "STATEFUL_NOTIFY"); err != nil {
+			return nil, err
+		}
+
+		if cfg.IdempotencyTTL, err = parseDuration(getEnv, "IDEMPOTENCY_TTL", cfg.IdempotencyTTL); err != nil {
+			return nil, err
+		}
+
+		if cfg.IdempotencyMaxKeys, err = parseInt(getEnv, "IDEMPOTENCY_MAX_KEYS", cfg.IdempotencyMaxKeys); err != nil {
+			return nil, err
+		}
+
+		if cfg.MaxEventAge, err = parseDuration(getEnv, "MAX_EVENT_AGE", 0); err != nil {
+			return nil, err
+		}
+
+		if cfg.EventClockSkew, err = parseDuration(getEnv, "EVENT_CLOCK_SKEW", cfg.EventClockSkew); err != nil {
+			return nil, err
+		}
+
+		if cfg.RequireEventTimestamp, err = parseBool(getEnv, "REQUIRE_EVENT_TIMESTAMP"); err != nil {
+			return nil, err
+		}
+
+		cfg.IncludeKinds = splitList(getEnv("INCLUDE_KINDS"))
+		cfg.ExcludeKinds = splitList(getEnv("EXCLUDE_KINDS"))
+
+		if cfg.PerNamespaceRate, err = parseFloat(getEnv, "PER_NAMESPACE_RATE", 0); err != nil {
+			return nil, err
+		}
+		if cfg.ShedMaxConcurrent, err = parseInt(getEnv, "SHED_MAX_CONCURRENT", 0); err != nil {
+			return nil, err
+		}
+		if cfg.ShedHighWater, err = parseInt(getEnv, "SHED_HIGH_WATER", cfg.ShedHighWater); err != nil {
+			return nil, err
+		}
+
+		if cfg.CoalesceWindow, err = parseDuration(getEnv, "COALESCE_WINDOW", 0); err != nil {
+			return nil, err
+		}
+		if cfg.CoalesceBypassErrors, err = parseBool(getEnv, "COALESCE_BYPASS_ERRORS"); err != nil {
+			return nil, err
+		}
+		if cfg.DispatchWorkers, err = parseInt(getEnv, "DISPATCH_WORKERS", cfg.DispatchWorkers); err != nil {
+			return nil, err
+		}
+		if cfg.QueueUnhealthyDepth, err = parseInt(getEnv, "QUEUE_UNHEALTHY_DEPTH", 0); err != nil {
+			return nil, err
+		}
+		if cfg.SendFailureUnreadyWindow, err = parseDuration(getEnv, "SEND_FAILURE_UNREADY_WINDOW", cfg.SendFailureUnreadyWindow); err != nil {
+			return nil, err
+		}
+
+		if cfg.RetryMaxAttempts, err = parseInt(getEnv, "RETRY_MAX_ATTEMPTS", cfg.RetryMaxAttempts); err != nil {
+			return nil, err
+		}
+
+		if cfg.RetryBudgetRatio, err = parseFloat(getEnv, "RETRY_BUDGET_RATIO", cfg.RetryBudgetRatio); err != nil {
+			return nil, err
+		}
+
+		if cfg.RetryOnTimeout, err = parseBool(getEnv, "RETRY_ON_TIMEOUT"); err != nil {
+			return nil, err
+		}
+
+		if cfg.RetryAfterSeconds, err = parseInt(getEnv, "RETRY_AFTER_SECONDS", cfg.RetryAfterSeconds); err != nil {
+			return nil, err
+		}
+
+		if cfg.PushoverTimeout, err = parseDuration(getEnv, "PUSHOVER_TIMEOUT", cfg.PushoverTimeout); err != nil {
+			return nil, err
+		}
+		if cfg.RequestDeadline, err = parseDuration(getEnv, "REQUEST_DEADLINE", cfg.RequestDeadline); err != nil {
+			return nil, err
+		}
+
+		// The pool settings were named HTTP_* before
+		maxIdleConns := cfg.envAlias(getEnv, "PUSHOVER_MAX_IDLE_CONNS", "HTTP_MAX_IDLE_CONNS")
+		if cfg.HTTPMaxIdleConns, err = parseInt(getEnv, maxIdleConns, cfg.HTTPMaxIdleConns); err != nil {
+			return nil, err
+		}
+
+		maxIdleConnsPerHost := cfg.envAlias(getEnv, "PUSHOVER_MAX_IDLE_CONNS_PER_HOST", "HTTP_MAX_IDLE_CONNS_PER_HOST")
+		if cfg.HTTPMaxIdleConnsPerHost, err = parseInt(getEnv, maxIdleConnsPerHost, cfg.HTTPMaxIdleConnsPerHost); err != nil {
+			return nil, err
+		}
+		if cfg.PushoverMaxInFlight, err = parseInt(getEnv, "PUSHOVER_MAX_INFLIGHT", 0); err != nil {
+			return nil, err
+		}
+
+		if cfg.HTTPIdleConnTimeout, err = parseDuration(getEnv, "HTTP_IDLE_CONN_TIMEOUT", cfg.HTTPIdleConnTimeout); err != nil {
+			return nil, err
+		}
+
+		if cfg.HTTPTLSHandshakeTimeout, err = parseDuration(getEnv, "HTTP_TLS_HANDSHAKE_TIMEOUT", cfg.HTTPTLSHandshakeTimeout); err != nil {
+			return nil, err
+		}
+
+		if cfg.HTTPExpectContinueTimeout, err = parseDuration(getEnv, "HTTP_EXPECT_CONTINUE_TIMEOUT", cfg.HTTPExpectContinueTimeout); err != nil {
+			return nil, err
+		}
+
+		// HTTP/2 is on unless disabled explicitly
+		if getEnv("PUSHOVER_HTTP2") != "" {
+			if cfg.HTTP2, err = parseBool(getEnv, "PUSHOVER_HTTP2"); err != nil {
+				return nil, err
+			}
+		}
+
+		if cfg.HTTPCompression, err = parseBool(getEnv, "HTTP_COMPRESSION"); err != nil {
+			return nil, err
+		}
+
+		if cfg.StrictAlerts, err = parseBool(getEnv, "STRICT_ALERTS"); err != nil {
+			return nil, err
+		}
+		if mode := getEnv("VALIDATION_MODE"); mode != "" {
+			cfg.ValidationMode = strings.ToLower(strings.TrimSpace(mode))
+		}
+		cfg.ExtraSeverities = splitList(getEnv("EXTRA_SEVERITIES"))
+		if cfg.FieldAliases, err = parseMapping(getEnv, "FIELD_ALIASES"); err != nil {
+			return nil, err
+		}
+		for alias, field := range cfg.FieldAliases {
+			if path, ok := AlertFieldPath(field); ok {
+				cfg.FieldAliases[alias] = path
+			}
+		}
+
+		if cfg.MaxJSONDepth, err = parseInt(getEnv, "MAX_JSON_DEPTH", cfg.MaxJSONDepth); err != nil {
+			return nil, err
+		}
+
+		if cfg.MaxJSONTokens, err = parseInt(getEnv, "MAX_JSON_TOKENS", cfg.MaxJSONTokens); err != nil {
+			return nil, err
+		}
+
+		if cfg.DebugLogInvalidPayloads, err = parseBool(getEnv, "DEBUG_LOG_INVALID_PAYLOADS"); err != nil {
+			return nil, err
+		}
+		if cfg.DebugPayloadLogBytes, err = parseInt(getEnv, "DEBUG_PAYLOAD_LOG_BYTES", cfg.DebugPayloadLogBytes); err != nil {
+			return nil, err
+		}
+
+		// A bare port listens on all interfaces, like PORT
+		if debugAddr := strings.TrimSpace(getEnv("DEBUG_ADDR")); debugAddr != "" {
+			if !strings.Contains(debugAddr, ":") {
+				debugAddr = ":" + debugAddr
+			}
+			cfg.DebugAddr = debugAddr
+		}
+
+		// Both the old and the new token are listed while rotating
+		for _, entry := range strings.Split(getEnv("WEBHOOK_TOKENS"), ",") {
+			if strings.TrimSpace(entry) == "" {
+				continue
+			}
+			token, cluster, err := parseWebhookToken(entry, len(cfg.WebhookTokens))
+			if err != nil {
+				return nil, err
+			}
+			cfg.WebhookTokens = append(cfg.WebhookTokens, token)
+			cfg.WebhookTokenClusters = append(cfg.WebhookTokenClusters, cluster)
+		}
+		cfg.WebhookToken = strings.TrimSpace(getEnv("WEBHOOK_TOKEN"))
+		cfg.WebhookBasicUser = strings.TrimSpace(getEnv("WEBHOOK_BASIC_USER"))
+		cfg.WebhookBasicPassword = getEnv("WEBHOOK_BASIC_PASSWORD")
+
+		// Pre-compute Bearer token, falling back to the deprecated API token
+		switch {
+		case cfg.WebhookToken != "":
+			cfg.BearerToken = "Bearer " + cfg.WebhookToken
+		case cfg.PushoverAPIToken != "":
+			cfg.BearerToken = "Bearer " + cfg.PushoverAPIToken
+		}
+
+		return cfg, nil
+	}
+}
+
+// redactedValue replaces credentials in Redacted
+const redactedValue = "[REDACTED]"
+
+// Redacted returns a copy of cfg with credentials masked, for logging (pure function)
+func Redacted(cfg *Config) *Config {
+	if cfg == nil {
+		return nil
+	}
+
+	redacted := *cfg
+	for _, secret := range []*string{
+		&redacted.PushoverUserKey,
+		&redacted.PushoverAPIToken,
+		&redacted.BearerToken,
+		&redacted.WebhookToken,
+		&redacted.NtfyToken,
+		&redacted.OutgoingWebhookToken,
+		&redacted.ForwardToken,
+		&redacted.ForwardHMACSecret,
+		&redacted.SlackWebhookURL,
+		&redacted.RedisPassword,
+		&redacted.WebhookBasicPassword,
+	} {
+		if *secret != "" {
+			*secret = redactedValue
+		}
+	}
+	if len(cfg.SeverityCredentials) > 0 {
+		redacted.SeverityCredentials = make(map[string]PushoverCredentials, len(cfg.SeverityCredentials))
+		for severity, credentials := range cfg.SeverityCredentials {
+			if credentials.Token != "" {
+				credentials.Token = redactedValue
+			}
+			if credentials.User != "" {
+				credentials.User = redactedValue
+			}
+			redacted.SeverityCredentials[severity] = credentials
+		}
+	}
+	if len(cfg.WebhookTokens) > 0 {
+		redacted.WebhookTokens = make([]string, len(cfg.WebhookTokens))
+		for i := range redacted.WebhookTokens {
+			redacted.WebhookTokens[i] = redactedValue
+		}
+	}
+	return &redacted
+}
+
+// DefaultConfigLoader loads config from os.Getenv
+var DefaultConfigLoader = LoadFromEnv(os.Getenv)
+
+// ValidateConfig validates the configuration (pure function)
+func ValidateConfig(cfg *Config) error {
+	if cfg == nil {
+		return fmt.Errorf("config is nil")
+	}
+
+	if strings.TrimSpace(cfg.PushoverUserKey) == "" {
+		return fmt.Errorf("PUSHOVER_USER_KEY is required")
+	}
+
+	if strings.TrimSpace(cfg.PushoverAPIToken) == "" {
+		return fmt.Errorf("PUSHOVER_API_TOKEN is required")
+	}
+
+	if err := validateSeverityCredentials(cfg); err != nil {
+		return err
+	}
+
+	for i, cluster := range cfg.WebhookTokenClusters {
+		if cluster.Name != "" && !ValidClusterName(cluster.Name) {
+			return fmt.Errorf("WEBHOOK_TOKENS entry %d: cluster must be 1-%d letters, digits, '.', '_' or '-'", i+1, maxClusterNameLength)
+		}
+	}
+
+	if (cfg.WebhookBasicUser == "") != (cfg.WebhookBasicPassword == "") {
+		return fmt.Errorf("WEBHOOK_BASIC_USER and WEBHOOK_BASIC_PASSWORD must be set together")
+	}
+
+	if cfg.SuccessStatus != 0 && cfg.SuccessStatus != http.StatusOK && cfg.SuccessStatus != http.StatusAccepted {
+		return fmt.Errorf("SUCCESS_STATUS must be %d or %d", http.StatusOK, http.StatusAccepted)
+	}
+
+	if err := validateRetry(cfg); err != nil {
+		return err
+	}
+
+	if cfg.PushoverTimeout < 0 {
+		return fmt.Errorf("PUSHOVER_TIMEOUT must not be negative")
+	}
+	if cfg.RequestDeadline < 0 {
+		return fmt.Errorf("REQUEST_DEADLINE must not be negative")
+	}
+
+	if err := validateLeaderElection(cfg); err != nil {
+		return err
+	}
+
+	if cfg.DedupWindow < 0 {
+		return fmt.Errorf("DEDUP_WINDOW must not be negative")
+	}
+
+	if err := validateFieldAliases(cfg.FieldAliases); err != nil {
+		return err
+	}
+
+	switch cfg.ValidationMode {
+	case "", ValidationModeStrict, ValidationModeLenient:
+	default:
+		return fmt.Errorf("VALIDATION_MODE must be %q or %q", ValidationModeStrict, ValidationModeLenient)
+	}
+
+	switch cfg.ChangeDetection {
+	case "", ChangeDetectionMessage, ChangeDetectionReason:
+	default:
+		return fmt.Errorf("CHANGE_DETECTION must be %q or %q", ChangeDetectionReason, ChangeDetectionMessage)
+	}
+
+	if cfg.StateTTL < 0 {
+		return fmt.Errorf("STATE_TTL must not be negative")
+	}
+
+	if cfg.IdempotencyTTL < 0 {
+		return fmt.Errorf("IDEMPOTENCY_TTL must not be negative")
+	}
+
+	if cfg.IdempotencyMaxKeys < 0 {
+		return fmt.Errorf("IDEMPOTENCY_MAX_KEYS must not be negative")
+	}
+
+	if cfg.MaxEventAge < 0 {
+		return fmt.Errorf("MAX_EVENT_AGE must not be negative")
+	}
+
+	if cfg.EventClockSkew < 0 {
+		return fmt.Errorf("EVENT_CLOCK_SKEW must not be negative")
+	}
+
+	if cfg.PerNamespaceRate < 0 {
+		return fmt.Errorf("PER_NAMESPACE_RATE must not be negative")
+	}
+
+	if cfg.ShedMaxConcurrent < 0 {
+		return fmt.Errorf("SHED_MAX_CONCURRENT must not be negative")
+	}
+	if cfg.ShedMaxConcurrent > 0 && cfg.ShedHighWater < 1 {
+		return fmt.Errorf("SHED_HIGH_WATER must be at least 1")
+	}
+
+	if cfg.CoalesceWindow < 0 {
+		return fmt.Errorf("COALESCE_WINDOW must not be negative")
+	}
+	if cfg.CoalesceWindow > 0 && cfg.DispatchWorkers < 1 {
+		return fmt.Errorf("DISPATCH_WORKERS must be at least 1")
+	}
+	if cfg.QueueUnhealthyDepth < 0 {
+		return fmt.Errorf("QUEUE_UNHEALTHY_DEPTH must not be negative")
+	}
+	if cfg.SendFailureUnreadyWindow < 0 {
+		return fmt.Errorf("SEND_FAILURE_UNREADY_WINDOW must not be negative")
+	}
+
+	if err := validateHTTPTransport(cfg); err != nil {
+		return err
+	}
+
+	if err := validateDebugAddr(cfg); err != nil {
+		return err
+	}
+
+	if cfg.PanicNotifyCooldown < 0 {
+		return fmt.Errorf("PANIC_NOTIFY_COOLDOWN must not be negative")
+	}
+
+	if cfg.WatchdogFailureStreak < 0 {
+		return fmt.Errorf("WATCHDOG_FAILURE_STREAK must not be negative")
+	}
+	if cfg.WatchdogSaturation < 0 {
+		return fmt.Errorf("WATCHDOG_SATURATION must not be negative")
+	}
+	if cfg.WatchdogFlaps < 0 {
+		return fmt.Errorf("WATCHDOG_READINESS_FLAPS must not be negative")
+	}
+	if cfg.WatchdogFlaps > 0 && cfg.WatchdogFlapWindow <= 0 {
+		return fmt.Errorf("WATCHDOG_FLAP_WINDOW must be positive")
+	}
+
+	if cfg.CredentialsRecheckInterval < 0 {
+		return fmt.Errorf("CREDENTIALS_RECHECK_INTERVAL must not be negative")
+	}
+	if cfg.PreShutdownDelay < 0 {
+		return fmt.Errorf("PRESHUTDOWN_DELAY must not be negative")
+	}
+	if cfg.ShutdownTimeout < 0 {
+		return fmt.Errorf("SHUTDOWN_TIMEOUT must not be negative")
+	}
+
+	if cfg.LogSampleWindow < 0 {
+		return fmt.Errorf("LOG_SAMPLE_WINDOW must not be negative")
+	}
+
+	if cfg.LogSampleWindow > 0 && cfg.LogSampleBurst <= 0 {
+		return fmt.Errorf("LOG_SAMPLE_BURST must be positive")
+	}
+
+	if cfg.AuditLogPath != "" && cfg.AuditLogMaxSizeMB <= 0 {
+		return fmt.Errorf("AUDIT_LOG_MAX_SIZE_MB must be positive")
+	}
+
+	if cfg.AuditLogMaxFiles < 0 {
+		return fmt.Errorf("AUDIT_LOG_MAX_FILES must not be negative")
+	}
+
+	if cfg.MaxJSONDepth < 0 {
+		return fmt.Errorf("MAX_JSON_DEPTH must not be negative")
+	}
+
+	if cfg.MaxJSONTokens < 0 {
+		return fmt.Errorf("MAX_JSON_TOKENS must not be negative")
+	}
+
+	if cfg.DebugLogInvalidPayloads && cfg.DebugPayloadLogBytes <= 0 {
+		return fmt.Errorf("DEBUG_PAYLOAD_LOG_BYTES must be positive")
+	}
+
+	if cfg.MaxTitleLength < 0 || cfg.MaxTitleLength > types.MaxTitleLength {
+		return fmt.Errorf("MAX_TITLE_LENGTH must be between 0 and %d", types.MaxTitleLength)
+	}
+	if cfg.MaxMessageLines < 0 {
+		return fmt.Errorf("MAX_MESSAGE_LINES must not be negative")
+	}
+
+	if cfg.AttachOverflow && (cfg.AttachmentMaxBytes < 1 || cfg.AttachmentMaxBytes > types.MaxAttachmentBytes) {
+		return fmt.Errorf("ATTACHMENT_MAX_BYTES must be between 1 and %d", types.MaxAttachmentBytes)
+	}
+	if cfg.ImageURLTemplate != "" {
+		if cfg.ImageMaxBytes < 1 || cfg.ImageMaxBytes > types.MaxAttachmentBytes {
+			return fmt.Errorf("IMAGE_MAX_BYTES must be between 1 and %d", types.MaxAttachmentBytes)
+		}
+		if cfg.ImageFetchTimeout <= 0 {
+			return fmt.Errorf("IMAGE_FETCH_TIMEOUT must be positive")
+		}
+	}
+
+	if err := validateSounds(cfg); err != nil {
+		return err
+	}
+
+	switch cfg.RevisionFormat {
+	case "", RevisionFormatFull, RevisionFormatShort, RevisionFormatBranchShort:
+	default:
+		return fmt.Errorf("REVISION_FORMAT must be %q, %q or %q", RevisionFormatFull, RevisionFormatShort, RevisionFormatBranchShort)
+	}
+	if cfg.ShortenRevision && cfg.RevisionFormat != "" && cfg.RevisionFormat != RevisionFormatFull {
+		return fmt.Errorf("SHORTEN_REVISION cannot be combined with REVISION_FORMAT=%s", cfg.RevisionFormat)
+	}
+
+	if err := validatePublicURL(cfg); err != nil {
+		return err
+	}
+
+	if cfg.ReceiptPollInterval != 0 && cfg.ReceiptPollInterval < MinReceiptPollInterval {
+		return fmt.Errorf("RECEIPT_POLL_INTERVAL must be 0 or at least %s", MinReceiptPollInterval)
+	}
+
+	if err := validatePushoverBaseURL(cfg); err != nil {
+		return err
+	}
+
+	if err := validatePushoverResolve(cfg); err != nil {
+		return err
+	}
+
+	if cfg.ForwardSign && cfg.ForwardHMACSecret == "" {
+		return fmt.Errorf("FORWARD_SIGN needs FORWARD_HMAC_SECRET")
+	}
+	if err := validateForwardedHTTPS(cfg); err != nil {
+		return err
+	}
+
+	if err := validateAllowedOrigins(cfg); err != nil {
+		return err
+	}
+
+	if err := validatePaths(cfg); err != nil {
+		return err
+	}
+
+	if err := validateQuietHours(cfg); err != nil {
+		return err
+	}
+	if err := validateMaintenance(cfg); err != nil {
+		return err
+	}
+
+	switch cfg.Glances {
+	case "", GlancesOff, GlancesAlongside, GlancesInstead:
+	default:
+		return fmt.Errorf("GLANCES must be %q, %q or %q", GlancesOff, GlancesAlongside, GlancesInstead)
+	}
+	if cfg.EnableGlances && cfg.Glances != "" && cfg.Glances != GlancesOff {
+		return fmt.Errorf("ENABLE_GLANCES and GLANCES=%s both update the Glances widget, enable only one", cfg.Glances)
+	}
+
+	return validateProviders(cfg)
+}
+
+// validateSounds validates the sounds by reason and severity
+func validateSounds(cfg *Config) error {
+	for severity, sound := range cfg.SeveritySounds {
+		switch severity {
+		case types.SeverityInfo, types.SeverityWarning, types.SeverityError:
+		default:
+			return fmt.Errorf("unknown severity %q in SEVERITY_SOUNDS", severity)
+		}
+		if !types.IsPushoverSound(sound) {
+			return fmt.Errorf("unknown Pushover sound %q in SEVERITY_SOUNDS", sound)
+		}
+	}
+	for _, sound := range cfg.ReasonSounds {
+		if !types.IsPushoverSound(sound) {
+			return fmt.Errorf("unknown Pushover sound %q in REASON_SOUNDS", sound)
+		}
+	}
+	return nil
+}
+
+// validateSeverityCredentials checks the format of the per-severity
+// Pushover tokens and user keys (pure function)
+func validateSeverityCredentials(cfg *Config) error {
+	for _, severity := range credentialSeverities {
+		credentials := cfg.SeverityCredentials[severity]
+		suffix := "_" + strings.ToUpper(severity)
+		if credentials.Token != "" && !isPushoverKey(credentials.Token) {
+			return fmt.Errorf("PUSHOVER_API_TOKEN%s must be 30 letters and digits", suffix)
+		}
+		if credentials.User != "" && !isPushoverKey(credentials.User) {
+			return fmt.Errorf("PUSHOVER_USER_KEY%s must be 30 letters and digits", suffix)
+		}
+	}
+	return nil
+}
+
+// isPushoverKey reports whether key has the format of Pushover application
+// tokens and user keys (pure function)
+func isPushoverKey(key string) bool {
+	if len(key) != 30 {
+		return false
+	}
+	for _, r := range key {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// validateRetry validates the retry settings
+func validateRetry(cfg *Config) error {
+	if cfg.RetryMaxAttempts < 0 {
+		return fmt.Errorf("RETRY_MAX_ATTEMPTS must not be negative")
+	}
+
+	if cfg.RetryBudgetRatio < 0 {
+		return fmt.Errorf("RETRY_BUDGET_RATIO must not be negative")
+	}
+
+	if cfg.RetryAfterSeconds < 0 {
+		return fmt.Errorf("RETRY_AFTER_SECONDS must not be negative")
+	}
+
+	return nil
+}
+
+// validateLeaderElection validates the leader election settings
+func validateLeaderElection(cfg *Config) error {
+	switch cfg.LeaderElectionMode {
+	case "", LeaderElectionModeStandby, LeaderElectionModeProxy:
+		return nil
+	default:
+		return fmt.Errorf("LEADER_ELECTION_MODE must be %q or %q", LeaderElectionModeStandby, LeaderElectionModeProxy)
+	}
+}
+
+// validateHTTPTransport validates the connection pool and protocol settings
+func validateHTTPTransport(cfg *Config) error {
+	if cfg.HTTPMaxIdleConns < 0 {
+		return fmt.Errorf("PUSHOVER_MAX_IDLE_CONNS must not be negative")
+	}
+
+	if cfg.HTTPMaxIdleConnsPerHost < 0 {
+		return fmt.Errorf("PUSHOVER_MAX_IDLE_CONNS_PER_HOST must not be negative")
+	}
+
+	if cfg.PushoverMaxInFlight < 0 {
+		return fmt.Errorf("PUSHOVER_MAX_INFLIGHT must not be negative")
+	}
+
+	if cfg.HTTPIdleConnTimeout < 0 {
+		return fmt.Errorf("HTTP_IDLE_CONN_TIMEOUT must not be negative")
+	}
+
+	if cfg.HTTPTLSHandshakeTimeout < 0 {
+		return fmt.Errorf("HTTP_TLS_HANDSHAKE_TIMEOUT must not be negative")
+	}
+
+	if cfg.HTTPExpectContinueTimeout < 0 {
+		return fmt.Errorf("HTTP_EXPECT_CONTINUE_TIMEOUT must not be negative")
+	}
+
+	return nil
+}
+
+// validateForwardedHTTPS requires proxies to trust for REQUIRE_FORWARDED_HTTPS,
+// without them every request would be refused
+func validateForwardedHTTPS(cfg *Config) error {
+	if cfg.RequireForwardedHTTPS && len(cfg.TrustedProxies) == 0 {
+		return fmt.Errorf("REQUIRE_FORWARDED_HTTPS needs TRUSTED_PROXIES")
+	}
+	return nil
+}
+
+// validateAllowedOrigins requires "*" or origins of a scheme and host
+// without a path, as browsers send them
+func validateAllowedOrigins(cfg *Config) error {
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+			return fmt.Errorf("invalid ALLOWED_ORIGINS entry %q, expected e.g. https://dashboard.example.com", origin)
+		}
+	}
+	return nil
+}
+
+// validateQuietHours validates the quiet hours window, time zone and mode
+func validateQuietHours(cfg *Config) error {
+	switch cfg.QuietHoursMode {
+	case "", QuietHoursSilent, QuietHoursSuppress:
+	default:
+		return fmt.Errorf("QUIET_HOURS_MODE must be %q or %q", QuietHoursSilent, QuietHoursSuppress)
+	}
+
+	if cfg.QuietTTL < 0 || (cfg.QuietTTL > 0 && cfg.QuietTTL < time.Second) {
+		return fmt.Errorf("QUIET_TTL must be 0 or at least 1s")
+	}
+
+	if cfg.QuietHours == "" {
+		if cfg.QuietTTL > 0 {
+			return fmt.Errorf("QUIET_TTL requires QUIET_HOURS")
+		}
+		return nil
+	}
+	if _, _, err := ParseQuietHours(cfg.QuietHours); err != nil {
+		return fmt.Errorf("invalid QUIET_HOURS: %w", err)
+	}
+	if _, err := time.LoadLocation(cfg.QuietHoursTimezone); err != nil {
+		return fmt.Errorf("invalid QUIET_HOURS_TIMEZONE: %w", err)
+	}
+	return nil
+}
+
+// validateMaintenance validates the maintenance windows, time zone and mode
+func validateMaintenance(cfg *Config) error {
+	switch cfg.MaintenanceMode {
+	case "", MaintenanceSuppress, MaintenanceDowngrade:
+	default:
+		return fmt.Errorf("MAINTENANCE_MODE must be %q or %q", MaintenanceSuppress, MaintenanceDowngrade)
+	}
+
+	if cfg.MaintenanceWindows == "" {
+		return nil
+	}
+	if _, err := maintenance.ParseWindows(cfg.MaintenanceWindows); err != nil {
+		return fmt.Errorf("invalid MAINTENANCE_WINDOWS: %w", err)
+	}
+	if _, err := time.LoadLocation(cfg.MaintenanceTimezone); err != nil {
+		return fmt.Errorf("invalid MAINTENANCE_TIMEZONE: %w", err)
+	}
+	return nil
+}
+
+// ParseQuietHours parses a "HH:MM-HH:MM" window into its start and end as
+// offsets from midnight. The end may be before the start for windows
+// crossing midnight (pure function).
+func ParseQuietHours(window string) (start, end time.Duration, err error) {
+	from, to, found := strings.Cut(window, "-")
+	if !found {
+		return 0, 0, fmt.Errorf("%q is not a HH:MM-HH:MM window", window)
+	}
+	if start, err = parseClock(strings.TrimSpace(from)); err != nil {
+		return 0, 0, err
+	}
+	if end, err = parseClock(strings.TrimSpace(to)); err != nil {
+		return 0, 0, err
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("%q is empty, start and end must differ", window)
+	}
+	return start, end, nil
+}
+
+// parseClock parses a 24-hour "HH:MM" time of day (pure function)
+func parseClock(clock string) (time.Duration, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", clock)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// validatePublicURL requires an absolute http(s) URL for callbacks
+func validatePublicURL(cfg *Config) error {
+	if cfg.PublicURL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(cfg.PublicURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("PUBLIC_URL must be an absolute http or https URL")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("PUBLIC_URL must not have a query or fragment")
+	}
+	return nil
+}
+
+// reservedPaths are the built-in routes a renamed webhook must not shadow
+var reservedPaths = []string{"/health", "/ready", "/status", "/metrics", "/pushover-callback", "/debug"}
+
+// validatePaths validates BASE_PATH and WEBHOOK_PATH
+func validatePaths(cfg *Config) error {
+	if cfg.BasePath != "" {
+		if err := validateRoutePath("BASE_PATH", cfg.BasePath); err != nil {
+			return err
+		}
+	}
+
+	// Configs built in code may leave the webhook path at the default
+	if cfg.WebhookPath == "" {
+		return nil
+	}
+	if err := validateRoutePath("WEBHOOK_PATH", cfg.WebhookPath); err != nil {
+		return err
+	}
+	for _, reserved := range reservedPaths {
+		if cfg.WebhookPath == reserved || strings.HasPrefix(cfg.WebhookPath, reserved+"/") {
+			return fmt.Errorf("WEBHOOK_PATH must not be the built-in route %s", reserved)
+		}
+	}
+	return nil
+}
+
+// validateRoutePath requires a clean absolute path of plain URL characters,
+// anything else would be read as a ServeMux pattern
+func validateRoutePath(name, route string) error {
+	if !strings.HasPrefix(route, "/") {
+		return fmt.Errorf("%s must start with /", name)
+	}
+	if route == "/" || path.Clean(route) != route {
+		return fmt.Errorf("%s must be a clean path below /, got %q", name, route)
+	}
+	for _, r := range route {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("/-._~", r)) {
+			return fmt.Errorf("%s must only contain letters, digits and /-._~, got %q", name, route)
+		}
+	}
+	return nil
+}
+
+// NormalizePath trims whitespace and trailing slashes from a route, so
+// "/hooks/pushover/" and "/hooks/pushover" mount the same way and "/" is
+// the root (pure function)
+func NormalizePath(route string) string {
+	return strings.TrimRight(strings.TrimSpace(route), "/")
+}
+
+// validatePushoverBaseURL requires an absolute http(s) URL for relays
+func validatePushoverBaseURL(cfg *Config) error {
+	if cfg.PushoverBaseURL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(cfg.PushoverBaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("PUSHOVER_BASE_URL must be an absolute http or https URL")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("PUSHOVER_BASE_URL must not have a query or fragment")
+	}
+	return nil
+}
+
+// validatePushoverResolve validates the DNS hardening of the Pushover host
+func validatePushoverResolve(cfg *Config) error {
+	if cfg.PushoverResolveOverride != "" {
+		if _, err := netip.ParseAddrPort(cfg.PushoverResolveOverride); err != nil {
+			return fmt.Errorf("PUSHOVER_RESOLVE_OVERRIDE must be IP:port: %w", err)
+		}
+	}
+
+	if cfg.PushoverDNSCacheTTL < 0 {
+		return fmt.Errorf("PUSHOVER_DNS_CACHE_TTL must not be negative")
+	}
+
+	if cfg.PushoverDNSTimeout < 0 {
+		return fmt.Errorf("PUSHOVER_DNS_TIMEOUT must not be negative")
+	}
+	return nil
+}
+
+// validateDebugAddr keeps the debug listener off the public address
+func validateDebugAddr(cfg *Config) error {
+	if cfg.DebugAddr == "" {
+		return nil
+	}
+
+	debugHost, debugPort, err := net.SplitHostPort(cfg.DebugAddr)
+	if err != nil {
+		return fmt.Errorf("DEBUG_ADDR must be host:port: %w", err)
+	}
+
+	mainHost, mainPort, err := net.SplitHostPort(cfg.Port)
+	// Port 0 picks a free port, which can never collide
+	if err != nil || debugPort != mainPort || debugPort == "0" {
+		return nil
+	}
+
+	if debugHost == mainHost || isWildcardHost(debugHost) || isWildcardHost(mainHost) {
+		return fmt.Errorf("DEBUG_ADDR must differ from the main listen address %s", cfg.Port)
+	}
+
+	return nil
+}
+
+// isWildcardHost reports whether host listens on all interfaces (pure function)
+func isWildcardHost(host string) bool {
+	return host == "" || host == "0.0.0.0" || host == "::"
+}
+
+// validateProviders validates the notification provider settings
+func validateProviders(cfg *Config) error {
+	switch cfg.ProvidersMode {
+	case "", ProvidersModeFanOut, ProvidersModeFailover:
+	default:
+		return fmt.Errorf("PROVIDERS_MODE must be %q or %q", ProvidersModeFanOut, ProvidersModeFailover)
+	}
+
+	for _, provider := range cfg.Providers {
+		switch provider {
+		case ProviderPushover:
+		case ProviderNtfy:
+			if cfg.NtfyTopic == "" {
+				return fmt.Errorf("NTFY_TOPIC is required when ntfy provider is enabled")
+			}
+		case ProviderWebhook:
+			if cfg.OutgoingWebhookURL == "" {
+				return fmt.Errorf("OUTGOING_WEBHOOK_URL is required when webhook provider is enabled")
+			}
+		case ProviderSlack:
+			if cfg.SlackWebhookURL == "" {
+				return fmt.Errorf("SLACK_WEBHOOK_URL is required when slack provider is enabled")
+			}
+		default:
+			return fmt.Errorf("unknown provider %q in PROVIDERS", provider)
+		}
+	}
+
+	return nil
+}
+
+// parseBool parses an optional boolean environment variable
+func parseBool(getEnv func(string) string, key string) (bool, error) {
+	value := getEnv(key)
+	if value == "" {
+		return false, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean: %w", key, err)
+	}
+	return parsed, nil
+}
+
+// parseInt parses an optional integer environment variable
+func parseInt(getEnv func(string) string, key string, def int) (int, error) {
+	value := getEnv(key)
+	if value == "" {
+		return def, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return def, fmt.Errorf("%s must be an integer: %w", key, err)
+	}
+	return parsed, nil
+}
+
+// parseFloat parses an optional floating point environment variable
+func parseFloat(getEnv func(string) string, key string, def float64) (float64, error) {
+	value := getEnv(key)
+	if value == "" {
+		return def, nil
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return def, fmt.Errorf("%s must be a number: %w", key, err)
+	}
+	return parsed, nil
+}
+
+// parseDuration parses an optional duration environment variable such as "5m"
+func parseDuration(getEnv func(string) string, key string, def time.Duration) (time.Duration, error) {
+	value := getEnv(key)
+	if value == "" {
+		return def, nil
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return def, fmt.Errorf("%s must be a duration: %w", key, err)
+	}
+	return parsed, nil
+}
+
+// splitList splits a comma-separated list, trimming and lowercasing entries (pure function)
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseMapping parses a comma-separated list of key=value pairs, e.g.
+// "ImagePullBackOff=siren,HealthCheckFailed=falling", lowercasing both
+func parseMapping(getEnv func(string) string, key string) (map[string]string, error) {
+	var mapping map[string]string
+	for _, item := range splitList(getEnv(key)) {
+		name, value, found := strings.Cut(item, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !found || name == "" || value == "" {
+			return nil, fmt.Errorf("invalid %s entry %q, expected name=value", key, item)
+		}
+		if mapping == nil {
+			mapping = make(map[string]string)
+		}
+		mapping[name] = value
+	}
+	return mapping, nil
+}
+
+// parsePrefixes parses a comma-separated list of CIDRs, a bare address
+// stands for itself
+func parsePrefixes(getEnv func(string) string, key string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range strings.Split(getEnv(key), ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s entry %q: %w", key, item, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", key, item, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
 }
 
 // WithValidation wraps a ConfigLoader with validation
@@ -2,7 +2,13 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
 )
 
 func TestNewConfig(t *testing.T) {
@@ -108,169 +114,2798 @@ func TestLoadFromEnv(t *testing.T) {
 	}
 }
 
-func TestValidateConfig(t *testing.T) {
+func TestLoadFromEnv_ResponseOverrides(t *testing.T) {
+	env := map[string]string{
+		"RESPONSE_CONTENT_TYPE":            "text/plain",
+		"RESPONSE_OK_BODY":                 "ok",
+		"RESPONSE_UNAUTHORIZED_BODY":       "denied",
+		"RESPONSE_INVALID_JSON_BODY":       "bad",
+		"RESPONSE_METHOD_NOT_ALLOWED_BODY": "nope",
+	}
+
+	config, err := LoadFromEnv(func(key string) string { return env[key] })()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if config.ResponseContentType != "text/plain" {
+		t.Errorf("ResponseContentType: expected text/plain, got %s", config.ResponseContentType)
+	}
+
+	if config.ResponseOKBody != "ok" {
+		t.Errorf("ResponseOKBody: expected ok, got %s", config.ResponseOKBody)
+	}
+
+	if config.ResponseUnauthorizedBody != "denied" {
+		t.Errorf("ResponseUnauthorizedBody: expected denied, got %s", config.ResponseUnauthorizedBody)
+	}
+
+	if config.ResponseInvalidJSONBody != "bad" {
+		t.Errorf("ResponseInvalidJSONBody: expected bad, got %s", config.ResponseInvalidJSONBody)
+	}
+
+	if config.ResponseMethodNotAllowedBody != "nope" {
+		t.Errorf("ResponseMethodNotAllowedBody: expected nope, got %s", config.ResponseMethodNotAllowedBody)
+	}
+}
+
+func TestLoadFromEnv_Providers(t *testing.T) {
+	env := map[string]string{
+		"PROVIDERS":              " Pushover, ntfy ,,webhook",
+		"PROVIDERS_MODE":         "FAILOVER",
+		"NTFY_TOPIC":             "flux",
+		"NTFY_TOKEN":             "tk",
+		"OUTGOING_WEBHOOK_URL":   "https://hooks.example.com",
+		"OUTGOING_WEBHOOK_TOKEN": "wh",
+	}
+
+	config, err := LoadFromEnv(func(key string) string { return env[key] })()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{ProviderPushover, ProviderNtfy, ProviderWebhook}
+	if fmt.Sprint(config.Providers) != fmt.Sprint(expected) {
+		t.Errorf("Providers: expected %v, got %v", expected, config.Providers)
+	}
+
+	if config.ProvidersMode != ProvidersModeFailover {
+		t.Errorf("ProvidersMode: expected %s, got %s", ProvidersModeFailover, config.ProvidersMode)
+	}
+
+	if config.NtfyURL != "https://ntfy.sh" {
+		t.Errorf("NtfyURL: expected default, got %s", config.NtfyURL)
+	}
+
+	if config.NtfyTopic != "flux" || config.NtfyToken != "tk" {
+		t.Errorf("Unexpected ntfy settings: %s %s", config.NtfyTopic, config.NtfyToken)
+	}
+
+	if config.OutgoingWebhookURL != "https://hooks.example.com" || config.OutgoingWebhookToken != "wh" {
+		t.Errorf("Unexpected webhook settings: %s %s", config.OutgoingWebhookURL, config.OutgoingWebhookToken)
+	}
+}
+
+func TestLoadFromEnv_Forward(t *testing.T) {
+	env := map[string]string{
+		"FORWARD_URL":   "https://audit.example.com/events",
+		"FORWARD_TOKEN": "audit",
+	}
+
+	config, err := LoadFromEnv(func(key string) string { return env[key] })()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if config.ForwardURL != "https://audit.example.com/events" {
+		t.Errorf("ForwardURL: expected audit URL, got %s", config.ForwardURL)
+	}
+
+	if config.ForwardToken != "audit" {
+		t.Errorf("ForwardToken: expected audit, got %s", config.ForwardToken)
+	}
+
+	if config.ForwardSign || config.ForwardHMACSecret != "" {
+		t.Errorf("Expected unsigned forwards by default, got %v %q", config.ForwardSign, config.ForwardHMACSecret)
+	}
+}
+
+func TestValidateConfig_ForwardSign(t *testing.T) {
 	tests := []struct {
-		name      string
-		config    *Config
-		wantError bool
-		errorMsg  string
+		name     string
+		env      map[string]string
+		expected string
 	}{
-		{
-			name:      "nil config",
-			config:    nil,
-			wantError: true,
-			errorMsg:  "config is nil",
-		},
-		{
-			name:      "missing user key",
-			config:    &Config{PushoverAPIToken: "token"},
-			wantError: true,
-			errorMsg:  "PUSHOVER_USER_KEY is required",
-		},
-		{
-			name:      "missing API token",
-			config:    &Config{PushoverUserKey: "user"},
-			wantError: true,
-			errorMsg:  "PUSHOVER_API_TOKEN is required",
-		},
-		{
-			name: "valid config",
-			config: &Config{
-				PushoverUserKey:  "user",
-				PushoverAPIToken: "token",
-			},
-			wantError: false,
-		},
+		{"signing with a secret", map[string]string{"FORWARD_SIGN": "true", "FORWARD_HMAC_SECRET": "s3cret"}, ""},
+		{"signing without a secret", map[string]string{"FORWARD_SIGN": "true"}, "FORWARD_SIGN needs FORWARD_HMAC_SECRET"},
+		{"secret without signing", map[string]string{"FORWARD_HMAC_SECRET": "s3cret"}, ""},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := ValidateConfig(tt.config)
+			tt.env["FORWARD_URL"] = "https://audit.example.com/events"
+			tt.env["PUSHOVER_USER_KEY"] = "user"
+			tt.env["PUSHOVER_API_TOKEN"] = "token"
+			cfg, err := LoadFromEnv(func(key string) string { return tt.env[key] })()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
 
-			if tt.wantError {
-				if err == nil {
-					t.Error("Expected error but got nil")
-				} else if err.Error() != tt.errorMsg {
-					t.Errorf("Expected error '%s', got '%s'", tt.errorMsg, err.Error())
-				}
-			} else {
-				if err != nil {
-					t.Errorf("Unexpected error: %v", err)
+			err = ValidateConfig(cfg)
+			if tt.expected == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.expected != "" && (err == nil || err.Error() != tt.expected) {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+			if redacted := Redacted(cfg); cfg.ForwardHMACSecret != "" && redacted.ForwardHMACSecret == cfg.ForwardHMACSecret {
+				t.Error("Expected FORWARD_HMAC_SECRET to be redacted")
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_EmitK8sEvents(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    bool
+		expectError bool
+	}{
+		{"", false, false},
+		{"true", true, false},
+		{"false", false, false},
+		{"yes-please", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			loader := LoadFromEnv(func(key string) string {
+				if key == "EMIT_K8S_EVENTS" {
+					return tt.value
 				}
+				return ""
+			})
+
+			config, err := loader()
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+			}
+
+			if err == nil && config.EmitK8sEvents != tt.expected {
+				t.Errorf("EmitK8sEvents: expected %v, got %v", tt.expected, config.EmitK8sEvents)
 			}
 		})
 	}
 }
 
-func TestWithValidation(t *testing.T) {
+func TestLoadFromEnv_PreserveKindCase(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		if key == "PRESERVE_KIND_CASE" {
+			return "true"
+		}
+		return ""
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !config.PreserveKindCase {
+		t.Error("Expected PreserveKindCase to be true")
+	}
+
+	if config, _ := LoadFromEnv(func(string) string { return "" })(); config.PreserveKindCase {
+		t.Error("Expected PreserveKindCase to default to false")
+	}
+}
+
+func TestLoadFromEnv_ShowUID(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"SHOW_UID": "true"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.ShowUID {
+		t.Error("Expected ShowUID to be true")
+	}
+
+	if defaults := NewConfig(); defaults.ShowUID {
+		t.Error("Expected ShowUID to default to false")
+	}
+}
+
+func TestLoadFromEnv_RootOK(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		if key == "ROOT_OK" {
+			return "true"
+		}
+		return ""
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !config.RootOK {
+		t.Error("Expected RootOK to be true")
+	}
+
+	if config, _ := LoadFromEnv(func(string) string { return "" })(); config.RootOK {
+		t.Error("Expected RootOK to default to false")
+	}
+
+	if _, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"ROOT_OK": "sometimes"}[key]
+	})(); err == nil {
+		t.Error("Expected error for non-boolean ROOT_OK")
+	}
+}
+
+func TestLoadFromEnv_AccessLog(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"ACCESS_LOG": "true"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !config.AccessLog {
+		t.Error("Expected AccessLog to be true")
+	}
+
+	if config, _ := LoadFromEnv(func(string) string { return "" })(); config.AccessLog {
+		t.Error("Expected AccessLog to default to false")
+	}
+}
+
+func TestLoadFromEnv_Retry(t *testing.T) {
 	tests := []struct {
-		name      string
-		config    *Config
-		wantError bool
+		name             string
+		env              map[string]string
+		expectedAttempts int
+		expectedRatio    float64
+		expectedTimeout  bool
+		expectError      bool
 	}{
-		{
-			name: "valid config passes validation",
-			config: &Config{
-				PushoverUserKey:  "user",
-				PushoverAPIToken: "token",
-			},
-			wantError: false,
-		},
-		{
-			name: "invalid config fails validation",
-			config: &Config{
-				PushoverUserKey: "user",
-				// Missing API token
-			},
-			wantError: true,
-		},
+		{"defaults", map[string]string{}, 1, 0.1, false, false},
+		{"custom", map[string]string{"RETRY_MAX_ATTEMPTS": "4", "RETRY_BUDGET_RATIO": "0.25", "RETRY_ON_TIMEOUT": "true"}, 4, 0.25, true, false},
+		{"invalid attempts", map[string]string{"RETRY_MAX_ATTEMPTS": "many"}, 0, 0, false, true},
+		{"invalid ratio", map[string]string{"RETRY_BUDGET_RATIO": "lots"}, 0, 0, false, true},
+		{"invalid retry on timeout", map[string]string{"RETRY_ON_TIMEOUT": "maybe"}, 0, 0, false, true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockLoader := func() (*Config, error) {
-				return tt.config, nil
+			config, err := LoadFromEnv(func(key string) string { return tt.env[key] })()
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
 			}
 
-			validatedLoader := WithValidation(mockLoader, ValidateConfig)
-			config, err := validatedLoader()
+			if err != nil {
+				return
+			}
 
-			if tt.wantError {
-				if err == nil {
-					t.Error("Expected validation error")
-				}
-			} else {
-				if err != nil {
-					t.Errorf("Unexpected error: %v", err)
-				}
-				if config != tt.config {
-					t.Error("Config was modified during validation")
-				}
+			if config.RetryMaxAttempts != tt.expectedAttempts {
+				t.Errorf("RetryMaxAttempts: expected %d, got %d", tt.expectedAttempts, config.RetryMaxAttempts)
+			}
+
+			if config.RetryBudgetRatio != tt.expectedRatio {
+				t.Errorf("RetryBudgetRatio: expected %v, got %v", tt.expectedRatio, config.RetryBudgetRatio)
+			}
+
+			if config.RetryOnTimeout != tt.expectedTimeout {
+				t.Errorf("RetryOnTimeout: expected %v, got %v", tt.expectedTimeout, config.RetryOnTimeout)
 			}
 		})
 	}
 }
 
-func TestWithValidation_LoaderError(t *testing.T) {
-	expectedErr := fmt.Errorf("loader error")
+func TestLoadFromEnv_LeaderElection(t *testing.T) {
+	env := map[string]string{
+		"ENABLE_LEADER_ELECTION":     "true",
+		"LEADER_ELECTION_MODE":       "Proxy",
+		"LEADER_ELECTION_LEASE_NAME": "pushover-leader",
+	}
 
-	mockLoader := func() (*Config, error) {
-		return nil, expectedErr
+	config, err := LoadFromEnv(func(key string) string { return env[key] })()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
 	}
 
-	validatedLoader := WithValidation(mockLoader, ValidateConfig)
-	_, err := validatedLoader()
+	if !config.EnableLeaderElection || config.LeaderElectionMode != LeaderElectionModeProxy || config.LeaderElectionLease != "pushover-leader" {
+		t.Errorf("Unexpected leader election config: %v %q %q", config.EnableLeaderElection, config.LeaderElectionMode, config.LeaderElectionLease)
+	}
 
-	if err != expectedErr {
-		t.Errorf("Expected loader error to be returned, got %v", err)
+	defaults, err := LoadFromEnv(func(string) string { return "" })()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if defaults.EnableLeaderElection || defaults.LeaderElectionMode != LeaderElectionModeStandby || defaults.LeaderElectionLease != "flux-provider-pushover" {
+		t.Errorf("Unexpected defaults: %v %q %q", defaults.EnableLeaderElection, defaults.LeaderElectionMode, defaults.LeaderElectionLease)
+	}
+
+	if _, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"ENABLE_LEADER_ELECTION": "maybe"}[key]
+	})(); err == nil {
+		t.Error("Expected error for invalid ENABLE_LEADER_ELECTION")
 	}
 }
 
-func TestWithValidation_MultipleValidators(t *testing.T) {
-	config := &Config{
-		PushoverUserKey:  "user",
-		PushoverAPIToken: "token",
+func TestValidateConfig_LeaderElection(t *testing.T) {
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.LeaderElectionMode = "redirect"
+
+	if err := ValidateConfig(cfg); err == nil || err.Error() != `LEADER_ELECTION_MODE must be "standby" or "proxy"` {
+		t.Errorf("Expected mode error, got %v", err)
 	}
+}
 
-	validator1Called := false
-	validator2Called := false
+func TestLoadFromEnv_Dedup(t *testing.T) {
+	env := map[string]string{
+		"DEDUP_WINDOW":   "5m",
+		"REDIS_ADDR":     "redis:6379",
+		"REDIS_PASSWORD": "secret",
+	}
 
-	validator1 := func(c *Config) error {
-		validator1Called = true
-		return nil
+	config, err := LoadFromEnv(func(key string) string { return env[key] })()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
 	}
 
-	validator2 := func(c *Config) error {
-		validator2Called = true
-		return nil
+	if config.DedupWindow != 5*time.Minute || config.RedisAddr != "redis:6379" || config.RedisPassword != "secret" {
+		t.Errorf("Unexpected dedup config: %v %q %q", config.DedupWindow, config.RedisAddr, config.RedisPassword)
 	}
 
-	mockLoader := func() (*Config, error) {
-		return config, nil
+	if _, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"DEDUP_WINDOW": "300"}[key]
+	})(); err == nil {
+		t.Error("Expected error for DEDUP_WINDOW without unit")
 	}
 
-	validatedLoader := WithValidation(mockLoader, validator1, validator2)
-	_, err := validatedLoader()
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.DedupWindow = -time.Second
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "DEDUP_WINDOW must not be negative" {
+		t.Errorf("Expected negative window error, got %v", err)
+	}
+}
 
+func TestLoadFromEnv_Idempotency(t *testing.T) {
+	defaults, err := LoadFromEnv(func(string) string { return "" })()
 	if err != nil {
-		t.Errorf("Unexpected error: %v", err)
+		t.Fatalf("Unexpected error: %v", err)
 	}
 
-	if !validator1Called {
-		t.Error("Validator 1 was not called")
+	if defaults.IdempotencyTTL != 0 || defaults.IdempotencyMaxKeys != 10000 {
+		t.Errorf("Unexpected defaults: %v %d", defaults.IdempotencyTTL, defaults.IdempotencyMaxKeys)
 	}
 
-	if !validator2Called {
-		t.Error("Validator 2 was not called")
+	env := map[string]string{"IDEMPOTENCY_TTL": "10m", "IDEMPOTENCY_MAX_KEYS": "50"}
+	config, err := LoadFromEnv(func(key string) string { return env[key] })()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if config.IdempotencyTTL != 10*time.Minute || config.IdempotencyMaxKeys != 50 {
+		t.Errorf("Unexpected config: %v %d", config.IdempotencyTTL, config.IdempotencyMaxKeys)
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.IdempotencyMaxKeys = -1
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "IDEMPOTENCY_MAX_KEYS must not be negative" {
+		t.Errorf("Expected max keys error, got %v", err)
 	}
 }
 
-func TestDefaultConfigLoader(t *testing.T) {
-	// This test ensures DefaultConfigLoader is properly defined
-	// It will use actual environment variables
-	_, err := DefaultConfigLoader()
+func TestLoadFromEnv_EventFreshness(t *testing.T) {
+	defaults, err := LoadFromEnv(func(string) string { return "" })()
 	if err != nil {
-		// This is expected if env vars are not set
-		t.Logf("DefaultConfigLoader returned error as expected: %v", err)
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if defaults.MaxEventAge != 0 || defaults.EventClockSkew != 30*time.Second || defaults.RequireEventTimestamp {
+		t.Errorf("Unexpected defaults: %v %v %v", defaults.MaxEventAge, defaults.EventClockSkew, defaults.RequireEventTimestamp)
+	}
+
+	env := map[string]string{"MAX_EVENT_AGE": "5m", "EVENT_CLOCK_SKEW": "2s", "REQUIRE_EVENT_TIMESTAMP": "true"}
+	config, err := LoadFromEnv(func(key string) string { return env[key] })()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if config.MaxEventAge != 5*time.Minute || config.EventClockSkew != 2*time.Second || !config.RequireEventTimestamp {
+		t.Errorf("Unexpected config: %v %v %v", config.MaxEventAge, config.EventClockSkew, config.RequireEventTimestamp)
+	}
+
+	for key, expected := range map[string]string{
+		"MAX_EVENT_AGE":    "MAX_EVENT_AGE must not be negative",
+		"EVENT_CLOCK_SKEW": "EVENT_CLOCK_SKEW must not be negative",
+	} {
+		config, err := LoadFromEnv(func(k string) string {
+			if k == key {
+				return "-1s"
+			}
+			return ""
+		})()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		config.PushoverUserKey = "user"
+		config.PushoverAPIToken = "token"
+		if err := ValidateConfig(config); err == nil || err.Error() != expected {
+			t.Errorf("Expected %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestLoadFromEnv_ClusterName(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"CLUSTER_NAME": " prod-eu "}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if config.ClusterName != "prod-eu" {
+		t.Errorf("Expected trimmed cluster name, got %q", config.ClusterName)
+	}
+}
+
+func TestLoadFromEnv_MessagePrefix(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"MESSAGE_PREFIX": " [PROD] "}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if config.MessagePrefix != "[PROD]" {
+		t.Errorf("Expected trimmed message prefix, got %q", config.MessagePrefix)
+	}
+}
+
+func TestLoadFromEnv_RevisionFormat(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"REVISION_FORMAT": " Branch-Short "}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if config.RevisionFormat != RevisionFormatBranchShort {
+		t.Errorf("Expected %q, got %q", RevisionFormatBranchShort, config.RevisionFormat)
+	}
+
+	if NewConfig().RevisionFormat != RevisionFormatFull {
+		t.Errorf("Expected default %q, got %q", RevisionFormatFull, NewConfig().RevisionFormat)
+	}
+}
+
+func TestLoadFromEnv_ShortenRevision(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"SHORTEN_REVISION": "true"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.ShortenRevision || NewConfig().ShortenRevision {
+		t.Error("Expected SHORTEN_REVISION enabled, and disabled by default")
+	}
+
+	config.PushoverUserKey = "user"
+	config.PushoverAPIToken = "token"
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	config.RevisionFormat = RevisionFormatShort
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "SHORTEN_REVISION") {
+		t.Errorf("Expected SHORTEN_REVISION rejected with REVISION_FORMAT=short, got %v", err)
+	}
+}
+
+func TestLoadFromEnv_MetadataPrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{"default", "", "pushover."},
+		{"custom", " alerts.pushover/ ", "alerts.pushover/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := LoadFromEnv(func(key string) string {
+				return map[string]string{"PUSHOVER_METADATA_PREFIX": tt.value}[key]
+			})()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if config.MetadataPrefix != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, config.MetadataPrefix)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_SuccessStatus(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"SUCCESS_STATUS": "202"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.SuccessStatus != 202 {
+		t.Errorf("Expected 202, got %d", config.SuccessStatus)
+	}
+
+	if NewConfig().SuccessStatus != 200 {
+		t.Errorf("Expected default 200, got %d", NewConfig().SuccessStatus)
+	}
+
+	if _, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"SUCCESS_STATUS": "accepted"}[key]
+	})(); err == nil {
+		t.Error("Expected error for non-numeric SUCCESS_STATUS")
+	}
+}
+
+func TestValidateConfig_SuccessStatus(t *testing.T) {
+	tests := []struct {
+		status    int
+		wantError bool
+	}{
+		{200, false},
+		{202, false},
+		{204, true},
+		{500, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.status), func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = "user"
+			cfg.PushoverAPIToken = "token"
+			cfg.SuccessStatus = tt.status
+
+			err := ValidateConfig(cfg)
+			if (err != nil) != tt.wantError {
+				t.Errorf("ValidateConfig() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantError && err.Error() != "SUCCESS_STATUS must be 200 or 202" {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_PerNamespaceRate(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"PER_NAMESPACE_RATE": "2.5"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.PerNamespaceRate != 2.5 {
+		t.Errorf("Expected 2.5, got %v", config.PerNamespaceRate)
+	}
+
+	if _, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"PER_NAMESPACE_RATE": "fast"}[key]
+	})(); err == nil {
+		t.Error("Expected error for non-numeric PER_NAMESPACE_RATE")
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.PerNamespaceRate = -1
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "PER_NAMESPACE_RATE must not be negative" {
+		t.Errorf("Expected negative rate error, got %v", err)
+	}
+}
+
+func TestLoadFromEnv_Shed(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"SHED_MAX_CONCURRENT": "4", "SHED_HIGH_WATER": "50"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.ShedMaxConcurrent != 4 || config.ShedHighWater != 50 {
+		t.Errorf("Unexpected load shedding %d %d", config.ShedMaxConcurrent, config.ShedHighWater)
+	}
+	if defaults := NewConfig(); defaults.ShedMaxConcurrent != 0 || defaults.ShedHighWater != 100 {
+		t.Errorf("Expected load shedding off with a high-water mark of 100, got %d %d", defaults.ShedMaxConcurrent, defaults.ShedHighWater)
+	}
+
+	tests := []struct {
+		name          string
+		maxConcurrent int
+		highWater     int
+		expected      string
+	}{
+		{"disabled ignores the mark", 0, 0, ""},
+		{"enabled", 2, 1, ""},
+		{"negative concurrency", -1, 100, "SHED_MAX_CONCURRENT must not be negative"},
+		{"no high-water mark", 2, 0, "SHED_HIGH_WATER must be at least 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = "user"
+			cfg.PushoverAPIToken = "token"
+			cfg.ShedMaxConcurrent = tt.maxConcurrent
+			cfg.ShedHighWater = tt.highWater
+
+			err := ValidateConfig(cfg)
+			if tt.expected == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.expected != "" && (err == nil || err.Error() != tt.expected) {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_Coalesce(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{
+			"COALESCE_WINDOW":        "10s",
+			"COALESCE_BYPASS_ERRORS": "true",
+		}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.CoalesceWindow != 10*time.Second || !config.CoalesceBypassErrors {
+		t.Errorf("Unexpected coalesce settings: %v %v", config.CoalesceWindow, config.CoalesceBypassErrors)
+	}
+
+	if _, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"COALESCE_WINDOW": "soon"}[key]
+	})(); err == nil {
+		t.Error("Expected error for invalid COALESCE_WINDOW")
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.CoalesceWindow = -time.Second
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "COALESCE_WINDOW must not be negative" {
+		t.Errorf("Expected negative window error, got %v", err)
+	}
+}
+
+func TestLoadFromEnv_DispatchWorkers(t *testing.T) {
+	if defaults := NewConfig(); defaults.DispatchWorkers != 4 {
+		t.Errorf("Expected 4 dispatch workers by default, got %d", defaults.DispatchWorkers)
+	}
+
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"COALESCE_WINDOW": "10s", "DISPATCH_WORKERS": "8"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.DispatchWorkers != 8 {
+		t.Errorf("Expected 8 dispatch workers, got %d", config.DispatchWorkers)
+	}
+
+	if _, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"DISPATCH_WORKERS": "many"}[key]
+	})(); err == nil {
+		t.Error("Expected error for invalid DISPATCH_WORKERS")
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.CoalesceWindow = time.Second
+	cfg.DispatchWorkers = 0
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "DISPATCH_WORKERS must be at least 1" {
+		t.Errorf("Expected dispatch workers error, got %v", err)
+	}
+}
+
+func TestLoadFromEnv_Image(t *testing.T) {
+	defaults := NewConfig()
+	if defaults.ImageURLTemplate != "" || defaults.ImageMaxBytes != types.MaxAttachmentBytes || defaults.ImageFetchTimeout != 5*time.Second {
+		t.Errorf("Unexpected image defaults %q %d %s", defaults.ImageURLTemplate, defaults.ImageMaxBytes, defaults.ImageFetchTimeout)
+	}
+
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{
+			"IMAGE_URL_TEMPLATE":  " https://grafana.example.com/render/{{.Name}}.png ",
+			"IMAGE_MAX_BYTES":     "500000",
+			"IMAGE_FETCH_TIMEOUT": "2s",
+		}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.ImageURLTemplate != "https://grafana.example.com/render/{{.Name}}.png" || config.ImageMaxBytes != 500000 || config.ImageFetchTimeout != 2*time.Second {
+		t.Errorf("Unexpected image settings %q %d %s", config.ImageURLTemplate, config.ImageMaxBytes, config.ImageFetchTimeout)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{"no bytes", func(c *Config) { c.ImageMaxBytes = 0 }, fmt.Sprintf("IMAGE_MAX_BYTES must be between 1 and %d", types.MaxAttachmentBytes)},
+		{"over the Pushover limit", func(c *Config) { c.ImageMaxBytes = types.MaxAttachmentBytes + 1 }, fmt.Sprintf("IMAGE_MAX_BYTES must be between 1 and %d", types.MaxAttachmentBytes)},
+		{"no timeout", func(c *Config) { c.ImageFetchTimeout = 0 }, "IMAGE_FETCH_TIMEOUT must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = "user"
+			cfg.PushoverAPIToken = "token"
+			cfg.ImageURLTemplate = "https://grafana.example.com/render.png"
+			tt.modify(cfg)
+			if err := ValidateConfig(cfg); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_QueueUnhealthyDepth(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"QUEUE_UNHEALTHY_DEPTH": "500"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.QueueUnhealthyDepth != 500 {
+		t.Errorf("Expected a depth of 500, got %d", config.QueueUnhealthyDepth)
+	}
+	if NewConfig().QueueUnhealthyDepth != 0 {
+		t.Error("Expected the queue check to be off by default")
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.QueueUnhealthyDepth = -1
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "QUEUE_UNHEALTHY_DEPTH must not be negative" {
+		t.Errorf("Expected queue depth error, got %v", err)
+	}
+}
+
+func TestLoadFromEnv_SendFailureUnreadyWindow(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"SEND_FAILURE_UNREADY_WINDOW": "5m"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.SendFailureUnreadyWindow != 5*time.Minute {
+		t.Errorf("Expected a window of 5m, got %v", config.SendFailureUnreadyWindow)
+	}
+	if NewConfig().SendFailureUnreadyWindow != time.Minute {
+		t.Errorf("Expected a window of 1m by default, got %v", NewConfig().SendFailureUnreadyWindow)
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.SendFailureUnreadyWindow = -time.Second
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "SEND_FAILURE_UNREADY_WINDOW must not be negative" {
+		t.Errorf("Expected window error, got %v", err)
+	}
+}
+
+func TestLoadFromEnv_NotifyOnChangeOnly(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{
+			"NOTIFY_ON_CHANGE_ONLY": "true",
+			"CHANGE_DETECTION":      " Reason ",
+			"STATE_TTL":             "6h",
+		}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !config.NotifyOnChangeOnly || config.ChangeDetection != ChangeDetectionReason || config.StateTTL != 6*time.Hour {
+		t.Errorf("Unexpected change detection settings: %v %q %v", config.NotifyOnChangeOnly, config.ChangeDetection, config.StateTTL)
+	}
+
+	defaults := NewConfig()
+	if defaults.NotifyOnChangeOnly || defaults.ChangeDetection != ChangeDetectionMessage || defaults.StateTTL != 24*time.Hour {
+		t.Errorf("Unexpected defaults: %v %q %v", defaults.NotifyOnChangeOnly, defaults.ChangeDetection, defaults.StateTTL)
+	}
+}
+
+func TestValidateConfig_ChangeDetection(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *Config)
+		expected string
+	}{
+		{"unknown detection", func(cfg *Config) { cfg.ChangeDetection = "severity" }, `CHANGE_DETECTION must be "reason" or "message"`},
+		{"negative ttl", func(cfg *Config) { cfg.StateTTL = -time.Second }, "STATE_TTL must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = "user"
+			cfg.PushoverAPIToken = "token"
+			tt.modify(cfg)
+
+			if err := ValidateConfig(cfg); err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_RevisionFormat(t *testing.T) {
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.RevisionFormat = "tiny"
+
+	expected := `REVISION_FORMAT must be "full", "short" or "branch-short"`
+	if err := ValidateConfig(cfg); err == nil || err.Error() != expected {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+}
+
+func TestLoadFromEnv_Glances(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"GLANCES": " Alongside "}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Glances != GlancesAlongside {
+		t.Errorf("Expected %q, got %q", GlancesAlongside, config.Glances)
+	}
+
+	if defaults := NewConfig(); defaults.Glances != GlancesOff {
+		t.Errorf("Expected Glances off by default, got %q", defaults.Glances)
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.Glances = "sometimes"
+	expected := `GLANCES must be "off", "alongside" or "instead"`
+	if err := ValidateConfig(cfg); err == nil || err.Error() != expected {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+}
+
+func TestLoadFromEnv_PreShutdownDelay(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"PRESHUTDOWN_DELAY": "5s"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.PreShutdownDelay != 5*time.Second {
+		t.Errorf("Expected 5s, got %v", config.PreShutdownDelay)
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.PreShutdownDelay = -time.Second
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "PRESHUTDOWN_DELAY must not be negative" {
+		t.Errorf("Expected negative delay error, got %v", err)
+	}
+}
+
+func TestLoadFromEnv_ShutdownTimeout(t *testing.T) {
+	if got := NewConfig().ShutdownTimeout; got != 30*time.Second {
+		t.Errorf("Expected 30s by default, got %v", got)
+	}
+
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"SHUTDOWN_TIMEOUT": "45s"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.ShutdownTimeout != 45*time.Second {
+		t.Errorf("Expected 45s, got %v", config.ShutdownTimeout)
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.ShutdownTimeout = -time.Second
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "SHUTDOWN_TIMEOUT must not be negative" {
+		t.Errorf("Expected negative timeout error, got %v", err)
+	}
+}
+
+func TestLoadFromEnv_CredentialsRecheckInterval(t *testing.T) {
+	if got := NewConfig().CredentialsRecheckInterval; got != 5*time.Minute {
+		t.Errorf("Expected 5m by default, got %v", got)
+	}
+
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"CREDENTIALS_RECHECK_INTERVAL": "0"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.CredentialsRecheckInterval != 0 {
+		t.Errorf("Expected 0 to disable the recheck, got %v", config.CredentialsRecheckInterval)
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey, cfg.PushoverAPIToken = "user", "token"
+	cfg.CredentialsRecheckInterval = -time.Second
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "CREDENTIALS_RECHECK_INTERVAL must not be negative" {
+		t.Errorf("Expected negative interval error, got %v", err)
+	}
+}
+
+func TestLoadFromEnv_AuditLog(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{
+			"AUDIT_LOG_PATH":        " /var/log/audit.log ",
+			"AUDIT_LOG_MAX_SIZE_MB": "10",
+			"AUDIT_LOG_MAX_FILES":   "3",
+		}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.AuditLogPath != "/var/log/audit.log" || config.AuditLogMaxSizeMB != 10 || config.AuditLogMaxFiles != 3 {
+		t.Errorf("Unexpected audit log settings: %q %d %d", config.AuditLogPath, config.AuditLogMaxSizeMB, config.AuditLogMaxFiles)
+	}
+
+	defaults := NewConfig()
+	if defaults.AuditLogPath != "" || defaults.AuditLogMaxSizeMB != 100 || defaults.AuditLogMaxFiles != 5 {
+		t.Errorf("Unexpected defaults: %q %d %d", defaults.AuditLogPath, defaults.AuditLogMaxSizeMB, defaults.AuditLogMaxFiles)
+	}
+}
+
+func TestValidateConfig_AuditLog(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *Config)
+		expected string
+	}{
+		{"zero size", func(cfg *Config) { cfg.AuditLogPath = "/tmp/audit.log"; cfg.AuditLogMaxSizeMB = 0 }, "AUDIT_LOG_MAX_SIZE_MB must be positive"},
+		{"negative files", func(cfg *Config) { cfg.AuditLogMaxFiles = -1 }, "AUDIT_LOG_MAX_FILES must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = "user"
+			cfg.PushoverAPIToken = "token"
+			tt.modify(cfg)
+
+			if err := ValidateConfig(cfg); err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_TemplatesFile(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"MESSAGE_TEMPLATES_FILE": " /etc/templates.json\n", "MESSAGE_FALLBACK": "true"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if config.TemplatesFile != "/etc/templates.json" {
+		t.Errorf("Expected trimmed templates file, got %q", config.TemplatesFile)
+	}
+	if !config.MessageFallback {
+		t.Error("Expected the message fallback enabled")
+	}
+}
+
+func TestValidateConfig_Retry(t *testing.T) {
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+
+	cfg.RetryBudgetRatio = -1
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "RETRY_BUDGET_RATIO must not be negative" {
+		t.Errorf("Expected ratio error, got %v", err)
+	}
+
+	cfg.RetryBudgetRatio = 0.1
+	cfg.RetryMaxAttempts = -1
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "RETRY_MAX_ATTEMPTS must not be negative" {
+		t.Errorf("Expected attempts error, got %v", err)
+	}
+}
+
+func TestValidateConfig_Providers(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(*Config)
+		errorMsg string
+	}{
+		{
+			name:   "default providers",
+			modify: func(c *Config) {},
+		},
+		{
+			name:     "unknown provider",
+			modify:   func(c *Config) { c.Providers = []string{"sms"} },
+			errorMsg: `unknown provider "sms" in PROVIDERS`,
+		},
+		{
+			name:     "invalid mode",
+			modify:   func(c *Config) { c.ProvidersMode = "random" },
+			errorMsg: `PROVIDERS_MODE must be "fanout" or "failover"`,
+		},
+		{
+			name:     "ntfy without topic",
+			modify:   func(c *Config) { c.Providers = []string{ProviderNtfy} },
+			errorMsg: "NTFY_TOPIC is required when ntfy provider is enabled",
+		},
+		{
+			name:     "webhook without URL",
+			modify:   func(c *Config) { c.Providers = []string{ProviderWebhook} },
+			errorMsg: "OUTGOING_WEBHOOK_URL is required when webhook provider is enabled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = "user"
+			cfg.PushoverAPIToken = "token"
+			tt.modify(cfg)
+
+			err := ValidateConfig(cfg)
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != tt.errorMsg {
+				t.Errorf("Expected error '%s', got '%v'", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *Config
+		wantError bool
+		errorMsg  string
+	}{
+		{
+			name:      "nil config",
+			config:    nil,
+			wantError: true,
+			errorMsg:  "config is nil",
+		},
+		{
+			name:      "missing user key",
+			config:    &Config{PushoverAPIToken: "token"},
+			wantError: true,
+			errorMsg:  "PUSHOVER_USER_KEY is required",
+		},
+		{
+			name:      "missing API token",
+			config:    &Config{PushoverUserKey: "user"},
+			wantError: true,
+			errorMsg:  "PUSHOVER_API_TOKEN is required",
+		},
+		{
+			name: "valid config",
+			config: &Config{
+				PushoverUserKey:  "user",
+				PushoverAPIToken: "token",
+			},
+			wantError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(tt.config)
+
+			if tt.wantError {
+				if err == nil {
+					t.Error("Expected error but got nil")
+				} else if err.Error() != tt.errorMsg {
+					t.Errorf("Expected error '%s', got '%s'", tt.errorMsg, err.Error())
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestWithValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *Config
+		wantError bool
+	}{
+		{
+			name: "valid config passes validation",
+			config: &Config{
+				PushoverUserKey:  "user",
+				PushoverAPIToken: "token",
+			},
+			wantError: false,
+		},
+		{
+			name: "invalid config fails validation",
+			config: &Config{
+				PushoverUserKey: "user",
+				// Missing API token
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLoader := func() (*Config, error) {
+				return tt.config, nil
+			}
+
+			validatedLoader := WithValidation(mockLoader, ValidateConfig)
+			config, err := validatedLoader()
+
+			if tt.wantError {
+				if err == nil {
+					t.Error("Expected validation error")
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				if config != tt.config {
+					t.Error("Config was modified during validation")
+				}
+			}
+		})
+	}
+}
+
+func TestWithValidation_LoaderError(t *testing.T) {
+	expectedErr := fmt.Errorf("loader error")
+
+	mockLoader := func() (*Config, error) {
+		return nil, expectedErr
+	}
+
+	validatedLoader := WithValidation(mockLoader, ValidateConfig)
+	_, err := validatedLoader()
+
+	if err != expectedErr {
+		t.Errorf("Expected loader error to be returned, got %v", err)
+	}
+}
+
+func TestWithValidation_MultipleValidators(t *testing.T) {
+	config := &Config{
+		PushoverUserKey:  "user",
+		PushoverAPIToken: "token",
+	}
+
+	validator1Called := false
+	validator2Called := false
+
+	validator1 := func(c *Config) error {
+		validator1Called = true
+		return nil
+	}
+
+	validator2 := func(c *Config) error {
+		validator2Called = true
+		return nil
+	}
+
+	mockLoader := func() (*Config, error) {
+		return config, nil
+	}
+
+	validatedLoader := WithValidation(mockLoader, validator1, validator2)
+	_, err := validatedLoader()
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if !validator1Called {
+		t.Error("Validator 1 was not called")
+	}
+
+	if !validator2Called {
+		t.Error("Validator 2 was not called")
+	}
+}
+
+func TestDefaultConfigLoader(t *testing.T) {
+	// This test ensures DefaultConfigLoader is properly defined
+	// It will use actual environment variables
+	_, err := DefaultConfigLoader()
+	if err != nil {
+		// This is expected if env vars are not set
+		t.Logf("DefaultConfigLoader returned error as expected: %v", err)
+	}
+}
+
+func TestLoadFromEnv_TrimsCredentials(t *testing.T) {
+	tests := []struct {
+		name  string
+		user  string
+		token string
+	}{
+		{"spaces", "  user123  ", " token456 "},
+		{"tabs", "\tuser123", "token456\t"},
+		{"trailing newline", "user123\n", "token456\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{"PUSHOVER_USER_KEY": tt.user, "PUSHOVER_API_TOKEN": tt.token}
+			config, err := LoadFromEnv(func(key string) string { return env[key] })()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if config.PushoverUserKey != "user123" || config.PushoverAPIToken != "token456" {
+				t.Errorf("Expected trimmed credentials, got %q %q", config.PushoverUserKey, config.PushoverAPIToken)
+			}
+			if config.BearerToken != "Bearer token456" {
+				t.Errorf("Expected bearer token from trimmed token, got %q", config.BearerToken)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_WhitespaceCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		token    string
+		expected string
+	}{
+		{"space user key", " ", "token", "PUSHOVER_USER_KEY is required"},
+		{"tab user key", "\t", "token", "PUSHOVER_USER_KEY is required"},
+		{"newline api token", "user", "\n", "PUSHOVER_API_TOKEN is required"},
+		{"mixed whitespace api token", "user", " \t\r\n", "PUSHOVER_API_TOKEN is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = tt.user
+			cfg.PushoverAPIToken = tt.token
+
+			if err := ValidateConfig(cfg); err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	env := map[string]string{"PUSHOVER_USER_KEY": " ", "PUSHOVER_API_TOKEN": "token"}
+	config, err := LoadFromEnv(func(key string) string { return env[key] })()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := ValidateConfig(config); err == nil {
+		t.Error("Expected whitespace-only user key loaded from env to fail validation")
+	}
+}
+
+func TestLoadFromEnv_DebugAddr(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{"unset", "", ""},
+		{"host and port", "127.0.0.1:6060", "127.0.0.1:6060"},
+		{"bare port", "6060", ":6060"},
+		{"whitespace", " :6060 ", ":6060"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{"DEBUG_ADDR": tt.value}
+			config, err := LoadFromEnv(func(key string) string { return env[key] })()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if config.DebugAddr != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, config.DebugAddr)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_DebugAddr(t *testing.T) {
+	tests := []struct {
+		name      string
+		port      string
+		debugAddr string
+		expected  string
+	}{
+		{"disabled", ":8080", "", ""},
+		{"different port", ":8080", ":6060", ""},
+		{"loopback on another port", ":8080", "127.0.0.1:6060", ""},
+		{"same address", ":8080", ":8080", "DEBUG_ADDR must differ from the main listen address :8080"},
+		{"loopback on the main port", ":8080", "127.0.0.1:8080", "DEBUG_ADDR must differ from the main listen address :8080"},
+		{"all interfaces on the main port", ":8080", "0.0.0.0:8080", "DEBUG_ADDR must differ from the main listen address :8080"},
+		{"random ports", ":0", ":0", ""},
+		{"missing port", ":8080", "localhost", "DEBUG_ADDR must be host:port: address localhost: missing port in address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = "user"
+			cfg.PushoverAPIToken = "token"
+			cfg.Port = tt.port
+			cfg.DebugAddr = tt.debugAddr
+
+			err := ValidateConfig(cfg)
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.BearerToken = "Bearer token"
+	cfg.NtfyToken = "ntfy"
+	cfg.ForwardToken = "forward"
+	cfg.RedisPassword = "redis"
+	cfg.NtfyTopic = "flux"
+	cfg.SeverityCredentials = map[string]PushoverCredentials{types.SeverityError: {Token: "error-token"}}
+
+	redacted := Redacted(cfg)
+	for name, value := range map[string]string{
+		"PushoverUserKey":  redacted.PushoverUserKey,
+		"PushoverAPIToken": redacted.PushoverAPIToken,
+		"BearerToken":      redacted.BearerToken,
+		"NtfyToken":        redacted.NtfyToken,
+		"ForwardToken":     redacted.ForwardToken,
+		"RedisPassword":    redacted.RedisPassword,
+	} {
+		if value != "[REDACTED]" {
+			t.Errorf("%s: expected [REDACTED], got %q", name, value)
+		}
+	}
+
+	if got := redacted.SeverityCredentials[types.SeverityError]; got != (PushoverCredentials{Token: "[REDACTED]"}) {
+		t.Errorf("Expected the error token redacted and the unset user empty, got %+v", got)
+	}
+	if cfg.SeverityCredentials[types.SeverityError].Token != "error-token" {
+		t.Error("Expected the original severity credentials to be unchanged")
+	}
+
+	if redacted.OutgoingWebhookToken != "" {
+		t.Errorf("Expected unset secret to stay empty, got %q", redacted.OutgoingWebhookToken)
+	}
+	if redacted.NtfyTopic != "flux" || redacted.Port != cfg.Port {
+		t.Error("Expected non-secret fields to be kept")
+	}
+	if cfg.PushoverAPIToken != "token" {
+		t.Error("Expected the original config to be unchanged")
+	}
+	if Redacted(nil) != nil {
+		t.Error("Expected nil for nil config")
+	}
+}
+
+func TestLoadFromEnv_HTTPPool(t *testing.T) {
+	defaults, err := LoadFromEnv(func(string) string { return "" })()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if defaults.HTTPMaxIdleConns != 10 || defaults.HTTPMaxIdleConnsPerHost != 2 || defaults.HTTPIdleConnTimeout != 90*time.Second {
+		t.Errorf("Unexpected defaults: %d %d %v", defaults.HTTPMaxIdleConns, defaults.HTTPMaxIdleConnsPerHost, defaults.HTTPIdleConnTimeout)
+	}
+
+	env := map[string]string{
+		"PUSHOVER_MAX_IDLE_CONNS":          "64",
+		"PUSHOVER_MAX_IDLE_CONNS_PER_HOST": "16",
+		"HTTP_IDLE_CONN_TIMEOUT":           "30s",
+		"PUSHOVER_MAX_INFLIGHT":            "4",
+	}
+	config, err := LoadFromEnv(func(key string) string { return env[key] })()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if config.HTTPMaxIdleConns != 64 || config.HTTPMaxIdleConnsPerHost != 16 || config.HTTPIdleConnTimeout != 30*time.Second {
+		t.Errorf("Unexpected pool config: %d %d %v", config.HTTPMaxIdleConns, config.HTTPMaxIdleConnsPerHost, config.HTTPIdleConnTimeout)
+	}
+	if len(config.DeprecatedEnv) != 0 {
+		t.Errorf("Expected no deprecated variables, got %v", config.DeprecatedEnv)
+	}
+	if config.PushoverMaxInFlight != 4 || defaults.PushoverMaxInFlight != 0 {
+		t.Errorf("Expected 4 requests in flight, unlimited by default, got %d and %d", config.PushoverMaxInFlight, defaults.PushoverMaxInFlight)
+	}
+
+	if _, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"PUSHOVER_MAX_IDLE_CONNS": "many"}[key]
+	})(); err == nil {
+		t.Error("Expected error for non-numeric PUSHOVER_MAX_IDLE_CONNS")
+	}
+}
+
+func TestLoadFromEnv_DeprecatedHTTPPool(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{
+			"HTTP_MAX_IDLE_CONNS":          "64",
+			"HTTP_MAX_IDLE_CONNS_PER_HOST": "16",
+		}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.HTTPMaxIdleConns != 64 || config.HTTPMaxIdleConnsPerHost != 16 {
+		t.Errorf("Expected the deprecated names honoured, got %d %d", config.HTTPMaxIdleConns, config.HTTPMaxIdleConnsPerHost)
+	}
+	expected := map[string]string{
+		"HTTP_MAX_IDLE_CONNS":          "PUSHOVER_MAX_IDLE_CONNS",
+		"HTTP_MAX_IDLE_CONNS_PER_HOST": "PUSHOVER_MAX_IDLE_CONNS_PER_HOST",
+	}
+	if !reflect.DeepEqual(config.DeprecatedEnv, expected) {
+		t.Errorf("Expected %v, got %v", expected, config.DeprecatedEnv)
+	}
+
+	// The new name wins when both are set
+	config, err = LoadFromEnv(func(key string) string {
+		return map[string]string{"HTTP_MAX_IDLE_CONNS": "64", "PUSHOVER_MAX_IDLE_CONNS": "32"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.HTTPMaxIdleConns != 32 || len(config.DeprecatedEnv) != 0 {
+		t.Errorf("Expected PUSHOVER_MAX_IDLE_CONNS to win without a warning, got %d %v", config.HTTPMaxIdleConns, config.DeprecatedEnv)
+	}
+
+	if _, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"HTTP_MAX_IDLE_CONNS_PER_HOST": "many"}[key]
+	})(); err == nil || !strings.Contains(err.Error(), "HTTP_MAX_IDLE_CONNS_PER_HOST") {
+		t.Errorf("Expected error naming HTTP_MAX_IDLE_CONNS_PER_HOST, got %v", err)
+	}
+}
+
+func TestLoadFromEnv_HTTPProtocol(t *testing.T) {
+	defaults := NewConfig()
+	if defaults.HTTPTLSHandshakeTimeout != 10*time.Second || defaults.HTTPExpectContinueTimeout != time.Second || !defaults.HTTP2 || defaults.HTTPCompression {
+		t.Errorf("Unexpected defaults: %v %v %v %v", defaults.HTTPTLSHandshakeTimeout, defaults.HTTPExpectContinueTimeout, defaults.HTTP2, defaults.HTTPCompression)
+	}
+
+	env := map[string]string{
+		"HTTP_TLS_HANDSHAKE_TIMEOUT":   "3s",
+		"HTTP_EXPECT_CONTINUE_TIMEOUT": "500ms",
+		"PUSHOVER_HTTP2":               "false",
+		"HTTP_COMPRESSION":             "true",
+	}
+	config, err := LoadFromEnv(func(key string) string { return env[key] })()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.HTTPTLSHandshakeTimeout != 3*time.Second || config.HTTPExpectContinueTimeout != 500*time.Millisecond || config.HTTP2 || !config.HTTPCompression {
+		t.Errorf("Unexpected protocol config: %v %v %v %v", config.HTTPTLSHandshakeTimeout, config.HTTPExpectContinueTimeout, config.HTTP2, config.HTTPCompression)
+	}
+
+	if _, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"PUSHOVER_HTTP2": "sometimes"}[key]
+	})(); err == nil {
+		t.Error("Expected error for non-boolean PUSHOVER_HTTP2")
+	}
+}
+
+func TestValidateConfig_HTTPPool(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *Config)
+		expected string
+	}{
+		{"negative max idle", func(cfg *Config) { cfg.HTTPMaxIdleConns = -1 }, "PUSHOVER_MAX_IDLE_CONNS must not be negative"},
+		{"negative per host", func(cfg *Config) { cfg.HTTPMaxIdleConnsPerHost = -1 }, "PUSHOVER_MAX_IDLE_CONNS_PER_HOST must not be negative"},
+		{"negative in flight", func(cfg *Config) { cfg.PushoverMaxInFlight = -1 }, "PUSHOVER_MAX_INFLIGHT must not be negative"},
+		{"negative timeout", func(cfg *Config) { cfg.HTTPIdleConnTimeout = -time.Second }, "HTTP_IDLE_CONN_TIMEOUT must not be negative"},
+		{"negative TLS handshake timeout", func(cfg *Config) { cfg.HTTPTLSHandshakeTimeout = -time.Second }, "HTTP_TLS_HANDSHAKE_TIMEOUT must not be negative"},
+		{"negative expect continue timeout", func(cfg *Config) { cfg.HTTPExpectContinueTimeout = -time.Second }, "HTTP_EXPECT_CONTINUE_TIMEOUT must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = "user"
+			cfg.PushoverAPIToken = "token"
+			tt.modify(cfg)
+
+			if err := ValidateConfig(cfg); err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_JSONLimits(t *testing.T) {
+	defaults, err := LoadFromEnv(func(string) string { return "" })()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if defaults.MaxJSONDepth != 32 || defaults.MaxJSONTokens != 10000 {
+		t.Errorf("Unexpected defaults: %d %d", defaults.MaxJSONDepth, defaults.MaxJSONTokens)
+	}
+
+	env := map[string]string{
+		"MAX_JSON_DEPTH":  "8",
+		"MAX_JSON_TOKENS": "0",
+	}
+	config, err := LoadFromEnv(func(key string) string { return env[key] })()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if config.MaxJSONDepth != 8 || config.MaxJSONTokens != 0 {
+		t.Errorf("Unexpected limits: %d %d", config.MaxJSONDepth, config.MaxJSONTokens)
+	}
+
+	if _, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"MAX_JSON_DEPTH": "deep"}[key]
+	})(); err == nil {
+		t.Error("Expected error for non-numeric MAX_JSON_DEPTH")
+	}
+}
+
+func TestValidateConfig_JSONLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *Config)
+		expected string
+	}{
+		{"negative depth", func(cfg *Config) { cfg.MaxJSONDepth = -1 }, "MAX_JSON_DEPTH must not be negative"},
+		{"negative tokens", func(cfg *Config) { cfg.MaxJSONTokens = -1 }, "MAX_JSON_TOKENS must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = "user"
+			cfg.PushoverAPIToken = "token"
+			tt.modify(cfg)
+
+			if err := ValidateConfig(cfg); err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_PublicURL(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"PUBLIC_URL": " https://flux.example.com/ "}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.PublicURL != "https://flux.example.com" {
+		t.Errorf("Expected the trailing slash to be trimmed, got %q", config.PublicURL)
+	}
+
+	tests := []struct {
+		url         string
+		expectedErr string
+	}{
+		{"https://flux.example.com/pushover", ""},
+		{"flux.example.com", "PUBLIC_URL must be an absolute http or https URL"},
+		{"ftp://flux.example.com", "PUBLIC_URL must be an absolute http or https URL"},
+		{"https://flux.example.com?x=1", "PUBLIC_URL must not have a query or fragment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = "user"
+			cfg.PushoverAPIToken = "token"
+			cfg.PublicURL = tt.url
+			err := ValidateConfig(cfg)
+			if tt.expectedErr == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.expectedErr != "" && (err == nil || err.Error() != tt.expectedErr) {
+				t.Errorf("Expected %q, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_WebhookAuth(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{
+			"WEBHOOK_TOKENS":         " Old-Token , ,new-token",
+			"WEBHOOK_BASIC_USER":     " flux ",
+			"WEBHOOK_BASIC_PASSWORD": "secret",
+		}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(config.WebhookTokens, []string{"Old-Token", "new-token"}) {
+		t.Errorf("Expected both tokens with their case, got %v", config.WebhookTokens)
+	}
+	if config.WebhookBasicUser != "flux" || config.WebhookBasicPassword != "secret" {
+		t.Errorf("Unexpected basic credentials %q/%q", config.WebhookBasicUser, config.WebhookBasicPassword)
+	}
+
+	redacted := Redacted(config)
+	if !reflect.DeepEqual(redacted.WebhookTokens, []string{"[REDACTED]", "[REDACTED]"}) || redacted.WebhookBasicPassword != "[REDACTED]" {
+		t.Errorf("Expected webhook credentials to be redacted, got %v %q", redacted.WebhookTokens, redacted.WebhookBasicPassword)
+	}
+	if config.WebhookTokens[0] != "Old-Token" {
+		t.Error("Expected Redacted to leave the original config untouched")
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.WebhookBasicUser = "flux"
+	expected := "WEBHOOK_BASIC_USER and WEBHOOK_BASIC_PASSWORD must be set together"
+	if err := ValidateConfig(cfg); err == nil || err.Error() != expected {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+}
+
+func TestLoadFromEnv_Validation(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{
+			"VALIDATION_MODE":  " Strict ",
+			"EXTRA_SEVERITIES": "Critical, ,debug",
+		}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.ValidationMode != ValidationModeStrict {
+		t.Errorf("Expected strict validation, got %q", config.ValidationMode)
+	}
+	if !reflect.DeepEqual(config.ExtraSeverities, []string{"critical", "debug"}) {
+		t.Errorf("Expected the lower-cased extra severities, got %v", config.ExtraSeverities)
+	}
+
+	if NewConfig().ValidationMode != ValidationModeLenient {
+		t.Error("Expected lenient validation by default")
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.ValidationMode = "loose"
+	expected := `VALIDATION_MODE must be "strict" or "lenient"`
+	if err := ValidateConfig(cfg); err == nil || err.Error() != expected {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+}
+
+func TestLoadFromEnv_WebhookTokenClusters(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{
+			"WEBHOOK_TOKENS": "prod-token;cluster=prod, edge-token ; Allow_Cluster_Header ; cluster = edge ,plain-token",
+		}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(config.WebhookTokens, []string{"prod-token", "edge-token", "plain-token"}) {
+		t.Errorf("Expected the tokens without their options, got %v", config.WebhookTokens)
+	}
+	expected := []WebhookTokenCluster{{Name: "prod"}, {Name: "edge", AllowHeader: true}, {}}
+	if !reflect.DeepEqual(config.WebhookTokenClusters, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, config.WebhookTokenClusters)
+	}
+
+	for env, expectedErr := range map[string]string{
+		"token;region=eu":     `WEBHOOK_TOKENS entry 1: unknown option "region"`,
+		"token,;cluster=prod": "WEBHOOK_TOKENS entry 2: token is empty",
+	} {
+		_, err := LoadFromEnv(func(key string) string {
+			return map[string]string{"WEBHOOK_TOKENS": env}[key]
+		})()
+		if err == nil || err.Error() != expectedErr {
+			t.Errorf("%s: expected %q, got %v", env, expectedErr, err)
+		}
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.WebhookTokens = []string{"prod-token"}
+	cfg.WebhookTokenClusters = []WebhookTokenCluster{{Name: "prod cluster"}}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "WEBHOOK_TOKENS entry 1: cluster must be") {
+		t.Errorf("Expected the invalid cluster rejected, got %v", err)
+	}
+}
+
+func TestValidClusterName(t *testing.T) {
+	for name, expected := range map[string]bool{
+		"prod-eu.1_a":           true,
+		"":                      false,
+		"prod eu":               false,
+		"prod\n":                false,
+		strings.Repeat("a", 63): true,
+		strings.Repeat("a", 64): false,
+	} {
+		if got := ValidClusterName(name); got != expected {
+			t.Errorf("%q: expected %v, got %v", name, expected, got)
+		}
+	}
+}
+
+func TestLoadFromEnv_WebhookToken(t *testing.T) {
+	tests := []struct {
+		name            string
+		env             map[string]string
+		expectedBearer  string
+		acceptsAPIToken bool
+	}{
+		{
+			name:           "dedicated webhook token",
+			env:            map[string]string{"PUSHOVER_API_TOKEN": "api-token", "WEBHOOK_TOKEN": " webhook-token "},
+			expectedBearer: "Bearer webhook-token",
+		},
+		{
+			name:            "legacy API token",
+			env:             map[string]string{"PUSHOVER_API_TOKEN": "api-token"},
+			expectedBearer:  "Bearer api-token",
+			acceptsAPIToken: true,
+		},
+		{
+			name:           "replaced by webhook tokens",
+			env:            map[string]string{"PUSHOVER_API_TOKEN": "api-token", "WEBHOOK_TOKENS": "new-token"},
+			expectedBearer: "Bearer api-token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := LoadFromEnv(func(key string) string { return tt.env[key] })()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if config.BearerToken != tt.expectedBearer {
+				t.Errorf("Expected %q, got %q", tt.expectedBearer, config.BearerToken)
+			}
+			if config.AcceptsAPIToken() != tt.acceptsAPIToken {
+				t.Errorf("Expected AcceptsAPIToken %v", tt.acceptsAPIToken)
+			}
+			if redacted := Redacted(config); redacted.WebhookToken != "" && redacted.WebhookToken != "[REDACTED]" {
+				t.Errorf("Expected the webhook token redacted, got %q", redacted.WebhookToken)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_ForwardedHTTPS(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{
+			"TRUSTED_PROXIES":         "10.1.2.3/8, 192.168.1.1 ,fd00::/8",
+			"REQUIRE_FORWARDED_HTTPS": "true",
+		}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.1.1/32"),
+		netip.MustParsePrefix("fd00::/8"),
+	}
+	if !reflect.DeepEqual(config.TrustedProxies, expected) {
+		t.Errorf("Expected %v, got %v", expected, config.TrustedProxies)
+	}
+	if !config.RequireForwardedHTTPS {
+		t.Error("Expected RequireForwardedHTTPS to be enabled")
+	}
+
+	if defaults := NewConfig(); defaults.RequireForwardedHTTPS || defaults.TrustedProxies != nil {
+		t.Error("Expected forwarded HTTPS checks to be disabled by default")
+	}
+
+	_, err = LoadFromEnv(func(key string) string {
+		return map[string]string{"TRUSTED_PROXIES": "10.0.0.0/33"}[key]
+	})()
+	if err == nil || !strings.Contains(err.Error(), `invalid TRUSTED_PROXIES entry "10.0.0.0/33"`) {
+		t.Errorf("Expected invalid CIDR error, got %v", err)
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.RequireForwardedHTTPS = true
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "REQUIRE_FORWARDED_HTTPS needs TRUSTED_PROXIES" {
+		t.Errorf("Expected missing proxies error, got %v", err)
+	}
+}
+
+func TestLoadFromEnv_MaxTitleLength(t *testing.T) {
+	if defaults := NewConfig(); defaults.MaxTitleLength != 250 {
+		t.Errorf("Expected the Pushover limit by default, got %d", defaults.MaxTitleLength)
+	}
+
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"MAX_TITLE_LENGTH": "60"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.MaxTitleLength != 60 {
+		t.Errorf("Expected 60, got %d", config.MaxTitleLength)
+	}
+
+	for _, length := range []int{-1, 251} {
+		cfg := NewConfig()
+		cfg.PushoverUserKey = "user"
+		cfg.PushoverAPIToken = "token"
+		cfg.MaxTitleLength = length
+		if err := ValidateConfig(cfg); err == nil || err.Error() != "MAX_TITLE_LENGTH must be between 0 and 250" {
+			t.Errorf("Expected range error for %d, got %v", length, err)
+		}
+	}
+}
+
+func TestLoadFromEnv_SeverityTitles(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"PUSHOVER_TITLE_ERROR": " FluxCD ‼️ ", "PUSHOVER_TITLE_INFO": "FluxCD"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		severity string
+		expected string
+	}{
+		{"error", "FluxCD ‼️"},
+		{"ERROR", "FluxCD ‼️"},
+		{"info", "FluxCD"},
+		{"warning", types.AppTitle},
+	}
+	for _, tt := range tests {
+		if got := config.Title(tt.severity); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.severity, tt.expected, got)
+		}
+	}
+
+	if got := NewConfig().Title("error"); got != types.AppTitle {
+		t.Errorf("Expected the app title by default, got %q", got)
+	}
+}
+
+func TestLoadFromEnv_MaxMessageLines(t *testing.T) {
+	if NewConfig().MaxMessageLines != 0 {
+		t.Error("Expected every metadata line rendered by default")
+	}
+
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"MAX_MESSAGE_LINES": "3"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.MaxMessageLines != 3 {
+		t.Errorf("Expected 3, got %d", config.MaxMessageLines)
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.MaxMessageLines = -1
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "MAX_MESSAGE_LINES must not be negative" {
+		t.Errorf("Expected a negative line cap rejected, got %v", err)
+	}
+}
+
+func TestLoadFromEnv_DebugLogInvalidPayloads(t *testing.T) {
+	defaults := NewConfig()
+	if defaults.DebugLogInvalidPayloads || defaults.DebugPayloadLogBytes != 4096 {
+		t.Errorf("Expected payload logging off with a 4KB limit by default, got %v %d", defaults.DebugLogInvalidPayloads, defaults.DebugPayloadLogBytes)
+	}
+
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"DEBUG_LOG_INVALID_PAYLOADS": "true", "DEBUG_PAYLOAD_LOG_BYTES": "512"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.DebugLogInvalidPayloads || config.DebugPayloadLogBytes != 512 {
+		t.Errorf("Expected payload logging with 512 bytes, got %v %d", config.DebugLogInvalidPayloads, config.DebugPayloadLogBytes)
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.DebugLogInvalidPayloads = true
+	cfg.DebugPayloadLogBytes = 0
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "DEBUG_PAYLOAD_LOG_BYTES must be positive" {
+		t.Errorf("Expected limit error, got %v", err)
+	}
+}
+
+func TestLoadFromEnv_LogSample(t *testing.T) {
+	defaults := NewConfig()
+	if defaults.LogSampleWindow != time.Minute || defaults.LogSampleBurst != 10 {
+		t.Errorf("Expected 1m and 10 by default, got %v and %d", defaults.LogSampleWindow, defaults.LogSampleBurst)
+	}
+
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"LOG_SAMPLE_WINDOW": "30s", "LOG_SAMPLE_BURST": "3"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.LogSampleWindow != 30*time.Second || config.LogSampleBurst != 3 {
+		t.Errorf("Expected 30s and 3, got %v and %d", config.LogSampleWindow, config.LogSampleBurst)
+	}
+
+	tests := []struct {
+		window      time.Duration
+		burst       int
+		expectedErr string
+	}{
+		{-time.Second, 10, "LOG_SAMPLE_WINDOW must not be negative"},
+		{time.Minute, 0, "LOG_SAMPLE_BURST must be positive"},
+		{0, 0, ""},
+	}
+
+	for _, tt := range tests {
+		cfg := NewConfig()
+		cfg.PushoverUserKey = "user"
+		cfg.PushoverAPIToken = "token"
+		cfg.LogSampleWindow = tt.window
+		cfg.LogSampleBurst = tt.burst
+		err := ValidateConfig(cfg)
+		if tt.expectedErr == "" && err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if tt.expectedErr != "" && (err == nil || err.Error() != tt.expectedErr) {
+			t.Errorf("Expected %q, got %v", tt.expectedErr, err)
+		}
+	}
+}
+
+func TestLoadFromEnv_Slack(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"PROVIDERS": "pushover,slack", "SLACK_WEBHOOK_URL": " https://hooks.slack.com/services/T/B/X "}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.SlackWebhookURL != "https://hooks.slack.com/services/T/B/X" {
+		t.Errorf("Unexpected SLACK_WEBHOOK_URL %q", config.SlackWebhookURL)
+	}
+	if Redacted(config).SlackWebhookURL != "[REDACTED]" {
+		t.Error("Expected the Slack webhook URL, which embeds its secret, to be redacted")
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.Providers = []string{ProviderSlack}
+	expected := "SLACK_WEBHOOK_URL is required when slack provider is enabled"
+	if err := ValidateConfig(cfg); err == nil || err.Error() != expected {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+}
+
+func TestLoadFromEnv_QuietHours(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{
+			"QUIET_HOURS":          " 22:00-07:00 ",
+			"QUIET_HOURS_TIMEZONE": "Europe/Budapest",
+			"QUIET_HOURS_MODE":     "Suppress",
+			"QUIET_TTL":            "8h",
+		}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.QuietHours != "22:00-07:00" || config.QuietHoursTimezone != "Europe/Budapest" || config.QuietHoursMode != QuietHoursSuppress {
+		t.Errorf("Unexpected quiet hours %q %q %q", config.QuietHours, config.QuietHoursTimezone, config.QuietHoursMode)
+	}
+	if config.QuietTTL != 8*time.Hour {
+		t.Errorf("Expected QUIET_TTL 8h, got %v", config.QuietTTL)
+	}
+
+	defaults := NewConfig()
+	if defaults.QuietHours != "" || defaults.QuietHoursTimezone != "UTC" || defaults.QuietHoursMode != QuietHoursSilent {
+		t.Errorf("Unexpected quiet hours defaults %q %q %q", defaults.QuietHours, defaults.QuietHoursTimezone, defaults.QuietHoursMode)
+	}
+	if defaults.QuietTTL != 0 {
+		t.Errorf("Expected no QUIET_TTL by default, got %v", defaults.QuietTTL)
+	}
+}
+
+func TestParseQuietHours(t *testing.T) {
+	tests := []struct {
+		window        string
+		expectedStart time.Duration
+		expectedEnd   time.Duration
+		expectError   bool
+	}{
+		{"22:00-07:00", 22 * time.Hour, 7 * time.Hour, false},
+		{"01:30-05:45", time.Hour + 30*time.Minute, 5*time.Hour + 45*time.Minute, false},
+		{"23:00 - 00:00", 23 * time.Hour, 0, false},
+		{"22:00", 0, 0, true},
+		{"22:00-24:00", 0, 0, true},
+		{"10pm-7am", 0, 0, true},
+		{"07:00-07:00", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window, func(t *testing.T) {
+			start, end, err := ParseQuietHours(tt.window)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got %s-%s", start, end)
+				}
+				return
+			}
+			if err != nil || start != tt.expectedStart || end != tt.expectedEnd {
+				t.Errorf("Expected %s-%s, got %s-%s, %v", tt.expectedStart, tt.expectedEnd, start, end, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_QuietHours(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(*Config)
+		expected string
+	}{
+		{"valid", func(c *Config) { c.QuietHours = "22:00-07:00" }, ""},
+		{"invalid window", func(c *Config) { c.QuietHours = "22-7" }, `invalid QUIET_HOURS: "22" is not a HH:MM time`},
+		{"invalid timezone", func(c *Config) { c.QuietHours = "22:00-07:00"; c.QuietHoursTimezone = "Mars/Olympus" }, "invalid QUIET_HOURS_TIMEZONE"},
+		{"invalid mode", func(c *Config) { c.QuietHoursMode = "mute" }, `QUIET_HOURS_MODE must be "silent" or "suppress"`},
+		{"ttl", func(c *Config) { c.QuietHours = "22:00-07:00"; c.QuietTTL = 8 * time.Hour }, ""},
+		{"ttl without quiet hours", func(c *Config) { c.QuietTTL = 8 * time.Hour }, "QUIET_TTL requires QUIET_HOURS"},
+		{"sub-second ttl", func(c *Config) { c.QuietHours = "22:00-07:00"; c.QuietTTL = time.Millisecond }, "QUIET_TTL must be 0 or at least 1s"},
+		{"negative ttl", func(c *Config) { c.QuietHours = "22:00-07:00"; c.QuietTTL = -time.Hour }, "QUIET_TTL must be 0 or at least 1s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = "user"
+			cfg.PushoverAPIToken = "token"
+			tt.setup(cfg)
+
+			err := ValidateConfig(cfg)
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_Maintenance(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(*Config)
+		expected string
+	}{
+		{"valid", func(c *Config) { c.MaintenanceWindows = "0 2 * * 6 4h namespace=apps; @daily 30m" }, ""},
+		{"downgrade", func(c *Config) { c.MaintenanceWindows = "@daily 30m"; c.MaintenanceMode = MaintenanceDowngrade }, ""},
+		{"invalid window", func(c *Config) { c.MaintenanceWindows = "0 2 * * 6" }, "invalid MAINTENANCE_WINDOWS: invalid window"},
+		{"invalid schedule", func(c *Config) { c.MaintenanceWindows = "0 2 * * 8 1h" }, "invalid MAINTENANCE_WINDOWS: invalid window"},
+		{"invalid timezone", func(c *Config) { c.MaintenanceWindows = "@daily 30m"; c.MaintenanceTimezone = "Mars/Olympus" }, "invalid MAINTENANCE_TIMEZONE"},
+		{"invalid mode", func(c *Config) { c.MaintenanceMode = "mute" }, `MAINTENANCE_MODE must be "suppress" or "downgrade"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = "user"
+			cfg.PushoverAPIToken = "token"
+			tt.setup(cfg)
+
+			err := ValidateConfig(cfg)
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_Maintenance(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{
+			"MAINTENANCE_WINDOWS":  " 0 2 * * 6 4h namespace=apps ",
+			"MAINTENANCE_TIMEZONE": "Europe/Budapest",
+			"MAINTENANCE_MODE":     "Downgrade",
+		}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.MaintenanceWindows != "0 2 * * 6 4h namespace=apps" || config.MaintenanceTimezone != "Europe/Budapest" || config.MaintenanceMode != MaintenanceDowngrade {
+		t.Errorf("Unexpected maintenance settings %q %q %q", config.MaintenanceWindows, config.MaintenanceTimezone, config.MaintenanceMode)
+	}
+	if cfg := NewConfig(); cfg.MaintenanceTimezone != "UTC" || cfg.MaintenanceMode != MaintenanceSuppress {
+		t.Errorf("Unexpected defaults %q %q", cfg.MaintenanceTimezone, cfg.MaintenanceMode)
+	}
+}
+
+func TestLoadFromEnv_PanicNotify(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"PANIC_NOTIFY": "true", "PANIC_NOTIFY_COOLDOWN": "1h"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.PanicNotify || config.PanicNotifyCooldown != time.Hour {
+		t.Errorf("Expected panic notifications every hour, got %v %s", config.PanicNotify, config.PanicNotifyCooldown)
+	}
+
+	if NewConfig().PanicNotifyCooldown != 15*time.Minute {
+		t.Errorf("Expected a 15m default cool-down, got %s", NewConfig().PanicNotifyCooldown)
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.PanicNotifyCooldown = -time.Second
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "PANIC_NOTIFY_COOLDOWN must not be negative" {
+		t.Errorf("Expected cool-down validation error, got %v", err)
+	}
+}
+
+func TestLoadFromEnv_Watchdog(t *testing.T) {
+	defaults := NewConfig()
+	if defaults.WatchdogFailureStreak != 25 || defaults.WatchdogSaturation != 0 || defaults.WatchdogFlaps != 0 || defaults.WatchdogFlapWindow != 10*time.Minute {
+		t.Errorf("Unexpected watchdog defaults %d %s %d %s", defaults.WatchdogFailureStreak, defaults.WatchdogSaturation, defaults.WatchdogFlaps, defaults.WatchdogFlapWindow)
+	}
+
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{
+			"WATCHDOG_FAILURE_STREAK":  "0",
+			"WATCHDOG_SATURATION":      "2m",
+			"WATCHDOG_READINESS_FLAPS": "6",
+			"WATCHDOG_FLAP_WINDOW":     "5m",
+		}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.WatchdogFailureStreak != 0 || config.WatchdogSaturation != 2*time.Minute || config.WatchdogFlaps != 6 || config.WatchdogFlapWindow != 5*time.Minute {
+		t.Errorf("Unexpected watchdog settings %d %s %d %s", config.WatchdogFailureStreak, config.WatchdogSaturation, config.WatchdogFlaps, config.WatchdogFlapWindow)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{"negative streak", func(c *Config) { c.WatchdogFailureStreak = -1 }, "WATCHDOG_FAILURE_STREAK must not be negative"},
+		{"negative saturation", func(c *Config) { c.WatchdogSaturation = -time.Second }, "WATCHDOG_SATURATION must not be negative"},
+		{"negative flaps", func(c *Config) { c.WatchdogFlaps = -1 }, "WATCHDOG_READINESS_FLAPS must not be negative"},
+		{"flaps without window", func(c *Config) { c.WatchdogFlaps = 3; c.WatchdogFlapWindow = 0 }, "WATCHDOG_FLAP_WINDOW must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = "user"
+			cfg.PushoverAPIToken = "token"
+			tt.modify(cfg)
+			if err := ValidateConfig(cfg); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_ValidateCredentials(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"VALIDATE_CREDENTIALS": "true"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.ValidateCredentials {
+		t.Error("Expected VALIDATE_CREDENTIALS to be enabled")
+	}
+	if NewConfig().ValidateCredentials {
+		t.Error("Expected credential validation to be off by default")
+	}
+}
+
+func TestLoadFromEnv_StrictAlerts(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"STRICT_ALERTS": "true"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.StrictAlerts {
+		t.Error("Expected STRICT_ALERTS to be enabled")
+	}
+	if NewConfig().StrictAlerts {
+		t.Error("Expected lenient validation by default")
+	}
+}
+
+func TestLoadFromEnv_Paths(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{
+			"BASE_PATH":             " /hooks/pushover/ ",
+			"WEBHOOK_PATH":          "/flux/",
+			"HEALTH_AT_ROOT":        "true",
+			"REDIRECT_LEGACY_PATHS": "true",
+		}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.BasePath != "/hooks/pushover" || config.WebhookPath != "/flux" || !config.HealthAtRoot || !config.RedirectLegacyPaths {
+		t.Errorf("Unexpected paths %q %q %v %v", config.BasePath, config.WebhookPath, config.HealthAtRoot, config.RedirectLegacyPaths)
+	}
+
+	defaults := NewConfig()
+	if defaults.BasePath != "" || defaults.WebhookPath != "/webhook" || defaults.HealthAtRoot || defaults.RedirectLegacyPaths {
+		t.Errorf("Unexpected path defaults %q %q %v %v", defaults.BasePath, defaults.WebhookPath, defaults.HealthAtRoot, defaults.RedirectLegacyPaths)
+	}
+
+	if config, _ := LoadFromEnv(func(key string) string {
+		return map[string]string{"BASE_PATH": "/"}[key]
+	})(); config.BasePath != "" {
+		t.Errorf("Expected BASE_PATH / to serve at the root, got %q", config.BasePath)
+	}
+}
+
+func TestValidateConfig_Paths(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(*Config)
+		expected string
+	}{
+		{"valid", func(c *Config) { c.BasePath = "/hooks/pushover"; c.WebhookPath = "/flux-alerts" }, ""},
+		{"code default webhook", func(c *Config) { c.WebhookPath = "" }, ""},
+		{"relative base", func(c *Config) { c.BasePath = "hooks" }, "BASE_PATH must start with /"},
+		{"unclean base", func(c *Config) { c.BasePath = "/hooks/../admin" }, "BASE_PATH must be a clean path below /"},
+		{"pattern base", func(c *Config) { c.BasePath = "/{tenant}" }, "BASE_PATH must only contain letters, digits and /-._~"},
+		{"relative webhook", func(c *Config) { c.WebhookPath = "flux" }, "WEBHOOK_PATH must start with /"},
+		{"root webhook", func(c *Config) { c.WebhookPath = "/" }, "WEBHOOK_PATH must be a clean path below /"},
+		{"reserved webhook", func(c *Config) { c.WebhookPath = "/status" }, "WEBHOOK_PATH must not be the built-in route /status"},
+		{"below reserved webhook", func(c *Config) { c.WebhookPath = "/debug/flux" }, "WEBHOOK_PATH must not be the built-in route /debug"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = "user"
+			cfg.PushoverAPIToken = "token"
+			tt.setup(cfg)
+
+			err := ValidateConfig(cfg)
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"PUSHOVER_USER_KEY": "user", "PUSHOVER_API_TOKEN": "token", "WEBHOOK_PATH": "/"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := ValidateConfig(config); err == nil {
+		t.Error("Expected WEBHOOK_PATH / to be refused")
+	}
+}
+
+func TestLoadFromEnv_PushoverBaseURL(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"PUSHOVER_BASE_URL": " https://relay.example.com/pushover/v1/ "}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.PushoverBaseURL != "https://relay.example.com/pushover/v1" || config.PushoverURL != "https://relay.example.com/pushover/v1/messages.json" {
+		t.Errorf("Unexpected endpoints %q %q", config.PushoverBaseURL, config.PushoverURL)
+	}
+
+	// PUSHOVER_URL still overrides the messages endpoint
+	config, err = LoadFromEnv(func(key string) string {
+		return map[string]string{"PUSHOVER_BASE_URL": "https://relay.example.com/v1", "PUSHOVER_URL": "https://relay.example.com/send"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.PushoverBaseURL != "https://relay.example.com/v1" || config.PushoverURL != "https://relay.example.com/send" {
+		t.Errorf("Unexpected endpoints %q %q", config.PushoverBaseURL, config.PushoverURL)
+	}
+
+	tests := []struct {
+		baseURL  string
+		expected string
+	}{
+		{"relay.example.com/v1", "PUSHOVER_BASE_URL must be an absolute http or https URL"},
+		{"https://relay.example.com/v1?x=1", "PUSHOVER_BASE_URL must not have a query or fragment"},
+	}
+	for _, tt := range tests {
+		cfg := NewConfig()
+		cfg.PushoverUserKey = "user"
+		cfg.PushoverAPIToken = "token"
+		cfg.PushoverBaseURL = tt.baseURL
+		if err := ValidateConfig(cfg); err == nil || err.Error() != tt.expected {
+			t.Errorf("PUSHOVER_BASE_URL=%q: expected %q, got %v", tt.baseURL, tt.expected, err)
+		}
+	}
+}
+
+func TestLoadFromEnv_Kinds(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"INCLUDE_KINDS": "HelmRelease, Kustomization", "EXCLUDE_KINDS": " GitRepository "}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(config.IncludeKinds, ",") != "helmrelease,kustomization" || strings.Join(config.ExcludeKinds, ",") != "gitrepository" {
+		t.Errorf("Unexpected kinds %v %v", config.IncludeKinds, config.ExcludeKinds)
+	}
+
+	if defaults := NewConfig(); defaults.IncludeKinds != nil || defaults.ExcludeKinds != nil {
+		t.Errorf("Expected no kind filter by default, got %v %v", defaults.IncludeKinds, defaults.ExcludeKinds)
+	}
+}
+
+func TestLoadFromEnv_RetryAfterSeconds(t *testing.T) {
+	if defaults := NewConfig(); defaults.RetryAfterSeconds != 30 {
+		t.Errorf("Expected RetryAfterSeconds to default to 30, got %d", defaults.RetryAfterSeconds)
+	}
+
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"RETRY_AFTER_SECONDS": "120"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.RetryAfterSeconds != 120 {
+		t.Errorf("Expected RetryAfterSeconds 120, got %d", config.RetryAfterSeconds)
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.RetryAfterSeconds = -1
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "RETRY_AFTER_SECONDS must not be negative" {
+		t.Errorf("Expected negative RETRY_AFTER_SECONDS to be refused, got %v", err)
+	}
+}
+
+func TestLoadFromEnv_PushoverResolve(t *testing.T) {
+	defaults := NewConfig()
+	if defaults.PushoverResolveOverride != "" || defaults.PushoverDNSCacheTTL != 5*time.Minute || defaults.PushoverDNSTimeout != 2*time.Second {
+		t.Errorf("Unexpected defaults %q %v %v", defaults.PushoverResolveOverride, defaults.PushoverDNSCacheTTL, defaults.PushoverDNSTimeout)
+	}
+
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{
+			"PUSHOVER_RESOLVE_OVERRIDE": " 203.0.113.7:443 ",
+			"PUSHOVER_DNS_CACHE_TTL":    "1h",
+			"PUSHOVER_DNS_TIMEOUT":      "500ms",
+		}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.PushoverResolveOverride != "203.0.113.7:443" || config.PushoverDNSCacheTTL != time.Hour || config.PushoverDNSTimeout != 500*time.Millisecond {
+		t.Errorf("Unexpected resolve config %q %v %v", config.PushoverResolveOverride, config.PushoverDNSCacheTTL, config.PushoverDNSTimeout)
+	}
+
+	tests := []struct {
+		name     string
+		setup    func(*Config)
+		expected string
+	}{
+		{"hostname override", func(c *Config) { c.PushoverResolveOverride = "api.pushover.net:443" }, "PUSHOVER_RESOLVE_OVERRIDE must be IP:port"},
+		{"override without port", func(c *Config) { c.PushoverResolveOverride = "203.0.113.7" }, "PUSHOVER_RESOLVE_OVERRIDE must be IP:port"},
+		{"negative TTL", func(c *Config) { c.PushoverDNSCacheTTL = -time.Second }, "PUSHOVER_DNS_CACHE_TTL must not be negative"},
+		{"negative timeout", func(c *Config) { c.PushoverDNSTimeout = -time.Second }, "PUSHOVER_DNS_TIMEOUT must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = "user"
+			cfg.PushoverAPIToken = "token"
+			tt.setup(cfg)
+			if err := ValidateConfig(cfg); err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_Sounds(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{
+			"REASON_SOUNDS":   "ImagePullBackOff=siren, HealthCheckFailed = falling",
+			"SEVERITY_SOUNDS": "error=persistent,warning=tugboat",
+		}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(config.ReasonSounds) != 2 || config.ReasonSounds["imagepullbackoff"] != "siren" || config.ReasonSounds["healthcheckfailed"] != "falling" {
+		t.Errorf("Unexpected reason sounds %v", config.ReasonSounds)
+	}
+	if len(config.SeveritySounds) != 2 || config.SeveritySounds["error"] != "persistent" || config.SeveritySounds["warning"] != "tugboat" {
+		t.Errorf("Unexpected severity sounds %v", config.SeveritySounds)
+	}
+
+	if _, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"REASON_SOUNDS": "ImagePullBackOff"}[key]
+	})(); err == nil || !strings.Contains(err.Error(), "expected name=value") {
+		t.Errorf("Expected an error for an entry without a sound, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		setup    func(*Config)
+		expected string
+	}{
+		{"unknown reason sound", func(c *Config) { c.ReasonSounds = map[string]string{"backoff": "klaxon"} }, `unknown Pushover sound "klaxon" in REASON_SOUNDS`},
+		{"unknown severity sound", func(c *Config) { c.SeveritySounds = map[string]string{"error": "klaxon"} }, `unknown Pushover sound "klaxon" in SEVERITY_SOUNDS`},
+		{"unknown severity", func(c *Config) { c.SeveritySounds = map[string]string{"critical": "siren"} }, `unknown severity "critical" in SEVERITY_SOUNDS`},
+		{"valid", func(c *Config) {
+			c.ReasonSounds = map[string]string{"backoff": "none"}
+			c.SeveritySounds = map[string]string{"info": "pushover"}
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = "user"
+			cfg.PushoverAPIToken = "token"
+			tt.setup(cfg)
+			err := ValidateConfig(cfg)
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_ReceiptPollInterval(t *testing.T) {
+	if defaults := NewConfig(); defaults.ReceiptPollInterval != 0 {
+		t.Errorf("Expected receipt polling disabled by default, got %v", defaults.ReceiptPollInterval)
+	}
+
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"RECEIPT_POLL_INTERVAL": "30s"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.ReceiptPollInterval != 30*time.Second {
+		t.Errorf("Expected 30s, got %v", config.ReceiptPollInterval)
+	}
+
+	cfg := NewConfig()
+	cfg.PushoverUserKey = "user"
+	cfg.PushoverAPIToken = "token"
+	cfg.ReceiptPollInterval = time.Second
+	if err := ValidateConfig(cfg); err == nil || err.Error() != "RECEIPT_POLL_INTERVAL must be 0 or at least 5s" {
+		t.Errorf("Expected an error for polling faster than Pushover allows, got %v", err)
+	}
+}
+
+func TestLoadFromEnv_AutoCancelEmergency(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"AUTO_CANCEL_EMERGENCY": "true"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.AutoCancelEmergency || NewConfig().AutoCancelEmergency {
+		t.Errorf("Expected AUTO_CANCEL_EMERGENCY to enable auto-cancelling, off by default")
+	}
+
+	if _, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"AUTO_CANCEL_EMERGENCY": "sometimes"}[key]
+	})(); err == nil {
+		t.Error("Expected an error for a value that is not a boolean")
+	}
+}
+
+func TestLoadFromEnv_AllowedOrigins(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"ALLOWED_ORIGINS": "https://Dashboard.example.com, http://localhost:3000"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(config.AllowedOrigins, ",") != "https://dashboard.example.com,http://localhost:3000" {
+		t.Errorf("Unexpected origins %v", config.AllowedOrigins)
+	}
+	if NewConfig().AllowedOrigins != nil {
+		t.Error("Expected CORS disabled by default")
+	}
+
+	tests := []struct {
+		origin string
+		valid  bool
+	}{
+		{"https://dashboard.example.com", true},
+		{"http://localhost:3000", true},
+		{"*", true},
+		{"https://dashboard.example.com/", false},
+		{"https://dashboard.example.com/app", false},
+		{"dashboard.example.com", false},
+		{"ftp://dashboard.example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.PushoverUserKey = "user"
+			cfg.PushoverAPIToken = "token"
+			cfg.AllowedOrigins = []string{tt.origin}
+			if err := ValidateConfig(cfg); (err == nil) != tt.valid {
+				t.Errorf("Expected valid=%v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_EnableGlances(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"ENABLE_GLANCES": "true"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.EnableGlances || NewConfig().EnableGlances {
+		t.Error("Expected ENABLE_GLANCES enabled only when set")
+	}
+
+	config.PushoverUserKey = "user"
+	config.PushoverAPIToken = "token"
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	config.Glances = GlancesAlongside
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "ENABLE_GLANCES") {
+		t.Errorf("Expected ENABLE_GLANCES to conflict with GLANCES, got %v", err)
+	}
+}
+
+func TestLoadFromEnv_StatefulNotify(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"STATEFUL_NOTIFY": "true"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.StatefulNotify || NewConfig().StatefulNotify {
+		t.Error("Expected STATEFUL_NOTIFY enabled only when set")
+	}
+
+	if _, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"STATEFUL_NOTIFY": "maybe"}[key]
+	})(); err == nil {
+		t.Error("Expected an invalid STATEFUL_NOTIFY to fail")
+	}
+}
+
+func TestLoadFromEnv_AttachOverflow(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"ATTACH_OVERFLOW": "true", "ATTACHMENT_MAX_BYTES": "65536"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.AttachOverflow || config.AttachmentMaxBytes != 65536 {
+		t.Errorf("Unexpected attachment settings %v %d", config.AttachOverflow, config.AttachmentMaxBytes)
+	}
+	if NewConfig().AttachOverflow || NewConfig().AttachmentMaxBytes != types.DefaultAttachmentBytes {
+		t.Error("Expected ATTACH_OVERFLOW disabled with the default cap")
+	}
+
+	for _, maxBytes := range []int{0, types.MaxAttachmentBytes + 1} {
+		config.PushoverUserKey = "user"
+		config.PushoverAPIToken = "token"
+		config.AttachmentMaxBytes = maxBytes
+		if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "ATTACHMENT_MAX_BYTES") {
+			t.Errorf("Expected ATTACHMENT_MAX_BYTES=%d rejected, got %v", maxBytes, err)
+		}
+	}
+}
+
+func TestLoadFromEnv_SeverityCredentials(t *testing.T) {
+	errorToken := strings.Repeat("e", 30)
+	infoUser := strings.Repeat("i", 30)
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{
+			"PUSHOVER_API_TOKEN":       "default-token",
+			"PUSHOVER_USER_KEY":        "default-user",
+			"PUSHOVER_API_TOKEN_ERROR": " " + errorToken + "\n",
+			"PUSHOVER_USER_KEY_INFO":   infoUser,
+		}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for severity, want := range map[string]PushoverCredentials{
+		types.SeverityError:   {Token: errorToken, User: "default-user"},
+		types.SeverityWarning: {Token: "default-token", User: "default-user"},
+		types.SeverityInfo:    {Token: "default-token", User: infoUser},
+	} {
+		if got := config.Credentials(severity); got != want {
+			t.Errorf("%s: expected %+v, got %+v", severity, want, got)
+		}
+		if got := config.Credentials(strings.ToUpper(severity)); got != want {
+			t.Errorf("%s: expected upper case to select %+v, got %+v", severity, want, got)
+		}
+	}
+	if config.BearerToken != "Bearer default-token" {
+		t.Errorf("Expected the webhook token to stay the default API token, got %q", config.BearerToken)
+	}
+	if NewConfig().SeverityCredentials != nil {
+		t.Error("Expected no severity credentials by default")
+	}
+}
+
+func TestValidateConfig_SeverityCredentials(t *testing.T) {
+	valid := strings.Repeat("aB3", 10)
+	tests := []struct {
+		name        string
+		credentials PushoverCredentials
+		wantErr     string
+	}{
+		{"valid", PushoverCredentials{Token: valid, User: valid}, ""},
+		{"token only", PushoverCredentials{Token: valid}, ""},
+		{"short token", PushoverCredentials{Token: "abc"}, "PUSHOVER_API_TOKEN_WARNING"},
+		{"token with symbols", PushoverCredentials{Token: strings.Repeat("a-", 15)}, "PUSHOVER_API_TOKEN_WARNING"},
+		{"long user", PushoverCredentials{User: valid + "a"}, "PUSHOVER_USER_KEY_WARNING"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.PushoverUserKey = "user"
+			config.PushoverAPIToken = "token"
+			config.SeverityCredentials = map[string]PushoverCredentials{types.SeverityWarning: tt.credentials}
+
+			err := ValidateConfig(config)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected an error about %s, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_FieldAliases(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{
+			"FIELD_ALIASES": "Msg=message, level = SEVERITY,kind=involvedobject.kind",
+		}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]string{"msg": "message", "level": "severity", "kind": "involvedObject.kind"}
+	if !reflect.DeepEqual(config.FieldAliases, expected) {
+		t.Errorf("Expected %v, got %v", expected, config.FieldAliases)
+	}
+
+	for _, tt := range []struct {
+		aliases     map[string]string
+		expectedErr string
+	}{
+		{map[string]string{"msg": "body"}, `FIELD_ALIASES alias "msg" maps to unknown alert field "body"`},
+		{map[string]string{"meta.msg": "message"}, `FIELD_ALIASES alias "meta.msg" must be a top-level key`},
+		{map[string]string{"kind": "involvedobject.kind"}, `FIELD_ALIASES alias "kind" maps to unknown alert field "involvedobject.kind"`},
+	} {
+		cfg := NewConfig()
+		cfg.PushoverUserKey = "user"
+		cfg.PushoverAPIToken = "token"
+		cfg.FieldAliases = tt.aliases
+		if err := ValidateConfig(cfg); err == nil || err.Error() != tt.expectedErr {
+			t.Errorf("Expected %q, got %v", tt.expectedErr, err)
+		}
+	}
+}
+
+func TestLoadFromEnv_Deadlines(t *testing.T) {
+	config, err := LoadFromEnv(func(key string) string {
+		return map[string]string{"PUSHOVER_TIMEOUT": "5s", "REQUEST_DEADLINE": "15s"}[key]
+	})()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.PushoverTimeout != 5*time.Second || config.RequestDeadline != 15*time.Second {
+		t.Errorf("Expected 5s and 15s, got %v and %v", config.PushoverTimeout, config.RequestDeadline)
+	}
+
+	defaults := NewConfig()
+	if defaults.SendTimeout() != DefaultPushoverTimeout || defaults.RequestDeadline != 20*time.Second {
+		t.Errorf("Expected the default deadlines, got %v and %v", defaults.SendTimeout(), defaults.RequestDeadline)
+	}
+	if (&Config{}).SendTimeout() != DefaultPushoverTimeout {
+		t.Error("Expected an unset PUSHOVER_TIMEOUT to use the default")
+	}
+
+	for env, expected := range map[string]string{
+		"PUSHOVER_TIMEOUT": "PUSHOVER_TIMEOUT must not be negative",
+		"REQUEST_DEADLINE": "REQUEST_DEADLINE must not be negative",
+	} {
+		_, err := WithValidation(LoadFromEnv(func(key string) string {
+			return map[string]string{"PUSHOVER_USER_KEY": "user", "PUSHOVER_API_TOKEN": "token", env: "-1s"}[key]
+		}), ValidateConfig)()
+		if err == nil || err.Error() != expected {
+			t.Errorf("%s: expected %q, got %v", env, expected, err)
+		}
 	}
 }
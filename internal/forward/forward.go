@@ -0,0 +1,186 @@
+package forward
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+	"github.com/zhorvath83/flux-provider-pushover/internal/signing"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// Default retry settings
+const (
+	DefaultMaxAttempts    = 3
+	DefaultBackoff        = 500 * time.Millisecond
+	DefaultAttemptTimeout = 5 * time.Second
+)
+
+// HTTPClient interface for dependency injection
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Forwarder mirrors raw webhook payloads to a secondary URL in the background
+type Forwarder struct {
+	client         HTTPClient
+	url            string
+	token          string
+	secret         string // Signs the payloads when set
+	logger         server.Logger
+	maxAttempts    int
+	backoff        time.Duration
+	attemptTimeout time.Duration
+	ctx            context.Context // Cancelled on shutdown, abandoning forwards
+	clock          clock.Clock
+
+	forwarded *metrics.Counter
+	failures  *metrics.Counter
+
+	wg      sync.WaitGroup
+	pending atomic.Int64
+}
+
+// NewForwarder creates a new forwarder
+func NewForwarder(client HTTPClient, url, token string, logger server.Logger, registry *metrics.Registry) *Forwarder {
+	return &Forwarder{
+		client:         client,
+		url:            url,
+		token:          token,
+		logger:         logger,
+		maxAttempts:    DefaultMaxAttempts,
+		backoff:        DefaultBackoff,
+		attemptTimeout: DefaultAttemptTimeout,
+		ctx:            context.Background(),
+		clock:          clock.Real{},
+		forwarded:      registry.Counter("forwarded_events_total", "Events mirrored to FORWARD_URL"),
+		failures:       registry.Counter("forward_failures_total", "Events that could not be mirrored to FORWARD_URL"),
+	}
+}
+
+// WithRetry overrides the retry attempts and base backoff
+func (f *Forwarder) WithRetry(maxAttempts int, backoff time.Duration) *Forwarder {
+	if maxAttempts > 0 {
+		f.maxAttempts = maxAttempts
+	}
+	f.backoff = backoff
+	return f
+}
+
+// WithSigning signs the payloads with secret, see signing.Sign
+func (f *Forwarder) WithSigning(secret string) *Forwarder {
+	f.secret = secret
+	return f
+}
+
+// WithContext sets a context whose cancellation abandons the forwards still
+// in flight, e.g. the application's on shutdown
+func (f *Forwarder) WithContext(ctx context.Context) *Forwarder {
+	f.ctx = ctx
+	return f
+}
+
+// Forward asynchronously posts body to the forward URL. It never blocks the caller.
+func (f *Forwarder) Forward(body []byte) {
+	payload := make([]byte, len(body))
+	copy(payload, body)
+
+	f.wg.Add(1)
+	f.pending.Add(1)
+	go func() {
+		defer f.wg.Done()
+		defer f.pending.Add(-1)
+
+		if err := f.send(payload); err != nil {
+			f.failures.Inc()
+			f.logger.Printf("Failed to forward event: %v", err)
+			return
+		}
+		f.forwarded.Inc()
+	}()
+}
+
+// send posts the payload with a bounded number of attempts
+func (f *Forwarder) send(payload []byte) error {
+	var err error
+	for attempt := 1; attempt <= f.maxAttempts; attempt++ {
+		if err = f.post(payload); err == nil {
+			return nil
+		}
+		if attempt < f.maxAttempts {
+			select {
+			case <-f.clock.After(time.Duration(attempt) * f.backoff):
+			case <-f.ctx.Done():
+				return fmt.Errorf("abandoned after %d attempts: %w", attempt, err)
+			}
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", f.maxAttempts, err)
+}
+
+// post performs a single forwarding attempt
+func (f *Forwarder) post(payload []byte) error {
+	ctx, cancel := context.WithTimeout(f.ctx, f.attemptTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", f.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", types.ContentTypeJSON)
+	if f.token != "" {
+		req.Header.Set("Authorization", types.BearerPrefix+f.token)
+	}
+	// Every attempt is signed anew, its timestamp must be fresh
+	if f.secret != "" {
+		signing.Sign(req, f.secret, payload, f.clock.Now())
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return fmt.Errorf("failed to discard response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("forward target returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Pending returns the number of forwards still in flight
+func (f *Forwarder) Pending() int64 {
+	if f == nil {
+		return 0
+	}
+	return f.pending.Load()
+}
+
+// Drain waits for in-flight forwards to finish or ctx to expire
+func (f *Forwarder) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		f.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("forwarder drain interrupted: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,234 @@
+package forward
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/signing"
+)
+
+// MockLogger for testing (thread-safe)
+type MockLogger struct {
+	mu       sync.Mutex
+	Messages []string
+}
+
+func (m *MockLogger) Printf(format string, v ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Messages = append(m.Messages, fmt.Sprintf(format, v...))
+}
+
+func (m *MockLogger) Println(v ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Messages = append(m.Messages, fmt.Sprint(v...))
+}
+
+func TestForwarder_MirrorsPayload(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan string, 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- string(body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	registry := metrics.NewRegistry()
+	forwarder := NewForwarder(ts.Client(), ts.URL, "audit-token", &MockLogger{}, registry)
+
+	payload := []byte(`{"severity":"error","message":"boom"}`)
+	forwarder.Forward(payload)
+	payload[0] = 'X' // Caller may reuse its buffer
+
+	if err := forwarder.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	req := <-received
+	if req.Header.Get("Authorization") != "Bearer audit-token" {
+		t.Errorf("Unexpected Authorization header: %s", req.Header.Get("Authorization"))
+	}
+
+	if body := <-bodies; body != `{"severity":"error","message":"boom"}` {
+		t.Errorf("Unexpected mirrored body: %s", body)
+	}
+
+	if v := registry.Counter("forwarded_events_total", "").Value(); v != 1 {
+		t.Errorf("Expected forwarded_events_total 1, got %d", v)
+	}
+}
+
+func TestForwarder_BoundedRetry(t *testing.T) {
+	var attempts atomic.Int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	registry := metrics.NewRegistry()
+	logger := &MockLogger{}
+	forwarder := NewForwarder(ts.Client(), ts.URL, "", logger, registry).WithRetry(0, time.Millisecond)
+
+	forwarder.Forward([]byte(`{}`))
+	if err := forwarder.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	if got := attempts.Load(); got != DefaultMaxAttempts {
+		t.Errorf("Expected %d attempts, got %d", DefaultMaxAttempts, got)
+	}
+
+	if v := registry.Counter("forward_failures_total", "").Value(); v != 1 {
+		t.Errorf("Expected forward_failures_total 1, got %d", v)
+	}
+
+	if len(logger.Messages) != 1 {
+		t.Errorf("Expected 1 log message, got %v", logger.Messages)
+	}
+}
+
+func TestForwarder_RecoversOnRetry(t *testing.T) {
+	var attempts atomic.Int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	registry := metrics.NewRegistry()
+	forwarder := NewForwarder(ts.Client(), ts.URL, "", &MockLogger{}, registry).WithRetry(0, time.Millisecond)
+
+	forwarder.Forward([]byte(`{}`))
+	if err := forwarder.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("Expected 2 attempts, got %d", got)
+	}
+
+	if v := registry.Counter("forward_failures_total", "").Value(); v != 0 {
+		t.Errorf("Expected no failures, got %d", v)
+	}
+}
+
+func TestForwarder_Signing(t *testing.T) {
+	verified := make(chan error, 2)
+	var attempts atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verified <- signing.Verify(r, "s3cret", body, time.Now(), signing.DefaultTolerance)
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	forwarder := NewForwarder(ts.Client(), ts.URL, "", &MockLogger{}, nil).WithRetry(0, time.Millisecond).WithSigning("s3cret")
+	forwarder.Forward([]byte(`{"severity":"error"}`))
+	if err := forwarder.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	// The retry is signed as well
+	for i := 0; i < 2; i++ {
+		if err := <-verified; err != nil {
+			t.Errorf("Expected attempt %d to verify, got %v", i+1, err)
+		}
+	}
+}
+
+func TestForwarder_Unsigned(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+	}))
+	defer ts.Close()
+
+	forwarder := NewForwarder(ts.Client(), ts.URL, "", &MockLogger{}, nil)
+	forwarder.Forward([]byte(`{}`))
+	if err := forwarder.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	if header := <-headers; header.Get(signing.SignatureHeader) != "" || header.Get(signing.TimestampHeader) != "" {
+		t.Errorf("Expected no signature without a secret, got %v", header)
+	}
+}
+
+func TestForwarder_DrainTimeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	forwarder := NewForwarder(ts.Client(), ts.URL, "", &MockLogger{}, nil)
+	forwarder.Forward([]byte(`{}`))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := forwarder.Drain(ctx); err == nil {
+		t.Error("Expected drain to be interrupted by context")
+	}
+
+	if pending := forwarder.Pending(); pending != 1 {
+		t.Errorf("Expected 1 pending forward, got %d", pending)
+	}
+}
+
+func TestForwarder_ContextAbandonsRetries(t *testing.T) {
+	var attempts atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	logger := &MockLogger{}
+	forwarder := NewForwarder(ts.Client(), ts.URL, "", logger, nil).WithRetry(0, time.Hour).WithContext(ctx)
+	forwarder.Forward([]byte(`{}`))
+
+	// The first attempt fails, the backoff before the second is cut short
+	deadline := time.Now().Add(2 * time.Second)
+	for attempts.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer drainCancel()
+	if err := forwarder.Drain(drainCtx); err != nil {
+		t.Fatalf("Expected the cancelled forward to finish, got %v", err)
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("Expected 1 attempt, got %d", got)
+	}
+	if len(logger.Messages) != 1 || logger.Messages[0] != "Failed to forward event: abandoned after 1 attempts: forward target returned status 503" {
+		t.Errorf("Expected the abandoned forward to be logged, got %v", logger.Messages)
+	}
+}
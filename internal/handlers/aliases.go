@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// alertDecoder returns the decoder of single alerts, renaming the keys
+// of FIELD_ALIASES before decoding strictly
+func alertDecoder(cfg *config.Config) func(data []byte, alert *types.FluxAlert) error {
+	return func(data []byte, alert *types.FluxAlert) error {
+		renamed, err := applyFieldAliases(data, cfg.FieldAliases)
+		if err != nil {
+			return err
+		}
+		return decodeAlert(renamed, alert)
+	}
+}
+
+// applyFieldAliases renames the top-level keys of a JSON object matching
+// an alias case insensitively to the alert field they stand for. A field
+// also sent under its own name keeps that value. Anything but an object is
+// returned unchanged for the decoder to reject (pure function).
+func applyFieldAliases(data []byte, aliases map[string]string) ([]byte, error) {
+	if len(aliases) == 0 {
+		return data, nil
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil || object == nil {
+		return data, nil
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	renamed := false
+	for _, key := range keys {
+		field, ok := aliases[strings.ToLower(key)]
+		if !ok {
+			continue
+		}
+		value := object[key]
+		delete(object, key)
+		renamed = true
+
+		parent, child, nested := strings.Cut(field, ".")
+		if !nested {
+			if _, exists := object[field]; !exists {
+				object[field] = value
+			}
+			continue
+		}
+
+		var inner map[string]json.RawMessage
+		if raw, exists := object[parent]; exists {
+			if err := json.Unmarshal(raw, &inner); err != nil || inner == nil {
+				return nil, &ValidationError{Fields: []FieldError{{
+					Field:  parent,
+					Reason: "must be an object to hold the aliased " + key,
+				}}}
+			}
+		} else {
+			inner = make(map[string]json.RawMessage)
+		}
+		if _, exists := inner[child]; exists {
+			continue
+		}
+		inner[child] = value
+		raw, err := json.Marshal(inner)
+		if err != nil {
+			return nil, err
+		}
+		object[parent] = raw
+	}
+
+	if !renamed {
+		return data, nil
+	}
+	return json.Marshal(object)
+}
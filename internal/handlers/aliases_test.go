@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+func TestApplyFieldAliases(t *testing.T) {
+	aliases := map[string]string{
+		"msg":   "message",
+		"level": "severity",
+		"kind":  "involvedObject.kind",
+	}
+	tests := []struct {
+		name        string
+		data        string
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:     "renames aliased keys",
+			data:     `{"MSG":"deployed","level":"info"}`,
+			expected: `{"message":"deployed","severity":"info"}`,
+		},
+		{
+			name:     "own name wins",
+			data:     `{"message":"kept","msg":"dropped"}`,
+			expected: `{"message":"kept"}`,
+		},
+		{
+			name:     "nested target",
+			data:     `{"kind":"Kustomization","involvedObject":{"name":"apps"}}`,
+			expected: `{"involvedObject":{"kind":"Kustomization","name":"apps"}}`,
+		},
+		{
+			name:     "nested target without its object",
+			data:     `{"kind":"Kustomization"}`,
+			expected: `{"involvedObject":{"kind":"Kustomization"}}`,
+		},
+		{
+			name:     "nested own name wins",
+			data:     `{"kind":"dropped","involvedObject":{"kind":"kept"}}`,
+			expected: `{"involvedObject":{"kind":"kept"}}`,
+		},
+		{
+			name:        "nested target in a non-object",
+			data:        `{"kind":"Kustomization","involvedObject":"apps"}`,
+			expectedErr: `invalid alert: involvedObject: must be an object to hold the aliased kind`,
+		},
+		{
+			name:     "no aliased key",
+			data:     `{ "message" : "untouched" }`,
+			expected: `{ "message" : "untouched" }`,
+		},
+		{
+			name:     "not an object",
+			data:     `["msg"]`,
+			expected: `["msg"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyFieldAliases([]byte(tt.data), aliases)
+			if tt.expectedErr != "" {
+				if err == nil || err.Error() != tt.expectedErr {
+					t.Errorf("Expected %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_FieldAliases(t *testing.T) {
+	var sent []string
+	client := &MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			sent = append(sent, msg.Message)
+			return nil
+		},
+	}
+	handler := CreateWebhookHandler(&HandlerDependencies{
+		Config: &config.Config{
+			PushoverAPIToken: "token",
+			BearerToken:      "Bearer token",
+			FieldAliases:     map[string]string{"msg": "message", "level": "severity"},
+		},
+		PushoverClient: client,
+		Logger:         &MockLogger{},
+		MessageBuilder: func(alert *types.FluxAlert) string { return alert.Severity + ":" + alert.Message },
+	})
+
+	for _, body := range []string{
+		`{"level":"error","msg":"single","involvedObject":{"kind":"Kustomization","name":"a"}}`,
+		`[{"level":"info","msg":"first","involvedObject":{"kind":"Kustomization","name":"b"}},{"severity":"warning","message":"second","involvedObject":{"kind":"Kustomization","name":"c"}}]`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer token")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	expected := "[error:single info:first warning:second]"
+	if got := "[" + strings.Join(sent, " ") + "]"; got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
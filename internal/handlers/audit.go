@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/audit"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// RequestIDHeader identifies a webhook in the audit log, generated when the
+// sender does not set it
+const RequestIDHeader = "X-Request-Id"
+
+// ensureRequestID gives r a request identifier unless it has one
+func ensureRequestID(r *http.Request) {
+	if r.Header.Get(RequestIDHeader) != "" {
+		return
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return
+	}
+	r.Header.Set(RequestIDHeader, hex.EncodeToString(id))
+}
+
+// auditAlert records what became of alert in the audit log. requestID is
+// empty for deliveries that outlive their webhook, like coalesced ones.
+func auditAlert(deps *HandlerDependencies, requestID string, alert *types.FluxAlert, decision Decision, sendErr error, pushoverRequestID string) {
+	if deps.Audit == nil {
+		return
+	}
+
+	severity, _ := NormalizeSeverity(alert.Severity)
+	entry := audit.Entry{
+		Time:              deps.clock().Now().UTC(),
+		RequestID:         requestID,
+		Kind:              alert.InvolvedObject.Kind,
+		Namespace:         alert.InvolvedObject.Namespace,
+		Name:              alert.InvolvedObject.Name,
+		Severity:          severity,
+		Reason:            alert.Reason,
+		Message:           alert.Message,
+		Revision:          alert.Metadata[types.MetadataRevision],
+		Outcome:           decision.Outcome,
+		Rule:              decision.Rule,
+		PushoverRequestID: pushoverRequestID,
+	}
+	if sendErr != nil {
+		entry.Error = sendErr.Error()
+	}
+	deps.Audit.Record(entry)
+}
+
+// auditCoalesced records the delivery of the alerts held back for one object
+func auditCoalesced(deps *HandlerDependencies, alerts []*types.FluxAlert, outcome string, sendErr error, pushoverRequestID string) {
+	for _, alert := range alerts {
+		auditAlert(deps, "", alert, Decision{Outcome: outcome, Rule: "COALESCE_WINDOW"}, sendErr, pushoverRequestID)
+	}
+}
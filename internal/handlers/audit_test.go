@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/audit"
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/pushover"
+	"github.com/zhorvath83/flux-provider-pushover/internal/store"
+)
+
+func TestCreateWebhookHandler_Audit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	file, err := audit.OpenRotatingFile(path, 1<<20, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	status := http.StatusOK
+	httpClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(`{"status":1,"request":"pushover-1"}`))}, nil
+		},
+	}
+	deps := &HandlerDependencies{
+		Config: &config.Config{
+			PushoverAPIToken: "test_token",
+			BearerToken:      "Bearer test_token",
+			DedupWindow:      time.Minute,
+		},
+		PushoverClient: pushover.NewPushoverClient(httpClient, "http://pushover.test/1/messages.json"),
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		Dedup:          store.NewMemoryStore(),
+		Audit:          audit.NewLogger(file, time.Hour, &MockLogger{}, nil),
+		Clock:          clock.NewFake(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)),
+	}
+	handler := CreateWebhookHandler(deps)
+
+	post := func(reason, requestID string) {
+		body := `{"severity":"WARN","reason":"` + reason + `","message":"m","metadata":{"revision":"main@sha1:abc"},` +
+			`"involvedObject":{"kind":"Kustomization","namespace":"apps","name":"apps"}}`
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test_token")
+		if requestID != "" {
+			req.Header.Set(RequestIDHeader, requestID)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	post("HealthCheckFailed", "req-1")
+	post("HealthCheckFailed", "req-2")
+	status = http.StatusBadRequest
+	post("ReconciliationFailed", "")
+
+	if err := deps.Drain(context.Background()); err != nil {
+		t.Fatalf("Unexpected drain error: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer f.Close()
+
+	var entries []audit.Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry audit.Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %+v", entries)
+	}
+
+	sent, duplicate, failed := entries[0], entries[1], entries[2]
+	if sent.RequestID != "req-1" || sent.Outcome != OutcomeSent || sent.PushoverRequestID != "pushover-1" ||
+		sent.Severity != "warning" || sent.Kind != "Kustomization" || sent.Namespace != "apps" ||
+		sent.Revision != "main@sha1:abc" || !sent.Time.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("Unexpected sent entry %+v", sent)
+	}
+	if duplicate.RequestID != "req-2" || duplicate.Outcome != OutcomeSuppressed || duplicate.Rule != "DEDUP_WINDOW" {
+		t.Errorf("Unexpected duplicate entry %+v", duplicate)
+	}
+	if failed.Outcome != OutcomeFailed || len(failed.RequestID) != 16 || !strings.Contains(failed.Error, "status 400") {
+		t.Errorf("Expected a failed entry with a generated request id, got %+v", failed)
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// credential is one accepted webhook secret
+type credential struct {
+	id            string // Safe to log, never contains the secret
+	authorization []byte // Expected Authorization header of bearer tokens
+	user          []byte // Basic credentials when authorization is nil
+	password      []byte
+
+	cluster            string // Static cluster label, empty for CLUSTER_NAME
+	allowClusterHeader bool   // Whether ClusterHeader may name the cluster
+}
+
+// Authenticator checks webhook requests against every accepted credential
+type Authenticator struct {
+	credentials   []credential
+	authenticated *metrics.CounterVec
+}
+
+// NewAuthenticator accepts the WEBHOOK_TOKENS, or else the WEBHOOK_TOKEN or
+// the API token, and the WEBHOOK_BASIC_USER credentials
+func NewAuthenticator(cfg *config.Config, registry *metrics.Registry) *Authenticator {
+	a := &Authenticator{
+		authenticated: registry.CounterVec("webhook_authenticated_total", "Authenticated webhook requests, by credential id", "credential"),
+	}
+
+	if len(cfg.WebhookTokens) == 0 && cfg.BearerToken != "" {
+		id := "api-token"
+		if cfg.WebhookToken != "" {
+			id = "webhook-token"
+		}
+		a.credentials = append(a.credentials, credential{
+			id:            id,
+			authorization: []byte(cfg.BearerToken),
+		})
+	}
+	for i, token := range cfg.WebhookTokens {
+		c := credential{
+			id:            CredentialID(i, token),
+			authorization: []byte(types.BearerPrefix + token),
+		}
+		if i < len(cfg.WebhookTokenClusters) {
+			c.cluster = cfg.WebhookTokenClusters[i].Name
+			c.allowClusterHeader = cfg.WebhookTokenClusters[i].AllowHeader
+		}
+		a.credentials = append(a.credentials, c)
+	}
+	if cfg.WebhookBasicUser != "" {
+		a.credentials = append(a.credentials, credential{
+			id:       "basic:" + cfg.WebhookBasicUser,
+			user:     []byte(cfg.WebhookBasicUser),
+			password: []byte(cfg.WebhookBasicPassword),
+		})
+	}
+	return a
+}
+
+// CredentialID identifies the WEBHOOK_TOKENS entry at index by its position
+// and a hash prefix, e.g. "token#1:9f86d081" (pure function)
+func CredentialID(index int, token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("token#%d:%s", index+1, hex.EncodeToString(sum[:4]))
+}
+
+// Authenticate returns the id of the credential r was authenticated with.
+// Every credential is compared in constant time, so the response time does
+// not reveal which one nearly matched.
+func (a *Authenticator) Authenticate(r *http.Request) (string, bool) {
+	authorization := []byte(r.Header.Get("Authorization"))
+	user, password, basic := r.BasicAuth()
+
+	id, ok := "", false
+	for _, c := range a.credentials {
+		var match bool
+		if c.authorization != nil {
+			match = subtle.ConstantTimeCompare(authorization, c.authorization) == 1
+		} else {
+			userMatch := subtle.ConstantTimeCompare([]byte(user), c.user)
+			passwordMatch := subtle.ConstantTimeCompare([]byte(password), c.password)
+			match = basic && userMatch&passwordMatch == 1
+		}
+		if match && !ok {
+			id, ok = c.id, true
+		}
+	}
+
+	if ok {
+		a.authenticated.WithLabelValues(id).Inc()
+	}
+	return id, ok
+}
+
+// Rotating reports whether more than one credential is accepted, so that
+// the one in use is worth logging
+func (a *Authenticator) Rotating() bool {
+	return len(a.credentials) > 1
+}
+
+// Cluster returns the cluster named by r, which was authenticated with the
+// credential id: its ClusterHeader when the credential has
+// allow_cluster_header, else the credential's cluster label, empty for
+// CLUSTER_NAME. A header the credential may not set, or with an invalid
+// name, is ignored and returned as the error.
+func (a *Authenticator) Cluster(r *http.Request, id string) (string, error) {
+	var c credential
+	for _, candidate := range a.credentials {
+		if candidate.id == id {
+			c = candidate
+			break
+		}
+	}
+
+	header := strings.TrimSpace(r.Header.Get(ClusterHeader))
+	switch {
+	case header == "":
+		return c.cluster, nil
+	case !c.allowClusterHeader:
+		return c.cluster, errClusterHeaderNotAllowed
+	case !config.ValidClusterName(header):
+		return c.cluster, errClusterHeaderInvalid
+	default:
+		return header, nil
+	}
+}
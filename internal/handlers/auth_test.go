@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+)
+
+func TestAuthenticator(t *testing.T) {
+	rotating := &config.Config{
+		BearerToken:          "Bearer api-token",
+		WebhookTokens:        []string{"old-token", "new-token"},
+		WebhookBasicUser:     "flux",
+		WebhookBasicPassword: "secret",
+	}
+
+	tests := []struct {
+		name       string
+		cfg        *config.Config
+		setup      func(r *http.Request)
+		expectedID string
+		expectedOK bool
+	}{
+		{
+			name:       "api token by default",
+			cfg:        &config.Config{BearerToken: "Bearer api-token"},
+			setup:      func(r *http.Request) { r.Header.Set("Authorization", "Bearer api-token") },
+			expectedID: "api-token",
+			expectedOK: true,
+		},
+		{
+			name:       "dedicated webhook token",
+			cfg:        &config.Config{WebhookToken: "webhook-token", BearerToken: "Bearer webhook-token"},
+			setup:      func(r *http.Request) { r.Header.Set("Authorization", "Bearer webhook-token") },
+			expectedID: "webhook-token",
+			expectedOK: true,
+		},
+		{
+			name:  "api token replaced by webhook token",
+			cfg:   &config.Config{PushoverAPIToken: "api-token", WebhookToken: "webhook-token", BearerToken: "Bearer webhook-token"},
+			setup: func(r *http.Request) { r.Header.Set("Authorization", "Bearer api-token") },
+		},
+		{
+			name:       "old token during rotation",
+			cfg:        rotating,
+			setup:      func(r *http.Request) { r.Header.Set("Authorization", "Bearer old-token") },
+			expectedID: CredentialID(0, "old-token"),
+			expectedOK: true,
+		},
+		{
+			name:       "new token during rotation",
+			cfg:        rotating,
+			setup:      func(r *http.Request) { r.Header.Set("Authorization", "Bearer new-token") },
+			expectedID: CredentialID(1, "new-token"),
+			expectedOK: true,
+		},
+		{
+			name:  "api token replaced by webhook tokens",
+			cfg:   rotating,
+			setup: func(r *http.Request) { r.Header.Set("Authorization", "Bearer api-token") },
+		},
+		{
+			name:       "basic auth",
+			cfg:        rotating,
+			setup:      func(r *http.Request) { r.SetBasicAuth("flux", "secret") },
+			expectedID: "basic:flux",
+			expectedOK: true,
+		},
+		{
+			name:  "basic auth wrong password",
+			cfg:   rotating,
+			setup: func(r *http.Request) { r.SetBasicAuth("flux", "guess") },
+		},
+		{
+			name:  "basic auth wrong user",
+			cfg:   rotating,
+			setup: func(r *http.Request) { r.SetBasicAuth("admin", "secret") },
+		},
+		{
+			name:  "basic auth not configured",
+			cfg:   &config.Config{BearerToken: "Bearer api-token"},
+			setup: func(r *http.Request) { r.SetBasicAuth("", "") },
+		},
+		{
+			name:  "missing credentials",
+			cfg:   rotating,
+			setup: func(r *http.Request) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+			tt.setup(req)
+
+			id, ok := NewAuthenticator(tt.cfg, nil).Authenticate(req)
+			if ok != tt.expectedOK || id != tt.expectedID {
+				t.Errorf("Expected (%q, %v), got (%q, %v)", tt.expectedID, tt.expectedOK, id, ok)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_Cluster(t *testing.T) {
+	auth := NewAuthenticator(&config.Config{
+		BearerToken:   "Bearer api-token",
+		WebhookTokens: []string{"prod-token", "shared-token", "plain-token"},
+		WebhookTokenClusters: []config.WebhookTokenCluster{
+			{Name: "prod"},
+			{Name: "shared", AllowHeader: true},
+			{},
+		},
+	}, nil)
+
+	tests := []struct {
+		name          string
+		token         string
+		header        string
+		expected      string
+		expectedError error
+	}{
+		{"token label", "prod-token", "", "prod", nil},
+		{"spoofed header ignored", "prod-token", "staging", "prod", errClusterHeaderNotAllowed},
+		{"header of a trusted token", "shared-token", "staging", "staging", nil},
+		{"trusted token without header", "shared-token", "", "shared", nil},
+		{"invalid header", "shared-token", "staging cluster", "shared", errClusterHeaderInvalid},
+		{"no label falls back to CLUSTER_NAME", "plain-token", "", "", nil},
+		{"spoofed header without label", "plain-token", "prod", "", errClusterHeaderNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			if tt.header != "" {
+				req.Header.Set(ClusterHeader, tt.header)
+			}
+			id, ok := auth.Authenticate(req)
+			if !ok {
+				t.Fatal("Expected the request to authenticate")
+			}
+
+			cluster, err := auth.Cluster(req, id)
+			if cluster != tt.expected || err != tt.expectedError {
+				t.Errorf("Expected (%q, %v), got (%q, %v)", tt.expected, tt.expectedError, cluster, err)
+			}
+		})
+	}
+}
+
+func TestCredentialID(t *testing.T) {
+	id := CredentialID(0, "old-token")
+	if !strings.HasPrefix(id, "token#1:") || len(id) != len("token#1:")+8 {
+		t.Errorf("Expected position and hash prefix, got %q", id)
+	}
+	if strings.Contains(id, "old-token") {
+		t.Errorf("Expected the token to stay secret, got %q", id)
+	}
+	if id == CredentialID(0, "new-token") {
+		t.Error("Expected different tokens to have different ids")
+	}
+}
+
+func TestCreateWebhookHandler_LogsCredential(t *testing.T) {
+	registry := metrics.NewRegistry()
+	logger := &MockLogger{}
+	handler := CreateWebhookHandler(&HandlerDependencies{
+		Config: &config.Config{
+			PushoverAPIToken: "api-token",
+			BearerToken:      "Bearer api-token",
+			WebhookTokens:    []string{"old-token", "new-token"},
+		},
+		PushoverClient: &MockPushoverClient{},
+		Logger:         logger,
+		MessageBuilder: BuildPushoverMessage,
+		Metrics:        registry,
+	})
+
+	body := `{"severity":"info","message":"m","reason":"r","involvedObject":{"kind":"Kustomization","name":"apps"}}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer old-token")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	if !contains(strings.Join(logger.messages, "\n"), "Request from %s authenticated with %s") {
+		t.Errorf("Expected the credential id to be logged, got %v", logger.messages)
+	}
+	if count := registry.CounterVec("webhook_authenticated_total", "", "credential").WithLabelValues(CredentialID(0, "old-token")).Value(); count != 1 {
+		t.Errorf("Expected 1 request counted for the old token, got %d", count)
+	}
+}
+
+func TestCreateWebhookHandler_SingleCredentialNotLogged(t *testing.T) {
+	logger := &MockLogger{}
+	handler := CreateWebhookHandler(&HandlerDependencies{
+		Config:         &config.Config{PushoverAPIToken: "api-token", BearerToken: "Bearer api-token"},
+		PushoverClient: &MockPushoverClient{},
+		Logger:         logger,
+		MessageBuilder: BuildPushoverMessage,
+	})
+
+	body := `{"severity":"info","message":"m","reason":"r","involvedObject":{"kind":"Kustomization","name":"apps"}}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer api-token")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if contains(strings.Join(logger.messages, "\n"), "authenticated with") {
+		t.Errorf("Expected no credential log without rotation, got %v", logger.messages)
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// batchResponse summarizes the delivery of an alert batch
+type batchResponse struct {
+	Status    string `json:"status"`
+	Processed int    `json:"processed"`
+	Failed    int    `json:"failed"`
+}
+
+// dispatchFunc delivers one alert and writes its response to w
+type dispatchFunc func(w http.ResponseWriter, r *http.Request, alert *types.FluxAlert, raw []byte, key string)
+
+// isJSONArray reports whether the first non-whitespace byte opens a JSON
+// array (pure function)
+func isJSONArray(data []byte) bool {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// decodeBatch decodes and validates a JSON array of alerts, keeping each
+// element's raw JSON for mirroring. A single invalid alert rejects the batch,
+// its typed error names the offending element.
+func decodeBatch(data []byte, decode func([]byte, *types.FluxAlert) error, validate func(*types.FluxAlert) error) ([]types.FluxAlert, [][]byte, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, nil, classifyDecodeError(err)
+	}
+	if len(items) == 0 {
+		return nil, nil, fmt.Errorf("batch contains no alerts")
+	}
+
+	alerts := make([]types.FluxAlert, len(items))
+	raws := make([][]byte, len(items))
+	for i, item := range items {
+		if err := decode(item, &alerts[i]); err != nil {
+			return nil, nil, atBatchIndex(err, i)
+		}
+		if err := validate(&alerts[i]); err != nil {
+			return nil, nil, atBatchIndex(err, i)
+		}
+		raws[i] = item
+	}
+	return alerts, raws, nil
+}
+
+// deliverBatch dispatches every alert on its own and writes a summary. Any
+// failure answers 500 so the sender retries; alerts already delivered are
+// then replayed or suppressed when idempotency or deduplication is enabled.
+func deliverBatch(deps *HandlerDependencies, w http.ResponseWriter, r *http.Request, alerts []types.FluxAlert, raws [][]byte, dispatch dispatchFunc) {
+	summary := batchResponse{Status: "ok", Processed: len(alerts)}
+	for i := range alerts {
+		recorder := &statusOnlyRecorder{header: make(http.Header), status: http.StatusOK}
+		dispatch(recorder, r, &alerts[i], raws[i], batchItemKey(r, &alerts[i], i))
+		if recorder.status < 200 || recorder.status > 299 {
+			summary.Failed++
+		}
+	}
+
+	status := deps.responses().OKStatus
+	if summary.Failed > 0 {
+		summary.Status = "error"
+		status = http.StatusInternalServerError
+	}
+	deps.Logger.Printf("Processed alert batch: %d alerts, %d failed", summary.Processed, summary.Failed)
+
+	body, err := json.Marshal(summary)
+	if err != nil {
+		writeJSONResponse(w, http.StatusInternalServerError, types.ResponseInternalError)
+		return
+	}
+	writeJSONResponse(w, status, body)
+}
+
+// batchItemKey returns the idempotency key of a batch element. A client
+// supplied key covers the whole request, so it is suffixed with the index.
+func batchItemKey(r *http.Request, alert *types.FluxAlert, index int) string {
+	key := IdempotencyKey(r, alert)
+	if strings.HasPrefix(key, "header:") {
+		key += "#" + strconv.Itoa(index)
+	}
+	return key
+}
+
+// statusOnlyRecorder keeps the status of a batch element's response and
+// discards its body
+type statusOnlyRecorder struct {
+	header http.Header
+	status int
+}
+
+func (s *statusOnlyRecorder) Header() http.Header {
+	return s.header
+}
+
+func (s *statusOnlyRecorder) WriteHeader(status int) {
+	s.status = status
+}
+
+func (s *statusOnlyRecorder) Write(data []byte) (int, error) {
+	return len(data), nil
+}
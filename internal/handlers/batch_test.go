@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/idempotency"
+)
+
+func TestIsJSONArray(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected bool
+	}{
+		{"object", `{"message":"a"}`, false},
+		{"array", `[{"message":"a"}]`, true},
+		{"array after whitespace", " \r\n\t[{}]", true},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isJSONArray([]byte(tt.body)); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_Batch(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		failOn        int32 // 1-based send that fails, 0 for none
+		expectedCode  int
+		expectedSends int32
+		expectedBody  *batchResponse
+	}{
+		{
+			name:          "single object",
+			body:          `{"involvedObject":{"kind":"Kustomization","name":"apps"},"message":"one"}`,
+			expectedCode:  http.StatusOK,
+			expectedSends: 1,
+		},
+		{
+			name:          "two element array",
+			body:          `[{"involvedObject":{"kind":"Kustomization","name":"apps"},"message":"one"},{"involvedObject":{"kind":"HelmRelease","name":"podinfo"},"message":"two"}]`,
+			expectedCode:  http.StatusOK,
+			expectedSends: 2,
+			expectedBody:  &batchResponse{Status: "ok", Processed: 2, Failed: 0},
+		},
+		{
+			name:          "array with leading whitespace",
+			body:          "\n  [{\"message\":\"one\"}]",
+			expectedCode:  http.StatusOK,
+			expectedSends: 1,
+			expectedBody:  &batchResponse{Status: "ok", Processed: 1, Failed: 0},
+		},
+		{
+			name:          "one send fails",
+			body:          `[{"message":"one"},{"message":"two"}]`,
+			failOn:        2,
+			expectedCode:  http.StatusInternalServerError,
+			expectedSends: 2,
+			expectedBody:  &batchResponse{Status: "error", Processed: 2, Failed: 1},
+		},
+		{
+			name:         "empty array",
+			body:         `[]`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown field rejects the batch",
+			body:         `[{"message":"one"},{"message":"two","bogus":true}]`,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "malformed array",
+			body:         `[{"message":"one"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sends atomic.Int32
+			deps := newIdempotencyTestDeps(nil, func() error {
+				if sends.Add(1) == tt.failOn {
+					return errors.New("pushover down")
+				}
+				return nil
+			})
+
+			rr := postAlert(CreateWebhookHandler(deps), tt.body, "")
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+			if sends.Load() != tt.expectedSends {
+				t.Errorf("Expected %d sends, got %d", tt.expectedSends, sends.Load())
+			}
+			if tt.expectedBody == nil {
+				return
+			}
+
+			var summary batchResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &summary); err != nil {
+				t.Fatalf("Invalid summary %q: %v", rr.Body.String(), err)
+			}
+			if summary != *tt.expectedBody {
+				t.Errorf("Expected %+v, got %+v", *tt.expectedBody, summary)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_BatchIdempotency(t *testing.T) {
+	var sends atomic.Int32
+	cache := idempotency.NewCache(idempotency.DefaultTTL, 100, nil)
+	handler := CreateWebhookHandler(newIdempotencyTestDeps(cache, func() error {
+		sends.Add(1)
+		return nil
+	}))
+
+	body := `[{"message":"one"},{"message":"two"}]`
+
+	// One client key must not make the second alert a replay of the first
+	if rr := postAlert(handler, body, "batch-1"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	if sends.Load() != 2 {
+		t.Fatalf("Expected 2 sends, got %d", sends.Load())
+	}
+
+	// A retried batch is replayed alert by alert
+	if rr := postAlert(handler, body, "batch-1"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 on retry, got %d", rr.Code)
+	}
+	if sends.Load() != 2 {
+		t.Errorf("Expected retry to send nothing, got %d sends", sends.Load())
+	}
+}
@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/pushover"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// CallbackPath receives Pushover's acknowledgement callbacks
+const CallbackPath = "/pushover-callback"
+
+// CallbackToken authenticates acknowledgement callbacks, which carry no
+// Authorization header. It is derived from the API token so that every
+// replica accepts the callbacks of the others (pure function).
+func CallbackToken(cfg *config.Config) string {
+	mac := hmac.New(sha256.New, []byte(cfg.PushoverAPIToken))
+	mac.Write([]byte(CallbackPath))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// CallbackURL returns the acknowledgement callback URL set on emergency
+// messages, empty without PUBLIC_URL (pure function)
+func CallbackURL(cfg *config.Config) string {
+	if cfg.PublicURL == "" {
+		return ""
+	}
+	return cfg.PublicURL + cfg.BasePath + CallbackPath + "?token=" + CallbackToken(cfg)
+}
+
+// Acknowledgement is the body of a Pushover acknowledgement callback
+type Acknowledgement struct {
+	Receipt string
+	By      string // User key of the acknowledging user
+	Device  string
+	At      time.Time
+}
+
+// ParseAcknowledgement parses the form Pushover posts once an emergency
+// message is acknowledged (pure function)
+func ParseAcknowledgement(form url.Values) (Acknowledgement, error) {
+	ack := Acknowledgement{
+		Receipt: form.Get("receipt"),
+		By:      form.Get("acknowledged_by"),
+		Device:  form.Get("acknowledged_by_device"),
+	}
+	if ack.Receipt == "" {
+		return ack, fmt.Errorf("receipt is missing")
+	}
+	if form.Get("acknowledged") != "1" {
+		return ack, fmt.Errorf("receipt %s is not acknowledged", ack.Receipt)
+	}
+
+	at, err := strconv.ParseInt(form.Get("acknowledged_at"), 10, 64)
+	if err != nil {
+		return ack, fmt.Errorf("invalid acknowledged_at %q", form.Get("acknowledged_at"))
+	}
+	ack.At = time.Unix(at, 0).UTC()
+	return ack, nil
+}
+
+// pendingEmergency is an emergency message waiting to be acknowledged
+type pendingEmergency struct {
+	object     string
+	token      string // Of the application that sent it, empty for the default
+	sent       time.Time
+	cancelling bool // Claimed by ClaimRecovered, not claimed again
+}
+
+// States of emergency messages reported on /status
+const (
+	ReceiptPending      = "pending"
+	ReceiptAcknowledged = "acknowledged"
+	ReceiptExpired      = "expired"
+	ReceiptCancelled    = "cancelled"
+)
+
+// maxResolvedReceipts bounds the acknowledged and expired emergency
+// messages kept for /status
+const maxResolvedReceipts = 20
+
+// ReceiptInfo is the state of an emergency message reported on /status
+type ReceiptInfo struct {
+	Receipt        string     `json:"receipt"`
+	Object         string     `json:"object"`
+	Status         string     `json:"status"`
+	SentAt         time.Time  `json:"sent_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	Device         string     `json:"device,omitempty"`
+}
+
+// EmergencyTracker remembers the object of every unacknowledged emergency
+// message by its receipt, until it is acknowledged or Pushover stops
+// repeating it, and the last resolved ones. A nil EmergencyTracker tracks
+// nothing.
+type EmergencyTracker struct {
+	clock clock.Clock
+
+	mu       sync.Mutex
+	pending  map[string]pendingEmergency
+	resolved []ReceiptInfo // Oldest first
+
+	acknowledged *metrics.Counter
+	ackSeconds   *metrics.Counter
+	expired      *metrics.Counter
+}
+
+// NewEmergencyTracker creates an empty tracker
+func NewEmergencyTracker(registry *metrics.Registry) *EmergencyTracker {
+	return &EmergencyTracker{
+		clock:        clock.Real{},
+		pending:      make(map[string]pendingEmergency),
+		acknowledged: registry.Counter("pushover_acknowledgements_total", "Emergency messages acknowledged, seen through the Pushover callback or receipt polling"),
+		ackSeconds:   registry.Counter("pushover_acknowledgement_seconds_total", "Seconds from sending to acknowledgement summed over acknowledged emergency messages"),
+		expired:      registry.Counter("pushover_emergencies_expired_total", "Emergency messages Pushover stopped repeating without an acknowledgement"),
+	}
+}
+
+// Track remembers that the emergency message with receipt, sent by the
+// application with token, is about alert's object
+func (t *EmergencyTracker) Track(receipt, token string, alert *types.FluxAlert) {
+	if t == nil || receipt == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.expire()
+	t.pending[receipt] = pendingEmergency{
+		object: emergencyObject(alert),
+		token:  token,
+		sent:   t.clock.Now(),
+	}
+}
+
+// emergencyObject names the object of an emergency message (pure function)
+func emergencyObject(alert *types.FluxAlert) string {
+	return clusterScoped(alert, alertKind(alert)+"/"+alert.InvolvedObject.Namespace+"/"+alertName(alert))
+}
+
+// ClaimRecovered returns the receipts of the unacknowledged emergency
+// messages about alert's object for cancelling, each only once until
+// released by Cancelled
+func (t *EmergencyTracker) ClaimRecovered(alert *types.FluxAlert) []string {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.expire()
+	object := emergencyObject(alert)
+	var receipts []string
+	for receipt, pending := range t.pending {
+		if pending.object == object && !pending.cancelling {
+			pending.cancelling = true
+			t.pending[receipt] = pending
+			receipts = append(receipts, receipt)
+		}
+	}
+	sort.Strings(receipts)
+	return receipts
+}
+
+// Cancelled resolves a receipt claimed by ClaimRecovered once Pushover
+// cancelled it, or makes it claimable again when cancelling failed
+func (t *EmergencyTracker) Cancelled(receipt string, ok bool) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if ok {
+		t.resolve(receipt, ReceiptCancelled, time.Time{}, "")
+		return
+	}
+	if pending, found := t.pending[receipt]; found {
+		pending.cancelling = false
+		t.pending[receipt] = pending
+	}
+}
+
+// Acknowledge stops tracking receipt and returns its object, empty when the
+// receipt is unknown, e.g. sent by another replica
+func (t *EmergencyTracker) Acknowledge(receipt string) string {
+	if t == nil {
+		return ""
+	}
+	t.acknowledged.Inc()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	info, _ := t.resolve(receipt, ReceiptAcknowledged, t.clock.Now(), "")
+	return info.Object
+}
+
+// Receipts returns the receipts of the unacknowledged emergency messages
+func (t *EmergencyTracker) Receipts() []string {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.expire()
+	receipts := make([]string, 0, len(t.pending))
+	for receipt := range t.pending {
+		receipts = append(receipts, receipt)
+	}
+	sort.Strings(receipts)
+	return receipts
+}
+
+// Token returns the token of the application that sent the emergency
+// message with receipt, or fallback when it is not known
+func (t *EmergencyTracker) Token(receipt, fallback string) string {
+	if t == nil {
+		return fallback
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if pending, ok := t.pending[receipt]; ok && pending.token != "" {
+		return pending.token
+	}
+	return fallback
+}
+
+// Update resolves receipt by its polled status, returning the resolved
+// message, or false while it is pending or when it is no longer tracked
+func (t *EmergencyTracker) Update(receipt string, status *pushover.ReceiptStatus) (ReceiptInfo, bool) {
+	if t == nil || status == nil {
+		return ReceiptInfo{}, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch {
+	case status.Acknowledged:
+		at := status.AcknowledgedAt
+		if at.IsZero() {
+			at = t.clock.Now()
+		}
+		info, ok := t.resolve(receipt, ReceiptAcknowledged, at, status.Device)
+		if ok {
+			t.acknowledged.Inc()
+		}
+		return info, ok
+	case status.Expired:
+		info, ok := t.resolve(receipt, ReceiptExpired, time.Time{}, "")
+		if ok {
+			t.expired.Inc()
+		}
+		return info, ok
+	default:
+		return ReceiptInfo{}, false
+	}
+}
+
+// Snapshot returns the pending emergency messages, oldest first, followed
+// by the last resolved ones, newest first
+func (t *EmergencyTracker) Snapshot() []ReceiptInfo {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.expire()
+	infos := make([]ReceiptInfo, 0, len(t.pending)+len(t.resolved))
+	for receipt, pending := range t.pending {
+		infos = append(infos, ReceiptInfo{Receipt: receipt, Object: pending.object, Status: ReceiptPending, SentAt: pending.sent})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if !infos[i].SentAt.Equal(infos[j].SentAt) {
+			return infos[i].SentAt.Before(infos[j].SentAt)
+		}
+		return infos[i].Receipt < infos[j].Receipt
+	})
+	for i := len(t.resolved) - 1; i >= 0; i-- {
+		infos = append(infos, t.resolved[i])
+	}
+	return infos
+}
+
+// Pending returns the number of unacknowledged emergency messages
+func (t *EmergencyTracker) Pending() int {
+	if t == nil {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.expire()
+	return len(t.pending)
+}
+
+// resolve stops tracking receipt, remembering it with status, and counts
+// the acknowledgement latency. t.mu must be held.
+func (t *EmergencyTracker) resolve(receipt, status string, at time.Time, device string) (ReceiptInfo, bool) {
+	pending, ok := t.pending[receipt]
+	if !ok {
+		return ReceiptInfo{}, false
+	}
+	delete(t.pending, receipt)
+
+	info := ReceiptInfo{Receipt: receipt, Object: pending.object, Status: status, SentAt: pending.sent, Device: device}
+	if status == ReceiptAcknowledged {
+		info.AcknowledgedAt = &at
+		if latency := at.Sub(pending.sent); latency > 0 {
+			t.ackSeconds.Add(uint64(latency / time.Second))
+		}
+	}
+
+	t.resolved = append(t.resolved, info)
+	if len(t.resolved) > maxResolvedReceipts {
+		t.resolved = t.resolved[len(t.resolved)-maxResolvedReceipts:]
+	}
+	return info, true
+}
+
+// expire forgets messages Pushover stopped repeating, t.mu must be held
+func (t *EmergencyTracker) expire() {
+	cutoff := t.clock.Now().Add(-types.EmergencyExpire * time.Second)
+	for receipt, pending := range t.pending {
+		if pending.sent.Before(cutoff) {
+			t.resolve(receipt, ReceiptExpired, time.Time{}, "")
+			t.expired.Inc()
+		}
+	}
+}
+
+// CreateCallbackHandler creates a handler for Pushover's acknowledgement
+// callbacks, authenticated by the token in the callback URL
+func CreateCallbackHandler(deps *HandlerDependencies) http.HandlerFunc {
+	token := []byte(CallbackToken(deps.Config))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSONResponse(w, http.StatusMethodNotAllowed, types.ResponseMethodNotAllowed)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), token) != 1 {
+			deps.Logger.Printf("Unauthorized acknowledgement callback from %s", r.RemoteAddr)
+			writeJSONResponse(w, http.StatusUnauthorized, types.ResponseUnauthorized)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, types.MaxBodySize)
+		if err := r.ParseForm(); err != nil {
+			deps.Logger.Printf("Invalid acknowledgement callback: %v", err)
+			writeJSONResponse(w, http.StatusBadRequest, types.ResponseInvalidForm)
+			return
+		}
+
+		ack, err := ParseAcknowledgement(r.PostForm)
+		if err != nil {
+			deps.Logger.Printf("Invalid acknowledgement callback: %v", err)
+			writeJSONResponse(w, http.StatusBadRequest, types.ResponseInvalidForm)
+			return
+		}
+
+		object := defaultIfEmpty(deps.Emergencies.Acknowledge(ack.Receipt), types.DefaultValue)
+		deps.Logger.Printf("Emergency alert for %s acknowledged: receipt %s on device %q at %s",
+			object, ack.Receipt, ack.Device, ack.At.Format(time.RFC3339))
+		writeJSONResponse(w, http.StatusOK, types.ResponseOK)
+	}
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/pushover"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+func TestParseAcknowledgement(t *testing.T) {
+	tests := []struct {
+		name        string
+		form        string
+		expected    Acknowledgement
+		expectedErr string
+	}{
+		{
+			name: "acknowledged",
+			form: "receipt=rLqVuqTRh62UzxtmqiaLzQmVcPgiCy&acknowledged=1&acknowledged_at=1700000000&acknowledged_by=uQiRzpo4DXghDmr9QzzfQu27cmVRsG&acknowledged_by_device=iphone",
+			expected: Acknowledgement{
+				Receipt: "rLqVuqTRh62UzxtmqiaLzQmVcPgiCy",
+				By:      "uQiRzpo4DXghDmr9QzzfQu27cmVRsG",
+				Device:  "iphone",
+				At:      time.Unix(1700000000, 0).UTC(),
+			},
+		},
+		{name: "missing receipt", form: "acknowledged=1&acknowledged_at=1700000000", expectedErr: "receipt is missing"},
+		{name: "not acknowledged", form: "receipt=r1&acknowledged=0", expectedErr: "receipt r1 is not acknowledged"},
+		{name: "invalid time", form: "receipt=r1&acknowledged=1&acknowledged_at=soon", expectedErr: `invalid acknowledged_at "soon"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form, _ := url.ParseQuery(tt.form)
+			ack, err := ParseAcknowledgement(form)
+			if tt.expectedErr != "" {
+				if err == nil || err.Error() != tt.expectedErr {
+					t.Errorf("Expected error %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ack != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, ack)
+			}
+		})
+	}
+}
+
+func TestCallbackURL(t *testing.T) {
+	cfg := &config.Config{PushoverAPIToken: "token"}
+	if CallbackURL(cfg) != "" {
+		t.Errorf("Expected no callback without PUBLIC_URL, got %q", CallbackURL(cfg))
+	}
+
+	cfg.PublicURL = "https://flux.example.com"
+	expected := "https://flux.example.com/pushover-callback?token=" + CallbackToken(cfg)
+	if CallbackURL(cfg) != expected {
+		t.Errorf("Expected %q, got %q", expected, CallbackURL(cfg))
+	}
+
+	// PUBLIC_URL is the host, the callback is served under BASE_PATH
+	cfg.BasePath = "/hooks/pushover"
+	expected = "https://flux.example.com/hooks/pushover/pushover-callback?token=" + CallbackToken(cfg)
+	if CallbackURL(cfg) != expected {
+		t.Errorf("Expected %q, got %q", expected, CallbackURL(cfg))
+	}
+
+	other := &config.Config{PushoverAPIToken: "other"}
+	if len(CallbackToken(cfg)) != 32 || CallbackToken(cfg) == CallbackToken(other) {
+		t.Errorf("Expected a token derived from the API token, got %q and %q", CallbackToken(cfg), CallbackToken(other))
+	}
+}
+
+func TestEmergencyTracker(t *testing.T) {
+	registry := metrics.NewRegistry()
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	tracker := NewEmergencyTracker(registry)
+	tracker.clock = fake
+
+	alert := &types.FluxAlert{}
+	alert.InvolvedObject.Kind = "HelmRelease"
+	alert.InvolvedObject.Namespace = "apps"
+	alert.InvolvedObject.Name = "podinfo"
+
+	tracker.Track("r1", "", alert)
+	tracker.Track("r2", "", alert)
+	tracker.Track("", "", alert)
+	if tracker.Pending() != 2 {
+		t.Fatalf("Expected 2 pending, got %d", tracker.Pending())
+	}
+
+	if object := tracker.Acknowledge("r1"); object != "HelmRelease/apps/podinfo" {
+		t.Errorf("Expected the object of r1, got %q", object)
+	}
+	if object := tracker.Acknowledge("unknown"); object != "" {
+		t.Errorf("Expected no object for an unknown receipt, got %q", object)
+	}
+	if tracker.Pending() != 1 {
+		t.Errorf("Expected 1 pending after acknowledgement, got %d", tracker.Pending())
+	}
+
+	// Pushover stops repeating after EmergencyExpire
+	fake.Advance(types.EmergencyExpire*time.Second + time.Second)
+	if tracker.Pending() != 0 {
+		t.Errorf("Expected expired receipts to be forgotten, got %d", tracker.Pending())
+	}
+
+	if acks := registry.Counter("pushover_acknowledgements_total", "").Value(); acks != 2 {
+		t.Errorf("Expected 2 acknowledgements, got %d", acks)
+	}
+
+	var nilTracker *EmergencyTracker
+	nilTracker.Track("r1", "", alert)
+	if nilTracker.Acknowledge("r1") != "" || nilTracker.Pending() != 0 {
+		t.Error("Expected nil tracker to track nothing")
+	}
+}
+
+func TestCreateCallbackHandler(t *testing.T) {
+	cfg := &config.Config{PushoverAPIToken: "token", PublicURL: "https://flux.example.com"}
+	validForm := "receipt=r1&acknowledged=1&acknowledged_at=1700000000&acknowledged_by=user&acknowledged_by_device=iphone"
+
+	tests := []struct {
+		name           string
+		method         string
+		token          string
+		body           string
+		expectedStatus int
+		expectedLog    string
+	}{
+		{"acknowledged", http.MethodPost, CallbackToken(cfg), validForm, http.StatusOK, "Emergency alert for %s acknowledged: receipt %s on device %q at %s"},
+		{"wrong token", http.MethodPost, "guess", validForm, http.StatusUnauthorized, "Unauthorized acknowledgement callback from %s"},
+		{"missing token", http.MethodPost, "", validForm, http.StatusUnauthorized, "Unauthorized acknowledgement callback from %s"},
+		{"invalid form", http.MethodPost, CallbackToken(cfg), "acknowledged=1", http.StatusBadRequest, "Invalid acknowledgement callback: %v"},
+		{"wrong method", http.MethodGet, CallbackToken(cfg), "", http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &MockLogger{}
+			tracker := NewEmergencyTracker(nil)
+			alert := &types.FluxAlert{}
+			alert.InvolvedObject.Kind = "Kustomization"
+			tracker.Track("r1", "", alert)
+
+			router := CreateRouter(&HandlerDependencies{
+				Config:         cfg,
+				PushoverClient: &MockPushoverClient{},
+				Logger:         logger,
+				MessageBuilder: BuildPushoverMessage,
+				Emergencies:    tracker,
+			})
+
+			req := httptest.NewRequest(tt.method, CallbackPath+"?token="+url.QueryEscape(tt.token), strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", types.ContentTypeForm)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if tt.expectedLog != "" && !contains(strings.Join(logger.messages, "\n"), tt.expectedLog) {
+				t.Errorf("Expected %q to be logged, got %v", tt.expectedLog, logger.messages)
+			}
+
+			acknowledged := tt.expectedStatus == http.StatusOK
+			if (tracker.Pending() == 0) != acknowledged {
+				t.Errorf("Expected acknowledged %v, got %d pending", acknowledged, tracker.Pending())
+			}
+		})
+	}
+}
+
+func TestCreateRouter_CallbackRequiresPublicURL(t *testing.T) {
+	router := CreateRouter(&HandlerDependencies{
+		Config:         &config.Config{PushoverAPIToken: "token"},
+		PushoverClient: &MockPushoverClient{},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+	})
+
+	req := httptest.NewRequest(http.MethodPost, CallbackPath, nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected the root handler's 400 without PUBLIC_URL, got %d", rr.Code)
+	}
+}
+
+func TestCreateWebhookHandler_TracksEmergencies(t *testing.T) {
+	var form url.Values
+	httpClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			form, _ = url.ParseQuery(string(body))
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"status":1,"request":"req","receipt":"r1"}`))}, nil
+		},
+	}
+	cfg := &config.Config{
+		PushoverAPIToken: "token",
+		BearerToken:      "Bearer token",
+		PublicURL:        "https://flux.example.com",
+		MetadataPrefix:   "pushover.",
+	}
+	tracker := NewEmergencyTracker(nil)
+	handler := CreateWebhookHandler(&HandlerDependencies{
+		Config:         cfg,
+		PushoverClient: pushover.NewPushoverClient(httpClient, "http://pushover.test/1/messages.json"),
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		Emergencies:    tracker,
+	})
+
+	body := `{"severity":"error","reason":"HealthCheckFailed","metadata":{"pushover.priority":"2"},"involvedObject":{"kind":"Kustomization","namespace":"apps","name":"apps"}}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer token")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+	}
+	if form.Get("callback") != CallbackURL(cfg) {
+		t.Errorf("Expected the callback URL on the emergency message, got %q", form.Get("callback"))
+	}
+	if object := tracker.Acknowledge("r1"); object != "Kustomization/apps/apps" {
+		t.Errorf("Expected the receipt to be tracked, got %q", object)
+	}
+}
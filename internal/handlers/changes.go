@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// stateKeyPrefix namespaces per-object state keys in a shared store
+const stateKeyPrefix = "flux-provider-pushover:state:"
+
+// StateKey identifies the object an alert is about (pure function)
+func StateKey(alert *types.FluxAlert) string {
+	obj := alert.InvolvedObject
+	return stateKeyPrefix + clusterScoped(alert, obj.Kind+"/"+obj.Namespace+"/"+obj.Name)
+}
+
+// AlertState fingerprints the severity and reason of alert, and its message
+// unless detection is CHANGE_DETECTION=reason (pure function)
+func AlertState(alert *types.FluxAlert, detection string) string {
+	severity, _ := NormalizeSeverity(alert.Severity)
+	hash := sha256.New()
+	hash.Write([]byte(severity))
+	hash.Write([]byte{0})
+	hash.Write([]byte(alert.Reason))
+	if detection != config.ChangeDetectionReason {
+		hash.Write([]byte{0})
+		hash.Write([]byte(alert.Message))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// checkStateChange reports whether alert changes its object's last delivered
+// state. It returns the state to record once the alert is delivered, empty
+// when change detection is disabled.
+func checkStateChange(deps *HandlerDependencies, alert *types.FluxAlert) (string, bool) {
+	if deps.State == nil || !deps.Config.NotifyOnChangeOnly {
+		return "", true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	state := AlertState(alert, deps.Config.ChangeDetection)
+	last, ok, err := deps.State.Get(ctx, StateKey(alert))
+	if err != nil {
+		// Never drop an alert because the store failed
+		deps.Logger.Printf("Failed to check object state: %v", err)
+		return state, true
+	}
+	return state, !ok || last != state
+}
+
+// recordState remembers the state of a delivered alert for STATE_TTL
+func recordState(deps *HandlerDependencies, alert *types.FluxAlert, state string) {
+	if state == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := deps.State.Set(ctx, StateKey(alert), state, deps.Config.StateTTL); err != nil {
+		deps.Logger.Printf("Failed to record object state: %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// ClockStore is an in-memory store.Store whose expiry follows a fake clock
+type ClockStore struct {
+	now     time.Time
+	values  map[string]string
+	expires map[string]time.Time
+	err     error
+}
+
+func NewClockStore() *ClockStore {
+	return &ClockStore{now: time.Unix(1700000000, 0), values: map[string]string{}, expires: map[string]time.Time{}}
+}
+
+func (s *ClockStore) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if _, ok, _ := s.Get(ctx, key); ok {
+		return false, nil
+	}
+	return true, s.Set(ctx, key, "1", ttl)
+}
+
+func (s *ClockStore) Get(ctx context.Context, key string) (string, bool, error) {
+	if s.err != nil {
+		return "", false, s.err
+	}
+	value, ok := s.values[key]
+	if expires, set := s.expires[key]; ok && set && !s.now.Before(expires) {
+		return "", false, nil
+	}
+	return value, ok, nil
+}
+
+func (s *ClockStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	s.values[key] = value
+	delete(s.expires, key)
+	if ttl > 0 {
+		s.expires[key] = s.now.Add(ttl)
+	}
+	return nil
+}
+
+func (s *ClockStore) Delete(ctx context.Context, key string) error {
+	delete(s.values, key)
+	delete(s.expires, key)
+	return nil
+}
+
+// unchangedResponse acknowledges an alert repeating its object's state
+var unchangedResponse = []byte(`{"status":"unchanged","rule":"NOTIFY_ON_CHANGE_ONLY","detail":"state unchanged since the last notification"}`)
+
+func TestAlertState(t *testing.T) {
+	base := &types.FluxAlert{Severity: "error", Reason: "HealthCheckFailed", Message: "timeout"}
+	otherMessage := &types.FluxAlert{Severity: "error", Reason: "HealthCheckFailed", Message: "timeout again"}
+	otherReason := &types.FluxAlert{Severity: "error", Reason: "ReconciliationFailed", Message: "timeout"}
+	sameSeverity := &types.FluxAlert{Severity: "ERROR", Reason: "HealthCheckFailed", Message: "timeout"}
+
+	if AlertState(base, config.ChangeDetectionMessage) == AlertState(otherMessage, config.ChangeDetectionMessage) {
+		t.Error("Expected message detection to tell messages apart")
+	}
+	if AlertState(base, config.ChangeDetectionReason) != AlertState(otherMessage, config.ChangeDetectionReason) {
+		t.Error("Expected reason detection to ignore the message")
+	}
+	if AlertState(base, config.ChangeDetectionReason) == AlertState(otherReason, config.ChangeDetectionReason) {
+		t.Error("Expected reason detection to tell reasons apart")
+	}
+	if AlertState(base, config.ChangeDetectionMessage) != AlertState(sameSeverity, config.ChangeDetectionMessage) {
+		t.Error("Expected severity to be compared case-insensitively")
+	}
+}
+
+func TestCreateWebhookHandler_NotifyOnChangeOnly(t *testing.T) {
+	type step struct {
+		advance  time.Duration // Clock change before the alert
+		reason   string
+		message  string
+		expected []byte
+	}
+
+	tests := []struct {
+		name      string
+		detection string
+		steps     []step
+	}{
+		{
+			name:      "first seen then repeat",
+			detection: config.ChangeDetectionMessage,
+			steps: []step{
+				{0, "HealthCheckFailed", "timeout", types.ResponseOK},
+				{time.Minute, "HealthCheckFailed", "timeout", unchangedResponse},
+			},
+		},
+		{
+			name:      "changed reason",
+			detection: config.ChangeDetectionMessage,
+			steps: []step{
+				{0, "HealthCheckFailed", "timeout", types.ResponseOK},
+				{0, "ReconciliationSucceeded", "timeout", types.ResponseOK},
+				{0, "HealthCheckFailed", "timeout", types.ResponseOK},
+			},
+		},
+		{
+			name:      "changed message",
+			detection: config.ChangeDetectionMessage,
+			steps: []step{
+				{0, "HealthCheckFailed", "timeout after 1m", types.ResponseOK},
+				{0, "HealthCheckFailed", "timeout after 2m", types.ResponseOK},
+			},
+		},
+		{
+			name:      "message ignored by reason detection",
+			detection: config.ChangeDetectionReason,
+			steps: []step{
+				{0, "HealthCheckFailed", "timeout after 1m", types.ResponseOK},
+				{0, "HealthCheckFailed", "timeout after 2m", unchangedResponse},
+			},
+		},
+		{
+			name:      "expiry then repeat",
+			detection: config.ChangeDetectionMessage,
+			steps: []step{
+				{0, "HealthCheckFailed", "timeout", types.ResponseOK},
+				{time.Hour - time.Second, "HealthCheckFailed", "timeout", unchangedResponse},
+				{time.Hour, "HealthCheckFailed", "timeout", types.ResponseOK},
+				{time.Minute, "HealthCheckFailed", "timeout", unchangedResponse},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := NewClockStore()
+			sent := 0
+			deps := &HandlerDependencies{
+				Config: &config.Config{
+					PushoverAPIToken:   "test_token",
+					BearerToken:        "Bearer test_token",
+					NotifyOnChangeOnly: true,
+					ChangeDetection:    tt.detection,
+					StateTTL:           time.Hour,
+				},
+				PushoverClient: &MockPushoverClient{
+					SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+						sent++
+						return nil
+					},
+				},
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+				State:          state,
+			}
+			handler := CreateWebhookHandler(deps)
+
+			delivered := 0
+			for i, step := range tt.steps {
+				state.now = state.now.Add(step.advance)
+
+				body := `{"severity":"error","reason":"` + step.reason + `","message":"` + step.message + `",` +
+					`"involvedObject":{"kind":"HelmRelease","namespace":"apps","name":"podinfo"}}`
+				req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+				req.Header.Set("Authorization", "Bearer test_token")
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+
+				if rr.Code != http.StatusOK || rr.Body.String() != string(step.expected) {
+					t.Errorf("Step %d: expected 200 %s, got %d %s", i, step.expected, rr.Code, rr.Body.String())
+				}
+				if string(step.expected) == string(types.ResponseOK) {
+					delivered++
+				}
+			}
+
+			if sent != delivered {
+				t.Errorf("Expected %d notifications, got %d", delivered, sent)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_NotifyOnChangeOnly_FailedSendNotRecorded(t *testing.T) {
+	sendErr := errors.New("pushover down")
+	state := NewClockStore()
+	deps := &HandlerDependencies{
+		Config: &config.Config{
+			PushoverAPIToken:   "test_token",
+			BearerToken:        "Bearer test_token",
+			NotifyOnChangeOnly: true,
+			StateTTL:           time.Hour,
+		},
+		PushoverClient: &MockPushoverClient{
+			SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+				return sendErr
+			},
+		},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		State:          state,
+	}
+	handler := CreateWebhookHandler(deps)
+
+	send := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"reason":"HealthCheckFailed"}`))
+		req.Header.Set("Authorization", "Bearer test_token")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := send(); code != http.StatusInternalServerError {
+		t.Fatalf("Expected failed send, got %d", code)
+	}
+
+	sendErr = nil
+	if code := send(); code != http.StatusOK || len(state.values) != 1 {
+		t.Errorf("Expected the retry to be delivered and recorded, got %d with %d states", code, len(state.values))
+	}
+}
+
+func TestCreateWebhookHandler_NotifyOnChangeOnly_StoreFailure(t *testing.T) {
+	state := NewClockStore()
+	state.err = errors.New("store unavailable")
+	deps := &HandlerDependencies{
+		Config:         &config.Config{PushoverAPIToken: "test_token", BearerToken: "Bearer test_token", NotifyOnChangeOnly: true},
+		PushoverClient: &MockPushoverClient{},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		State:          state,
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"reason":"HealthCheckFailed"}`))
+	req.Header.Set("Authorization", "Bearer test_token")
+	rr := httptest.NewRecorder()
+	CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+	if rr.Body.String() != string(types.ResponseOK) {
+		t.Errorf("Expected the alert to be delivered when the store fails, got %s", rr.Body.String())
+	}
+}
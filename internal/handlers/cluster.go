@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// ClusterHeader names the cluster an alert comes from, honoured only for
+// WEBHOOK_TOKENS entries with allow_cluster_header
+const ClusterHeader = "X-Cluster-Name"
+
+// Reasons a ClusterHeader is ignored
+var (
+	errClusterHeaderNotAllowed = errors.New(ClusterHeader + " is not allowed for the credential")
+	errClusterHeaderInvalid    = errors.New(ClusterHeader + " must be 1-63 letters, digits, '.', '_' or '-'")
+)
+
+// alertCluster returns the cluster alert comes from, CLUSTER_NAME unless
+// its webhook named another (pure function)
+func alertCluster(cfg *config.Config, alert *types.FluxAlert) string {
+	return defaultIfEmpty(alert.Cluster, cfg.ClusterName)
+}
+
+// clusterScoped prefixes key with the cluster its webhook named, so that
+// objects of the same kind, namespace and name in different clusters are
+// told apart. Keys of alerts without one are unchanged (pure function).
+func clusterScoped(alert *types.FluxAlert, key string) string {
+	if alert.Cluster == "" {
+		return key
+	}
+	return alert.Cluster + ":" + key
+}
+
+// inCluster returns the " in cluster <name>" suffix of log lines about
+// alert, empty without a cluster (pure function)
+func inCluster(cfg *config.Config, alert *types.FluxAlert) string {
+	cluster := alertCluster(cfg, alert)
+	if cluster == "" {
+		return ""
+	}
+	return " in cluster " + cluster
+}
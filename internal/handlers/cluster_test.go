@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/store"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+func TestCreateWebhookHandler_Cluster(t *testing.T) {
+	tests := []struct {
+		name            string
+		token           string
+		header          string
+		expectedCluster string
+	}{
+		{"trusted header", "edge-token", "edge-42", "edge-42"},
+		{"label of a trusted token", "edge-token", "", "edge"},
+		{"token label", "prod-token", "", "prod"},
+		{"spoofed header keeps the token label", "prod-token", "edge-42", "prod"},
+		{"CLUSTER_NAME", "plain-token", "", "central"},
+		{"spoofed header keeps CLUSTER_NAME", "plain-token", "prod", "central"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []string
+			registry := metrics.NewRegistry()
+			logger := &RecordingLogger{}
+			cfg := &config.Config{
+				PushoverAPIToken: "api-token",
+				WebhookTokens:    []string{"edge-token", "prod-token", "plain-token"},
+				WebhookTokenClusters: []config.WebhookTokenCluster{
+					{Name: "edge", AllowHeader: true},
+					{Name: "prod"},
+					{},
+				},
+				ClusterName: "central",
+			}
+			handler := CreateWebhookHandler(&HandlerDependencies{
+				Config: cfg,
+				PushoverClient: &MockPushoverClient{SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+					sent = append(sent, msg.Message)
+					return nil
+				}},
+				Logger:         logger,
+				MessageBuilder: NewMessageBuilder(MessageOptionsFromConfig(cfg)),
+				Metrics:        registry,
+			})
+
+			body := `{"severity":"error","message":"m","reason":"r","involvedObject":{"kind":"Kustomization","namespace":"flux-system","name":"apps"}}`
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			if tt.header != "" {
+				req.Header.Set(ClusterHeader, tt.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d", rr.Code)
+			}
+			if len(sent) != 1 || !strings.HasSuffix(sent[0], types.ClusterFooterPrefix+tt.expectedCluster) {
+				t.Errorf("Expected the %s footer, got %q", tt.expectedCluster, sent)
+			}
+			if count := registry.CounterVec("alerts_received_total", "", "cluster").WithLabelValues(tt.expectedCluster).Value(); count != 1 {
+				t.Errorf("Expected 1 alert counted for %s, got %d", tt.expectedCluster, count)
+			}
+			logs := strings.Join(logger.lines, "\n")
+			if !strings.Contains(logs, "Kustomization/apps in cluster "+tt.expectedCluster) {
+				t.Errorf("Expected the cluster logged, got %s", logs)
+			}
+			if spoofed := tt.header != "" && tt.expectedCluster != tt.header; spoofed != strings.Contains(logs, "Ignoring the cluster header") {
+				t.Errorf("Expected the ignored header logged %v, got %s", spoofed, logs)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_ClusterBatch(t *testing.T) {
+	var sent []string
+	cfg := &config.Config{
+		PushoverAPIToken:     "api-token",
+		WebhookTokens:        []string{"prod-token"},
+		WebhookTokenClusters: []config.WebhookTokenCluster{{Name: "prod"}},
+	}
+	handler := CreateWebhookHandler(&HandlerDependencies{
+		Config: cfg,
+		PushoverClient: &MockPushoverClient{SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			sent = append(sent, msg.Message)
+			return nil
+		}},
+		Logger:         &MockLogger{},
+		MessageBuilder: NewMessageBuilder(MessageOptionsFromConfig(cfg)),
+	})
+
+	body := `[{"severity":"info","message":"a","involvedObject":{"kind":"Kustomization","name":"a"}},` +
+		`{"severity":"info","message":"b","involvedObject":{"kind":"Kustomization","name":"b"}}]`
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer prod-token")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(sent) != 2 {
+		t.Fatalf("Expected both alerts sent, got %d", len(sent))
+	}
+	for _, message := range sent {
+		if !strings.HasSuffix(message, types.ClusterFooterPrefix+"prod") {
+			t.Errorf("Expected the prod footer, got %q", message)
+		}
+	}
+}
+
+func TestCreateWebhookHandler_ClustersDeduplicatedApart(t *testing.T) {
+	sent := 0
+	cfg := &config.Config{
+		PushoverAPIToken: "api-token",
+		WebhookTokens:    []string{"prod-token", "staging-token"},
+		WebhookTokenClusters: []config.WebhookTokenCluster{
+			{Name: "prod"},
+			{Name: "staging"},
+		},
+		DedupWindow: time.Minute,
+	}
+	handler := CreateWebhookHandler(&HandlerDependencies{
+		Config: cfg,
+		PushoverClient: &MockPushoverClient{SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			sent++
+			return nil
+		}},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		Dedup:          store.NewMemoryStore(),
+	})
+
+	// The same object exists in both clusters
+	for _, token := range []string{"prod-token", "staging-token", "prod-token"} {
+		body := `{"severity":"error","message":"m","reason":"r","involvedObject":{"kind":"Kustomization","namespace":"flux-system","name":"flux-system"}}`
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if sent != 2 {
+		t.Errorf("Expected one notification per cluster, got %d", sent)
+	}
+}
+
+func TestClusterScopedKeys(t *testing.T) {
+	alert := &types.FluxAlert{Severity: "error", Message: "m"}
+	alert.InvolvedObject.Kind = "Kustomization"
+	alert.InvolvedObject.Namespace = "flux-system"
+	alert.InvolvedObject.Name = "apps"
+
+	unscoped := []string{DedupKey(alert), StateKey(alert), ObjectKey(alert), emergencyObject(alert)}
+	if StateKey(alert) != "flux-provider-pushover:state:Kustomization/flux-system/apps" {
+		t.Errorf("Expected alerts without a cluster to keep their keys, got %q", StateKey(alert))
+	}
+
+	alert.Cluster = "prod"
+	for i, key := range []string{DedupKey(alert), StateKey(alert), ObjectKey(alert), emergencyObject(alert)} {
+		if key == unscoped[i] {
+			t.Errorf("Expected key %d scoped to the cluster, got %q", i, key)
+		}
+	}
+	if ObjectKey(alert) != "prod:flux-system/Kustomization/apps" {
+		t.Errorf("Unexpected object key %q", ObjectKey(alert))
+	}
+}
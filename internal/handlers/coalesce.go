@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// FlushFunc delivers the alerts collected for one object, in arrival order
+type FlushFunc func(alerts []*types.FluxAlert)
+
+// coalesceGroup collects the alerts of one object until its window closes
+type coalesceGroup struct {
+	alerts []*types.FluxAlert
+	flush  FlushFunc
+	stop   func() bool
+}
+
+// Coalescer merges alerts for the same object arriving within a window, so
+// that the handful of events of one failing reconciliation become a single
+// notification. Every object has its own window, started by its first alert.
+// A nil Coalescer coalesces nothing.
+type Coalescer struct {
+	window       time.Duration
+	bypassErrors bool
+	afterFunc    func(d time.Duration, f func()) (stop func() bool)
+
+	mu     sync.Mutex
+	groups map[string]*coalesceGroup
+	wg     sync.WaitGroup // One per pending or flushing group
+
+	coalesced *metrics.Counter
+}
+
+// NewCoalescer creates a coalescer with the given window. With bypassErrors,
+// alerts of error severity are never held back.
+func NewCoalescer(window time.Duration, bypassErrors bool, registry *metrics.Registry) *Coalescer {
+	return &Coalescer{
+		window:       window,
+		bypassErrors: bypassErrors,
+		afterFunc: func(d time.Duration, f func()) func() bool {
+			return time.AfterFunc(d, f).Stop
+		},
+		groups:    make(map[string]*coalesceGroup),
+		coalesced: registry.Counter("alerts_coalesced_total", "Alerts merged into the notification of an earlier alert for the same object"),
+	}
+}
+
+// Add holds alert back until its object's window closes and reports true, or
+// reports false when the caller should deliver alert itself. The flush of
+// the object's first alert delivers the whole group.
+func (c *Coalescer) Add(alert *types.FluxAlert, flush FlushFunc) bool {
+	if c == nil {
+		return false
+	}
+	if severity, _ := NormalizeSeverity(alert.Severity); c.bypassErrors && severity == types.SeverityError {
+		return false
+	}
+
+	// Pooled alerts are reused once the handler returns
+	held := *alert
+	key := ObjectKey(alert)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if group, ok := c.groups[key]; ok {
+		group.alerts = append(group.alerts, &held)
+		c.coalesced.Inc()
+		return true
+	}
+
+	group := &coalesceGroup{alerts: []*types.FluxAlert{&held}, flush: flush}
+	c.groups[key] = group
+	c.wg.Add(1)
+	group.stop = c.afterFunc(c.window, func() { c.expire(key, group) })
+	return true
+}
+
+// Pending returns the number of objects with alerts held back
+func (c *Coalescer) Pending() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.groups)
+}
+
+// Drain flushes every pending group immediately and waits for all flushes
+// to finish, for use on shutdown
+func (c *Coalescer) Drain(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	pending := make([]*coalesceGroup, 0, len(c.groups))
+	for key, group := range c.groups {
+		group.stop()
+		delete(c.groups, key)
+		pending = append(pending, group)
+	}
+	c.mu.Unlock()
+
+	for _, group := range pending {
+		group.flush(group.alerts)
+		c.wg.Done()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// expire flushes group when its window closes, unless Drain took it first
+func (c *Coalescer) expire(key string, group *coalesceGroup) {
+	c.mu.Lock()
+	if c.groups[key] != group {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.groups, key)
+	c.mu.Unlock()
+
+	group.flush(group.alerts)
+	c.wg.Done()
+}
+
+// ObjectKey identifies the object an alert is about (pure function)
+func ObjectKey(alert *types.FluxAlert) string {
+	obj := alert.InvolvedObject
+	return clusterScoped(alert, obj.Namespace+"/"+obj.Kind+"/"+obj.Name)
+}
+
+// buildCoalescedMessage lists the reason and message of every alert as a
+// bullet under the most severe one's header, followed by the details of the
+// object (pure function)
+func buildCoalescedMessage(alerts []*types.FluxAlert, opts MessageOptions) string {
+	lead := mostSevere(alerts)
+	severity, _ := NormalizeSeverity(lead.Severity)
+
+	var body strings.Builder
+	if opts.Prefix != "" {
+		body.WriteString(opts.Prefix)
+		body.WriteByte(' ')
+	}
+	body.WriteString(defaultIfEmpty(lead.Reason, types.DefaultValue))
+	body.WriteString(" [")
+	body.WriteString(strings.ToUpper(severity))
+	body.WriteString("]\n")
+	for _, alert := range alerts {
+		body.WriteString("• ")
+		body.WriteString(defaultIfEmpty(alert.Reason, types.DefaultValue))
+		body.WriteString(": ")
+		body.WriteString(defaultIfEmpty(alert.Message, types.NoMessage))
+		body.WriteByte('\n')
+	}
+
+	kind := defaultIfEmpty(lead.InvolvedObject.Kind, types.DefaultValue)
+	if !opts.PreserveKindCase {
+		kind = strings.ToLower(kind)
+	}
+	last := alerts[len(alerts)-1]
+	body.WriteString("\nController: ")
+	body.WriteString(defaultIfEmpty(lead.ReportingController, types.DefaultValue))
+	body.WriteString("\nObject: ")
+	body.WriteString(kind)
+	body.WriteByte('/')
+	body.WriteString(defaultIfEmpty(lead.InvolvedObject.Name, types.DefaultValue))
+	body.WriteString("\nRevision: ")
+	body.WriteString(displayRevision(last, opts))
+	body.WriteByte('\n')
+	if uid := lead.InvolvedObject.UID; opts.ShowUID && uid != "" {
+		body.WriteString("UID: ")
+		body.WriteString(uid)
+		body.WriteByte('\n')
+	}
+
+	return truncateMessage(body.String(), clusterFooter(opts, lead), types.MaxMessageLength)
+}
+
+// mostSevere returns the first alert of the highest severity (pure function)
+func mostSevere(alerts []*types.FluxAlert) *types.FluxAlert {
+	rank := map[string]int{types.SeverityInfo: 0, types.SeverityWarning: 1, types.SeverityError: 2}
+	lead := alerts[0]
+	for _, alert := range alerts[1:] {
+		severity, _ := NormalizeSeverity(alert.Severity)
+		leadSeverity, _ := NormalizeSeverity(lead.Severity)
+		if rank[severity] > rank[leadSeverity] {
+			lead = alert
+		}
+	}
+	return lead
+}
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// FakeTimers replaces time.AfterFunc, running timers only when fired
+type FakeTimers struct {
+	mu     sync.Mutex
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	delay   time.Duration
+	f       func()
+	stopped bool
+}
+
+func (c *FakeTimers) AfterFunc(d time.Duration, f func()) func() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timer := &fakeTimer{delay: d, f: f}
+	c.timers = append(c.timers, timer)
+	return func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		wasActive := !timer.stopped
+		timer.stopped = true
+		return wasActive
+	}
+}
+
+// Fire runs every pending timer, as if the window closed
+func (c *FakeTimers) Fire() {
+	c.mu.Lock()
+	var due []*fakeTimer
+	for _, timer := range c.timers {
+		if !timer.stopped {
+			timer.stopped = true
+			due = append(due, timer)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, timer := range due {
+		timer.f()
+	}
+}
+
+func newTestCoalescer(bypassErrors bool, registry *metrics.Registry) (*Coalescer, *FakeTimers) {
+	timers := &FakeTimers{}
+	coalescer := NewCoalescer(5*time.Second, bypassErrors, registry)
+	coalescer.afterFunc = timers.AfterFunc
+	return coalescer, timers
+}
+
+func newCoalesceAlert(severity, kind, name, reason string) *types.FluxAlert {
+	alert := &types.FluxAlert{Severity: severity, Reason: reason, Message: strings.ToLower(reason)}
+	alert.InvolvedObject.Kind = kind
+	alert.InvolvedObject.Namespace = "apps"
+	alert.InvolvedObject.Name = name
+	return alert
+}
+
+// FlushRecorder records the groups flushed by a Coalescer
+type FlushRecorder struct {
+	mu     sync.Mutex
+	groups [][]string
+}
+
+func (f *FlushRecorder) Flush(alerts []*types.FluxAlert) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	reasons := make([]string, len(alerts))
+	for i, alert := range alerts {
+		reasons[i] = alert.Reason
+	}
+	f.groups = append(f.groups, reasons)
+}
+
+func (f *FlushRecorder) Groups() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.groups
+}
+
+func TestCoalescer_MergesPerObject(t *testing.T) {
+	registry := metrics.NewRegistry()
+	coalescer, timers := newTestCoalescer(false, registry)
+	recorder := &FlushRecorder{}
+
+	for _, alert := range []*types.FluxAlert{
+		newCoalesceAlert("error", "Kustomization", "apps", "HealthCheckFailed"),
+		newCoalesceAlert("info", "HelmRelease", "podinfo", "UpgradeSucceeded"),
+		newCoalesceAlert("error", "Kustomization", "apps", "ReconciliationFailed"),
+		newCoalesceAlert("error", "Kustomization", "apps", "DependencyNotReady"),
+	} {
+		if !coalescer.Add(alert, recorder.Flush) {
+			t.Fatalf("Expected %s to be held back", alert.Reason)
+		}
+	}
+
+	if coalescer.Pending() != 2 || len(recorder.Groups()) != 0 {
+		t.Fatalf("Expected 2 pending objects and no flush before the window closes, got %d and %v", coalescer.Pending(), recorder.Groups())
+	}
+
+	timers.Fire()
+
+	groups := recorder.Groups()
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 flushed groups, got %v", groups)
+	}
+	merged := map[string]bool{}
+	for _, group := range groups {
+		merged[strings.Join(group, ",")] = true
+	}
+	if !merged["HealthCheckFailed,ReconciliationFailed,DependencyNotReady"] || !merged["UpgradeSucceeded"] {
+		t.Errorf("Unexpected groups %v", groups)
+	}
+
+	if coalesced := registry.Counter("alerts_coalesced_total", "").Value(); coalesced != 2 {
+		t.Errorf("Expected 2 coalesced alerts, got %d", coalesced)
+	}
+}
+
+func TestCoalescer_WindowExpiry(t *testing.T) {
+	coalescer, timers := newTestCoalescer(false, nil)
+	recorder := &FlushRecorder{}
+
+	coalescer.Add(newCoalesceAlert("info", "Kustomization", "apps", "First"), recorder.Flush)
+	timers.Fire()
+	coalescer.Add(newCoalesceAlert("info", "Kustomization", "apps", "Second"), recorder.Flush)
+
+	if groups := recorder.Groups(); len(groups) != 1 || groups[0][0] != "First" {
+		t.Fatalf("Expected only the first window flushed, got %v", groups)
+	}
+
+	timers.Fire()
+	if groups := recorder.Groups(); len(groups) != 2 || groups[1][0] != "Second" {
+		t.Errorf("Expected an alert after the window to start a new group, got %v", groups)
+	}
+	if coalescer.Pending() != 0 {
+		t.Errorf("Expected no pending groups, got %d", coalescer.Pending())
+	}
+
+	for _, timer := range timers.timers {
+		if timer.delay != 5*time.Second {
+			t.Errorf("Expected a 5s window, got %s", timer.delay)
+		}
+	}
+}
+
+func TestCoalescer_BypassErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		bypassErrors bool
+		severity     string
+		held         bool
+	}{
+		{"error bypasses", true, "error", false},
+		{"error bypasses case-insensitively", true, "ERROR", false},
+		{"info is still held", true, "info", true},
+		{"error held without bypass", false, "error", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coalescer, _ := newTestCoalescer(tt.bypassErrors, nil)
+			held := coalescer.Add(newCoalesceAlert(tt.severity, "Kustomization", "apps", "Failed"), (&FlushRecorder{}).Flush)
+			if held != tt.held {
+				t.Errorf("Expected held %v, got %v", tt.held, held)
+			}
+		})
+	}
+}
+
+func TestCoalescer_DrainFlushesPending(t *testing.T) {
+	coalescer, timers := newTestCoalescer(false, nil)
+	recorder := &FlushRecorder{}
+
+	coalescer.Add(newCoalesceAlert("info", "Kustomization", "apps", "First"), recorder.Flush)
+	coalescer.Add(newCoalesceAlert("info", "Kustomization", "apps", "Second"), recorder.Flush)
+
+	if err := coalescer.Drain(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if groups := recorder.Groups(); len(groups) != 1 || len(groups[0]) != 2 {
+		t.Fatalf("Expected the pending group to be flushed on drain, got %v", groups)
+	}
+
+	// A timer racing with the drain never flushes twice
+	for _, timer := range timers.timers {
+		timer.f()
+	}
+	if groups := recorder.Groups(); len(groups) != 1 {
+		t.Errorf("Expected a single flush, got %v", groups)
+	}
+}
+
+func TestCoalescer_Nil(t *testing.T) {
+	var coalescer *Coalescer
+	if coalescer.Add(&types.FluxAlert{}, nil) {
+		t.Error("Expected nil coalescer to hold nothing back")
+	}
+	if err := coalescer.Drain(context.Background()); err != nil || coalescer.Pending() != 0 {
+		t.Errorf("Expected nil coalescer to drain cleanly, got %v", err)
+	}
+}
+
+func TestBuildCoalescedMessage(t *testing.T) {
+	alerts := []*types.FluxAlert{
+		newCoalesceAlert("info", "Kustomization", "apps", "Progressing"),
+		newCoalesceAlert("error", "Kustomization", "apps", "HealthCheckFailed"),
+		newCoalesceAlert("error", "Kustomization", "apps", "ReconciliationFailed"),
+	}
+	alerts[2].Metadata = map[string]string{"revision": "main@sha1:abc"}
+
+	expected := "[PROD] HealthCheckFailed [ERROR]\n" +
+		"• Progressing: progressing\n" +
+		"• HealthCheckFailed: healthcheckfailed\n" +
+		"• ReconciliationFailed: reconciliationfailed\n" +
+		"\nController: Unknown\nObject: kustomization/apps\nRevision: main@sha1:abc\n" +
+		"— cluster: prod"
+
+	message := buildCoalescedMessage(alerts, MessageOptions{Prefix: "[PROD]", ClusterName: "prod"})
+	if message != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, message)
+	}
+
+	// The UID is the lead's, all alerts share the object
+	alerts[1].InvolvedObject.UID = "3c2e5f1a"
+	message = buildCoalescedMessage(alerts, MessageOptions{ShowUID: true})
+	if !strings.HasSuffix(message, "Revision: main@sha1:abc\nUID: 3c2e5f1a\n") {
+		t.Errorf("Expected UID line, got:\n%s", message)
+	}
+	// SHORTEN_REVISION applies to the last alert's revision
+	alerts[2].Metadata["revision"] = "main@sha1:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b"
+	message = buildCoalescedMessage(alerts, MessageOptions{ShortenRevision: true})
+	if !strings.HasSuffix(message, "Revision: 9f86d08\n") {
+		t.Errorf("Expected short SHA revision, got:\n%s", message)
+	}
+}
+
+func TestCreateWebhookHandler_Coalesce(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	coalescer, timers := newTestCoalescer(true, nil)
+	deps := &HandlerDependencies{
+		Config: &config.Config{PushoverAPIToken: "test_token", BearerToken: "Bearer test_token"},
+		PushoverClient: &MockPushoverClient{
+			SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+				mu.Lock()
+				defer mu.Unlock()
+				sent = append(sent, msg.Message)
+				return nil
+			},
+		},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		Coalescer:      coalescer,
+	}
+	handler := CreateWebhookHandler(deps)
+
+	post := func(severity, reason string) *httptest.ResponseRecorder {
+		body := `{"severity":"` + severity + `","reason":"` + reason + `","involvedObject":{"kind":"Kustomization","namespace":"apps","name":"apps"}}`
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test_token")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for _, reason := range []string{"Progressing", "DependencyNotReady"} {
+		if rr := post("info", reason); rr.Code != http.StatusAccepted || rr.Body.String() != string(types.ResponseQueued) {
+			t.Errorf("Expected 202 queued, got %d %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	// Errors bypass the window and are answered as usual
+	if rr := post("error", "HealthCheckFailed"); rr.Code != http.StatusOK {
+		t.Errorf("Expected error alert to be sent immediately, got %d", rr.Code)
+	}
+
+	timers.Fire()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 2 {
+		t.Fatalf("Expected 2 notifications, got %q", sent)
+	}
+	if !strings.HasPrefix(sent[0], "HealthCheckFailed [ERROR]") {
+		t.Errorf("Expected the error first, got %q", sent[0])
+	}
+	if !strings.Contains(sent[1], "• Progressing: No Message\n• DependencyNotReady: No Message\n") {
+		t.Errorf("Expected merged bullets, got %q", sent[1])
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// corsMaxAge is how long browsers may cache a preflight, in seconds
+const corsMaxAge = 600
+
+// corsAllowedHeaders are the request headers a browser may send to the webhook
+var corsAllowedHeaders = strings.Join([]string{
+	"Authorization",
+	"Content-Type",
+	IdempotencyKeyHeader,
+	XIdempotencyKeyHeader,
+	RequestIDHeader,
+	TimestampHeader,
+}, ", ")
+
+// IsAllowedOrigin reports whether origin is one of ALLOWED_ORIGINS, which
+// are lowercased, or they contain "*" (pure function)
+func IsAllowedOrigin(origin string, allowed []string) bool {
+	if origin == "" {
+		return false
+	}
+	return slices.Contains(allowed, "*") || slices.Contains(allowed, strings.ToLower(origin))
+}
+
+// handleCORS sets the CORS headers of a request from an allowed origin and
+// answers its preflight, reporting whether the request was answered.
+// Without ALLOWED_ORIGINS no CORS headers are set and OPTIONS is left to
+// the method check.
+func handleCORS(w http.ResponseWriter, r *http.Request, allowed []string) bool {
+	if len(allowed) == 0 {
+		return false
+	}
+
+	origin := r.Header.Get("Origin")
+	w.Header().Add("Vary", "Origin")
+	if !IsAllowedOrigin(origin, allowed) {
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusForbidden)
+			return true
+		}
+		return false
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if r.Method != http.MethodOptions {
+		return false
+	}
+
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+	w.Header().Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
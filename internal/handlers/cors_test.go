@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+)
+
+func TestIsAllowedOrigin(t *testing.T) {
+	tests := []struct {
+		name     string
+		origin   string
+		allowed  []string
+		expected bool
+	}{
+		{"listed", "https://dashboard.example.com", []string{"https://dashboard.example.com"}, true},
+		{"case-insensitive", "https://Dashboard.Example.com", []string{"https://dashboard.example.com"}, true},
+		{"other origin", "https://evil.example.com", []string{"https://dashboard.example.com"}, false},
+		{"other port", "https://dashboard.example.com:8443", []string{"https://dashboard.example.com"}, false},
+		{"wildcard", "https://evil.example.com", []string{"*"}, true},
+		{"no origin", "", []string{"*"}, false},
+		{"unconfigured", "https://dashboard.example.com", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsAllowedOrigin(tt.origin, tt.allowed); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_CORS(t *testing.T) {
+	allowed := []string{"https://dashboard.example.com"}
+
+	tests := []struct {
+		name           string
+		allowed        []string
+		method         string
+		origin         string
+		expectedStatus int
+		expectedOrigin string
+	}{
+		{"preflight from an allowed origin", allowed, http.MethodOptions, "https://dashboard.example.com", http.StatusNoContent, "https://dashboard.example.com"},
+		{"preflight from a disallowed origin", allowed, http.MethodOptions, "https://evil.example.com", http.StatusForbidden, ""},
+		{"preflight without origin", allowed, http.MethodOptions, "", http.StatusForbidden, ""},
+		{"preflight with any origin allowed", []string{"*"}, http.MethodOptions, "https://evil.example.com", http.StatusNoContent, "https://evil.example.com"},
+		{"unconfigured", nil, http.MethodOptions, "https://dashboard.example.com", http.StatusMethodNotAllowed, ""},
+		{"post from an allowed origin", allowed, http.MethodPost, "https://dashboard.example.com", http.StatusOK, "https://dashboard.example.com"},
+		{"post from a disallowed origin", allowed, http.MethodPost, "https://evil.example.com", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := &HandlerDependencies{
+				Config:         &config.Config{PushoverAPIToken: "test_token", BearerToken: "Bearer test_token", AllowedOrigins: tt.allowed},
+				PushoverClient: &MockPushoverClient{},
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+			}
+
+			req := httptest.NewRequest(tt.method, "/webhook", strings.NewReader(`{"message":"deployed"}`))
+			req.Header.Set("Authorization", "Bearer test_token")
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			rr := httptest.NewRecorder()
+			CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if origin := rr.Header().Get("Access-Control-Allow-Origin"); origin != tt.expectedOrigin {
+				t.Errorf("Expected Access-Control-Allow-Origin %q, got %q", tt.expectedOrigin, origin)
+			}
+
+			preflight := tt.method == http.MethodOptions && tt.expectedStatus == http.StatusNoContent
+			methods := rr.Header().Get("Access-Control-Allow-Methods")
+			headers := rr.Header().Get("Access-Control-Allow-Headers")
+			if preflight != (methods == "POST, OPTIONS") || preflight != strings.Contains(headers, "Authorization") {
+				t.Errorf("Unexpected preflight headers, methods %q and headers %q", methods, headers)
+			}
+			if vary := rr.Header().Get("Vary"); (tt.allowed != nil) != (vary == "Origin") {
+				t.Errorf("Unexpected Vary %q", vary)
+			}
+		})
+	}
+}
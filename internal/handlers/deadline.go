@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+)
+
+// Margins of the send budget within a request's deadline
+const (
+	deadlineMargin = 500 * time.Millisecond // Left to write the response once a send ends
+	minSendBudget  = 250 * time.Millisecond // Shorter budgets fail without sending
+)
+
+// errDeadlineBudget is returned for alerts with too little of the request
+// deadline left to send them
+var errDeadlineBudget = errors.New("too little of REQUEST_DEADLINE left to send")
+
+// sendBudget returns how long a send may take: timeout, shortened to end
+// deadlineMargin before the request's deadline unless that is zero. It
+// reports false when less than minSendBudget is left, a send that would be
+// cut off by the server tearing down the connection (pure function).
+func sendBudget(timeout time.Duration, deadline, now time.Time) (time.Duration, bool) {
+	if deadline.IsZero() {
+		return timeout, true
+	}
+	budget := min(timeout, deadline.Sub(now)-deadlineMargin)
+	return budget, budget >= minSendBudget
+}
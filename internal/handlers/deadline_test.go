@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+func TestSendBudget(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name           string
+		timeout        time.Duration
+		deadline       time.Time
+		expectedBudget time.Duration
+		expectedOK     bool
+	}{
+		{"no deadline", 10 * time.Second, time.Time{}, 10 * time.Second, true},
+		{"deadline far off", 10 * time.Second, now.Add(20 * time.Second), 10 * time.Second, true},
+		{"deadline near", 10 * time.Second, now.Add(3 * time.Second), 3*time.Second - deadlineMargin, true},
+		{"just enough", 10 * time.Second, now.Add(deadlineMargin + minSendBudget), minSendBudget, true},
+		{"too little left", 10 * time.Second, now.Add(deadlineMargin + minSendBudget - time.Millisecond), minSendBudget - time.Millisecond, false},
+		{"deadline passed", 10 * time.Second, now.Add(-time.Second), -time.Second - deadlineMargin, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget, ok := sendBudget(tt.timeout, tt.deadline, now)
+			if budget != tt.expectedBudget || ok != tt.expectedOK {
+				t.Errorf("Expected %v %v, got %v %v", tt.expectedBudget, tt.expectedOK, budget, ok)
+			}
+		})
+	}
+}
+
+func TestServer_SlowPushoverAnswers503(t *testing.T) {
+	tests := []struct {
+		name          string
+		deadline      time.Duration
+		expectedSends int32
+	}{
+		// The send is cut short before the deadline, in time to answer
+		{"send cut short", 1500 * time.Millisecond, 1},
+		// Too little is left for a send to succeed, none is started
+		{"budget too short", 700 * time.Millisecond, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sends atomic.Int32
+			slow := &MockPushoverClient{
+				SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+					sends.Add(1)
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case <-time.After(30 * time.Second):
+						return nil
+					}
+				},
+			}
+			cfg := &config.Config{
+				Port:              "127.0.0.1:0",
+				PushoverAPIToken:  "token",
+				BearerToken:       "Bearer token",
+				PushoverTimeout:   10 * time.Second,
+				RequestDeadline:   tt.deadline,
+				RetryAfterSeconds: 30,
+			}
+			srv := server.NewServer(cfg, CreateRouter(&HandlerDependencies{
+				Config:         cfg,
+				PushoverClient: slow,
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+			}), &MockLogger{})
+			if err := srv.Start(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer func() { _ = srv.Shutdown(context.Background()) }()
+
+			body := `{"severity":"error","message":"failed","involvedObject":{"kind":"Kustomization","name":"apps"}}`
+			req, _ := http.NewRequest(http.MethodPost, "http://"+srv.Addr().String()+"/webhook", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer token")
+			start := time.Now()
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Expected a response instead of a torn down connection, got %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "30" {
+				t.Errorf("Expected 503 with Retry-After, got %d %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+			}
+			var response types.SendErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&response); err != nil || response.Code != codeDeadline {
+				t.Errorf("Expected the %s code, got %+v (%v)", codeDeadline, response, err)
+			}
+			if elapsed := time.Since(start); elapsed > tt.deadline {
+				t.Errorf("Expected an answer within the %v deadline, took %v", tt.deadline, elapsed)
+			}
+			if got := sends.Load(); got != tt.expectedSends {
+				t.Errorf("Expected %d sends, got %d", tt.expectedSends, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/notify"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// Outcomes of the checks run before an alert is sent
+const (
+	OutcomeDeliver     = "deliver"
+	OutcomeSuppressed  = "suppressed"
+	OutcomeUnchanged   = "unchanged"
+	OutcomeRateLimited = "rate_limited"
+	OutcomeFiltered    = "filtered"
+	OutcomeShed        = "shed"
+)
+
+// Outcomes of delivered alerts, recorded in the audit log
+const (
+	OutcomeSent     = "sent"
+	OutcomeFailed   = "failed"
+	OutcomeQueued   = "queued"
+	OutcomeTestMode = "test_mode"
+)
+
+// Decision tells whether an alert is sent and, if not, which setting
+// dropped it and why. It is the 200 response body of dropped alerts.
+type Decision struct {
+	Outcome string `json:"status"`
+	Rule    string `json:"rule,omitempty"`   // Setting that dropped the alert, e.g. DEDUP_WINDOW
+	Detail  string `json:"detail,omitempty"` // Why the rule matched
+}
+
+// Delivered reports whether the alert passed every check
+func (d Decision) Delivered() bool {
+	return d.Outcome == OutcomeDeliver
+}
+
+// alertChecks is the decision on an alert with the bookkeeping needed once
+// it has been sent
+type alertChecks struct {
+	Decision
+	dedupKey string // Claim released when the send fails
+	state    string // Object state recorded when the send succeeds
+	quiet    bool   // Sent without sound during quiet hours
+	// Sent without sound during a maintenance window with MAINTENANCE_MODE=downgrade
+	maintenanceDowngraded bool
+}
+
+// decide runs the checks that may drop alert, in order: the kind filter,
+// quiet hours, maintenance windows, deduplication, change detection, severity transitions and the
+// namespace rate limit.
+// Rate limited alerts release their dedup claim, so that a later identical
+// alert is not suppressed.
+func decide(deps *HandlerDependencies, alert *types.FluxAlert) alertChecks {
+	// Drop kinds nobody wants to hear about
+	if rule, filtered := filterKind(deps.Config, alert.InvolvedObject.Kind); filtered {
+		return alertChecks{Decision: Decision{
+			Outcome: OutcomeFiltered,
+			Rule:    rule,
+			Detail:  fmt.Sprintf("kind %q is filtered", alert.InvolvedObject.Kind),
+		}}
+	}
+
+	// Quiet non-error alerts during quiet hours, before they claim anything
+	quiet := deps.QuietHours.Quiets(alert)
+	if quiet && deps.QuietHours.Suppresses() {
+		return alertChecks{Decision: Decision{
+			Outcome: OutcomeSuppressed,
+			Rule:    "QUIET_HOURS",
+			Detail:  deps.QuietHours.Detail(),
+		}}
+	}
+
+	// Drop alerts about objects under planned maintenance, or quiet them
+	maintenanceDowngraded := false
+	if until, ok := deps.Maintenance.ActiveFor(alert.InvolvedObject.Namespace, alert.InvolvedObject.Kind); ok {
+		if deps.Config.MaintenanceMode != config.MaintenanceDowngrade {
+			return alertChecks{Decision: Decision{
+				Outcome: OutcomeSuppressed,
+				Rule:    "MAINTENANCE_WINDOWS",
+				Detail:  "maintenance window until " + until.Format(time.RFC3339),
+			}}
+		}
+		maintenanceDowngraded = true
+	}
+
+	// Suppress alerts already delivered within the dedup window
+	dedupKey, duplicate := claimAlert(deps, alert)
+	if duplicate {
+		return alertChecks{Decision: Decision{
+			Outcome: OutcomeSuppressed,
+			Rule:    "DEDUP_WINDOW",
+			Detail:  fmt.Sprintf("identical alert sent within %s", deps.Config.DedupWindow),
+		}}
+	}
+
+	// Acknowledge alerts repeating their object's last delivered state
+	state, changed := checkStateChange(deps, alert)
+	if !changed {
+		return alertChecks{Decision: Decision{
+			Outcome: OutcomeUnchanged,
+			Rule:    "NOTIFY_ON_CHANGE_ONLY",
+			Detail:  "state unchanged since the last notification",
+		}}
+	}
+
+	// Acknowledge alerts repeating their object's last delivered severity
+	if last, transition := deps.Transitions.Transitions(alert); !transition {
+		detail := "success of an object not known to fail"
+		if last != "" {
+			detail = fmt.Sprintf("severity still %s since the last notification", last)
+		}
+		return alertChecks{Decision: Decision{
+			Outcome: OutcomeUnchanged,
+			Rule:    "STATEFUL_NOTIFY",
+			Detail:  detail,
+		}}
+	}
+
+	// Drop alerts of namespaces over their rate, the sender must not retry them
+	if !deps.RateLimiter.Allow(alert.InvolvedObject.Namespace) {
+		releaseAlert(deps, dedupKey)
+		return alertChecks{Decision: Decision{
+			Outcome: OutcomeRateLimited,
+			Rule:    "PER_NAMESPACE_RATE",
+			Detail:  fmt.Sprintf("namespace %q over %g alerts a minute", alert.InvolvedObject.Namespace, deps.RateLimiter.PerMinute()),
+		}}
+	}
+
+	return alertChecks{
+		Decision:              Decision{Outcome: OutcomeDeliver},
+		dedupKey:              dedupKey,
+		state:                 state,
+		quiet:                 quiet,
+		maintenanceDowngraded: maintenanceDowngraded,
+	}
+}
+
+// silence marks n to be sent without sound for quiet hours or a maintenance
+// downgrade. Only quiet hours make it Quiet, expiring after QUIET_TTL.
+func silence(n *notify.Notification, quiet, maintenanceDowngraded bool) {
+	n.Silent = quiet || maintenanceDowngraded
+	n.Quiet = quiet
+}
+
+// maintenanceDowngrades reports whether alert is sent silently because a
+// maintenance window is in progress with MAINTENANCE_MODE=downgrade
+func (d *HandlerDependencies) maintenanceDowngrades(alert *types.FluxAlert) bool {
+	if d.Config.MaintenanceMode != config.MaintenanceDowngrade {
+		return false
+	}
+	_, ok := d.Maintenance.ActiveFor(alert.InvolvedObject.Namespace, alert.InvolvedObject.Kind)
+	return ok
+}
+
+// filterKind reports whether alerts about kind are dropped and by which
+// setting. INCLUDE_KINDS takes precedence, EXCLUDE_KINDS is ignored when it
+// is set. Kinds match case-insensitively (pure function).
+func filterKind(cfg *config.Config, kind string) (string, bool) {
+	kind = strings.ToLower(kind)
+	if len(cfg.IncludeKinds) > 0 {
+		return "INCLUDE_KINDS", !slices.Contains(cfg.IncludeKinds, kind)
+	}
+	return "EXCLUDE_KINDS", slices.Contains(cfg.ExcludeKinds, kind)
+}
+
+// decisionResponse renders a decision as a response body (pure function)
+func decisionResponse(d Decision) []byte {
+	body, err := json.Marshal(d)
+	if err != nil {
+		return types.ResponseInternalError
+	}
+	return body
+}
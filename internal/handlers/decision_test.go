@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/ratelimit"
+	"github.com/zhorvath83/flux-provider-pushover/internal/store"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+func TestCreateWebhookHandler_Decisions(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(deps *HandlerDependencies)
+		expected Decision
+	}{
+		{
+			name: "duplicate",
+			setup: func(deps *HandlerDependencies) {
+				deps.Config.DedupWindow = time.Minute
+				deps.Dedup = store.NewMemoryStore()
+			},
+			expected: Decision{Outcome: OutcomeSuppressed, Rule: "DEDUP_WINDOW", Detail: "identical alert sent within 1m0s"},
+		},
+		{
+			name: "unchanged",
+			setup: func(deps *HandlerDependencies) {
+				deps.Config.NotifyOnChangeOnly = true
+				deps.Config.StateTTL = time.Hour
+				deps.State = store.NewMemoryStore()
+			},
+			expected: Decision{Outcome: OutcomeUnchanged, Rule: "NOTIFY_ON_CHANGE_ONLY", Detail: "state unchanged since the last notification"},
+		},
+		{
+			name: "rate limited",
+			setup: func(deps *HandlerDependencies) {
+				deps.RateLimiter = ratelimit.NewKeyedLimiter(1, 10, nil)
+			},
+			expected: Decision{Outcome: OutcomeRateLimited, Rule: "PER_NAMESPACE_RATE", Detail: `namespace "apps" over 1 alerts a minute`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := metrics.NewRegistry()
+			logger := &MockLogger{}
+			sent := 0
+			deps := &HandlerDependencies{
+				Config: &config.Config{PushoverAPIToken: "test_token", BearerToken: "Bearer test_token"},
+				PushoverClient: &MockPushoverClient{
+					SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+						sent++
+						return nil
+					},
+				},
+				Logger:         logger,
+				MessageBuilder: BuildPushoverMessage,
+				Metrics:        registry,
+			}
+			tt.setup(deps)
+			handler := CreateWebhookHandler(deps)
+
+			post := func() *httptest.ResponseRecorder {
+				body := `{"reason":"HealthCheckFailed","involvedObject":{"kind":"Kustomization","namespace":"apps","name":"apps"}}`
+				req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+				req.Header.Set("Authorization", "Bearer test_token")
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+				return rr
+			}
+
+			if rr := post(); rr.Body.String() != string(types.ResponseOK) {
+				t.Fatalf("Expected the first alert to be sent, got %d %s", rr.Code, rr.Body.String())
+			}
+
+			rr := post()
+			if rr.Code != http.StatusOK {
+				t.Errorf("Expected status 200, got %d", rr.Code)
+			}
+			var decision Decision
+			if err := json.Unmarshal(rr.Body.Bytes(), &decision); err != nil {
+				t.Fatalf("Expected a decision body, got %s", rr.Body.String())
+			}
+			if decision != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, decision)
+			}
+
+			if sent != 1 {
+				t.Errorf("Expected a single notification, got %d", sent)
+			}
+			if dropped := registry.CounterVec("alerts_dropped_total", "", "outcome", "rule").WithLabelValues(tt.expected.Outcome, tt.expected.Rule).Value(); dropped != 1 {
+				t.Errorf("Expected 1 dropped alert, got %d", dropped)
+			}
+			if !contains(strings.Join(logger.messages, "\n"), "Dropped alert for %s/%s/%s%s: %s by %s, %s") {
+				t.Errorf("Expected the decision to be logged, got %v", logger.messages)
+			}
+		})
+	}
+}
+
+func TestFilterKind(t *testing.T) {
+	tests := []struct {
+		name         string
+		include      []string
+		exclude      []string
+		kind         string
+		expectedRule string
+		filtered     bool
+	}{
+		{"no lists", nil, nil, "Kustomization", "EXCLUDE_KINDS", false},
+		{"include only, listed", []string{"helmrelease"}, nil, "HelmRelease", "INCLUDE_KINDS", false},
+		{"include only, not listed", []string{"helmrelease"}, nil, "Kustomization", "INCLUDE_KINDS", true},
+		{"exclude only, listed", nil, []string{"kustomization"}, "Kustomization", "EXCLUDE_KINDS", true},
+		{"exclude only, not listed", nil, []string{"kustomization"}, "HelmRelease", "EXCLUDE_KINDS", false},
+		{"both, include wins", []string{"kustomization"}, []string{"kustomization"}, "Kustomization", "INCLUDE_KINDS", false},
+		{"both, not included", []string{"helmrelease"}, []string{"gitrepository"}, "Kustomization", "INCLUDE_KINDS", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, filtered := filterKind(&config.Config{IncludeKinds: tt.include, ExcludeKinds: tt.exclude}, tt.kind)
+			if filtered != tt.filtered || (filtered && rule != tt.expectedRule) {
+				t.Errorf("Expected filtered %v by %s, got %v by %s", tt.filtered, tt.expectedRule, filtered, rule)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_FilteredKind(t *testing.T) {
+	sent := 0
+	deps := &HandlerDependencies{
+		Config: &config.Config{PushoverAPIToken: "test_token", BearerToken: "Bearer test_token", ExcludeKinds: []string{"kustomization"}},
+		PushoverClient: &MockPushoverClient{
+			SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+				sent++
+				return nil
+			},
+		},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+	}
+	handler := CreateWebhookHandler(deps)
+
+	post := func(kind string) *httptest.ResponseRecorder {
+		body := `{"reason":"Progressing","involvedObject":{"kind":"` + kind + `","namespace":"apps","name":"apps"}}`
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test_token")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := post("Kustomization")
+	var decision Decision
+	if err := json.Unmarshal(rr.Body.Bytes(), &decision); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected a 200 decision, got %d %s", rr.Code, rr.Body.String())
+	}
+	expected := Decision{Outcome: OutcomeFiltered, Rule: "EXCLUDE_KINDS", Detail: `kind "Kustomization" is filtered`}
+	if decision != expected {
+		t.Errorf("Expected %+v, got %+v", expected, decision)
+	}
+
+	if rr := post("HelmRelease"); rr.Body.String() != string(types.ResponseOK) {
+		t.Errorf("Expected other kinds to be sent, got %d %s", rr.Code, rr.Body.String())
+	}
+	if sent != 1 {
+		t.Errorf("Expected only the HelmRelease alert to be sent, got %d", sent)
+	}
+}
+
+func TestDecisionResponse(t *testing.T) {
+	tests := []struct {
+		decision Decision
+		expected string
+	}{
+		{
+			Decision{Outcome: OutcomeSuppressed, Rule: "DEDUP_WINDOW", Detail: "identical alert sent within 1m0s"},
+			`{"status":"suppressed","rule":"DEDUP_WINDOW","detail":"identical alert sent within 1m0s"}`,
+		},
+		{Decision{Outcome: OutcomeDeliver}, `{"status":"deliver"}`},
+	}
+
+	for _, tt := range tests {
+		if body := string(decisionResponse(tt.decision)); body != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, body)
+		}
+	}
+}
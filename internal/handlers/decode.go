@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// Errors of webhook payloads that never make it to decoding an alert
+var (
+	ErrSyntax   = errors.New("malformed JSON")
+	ErrTooLarge = errors.New("payload too large")
+)
+
+// UnknownFieldError is returned for a payload field the alert schema lacks
+type UnknownFieldError struct {
+	Field string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field %q", e.Field)
+}
+
+// FieldError describes why one alert field was rejected
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// ValidationError is returned for well-formed JSON that is not a valid alert
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	reasons := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		reasons[i] = field.Field + ": " + field.Reason
+	}
+	return "invalid alert: " + strings.Join(reasons, "; ")
+}
+
+// validationResponse is the 422 body listing the rejected fields
+type validationResponse struct {
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields"`
+}
+
+// Body buffers grown past this size by large payloads are not kept
+const maxPooledBodyBytes = 64 << 10
+
+// bodyBufferPool reuses the buffers request bodies are read into
+var bodyBufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// putBodyBuffer returns a body buffer to the pool unless it grew too large
+func putBodyBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBodyBytes {
+		return
+	}
+	buf.Reset()
+	bodyBufferPool.Put(buf)
+}
+
+// readBody reads a request body limited by http.MaxBytesReader into buf
+func readBody(buf *bytes.Buffer, body io.Reader) ([]byte, error) {
+	if _, err := buf.ReadFrom(body); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, fmt.Errorf("%w: body exceeds %d bytes", ErrTooLarge, tooLarge.Limit)
+		}
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeAlert strictly decodes a single alert, classifying failures as
+// ErrSyntax, *UnknownFieldError or *ValidationError for mistyped fields
+func decodeAlert(data []byte, alert *types.FluxAlert) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(alert); err != nil {
+		return classifyDecodeError(err)
+	}
+	return nil
+}
+
+// classifyDecodeError maps an encoding/json error to the typed errors (pure function)
+func classifyDecodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &ValidationError{Fields: []FieldError{{
+			Field:  typeErr.Field,
+			Reason: fmt.Sprintf("must be %s, not %s", jsonKind(typeErr.Type), typeErr.Value),
+		}}}
+	}
+
+	// encoding/json has no error type for unknown fields
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		if unquoted, unquoteErr := strconv.Unquote(field); unquoteErr == nil {
+			field = unquoted
+		}
+		return &UnknownFieldError{Field: field}
+	}
+
+	return fmt.Errorf("%w: %v", ErrSyntax, err)
+}
+
+// jsonKind names the JSON type a Go type is decoded from (pure function)
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	default:
+		return "a number"
+	}
+}
+
+// atBatchIndex prefixes the fields of a typed error with the position of
+// the alert in a batch
+func atBatchIndex(err error, index int) error {
+	prefix := "[" + strconv.Itoa(index) + "]."
+
+	var unknown *UnknownFieldError
+	if errors.As(err, &unknown) {
+		return &UnknownFieldError{Field: prefix + unknown.Field}
+	}
+
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		fields := make([]FieldError, len(invalid.Fields))
+		for i, field := range invalid.Fields {
+			fields[i] = FieldError{Field: prefix + field.Field, Reason: field.Reason}
+		}
+		return &ValidationError{Fields: fields}
+	}
+
+	return fmt.Errorf("alert %d: %w", index, err)
+}
+
+// writePayloadError answers a payload rejected while reading, decoding or
+// validating it: 413 when too large, 422 listing the fields of a
+// well-formed payload that is not a valid alert, 400 with an error code for
+// stale events and 400 otherwise
+func writePayloadError(w http.ResponseWriter, responses *types.Responses, err error) {
+	var unknown *UnknownFieldError
+	var invalid *ValidationError
+	var stale *FreshnessError
+	switch {
+	case errors.Is(err, ErrTooLarge):
+		writeJSONResponse(w, http.StatusRequestEntityTooLarge, types.ResponsePayloadTooLarge)
+	case errors.As(err, &stale):
+		writeFreshnessError(w, stale)
+	case errors.As(err, &unknown):
+		writeValidationError(w, []FieldError{{Field: unknown.Field, Reason: "unknown field"}})
+	case errors.As(err, &invalid):
+		writeValidationError(w, invalid.Fields)
+	default:
+		writeResponse(w, responses.ContentType, http.StatusBadRequest, responses.InvalidJSON)
+	}
+}
+
+// logInvalidPayload logs the body of a webhook that failed to decode, when
+// DEBUG_LOG_INVALID_PAYLOADS is set, so that payload changes of new Flux
+// versions can be seen. Headers, which carry the credentials, are never logged.
+func logInvalidPayload(deps *HandlerDependencies, r *http.Request, data []byte, err error) {
+	if !deps.Config.DebugLogInvalidPayloads {
+		return
+	}
+	deps.Logger.Printf("Invalid payload from %s (request %s): %v, body %s",
+		r.RemoteAddr, defaultIfEmpty(r.Header.Get(RequestIDHeader), "-"), err, FormatPayload(data, deps.Config.DebugPayloadLogBytes))
+}
+
+// FormatPayload quotes the first limit bytes of data for a log line, control
+// characters and invalid UTF-8 escaped (pure function)
+func FormatPayload(data []byte, limit int) string {
+	if limit <= 0 || len(data) <= limit {
+		return strconv.Quote(string(data))
+	}
+	return fmt.Sprintf("%s (truncated, %d of %d bytes)", strconv.Quote(string(data[:limit])), limit, len(data))
+}
+
+// writeValidationError writes the 422 response for the rejected fields
+func writeValidationError(w http.ResponseWriter, fields []FieldError) {
+	body, err := json.Marshal(validationResponse{Error: types.InvalidAlertError, Fields: fields})
+	if err != nil {
+		writeJSONResponse(w, http.StatusInternalServerError, types.ResponseInternalError)
+		return
+	}
+	writeJSONResponse(w, http.StatusUnprocessableEntity, body)
+}
+
+// checkJSONShape rejects payloads that are expensive to decode before they
+// reach the decoder: a top-level value other than an object or an array,
+// nesting deeper than maxDepth or more than maxTokens values and delimiters.
+// Zero limits are not enforced. Syntax is left to the decoder (pure function).
+func checkJSONShape(data []byte, maxDepth, maxTokens int) error {
+	depth, tokens := 0, 0
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch c {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			continue
+		case '}', ']':
+			depth--
+			continue
+		}
+
+		if tokens == 0 && c != '{' && c != '[' {
+			return fmt.Errorf("top-level JSON value must be an object or an array of objects")
+		}
+
+		switch c {
+		case '{', '[':
+			depth++
+			if maxDepth > 0 && depth > maxDepth {
+				return fmt.Errorf("JSON nesting exceeds %d levels", maxDepth)
+			}
+		case '"':
+			i = skipJSONString(data, i)
+		default:
+			// Numbers and literals run until the next delimiter
+			for i+1 < len(data) && !isJSONDelimiter(data[i+1]) {
+				i++
+			}
+		}
+
+		tokens++
+		if maxTokens > 0 && tokens > maxTokens {
+			return fmt.Errorf("JSON exceeds %d tokens", maxTokens)
+		}
+	}
+	return nil
+}
+
+// skipJSONString returns the index of the quote closing the string opened
+// at data[start], or the last index for an unterminated string (pure function)
+func skipJSONString(data []byte, start int) int {
+	for i := start + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return len(data) - 1
+}
+
+// isJSONDelimiter reports whether c ends a number or literal (pure function)
+func isJSONDelimiter(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', ',', ':', '{', '}', '[', ']', '"':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,481 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/notify"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+func TestCheckJSONShape(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		maxDepth  int
+		maxTokens int
+		expected  string
+	}{
+		{"alert", `{"severity":"info","involvedObject":{"kind":"Kustomization","name":"apps"},"metadata":{"revision":"main@sha1:abc"}}`, 32, 10000, ""},
+		{"batch", `[{"message":"a"},{"message":"b"}]`, 32, 10000, ""},
+		{"empty body left to the decoder", "", 32, 10000, ""},
+		{"string", `"alert"`, 32, 10000, "top-level JSON value must be an object or an array of objects"},
+		{"number after whitespace", " 42", 32, 10000, "top-level JSON value must be an object or an array of objects"},
+		{"literal", `null`, 32, 10000, "top-level JSON value must be an object or an array of objects"},
+		{"at depth limit", `{"a":{"b":{}}}`, 3, 0, ""},
+		{"too deep", `{"a":{"b":{"c":{}}}}`, 3, 0, "JSON nesting exceeds 3 levels"},
+		{"brackets inside strings", `{"message":"[[[{{{\"[[["}`, 1, 0, ""},
+		{"at token limit", `{"a":1,"b":true}`, 0, 5, ""},
+		{"too many tokens", `{"a":1,"b":true,"c":null}`, 0, 5, "JSON exceeds 5 tokens"},
+		{"limits disabled", strings.Repeat("[", 100) + strings.Repeat("]", 100), 0, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkJSONShape([]byte(tt.body), tt.maxDepth, tt.maxTokens)
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_JSONLimits(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		expectedLog  string
+	}{
+		{
+			name:         "normal alert",
+			body:         `{"severity":"info","reason":"ReconciliationSucceeded","involvedObject":{"kind":"Kustomization","name":"apps"},"metadata":{"revision":"main@sha1:abc"}}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "deeply nested object",
+			body:         strings.Repeat(`{"a":`, 10000) + "1" + strings.Repeat("}", 10000),
+			expectedCode: http.StatusBadRequest,
+			expectedLog:  "Rejected payload: JSON nesting exceeds 32 levels",
+		},
+		{
+			name:         "top-level string",
+			body:         `"alert"`,
+			expectedCode: http.StatusBadRequest,
+			expectedLog:  "Rejected payload: top-level JSON value must be an object or an array of objects",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewConfig()
+			cfg.PushoverAPIToken = "api_token"
+			cfg.PushoverUserKey = "user_key"
+			cfg.BearerToken = "Bearer test_token"
+
+			logger := &RecordingLogger{}
+			deps := &HandlerDependencies{
+				Config:         cfg,
+				Logger:         logger,
+				MessageBuilder: BuildPushoverMessage,
+				Notifier:       notify.NewCoordinator(notify.ModeFanOut, &MockNotificationSender{name: "pushover"}),
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer test_token")
+			rr := httptest.NewRecorder()
+			CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+			if tt.expectedLog != "" && !strings.Contains(strings.Join(logger.lines, "\n"), tt.expectedLog) {
+				t.Errorf("Expected log %q, got %v", tt.expectedLog, logger.lines)
+			}
+		})
+	}
+}
+
+func TestDecodeAlert(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		check    func(err error) bool
+		expected string
+	}{
+		{"valid", `{"severity":"info"}`, func(err error) bool { return err == nil }, ""},
+		{"syntax", `{"severity":`, func(err error) bool { return errors.Is(err, ErrSyntax) }, "malformed JSON: unexpected EOF"},
+		{"empty", ``, func(err error) bool { return errors.Is(err, ErrSyntax) }, "malformed JSON: EOF"},
+		{
+			name: "unknown field",
+			body: `{"severity":"info","priority":1}`,
+			check: func(err error) bool {
+				var unknown *UnknownFieldError
+				return errors.As(err, &unknown) && unknown.Field == "priority"
+			},
+			expected: `unknown field "priority"`,
+		},
+		{
+			name: "wrong type",
+			body: `{"involvedObject":{"name":42}}`,
+			check: func(err error) bool {
+				var invalid *ValidationError
+				return errors.As(err, &invalid) && len(invalid.Fields) == 1
+			},
+			expected: "invalid alert: involvedObject.name: must be a string, not number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var alert types.FluxAlert
+			err := decodeAlert([]byte(tt.body), &alert)
+			if !tt.check(err) {
+				t.Fatalf("Unexpected error %T: %v", err, err)
+			}
+			if tt.expected != "" && err.Error() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_PayloadErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "syntax error",
+			body:         `{"severity":"info",}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: string(types.ResponseInvalidJSON),
+		},
+		{
+			name:         "too large",
+			body:         `{"message":"` + strings.Repeat("x", types.MaxBodySize) + `"}`,
+			expectedCode: http.StatusRequestEntityTooLarge,
+			expectedBody: string(types.ResponsePayloadTooLarge),
+		},
+		{
+			name:         "unknown field",
+			body:         `{"severity":"info","priority":1}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"error":"Invalid alert","fields":[{"field":"priority","reason":"unknown field"}]}`,
+		},
+		{
+			name:         "wrong type",
+			body:         `{"severity":["info"]}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"error":"Invalid alert","fields":[{"field":"severity","reason":"must be a string, not array"}]}`,
+		},
+		{
+			name:         "unknown severity and invalid timestamp",
+			body:         `{"severity":"fatal","timestamp":"2024-13-01"}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"error":"Invalid alert","fields":[{"field":"timestamp","reason":"must be an RFC 3339 time"}]}`,
+		},
+		{
+			name:         "invalid alert in a batch",
+			body:         `[{"severity":"info"},{"severity":"fatal","timestamp":"yesterday"}]`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"error":"Invalid alert","fields":[{"field":"[1].timestamp","reason":"must be an RFC 3339 time"}]}`,
+		},
+		{
+			name:         "valid alert",
+			body:         `{"severity":"error","timestamp":"2024-01-02T03:04:05Z"}`,
+			expectedCode: http.StatusOK,
+			expectedBody: string(types.ResponseOK),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := &HandlerDependencies{
+				Config: &config.Config{
+					PushoverAPIToken: "test_api_token",
+					PushoverUserKey:  "user_key",
+					BearerToken:      "Bearer test_token",
+				},
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer test_token")
+			rr := httptest.NewRecorder()
+			CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("Expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_ValidationMode(t *testing.T) {
+	tests := []struct {
+		name         string
+		mode         string
+		body         string
+		expectedCode int
+		expectedBody string
+		expectedLog  string
+	}{
+		{
+			name:         "strict rejects every violated field",
+			mode:         config.ValidationModeStrict,
+			body:         `{"severity":"bogus","involvedObject":{"kind":"` + strings.Repeat("k", 64) + `"}}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"error":"Invalid alert","fields":[{"field":"severity","reason":"must be \"info\", \"warning\" or \"error\""},{"field":"message","reason":"is required when reason is empty"},{"field":"involvedObject.kind","reason":"must be at most 63 bytes"}]}`,
+		},
+		{
+			name:         "lenient delivers alerts over the limits",
+			mode:         config.ValidationModeLenient,
+			body:         `{"severity":"error","involvedObject":{"kind":"` + strings.Repeat("k", 64) + `"}}`,
+			expectedCode: http.StatusOK,
+			expectedBody: string(types.ResponseOK),
+			expectedLog:  "despite VALIDATION_MODE=lenient: invalid alert: message",
+		},
+		{
+			name:         "lenient by default",
+			body:         `{"severity":"error"}`,
+			expectedCode: http.StatusOK,
+			expectedBody: string(types.ResponseOK),
+			expectedLog:  "despite VALIDATION_MODE=lenient: invalid alert: message",
+		},
+		{
+			name:         "lenient delivers unknown severities as info",
+			mode:         config.ValidationModeLenient,
+			body:         `{"severity":"bogus","message":"m"}`,
+			expectedCode: http.StatusOK,
+			expectedBody: string(types.ResponseOK),
+			expectedLog:  `Delivering Unknown/Unknown with unknown severity "bogus" as info`,
+		},
+		{
+			name:         "lenient still rejects mistyped fields",
+			mode:         config.ValidationModeLenient,
+			body:         `{"severity":["info"]}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"error":"Invalid alert","fields":[{"field":"severity","reason":"must be a string, not array"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &RecordingLogger{}
+			deps := &HandlerDependencies{
+				Config: &config.Config{
+					PushoverAPIToken: "test_api_token",
+					BearerToken:      "Bearer test_token",
+					ValidationMode:   tt.mode,
+				},
+				Logger:         logger,
+				MessageBuilder: BuildPushoverMessage,
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer test_token")
+			rr := httptest.NewRecorder()
+			CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("Expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+			if !strings.Contains(strings.Join(logger.lines, "\n"), tt.expectedLog) {
+				t.Errorf("Expected %q logged, got %v", tt.expectedLog, logger.lines)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_UnknownSeverity(t *testing.T) {
+	var sent *types.PushoverMessage
+	deps := &HandlerDependencies{
+		Config: &config.Config{PushoverAPIToken: "api-token", BearerToken: "Bearer api-token"},
+		PushoverClient: &MockPushoverClient{
+			SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+				sent = msg
+				return nil
+			},
+		},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"severity":"critical","message":"m"}`))
+	req.Header.Set("Authorization", "Bearer api-token")
+	rr := httptest.NewRecorder()
+	CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || sent == nil {
+		t.Fatalf("Expected the alert sent, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.HasPrefix(sent.Message, "Unknown [INFO]\n") {
+		t.Errorf("Expected the alert delivered as info, got %q", sent.Message)
+	}
+}
+
+func TestCreateWebhookHandler_StrictAlerts(t *testing.T) {
+	tests := []struct {
+		name         string
+		strict       bool
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "missing kind",
+			strict:       true,
+			body:         `{"severity":"error","involvedObject":{"name":"apps"}}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"error":"Invalid alert","fields":[{"field":"involvedObject.kind","reason":"is required"}]}`,
+		},
+		{
+			name:         "missing severity",
+			strict:       true,
+			body:         `{"involvedObject":{"kind":"Kustomization","name":"apps"}}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"error":"Invalid alert","fields":[{"field":"severity","reason":"is required"}]}`,
+		},
+		{
+			name:         "missing both",
+			strict:       true,
+			body:         `{"message":"m"}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"error":"Invalid alert","fields":[{"field":"severity","reason":"is required"},{"field":"involvedObject.kind","reason":"is required"}]}`,
+		},
+		{
+			name:         "missing kind in a batch",
+			strict:       true,
+			body:         `[{"severity":"info","involvedObject":{"kind":"Kustomization"}},{"severity":"info"}]`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"error":"Invalid alert","fields":[{"field":"[1].involvedObject.kind","reason":"is required"}]}`,
+		},
+		{
+			name:         "complete alert",
+			strict:       true,
+			body:         `{"severity":"info","involvedObject":{"kind":"Kustomization","name":"apps"}}`,
+			expectedCode: http.StatusOK,
+			expectedBody: string(types.ResponseOK),
+		},
+		{
+			name:         "lenient by default",
+			body:         `{"message":"m"}`,
+			expectedCode: http.StatusOK,
+			expectedBody: string(types.ResponseOK),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := &HandlerDependencies{
+				Config: &config.Config{
+					PushoverAPIToken: "test_api_token",
+					PushoverUserKey:  "user_key",
+					BearerToken:      "Bearer test_token",
+					StrictAlerts:     tt.strict,
+				},
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer test_token")
+			rr := httptest.NewRecorder()
+			CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("Expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestFormatPayload(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		limit    int
+		expected string
+	}{
+		{"short payload", `{"a":1}`, 16, `"{\"a\":1}"`},
+		{"control characters escaped", "{\x1b[31m\n", 16, `"{\x1b[31m\n"`},
+		{"truncated", `{"message":"long"}`, 5, `"{\"mes" (truncated, 5 of 18 bytes)`},
+		{"split rune escaped", "ééé", 3, `"é\xc3" (truncated, 3 of 6 bytes)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatPayload([]byte(tt.data), tt.limit); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_LogInvalidPayloads(t *testing.T) {
+	body := `{"severity":"info","message":"` + strings.Repeat("x", 100) + `",`
+
+	for _, enabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("enabled=%v", enabled), func(t *testing.T) {
+			cfg := config.NewConfig()
+			cfg.BearerToken = "Bearer test_token"
+			cfg.DebugLogInvalidPayloads = enabled
+			cfg.DebugPayloadLogBytes = 32
+
+			logger := &RecordingLogger{}
+			deps := &HandlerDependencies{
+				Config:         cfg,
+				Logger:         logger,
+				MessageBuilder: BuildPushoverMessage,
+				Notifier:       notify.NewCoordinator(notify.ModeFanOut, &MockNotificationSender{name: "pushover"}),
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer test_token")
+			req.Header.Set(RequestIDHeader, "req-42")
+			rr := httptest.NewRecorder()
+			CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+			}
+
+			logs := strings.Join(logger.lines, "\n")
+			truncated := FormatPayload([]byte(body), 32)
+			if strings.Contains(logs, truncated) != enabled {
+				t.Errorf("Expected body logged %v, got %v", enabled, logger.lines)
+			}
+			if enabled && (!strings.Contains(logs, "(request req-42)") || !strings.Contains(logs, fmt.Sprintf("truncated, 32 of %d bytes", len(body)))) {
+				t.Errorf("Expected request id and truncation, got %v", logger.lines)
+			}
+			if strings.Contains(logs, "test_token") || strings.Contains(logs, strings.Repeat("x", 100)) {
+				t.Errorf("Expected neither credentials nor the full body to be logged, got %v", logger.lines)
+			}
+		})
+	}
+}
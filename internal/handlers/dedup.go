@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// dedupKeyPrefix namespaces deduplication keys in a shared store
+const dedupKeyPrefix = "flux-provider-pushover:dedup:"
+
+// DedupKey identifies alerts that would produce the same notification (pure function)
+func DedupKey(alert *types.FluxAlert) string {
+	obj := alert.InvolvedObject
+	hash := sha256.New()
+	for _, field := range []string{
+		obj.Kind, obj.Namespace, obj.Name,
+		strings.ToLower(alert.Severity), alert.Reason, alert.Message, alert.Metadata[types.MetadataRevision],
+	} {
+		hash.Write([]byte(field))
+		hash.Write([]byte{0})
+	}
+	// Keys of alerts without a cluster of their own stay as they were
+	if alert.Cluster != "" {
+		hash.Write([]byte(alert.Cluster))
+	}
+	return dedupKeyPrefix + hex.EncodeToString(hash.Sum(nil))
+}
+
+// claimAlert claims alert for the dedup window and reports whether it is a
+// duplicate. The returned key is empty when deduplication is disabled.
+func claimAlert(deps *HandlerDependencies, alert *types.FluxAlert) (string, bool) {
+	if deps.Dedup == nil || deps.Config.DedupWindow <= 0 {
+		return "", false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	key := DedupKey(alert)
+	claimed, err := deps.Dedup.Claim(ctx, key, deps.Config.DedupWindow)
+	if err != nil {
+		// Never drop an alert because the store failed
+		deps.Logger.Printf("Failed to check for duplicate alert: %v", err)
+		return "", false
+	}
+	return key, !claimed
+}
+
+// releaseAlert drops the claim of an alert that could not be delivered, so
+// that Flux's retry is not suppressed
+func releaseAlert(deps *HandlerDependencies, key string) {
+	if key == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := deps.Dedup.Delete(ctx, key); err != nil {
+		deps.Logger.Printf("Failed to release duplicate check: %v", err)
+	}
+}
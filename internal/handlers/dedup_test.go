@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/store"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+func TestDedupKey(t *testing.T) {
+	alert := &types.FluxAlert{Severity: "error", Reason: "ReconciliationFailed", Message: "failed"}
+	alert.InvolvedObject.Kind = "Kustomization"
+	alert.InvolvedObject.Namespace = "apps"
+	alert.InvolvedObject.Name = "podinfo"
+
+	same := *alert
+	same.Timestamp = "2024-01-02T03:04:05Z"
+
+	other := *alert
+	other.Message = "failed again"
+
+	if DedupKey(alert) != DedupKey(&same) {
+		t.Error("Expected alerts differing only in timestamp to share a key")
+	}
+
+	shouting := *alert
+	shouting.Severity = "ERROR"
+	if DedupKey(alert) != DedupKey(&shouting) {
+		t.Error("Expected severities differing only in case to share a key")
+	}
+
+	if DedupKey(alert) == DedupKey(&other) {
+		t.Error("Expected alerts with different messages to have different keys")
+	}
+
+	if !strings.HasPrefix(DedupKey(alert), dedupKeyPrefix) {
+		t.Errorf("Expected key prefix, got %s", DedupKey(alert))
+	}
+}
+
+func TestCreateWebhookHandler_Dedup(t *testing.T) {
+	var sendErr error
+	sent := 0
+	deps := &HandlerDependencies{
+		Config: &config.Config{
+			PushoverAPIToken: "test_token",
+			BearerToken:      "Bearer test_token",
+			DedupWindow:      time.Minute,
+		},
+		PushoverClient: &MockPushoverClient{
+			SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+				sent++
+				return sendErr
+			},
+		},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		Dedup:          store.NewMemoryStore(),
+	}
+	handler := CreateWebhookHandler(deps)
+
+	send := func(message string) int {
+		body := fmt.Sprintf(`{"message":%q}`, message)
+		req := httptest.NewRequest("POST", "/webhook", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test_token")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// A failed delivery releases the claim so Flux's retry is delivered
+	sendErr = fmt.Errorf("pushover API returned status 500")
+	if code := send("first"); code != http.StatusInternalServerError {
+		t.Fatalf("Expected 500, got %d", code)
+	}
+
+	sendErr = nil
+	for i := 0; i < 3; i++ {
+		if code := send("first"); code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", code)
+		}
+	}
+
+	if code := send("second"); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+
+	if sent != 3 {
+		t.Errorf("Expected 3 sends (failed, retried, distinct alert), got %d", sent)
+	}
+}
+
+func TestCreateWebhookHandler_DedupWindowOnFakeClock(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	sent := 0
+	deps := &HandlerDependencies{
+		Config: &config.Config{
+			PushoverAPIToken: "test_token",
+			BearerToken:      "Bearer test_token",
+			DedupWindow:      5 * time.Minute,
+		},
+		PushoverClient: &MockPushoverClient{
+			SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+				sent++
+				return nil
+			},
+		},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		Dedup:          store.NewMemoryStore().WithClock(fake),
+		Clock:          fake,
+	}
+	handler := CreateWebhookHandler(deps)
+
+	steps := []struct {
+		advance  time.Duration
+		expected int // Sends so far
+	}{
+		{0, 1},
+		{time.Minute, 1},
+		{4*time.Minute - time.Second, 1}, // Still within the window
+		{time.Second, 2},                 // The window of the first send ended
+		{time.Minute, 2},
+	}
+
+	for i, step := range steps {
+		fake.Advance(step.advance)
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"message":"failed"}`))
+		req.Header.Set("Authorization", "Bearer test_token")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Step %d: expected 200, got %d", i, rr.Code)
+		}
+		if sent != step.expected {
+			t.Errorf("Step %d: expected %d sends, got %d", i, step.expected, sent)
+		}
+	}
+}
@@ -1,10 +1,19 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
 	"github.com/zhorvath83/flux-provider-pushover/internal/config"
 	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
 )
 
 // TestCreateServerDependencies tests the CreateServerDependencies function
@@ -21,7 +30,7 @@ func TestCreateServerDependencies(t *testing.T) {
 	logger := &MockLogger{}
 
 	// Test creating dependencies
-	deps, err := CreateServerDependencies(cfg, logger)
+	deps, err := CreateServerDependencies(context.Background(), cfg, logger)
 	if err != nil {
 		t.Fatalf("CreateServerDependencies failed: %v", err)
 	}
@@ -62,7 +71,7 @@ func TestCreateServerDependencies_FullIntegration(t *testing.T) {
 	logger := server.Logger(&MockLogger{})
 
 	// Test creating dependencies
-	deps, err := CreateServerDependencies(cfg, logger)
+	deps, err := CreateServerDependencies(context.Background(), cfg, logger)
 	if err != nil {
 		t.Fatalf("CreateServerDependencies failed: %v", err)
 	}
@@ -82,3 +91,61 @@ func TestCreateServerDependencies_FullIntegration(t *testing.T) {
 		})
 	}
 }
+
+// TestCreateServerDependenciesWithClock tests that quiet hours and the
+// namespace rate limit follow the clock the dependencies are created with
+func TestCreateServerDependenciesWithClock(t *testing.T) {
+	var mu sync.Mutex
+	var priorities []string
+	pushoverAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		priorities = append(priorities, r.FormValue("priority"))
+		w.Write([]byte(`{"status":1,"request":"abc"}`))
+	}))
+	defer pushoverAPI.Close()
+
+	cfg := &config.Config{
+		PushoverUserKey:    "test_user",
+		PushoverAPIToken:   "test_token",
+		BearerToken:        "Bearer test_token",
+		PushoverURL:        pushoverAPI.URL,
+		QuietHours:         "22:00-07:00",
+		QuietHoursTimezone: "UTC",
+		PerNamespaceRate:   1,
+	}
+	fake := clock.NewFake(time.Date(2024, 3, 10, 21, 59, 30, 0, time.UTC))
+	deps, err := CreateServerDependenciesWithClock(context.Background(), cfg, &MockLogger{}, fake)
+	if err != nil {
+		t.Fatalf("CreateServerDependenciesWithClock failed: %v", err)
+	}
+	defer deps.Drain(context.Background())
+	handler := CreateWebhookHandler(deps)
+
+	post := func() []byte {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"severity":"info","reason":"Progressing","involvedObject":{"namespace":"apps"}}`))
+		req.Header.Set("Authorization", "Bearer test_token")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Body.Bytes()
+	}
+
+	if body := post(); !bytes.Equal(body, types.ResponseOK) {
+		t.Fatalf("Expected the first alert sent, got %s", body)
+	}
+	if body := post(); !bytes.Contains(body, []byte(`"status":"rate_limited"`)) {
+		t.Fatalf("Expected the second alert rate limited, got %s", body)
+	}
+
+	// A minute later the bucket has refilled and quiet hours have begun
+	fake.Advance(time.Minute)
+	if body := post(); !bytes.Equal(body, types.ResponseOK) {
+		t.Fatalf("Expected the alert after the refill sent, got %s", body)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(priorities) != 2 || priorities[0] == "-2" || priorities[1] != "-2" {
+		t.Errorf("Expected a normal then a quiet alert, got priorities %q", priorities)
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"runtime"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+)
+
+// DumpDiagnostics logs a one-shot snapshot of the configuration (with
+// credentials redacted) and the runtime state of every component, one
+// "Diagnostics <section>: <json>" entry per component. Components that are
+// not configured are skipped.
+func (d *HandlerDependencies) DumpDiagnostics(logger server.Logger) {
+	logger.Println("Diagnostics dump requested")
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	logDiagnostics(logger, "runtime", map[string]interface{}{
+		"goroutines": runtime.NumGoroutine(),
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"heap_alloc": memStats.HeapAlloc,
+		"heap_inuse": memStats.HeapInuse,
+		"num_gc":     memStats.NumGC,
+		"go_version": runtime.Version(),
+	})
+
+	if d == nil {
+		return
+	}
+
+	if d.Config != nil {
+		logDiagnostics(logger, "config", config.Redacted(d.Config))
+	}
+
+	if d.Metrics != nil {
+		logDiagnostics(logger, "metrics", d.Metrics.Snapshot())
+	}
+
+	if d.Forwarder != nil {
+		logDiagnostics(logger, "forwarder", map[string]interface{}{
+			"queue_depth": d.Forwarder.Pending(),
+		})
+	}
+
+	if d.Idempotency != nil {
+		logDiagnostics(logger, "idempotency", map[string]interface{}{
+			"keys": d.Idempotency.Len(),
+		})
+	}
+
+	if d.SendStatus != nil {
+		status := map[string]interface{}{"ready": true}
+		if lastErr := d.SendStatus.LastError(); lastErr != nil {
+			status["ready"] = false
+			status["last_error"] = lastErr.Message
+			status["last_error_time"] = lastErr.Time.UTC().Format(time.RFC3339)
+		}
+		if state := d.Credentials.Invalid(); state != nil {
+			status["ready"] = false
+			status["credentials_invalid"] = state.Reason
+		}
+		logDiagnostics(logger, "pushover", status)
+	}
+
+	logDiagnostics(logger, "leader_election", d.elector().Status())
+}
+
+// logDiagnostics logs one diagnostics section as JSON
+func logDiagnostics(logger server.Logger, section string, value interface{}) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		logger.Printf("Diagnostics %s: failed to encode: %v", section, err)
+		return
+	}
+	logger.Printf("Diagnostics %s: %s", section, encoded)
+}
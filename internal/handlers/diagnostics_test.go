@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/forward"
+	"github.com/zhorvath83/flux-provider-pushover/internal/idempotency"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/pushover"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// section returns the JSON logged for a diagnostics section, or ""
+func section(lines []string, name string) string {
+	prefix := "Diagnostics " + name + ": "
+	for _, line := range lines {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line, prefix)
+		}
+	}
+	return ""
+}
+
+func TestDumpDiagnostics(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.PushoverUserKey = "user-secret"
+	cfg.PushoverAPIToken = "token-secret"
+	cfg.BearerToken = "Bearer token-secret"
+	cfg.ClusterName = "prod"
+
+	registry := metrics.NewRegistry()
+	registry.Counter("notifications_sent_total", "Sent").Add(2)
+
+	sendStatus := pushover.NewStatusTracker(&MockPushoverClient{})
+	deps := &HandlerDependencies{
+		Config:      cfg,
+		Metrics:     registry,
+		Forwarder:   forward.NewForwarder(&MockHTTPClient{}, "http://mirror", "", &MockLogger{}, registry),
+		Idempotency: idempotency.NewCache(idempotency.DefaultTTL, 10, registry),
+		SendStatus:  sendStatus,
+	}
+
+	logger := &RecordingLogger{}
+	deps.DumpDiagnostics(logger)
+
+	output := strings.Join(logger.lines, "\n")
+	if strings.Contains(output, "secret") {
+		t.Errorf("Expected credentials to be redacted:\n%s", output)
+	}
+
+	expected := map[string]string{
+		"runtime":         `"goroutines":`,
+		"config":          `"ClusterName":"prod"`,
+		"metrics":         `"notifications_sent_total":2`,
+		"forwarder":       `"queue_depth":0`,
+		"idempotency":     `"keys":0`,
+		"pushover":        `"ready":true`,
+		"leader_election": `"enabled":false`,
+	}
+	for name, want := range expected {
+		if got := section(logger.lines, name); !strings.Contains(got, want) {
+			t.Errorf("Diagnostics %s: expected %s in %q", name, want, got)
+		}
+	}
+}
+
+func TestDumpDiagnostics_LastSendError(t *testing.T) {
+	sendStatus := pushover.NewStatusTracker(&MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			return errors.New("invalid token")
+		},
+	})
+	deps := &HandlerDependencies{SendStatus: sendStatus}
+
+	if err := sendStatus.SendMessage(context.Background(), &types.PushoverMessage{}); err == nil {
+		t.Fatal("Expected send error")
+	}
+
+	logger := &RecordingLogger{}
+	deps.DumpDiagnostics(logger)
+
+	if got := section(logger.lines, "pushover"); !strings.Contains(got, `"ready":false`) || !strings.Contains(got, "invalid token") {
+		t.Errorf("Expected not ready after a failed send, got %q", got)
+	}
+}
+
+func TestDumpDiagnostics_PartiallyInitialised(t *testing.T) {
+	tests := []struct {
+		name string
+		deps *HandlerDependencies
+	}{
+		{"nil dependencies", nil},
+		{"empty dependencies", &HandlerDependencies{}},
+		{"config only", &HandlerDependencies{Config: &config.Config{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &RecordingLogger{}
+			tt.deps.DumpDiagnostics(logger)
+
+			if section(logger.lines, "runtime") == "" {
+				t.Errorf("Expected runtime section, got %v", logger.lines)
+			}
+			if section(logger.lines, "forwarder") != "" {
+				t.Error("Expected unconfigured components to be skipped")
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+)
+
+// dispatchQueueSize is the number of deliveries a worker holds before
+// Submit blocks
+const dispatchQueueSize = 256
+
+// Dispatcher runs background deliveries on a fixed set of workers. Every
+// key is hashed onto one worker, so deliveries for the same object run one
+// at a time in submission order while different objects proceed in
+// parallel. A nil Dispatcher runs each delivery in the submitting goroutine.
+type Dispatcher struct {
+	queues        []chan func()
+	depth         []*metrics.Gauge
+	onDepthChange func() // Nil when unset
+
+	mu         sync.RWMutex // Held for reading to register a submitter, for writing to close
+	closed     atomic.Bool
+	closeOnce  sync.Once
+	submitting sync.WaitGroup // Submitters that may still send on a queue
+	wg         sync.WaitGroup // One per worker
+
+	abandon   chan struct{} // Closed once the final flush ran out of time
+	abandoned sync.Once
+	flushed   atomic.Int64 // Deliveries finished after closing
+	dropped   atomic.Int64 // Deliveries skipped after abandoning
+}
+
+// NewDispatcher starts workers, at least one, each with its own queue
+func NewDispatcher(workers int, registry *metrics.Registry) *Dispatcher {
+	workers = max(workers, 1)
+	depth := registry.GaugeVec("dispatch_queue_depth", "Deliveries waiting for each dispatch worker", "worker")
+
+	d := &Dispatcher{
+		queues:  make([]chan func(), workers),
+		depth:   make([]*metrics.Gauge, workers),
+		abandon: make(chan struct{}),
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan func(), dispatchQueueSize)
+		d.depth[i] = depth.WithLabelValues(strconv.Itoa(i))
+		d.wg.Add(1)
+		go d.work(i)
+	}
+	return d
+}
+
+// Submit queues deliver on the worker owning key, blocking while its queue
+// is full. Once the dispatcher is drained, deliver runs at once instead.
+func (d *Dispatcher) Submit(key string, deliver func()) {
+	if d == nil {
+		deliver()
+		return
+	}
+
+	// The lock is not held while sending, a full queue must not keep Flush
+	// from closing the dispatcher; the queues stay open until submitters
+	// registered before closing have sent
+	d.mu.RLock()
+	if d.closed.Load() {
+		d.mu.RUnlock()
+		deliver()
+		return
+	}
+	d.submitting.Add(1)
+	d.mu.RUnlock()
+
+	worker := shardOf(key, len(d.queues))
+	d.depth[worker].Add(1)
+	d.queues[worker] <- deliver
+	d.submitting.Done()
+	d.depthChanged()
+}
+
+// OnDepthChange registers fn to be called whenever a delivery is queued or
+// taken off its queue. It must be registered before the first Submit.
+func (d *Dispatcher) OnDepthChange(fn func()) {
+	if d != nil {
+		d.onDepthChange = fn
+	}
+}
+
+// depthChanged calls the OnDepthChange function
+func (d *Dispatcher) depthChanged() {
+	if d.onDepthChange != nil {
+		d.onDepthChange()
+	}
+}
+
+// Workers returns the number of workers
+func (d *Dispatcher) Workers() int {
+	if d == nil {
+		return 0
+	}
+	return len(d.queues)
+}
+
+// Pending returns the number of deliveries waiting for a worker
+func (d *Dispatcher) Pending() int64 {
+	if d == nil {
+		return 0
+	}
+	var pending int64
+	for _, queue := range d.queues {
+		pending += int64(len(queue))
+	}
+	return pending
+}
+
+// Drain stops accepting deliveries and waits for the queued ones to finish
+// or ctx to expire
+func (d *Dispatcher) Drain(ctx context.Context) error {
+	_, _, err := d.Flush(ctx)
+	return err
+}
+
+// Flush stops accepting deliveries and runs the queued ones until ctx
+// expires, dropping those that have not started by then. It returns how
+// many were delivered and dropped.
+func (d *Dispatcher) Flush(ctx context.Context) (delivered, dropped int64, err error) {
+	if d == nil {
+		return 0, 0, nil
+	}
+
+	d.closeOnce.Do(func() {
+		d.mu.Lock()
+		d.closed.Store(true)
+		d.mu.Unlock()
+
+		// Workers keep draining meanwhile, so blocked submitters get through
+		go func() {
+			d.submitting.Wait()
+			for _, queue := range d.queues {
+				close(queue)
+			}
+		}()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return d.flushed.Load(), d.dropped.Load(), nil
+	case <-ctx.Done():
+		// Deliveries still queued are dropped, running ones finish on their own
+		d.abandoned.Do(func() { close(d.abandon) })
+		return d.flushed.Load(), d.dropped.Load() + d.Pending(), ctx.Err()
+	}
+}
+
+// work runs the deliveries of worker i until its queue is closed
+func (d *Dispatcher) work(i int) {
+	defer d.wg.Done()
+	for deliver := range d.queues[i] {
+		d.depth[i].Add(-1)
+		d.depthChanged()
+		select {
+		case <-d.abandon:
+			d.dropped.Add(1)
+			continue
+		default:
+		}
+
+		deliver()
+		if d.isClosed() {
+			d.flushed.Add(1)
+		}
+	}
+}
+
+// isClosed reports whether Drain or Flush began
+func (d *Dispatcher) isClosed() bool {
+	return d.closed.Load()
+}
+
+// shardOf returns the worker of key among workers (pure function)
+func shardOf(key string, workers int) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(workers))
+}
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+func TestDispatcher_PreservesPerObjectOrder(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	client := &MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			// Jitter lets later deliveries overtake earlier ones unless ordered
+			time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, msg.Message)
+			return nil
+		},
+	}
+
+	// Two objects on different workers, so that they proceed in parallel
+	dispatcher := NewDispatcher(4, nil)
+	first := newCoalesceAlert("error", "Kustomization", "apps", "")
+	second := newCoalesceAlert("error", "HelmRelease", "", "")
+	for i := 0; shardOf(ObjectKey(first), 4) == shardOf(ObjectKey(second), 4); i++ {
+		second.InvolvedObject.Name = fmt.Sprintf("release-%d", i)
+	}
+
+	const events = 20
+	for i := 0; i < events; i++ {
+		for _, alert := range []*types.FluxAlert{first, second} {
+			key := ObjectKey(alert)
+			message := fmt.Sprintf("%s#%d", key, i)
+			dispatcher.Submit(key, func() {
+				_ = client.SendMessage(context.Background(), &types.PushoverMessage{Message: message})
+			})
+		}
+	}
+	if err := dispatcher.Drain(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 2*events {
+		t.Fatalf("Expected %d sends, got %d", 2*events, len(calls))
+	}
+	for _, alert := range []*types.FluxAlert{first, second} {
+		key := ObjectKey(alert)
+		next := 0
+		for _, call := range calls {
+			var n int
+			if _, err := fmt.Sscanf(call, key+"#%d", &n); err != nil {
+				continue
+			}
+			if n != next {
+				t.Fatalf("Expected %s#%d next, got %s in %q", key, next, call, calls)
+			}
+			next++
+		}
+		if next != events {
+			t.Errorf("Expected %d sends for %s, got %d", events, key, next)
+		}
+	}
+}
+
+func TestDispatcher_QueueDepth(t *testing.T) {
+	registry := metrics.NewRegistry()
+	dispatcher := NewDispatcher(1, registry)
+	var changes atomic.Int64
+	dispatcher.OnDepthChange(func() { changes.Add(1) })
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	dispatcher.Submit("a", func() {
+		close(started)
+		<-release
+	})
+	<-started
+	dispatcher.Submit("a", func() {})
+	dispatcher.Submit("b", func() {})
+
+	if pending := dispatcher.Pending(); pending != 2 {
+		t.Errorf("Expected 2 pending deliveries, got %d", pending)
+	}
+	depth := registry.GaugeVec("dispatch_queue_depth", "", "worker").WithLabelValues("0")
+	if depth.Value() != 2 {
+		t.Errorf("Expected worker 0 queue depth 2, got %d", depth.Value())
+	}
+
+	close(release)
+	if err := dispatcher.Drain(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if depth.Value() != 0 || dispatcher.Pending() != 0 {
+		t.Errorf("Expected empty queues after drain, got %d %d", depth.Value(), dispatcher.Pending())
+	}
+	if changes.Load() != 6 {
+		t.Errorf("Expected a depth change per queued and dequeued delivery, got %d", changes.Load())
+	}
+}
+
+func TestDispatcher_DrainTimeout(t *testing.T) {
+	dispatcher := NewDispatcher(1, nil)
+	release := make(chan struct{})
+	defer close(release)
+	dispatcher.Submit("a", func() { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := dispatcher.Drain(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestDispatcher_FlushDropsWhatRunsOutOfTime(t *testing.T) {
+	dispatcher := NewDispatcher(1, nil)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	dispatcher.Submit("a", func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	var ran atomic.Int32
+	for i := 0; i < 3; i++ {
+		dispatcher.Submit("a", func() { ran.Add(1) })
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	delivered, dropped, err := dispatcher.Flush(ctx)
+	if err != context.DeadlineExceeded || delivered != 0 || dropped != 3 {
+		t.Errorf("Expected the 3 queued deliveries dropped, got %d %d %v", delivered, dropped, err)
+	}
+
+	// The running delivery finishes, the dropped ones never start
+	close(release)
+	if err := dispatcher.Drain(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ran.Load() != 0 {
+		t.Errorf("Expected dropped deliveries not to run, %d did", ran.Load())
+	}
+}
+
+func TestDispatcher_FlushWithFullQueue(t *testing.T) {
+	dispatcher := NewDispatcher(1, nil)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	dispatcher.Submit("a", func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	var ran atomic.Int32
+	for i := 0; i < dispatchQueueSize; i++ {
+		dispatcher.Submit("a", func() { ran.Add(1) })
+	}
+
+	// The queue is full, this submitter blocks until the worker makes room
+	submitted := make(chan struct{})
+	go func() {
+		dispatcher.Submit("a", func() { ran.Add(1) })
+		close(submitted)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	flushed := make(chan error, 1)
+	go func() {
+		_, _, err := dispatcher.Flush(ctx)
+		flushed <- err
+	}()
+	waitFor(t, dispatcher.isClosed)
+	close(release)
+
+	if err := <-flushed; err != nil {
+		t.Fatalf("Expected the flush to finish, got %v", err)
+	}
+	<-submitted
+	if ran.Load() != dispatchQueueSize+1 {
+		t.Errorf("Expected %d deliveries, got %d", dispatchQueueSize+1, ran.Load())
+	}
+}
+
+func TestHandlerDependencies_DrainFlushesQueue(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	client := &MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			mu.Lock()
+			defer mu.Unlock()
+			sent = append(sent, msg.Message)
+			return nil
+		},
+	}
+	logger := &RecordingLogger{}
+	deps := &HandlerDependencies{Logger: logger, Dispatcher: NewDispatcher(1, nil)}
+
+	// The worker is held up until shutdown, the messages stay queued
+	release := make(chan struct{})
+	deps.Dispatcher.Submit("apps", func() { <-release })
+	for i := 0; i < 3; i++ {
+		message := fmt.Sprintf("alert %d", i)
+		deps.Dispatcher.Submit("apps", func() {
+			_ = client.SendMessage(context.Background(), &types.PushoverMessage{Message: message})
+		})
+	}
+
+	// Shutting down releases the worker, the flush delivers the queue
+	drained := make(chan error, 1)
+	go func() { drained <- deps.Drain(context.Background()) }()
+	waitFor(t, deps.Dispatcher.isClosed)
+	close(release)
+	if err := <-drained; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(sent) != "[alert 0 alert 1 alert 2]" {
+		t.Errorf("Expected the queued messages delivered before exit, got %v", sent)
+	}
+	if len(logger.lines) != 1 || logger.lines[0] != "Final flush: delivered 4 queued deliveries, dropped 0" {
+		t.Errorf("Expected the flush logged, got %q", logger.lines)
+	}
+}
+
+func TestDispatcher_RunsInlineWhenNilOrDrained(t *testing.T) {
+	var nilDispatcher *Dispatcher
+	ran := false
+	nilDispatcher.Submit("a", func() { ran = true })
+	if !ran {
+		t.Error("Expected nil dispatcher to deliver at once")
+	}
+	if err := nilDispatcher.Drain(context.Background()); err != nil || nilDispatcher.Workers() != 0 {
+		t.Errorf("Expected nil dispatcher to drain cleanly, got %v", err)
+	}
+
+	dispatcher := NewDispatcher(0, nil)
+	if dispatcher.Workers() != 1 {
+		t.Errorf("Expected at least one worker, got %d", dispatcher.Workers())
+	}
+	if err := dispatcher.Drain(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ran = false
+	dispatcher.Submit("a", func() { ran = true })
+	if !ran {
+		t.Error("Expected a drained dispatcher to deliver at once")
+	}
+	if err := dispatcher.Drain(context.Background()); err != nil {
+		t.Errorf("Expected a second drain to succeed, got %v", err)
+	}
+}
+
+func TestShardOf(t *testing.T) {
+	for _, key := range []string{"", "apps/Kustomization/apps", "flux-system/HelmRelease/podinfo"} {
+		shard := shardOf(key, 4)
+		if shard < 0 || shard >= 4 {
+			t.Errorf("Expected shard of %q within 4 workers, got %d", key, shard)
+		}
+		if shardOf(key, 4) != shard {
+			t.Errorf("Expected a stable shard for %q", key)
+		}
+	}
+}
+
+func TestCreateWebhookHandler_CoalesceDispatch(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	coalescer, timers := newTestCoalescer(false, nil)
+	deps := &HandlerDependencies{
+		Config: &config.Config{PushoverAPIToken: "test_token", BearerToken: "Bearer test_token"},
+		PushoverClient: &MockPushoverClient{
+			SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+				mu.Lock()
+				defer mu.Unlock()
+				sent = append(sent, msg.Message)
+				return nil
+			},
+		},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		Coalescer:      coalescer,
+		Dispatcher:     NewDispatcher(2, nil),
+	}
+	handler := CreateWebhookHandler(deps)
+
+	body := `{"severity":"info","reason":"Progressing","involvedObject":{"kind":"Kustomization","namespace":"apps","name":"apps"}}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test_token")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", rr.Code)
+	}
+
+	// The flush is handed to a worker, the drain waits for its send
+	timers.Fire()
+	if err := deps.Drain(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 1 || !strings.HasPrefix(sent[0], "Progressing") {
+		t.Errorf("Expected the held alert to be sent by a dispatch worker, got %q", sent)
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// TimestampHeader carries the event time for senders whose payload has none
+const TimestampHeader = "X-Timestamp"
+
+// Error codes of events rejected by the freshness check
+const (
+	CodeEventTooOld           = "event_too_old"
+	CodeEventInFuture         = "event_in_future"
+	CodeEventTimestampMissing = "event_timestamp_missing"
+	CodeEventTimestampInvalid = "event_timestamp_invalid"
+)
+
+// FreshnessError is returned for an event outside the accepted age window
+type FreshnessError struct {
+	Code    string
+	Message string
+}
+
+func (e *FreshnessError) Error() string {
+	return e.Message
+}
+
+// freshnessResponse is the 400 body of a rejected event
+type freshnessResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// FreshnessChecker rejects replayed events by their timestamp. A nil
+// FreshnessChecker accepts every event.
+type FreshnessChecker struct {
+	maxAge           time.Duration // Zero only checks for presence
+	skew             time.Duration
+	requireTimestamp bool
+	clock            clock.Clock
+	rejected         *metrics.CounterVec
+}
+
+// NewFreshnessChecker creates a checker rejecting events older than maxAge
+// or more than skew in the future, and events without a timestamp when
+// requireTimestamp is set
+func NewFreshnessChecker(maxAge, skew time.Duration, requireTimestamp bool, registry *metrics.Registry) *FreshnessChecker {
+	return &FreshnessChecker{
+		maxAge:           maxAge,
+		skew:             skew,
+		requireTimestamp: requireTimestamp,
+		clock:            clock.Real{},
+		rejected:         registry.CounterVec("events_rejected_total", "Webhook events rejected by the freshness check", "reason"),
+	}
+}
+
+// WithClock makes the checker tell the age of events by clk
+func (f *FreshnessChecker) WithClock(clk clock.Clock) *FreshnessChecker {
+	f.clock = clk
+	return f
+}
+
+// Check validates the event time of alert, falling back to the
+// X-Timestamp header when the alert carries none
+func (f *FreshnessChecker) Check(r *http.Request, alert *types.FluxAlert) error {
+	if f == nil {
+		return nil
+	}
+
+	err := f.check(eventTimestamp(r, alert))
+	if err != nil {
+		f.rejected.WithLabelValues(err.Code).Inc()
+		return err
+	}
+	return nil
+}
+
+// check validates a raw timestamp against the accepted window
+func (f *FreshnessChecker) check(raw string) *FreshnessError {
+	if raw == "" {
+		if f.requireTimestamp {
+			return &FreshnessError{Code: CodeEventTimestampMissing, Message: "event timestamp is required"}
+		}
+		return nil
+	}
+
+	timestamp, err := ParseEventTimestamp(raw)
+	if err != nil {
+		return &FreshnessError{Code: CodeEventTimestampInvalid, Message: err.Error()}
+	}
+
+	now := f.clock.Now()
+	if f.maxAge > 0 && now.Sub(timestamp) > f.maxAge {
+		return &FreshnessError{
+			Code:    CodeEventTooOld,
+			Message: fmt.Sprintf("event from %s is older than %s", timestamp.UTC().Format(time.RFC3339), f.maxAge),
+		}
+	}
+	if timestamp.Sub(now) > f.skew {
+		return &FreshnessError{
+			Code:    CodeEventInFuture,
+			Message: fmt.Sprintf("event from %s is more than %s in the future", timestamp.UTC().Format(time.RFC3339), f.skew),
+		}
+	}
+	return nil
+}
+
+// eventTimestamp returns the alert's timestamp or the X-Timestamp header (pure function)
+func eventTimestamp(r *http.Request, alert *types.FluxAlert) string {
+	if alert.Timestamp != "" {
+		return alert.Timestamp
+	}
+	return r.Header.Get(TimestampHeader)
+}
+
+// ParseEventTimestamp parses an RFC 3339 time or Unix seconds (pure function)
+func ParseEventTimestamp(raw string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("event timestamp %q is neither RFC 3339 nor Unix seconds", raw)
+	}
+	return timestamp, nil
+}
+
+// writeFreshnessError writes the 400 response of a rejected event
+func writeFreshnessError(w http.ResponseWriter, err *FreshnessError) {
+	body, marshalErr := json.Marshal(freshnessResponse{Error: err.Message, Code: err.Code})
+	if marshalErr != nil {
+		writeJSONResponse(w, http.StatusInternalServerError, types.ResponseInternalError)
+		return
+	}
+	writeJSONResponse(w, http.StatusBadRequest, body)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+var freshnessNow = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// newTestFreshnessChecker returns a checker whose clock is freshnessNow
+func newTestFreshnessChecker(requireTimestamp bool, registry *metrics.Registry) *FreshnessChecker {
+	checker := NewFreshnessChecker(5*time.Minute, 30*time.Second, requireTimestamp, registry)
+	checker.clock = clock.NewFake(freshnessNow)
+	return checker
+}
+
+func TestFreshnessChecker_Check(t *testing.T) {
+	tests := []struct {
+		name             string
+		timestamp        string
+		header           string
+		requireTimestamp bool
+		expectedCode     string
+	}{
+		{"fresh", "2024-01-02T03:00:05Z", "", false, ""},
+		{"at max age", "2024-01-02T02:59:05Z", "", false, ""},
+		{"stale", "2024-01-02T02:59:04Z", "", false, CodeEventTooOld},
+		{"within skew", "2024-01-02T03:04:35Z", "", false, ""},
+		{"future dated", "2024-01-02T03:04:36Z", "", false, CodeEventInFuture},
+		{"other time zone", "2024-01-02T04:03:05+01:00", "", false, ""},
+		{"missing allowed", "", "", false, ""},
+		{"missing required", "", "", true, CodeEventTimestampMissing},
+		{"header unix seconds", "", "1704164645", true, ""},
+		{"stale header", "", "2024-01-01T00:00:00Z", false, CodeEventTooOld},
+		{"alert wins over header", "2024-01-02T03:04:00Z", "2024-01-01T00:00:00Z", false, ""},
+		{"invalid header", "", "yesterday", false, CodeEventTimestampInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := metrics.NewRegistry()
+			checker := newTestFreshnessChecker(tt.requireTimestamp, registry)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+			if tt.header != "" {
+				req.Header.Set(TimestampHeader, tt.header)
+			}
+
+			err := checker.Check(req, &types.FluxAlert{Timestamp: tt.timestamp})
+			if tt.expectedCode == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			var stale *FreshnessError
+			if !errors.As(err, &stale) || stale.Code != tt.expectedCode {
+				t.Fatalf("Expected code %s, got %v", tt.expectedCode, err)
+			}
+			if got := registry.CounterVec("events_rejected_total", "", "reason").WithLabelValues(tt.expectedCode).Value(); got != 1 {
+				t.Errorf("Expected 1 rejection counted, got %d", got)
+			}
+		})
+	}
+}
+
+func TestFreshnessChecker_Nil(t *testing.T) {
+	var checker *FreshnessChecker
+	if err := checker.Check(httptest.NewRequest(http.MethodPost, "/webhook", nil), &types.FluxAlert{Timestamp: "1999-01-01T00:00:00Z"}); err != nil {
+		t.Errorf("Expected nil checker to accept, got %v", err)
+	}
+}
+
+func TestCreateWebhookHandler_Freshness(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "fresh",
+			body:         `{"severity":"info","timestamp":"2024-01-02T03:04:00Z"}`,
+			expectedCode: http.StatusOK,
+			expectedBody: string(types.ResponseOK),
+		},
+		{
+			name:         "stale",
+			body:         `{"severity":"info","timestamp":"2024-01-01T03:04:05Z"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"event from 2024-01-01T03:04:05Z is older than 5m0s","code":"event_too_old"}`,
+		},
+		{
+			name:         "future dated",
+			body:         `{"severity":"info","timestamp":"2024-01-02T04:04:05Z"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"event from 2024-01-02T04:04:05Z is more than 30s in the future","code":"event_in_future"}`,
+		},
+		{
+			name:         "missing",
+			body:         `{"severity":"info"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"event timestamp is required","code":"event_timestamp_missing"}`,
+		},
+		{
+			name:         "stale alert in a batch",
+			body:         `[{"timestamp":"2024-01-02T03:04:00Z"},{"timestamp":"2024-01-01T03:04:05Z"}]`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"event from 2024-01-01T03:04:05Z is older than 5m0s","code":"event_too_old"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := metrics.NewRegistry()
+			deps := &HandlerDependencies{
+				Config: &config.Config{
+					PushoverAPIToken: "test_api_token",
+					PushoverUserKey:  "user_key",
+					BearerToken:      "Bearer test_token",
+				},
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+				Metrics:        registry,
+				Freshness:      newTestFreshnessChecker(true, registry),
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer test_token")
+			rr := httptest.NewRecorder()
+			CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("Expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/notify"
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// glanceFailureWindow is how long an error alert counts towards the Glances count
+const glanceFailureWindow = time.Hour
+
+// ENABLE_GLANCES updates wait glanceDebounce for changes to settle and are
+// at least glanceMinInterval apart, Pushover limits how often widgets update
+const (
+	glanceDebounce    = 30 * time.Second
+	glanceMinInterval = 5 * time.Minute
+)
+
+// GlanceClient is implemented by pushover.PushoverClient
+type GlanceClient interface {
+	SendGlance(ctx context.Context, glance *types.PushoverGlance) error
+}
+
+// FailureCounter counts the error alerts seen within a sliding window
+type FailureCounter struct {
+	window time.Duration
+	clock  clock.Clock
+
+	mu       sync.Mutex
+	failures []time.Time // Oldest first
+}
+
+// NewFailureCounter creates a counter of the error alerts within window
+func NewFailureCounter(window time.Duration) *FailureCounter {
+	return &FailureCounter{window: window, clock: clock.Real{}}
+}
+
+// Record counts alert if it is an error and returns the number of error
+// alerts within the window
+func (c *FailureCounter) Record(alert *types.FluxAlert) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	expired := 0
+	for expired < len(c.failures) && now.Sub(c.failures[expired]) >= c.window {
+		expired++
+	}
+	c.failures = c.failures[expired:]
+
+	if severity, _ := NormalizeSeverity(alert.Severity); severity == types.SeverityError {
+		c.failures = append(c.failures, now)
+	}
+	return len(c.failures)
+}
+
+// BuildGlance summarizes alert for the Glances widget: the cluster as title,
+// the reason and severity as text, the object as subtext and the recent
+// failures as count (pure function)
+func BuildGlance(cfg *config.Config, alert *types.FluxAlert, failures int) *types.PushoverGlance {
+	severity, _ := NormalizeSeverity(alert.Severity)
+	kind := defaultIfEmpty(alert.InvolvedObject.Kind, types.DefaultValue)
+	if !cfg.PreserveKindCase {
+		kind = strings.ToLower(kind)
+	}
+
+	return &types.PushoverGlance{
+		Token:   cfg.PushoverAPIToken,
+		User:    cfg.PushoverUserKey,
+		Title:   truncateMessage(defaultIfEmpty(cfg.ClusterName, types.AppTitle), "", types.MaxGlanceLength),
+		Text:    truncateMessage(defaultIfEmpty(alert.Reason, types.DefaultValue)+" ["+strings.ToUpper(severity)+"]", "", types.MaxGlanceLength),
+		Subtext: truncateMessage(kind+"/"+alertName(alert), "", types.MaxGlanceLength),
+		Count:   &failures,
+	}
+}
+
+// glanceSender updates the Glances widget alongside or instead of sending
+// Pushover messages
+type glanceSender struct {
+	messages notify.NotificationSender // nil sends Glances only
+	client   GlanceClient
+	cfg      *config.Config
+	failures *FailureCounter
+	logger   server.Logger
+}
+
+// newGlanceSender wraps messages with Glances updates, messages is nil for
+// GLANCES=instead
+func newGlanceSender(cfg *config.Config, messages notify.NotificationSender, client GlanceClient, logger server.Logger) notify.NotificationSender {
+	return &glanceSender{
+		messages: messages,
+		client:   client,
+		cfg:      cfg,
+		failures: NewFailureCounter(glanceFailureWindow),
+		logger:   logger,
+	}
+}
+
+// Name returns the provider name
+func (g *glanceSender) Name() string {
+	return config.ProviderPushover
+}
+
+// Send sends the message, then updates the widget. Alongside messages a
+// failed Glances update is only logged, the message was delivered.
+func (g *glanceSender) Send(ctx context.Context, n *notify.Notification) error {
+	if g.messages != nil {
+		if err := g.messages.Send(ctx, n); err != nil {
+			return err
+		}
+	}
+
+	alert := n.Event
+	if alert == nil {
+		alert = &types.FluxAlert{Severity: n.Severity}
+	}
+	err := g.client.SendGlance(ctx, BuildGlance(g.cfg, alert, g.failures.Record(alert)))
+	if err != nil && g.messages != nil {
+		g.logger.Printf("Failed to update Pushover Glances: %v", err)
+		return nil
+	}
+	return err
+}
+
+// BuildFailingGlance summarizes the objects in error state for the Glances
+// widget: "prod: 2 failing" as text, the objects as subtext and their
+// number as count (pure function)
+func BuildFailingGlance(cfg *config.Config, failing []string) *types.PushoverGlance {
+	count := len(failing)
+	text := strconv.Itoa(count) + " failing"
+	if cfg.ClusterName != "" {
+		text = cfg.ClusterName + ": " + text
+	}
+
+	return &types.PushoverGlance{
+		Token:   cfg.PushoverAPIToken,
+		User:    cfg.PushoverUserKey,
+		Title:   truncateMessage(defaultIfEmpty(cfg.ClusterName, types.AppTitle), "", types.MaxGlanceLength),
+		Text:    truncateMessage(text, "", types.MaxGlanceLength),
+		Subtext: truncateMessage(strings.Join(failing, ", "), "", types.MaxGlanceLength),
+		Count:   &count,
+	}
+}
+
+// GlanceUpdater keeps the Glances widget showing the objects currently in
+// error state, for ENABLE_GLANCES. An error alert marks its object failing
+// and an info alert recovers it, warnings leave it as it was. Changes are
+// debounced and updates rate-limited. A nil GlanceUpdater updates nothing.
+type GlanceUpdater struct {
+	client      GlanceClient
+	cfg         *config.Config
+	logger      server.Logger
+	debounce    time.Duration
+	minInterval time.Duration
+	clock       clock.Clock
+
+	mu      sync.Mutex
+	failing map[string]bool // Keyed by emergencyObject
+	sent    int             // Count last shown, -1 before the first update
+
+	changed  chan struct{}
+	started  atomic.Bool
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+
+	count    *metrics.Gauge
+	failures *metrics.Counter
+}
+
+// NewGlanceUpdater creates an updater of the Glances widget through client
+func NewGlanceUpdater(cfg *config.Config, client GlanceClient, logger server.Logger, registry *metrics.Registry) *GlanceUpdater {
+	return &GlanceUpdater{
+		client:      client,
+		cfg:         cfg,
+		logger:      logger,
+		debounce:    glanceDebounce,
+		minInterval: glanceMinInterval,
+		clock:       clock.Real{},
+		failing:     make(map[string]bool),
+		sent:        -1,
+		changed:     make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+		count:       registry.Gauge("pushover_glance_failing_objects", "Objects in error state shown on the Glances widget"),
+		failures:    registry.Counter("pushover_glance_update_failures_total", "Glances widget updates that failed"),
+	}
+}
+
+// Observe records the state of a delivered alert's object and reports
+// whether the number of failing objects changed
+func (u *GlanceUpdater) Observe(alert *types.FluxAlert) bool {
+	if u == nil {
+		return false
+	}
+
+	object := emergencyObject(alert)
+	severity, _ := NormalizeSeverity(alert.Severity)
+
+	u.mu.Lock()
+	before := len(u.failing)
+	switch severity {
+	case types.SeverityError:
+		u.failing[object] = true
+	case types.SeverityInfo:
+		delete(u.failing, object)
+	}
+	count := len(u.failing)
+	u.mu.Unlock()
+
+	if count == before {
+		return false
+	}
+	u.count.Set(int64(count))
+	select {
+	case u.changed <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// Failing returns the failing objects, sorted
+func (u *GlanceUpdater) Failing() []string {
+	if u == nil {
+		return nil
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	failing := make([]string, 0, len(u.failing))
+	for object := range u.failing {
+		failing = append(failing, object)
+	}
+	slices.Sort(failing)
+	return failing
+}
+
+// Update shows the failing objects on the widget unless their number is
+// already shown, and reports whether the widget is up to date
+func (u *GlanceUpdater) Update(ctx context.Context) bool {
+	failing := u.Failing()
+
+	u.mu.Lock()
+	shown := u.sent == len(failing)
+	u.mu.Unlock()
+	if shown {
+		return true
+	}
+
+	if err := u.client.SendGlance(ctx, BuildFailingGlance(u.cfg, failing)); err != nil {
+		u.failures.Inc()
+		u.logger.Printf("Failed to update Pushover Glances with %d failing objects: %v", len(failing), err)
+		return false
+	}
+
+	u.mu.Lock()
+	u.sent = len(failing)
+	u.mu.Unlock()
+	return true
+}
+
+// Start updates the widget in the background until Stop or ctx is cancelled
+func (u *GlanceUpdater) Start(ctx context.Context) {
+	if u == nil || u.started.Swap(true) {
+		return
+	}
+	go u.run(ctx)
+}
+
+// Stop ends updating and waits for an ongoing update to finish
+func (u *GlanceUpdater) Stop(ctx context.Context) error {
+	if u == nil || !u.started.Load() {
+		return nil
+	}
+	u.stopOnce.Do(func() { close(u.stop) })
+
+	select {
+	case <-u.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run updates the widget debounce after a change, at most once every
+// minInterval, retrying failed updates
+func (u *GlanceUpdater) run(ctx context.Context) {
+	defer close(u.done)
+
+	// Updates in flight are cancelled by Stop too
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-u.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var last time.Time
+	retry := false
+	for {
+		if !retry {
+			select {
+			case <-u.changed:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		// Changes arriving meanwhile are shown by this update
+		delay := max(u.debounce, last.Add(u.minInterval).Sub(u.clock.Now()))
+		if !sleep(ctx, u.clock, delay) {
+			return
+		}
+		last = u.clock.Now()
+		retry = !u.Update(ctx)
+	}
+}
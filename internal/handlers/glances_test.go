@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/notify"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// MockGlanceClient is a mock implementation of GlanceClient
+type MockGlanceClient struct {
+	mu      sync.Mutex
+	glances []*types.PushoverGlance
+	err     error
+}
+
+func (m *MockGlanceClient) SendGlance(ctx context.Context, glance *types.PushoverGlance) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.glances = append(m.glances, glance)
+	return m.err
+}
+
+// counts returns the counts of the glances sent so far
+func (m *MockGlanceClient) counts() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := make([]int, 0, len(m.glances))
+	for _, glance := range m.glances {
+		counts = append(counts, *glance.Count)
+	}
+	return counts
+}
+
+func TestFailureCounter(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	counter := NewFailureCounter(time.Hour)
+	counter.clock = fake
+
+	steps := []struct {
+		advance  time.Duration
+		severity string
+		expected int
+	}{
+		{0, "error", 1},
+		{time.Minute, "info", 1},
+		{time.Minute, "ERROR", 2},
+		{59 * time.Minute, "warning", 1}, // The first error is an hour old
+		{time.Minute, "info", 0},
+	}
+
+	for i, step := range steps {
+		fake.Advance(step.advance)
+		if count := counter.Record(&types.FluxAlert{Severity: step.severity}); count != step.expected {
+			t.Errorf("Step %d: expected %d failures, got %d", i, step.expected, count)
+		}
+	}
+}
+
+func TestBuildGlance(t *testing.T) {
+	cfg := &config.Config{PushoverAPIToken: "token", PushoverUserKey: "user"}
+	alert := &types.FluxAlert{Severity: "warn", Reason: "DependencyNotReady"}
+	alert.InvolvedObject.Kind = "HelmRelease"
+	alert.InvolvedObject.Name = "podinfo"
+
+	glance := BuildGlance(cfg, alert, 2)
+	if glance.Token != "token" || glance.User != "user" || glance.Title != types.AppTitle ||
+		glance.Text != "DependencyNotReady [WARNING]" || glance.Subtext != "helmrelease/podinfo" || *glance.Count != 2 {
+		t.Errorf("Unexpected glance %+v", glance)
+	}
+
+	cfg.ClusterName = "prod"
+	cfg.PreserveKindCase = true
+	alert.Reason = strings.Repeat("r", 150)
+	glance = BuildGlance(cfg, alert, 0)
+	if glance.Title != "prod" || glance.Subtext != "HelmRelease/podinfo" {
+		t.Errorf("Unexpected glance %+v", glance)
+	}
+	if len([]rune(glance.Text)) != types.MaxGlanceLength || !strings.HasSuffix(glance.Text, types.TruncationMarker) {
+		t.Errorf("Expected text truncated to %d characters, got %q", types.MaxGlanceLength, glance.Text)
+	}
+}
+
+func TestGlanceSender(t *testing.T) {
+	sendErr := errors.New("pushover down")
+	glanceErr := errors.New("glances down")
+
+	tests := []struct {
+		name        string
+		alongside   bool
+		messageErr  error
+		glanceErr   error
+		expectedErr error
+		glances     int
+	}{
+		{"alongside", true, nil, nil, nil, 1},
+		{"alongside ignores glance failures", true, nil, glanceErr, nil, 1},
+		{"alongside skips glance when the message fails", true, sendErr, nil, sendErr, 0},
+		{"instead", false, nil, nil, nil, 1},
+		{"instead reports glance failures", false, nil, glanceErr, glanceErr, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{PushoverAPIToken: "token", PushoverUserKey: "user"}
+			messages := 0
+			var messageSender notify.NotificationSender
+			if tt.alongside {
+				messageSender = newPushoverSender(cfg, &MockPushoverClient{
+					SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+						messages++
+						return tt.messageErr
+					},
+				}, nil, &MockLogger{})
+			}
+			client := &MockGlanceClient{err: tt.glanceErr}
+			sender := newGlanceSender(cfg, messageSender, client, &MockLogger{})
+
+			alert := &types.FluxAlert{Severity: "error", Reason: "HealthCheckFailed"}
+			err := sender.Send(context.Background(), CreateNotification(alert, "message"))
+			if !errors.Is(err, tt.expectedErr) || (err == nil) != (tt.expectedErr == nil) {
+				t.Errorf("Expected error %v, got %v", tt.expectedErr, err)
+			}
+			if len(client.glances) != tt.glances {
+				t.Errorf("Expected %d glances, got %d", tt.glances, len(client.glances))
+			}
+			if tt.alongside && messages != 1 {
+				t.Errorf("Expected the message to be sent, got %d", messages)
+			}
+			if !tt.alongside && messages != 0 {
+				t.Errorf("Expected no message, got %d", messages)
+			}
+			if sender.Name() != "pushover" {
+				t.Errorf("Expected pushover provider, got %s", sender.Name())
+			}
+		})
+	}
+}
+
+func TestCreateNotifier_GlancesInstead(t *testing.T) {
+	var requests []string
+	var form url.Values
+	httpClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			requests = append(requests, req.URL.String())
+			body, _ := io.ReadAll(req.Body)
+			form, _ = url.ParseQuery(string(body))
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"status":1}`))}, nil
+		},
+	}
+	cfg := &config.Config{
+		PushoverAPIToken: "token",
+		PushoverUserKey:  "user",
+		PushoverURL:      "https://api.pushover.net/1/messages.json",
+		Glances:          config.GlancesInstead,
+	}
+	pushoverClient := &MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			t.Error("Expected no message with GLANCES=instead")
+			return nil
+		},
+	}
+
+	notifier, err := CreateNotifier(cfg, httpClient, pushoverClient, &MockLogger{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	alert := &types.FluxAlert{Severity: "error", Reason: "HealthCheckFailed"}
+	alert.InvolvedObject.Kind = "Kustomization"
+	alert.InvolvedObject.Name = "apps"
+	if _, err := notifier.Send(context.Background(), CreateNotification(alert, "message")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(requests) != 1 || requests[0] != "https://api.pushover.net/1/glances.json" {
+		t.Fatalf("Expected a single Glances request, got %v", requests)
+	}
+	expected := url.Values{
+		"token":   {"token"},
+		"user":    {"user"},
+		"title":   {"FluxCD"},
+		"text":    {"HealthCheckFailed [ERROR]"},
+		"subtext": {"kustomization/apps"},
+		"count":   {"1"},
+	}
+	if form.Encode() != expected.Encode() {
+		t.Errorf("Expected form %s, got %s", expected.Encode(), form.Encode())
+	}
+}
+
+// failingAlert returns an alert about kustomization/flux-system/name
+func failingAlert(name, severity string) *types.FluxAlert {
+	alert := &types.FluxAlert{Severity: severity, Reason: "ReconciliationFailed"}
+	alert.InvolvedObject.Kind = "Kustomization"
+	alert.InvolvedObject.Namespace = "flux-system"
+	alert.InvolvedObject.Name = name
+	return alert
+}
+
+func TestBuildFailingGlance(t *testing.T) {
+	cfg := &config.Config{PushoverAPIToken: "token", PushoverUserKey: "user", ClusterName: "prod"}
+
+	glance := BuildFailingGlance(cfg, []string{"HelmRelease/default/podinfo", "Kustomization/flux-system/apps"})
+	if glance.Token != "token" || glance.User != "user" || glance.Title != "prod" || glance.Text != "prod: 2 failing" ||
+		glance.Subtext != "HelmRelease/default/podinfo, Kustomization/flux-system/apps" || *glance.Count != 2 {
+		t.Errorf("Unexpected glance %+v", glance)
+	}
+
+	glance = BuildFailingGlance(&config.Config{}, nil)
+	if glance.Title != types.AppTitle || glance.Text != "0 failing" || glance.Subtext != "" || *glance.Count != 0 {
+		t.Errorf("Unexpected glance %+v", glance)
+	}
+}
+
+func TestGlanceUpdater_Transitions(t *testing.T) {
+	client := &MockGlanceClient{}
+	updater := NewGlanceUpdater(&config.Config{}, client, &MockLogger{}, nil)
+
+	steps := []struct {
+		alert    *types.FluxAlert
+		changed  bool
+		expected []int // Counts sent so far
+	}{
+		{failingAlert("apps", "error"), true, []int{1}},
+		{failingAlert("apps", "error"), false, []int{1}},
+		{failingAlert("infra", "error"), true, []int{1, 2}},
+		{failingAlert("infra", "warning"), false, []int{1, 2}},
+		{failingAlert("apps", "info"), true, []int{1, 2, 1}},
+		{failingAlert("other", "info"), false, []int{1, 2, 1}},
+		{failingAlert("infra", "info"), true, []int{1, 2, 1, 0}},
+	}
+
+	for i, step := range steps {
+		if changed := updater.Observe(step.alert); changed != step.changed {
+			t.Errorf("Step %d: expected changed %v, got %v", i, step.changed, changed)
+		}
+		if !updater.Update(context.Background()) {
+			t.Errorf("Step %d: expected the update to succeed", i)
+		}
+		if counts := client.counts(); !slices.Equal(counts, step.expected) {
+			t.Errorf("Step %d: expected counts %v, got %v", i, step.expected, counts)
+		}
+	}
+
+	var nilUpdater *GlanceUpdater
+	if nilUpdater.Observe(failingAlert("apps", "error")) || nilUpdater.Failing() != nil || nilUpdater.Stop(context.Background()) != nil {
+		t.Error("Expected a nil updater to do nothing")
+	}
+}
+
+func TestGlanceUpdater_UpdateFailure(t *testing.T) {
+	client := &MockGlanceClient{err: errors.New("glances down")}
+	updater := NewGlanceUpdater(&config.Config{}, client, &MockLogger{}, metrics.NewRegistry())
+
+	updater.Observe(failingAlert("apps", "error"))
+	if updater.Update(context.Background()) {
+		t.Error("Expected the update to fail")
+	}
+
+	// The count is sent again once Pushover is back
+	client.err = nil
+	if !updater.Update(context.Background()) {
+		t.Error("Expected the retry to succeed")
+	}
+	if counts := client.counts(); !slices.Equal(counts, []int{1, 1}) {
+		t.Errorf("Expected the count retried, got %v", counts)
+	}
+	if updater.failures.Value() != 1 {
+		t.Errorf("Expected 1 failure counted, got %d", updater.failures.Value())
+	}
+}
+
+func TestGlanceUpdater_Debounce(t *testing.T) {
+	client := &MockGlanceClient{}
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	updater := NewGlanceUpdater(&config.Config{}, client, &MockLogger{}, nil)
+	updater.clock = fake
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updater.Start(ctx)
+
+	// A burst of failures is shown by a single update once it settled
+	updater.Observe(failingAlert("apps", "error"))
+	updater.Observe(failingAlert("infra", "error"))
+	updater.Observe(failingAlert("podinfo", "error"))
+	waitFor(t, func() bool { return fake.Waiters() == 1 })
+	if counts := client.counts(); len(counts) != 0 {
+		t.Errorf("Expected no update within the debounce window, got %v", counts)
+	}
+	fake.Advance(glanceDebounce)
+	waitFor(t, func() bool { return len(client.counts()) == 1 })
+
+	// The recovery waits for the minimum interval, then resets the widget
+	updater.Observe(failingAlert("apps", "info"))
+	updater.Observe(failingAlert("infra", "info"))
+	updater.Observe(failingAlert("podinfo", "info"))
+	waitFor(t, func() bool { return fake.Waiters() == 1 })
+	fake.Advance(glanceDebounce)
+	if counts := client.counts(); len(counts) != 1 || fake.Waiters() != 1 {
+		t.Errorf("Expected no update within the minimum interval, got %v", counts)
+	}
+	fake.Advance(glanceMinInterval - glanceDebounce)
+	waitFor(t, func() bool { return len(client.counts()) == 2 })
+
+	if err := updater.Stop(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if counts := client.counts(); !slices.Equal(counts, []int{3, 0}) {
+		t.Errorf("Expected counts [3 0], got %v", counts)
+	}
+}
+
+// waitFor polls cond for up to two seconds
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCreateWebhookHandler_ObservesGlances(t *testing.T) {
+	updater := NewGlanceUpdater(&config.Config{}, &MockGlanceClient{}, &MockLogger{}, nil)
+	deps := &HandlerDependencies{
+		Config:         &config.Config{PushoverAPIToken: "token", BearerToken: "Bearer token"},
+		PushoverClient: &MockPushoverClient{},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		Glances:        updater,
+	}
+	handler := CreateWebhookHandler(deps)
+	post := func(body string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer token")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	post(`{"severity":"error","reason":"UpgradeFailed","involvedObject":{"kind":"HelmRelease","namespace":"apps","name":"podinfo"}}`)
+	if failing := updater.Failing(); !slices.Equal(failing, []string{"HelmRelease/apps/podinfo"}) {
+		t.Errorf("Expected podinfo failing, got %v", failing)
+	}
+
+	post(`{"severity":"info","reason":"UpgradeSucceeded","involvedObject":{"kind":"HelmRelease","namespace":"apps","name":"podinfo"}}`)
+	if failing := updater.Failing(); len(failing) != 0 {
+		t.Errorf("Expected podinfo recovered, got %v", failing)
+	}
+}
@@ -1,22 +1,40 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/zhorvath83/flux-provider-pushover/internal/audit"
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/conditions"
 	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/forward"
+	"github.com/zhorvath83/flux-provider-pushover/internal/idempotency"
+	"github.com/zhorvath83/flux-provider-pushover/internal/kube"
+	"github.com/zhorvath83/flux-provider-pushover/internal/logsample"
+	"github.com/zhorvath83/flux-provider-pushover/internal/maintenance"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/notify"
 	"github.com/zhorvath83/flux-provider-pushover/internal/pushover"
+	"github.com/zhorvath83/flux-provider-pushover/internal/ratelimit"
 	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+	"github.com/zhorvath83/flux-provider-pushover/internal/store"
+	"github.com/zhorvath83/flux-provider-pushover/internal/telemetry"
 	"github.com/zhorvath83/flux-provider-pushover/internal/types"
 )
 
 // PushoverSender interface for sending messages
-type PushoverSender interface {
-	SendMessage(ctx context.Context, msg *types.PushoverMessage) error
-}
+type PushoverSender = notify.PushoverMessageSender
 
 // HandlerDependencies contains all dependencies for handlers
 type HandlerDependencies struct {
@@ -24,13 +42,235 @@ type HandlerDependencies struct {
 	PushoverClient PushoverSender
 	Logger         server.Logger
 	MessageBuilder MessageBuilder
+	Responses      *types.Responses        // nil means types.DefaultResponses()
+	Notifier       *notify.Coordinator     // nil means Pushover only via PushoverClient
+	Forwarder      *forward.Forwarder      // nil disables event mirroring
+	Metrics        *metrics.Registry       // nil disables metrics
+	Events         kube.EventEmitter       // nil disables Kubernetes Events
+	Elector        kube.LeaderElector      // nil means this replica always delivers
+	SendStatus     *pushover.StatusTracker // nil reports no last send error and never fails readiness for it
+	Dedup          store.Store             // nil disables deduplication
+	State          store.Store             // nil disables NOTIFY_ON_CHANGE_ONLY
+	Transitions    *TransitionTracker      // nil disables STATEFUL_NOTIFY
+	Idempotency    *idempotency.Cache      // nil disables replay protection
+	Tracer         *telemetry.Tracer       // nil disables tracing
+	Freshness      *FreshnessChecker       // nil accepts events of any age
+	RateLimiter    *ratelimit.KeyedLimiter // nil disables per-namespace rate limiting
+	Shedder        *LoadShedder            // nil sends every delivery at once
+	Coalescer      *Coalescer              // nil delivers every alert on its own
+	Dispatcher     *Dispatcher             // nil delivers coalesced alerts in the flushing goroutine
+	Health         *server.HealthState     // nil means always healthy
+	Audit          *audit.Logger           // nil disables the audit log
+	Emergencies    *EmergencyTracker       // nil disables acknowledgement tracking
+	Receipts       *ReceiptPoller          // nil disables receipt polling
+	Canceller      *EmergencyCanceller     // nil leaves emergencies repeating after recovery
+	Glances        *GlanceUpdater          // nil disables the failing objects widget
+	FailureLog     *logsample.Sampler      // nil logs every delivery failure
+	QuietHours     *QuietHours             // nil disables quiet hours
+	Maintenance    *maintenance.Windows    // nil disables maintenance windows
+	Panics         *PanicReporter          // nil only logs panics
+	Watchdog       *Watchdog               // nil never notifies about the provider being degraded
+	Conditions     *conditions.Set         // nil reports no conditions on /status
+	Background     context.Context         // Cancelled after shutdown to end background sends, nil never is
+	Clock          clock.Clock             // nil means the system clock
+
+	// Stops sends with credentials Pushover rejected, nil never considers them invalid
+	Credentials *pushover.CredentialGuard
+
+	conditionsMu sync.Mutex // Orders the updates of Ready and QueueHealthy
+}
+
+// Start launches background work needed before serving requests
+func (d *HandlerDependencies) Start() {
+	if d.Elector != nil {
+		d.Elector.Start()
+	}
+	d.Receipts.Start(d.background())
+	d.Glances.Start(d.background())
+	d.Watchdog.Start(d.background())
+	d.Credentials.Start(d.background())
+}
+
+// Drain waits for background work started by the handlers to finish
+func (d *HandlerDependencies) Drain(ctx context.Context) error {
+	// Pending groups are flushed first, their sends are still traced
+	errs := []error{d.Coalescer.Drain(ctx), d.flushDispatcher(ctx)}
+	if d.Forwarder != nil {
+		errs = append(errs, d.Forwarder.Drain(ctx))
+	}
+	if d.Elector != nil {
+		errs = append(errs, d.Elector.Stop(ctx))
+	}
+	errs = append(errs, d.Receipts.Stop(ctx))
+	errs = append(errs, d.Canceller.Drain(ctx))
+	errs = append(errs, d.Glances.Stop(ctx))
+	errs = append(errs, d.Panics.Drain(ctx))
+	errs = append(errs, d.Watchdog.Stop(ctx))
+	errs = append(errs, d.Credentials.Stop(ctx))
+	errs = append(errs, d.Audit.Close(ctx))
+	errs = append(errs, d.FailureLog.Close(ctx))
+	errs = append(errs, d.Tracer.Shutdown(ctx))
+	return errors.Join(errs...)
+}
+
+// flushDispatcher delivers the queued deliveries within ctx, logging how
+// many were delivered and dropped
+func (d *HandlerDependencies) flushDispatcher(ctx context.Context) error {
+	if d.Dispatcher == nil {
+		return nil
+	}
+
+	delivered, dropped, err := d.Dispatcher.Flush(ctx)
+	if delivered > 0 || dropped > 0 {
+		d.Logger.Printf("Final flush: delivered %d queued deliveries, dropped %d", delivered, dropped)
+	}
+	return err
+}
+
+// QueueDepth returns the number of deliveries waiting for a dispatch worker
+// or a delivery slot
+func (d *HandlerDependencies) QueueDepth() int64 {
+	return d.Dispatcher.Pending() + int64(d.Shedder.Waiting())
+}
+
+// DebugVars returns the values published on the debug listener's /debug/vars
+func (d *HandlerDependencies) DebugVars() map[string]expvar.Var {
+	return map[string]expvar.Var{
+		"forward_queue_depth": expvar.Func(func() interface{} {
+			return d.Forwarder.Pending()
+		}),
+		"delivery_queue_depth": expvar.Func(func() interface{} {
+			return d.Shedder.Waiting()
+		}),
+		"coalesce_pending": expvar.Func(func() interface{} {
+			return d.Coalescer.Pending()
+		}),
+		"dispatch_queue_depth": expvar.Func(func() interface{} {
+			return d.Dispatcher.Pending()
+		}),
+		"emergency_pending": expvar.Func(func() interface{} {
+			return d.Emergencies.Pending()
+		}),
+		"stateful_objects": expvar.Func(func() interface{} {
+			return d.Transitions.Len()
+		}),
+		"idempotency_keys": expvar.Func(func() interface{} {
+			if d.Idempotency == nil {
+				return 0
+			}
+			return d.Idempotency.Len()
+		}),
+		"metrics": expvar.Func(func() interface{} {
+			return d.Metrics.Snapshot()
+		}),
+	}
+}
+
+// background returns the parent context of sends outliving their request
+func (d *HandlerDependencies) background() context.Context {
+	if d.Background == nil {
+		return context.Background()
+	}
+	return d.Background
+}
+
+// clock returns the configured clock or the system clock
+func (d *HandlerDependencies) clock() clock.Clock {
+	if d.Clock == nil {
+		return clock.Real{}
+	}
+	return d.Clock
+}
+
+// elector returns the configured elector or a standalone one
+func (d *HandlerDependencies) elector() kube.LeaderElector {
+	if d.Elector == nil {
+		return kube.StandaloneElector{}
+	}
+	return d.Elector
+}
+
+// responses returns the configured response set or the defaults
+func (d *HandlerDependencies) responses() *types.Responses {
+	if d.Responses == nil {
+		return types.DefaultResponses()
+	}
+	return d.Responses
+}
+
+// notifier returns the configured coordinator or a Pushover-only one
+func (d *HandlerDependencies) notifier() *notify.Coordinator {
+	if d.Notifier == nil {
+		return notify.NewCoordinator(notify.ModeFanOut, newPushoverSender(d.Config, d.PushoverClient, nil, d.Logger))
+	}
+	return d.Notifier
+}
+
+// newPushoverSender adapts a PushoverSender to notify.NotificationSender,
+// selecting the sound by reason or severity, applying the Pushover options
+// overridden by the alert's metadata and attaching the image fetched by
+// images, nil fetching none, or else the event message the body overflowed
+// with ATTACH_OVERFLOW. Quiet messages of quiet hours expire after QUIET_TTL.
+func newPushoverSender(cfg *config.Config, client PushoverSender, images *ImageFetcher, logger server.Logger) notify.NotificationSender {
+	return notify.NewPushoverSender(client, func(ctx context.Context, n *notify.Notification) *types.PushoverMessage {
+		msg := CreatePushoverMessage(cfg, n.Body, n.Severity)
+		if n.Event != nil {
+			if cfg.AttachOverflow {
+				if attachment := OverflowAttachment(n.Body, n.Event.Message, cfg.AttachmentMaxBytes); attachment != nil {
+					msg.Attachment, msg.AttachmentName = attachment, types.OverflowAttachmentName
+				}
+			}
+			// Pushover takes a single attachment, the image is preferred
+			if image, name, err := images.Fetch(ctx, n.Event); err != nil {
+				logger.Printf("Sending without the image of %s/%s: %v", alertKind(n.Event), alertName(n.Event), err)
+			} else if image != nil {
+				msg.Attachment, msg.AttachmentName = image, name
+			}
+			msg.Sound = SelectSound(cfg, n.Event.Reason, n.Severity)
+			for _, err := range ApplyMetadataOverrides(msg, n.Event.Metadata, cfg.MetadataPrefix) {
+				logger.Printf("Ignoring Pushover override, using the default: %v", err)
+			}
+		}
+		// Overridden titles are shortened too
+		msg.Title = truncateTitle(msg.Title, cfg.MaxTitleLength)
+		if n.Silent {
+			priority := types.MinPriority
+			msg.Priority = &priority
+		}
+		if n.Quiet {
+			ApplyQuietTTL(msg, cfg.QuietTTL)
+		}
+		msg.Callback = CallbackURL(cfg)
+		return msg
+	})
 }
 
-// CreateRootHandler creates a handler for the root endpoint (pure function)
-func CreateRootHandler() http.HandlerFunc {
+// credentialsInvalidNotice logs that Pushover rejected the credentials and
+// attempts to tell the user once, which only gets through when the error
+// severity's credentials are not the rejected ones
+func credentialsInvalidNotice(cfg *config.Config, sender PushoverSender, logger server.Logger) func(context.Context, pushover.CredentialsState) {
+	return func(ctx context.Context, state pushover.CredentialsState) {
+		logger.Printf("ERROR: Pushover rejected the credentials, sends with them fail until they are replaced: %s", state.Reason)
+
+		message := CreatePushoverMessage(cfg, "Pushover rejected the credentials of the Flux provider, alerts sent with them are dropped until they are replaced: "+state.Reason, types.SeverityError)
+		if err := sender.SendMessage(ctx, message); err != nil {
+			logger.Printf("Failed to notify about the invalid Pushover credentials: %v", err)
+		}
+	}
+}
+
+// CreateRootHandler creates a handler for the root endpoint (pure function).
+// It answers 400 pointing at /webhook, or 200 with a short info body when
+// rootOK is set for load balancers probing "/".
+func CreateRootHandler(rootOK bool) http.HandlerFunc {
+	status, body := http.StatusBadRequest, types.ResponseRootError
+	if rootOK {
+		status, body = http.StatusOK, types.ResponseRootInfo
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusBadRequest)
-		if _, err := w.Write(types.ResponseRootError); err != nil {
+		w.WriteHeader(status)
+		if _, err := w.Write(body); err != nil {
 			// Response header already written, can't do much more
 			// This error is logged by the HTTP server itself
 			return
@@ -38,11 +278,29 @@ func CreateRootHandler() http.HandlerFunc {
 	}
 }
 
-// CreateHealthHandler creates a handler for the health endpoint (pure function)
-func CreateHealthHandler() http.HandlerFunc {
+// CreateHealthHandler creates a handler for the health endpoint. HEAD gets
+// the GET headers without a body, other methods are rejected. It answers 503
+// once health is draining before shutdown.
+func CreateHealthHandler(health *server.HealthState) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		if _, err := w.Write(types.ResponseHealthy); err != nil {
+		status, body := http.StatusOK, types.ResponseHealthy
+		if health.Draining() {
+			status, body = http.StatusServiceUnavailable, types.ResponseDraining
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodHead:
+			w.WriteHeader(status)
+			return
+		default:
+			w.Header().Set("Allow", "GET, HEAD")
+			writeJSONResponse(w, http.StatusMethodNotAllowed, types.ResponseMethodNotAllowed)
+			return
+		}
+
+		w.WriteHeader(status)
+		if _, err := w.Write(body); err != nil {
 			// Response header already written, can't do much more
 			// This error is logged by the HTTP server itself
 			return
@@ -50,26 +308,388 @@ func CreateHealthHandler() http.HandlerFunc {
 	}
 }
 
+// readinessResponse is the body of a failed readiness check
+type readinessResponse struct {
+	Status        string `json:"status"`
+	LastError     string `json:"last_error,omitempty"`
+	LastErrorTime string `json:"last_error_time,omitempty"`
+	QueueDepth    int64  `json:"queue_depth,omitempty"`
+}
+
+// CreateReadyHandler creates a handler for the readiness endpoint. It fails
+// while Pushover rejects the credentials and for SEND_FAILURE_UNREADY_WINDOW
+// after a failed send, reporting that error, and while more than
+// QUEUE_UNHEALTHY_DEPTH deliveries wait, reporting their number.
+func CreateReadyHandler(deps *HandlerDependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := deps.readiness()
+		switch {
+		case response == nil:
+			writeJSONResponse(w, http.StatusOK, types.ResponseReady)
+			return
+		case response.Status == "starting":
+			writeJSONResponse(w, http.StatusServiceUnavailable, types.ResponseStarting)
+			return
+		}
+
+		body, err := json.Marshal(response)
+		if err != nil {
+			writeJSONResponse(w, http.StatusInternalServerError, types.ResponseInternalError)
+			return
+		}
+		writeJSONResponse(w, http.StatusServiceUnavailable, body)
+	}
+}
+
+// readinessReasons are the Ready condition reasons of readiness statuses
+var readinessReasons = map[string]string{
+	"starting":            "Starting",
+	"credentials invalid": "CredentialsInvalid",
+	"not ready":           "SendFailed",
+	"queue backed up":     "QueueBackedUp",
+}
+
+// readiness returns why the replica is not ready, nil when it is
+func (d *HandlerDependencies) readiness() *readinessResponse {
+	// Not ready before the listener is bound and startup checks passed
+	if !d.Health.Started() {
+		return &readinessResponse{Status: "starting"}
+	}
+	// Rejected credentials stay not ready until the guard's recheck finds
+	// Pushover accepting them again
+	if state := d.Credentials.Invalid(); state != nil {
+		return &readinessResponse{
+			Status:        "credentials invalid",
+			LastError:     state.Reason,
+			LastErrorTime: state.Since.UTC().Format(time.RFC3339),
+		}
+	}
+	// A failed send only fails readiness for a while, since an unready
+	// replica gets no webhooks whose successful send would clear it
+	if last := d.lastSendError(); last != nil && d.clock().Now().Before(last.Time.Add(d.Config.SendFailureUnreadyWindow)) {
+		return &readinessResponse{
+			Status:        "not ready",
+			LastError:     last.Message,
+			LastErrorTime: last.Time.UTC().Format(time.RFC3339),
+		}
+	}
+	if depth, backedUp := d.queueBackedUp(); backedUp {
+		return &readinessResponse{Status: "queue backed up", QueueDepth: depth}
+	}
+	return nil
+}
+
+// queueBackedUp returns the queue depth and whether it exceeds
+// QUEUE_UNHEALTHY_DEPTH
+func (d *HandlerDependencies) queueBackedUp() (int64, bool) {
+	depth, limit := d.QueueDepth(), int64(d.Config.QueueUnhealthyDepth)
+	return depth, limit > 0 && depth > limit
+}
+
+// MarkStarted marks startup as complete, making the replica ready unless
+// something else keeps it from being so
+func (d *HandlerDependencies) MarkStarted() {
+	d.Health.MarkStarted()
+	d.updateConditions()
+}
+
+// watchConditions keeps the Ready and QueueHealthy conditions current,
+// updating them whenever the queue depth or another condition changes
+func (d *HandlerDependencies) watchConditions() {
+	d.Conditions.OnChange(func(condType string) {
+		if condType != conditions.Ready && condType != conditions.QueueHealthy {
+			d.updateConditions()
+		}
+	})
+	d.Dispatcher.OnDepthChange(d.updateConditions)
+	d.Shedder.OnDepthChange(d.updateConditions)
+	d.updateConditions()
+}
+
+// updateConditions sets the Ready and QueueHealthy conditions from the
+// current state
+func (d *HandlerDependencies) updateConditions() {
+	if d.Conditions == nil {
+		return
+	}
+
+	d.conditionsMu.Lock()
+	defer d.conditionsMu.Unlock()
+
+	depth, backedUp := d.queueBackedUp()
+	queueReason := "BelowThreshold"
+	if backedUp {
+		queueReason = "QueueBackedUp"
+	}
+	d.Conditions.Set(conditions.QueueHealthy, conditions.FromBool(!backedUp), queueReason, fmt.Sprintf("%d deliveries waiting", depth))
+
+	if response := d.readiness(); response == nil {
+		d.Conditions.Set(conditions.Ready, conditions.StatusTrue, "Ready", "")
+	} else {
+		d.Conditions.Set(conditions.Ready, conditions.StatusFalse, readinessReasons[response.Status], response.LastError)
+	}
+}
+
+// statusResponse is the body of the status endpoint
+type statusResponse struct {
+	Conditions     []conditions.Condition     `json:"conditions,omitempty"`
+	LeaderElection kube.LeaderStatus          `json:"leader_election"`
+	Emergencies    []ReceiptInfo              `json:"emergencies,omitempty"`
+	Credentials    *pushover.CredentialsState `json:"credentials_invalid,omitempty"`
+	LastSendError  *pushover.SendError        `json:"last_send_error,omitempty"`
+	Maintenance    []maintenance.Status       `json:"maintenance_windows,omitempty"`
+}
+
+// CreateStatusHandler creates a handler reporting runtime state such as leadership
+func CreateStatusHandler(deps *HandlerDependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// SEND_FAILURE_UNREADY_WINDOW may have passed since the last update
+		deps.updateConditions()
+		body, err := json.Marshal(statusResponse{
+			Conditions:     deps.Conditions.List(),
+			LeaderElection: deps.elector().Status(),
+			Emergencies:    deps.Emergencies.Snapshot(),
+			Credentials:    deps.Credentials.Invalid(),
+			LastSendError:  deps.lastSendError(),
+			Maintenance:    deps.Maintenance.Status(),
+		})
+		if err != nil {
+			writeJSONResponse(w, http.StatusInternalServerError, types.ResponseInternalError)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, body)
+	}
+}
+
+// lastSendError returns the error of the latest send, nil when it succeeded
+// or sends are not tracked
+func (d *HandlerDependencies) lastSendError() *pushover.SendError {
+	if d.SendStatus == nil {
+		return nil
+	}
+	return d.SendStatus.LastError()
+}
+
+// alertPool reuses the alert decoded for single-alert webhooks
+var alertPool = sync.Pool{
+	New: func() interface{} { return new(types.FluxAlert) },
+}
+
 // CreateWebhookHandler creates a webhook handler with dependencies
 func CreateWebhookHandler(deps *HandlerDependencies) http.HandlerFunc {
+	responses := deps.responses()
+	notifier := deps.notifier()
+	legacyResponse := isPushoverOnly(notifier)
+	elector := deps.elector()
+	auth := NewAuthenticator(deps.Config, deps.Metrics)
+	decode := schemaDecoder(alertDecoder(deps.Config), deps.Logger, deps.Metrics)
+	validate := alertValidator(deps.Config, deps.Logger)
+	retriesExhausted := deps.Metrics.Counter("pushover_retries_exhausted_total", "Sends that failed after every retry attempt")
+	dropped := deps.Metrics.CounterVec("alerts_dropped_total", "Alerts acknowledged without a notification, by outcome and rule", "outcome", "rule")
+	received := deps.Metrics.CounterVec("alerts_received_total", "Alerts accepted on the webhook, by the cluster they come from", "cluster")
+
+	// deliver mirrors, deduplicates and sends a validated alert
+	deliver := func(w http.ResponseWriter, r *http.Request, alert *types.FluxAlert, raw []byte) {
+		received.WithLabelValues(alertCluster(deps.Config, alert)).Inc()
+
+		// Mirror the accepted event, failures never affect the response
+		if deps.Forwarder != nil {
+			deps.Forwarder.Forward(raw)
+		}
+
+		// Acknowledge dropped alerts with the reason, the sender must not retry them
+		requestID := r.Header.Get(RequestIDHeader)
+		checks := decide(deps, alert)
+		if !checks.Delivered() {
+			deps.Logger.Printf("Dropped alert for %s/%s/%s%s: %s by %s, %s", alertKind(alert), alert.InvolvedObject.Namespace, alertName(alert), inCluster(deps.Config, alert), checks.Outcome, checks.Rule, checks.Detail)
+			dropped.WithLabelValues(checks.Outcome, checks.Rule).Inc()
+			auditAlert(deps, requestID, alert, checks.Decision, nil, "")
+			writeJSONResponse(w, http.StatusOK, decisionResponse(checks.Decision))
+			return
+		}
+		dedupKey, state := checks.dedupKey, checks.state
+
+		// Hold back alerts to merge them with other events of the same object,
+		// flushes of one object are delivered in order by its dispatch worker
+		flush := func(alerts []*types.FluxAlert) {
+			deps.Dispatcher.Submit(ObjectKey(alerts[0]), func() { deliverCoalesced(deps, notifier, alerts) })
+		}
+		if deps.Coalescer.Add(alert, flush) {
+			auditAlert(deps, requestID, alert, Decision{Outcome: OutcomeQueued, Rule: "COALESCE_WINDOW"}, nil, "")
+			writeJSONResponse(w, http.StatusAccepted, types.ResponseQueued)
+			return
+		}
+
+		// Build message, a panicking builder fails the alert instead of the process
+		message, err := buildMessageSafely(deps, alert)
+		if err != nil {
+			releaseAlert(deps, dedupKey)
+			auditAlert(deps, requestID, alert, Decision{Outcome: OutcomeFailed}, err, "")
+			writeJSONResponse(w, http.StatusInternalServerError, types.ResponseInternalError)
+			return
+		}
+
+		// Special handling for test mode
+		if deps.Config.PushoverAPIToken == "test_api_token" {
+			deps.Logger.Println("Test mode: not sending to Pushover")
+			auditAlert(deps, requestID, alert, Decision{Outcome: OutcomeTestMode}, nil, "")
+			writeResponse(w, responses.ContentType, responses.OKStatus, responses.OK)
+			return
+		}
+
+		// Wait for a delivery slot, error alerts first, shedding others under pressure
+		release, err := deps.Shedder.Acquire(r.Context(), alert)
+		if err != nil {
+			releaseAlert(deps, dedupKey)
+			if errors.Is(err, errShed) {
+				decision := deps.Shedder.Decision()
+				deps.Logger.Printf("Dropped alert for %s/%s/%s%s: %s by %s, %s", alertKind(alert), alert.InvolvedObject.Namespace, alertName(alert), inCluster(deps.Config, alert), decision.Outcome, decision.Rule, decision.Detail)
+				dropped.WithLabelValues(decision.Outcome, decision.Rule).Inc()
+				auditAlert(deps, requestID, alert, decision, nil, "")
+				writeJSONResponse(w, http.StatusOK, decisionResponse(decision))
+				return
+			}
+			// The sender gave up waiting, it will resend
+			writeJSONResponse(w, http.StatusServiceUnavailable, types.ResponseNotDelivered)
+			return
+		}
+		defer release()
+
+		// Send within what is left of the request deadline, a send that
+		// would be cut off fails at once with a clean 503 instead
+		deadline, _ := r.Context().Deadline()
+		budget, ok := sendBudget(deps.Config.SendTimeout(), deadline, time.Now())
+		if !ok {
+			releaseAlert(deps, dedupKey)
+			deps.Logger.Printf("Not sending alert for %s/%s%s: %v", alertKind(alert), alertName(alert), inCluster(deps.Config, alert), errDeadlineBudget)
+			auditAlert(deps, requestID, alert, Decision{Outcome: OutcomeFailed}, errDeadlineBudget, "")
+			setRetryAfter(w, deps.Config, nil)
+			writeJSONResponse(w, http.StatusServiceUnavailable, sendErrorResponse(codeDeadline, errDeadlineBudget))
+			return
+		}
+
+		// Send notification to the configured providers
+		// Keep the request's trace context but not its cancellation
+		notification := CreateNotification(alert, message)
+		silence(notification, checks.quiet, checks.maintenanceDowngraded)
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), budget)
+		defer cancel()
+		ctx, pushoverIDs := pushover.WithRequestIDs(ctx)
+
+		results, err := notifier.Send(ctx, notification)
+		if err != nil {
+			releaseAlert(deps, dedupKey)
+			recordDeliveryFailure(deps, alert, err)
+			auditAlert(deps, requestID, alert, Decision{Outcome: OutcomeFailed}, err, pushoverIDs.Last())
+		} else {
+			recordState(deps, alert, state)
+			deps.Transitions.Record(alert)
+			deps.Emergencies.Track(pushoverIDs.Receipt(), alertToken(deps.Config, alert), alert)
+			deps.Canceller.Recovered(deps.background(), alert)
+			deps.Glances.Observe(alert)
+			auditAlert(deps, requestID, alert, Decision{Outcome: OutcomeSent}, nil, pushoverIDs.Last())
+		}
+		for _, result := range results {
+			if isRetriesExhausted(result.Err) {
+				retriesExhausted.Inc()
+			}
+		}
+
+		if !legacyResponse {
+			if err != nil {
+				logFailure(deps, "notification send", err, "Failed to send notification: %v", err)
+				status, _ := sendFailure(err)
+				if status == http.StatusServiceUnavailable {
+					setRetryAfter(w, deps.Config, err)
+				}
+				writeJSONResponse(w, status, aggregateResults(results, err))
+				return
+			}
+			deps.Logger.Printf("Successfully sent alert for %s/%s%s", alertKind(alert), alertName(alert), inCluster(deps.Config, alert))
+			writeJSONResponse(w, responses.OKStatus, aggregateResults(results, nil))
+			return
+		}
+
+		var exhausted *pushover.RetriesExhaustedError
+		if errors.As(err, &exhausted) {
+			logFailure(deps, "pushover send", err, "Failed to send to Pushover: %v", err)
+			setRetryAfter(w, deps.Config, err)
+			writeJSONResponse(w, http.StatusServiceUnavailable, retriesExhaustedResponse(exhausted))
+			return
+		}
+		if err != nil {
+			logFailure(deps, "pushover send", err, "Failed to send to Pushover: %v", err)
+			status, code := sendFailure(err)
+			if status == http.StatusServiceUnavailable {
+				setRetryAfter(w, deps.Config, err)
+			}
+			writeJSONResponse(w, status, sendErrorResponse(code, err))
+			return
+		}
+
+		// Log success
+		deps.Logger.Printf("Successfully sent alert to Pushover for %s/%s%s", alertKind(alert), alertName(alert), inCluster(deps.Config, alert))
+		writeResponse(w, responses.ContentType, responses.OKStatus, responses.OK)
+	}
+
+	// dispatch delivers an alert, replaying the stored response for a
+	// retried webhook instead of sending again
+	dispatch := func(w http.ResponseWriter, r *http.Request, alert *types.FluxAlert, raw []byte, key string) {
+		if deps.Idempotency != nil {
+			serveIdempotent(deps, w, r, key, alert, func(w http.ResponseWriter) {
+				deliver(w, r, alert, raw)
+			})
+			return
+		}
+		deliver(w, r, alert, raw)
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Handle OPTIONS requests for CORS
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
+		// Answer CORS preflights from ALLOWED_ORIGINS
+		if handleCORS(w, r, deps.Config.AllowedOrigins) {
 			return
 		}
 
+		// Answer within REQUEST_DEADLINE, before the server's WriteTimeout
+		// tears the connection down
+		if deps.Config.RequestDeadline > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), deps.Config.RequestDeadline)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+
 		// Only accept POST requests
 		if r.Method != http.MethodPost {
 			deps.Logger.Printf("Invalid method %s from %s", r.Method, r.RemoteAddr)
-			writeJSONResponse(w, http.StatusMethodNotAllowed, types.ResponseMethodNotAllowed)
+			writeResponse(w, responses.ContentType, http.StatusMethodNotAllowed, responses.MethodNotAllowed)
 			return
 		}
 
 		// Check authorization
-		if r.Header.Get("Authorization") != deps.Config.BearerToken {
+		credential, ok := auth.Authenticate(r)
+		if !ok {
 			deps.Logger.Printf("Unauthorized request from %s", r.RemoteAddr)
-			writeJSONResponse(w, http.StatusUnauthorized, types.ResponseUnauthorized)
+			writeResponse(w, responses.ContentType, http.StatusUnauthorized, responses.Unauthorized)
+			return
+		}
+		if auth.Rotating() {
+			deps.Logger.Printf("Request from %s authenticated with %s", r.RemoteAddr, credential)
+		}
+
+		// Alerts of several clusters are told apart by a trusted header or the token's label
+		cluster, err := auth.Cluster(r, credential)
+		if err != nil {
+			deps.Logger.Printf("Ignoring the cluster header of the request from %s: %v", r.RemoteAddr, err)
+		}
+
+		// Audited alerts and logged payloads are traced back to their webhook
+		if deps.Audit != nil || deps.Config.DebugLogInvalidPayloads {
+			ensureRequestID(r)
+		}
+
+		// Only the leader replica delivers notifications
+		if !elector.IsLeader() {
+			handleNonLeader(deps, elector.Status(), w, r)
 			return
 		}
 
@@ -77,56 +697,310 @@ func CreateWebhookHandler(deps *HandlerDependencies) http.HandlerFunc {
 		r.Body = http.MaxBytesReader(w, r.Body, types.MaxBodySize)
 		defer r.Body.Close()
 
-		// Parse JSON payload
-		var alert types.FluxAlert
-		decoder := json.NewDecoder(r.Body)
-		decoder.DisallowUnknownFields()
+		body := bodyBufferPool.Get().(*bytes.Buffer)
+		defer putBodyBuffer(body)
+		data, err := readBody(body, r.Body)
+		if err != nil {
+			deps.Logger.Printf("Rejected payload from %s: %v", r.RemoteAddr, err)
+			writePayloadError(w, responses, err)
+			return
+		}
+
+		// Reject pathological payloads before decoding them
+		if err := checkJSONShape(data, deps.Config.MaxJSONDepth, deps.Config.MaxJSONTokens); err != nil {
+			deps.Logger.Printf("Rejected payload: %v", err)
+			logInvalidPayload(deps, r, data, err)
+			writeResponse(w, responses.ContentType, http.StatusBadRequest, responses.InvalidJSON)
+			return
+		}
+
+		// Forwarders may wrap several alerts in a JSON array
+		if isJSONArray(data) {
+			alerts, raws, err := decodeBatch(data, decode, validate)
+			if err != nil {
+				deps.Logger.Printf("Invalid alert batch: %v", err)
+				logInvalidPayload(deps, r, data, err)
+				writePayloadError(w, responses, err)
+				return
+			}
+			for i := range alerts {
+				alerts[i].Cluster = cluster
+				if err := deps.Freshness.Check(r, &alerts[i]); err != nil {
+					deps.Logger.Printf("Rejected alert batch from %s: alert %d: %v", r.RemoteAddr, i, err)
+					writePayloadError(w, responses, err)
+					return
+				}
+			}
+			deliverBatch(deps, w, r, alerts, raws, dispatch)
+			return
+		}
+
+		// Parse JSON payload, every consumer of the alert is done with it
+		// once dispatch returns
+		alert := alertPool.Get().(*types.FluxAlert)
+		*alert = types.FluxAlert{}
+		defer alertPool.Put(alert)
 
-		if err := decoder.Decode(&alert); err != nil {
+		if err := decode(data, alert); err != nil {
 			deps.Logger.Printf("Failed to parse JSON: %v", err)
-			writeJSONResponse(w, http.StatusBadRequest, types.ResponseInvalidJSON)
+			logInvalidPayload(deps, r, data, err)
+			writePayloadError(w, responses, err)
 			return
 		}
 
 		// Validate alert
-		if err := ValidateAlert(&alert); err != nil {
+		if err := validate(alert); err != nil {
 			deps.Logger.Printf("Invalid alert: %v", err)
-			writeJSONResponse(w, http.StatusBadRequest, types.ResponseInvalidJSON)
+			writePayloadError(w, responses, err)
 			return
 		}
+		alert.Cluster = cluster
 
-		// Build message
-		message := deps.MessageBuilder(&alert)
-
-		// Special handling for test mode
-		if deps.Config.PushoverAPIToken == "test_api_token" {
-			deps.Logger.Println("Test mode: not sending to Pushover")
-			writeJSONResponse(w, http.StatusOK, types.ResponseOK)
+		// Refuse replays of captured requests
+		if err := deps.Freshness.Check(r, alert); err != nil {
+			deps.Logger.Printf("Rejected event from %s: %v", r.RemoteAddr, err)
+			writePayloadError(w, responses, err)
 			return
 		}
 
-		// Create and send Pushover message
-		pushoverMsg := CreatePushoverMessage(deps.Config, message)
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
+		// The mirrored payload outlives the pooled body buffer
+		var raw []byte
+		if deps.Forwarder != nil {
+			raw = bytes.Clone(data)
+		}
 
-		if err := deps.PushoverClient.SendMessage(ctx, pushoverMsg); err != nil {
-			deps.Logger.Printf("Failed to send to Pushover: %v", err)
-			errorResponse := fmt.Sprintf(`{"error": "Failed to send to Pushover", "details": "%s"}`, err.Error())
-			writeJSONResponse(w, http.StatusInternalServerError, []byte(errorResponse))
-			return
+		dispatch(w, r, alert, raw, IdempotencyKey(r, alert))
+	}
+}
+
+// deliverCoalesced sends the alerts held back for one object as a single
+// notification, led by the most severe one
+func deliverCoalesced(deps *HandlerDependencies, notifier *notify.Coordinator, alerts []*types.FluxAlert) {
+	lead := mostSevere(alerts)
+	var message string
+	var err error
+	if len(alerts) > 1 {
+		message = buildCoalescedMessage(alerts, MessageOptionsFromConfig(deps.Config))
+	} else if message, err = buildMessageSafely(deps, lead); err != nil {
+		auditCoalesced(deps, alerts, OutcomeFailed, err, "")
+		return
+	}
+
+	if deps.Config.PushoverAPIToken == "test_api_token" {
+		deps.Logger.Println("Test mode: not sending to Pushover")
+		auditCoalesced(deps, alerts, OutcomeTestMode, nil, "")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(deps.background(), deps.Config.SendTimeout())
+	defer cancel()
+	ctx, pushoverIDs := pushover.WithRequestIDs(ctx)
+
+	// Quiet hours and maintenance are checked again, a window may have started meanwhile
+	notification := CreateNotification(lead, message)
+	silence(notification, deps.QuietHours.Quiets(lead) && !deps.QuietHours.Suppresses(), deps.maintenanceDowngrades(lead))
+
+	if _, err := notifier.Send(ctx, notification); err != nil {
+		logFailure(deps, "coalesced send", err, "Failed to send %d coalesced alerts for %s/%s%s: %v", len(alerts), alertKind(lead), alertName(lead), inCluster(deps.Config, lead), err)
+		recordDeliveryFailure(deps, lead, err)
+		auditCoalesced(deps, alerts, OutcomeFailed, err, pushoverIDs.Last())
+		return
+	}
+	deps.Emergencies.Track(pushoverIDs.Receipt(), alertToken(deps.Config, lead), lead)
+	auditCoalesced(deps, alerts, OutcomeSent, nil, pushoverIDs.Last())
+
+	// The last event is the object's current state
+	last := alerts[len(alerts)-1]
+	deps.Canceller.Recovered(deps.background(), last)
+	deps.Glances.Observe(last)
+	deps.Transitions.Record(last)
+	if deps.State != nil && deps.Config.NotifyOnChangeOnly {
+		recordState(deps, last, AlertState(last, deps.Config.ChangeDetection))
+	}
+	deps.Logger.Printf("Successfully sent %d coalesced alerts for %s/%s%s", len(alerts), alertKind(lead), alertName(lead), inCluster(deps.Config, lead))
+}
+
+// logFailure logs a failed delivery to endpoint, sampled by LOG_SAMPLE_WINDOW
+func logFailure(deps *HandlerDependencies, endpoint string, err error, format string, v ...interface{}) {
+	if deps.FailureLog == nil {
+		deps.Logger.Printf(format, v...)
+		return
+	}
+	deps.FailureLog.Failuref(endpoint, err, format, v...)
+}
+
+// recordDeliveryFailure emits a Kubernetes Event for a failed delivery
+func recordDeliveryFailure(deps *HandlerDependencies, alert *types.FluxAlert, sendErr error) {
+	if deps.Events == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(deps.background(), 5*time.Second)
+	defer cancel()
+
+	if err := deps.Events.Emit(ctx, kube.DeliveryFailureEvent(alert, sendErr)); err != nil {
+		deps.Logger.Printf("Failed to emit Kubernetes event: %v", err)
+	}
+}
+
+// isPushoverOnly reports whether Pushover is the sole provider, which keeps the original responses
+func isPushoverOnly(notifier *notify.Coordinator) bool {
+	providers := notifier.Providers()
+	return len(providers) == 1 && providers[0] == config.ProviderPushover
+}
+
+// providerResult is the per-provider entry of an aggregated response
+type providerResult struct {
+	Provider string `json:"provider"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// aggregatedResponse is returned when more than one provider is configured
+type aggregatedResponse struct {
+	Status  string           `json:"status,omitempty"`
+	Error   string           `json:"error,omitempty"`
+	Code    string           `json:"code,omitempty"`
+	Results []providerResult `json:"results"`
+}
+
+// aggregateResults renders per-provider results as a JSON response body
+func aggregateResults(results []notify.Result, err error) []byte {
+	response := aggregatedResponse{
+		Status:  "ok",
+		Results: make([]providerResult, 0, len(results)),
+	}
+
+	for _, result := range results {
+		entry := providerResult{Provider: result.Provider, Status: "ok"}
+		if result.Err != nil {
+			entry.Status = "error"
+			entry.Error = result.Err.Error()
+			response.Status = "partial"
+		}
+		response.Results = append(response.Results, entry)
+	}
+
+	if err != nil {
+		response.Status = ""
+		response.Error = "Failed to deliver notification"
+		_, response.Code = sendFailure(err)
+		if isRetriesExhausted(err) {
+			response.Error = "Retries exhausted"
 		}
+	}
 
-		// Log success
-		info := ExtractAlertInfo(&alert)
-		deps.Logger.Printf("Successfully sent alert to Pushover for %s/%s", info["kind"], info["name"])
-		writeJSONResponse(w, http.StatusOK, types.ResponseOK)
+	body, marshalErr := json.Marshal(response)
+	if marshalErr != nil {
+		return []byte(`{"error": "Failed to encode response"}`)
+	}
+	return body
+}
+
+// isRetriesExhausted reports whether a send failed only after every retry (pure function)
+func isRetriesExhausted(err error) bool {
+	var exhausted *pushover.RetriesExhaustedError
+	return errors.As(err, &exhausted)
+}
+
+// Machine-readable codes of a failed send, returned as "code" in the response body
+const (
+	codeRetriesExhausted = "retries_exhausted"
+	codeRateLimited      = "rate_limited"
+	codeTransportError   = "transport_error"
+	codeDeadline         = "deadline_exceeded"
+	codeAPIRejected      = "api_rejected"
+	codeCredentials      = "credentials_invalid"
+	codeSendFailed       = "send_failed"
+)
+
+// sendFailure maps a failed send to a status and code. Pushover staying
+// unavailable, rate limiting or unreachable is 503 so the sender retries
+// later, Pushover rejecting the message is 502 and anything else 500 (pure function).
+func sendFailure(err error) (int, string) {
+	var rateLimitErr *pushover.RateLimitError
+	var transportErr *pushover.TransportError
+	var apiErr *pushover.APIError
+	switch {
+	case isRetriesExhausted(err):
+		return http.StatusServiceUnavailable, codeRetriesExhausted
+	case errors.As(err, &rateLimitErr):
+		return http.StatusServiceUnavailable, codeRateLimited
+	case errors.As(err, &transportErr):
+		return http.StatusServiceUnavailable, codeTransportError
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable, codeDeadline
+	case errors.Is(err, pushover.ErrCredentialsInvalid):
+		return http.StatusBadGateway, codeCredentials
+	case errors.As(err, &apiErr):
+		return http.StatusBadGateway, codeAPIRejected
+	default:
+		return http.StatusInternalServerError, codeSendFailed
+	}
+}
+
+// setRetryAfter hints on a 503 how long the sender should back off before
+// resending: until Pushover's rate limit resets when err carries one, else
+// RETRY_AFTER_SECONDS, of which zero leaves it to the sender
+func setRetryAfter(w http.ResponseWriter, cfg *config.Config, err error) {
+	var rateLimitErr *pushover.RateLimitError
+	if errors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter > 0 {
+		seconds := int((rateLimitErr.RetryAfter + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		return
 	}
+	if cfg.RetryAfterSeconds > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(cfg.RetryAfterSeconds))
+	}
+}
+
+// sendErrorResponse renders the body of a failed Pushover send (pure function)
+func sendErrorResponse(code string, err error) []byte {
+	response := types.SendErrorResponse{
+		Error:   "Failed to send to Pushover",
+		Code:    code,
+		Details: err.Error(),
+	}
+	var apiErr *pushover.APIError
+	if errors.As(err, &apiErr) {
+		response.Errors = apiErr.Errors
+		response.Request = apiErr.Request
+	}
+	body, marshalErr := json.Marshal(response)
+	if marshalErr != nil {
+		return types.ResponseInternalError
+	}
+	return body
+}
+
+// retriesExhaustedResponse renders the 503 body of a send that failed after
+// every retry (pure function)
+func retriesExhaustedResponse(err *pushover.RetriesExhaustedError) []byte {
+	body, marshalErr := json.Marshal(struct {
+		Error    string `json:"error"`
+		Code     string `json:"code"`
+		Attempts int    `json:"attempts"`
+		Details  string `json:"details"`
+	}{
+		Error:    "Pushover retries exhausted",
+		Code:     codeRetriesExhausted,
+		Attempts: err.Attempts,
+		Details:  err.Err.Error(),
+	})
+	if marshalErr != nil {
+		return types.ResponseInternalError
+	}
+	return body
 }
 
 // writeJSONResponse writes a JSON response with proper headers
 func writeJSONResponse(w http.ResponseWriter, statusCode int, body []byte) {
-	w.Header().Set("Content-Type", types.ContentTypeJSON)
+	writeResponse(w, types.ContentTypeJSON, statusCode, body)
+}
+
+// writeResponse writes a response with the given content type
+func writeResponse(w http.ResponseWriter, contentType string, statusCode int, body []byte) {
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(statusCode)
 	if _, err := w.Write(body); err != nil {
 		// Response header already written, can't do much more
@@ -135,30 +1009,349 @@ func writeJSONResponse(w http.ResponseWriter, statusCode int, body []byte) {
 	}
 }
 
-// CreateRouter creates the HTTP router with all endpoints
+// CreateRouter creates the HTTP router with all endpoints, mounted under
+// BASE_PATH. The unprefixed routes answer 404, or redirect with 308 when
+// REDIRECT_LEGACY_PATHS is set.
 func CreateRouter(deps *HandlerDependencies) http.Handler {
+	routes := NewRoutes(deps.Config)
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", CreateRootHandler())
-	mux.HandleFunc("/health", CreateHealthHandler())
-	mux.HandleFunc("/webhook", CreateWebhookHandler(deps))
-	return mux
+	mux.HandleFunc(routes.Base+"/", CreateRootHandler(deps.Config.RootOK))
+	mux.HandleFunc(routes.Health, CreateHealthHandler(deps.Health))
+	mux.HandleFunc(routes.Ready, CreateReadyHandler(deps))
+	mux.HandleFunc(routes.Status, CreateStatusHandler(deps))
+	mux.HandleFunc(routes.Webhook, CreateWebhookHandler(deps))
+	mux.HandleFunc(routes.Test, CreateTestHandler(deps))
+	doc := NewAPIDocument(deps.Config, deps.Metrics != nil)
+	mux.HandleFunc(routes.OpenAPI, CreateOpenAPIHandler(doc))
+	mux.HandleFunc(routes.Docs, CreateDocsHandler(doc, routes.OpenAPI))
+	if deps.Config.PublicURL != "" {
+		mux.HandleFunc(routes.Callback, CreateCallbackHandler(deps))
+	}
+	// Profiling is only served by the DEBUG_ADDR listener
+	mux.Handle(routes.Base+"/debug/", http.NotFoundHandler())
+	if deps.Metrics != nil {
+		mux.Handle(routes.Metrics, WithGzip(deps.Metrics.Handler()))
+	}
+	if deps.Config.RedirectLegacyPaths {
+		for legacy, route := range routes.Legacy(deps.Config.PublicURL != "", deps.Metrics != nil) {
+			mux.Handle(legacy, redirectTo(route))
+		}
+	}
+
+	var handler http.Handler = mux
+	if deps.Config.RequireForwardedHTTPS {
+		handler = WithForwardedHTTPS(handler, deps.Config.TrustedProxies, deps.Logger, routes.Health, routes.Ready)
+	}
+	handler = WithRecovery(handler, deps.Logger, deps.Panics)
+	if deps.Config.AccessLog {
+		handler = WithAccessLog(handler, deps.Logger)
+	}
+	return telemetry.Middleware(handler, deps.Tracer, func(r *http.Request) string {
+		_, pattern := mux.Handler(r)
+		return pattern
+	})
+}
+
+// CreateServerDependencies creates all server dependencies. Cancelling ctx
+// ends the background work still running once they were drained.
+func CreateServerDependencies(ctx context.Context, cfg *config.Config, logger server.Logger) (*HandlerDependencies, error) {
+	return CreateServerDependenciesWithClock(ctx, cfg, logger, clock.Real{})
 }
 
-// CreateServerDependencies creates all server dependencies
-func CreateServerDependencies(cfg *config.Config, logger server.Logger) (*HandlerDependencies, error) {
+// CreateServerDependenciesWithClock creates all server dependencies, with the
+// quiet hours, maintenance windows, limits and expiring state telling the
+// time by clk
+func CreateServerDependenciesWithClock(ctx context.Context, cfg *config.Config, logger server.Logger, clk clock.Clock) (*HandlerDependencies, error) {
 	// Create HTTP client
-	httpClient := pushover.CreateOptimizedHTTPClient(10 * time.Second)
+	httpClient := pushover.NewHTTPClient(cfg.SendTimeout(), pushover.TransportOptions{
+		MaxIdleConns:          cfg.HTTPMaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.HTTPMaxIdleConnsPerHost,
+		IdleConnTimeout:       cfg.HTTPIdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.HTTPTLSHandshakeTimeout,
+		ExpectContinueTimeout: cfg.HTTPExpectContinueTimeout,
+		HTTP2:                 cfg.HTTP2,
+		Compression:           cfg.HTTPCompression,
+		Resolve: pushover.ResolveOptions{
+			Host:          pushoverHost(cfg),
+			Override:      cfg.PushoverResolveOverride,
+			CacheTTL:      cfg.PushoverDNSCacheTTL,
+			LookupTimeout: cfg.PushoverDNSTimeout,
+		},
+	})
+
+	registry := metrics.NewRegistry()
+
+	// Create Pushover client, optionally retrying within a shared budget
+	apiClient := newPushoverClient(httpClient, cfg).WithMetrics(registry)
+	var pushoverClient PushoverSender = apiClient
+	if cfg.RetryMaxAttempts > 1 {
+		budget := pushover.NewRetryBudget(pushover.DefaultRetryBudgetTokens, cfg.RetryBudgetRatio)
+		pushoverClient = pushover.NewRetryingSender(pushoverClient, cfg.RetryMaxAttempts, pushover.DefaultRetryBackoff, budget).WithRetryOnTimeout(cfg.RetryOnTimeout)
+	}
+
+	// Conditions of the components, reported on /status
+	conds := conditions.NewSetWithClock(clk, conditions.Ready, conditions.PushoverReachable, conditions.CredentialsValid, conditions.QueueHealthy)
+
+	// Stop sending with credentials Pushover rejected, e.g. of a deleted app
+	credentials := pushover.NewCredentialGuard(pushoverClient).WithConditions(conds).WithRecheck(apiClient, cfg.CredentialsRecheckInterval)
+	credentials.OnInvalid(credentialsInvalidNotice(cfg, credentials, logger))
+	pushoverClient = credentials
+
+	// Track the final outcome of each send for the readiness check
+	sendStatus := pushover.NewStatusTracker(pushoverClient).WithClock(clk).WithConditions(conds)
+	pushoverClient = sendStatus
+
+	var shedder *LoadShedder
+	if cfg.ShedMaxConcurrent > 0 {
+		shedder = NewLoadShedder(cfg.ShedMaxConcurrent, cfg.ShedHighWater, registry)
+	}
+
+	// Watch the outcomes for the provider degrading, notifying through the
+	// bare client that no retry budget or credential guard holds back
+	var watchdog *Watchdog
+	if cfg.WatchdogFailureStreak > 0 || cfg.WatchdogSaturation > 0 || cfg.WatchdogFlaps > 0 {
+		watchdog = NewWatchdog(ctx, cfg, pushoverClient, apiClient, shedder, logger, registry)
+		pushoverClient = watchdog
+	}
+
+	// Create notification coordinator
+	notifier, err := CreateNotifier(cfg, httpClient, pushoverClient, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	var forwarder *forward.Forwarder
+	if cfg.ForwardURL != "" {
+		forwarder = forward.NewForwarder(httpClient, cfg.ForwardURL, cfg.ForwardToken, logger, registry).WithContext(ctx)
+		if cfg.ForwardSign {
+			forwarder.WithSigning(cfg.ForwardHMACSecret)
+		}
+	}
+
+	// Create the store of deduplication claims and object states, shared
+	// between replicas when Redis is configured
+	var shared store.Store
+	if cfg.DedupWindow > 0 || cfg.NotifyOnChangeOnly {
+		shared = store.NewMemoryStore().WithClock(clk)
+		if cfg.RedisAddr != "" {
+			shared = store.NewFallbackStore(store.NewRedisStore(cfg.RedisAddr, cfg.RedisPassword), shared, logger)
+		}
+	}
+
+	var idempotencyCache *idempotency.Cache
+	if cfg.IdempotencyTTL > 0 {
+		idempotencyCache = idempotency.NewCache(cfg.IdempotencyTTL, cfg.IdempotencyMaxKeys, registry).WithClock(clk)
+	}
+
+	var transitions *TransitionTracker
+	if cfg.StatefulNotify {
+		transitions = NewTransitionTracker(cfg.StateTTL, DefaultMaxTransitionObjects, registry).WithClock(clk)
+	}
+
+	var rateLimiter *ratelimit.KeyedLimiter
+	if cfg.PerNamespaceRate > 0 {
+		rateLimiter = ratelimit.NewKeyedLimiter(cfg.PerNamespaceRate, ratelimit.DefaultMaxKeys, registry).WithClock(clk)
+	}
+
+	var coalescer *Coalescer
+	var dispatcher *Dispatcher
+	if cfg.CoalesceWindow > 0 {
+		coalescer = NewCoalescer(cfg.CoalesceWindow, cfg.CoalesceBypassErrors, registry)
+		dispatcher = NewDispatcher(cfg.DispatchWorkers, registry)
+	}
+
+	var emergencies *EmergencyTracker
+	var receipts *ReceiptPoller
+	var canceller *EmergencyCanceller
+	if cfg.PublicURL != "" || cfg.ReceiptPollInterval > 0 || cfg.AutoCancelEmergency {
+		emergencies = NewEmergencyTracker(registry)
+	}
+	if cfg.ReceiptPollInterval > 0 {
+		receipts = NewReceiptPoller(emergencies, apiClient, cfg.PushoverAPIToken, cfg.ReceiptPollInterval, logger, registry)
+	}
+	if cfg.AutoCancelEmergency {
+		canceller = NewEmergencyCanceller(emergencies, apiClient, cfg.PushoverAPIToken, logger, registry)
+	}
 
-	// Create Pushover client
-	pushoverClient := pushover.NewPushoverClient(httpClient, cfg.PushoverURL)
+	var glances *GlanceUpdater
+	if cfg.EnableGlances {
+		glances = NewGlanceUpdater(cfg, apiClient, logger, registry)
+	}
+
+	var freshness *FreshnessChecker
+	if cfg.MaxEventAge > 0 || cfg.RequireEventTimestamp {
+		freshness = NewFreshnessChecker(cfg.MaxEventAge, cfg.EventClockSkew, cfg.RequireEventTimestamp, registry).WithClock(clk)
+	}
+
+	// Message templates are validated here so that mistakes fail startup
+	messageBuilder := NewMessageBuilder(MessageOptionsFromConfig(cfg))
+	if cfg.TemplatesFile != "" {
+		templates, err := LoadTemplateSet(cfg.TemplatesFile, MessageOptionsFromConfig(cfg), logger, registry)
+		if err != nil {
+			return nil, err
+		}
+		messageBuilder = templates.Build
+	}
+
+	// Panics are reported through the Pushover client, sparing the other providers
+	var panicSender PushoverSender
+	if cfg.PanicNotify {
+		panicSender = pushoverClient
+	}
+	panics := NewPanicReporter(ctx, cfg, panicSender, cfg.PanicNotifyCooldown, logger, registry)
+
+	quietHours, err := NewQuietHours(cfg)
+	if err != nil {
+		return nil, err
+	}
+	quietHours.WithClock(clk)
+	maintenanceWindows, err := maintenance.NewWindows(cfg.MaintenanceWindows, cfg.MaintenanceTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAINTENANCE_WINDOWS: %w", err)
+	}
+	maintenanceWindows.WithClock(clk)
+
+	// Opened last, nothing can fail and leave the file open
+	var auditLogger *audit.Logger
+	if cfg.AuditLogPath != "" {
+		file, err := audit.OpenRotatingFile(cfg.AuditLogPath, int64(cfg.AuditLogMaxSizeMB)<<20, cfg.AuditLogMaxFiles)
+		if err != nil {
+			return nil, err
+		}
+		auditLogger = audit.NewLogger(file, audit.DefaultFlushInterval, logger, registry)
+	}
+
+	var failureLog *logsample.Sampler
+	if cfg.LogSampleWindow > 0 {
+		failureLog = logsample.NewSampler(logger, cfg.LogSampleWindow, cfg.LogSampleBurst, registry)
+	}
 
 	// Create dependencies
 	deps := &HandlerDependencies{
 		Config:         cfg,
 		PushoverClient: pushoverClient,
 		Logger:         logger,
-		MessageBuilder: BuildPushoverMessage,
+		MessageBuilder: messageBuilder,
+		Responses:      NewResponses(cfg),
+		Notifier:       notifier,
+		Forwarder:      forwarder,
+		Metrics:        registry,
+		SendStatus:     sendStatus,
+		Credentials:    credentials,
+		Dedup:          shared,
+		State:          shared,
+		Transitions:    transitions,
+		Idempotency:    idempotencyCache,
+		Freshness:      freshness,
+		RateLimiter:    rateLimiter,
+		Shedder:        shedder,
+		Coalescer:      coalescer,
+		Dispatcher:     dispatcher,
+		Health:         &server.HealthState{},
+		Audit:          auditLogger,
+		FailureLog:     failureLog,
+		QuietHours:     quietHours,
+		Maintenance:    maintenanceWindows,
+		Panics:         panics,
+		Watchdog:       watchdog,
+		Conditions:     conds,
+		Background:     ctx,
+		Clock:          clk,
+		Emergencies:    emergencies,
+		Receipts:       receipts,
+		Canceller:      canceller,
+		Glances:        glances,
+		Tracer:         telemetry.NewTracerFromEnv(os.Getenv, httpClient, logger),
+		Events:         kube.NewEmitter(cfg.EmitK8sEvents, os.Getenv, kube.ServiceAccountDir),
+		Elector: kube.NewElector(cfg.EnableLeaderElection, os.Getenv, kube.ServiceAccountDir, kube.ElectorOptions{
+			LeaseName: cfg.LeaderElectionLease,
+			Address:   podAddress(os.Getenv("POD_IP"), cfg.Port),
+		}, logger),
 	}
+	deps.watchConditions()
 
 	return deps, nil
 }
+
+// newPushoverClient creates a client for the configured Pushover endpoints
+func newPushoverClient(httpClient pushover.HTTPClient, cfg *config.Config) *pushover.PushoverClient {
+	return pushover.NewPushoverClientWithEndpoints(httpClient, pushover.ResolveEndpoints(cfg.PushoverBaseURL, cfg.PushoverURL)).
+		WithMaxInFlight(cfg.PushoverMaxInFlight)
+}
+
+// pushoverHost returns the hostname of the messages endpoint, empty when it
+// does not parse (pure function)
+func pushoverHost(cfg *config.Config) string {
+	u, err := url.Parse(pushover.ResolveEndpoints(cfg.PushoverBaseURL, cfg.PushoverURL).Messages)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// CreateNotifier creates the notification coordinator for the configured providers
+func CreateNotifier(cfg *config.Config, httpClient notify.HTTPClient, pushoverClient PushoverSender, logger server.Logger) (*notify.Coordinator, error) {
+	providers := cfg.Providers
+	if len(providers) == 0 {
+		providers = []string{config.ProviderPushover}
+	}
+
+	images, err := NewImageFetcher(cfg, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	senders := make([]notify.NotificationSender, 0, len(providers))
+	for _, provider := range providers {
+		switch provider {
+		case config.ProviderPushover:
+			var sender notify.NotificationSender = newPushoverSender(cfg, pushoverClient, images, logger)
+			switch cfg.Glances {
+			case config.GlancesAlongside:
+				sender = newGlanceSender(cfg, sender, newPushoverClient(httpClient, cfg), logger)
+			case config.GlancesInstead:
+				sender = newGlanceSender(cfg, nil, newPushoverClient(httpClient, cfg), logger)
+			}
+			senders = append(senders, sender)
+		case config.ProviderNtfy:
+			senders = append(senders, notify.NewNtfySender(httpClient, cfg.NtfyURL, cfg.NtfyTopic, cfg.NtfyToken))
+		case config.ProviderWebhook:
+			senders = append(senders, notify.NewWebhookSender(httpClient, cfg.OutgoingWebhookURL, cfg.OutgoingWebhookToken))
+		case config.ProviderSlack:
+			senders = append(senders, notify.NewSlackSender(httpClient, cfg.SlackWebhookURL))
+		default:
+			return nil, fmt.Errorf("unknown provider %q", provider)
+		}
+	}
+
+	mode := notify.ModeFanOut
+	if cfg.ProvidersMode == config.ProvidersModeFailover {
+		mode = notify.ModeFailover
+	}
+
+	return notify.NewCoordinator(mode, senders...), nil
+}
+
+// NewResponses builds the response set from config, keeping defaults for unset values
+func NewResponses(cfg *config.Config) *types.Responses {
+	responses := types.DefaultResponses()
+	if cfg.ResponseContentType != "" {
+		responses.ContentType = cfg.ResponseContentType
+	}
+	if cfg.SuccessStatus != 0 {
+		responses.OKStatus = cfg.SuccessStatus
+	}
+	if cfg.ResponseOKBody != "" {
+		responses.OK = []byte(cfg.ResponseOKBody)
+	}
+	if cfg.ResponseUnauthorizedBody != "" {
+		responses.Unauthorized = []byte(cfg.ResponseUnauthorizedBody)
+	}
+	if cfg.ResponseInvalidJSONBody != "" {
+		responses.InvalidJSON = []byte(cfg.ResponseInvalidJSONBody)
+	}
+	if cfg.ResponseMethodNotAllowedBody != "" {
+		responses.MethodNotAllowed = []byte(cfg.ResponseMethodNotAllowedBody)
+	}
+	return responses
+}
@@ -4,28 +4,49 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/conditions"
 	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/forward"
+	"github.com/zhorvath83/flux-provider-pushover/internal/kube"
+	"github.com/zhorvath83/flux-provider-pushover/internal/logsample"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/notify"
+	"github.com/zhorvath83/flux-provider-pushover/internal/pushover"
+	"github.com/zhorvath83/flux-provider-pushover/internal/ratelimit"
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
 	"github.com/zhorvath83/flux-provider-pushover/internal/types"
 )
 
-// MockLogger for testing
+// MockLogger for testing (thread-safe)
 type MockLogger struct {
+	mu       sync.Mutex
 	messages []string
 }
 
 func (m *MockLogger) Printf(format string, v ...interface{}) {
 	// Store formatted messages for verification in tests
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.messages = append(m.messages, format)
 }
 
 func (m *MockLogger) Println(v ...interface{}) {
 	// Store messages for verification in tests
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.messages = append(m.messages, "println")
 }
 
@@ -46,37 +67,100 @@ func (m *MockPushoverClient) SendMessage(ctx context.Context, msg *types.Pushove
 	return nil
 }
 
+// ValidatorFunc validates credentials with a function
+type ValidatorFunc func(ctx context.Context, token, user string) error
+
+func (f ValidatorFunc) ValidateUser(ctx context.Context, token, user string) error {
+	return f(ctx, token, user)
+}
+
 func TestCreateRootHandler(t *testing.T) {
-	handler := CreateRootHandler()
+	tests := []struct {
+		name           string
+		rootOK         bool
+		expectedStatus int
+		expectedBody   []byte
+	}{
+		{"default", false, http.StatusBadRequest, types.ResponseRootError},
+		{"root ok", true, http.StatusOK, types.ResponseRootInfo},
+	}
 
-	req, _ := http.NewRequest("GET", "/", nil)
-	rr := httptest.NewRecorder()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CreateRootHandler(tt.rootOK)
 
-	handler.ServeHTTP(rr, req)
+			req, _ := http.NewRequest("GET", "/", nil)
+			rr := httptest.NewRecorder()
 
-	if rr.Code != http.StatusBadRequest {
-		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
-	}
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
 
-	if !bytes.Equal(rr.Body.Bytes(), types.ResponseRootError) {
-		t.Errorf("Expected body %s, got %s", types.ResponseRootError, rr.Body.String())
+			if !bytes.Equal(rr.Body.Bytes(), tt.expectedBody) {
+				t.Errorf("Expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
 	}
 }
 
 func TestCreateHealthHandler(t *testing.T) {
-	handler := CreateHealthHandler()
+	tests := []struct {
+		method         string
+		expectedStatus int
+		expectedBody   []byte
+	}{
+		{"GET", http.StatusOK, types.ResponseHealthy},
+		{"HEAD", http.StatusOK, nil},
+		{"POST", http.StatusMethodNotAllowed, types.ResponseMethodNotAllowed},
+	}
 
-	req, _ := http.NewRequest("GET", "/health", nil)
-	rr := httptest.NewRecorder()
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			router := CreateRouter(&HandlerDependencies{
+				Config:         &config.Config{},
+				PushoverClient: &MockPushoverClient{},
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+			})
 
-	handler.ServeHTTP(rr, req)
+			req, _ := http.NewRequest(tt.method, "/health", nil)
+			rr := httptest.NewRecorder()
 
-	if rr.Code != http.StatusOK {
-		t.Errorf("Expected status %d, got %d", http.StatusOK, rr.Code)
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+
+			if !bytes.Equal(rr.Body.Bytes(), tt.expectedBody) {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, rr.Body.String())
+			}
+
+			if tt.expectedStatus == http.StatusMethodNotAllowed && rr.Header().Get("Allow") != "GET, HEAD" {
+				t.Errorf("Expected Allow header, got %q", rr.Header().Get("Allow"))
+			}
+		})
 	}
+}
+
+func TestCreateHealthHandler_Draining(t *testing.T) {
+	health := &server.HealthState{}
+	handler := CreateHealthHandler(health)
+
+	health.StartDraining()
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		req := httptest.NewRequest(method, "/health", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
 
-	if !bytes.Equal(rr.Body.Bytes(), types.ResponseHealthy) {
-		t.Errorf("Expected body %s, got %s", types.ResponseHealthy, rr.Body.String())
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected 503 while draining, got %d", method, rr.Code)
+		}
+		if method == http.MethodGet && rr.Body.String() != string(types.ResponseDraining) {
+			t.Errorf("Expected draining body, got %q", rr.Body.String())
+		}
 	}
 }
 
@@ -221,109 +305,1466 @@ func TestCreateWebhookHandler_LargePayload(t *testing.T) {
 	rr := httptest.NewRecorder()
 	handler.ServeHTTP(rr, req)
 
-	if rr.Code != http.StatusBadRequest {
-		t.Errorf("Expected status %d for large payload, got %d", http.StatusBadRequest, rr.Code)
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d for large payload, got %d", http.StatusRequestEntityTooLarge, rr.Code)
+	}
+	if rr.Body.String() != string(types.ResponsePayloadTooLarge) {
+		t.Errorf("Expected body %s, got %s", types.ResponsePayloadTooLarge, rr.Body.String())
 	}
 }
 
-func TestWriteJSONResponse(t *testing.T) {
+func TestCreateWebhookHandler_CustomResponses(t *testing.T) {
+	cfg := &config.Config{
+		PushoverAPIToken:        "test_token",
+		PushoverUserKey:         "test_user",
+		BearerToken:             "Bearer test_token",
+		ResponseContentType:     "text/plain",
+		ResponseOKBody:          "accepted",
+		ResponseInvalidJSONBody: "bad payload",
+	}
+
+	deps := &HandlerDependencies{
+		Config:         cfg,
+		PushoverClient: &MockPushoverClient{},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		Responses:      NewResponses(cfg),
+	}
+
+	handler := CreateWebhookHandler(deps)
+
 	tests := []struct {
-		statusCode int
-		body       []byte
+		name           string
+		authHeader     string
+		body           string
+		expectedStatus int
+		expectedBody   []byte
 	}{
-		{http.StatusOK, []byte(`{"status":"ok"}`)},
-		{http.StatusBadRequest, []byte(`{"error":"bad request"}`)},
-		{http.StatusInternalServerError, []byte(`{"error":"internal error"}`)},
+		{"overridden OK", "Bearer test_token", `{"message":"hi"}`, http.StatusOK, []byte("accepted")},
+		{"overridden invalid JSON", "Bearer test_token", "not json", http.StatusBadRequest, []byte("bad payload")},
+		{"default unauthorized", "Bearer wrong", "{}", http.StatusUnauthorized, types.ResponseUnauthorized},
 	}
 
 	for _, tt := range tests {
-		rr := httptest.NewRecorder()
-		writeJSONResponse(rr, tt.statusCode, tt.body)
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/webhook", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", tt.authHeader)
 
-		if rr.Code != tt.statusCode {
-			t.Errorf("Expected status %d, got %d", tt.statusCode, rr.Code)
-		}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
 
-		if contentType := rr.Header().Get("Content-Type"); contentType != types.ContentTypeJSON {
-			t.Errorf("Expected Content-Type %s, got %s", types.ContentTypeJSON, contentType)
-		}
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
 
-		if !bytes.Equal(rr.Body.Bytes(), tt.body) {
-			t.Errorf("Expected body %s, got %s", tt.body, rr.Body.String())
-		}
+			if !bytes.Equal(rr.Body.Bytes(), tt.expectedBody) {
+				t.Errorf("Expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+
+			if contentType := rr.Header().Get("Content-Type"); contentType != "text/plain" {
+				t.Errorf("Expected Content-Type text/plain, got %s", contentType)
+			}
+		})
 	}
 }
 
-func TestCreateRouter(t *testing.T) {
-	cfg := &config.Config{
-		PushoverAPIToken: "test_token",
-		PushoverUserKey:  "test_user",
-		BearerToken:      "Bearer test_token",
+func TestNewResponses_Defaults(t *testing.T) {
+	responses := NewResponses(&config.Config{})
+
+	if responses.ContentType != types.ContentTypeJSON {
+		t.Errorf("Expected Content-Type %s, got %s", types.ContentTypeJSON, responses.ContentType)
 	}
 
-	deps := &HandlerDependencies{
-		Config:         cfg,
-		PushoverClient: &MockPushoverClient{},
-		Logger:         &MockLogger{},
-		MessageBuilder: BuildPushoverMessage,
+	if !bytes.Equal(responses.OK, types.ResponseOK) {
+		t.Errorf("Expected OK body %s, got %s", types.ResponseOK, responses.OK)
 	}
 
-	router := CreateRouter(deps)
+	if !bytes.Equal(responses.Unauthorized, types.ResponseUnauthorized) {
+		t.Errorf("Expected Unauthorized body %s, got %s", types.ResponseUnauthorized, responses.Unauthorized)
+	}
 
-	// Test each route
+	if !bytes.Equal(responses.InvalidJSON, types.ResponseInvalidJSON) {
+		t.Errorf("Expected InvalidJSON body %s, got %s", types.ResponseInvalidJSON, responses.InvalidJSON)
+	}
+
+	if !bytes.Equal(responses.MethodNotAllowed, types.ResponseMethodNotAllowed) {
+		t.Errorf("Expected MethodNotAllowed body %s, got %s", types.ResponseMethodNotAllowed, responses.MethodNotAllowed)
+	}
+
+	if responses.OKStatus != http.StatusOK {
+		t.Errorf("Expected OK status %d, got %d", http.StatusOK, responses.OKStatus)
+	}
+}
+
+func TestCreateWebhookHandler_SuccessStatus(t *testing.T) {
 	tests := []struct {
-		path           string
-		method         string
+		name           string
+		successStatus  int
+		body           string
 		expectedStatus int
 	}{
-		{"/", "GET", http.StatusBadRequest},
-		{"/health", "GET", http.StatusOK},
-		{"/webhook", "POST", http.StatusUnauthorized}, // No auth header
+		{"default", 0, `{"message":"hi"}`, http.StatusOK},
+		{"ok", http.StatusOK, `{"message":"hi"}`, http.StatusOK},
+		{"accepted", http.StatusAccepted, `{"message":"hi"}`, http.StatusAccepted},
+		{"accepted batch", http.StatusAccepted, `[{"message":"a"},{"message":"b"}]`, http.StatusAccepted},
+		{"errors unchanged", http.StatusAccepted, "not json", http.StatusBadRequest},
 	}
 
 	for _, tt := range tests {
-		req, _ := http.NewRequest(tt.method, tt.path, nil)
-		rr := httptest.NewRecorder()
-		router.ServeHTTP(rr, req)
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				PushoverAPIToken: "test_token",
+				BearerToken:      "Bearer test_token",
+				SuccessStatus:    tt.successStatus,
+			}
+			deps := &HandlerDependencies{
+				Config:         cfg,
+				PushoverClient: &MockPushoverClient{},
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+				Responses:      NewResponses(cfg),
+			}
 
-		if rr.Code != tt.expectedStatus {
-			t.Errorf("Path %s: expected status %d, got %d",
-				tt.path, tt.expectedStatus, rr.Code)
-		}
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer test_token")
+			rr := httptest.NewRecorder()
+			CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
 	}
 }
 
-// Benchmark tests
-func BenchmarkCreateWebhookHandler(b *testing.B) {
-	cfg := &config.Config{
-		PushoverAPIToken: "test_api_token",
-		PushoverUserKey:  "test_user",
-		BearerToken:      "Bearer test_api_token",
+// MockNotificationSender for testing
+type MockNotificationSender struct {
+	name string
+	err  error
+}
+
+func (m *MockNotificationSender) Name() string {
+	return m.name
+}
+
+func (m *MockNotificationSender) Send(ctx context.Context, n *notify.Notification) error {
+	return m.err
+}
+
+func TestCreateWebhookHandler_MultipleProviders(t *testing.T) {
+	tests := []struct {
+		name           string
+		pushoverErr    error
+		ntfyErr        error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "all providers succeed",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"ok","results":[{"provider":"pushover","status":"ok"},{"provider":"ntfy","status":"ok"}]}`,
+		},
+		{
+			name:           "partial failure",
+			pushoverErr:    fmt.Errorf("outage"),
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"partial","results":[{"provider":"pushover","status":"error","error":"outage"},{"provider":"ntfy","status":"ok"}]}`,
+		},
+		{
+			name:           "all providers fail",
+			pushoverErr:    fmt.Errorf("outage"),
+			ntfyErr:        fmt.Errorf("refused"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"Failed to deliver notification","code":"send_failed","results":[{"provider":"pushover","status":"error","error":"outage"},{"provider":"ntfy","status":"error","error":"refused"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				PushoverAPIToken: "test_token",
+				PushoverUserKey:  "test_user",
+				BearerToken:      "Bearer test_token",
+			}
+
+			deps := &HandlerDependencies{
+				Config:         cfg,
+				PushoverClient: &MockPushoverClient{},
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+				Notifier: notify.NewCoordinator(notify.ModeFanOut,
+					&MockNotificationSender{name: "pushover", err: tt.pushoverErr},
+					&MockNotificationSender{name: "ntfy", err: tt.ntfyErr},
+				),
+			}
+
+			handler := CreateWebhookHandler(deps)
+
+			req, _ := http.NewRequest("POST", "/webhook", strings.NewReader(`{"severity":"error"}`))
+			req.Header.Set("Authorization", "Bearer test_token")
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("Expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
 	}
+}
 
+// RecordingNotificationSender keeps the messages it was asked to send
+type RecordingNotificationSender struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (r *RecordingNotificationSender) Name() string {
+	return "recording"
+}
+
+func (r *RecordingNotificationSender) Send(ctx context.Context, n *notify.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, n.Body)
+	return nil
+}
+
+func TestCreateWebhookHandler_PooledAlertReset(t *testing.T) {
+	sender := &RecordingNotificationSender{}
 	deps := &HandlerDependencies{
-		Config:         cfg,
-		PushoverClient: &MockPushoverClient{},
+		Config: &config.Config{
+			PushoverAPIToken: "api_token",
+			PushoverUserKey:  "user_key",
+			BearerToken:      "Bearer test_token",
+		},
 		Logger:         &MockLogger{},
 		MessageBuilder: BuildPushoverMessage,
+		Notifier:       notify.NewCoordinator(notify.ModeFanOut, sender),
 	}
-
 	handler := CreateWebhookHandler(deps)
 
-	alert := types.FluxAlert{
-		Severity: "error",
-		Message:  "Benchmark test message",
+	payloads := []string{
+		`{"severity":"error","reason":"HealthCheckFailed","message":"boom","reportingController":"kustomize-controller","involvedObject":{"kind":"Kustomization","name":"apps"},"metadata":{"revision":"main@sha1:abc"}}`,
+		`{"severity":"info"}`,
+		`{"severity":"error","reason":`,
+		`{"severity":"info"}`,
+	}
+	for _, payload := range payloads {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(payload))
+		req.Header.Set("Authorization", "Bearer test_token")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
 	}
 
-	body, _ := json.Marshal(alert)
+	expected := BuildPushoverMessage(&types.FluxAlert{Severity: "info"})
+	if len(sender.messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(sender.messages))
+	}
+	for _, i := range []int{1, 2} {
+		if sender.messages[i] != expected {
+			t.Errorf("Message %d carries state from an earlier request:\n%s", i, sender.messages[i])
+		}
+	}
+}
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		req, _ := http.NewRequest("POST", "/webhook", bytes.NewBuffer(body))
-		req.Header.Set("Authorization", "Bearer test_api_token")
-		req.Header.Set("Content-Type", "application/json")
+func TestCreateWebhookHandler_SeverityCredentials(t *testing.T) {
+	var sent []*types.PushoverMessage
+	deps := &HandlerDependencies{
+		Config: &config.Config{
+			PushoverAPIToken: "api_token",
+			PushoverUserKey:  "user_key",
+			BearerToken:      "Bearer api_token",
+			SeverityCredentials: map[string]config.PushoverCredentials{
+				types.SeverityError: {Token: "error_token", User: "error_user"},
+			},
+		},
+		PushoverClient: &MockPushoverClient{SendMessageFunc: func(_ context.Context, msg *types.PushoverMessage) error {
+			sent = append(sent, msg)
+			return nil
+		}},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+	}
+	handler := CreateWebhookHandler(deps)
 
+	for _, severity := range []string{"error", "info"} {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"severity":"`+severity+`","message":"m"}`))
+		// The webhook token stays the default API token for every severity
+		req.Header.Set("Authorization", "Bearer api_token")
 		rr := httptest.NewRecorder()
 		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", severity, rr.Code)
+		}
+	}
+
+	if len(sent) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(sent))
+	}
+	if sent[0].Token != "error_token" || sent[0].User != "error_user" {
+		t.Errorf("Expected the error alert sent with the error credentials, got %s/%s", sent[0].Token, sent[0].User)
+	}
+	if sent[1].Token != "api_token" || sent[1].User != "user_key" {
+		t.Errorf("Expected the info alert sent with the default credentials, got %s/%s", sent[1].Token, sent[1].User)
+	}
+}
+
+func TestCreateNotifier(t *testing.T) {
+	tests := []struct {
+		name              string
+		providers         []string
+		expectedProviders []string
+		expectError       bool
+	}{
+		{"default", nil, []string{"pushover"}, false},
+		{"all providers", []string{"ntfy", "pushover", "webhook", "slack"}, []string{"ntfy", "pushover", "webhook", "slack"}, false},
+		{"slack only", []string{"slack"}, []string{"slack"}, false},
+		{"pushover and slack", []string{"pushover", "slack"}, []string{"pushover", "slack"}, false},
+		{"unknown provider", []string{"carrier-pigeon"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Providers:          tt.providers,
+				NtfyURL:            "https://ntfy.sh",
+				NtfyTopic:          "flux",
+				OutgoingWebhookURL: "https://hooks.example.com",
+				SlackWebhookURL:    "https://hooks.slack.com/services/T/B/X",
+			}
+
+			notifier, err := CreateNotifier(cfg, &MockHTTPClient{}, &MockPushoverClient{}, &MockLogger{})
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+			}
+
+			if tt.expectError {
+				return
+			}
+
+			providers := notifier.Providers()
+			if strings.Join(providers, ",") != strings.Join(tt.expectedProviders, ",") {
+				t.Errorf("Expected providers %v, got %v", tt.expectedProviders, providers)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_ForwardingIsolated(t *testing.T) {
+	var forwardAttempts atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwardAttempts.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	cfg := &config.Config{
+		PushoverAPIToken: "test_token",
+		PushoverUserKey:  "test_user",
+		BearerToken:      "Bearer test_token",
+	}
+
+	var pushoverCalls atomic.Int32
+	registry := metrics.NewRegistry()
+	logger := &MockLogger{}
+	deps := &HandlerDependencies{
+		Config: cfg,
+		PushoverClient: &MockPushoverClient{
+			SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+				pushoverCalls.Add(1)
+				return nil
+			},
+		},
+		Logger:         logger,
+		MessageBuilder: BuildPushoverMessage,
+		Forwarder:      forward.NewForwarder(ts.Client(), ts.URL, "", logger, registry).WithRetry(2, time.Millisecond),
+		Metrics:        registry,
+	}
+
+	handler := CreateWebhookHandler(deps)
+
+	req, _ := http.NewRequest("POST", "/webhook", strings.NewReader(`{"severity":"error","message":"boom"}`))
+	req.Header.Set("Authorization", "Bearer test_token")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	if !bytes.Equal(rr.Body.Bytes(), types.ResponseOK) {
+		t.Errorf("Expected body %s, got %s", types.ResponseOK, rr.Body.String())
+	}
+
+	if pushoverCalls.Load() != 1 {
+		t.Errorf("Expected 1 Pushover call, got %d", pushoverCalls.Load())
+	}
+
+	if err := deps.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	if forwardAttempts.Load() == 0 {
+		t.Error("Expected the event to be forwarded")
+	}
+
+	if v := registry.Counter("forward_failures_total", "").Value(); v != 1 {
+		t.Errorf("Expected forward_failures_total 1, got %d", v)
+	}
+}
+
+func TestCreateWebhookHandler_ForwardsOnlyValidEvents(t *testing.T) {
+	var forwardAttempts atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwardAttempts.Add(1)
+	}))
+	defer ts.Close()
+
+	deps := &HandlerDependencies{
+		Config:         &config.Config{BearerToken: "Bearer test_token"},
+		PushoverClient: &MockPushoverClient{},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		Forwarder:      forward.NewForwarder(ts.Client(), ts.URL, "", &MockLogger{}, nil),
+	}
+
+	req, _ := http.NewRequest("POST", "/webhook", strings.NewReader("not json"))
+	req.Header.Set("Authorization", "Bearer test_token")
+
+	rr := httptest.NewRecorder()
+	CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	if err := deps.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	if forwardAttempts.Load() != 0 {
+		t.Errorf("Expected invalid payload not to be forwarded, got %d attempts", forwardAttempts.Load())
+	}
+}
+
+// FakeEventEmitter records emitted Kubernetes Events
+type FakeEventEmitter struct {
+	events []kube.Event
+}
+
+func (f *FakeEventEmitter) Emit(ctx context.Context, event kube.Event) error {
+	f.events = append(f.events, event)
+	return nil
+}
+
+func TestCreateWebhookHandler_KubernetesEvents(t *testing.T) {
+	tests := []struct {
+		name           string
+		pushoverError  error
+		expectedEvents int
+	}{
+		{"delivery succeeds", nil, 0},
+		{"delivery fails", fmt.Errorf("pushover API returned status 500"), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emitter := &FakeEventEmitter{}
+			deps := &HandlerDependencies{
+				Config: &config.Config{
+					PushoverAPIToken: "test_token",
+					BearerToken:      "Bearer test_token",
+				},
+				PushoverClient: &MockPushoverClient{
+					SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+						return tt.pushoverError
+					},
+				},
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+				Events:         emitter,
+			}
+
+			body := `{"involvedObject":{"kind":"Kustomization","namespace":"apps","name":"podinfo"}}`
+			req, _ := http.NewRequest("POST", "/webhook", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer test_token")
+
+			rr := httptest.NewRecorder()
+			CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+			if len(emitter.events) != tt.expectedEvents {
+				t.Fatalf("Expected %d events, got %d", tt.expectedEvents, len(emitter.events))
+			}
+
+			if tt.expectedEvents == 0 {
+				return
+			}
+
+			event := emitter.events[0]
+			if event.Namespace != "apps" || event.Kind != "Kustomization" || event.Name != "podinfo" {
+				t.Errorf("Unexpected event object: %+v", event)
+			}
+
+			if !contains(event.Message, "apps/Kustomization/podinfo") || !contains(event.Message, "status 500") {
+				t.Errorf("Unexpected event message: %s", event.Message)
+			}
+		})
+	}
+}
+
+func TestCreateReadyHandler(t *testing.T) {
+	var sendErr error
+	tracker := pushover.NewStatusTracker(&MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			return sendErr
+		},
+	})
+
+	deps := &HandlerDependencies{
+		Config: &config.Config{
+			PushoverAPIToken: "test_token",
+			BearerToken:      "Bearer test_token",
+		},
+		PushoverClient: tracker,
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		SendStatus:     tracker,
+	}
+	router := CreateRouter(deps)
+
+	send := func() {
+		req, _ := http.NewRequest("POST", "/webhook", strings.NewReader(`{"message":"hello"}`))
+		req.Header.Set("Authorization", "Bearer test_token")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	ready := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+		return rr
+	}
+
+	if rr := ready(); rr.Code != http.StatusOK || rr.Body.String() != `{"status":"ready"}` {
+		t.Fatalf("Expected ready before any send, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	lastSendError := func() *pushover.SendError {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/status", nil))
+		var body struct {
+			LastSendError *pushover.SendError `json:"last_send_error"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to decode status: %v", err)
+		}
+		return body.LastSendError
+	}
+
+	// Without SEND_FAILURE_UNREADY_WINDOW a failing send is only reported on
+	// /status
+	sendErr = fmt.Errorf("pushover API returned status 503")
+	send()
+
+	if rr := ready(); rr.Code != http.StatusOK {
+		t.Fatalf("Expected ready after a failed send, got %d %s", rr.Code, rr.Body.String())
+	}
+	if last := lastSendError(); last == nil || last.Message != sendErr.Error() || last.Time.IsZero() {
+		t.Errorf("Expected the send error on /status, got %+v", last)
+	}
+
+	// The next successful send clears it
+	sendErr = nil
+	send()
+
+	if last := lastSendError(); last != nil {
+		t.Errorf("Expected no send error after a successful send, got %+v", last)
+	}
+}
+
+func TestCreateReadyHandler_SendFailureWindow(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	conds := conditions.NewSetWithClock(fake, conditions.Ready)
+	var sendErr error
+	tracker := pushover.NewStatusTracker(&MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			return sendErr
+		},
+	}).WithClock(fake)
+	deps := &HandlerDependencies{
+		Config:         &config.Config{SendFailureUnreadyWindow: time.Minute},
+		PushoverClient: tracker,
+		Logger:         &MockLogger{},
+		SendStatus:     tracker,
+		Conditions:     conds,
+		Clock:          fake,
+	}
+	send := func() {
+		_ = tracker.SendMessage(context.Background(), &types.PushoverMessage{})
+	}
+	ready := func() (int, readinessResponse) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		CreateReadyHandler(deps).ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+		var body readinessResponse
+		if rr.Code != http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to decode body: %v", err)
+			}
+		}
+		return rr.Code, body
+	}
+	readyCondition := func() string {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		CreateStatusHandler(deps).ServeHTTP(rr, httptest.NewRequest("GET", "/status", nil))
+		var response struct {
+			Conditions []map[string]string `json:"conditions"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode status: %v", err)
+		}
+		return response.Conditions[0]["reason"]
+	}
+
+	// A failed send fails readiness, reporting the error
+	sendErr = fmt.Errorf("pushover API returned status 503")
+	send()
+	code, body := ready()
+	if code != http.StatusServiceUnavailable || body.Status != "not ready" || body.LastError != sendErr.Error() || body.LastErrorTime != "2024-01-02T03:04:05Z" {
+		t.Fatalf("Expected not ready after a failed send, got %d %+v", code, body)
+	}
+
+	// The next successful send clears it within the window
+	fake.Advance(30 * time.Second)
+	sendErr = nil
+	send()
+	if code, body := ready(); code != http.StatusOK {
+		t.Fatalf("Expected ready after a successful send, got %d %+v", code, body)
+	}
+
+	// Without one, the replica is ready again once the window has passed
+	sendErr = fmt.Errorf("pushover API returned status 503")
+	send()
+	fake.Advance(59 * time.Second)
+	if code, _ := ready(); code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected not ready within the window, got %d", code)
+	}
+	if reason := readyCondition(); reason != "SendFailed" {
+		t.Errorf("Expected the Ready condition SendFailed within the window, got %q", reason)
+	}
+	fake.Advance(time.Second)
+	if code, body := ready(); code != http.StatusOK {
+		t.Fatalf("Expected ready after the window, got %d %+v", code, body)
+	}
+	if reason := readyCondition(); reason != "Ready" {
+		t.Errorf("Expected the Ready condition Ready after the window, got %q", reason)
+	}
+}
+
+func TestCreateRouter_CredentialsInvalid(t *testing.T) {
+	revoked := &pushover.APIError{Status: http.StatusBadRequest, Errors: []string{"application token is invalid"}}
+	reactivated := false
+	var sent []*types.PushoverMessage
+	client := &MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			sent = append(sent, msg)
+			if !reactivated {
+				return revoked
+			}
+			return nil
+		},
+	}
+	validator := ValidatorFunc(func(ctx context.Context, token, user string) error {
+		if !reactivated {
+			return revoked
+		}
+		return nil
+	})
+
+	cfg := &config.Config{PushoverAPIToken: "app_token", PushoverUserKey: "user", BearerToken: "Bearer webhook_token"}
+	logger := &RecordingLogger{}
+	guard := pushover.NewCredentialGuard(client).WithRecheck(validator, time.Minute)
+	guard.OnInvalid(credentialsInvalidNotice(cfg, guard, logger))
+	deps := &HandlerDependencies{
+		Config:         cfg,
+		PushoverClient: guard,
+		Logger:         logger,
+		MessageBuilder: BuildPushoverMessage,
+		Credentials:    guard,
+	}
+	router := CreateRouter(deps)
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"severity":"error","message":"hello"}`))
+		req.Header.Set("Authorization", "Bearer webhook_token")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	// The rejection flips the state and attempts one notice
+	if rr := send(); rr.Code != http.StatusBadGateway || !contains(rr.Body.String(), `"code":"api_rejected"`) {
+		t.Fatalf("Expected the rejection, got %d %s", rr.Code, rr.Body.String())
+	}
+	if len(sent) != 1 {
+		t.Errorf("Expected the notice to fail fast with the same credentials, got %d sends", len(sent))
+	}
+	logged := strings.Join(logger.lines, "\n")
+	if !contains(logged, "Pushover rejected the credentials") || !contains(logged, "Failed to notify about the invalid Pushover credentials") {
+		t.Errorf("Expected the rejection and the failed notice logged, got %s", logged)
+	}
+
+	// Later sends fail fast without calling Pushover
+	if rr := send(); rr.Code != http.StatusBadGateway || !contains(rr.Body.String(), `"code":"credentials_invalid"`) {
+		t.Errorf("Expected a fast failure, got %d %s", rr.Code, rr.Body.String())
+	}
+	if len(sent) != 1 {
+		t.Errorf("Expected no further sends, got %d", len(sent))
+	}
+
+	if rr := get("/ready"); rr.Code != http.StatusServiceUnavailable || !contains(rr.Body.String(), `"status":"credentials invalid"`) || !contains(rr.Body.String(), "application token is invalid") {
+		t.Errorf("Expected not ready with invalid credentials, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := get("/status"); !contains(rr.Body.String(), `"credentials_invalid":{"reason":"pushover API returned status 400: application token is invalid"`) {
+		t.Errorf("Expected the state on /status, got %s", rr.Body.String())
+	}
+
+	// A recheck failing keeps the state
+	if rejected := guard.Recheck(context.Background()); rejected != 1 {
+		t.Errorf("Expected the credentials still rejected, got %d", rejected)
+	}
+
+	// Once Pushover accepts the credentials again, e.g. of a reactivated
+	// application, the recheck recovers without waiting for a send
+	reactivated = true
+	if rejected := guard.Recheck(context.Background()); rejected != 0 {
+		t.Errorf("Expected the credentials accepted, got %d", rejected)
+	}
+	if rr := get("/ready"); rr.Code != http.StatusOK {
+		t.Errorf("Expected ready after recovery, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := get("/status"); contains(rr.Body.String(), "credentials_invalid") {
+		t.Errorf("Expected the state cleared on /status, got %s", rr.Body.String())
+	}
+	if rr := send(); rr.Code != http.StatusOK {
+		t.Errorf("Expected the credentials sent with again, got %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestWriteJSONResponse(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		body       []byte
+	}{
+		{http.StatusOK, []byte(`{"status":"ok"}`)},
+		{http.StatusBadRequest, []byte(`{"error":"bad request"}`)},
+		{http.StatusInternalServerError, []byte(`{"error":"internal error"}`)},
+	}
+
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		writeJSONResponse(rr, tt.statusCode, tt.body)
+
+		if rr.Code != tt.statusCode {
+			t.Errorf("Expected status %d, got %d", tt.statusCode, rr.Code)
+		}
+
+		if contentType := rr.Header().Get("Content-Type"); contentType != types.ContentTypeJSON {
+			t.Errorf("Expected Content-Type %s, got %s", types.ContentTypeJSON, contentType)
+		}
+
+		if !bytes.Equal(rr.Body.Bytes(), tt.body) {
+			t.Errorf("Expected body %s, got %s", tt.body, rr.Body.String())
+		}
+	}
+}
+
+func TestCreateRouter(t *testing.T) {
+	cfg := &config.Config{
+		PushoverAPIToken: "test_token",
+		PushoverUserKey:  "test_user",
+		BearerToken:      "Bearer test_token",
+	}
+
+	deps := &HandlerDependencies{
+		Config:         cfg,
+		PushoverClient: &MockPushoverClient{},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+	}
+
+	router := CreateRouter(deps)
+
+	// Test each route
+	tests := []struct {
+		path           string
+		method         string
+		expectedStatus int
+	}{
+		{"/", "GET", http.StatusBadRequest},
+		{"/health", "GET", http.StatusOK},
+		{"/ready", "GET", http.StatusOK},
+		{"/status", "GET", http.StatusOK},
+		{"/webhook", "POST", http.StatusUnauthorized}, // No auth header
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, tt.path, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatus {
+			t.Errorf("Path %s: expected status %d, got %d",
+				tt.path, tt.expectedStatus, rr.Code)
+		}
+	}
+
+	// Load balancers probing "/" can be given a 200
+	cfg.RootOK = true
+	req, _ := http.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+	CreateRouter(deps).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d on / with ROOT_OK, got %d", http.StatusOK, rr.Code)
+	}
+}
+
+// Benchmark tests
+func BenchmarkCreateWebhookHandler(b *testing.B) {
+	alert := types.FluxAlert{
+		Severity:            "error",
+		Message:             "Benchmark test message",
+		Reason:              "ReconciliationFailed",
+		ReportingController: "kustomize-controller",
+	}
+	alert.InvolvedObject.Kind = "Kustomization"
+	alert.InvolvedObject.Namespace = "flux-system"
+	alert.InvolvedObject.Name = "apps"
+	alert.Metadata = map[string]string{"revision": "main@sha1:abc123"}
+
+	body, _ := json.Marshal(alert)
+
+	benchmarks := []struct {
+		name  string
+		token string
+	}{
+		{"test mode", "test_api_token"},
+		{"send", "api_token"},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			deps := &HandlerDependencies{
+				Config: &config.Config{
+					PushoverAPIToken: bm.token,
+					PushoverUserKey:  "test_user",
+					BearerToken:      "Bearer " + bm.token,
+				},
+				PushoverClient: &MockPushoverClient{},
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+			}
+			handler := CreateWebhookHandler(deps)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				req, _ := http.NewRequest("POST", "/webhook", bytes.NewReader(body))
+				req.Header.Set("Authorization", "Bearer "+bm.token)
+				req.Header.Set("Content-Type", "application/json")
+
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_RetriesExhausted(t *testing.T) {
+	tests := []struct {
+		name           string
+		failures       int
+		expectedStatus int
+		expectedMetric uint64
+	}{
+		{"succeeds on retry", 2, http.StatusOK, 0},
+		{"all retries fail", 3, http.StatusServiceUnavailable, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			client := &MockPushoverClient{
+				SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+					calls++
+					if calls <= tt.failures {
+						return &pushover.APIError{Status: http.StatusBadGateway}
+					}
+					return nil
+				},
+			}
+			registry := metrics.NewRegistry()
+			deps := &HandlerDependencies{
+				Config:         &config.Config{PushoverAPIToken: "test_token", BearerToken: "Bearer test_token"},
+				PushoverClient: pushover.NewRetryingSender(client, 3, 0, pushover.NewRetryBudget(10, 0.1)),
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+				Metrics:        registry,
+			}
+			handler := CreateWebhookHandler(deps)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"message":"failed"}`))
+			req.Header.Set("Authorization", "Bearer test_token")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedStatus == http.StatusServiceUnavailable && !contains(rr.Body.String(), `"attempts":3`) {
+				t.Errorf("Expected attempts in body, got %s", rr.Body.String())
+			}
+
+			exhausted := registry.Counter("pushover_retries_exhausted_total", "").Value()
+			if exhausted != tt.expectedMetric {
+				t.Errorf("Expected pushover_retries_exhausted_total %d, got %d", tt.expectedMetric, exhausted)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_RetryAfter(t *testing.T) {
+	exhausted := &pushover.RetriesExhaustedError{Attempts: 3, Err: &pushover.APIError{Status: http.StatusBadGateway}}
+	failing := &MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			return exhausted
+		},
+	}
+
+	tests := []struct {
+		name       string
+		retryAfter int
+		notifier   *notify.Coordinator
+		expected   string
+	}{
+		{"pushover only", 30, nil, "30"},
+		{"several providers", 45, notify.NewCoordinator(notify.ModeFanOut,
+			&MockNotificationSender{name: "pushover", err: exhausted},
+			&MockNotificationSender{name: "ntfy", err: exhausted},
+		), "45"},
+		{"disabled", 0, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := &HandlerDependencies{
+				Config:         &config.Config{PushoverAPIToken: "test_token", BearerToken: "Bearer test_token", RetryAfterSeconds: tt.retryAfter},
+				PushoverClient: failing,
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+				Notifier:       tt.notifier,
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"message":"failed"}`))
+			req.Header.Set("Authorization", "Bearer test_token")
+			rr := httptest.NewRecorder()
+			CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusServiceUnavailable {
+				t.Fatalf("Expected status 503, got %d: %s", rr.Code, rr.Body.String())
+			}
+			retryAfter := rr.Header().Get("Retry-After")
+			if retryAfter != tt.expected {
+				t.Errorf("Expected Retry-After %q, got %q", tt.expected, retryAfter)
+			}
+			if _, err := strconv.Atoi(retryAfter); tt.expected != "" && err != nil {
+				t.Errorf("Expected a numeric Retry-After, got %q", retryAfter)
+			}
+		})
+	}
+}
+
+func TestSendFailure(t *testing.T) {
+	rejected := &pushover.APIError{Status: http.StatusBadRequest, Errors: []string{"user identifier is invalid"}}
+	rateLimited := &pushover.RateLimitError{RetryAfter: time.Minute, Err: &pushover.APIError{Status: http.StatusTooManyRequests}}
+
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"retries exhausted", &pushover.RetriesExhaustedError{Attempts: 3, Err: rateLimited}, http.StatusServiceUnavailable, "retries_exhausted"},
+		{"rate limited", rateLimited, http.StatusServiceUnavailable, "rate_limited"},
+		{"transport", &pushover.TransportError{Err: errors.New("connection refused")}, http.StatusServiceUnavailable, "transport_error"},
+		{"rejected", rejected, http.StatusBadGateway, "api_rejected"},
+		{"credentials invalid", fmt.Errorf("%w: %v", pushover.ErrCredentialsInvalid, rejected), http.StatusBadGateway, "credentials_invalid"},
+		{"wrapped rejection", fmt.Errorf("pushover: %w", rejected), http.StatusBadGateway, "api_rejected"},
+		{"other", errors.New("outage"), http.StatusInternalServerError, "send_failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := sendFailure(tt.err)
+			if status != tt.expectedStatus || code != tt.expectedCode {
+				t.Errorf("Expected %d %q, got %d %q", tt.expectedStatus, tt.expectedCode, status, code)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_SendErrorBody(t *testing.T) {
+	tests := []struct {
+		name               string
+		err                error
+		expectedStatus     int
+		expectedRetryAfter string
+		expectedBody       string
+	}{
+		{
+			name:           "rejected",
+			err:            &pushover.APIError{Status: http.StatusBadRequest, Errors: []string{"user identifier is invalid"}, Request: "r-1"},
+			expectedStatus: http.StatusBadGateway,
+			expectedBody:   `{"error":"Failed to send to Pushover","code":"api_rejected","details":"pushover API returned status 400: user identifier is invalid","errors":["user identifier is invalid"],"request":"r-1"}`,
+		},
+		{
+			name:               "rate limited",
+			err:                &pushover.RateLimitError{RetryAfter: 90500 * time.Millisecond, Err: &pushover.APIError{Status: http.StatusTooManyRequests}},
+			expectedStatus:     http.StatusServiceUnavailable,
+			expectedRetryAfter: "91",
+			expectedBody:       `{"error":"Failed to send to Pushover","code":"rate_limited","details":"pushover rate limit reached, retry after 1m30.5s: pushover API returned status 429"}`,
+		},
+		{
+			name:               "transport",
+			err:                &pushover.TransportError{Err: errors.New("connection refused")},
+			expectedStatus:     http.StatusServiceUnavailable,
+			expectedRetryAfter: "30",
+			expectedBody:       `{"error":"Failed to send to Pushover","code":"transport_error","details":"failed to send request: connection refused"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := &HandlerDependencies{
+				Config: &config.Config{PushoverAPIToken: "test_token", BearerToken: "Bearer test_token", RetryAfterSeconds: 30},
+				PushoverClient: &MockPushoverClient{
+					SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+						return tt.err
+					},
+				},
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"message":"failed"}`))
+			req.Header.Set("Authorization", "Bearer test_token")
+			rr := httptest.NewRecorder()
+			CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if retryAfter := rr.Header().Get("Retry-After"); retryAfter != tt.expectedRetryAfter {
+				t.Errorf("Expected Retry-After %q, got %q", tt.expectedRetryAfter, retryAfter)
+			}
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("Expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_FirstTryFailureNotExhausted(t *testing.T) {
+	registry := metrics.NewRegistry()
+	deps := &HandlerDependencies{
+		Config: &config.Config{PushoverAPIToken: "test_token", BearerToken: "Bearer test_token"},
+		PushoverClient: &MockPushoverClient{
+			SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+				return &pushover.APIError{Status: http.StatusBadGateway}
+			},
+		},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		Metrics:        registry,
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"message":"failed"}`))
+	req.Header.Set("Authorization", "Bearer test_token")
+	rr := httptest.NewRecorder()
+	CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadGateway {
+		t.Errorf("Expected status %d, got %d", http.StatusBadGateway, rr.Code)
+	}
+	if value := registry.Counter("pushover_retries_exhausted_total", "").Value(); value != 0 {
+		t.Errorf("Expected no exhausted retries for a first-try failure, got %d", value)
+	}
+}
+
+// rateLimitedResponse acknowledges an alert dropped by a 2 a minute limit
+var rateLimitedResponse = []byte(`{"status":"rate_limited","rule":"PER_NAMESPACE_RATE","detail":"namespace \"noisy\" over 2 alerts a minute"}`)
+
+func TestCreateWebhookHandler_PerNamespaceRateLimit(t *testing.T) {
+	sent := map[string]int{}
+	registry := metrics.NewRegistry()
+	deps := &HandlerDependencies{
+		Config: &config.Config{PushoverAPIToken: "test_token", BearerToken: "Bearer test_token"},
+		PushoverClient: &MockPushoverClient{
+			SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+				sent[msg.Message[:strings.IndexByte(msg.Message, ' ')]]++
+				return nil
+			},
+		},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		RateLimiter:    ratelimit.NewKeyedLimiter(2, 10, registry),
+	}
+	handler := CreateWebhookHandler(deps)
+
+	post := func(namespace string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"reason":%q,"involvedObject":{"namespace":%q}}`, namespace, namespace)
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test_token")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 4; i++ {
+		rr := post("noisy")
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", rr.Code)
+		}
+		if limited := i >= 2; limited != bytes.Equal(rr.Body.Bytes(), rateLimitedResponse) {
+			t.Errorf("Alert %d: expected rate limited %v, got %s", i, limited, rr.Body.String())
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if rr := post("quiet"); !bytes.Equal(rr.Body.Bytes(), types.ResponseOK) {
+			t.Errorf("Expected quiet namespace to flow freely, got %s", rr.Body.String())
+		}
+	}
+
+	if sent["noisy"] != 2 || sent["quiet"] != 2 {
+		t.Errorf("Expected 2 alerts sent per namespace, got %v", sent)
+	}
+	if limited := registry.CounterVec("alerts_rate_limited_total", "", "namespace").WithLabelValues("noisy").Value(); limited != 2 {
+		t.Errorf("Expected 2 rate limited alerts, got %d", limited)
+	}
+}
+
+func TestCreateWebhookHandler_SampledFailureLog(t *testing.T) {
+	logger := &MockLogger{}
+	failureLog := logsample.NewSampler(logger, time.Hour, 2, nil)
+	handler := CreateWebhookHandler(&HandlerDependencies{
+		Config: &config.Config{PushoverAPIToken: "test_token", BearerToken: "Bearer test_token"},
+		PushoverClient: &MockPushoverClient{
+			SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+				return fmt.Errorf("pushover API returned status 503")
+			},
+		},
+		Logger:         logger,
+		MessageBuilder: BuildPushoverMessage,
+		FailureLog:     failureLog,
+	})
+
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"severity":"error","message":"failed"}`))
+		req.Header.Set("Authorization", "Bearer test_token")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		// Sampling only affects the logs, every failure is still answered
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("Expected 500, got %d", rr.Code)
+		}
+	}
+
+	failures := 0
+	for _, message := range logger.messages {
+		if message == "Failed to send to Pushover: %v" {
+			failures++
+		}
+	}
+	if failures != 2 {
+		t.Errorf("Expected 2 failures logged in full, got %d in %v", failures, logger.messages)
+	}
+
+	if err := failureLog.Close(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if last := logger.messages[len(logger.messages)-1]; last != "%s failed %d more times in the last %s: %s" {
+		t.Errorf("Expected the skipped failures to be summarised, got %q", last)
+	}
+}
+
+func TestCreateWebhookHandler_SlackSink(t *testing.T) {
+	tests := []struct {
+		name             string
+		providers        []string
+		pushoverErr      error
+		slackStatus      int
+		expectedStatus   int
+		expectedPushover int
+		expectedSlack    int
+	}{
+		{"pushover only", []string{"pushover"}, nil, http.StatusOK, http.StatusOK, 1, 0},
+		{"slack only", []string{"slack"}, nil, http.StatusOK, http.StatusOK, 0, 1},
+		{"both", []string{"pushover", "slack"}, nil, http.StatusOK, http.StatusOK, 1, 1},
+		{"both, slack failing", []string{"pushover", "slack"}, nil, http.StatusInternalServerError, http.StatusOK, 1, 1},
+		{"both failing", []string{"pushover", "slack"}, fmt.Errorf("status 503"), http.StatusInternalServerError, http.StatusInternalServerError, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pushoverSends, slackSends atomic.Int32
+			slack := &MockHTTPClient{
+				DoFunc: func(req *http.Request) (*http.Response, error) {
+					slackSends.Add(1)
+					return &http.Response{StatusCode: tt.slackStatus, Body: io.NopCloser(strings.NewReader(""))}, nil
+				},
+			}
+			pushoverClient := &MockPushoverClient{
+				SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+					pushoverSends.Add(1)
+					return tt.pushoverErr
+				},
+			}
+
+			cfg := &config.Config{
+				PushoverAPIToken: "test_token",
+				BearerToken:      "Bearer test_token",
+				Providers:        tt.providers,
+				SlackWebhookURL:  "https://hooks.slack.com/services/T/B/X",
+			}
+			notifier, err := CreateNotifier(cfg, slack, pushoverClient, &MockLogger{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			handler := CreateWebhookHandler(&HandlerDependencies{
+				Config:         cfg,
+				PushoverClient: pushoverClient,
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+				Notifier:       notifier,
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"severity":"error","message":"failed"}`))
+			req.Header.Set("Authorization", "Bearer test_token")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if int(pushoverSends.Load()) != tt.expectedPushover || int(slackSends.Load()) != tt.expectedSlack {
+				t.Errorf("Expected %d Pushover and %d Slack sends, got %d and %d",
+					tt.expectedPushover, tt.expectedSlack, pushoverSends.Load(), slackSends.Load())
+			}
+		})
+	}
+}
+
+func TestCreateReadyHandler_QueueDepth(t *testing.T) {
+	dispatcher := NewDispatcher(1, nil)
+	shedder := NewLoadShedder(1, 100, nil)
+	deps := &HandlerDependencies{
+		Config:     &config.Config{QueueUnhealthyDepth: 3},
+		Dispatcher: dispatcher,
+		Shedder:    shedder,
+	}
+	handler := CreateReadyHandler(deps)
+	ready := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+		return rr
+	}
+
+	// The worker is busy, so every further delivery waits in its queue
+	release := make(chan struct{})
+	started := make(chan struct{})
+	dispatcher.Submit("apps/Kustomization/apps", func() {
+		close(started)
+		<-release
+	})
+	<-started
+	for i := 0; i < 3; i++ {
+		dispatcher.Submit("apps/Kustomization/apps", func() {})
+	}
+	if rr := ready(); rr.Code != http.StatusOK {
+		t.Fatalf("Expected ready at the threshold, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	// A delivery waiting for a slot of the shedder counts as well
+	done, err := shedder.Acquire(context.Background(), &types.FluxAlert{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	acquired := make(chan struct{})
+	go func() {
+		release, err := shedder.Acquire(context.Background(), &types.FluxAlert{})
+		if err == nil {
+			release()
+		}
+		close(acquired)
+	}()
+	waitFor(t, func() bool { return shedder.Waiting() == 1 })
+
+	rr := ready()
+	if rr.Code != http.StatusServiceUnavailable || rr.Body.String() != `{"status":"queue backed up","queue_depth":4}` {
+		t.Fatalf("Expected 503 with the queue depth, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	// Draining the queues restores readiness
+	done()
+	<-acquired
+	close(release)
+	if err := dispatcher.Drain(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rr := ready(); rr.Code != http.StatusOK || rr.Body.String() != `{"status":"ready"}` {
+		t.Errorf("Expected ready once drained, got %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestCreateReadyHandler_Starting(t *testing.T) {
+	health := &server.HealthState{}
+	handler := CreateReadyHandler(&HandlerDependencies{Config: &config.Config{}, Health: health})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+	if rr.Code != http.StatusServiceUnavailable || rr.Body.String() != `{"status":"starting"}` {
+		t.Errorf("Expected 503 starting before startup completed, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	// /health does not wait for startup, the process is alive
+	rr = httptest.NewRecorder()
+	CreateHealthHandler(health).ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected /health to pass while starting, got %d", rr.Code)
+	}
+
+	health.MarkStarted()
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 once started, got %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestCreateStatusHandler_Conditions(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	conds := conditions.NewSetWithClock(fake, conditions.Ready, conditions.PushoverReachable, conditions.CredentialsValid, conditions.QueueHealthy)
+	sendErr := &pushover.APIError{Status: http.StatusServiceUnavailable}
+	var failing atomic.Bool
+	sendStatus := pushover.NewStatusTracker(&MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			if failing.Load() {
+				return sendErr
+			}
+			return nil
+		},
+	}).WithConditions(conds)
+	shedder := NewLoadShedder(1, 10, nil)
+	deps := &HandlerDependencies{
+		Config:     &config.Config{QueueUnhealthyDepth: 1},
+		Logger:     &MockLogger{},
+		SendStatus: sendStatus,
+		Shedder:    shedder,
+		Health:     &server.HealthState{},
+		Conditions: conds,
+	}
+	deps.watchConditions()
+	status := func() []map[string]string {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		CreateStatusHandler(deps).ServeHTTP(rr, httptest.NewRequest("GET", "/status", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rr.Code)
+		}
+		var response struct {
+			Conditions []map[string]string `json:"conditions"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode status: %v", err)
+		}
+		return response.Conditions
+	}
+
+	// Ready turns True when startup completes, not when it is polled
+	fake.Advance(time.Minute)
+	deps.MarkStarted()
+	fake.Advance(time.Minute)
+	failing.Store(true)
+	_ = sendStatus.SendMessage(context.Background(), &types.PushoverMessage{})
+	fake.Advance(time.Minute)
+	expected := []map[string]string{
+		{"type": "Ready", "status": "True", "reason": "Ready", "lastTransitionTime": "2024-01-02T03:05:05Z"},
+		{"type": "PushoverReachable", "status": "False", "reason": "APIUnavailable", "message": sendErr.Error(), "lastTransitionTime": "2024-01-02T03:06:05Z"},
+		{"type": "CredentialsValid", "status": "Unknown", "reason": "NotObserved", "lastTransitionTime": "2024-01-02T03:04:05Z"},
+		{"type": "QueueHealthy", "status": "True", "reason": "BelowThreshold", "message": "0 deliveries waiting", "lastTransitionTime": "2024-01-02T03:04:05Z"},
+	}
+	if got := status(); fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Expected conditions %v, got %v", expected, got)
+	}
+
+	// The queue backing up turns Ready and QueueHealthy False when it happens
+	release, err := shedder.Acquire(context.Background(), &types.FluxAlert{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = shedder.Acquire(ctx, &types.FluxAlert{})
+		}()
+	}
+	waitFor(t, func() bool { return shedder.Waiting() == 2 })
+	fake.Advance(time.Minute)
+	got := status()
+	if got[0]["status"] != "False" || got[0]["reason"] != "QueueBackedUp" || got[0]["lastTransitionTime"] != "2024-01-02T03:07:05Z" {
+		t.Errorf("Expected Ready False since the queue backed up, got %v", got[0])
+	}
+	if got[3]["status"] != "False" || got[3]["lastTransitionTime"] != "2024-01-02T03:07:05Z" {
+		t.Errorf("Expected QueueHealthy False since the queue backed up, got %v", got[3])
+	}
+
+	// Recovering moves the transition times of the changed conditions only
+	cancel()
+	wg.Wait()
+	release()
+	failing.Store(false)
+	_ = sendStatus.SendMessage(context.Background(), &types.PushoverMessage{})
+	fake.Advance(time.Minute)
+	got = status()
+	if got[0]["status"] != "True" || got[0]["lastTransitionTime"] != "2024-01-02T03:08:05Z" {
+		t.Errorf("Expected Ready True since the queue drained, got %v", got[0])
+	}
+	if got[1]["status"] != "True" || got[1]["reason"] != "Sent" {
+		t.Errorf("Expected PushoverReachable True, got %v", got[1])
+	}
+	if got[3]["status"] != "True" || got[3]["lastTransitionTime"] != "2024-01-02T03:08:05Z" {
+		t.Errorf("Expected QueueHealthy True since the queue drained, got %v", got[3])
+	}
+
+	// Without a set, /status reports no conditions
+	deps.Conditions = nil
+	if got := status(); got != nil {
+		t.Errorf("Expected no conditions, got %v", got)
 	}
 }
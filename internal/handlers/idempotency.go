@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/idempotency"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// Idempotency headers
+const (
+	IdempotencyKeyHeader  = "Idempotency-Key"
+	XIdempotencyKeyHeader = "X-Idempotency-Key" // Older spelling, used when Idempotency-Key is missing
+	ReplayedHeader        = "Idempotent-Replayed"
+)
+
+// IdempotencyKey returns the client supplied key, or one derived from the
+// normalized alert, whose timestamp tells retries apart from new events (pure function)
+func IdempotencyKey(r *http.Request, alert *types.FluxAlert) string {
+	for _, header := range []string{IdempotencyKeyHeader, XIdempotencyKeyHeader} {
+		if key := strings.TrimSpace(r.Header.Get(header)); key != "" {
+			return "header:" + key
+		}
+	}
+
+	// Re-encoding the decoded struct normalizes field order and whitespace
+	normalized, err := json.Marshal(alert)
+	if err != nil {
+		return ""
+	}
+	normalized = append(normalized, alert.Cluster...)
+	sum := sha256.Sum256(normalized)
+	return "alert:" + hex.EncodeToString(sum[:])
+}
+
+// serveIdempotent runs deliver at most once per idempotency key, replaying
+// the stored response to retries
+func serveIdempotent(deps *HandlerDependencies, w http.ResponseWriter, r *http.Request, key string, alert *types.FluxAlert, deliver func(http.ResponseWriter)) {
+	if key == "" {
+		deliver(w)
+		return
+	}
+
+	cached, err := deps.Idempotency.Begin(r.Context(), key)
+	if errors.Is(err, idempotency.ErrInFlight) {
+		deps.Logger.Printf("Rejected retry while the original request is in flight")
+		writeJSONResponse(w, http.StatusConflict, types.ResponseInFlight)
+		return
+	}
+	if err != nil {
+		deps.Logger.Printf("Idempotency check failed: %v", err)
+		deliver(w)
+		return
+	}
+
+	if cached != nil {
+		deps.Logger.Printf("Replayed response for retried alert %s/%s", alertKind(alert), alertName(alert))
+		w.Header().Set(ReplayedHeader, "true")
+		writeResponse(w, cached.ContentType, cached.Status, cached.Body)
+		return
+	}
+
+	capture := &responseCapture{ResponseWriter: w, status: http.StatusOK}
+	var response *idempotency.Response
+	defer func() {
+		deps.Idempotency.Complete(key, response)
+	}()
+
+	deliver(capture)
+
+	if idempotency.Cacheable(capture.status) {
+		response = &idempotency.Response{
+			Status:      capture.status,
+			ContentType: capture.Header().Get("Content-Type"),
+			Body:        capture.body.Bytes(),
+		}
+	}
+}
+
+// responseCapture records the status and body written through it
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	c.status = status
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(data []byte) (int, error) {
+	c.body.Write(data)
+	return c.ResponseWriter.Write(data)
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/idempotency"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+func newIdempotencyTestDeps(cache *idempotency.Cache, send func() error) *HandlerDependencies {
+	return &HandlerDependencies{
+		Config: &config.Config{
+			PushoverAPIToken: "test_token",
+			BearerToken:      "Bearer test_token",
+		},
+		PushoverClient: &MockPushoverClient{
+			SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+				return send()
+			},
+		},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		Idempotency:    cache,
+	}
+}
+
+func postAlert(handler http.Handler, body, key string) *httptest.ResponseRecorder {
+	return postAlertWithHeader(handler, body, IdempotencyKeyHeader, key)
+}
+
+func postAlertWithHeader(handler http.Handler, body, header, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/webhook", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test_token")
+	if key != "" {
+		req.Header.Set(header, key)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	alert := &types.FluxAlert{Message: "failed", Timestamp: "2024-01-02T03:04:05Z"}
+	later := *alert
+	later.Timestamp = "2024-01-02T03:05:05Z"
+
+	plain := httptest.NewRequest("POST", "/webhook", nil)
+	withHeader := httptest.NewRequest("POST", "/webhook", nil)
+	withHeader.Header.Set(IdempotencyKeyHeader, " abc ")
+
+	if IdempotencyKey(withHeader, alert) != "header:abc" {
+		t.Errorf("Expected header key, got %s", IdempotencyKey(withHeader, alert))
+	}
+
+	withXHeader := httptest.NewRequest("POST", "/webhook", nil)
+	withXHeader.Header.Set(XIdempotencyKeyHeader, "abc")
+	if IdempotencyKey(withXHeader, alert) != "header:abc" {
+		t.Errorf("Expected X-Idempotency-Key to be used, got %s", IdempotencyKey(withXHeader, alert))
+	}
+
+	withXHeader.Header.Set(IdempotencyKeyHeader, "def")
+	if IdempotencyKey(withXHeader, alert) != "header:def" {
+		t.Errorf("Expected Idempotency-Key to take precedence, got %s", IdempotencyKey(withXHeader, alert))
+	}
+
+	if IdempotencyKey(plain, alert) != IdempotencyKey(plain, alert) {
+		t.Error("Expected derived key to be stable")
+	}
+
+	if IdempotencyKey(plain, alert) == IdempotencyKey(plain, &later) {
+		t.Error("Expected different timestamps to give different keys")
+	}
+}
+
+func TestCreateWebhookHandler_IdempotentReplay(t *testing.T) {
+	tests := []struct {
+		name   string
+		bodies []string
+		header string
+		key    string
+	}{
+		{
+			name:   "idempotency key header",
+			bodies: []string{`{"message":"deployed"}`, `{"message":"deployed"}`},
+			header: IdempotencyKeyHeader,
+			key:    "retry-1",
+		},
+		{
+			name:   "x-idempotency key header",
+			bodies: []string{`{"message":"deployed"}`, `{"message":"deployed"}`},
+			header: XIdempotencyKeyHeader,
+			key:    "retry-1",
+		},
+		{
+			name: "derived from normalized alert",
+			bodies: []string{
+				`{"message":"deployed","timestamp":"2024-01-02T03:04:05Z","severity":"info"}`,
+				`{ "severity": "info", "timestamp": "2024-01-02T03:04:05Z", "message": "deployed" }`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent int32
+			registry := metrics.NewRegistry()
+			deps := newIdempotencyTestDeps(idempotency.NewCache(time.Minute, 10, registry), func() error {
+				atomic.AddInt32(&sent, 1)
+				return nil
+			})
+			handler := CreateWebhookHandler(deps)
+
+			first := postAlertWithHeader(handler, tt.bodies[0], tt.header, tt.key)
+			replay := postAlertWithHeader(handler, tt.bodies[1], tt.header, tt.key)
+
+			if sent != 1 {
+				t.Errorf("Expected one send, got %d", sent)
+			}
+
+			if replay.Code != first.Code || replay.Body.String() != first.Body.String() {
+				t.Errorf("Expected replayed response %d %s, got %d %s", first.Code, first.Body, replay.Code, replay.Body)
+			}
+
+			if replay.Header().Get(ReplayedHeader) != "true" || first.Header().Get(ReplayedHeader) != "" {
+				t.Error("Expected only the replay to be marked")
+			}
+
+			if hits := registry.Counter("idempotency_hits_total", "").Value(); hits != 1 {
+				t.Errorf("Expected 1 cache hit, got %d", hits)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_IdempotencyFailureNotCached(t *testing.T) {
+	var sent int32
+	sendErr := fmt.Errorf("pushover API returned status 500")
+	deps := newIdempotencyTestDeps(idempotency.NewCache(time.Minute, 10, metrics.NewRegistry()), func() error {
+		atomic.AddInt32(&sent, 1)
+		return sendErr
+	})
+	handler := CreateWebhookHandler(deps)
+
+	if rr := postAlert(handler, `{"message":"deployed"}`, "key"); rr.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected 500, got %d", rr.Code)
+	}
+
+	sendErr = nil
+	if rr := postAlert(handler, `{"message":"deployed"}`, "key"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected retry to be delivered, got %d", rr.Code)
+	}
+
+	if sent != 2 {
+		t.Errorf("Expected failed request to be retried, got %d sends", sent)
+	}
+}
+
+func TestCreateWebhookHandler_IdempotencyInFlight(t *testing.T) {
+	tests := []struct {
+		name         string
+		wait         time.Duration
+		expectedCode int
+	}{
+		{"retry waits for the original", time.Second, http.StatusOK},
+		{"retry gives up with conflict", 10 * time.Millisecond, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent int32
+			started := make(chan struct{})
+			release := make(chan struct{})
+			cache := idempotency.NewCache(time.Minute, 10, metrics.NewRegistry()).WithWait(tt.wait)
+			deps := newIdempotencyTestDeps(cache, func() error {
+				if atomic.AddInt32(&sent, 1) == 1 {
+					close(started)
+					<-release
+				}
+				return nil
+			})
+			handler := CreateWebhookHandler(deps)
+
+			var wg sync.WaitGroup
+			var first *httptest.ResponseRecorder
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				first = postAlert(handler, `{"message":"deployed"}`, "")
+			}()
+			<-started
+
+			// The retry arrives while the first send is in flight
+			var retry *httptest.ResponseRecorder
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				retry = postAlert(handler, `{"message":"deployed"}`, "")
+			}()
+
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+			wg.Wait()
+
+			if first.Code != http.StatusOK {
+				t.Errorf("Expected first request to succeed, got %d", first.Code)
+			}
+
+			if retry.Code != tt.expectedCode {
+				t.Errorf("Expected retry status %d, got %d %s", tt.expectedCode, retry.Code, retry.Body)
+			}
+
+			if sent != 1 {
+				t.Errorf("Expected a single send, got %d", sent)
+			}
+		})
+	}
+}
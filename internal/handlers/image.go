@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/notify"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// defaultImageName names fetched images whose URL has no file name
+const defaultImageName = "image"
+
+// errImageTooLarge is returned for images over IMAGE_MAX_BYTES
+var errImageTooLarge = errors.New("image exceeds IMAGE_MAX_BYTES")
+
+// ImageFetcher fetches the image IMAGE_URL_TEMPLATE names for an alert, to
+// be attached to its Pushover message. A nil ImageFetcher fetches nothing.
+type ImageFetcher struct {
+	client   notify.HTTPClient
+	template *template.Template
+	opts     MessageOptions
+	maxBytes int
+	timeout  time.Duration
+}
+
+// NewImageFetcher creates a fetcher for cfg's IMAGE_URL_TEMPLATE, nil when
+// it is empty. A template that fails to parse or execute fails startup.
+func NewImageFetcher(cfg *config.Config, client notify.HTTPClient) (*ImageFetcher, error) {
+	if cfg.ImageURLTemplate == "" {
+		return nil, nil
+	}
+
+	opts := MessageOptionsFromConfig(cfg)
+	tmpl, err := parseMessageTemplate("IMAGE_URL_TEMPLATE", cfg.ImageURLTemplate, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &ImageFetcher{
+		client:   client,
+		template: tmpl,
+		opts:     opts,
+		maxBytes: cfg.ImageMaxBytes,
+		timeout:  cfg.ImageFetchTimeout,
+	}, nil
+}
+
+// ImageURL renders the URL of alert's image, empty when it has none
+func (f *ImageFetcher) ImageURL(alert *types.FluxAlert) (string, error) {
+	var rendered strings.Builder
+	if err := f.template.Execute(&rendered, newTemplateData(alert, f.opts)); err != nil {
+		return "", fmt.Errorf("failed to render IMAGE_URL_TEMPLATE: %w", err)
+	}
+	return strings.TrimSpace(rendered.String()), nil
+}
+
+// Fetch downloads alert's image, returning nil data when it has none. The
+// download is bounded by IMAGE_FETCH_TIMEOUT and IMAGE_MAX_BYTES and must
+// be answered with an image content type.
+func (f *ImageFetcher) Fetch(ctx context.Context, alert *types.FluxAlert) ([]byte, string, error) {
+	if f == nil {
+		return nil, "", nil
+	}
+
+	imageURL, err := f.ImageURL(alert)
+	if err != nil || imageURL == "" {
+		return nil, "", err
+	}
+	parsed, err := url.Parse(imageURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, "", fmt.Errorf("image URL %q is not an absolute http or https URL", imageURL)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, "", fmt.Errorf("image source returned status %d", resp.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); !strings.HasPrefix(mediaType, "image/") {
+		return nil, "", fmt.Errorf("image source returned content type %q", resp.Header.Get("Content-Type"))
+	}
+	if resp.ContentLength > int64(f.maxBytes) {
+		return nil, "", fmt.Errorf("%w: %d bytes over %d", errImageTooLarge, resp.ContentLength, f.maxBytes)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(f.maxBytes)+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read image: %w", err)
+	}
+	if len(data) > f.maxBytes {
+		return nil, "", fmt.Errorf("%w: over %d bytes", errImageTooLarge, f.maxBytes)
+	}
+	return data, imageName(parsed), nil
+}
+
+// imageName returns the file name of an image URL (pure function)
+func imageName(imageURL *url.URL) string {
+	name := path.Base(imageURL.Path)
+	if name == "." || name == "/" {
+		return defaultImageName
+	}
+	return name
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/notify"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// pngImage is the signature of a PNG file, enough for content sniffing
+var pngImage = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+
+func newImageConfig(template string) *config.Config {
+	cfg := config.NewConfig()
+	cfg.PushoverAPIToken = "token"
+	cfg.PushoverUserKey = "user"
+	cfg.ImageURLTemplate = template
+	return cfg
+}
+
+func newImageAlert(name string) *types.FluxAlert {
+	alert := &types.FluxAlert{Severity: "error", Reason: "HealthCheckFailed", Message: "unhealthy"}
+	alert.InvolvedObject.Kind = "Kustomization"
+	alert.InvolvedObject.Namespace = "apps"
+	alert.InvolvedObject.Name = name
+	return alert
+}
+
+func TestImageFetcher_AttachesRemoteImage(t *testing.T) {
+	images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/panels/apps/podinfo.png" || r.Header.Get("Accept") != "image/*" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(pngImage)
+	}))
+	defer images.Close()
+
+	var mu sync.Mutex
+	var form url.Values
+	var attachment []byte
+	var attachmentName string
+	pushoverAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("Expected a multipart upload, got %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		file, header, err := r.FormFile("attachment")
+		if err != nil {
+			t.Errorf("Expected an attachment, got %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		mu.Lock()
+		defer mu.Unlock()
+		form = r.MultipartForm.Value
+		attachment, _ = io.ReadAll(file)
+		attachmentName = header.Filename
+		w.Write([]byte(`{"status":1,"request":"abc"}`))
+	}))
+	defer pushoverAPI.Close()
+
+	cfg := newImageConfig(images.URL + "/panels/{{.Namespace}}/{{.Name | urlquery}}.png")
+	cfg.PushoverURL = pushoverAPI.URL
+	notifier, err := CreateNotifier(cfg, http.DefaultClient, newPushoverClient(http.DefaultClient, cfg), &MockLogger{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	alert := newImageAlert("podinfo")
+	if _, err := notifier.Send(context.Background(), CreateNotification(alert, "HealthCheckFailed [ERROR]")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !bytes.Equal(attachment, pngImage) || attachmentName != "podinfo.png" {
+		t.Errorf("Expected the fetched image attached as podinfo.png, got %q %q", attachmentName, attachment)
+	}
+	if form.Get("message") != "HealthCheckFailed [ERROR]" || form.Get("token") != "token" {
+		t.Errorf("Unexpected message fields %v", form)
+	}
+}
+
+func TestImageFetcher_Fetch(t *testing.T) {
+	images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngImage)
+		case "/large.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(bytes.Repeat([]byte{0}, 64))
+		case "/streamed.png":
+			// No Content-Length, the limit applies while reading
+			w.Header().Set("Content-Type", "image/png")
+			w.(http.Flusher).Flush()
+			w.Write(bytes.Repeat([]byte{0}, 64))
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html></html>"))
+		case "/slow.png":
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer images.Close()
+
+	tests := []struct {
+		name     string
+		template string
+		wantName string
+		wantErr  string
+	}{
+		{name: "image", template: images.URL + "/ok.png", wantName: "ok.png"},
+		{name: "no image for the alert", template: `{{if eq .Name "other"}}` + images.URL + "/ok.png{{end}}"},
+		{name: "over the size limit", template: images.URL + "/large.png", wantErr: "image exceeds IMAGE_MAX_BYTES: 64 bytes over 32"},
+		{name: "over the size limit while reading", template: images.URL + "/streamed.png", wantErr: "image exceeds IMAGE_MAX_BYTES: over 32 bytes"},
+		{name: "not an image", template: images.URL + "/page", wantErr: `image source returned content type "text/html; charset=utf-8"`},
+		{name: "missing", template: images.URL + "/missing.png", wantErr: "image source returned status 404"},
+		{name: "timeout", template: images.URL + "/slow.png", wantErr: "context deadline exceeded"},
+		{name: "relative URL", template: "/ok.png", wantErr: `image URL "/ok.png" is not an absolute http or https URL`},
+		{name: "unsupported scheme", template: "file:///etc/passwd", wantErr: "is not an absolute http or https URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newImageConfig(tt.template)
+			cfg.ImageMaxBytes = 32
+			cfg.ImageFetchTimeout = 50 * time.Millisecond
+			fetcher, err := NewImageFetcher(cfg, http.DefaultClient)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			data, name, err := fetcher.Fetch(context.Background(), newImageAlert("podinfo"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tt.wantName == "" {
+				if data != nil {
+					t.Errorf("Expected no image, got %d bytes", len(data))
+				}
+				return
+			}
+			if !bytes.Equal(data, pngImage) || name != tt.wantName {
+				t.Errorf("Expected %s, got %q %q", tt.wantName, name, data)
+			}
+		})
+	}
+}
+
+func TestImageFetcher_FailureSendsWithoutImage(t *testing.T) {
+	var sent *types.PushoverMessage
+	client := &MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			sent = msg
+			return nil
+		},
+	}
+	httpClient := &MockHTTPClient{DoFunc: func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}}
+
+	cfg := newImageConfig("https://grafana.example.com/render/{{.Name}}.png")
+	fetcher, err := NewImageFetcher(cfg, httpClient)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	logger := &RecordingLogger{}
+	sender := newPushoverSender(cfg, client, fetcher, logger)
+
+	alert := newImageAlert("podinfo")
+	if err := sender.Send(context.Background(), &notify.Notification{Body: "body", Severity: "error", Event: alert}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sent == nil || sent.Attachment != nil {
+		t.Fatalf("Expected the message sent without an attachment, got %+v", sent)
+	}
+	if len(logger.lines) != 1 || !strings.Contains(logger.lines[0], "Sending without the image of Kustomization/podinfo: failed to fetch image") {
+		t.Errorf("Expected the fetch failure to be logged, got %q", logger.lines)
+	}
+}
+
+func TestNewImageFetcher(t *testing.T) {
+	if fetcher, err := NewImageFetcher(newImageConfig(""), http.DefaultClient); fetcher != nil || err != nil {
+		t.Errorf("Expected no fetcher without a template, got %v %v", fetcher, err)
+	}
+	if _, err := NewImageFetcher(newImageConfig("https://example.com/{{.Unknown}}"), http.DefaultClient); err == nil {
+		t.Error("Expected an unknown field to fail startup")
+	}
+
+	var fetcher *ImageFetcher
+	if data, _, err := fetcher.Fetch(context.Background(), newImageAlert("podinfo")); data != nil || err != nil {
+		t.Errorf("Expected nil fetcher to fetch nothing, got %v %v", data, err)
+	}
+}
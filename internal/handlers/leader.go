@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/kube"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// handleNonLeader answers a webhook received by a replica that is not the
+// leader. It is proxied to the leader in proxy mode, and acknowledged without
+// sending otherwise. A request that was already proxied is never proxied
+// again, so replicas that disagree about the leader cannot loop.
+func handleNonLeader(deps *HandlerDependencies, status kube.LeaderStatus, w http.ResponseWriter, r *http.Request) {
+	if deps.Config.LeaderElectionMode != config.LeaderElectionModeProxy ||
+		status.LeaderAddress == "" || r.Header.Get(types.ProxiedHeader) != "" {
+		deps.Logger.Printf("Standby: not sending alert, leader is %q", status.Leader)
+		writeJSONResponse(w, http.StatusOK, types.ResponseStandby)
+		return
+	}
+
+	target, err := url.Parse(status.LeaderAddress)
+	if err != nil {
+		deps.Logger.Printf("Invalid leader address %q: %v", status.LeaderAddress, err)
+		writeJSONResponse(w, http.StatusBadGateway, types.ResponseProxyError)
+		return
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.Out.Header.Set(types.ProxiedHeader, status.Identity)
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			deps.Logger.Printf("Failed to proxy to leader %q: %v", status.Leader, err)
+			writeJSONResponse(w, http.StatusBadGateway, types.ResponseProxyError)
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// podAddress returns the URL other replicas use to reach this pod, empty when
+// the pod IP is unknown (pure function)
+func podAddress(podIP, port string) string {
+	if podIP == "" {
+		return ""
+	}
+	return "http://" + net.JoinHostPort(podIP, strings.TrimPrefix(port, ":"))
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/kube"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// FakeElector is a LeaderElector with a fixed status
+type FakeElector struct {
+	status  kube.LeaderStatus
+	started bool
+	stopped bool
+}
+
+func (f *FakeElector) Start() {
+	f.started = true
+}
+
+func (f *FakeElector) Stop(ctx context.Context) error {
+	f.stopped = true
+	return nil
+}
+
+func (f *FakeElector) IsLeader() bool {
+	return f.status.IsLeader
+}
+
+func (f *FakeElector) Status() kube.LeaderStatus {
+	return f.status
+}
+
+func newLeaderTestDeps(mode string, elector kube.LeaderElector, sent *int32) *HandlerDependencies {
+	return &HandlerDependencies{
+		Config: &config.Config{
+			PushoverAPIToken:   "test_token",
+			BearerToken:        "Bearer test_token",
+			LeaderElectionMode: mode,
+		},
+		PushoverClient: &MockPushoverClient{
+			SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+				atomic.AddInt32(sent, 1)
+				return nil
+			},
+		},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		Elector:        elector,
+	}
+}
+
+func newWebhookRequest() *http.Request {
+	req := httptest.NewRequest("POST", "/webhook", strings.NewReader(`{"message":"hello"}`))
+	req.Header.Set("Authorization", "Bearer test_token")
+	return req
+}
+
+func TestCreateWebhookHandler_Leader(t *testing.T) {
+	var sent int32
+	deps := newLeaderTestDeps(config.LeaderElectionModeStandby, &FakeElector{status: kube.LeaderStatus{Enabled: true, IsLeader: true}}, &sent)
+
+	rr := httptest.NewRecorder()
+	CreateWebhookHandler(deps).ServeHTTP(rr, newWebhookRequest())
+
+	if rr.Code != http.StatusOK || rr.Body.String() != string(types.ResponseOK) {
+		t.Errorf("Expected OK, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	if sent != 1 {
+		t.Errorf("Expected leader to send, got %d sends", sent)
+	}
+}
+
+func TestCreateWebhookHandler_Standby(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		status  kube.LeaderStatus
+		proxied bool
+	}{
+		{"standby mode", config.LeaderElectionModeStandby, kube.LeaderStatus{Enabled: true, Leader: "pod-a", LeaderAddress: "http://10.0.0.5:8080"}, false},
+		{"proxy mode without leader address", config.LeaderElectionModeProxy, kube.LeaderStatus{Enabled: true, Leader: "pod-a"}, false},
+		{"proxy mode with proxied request", config.LeaderElectionModeProxy, kube.LeaderStatus{Enabled: true, Leader: "pod-a", LeaderAddress: "http://10.0.0.5:8080"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent int32
+			deps := newLeaderTestDeps(tt.mode, &FakeElector{status: tt.status}, &sent)
+
+			req := newWebhookRequest()
+			if tt.proxied {
+				req.Header.Set(types.ProxiedHeader, "pod-b")
+			}
+
+			rr := httptest.NewRecorder()
+			CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK || rr.Body.String() != `{"status":"standby"}` {
+				t.Errorf("Expected standby response, got %d %s", rr.Code, rr.Body.String())
+			}
+
+			if sent != 0 {
+				t.Errorf("Expected standby replica not to send, got %d sends", sent)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_StandbyRequiresAuth(t *testing.T) {
+	var sent int32
+	deps := newLeaderTestDeps(config.LeaderElectionModeStandby, &FakeElector{status: kube.LeaderStatus{Enabled: true}}, &sent)
+
+	req := newWebhookRequest()
+	req.Header.Set("Authorization", "Bearer wrong")
+
+	rr := httptest.NewRecorder()
+	CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401, got %d", rr.Code)
+	}
+}
+
+func TestCreateWebhookHandler_ProxyToLeader(t *testing.T) {
+	// The leader replica
+	var leaderSent int32
+	leaderDeps := newLeaderTestDeps(config.LeaderElectionModeProxy, &FakeElector{status: kube.LeaderStatus{Enabled: true, IsLeader: true}}, &leaderSent)
+
+	var proxiedBy string
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedBy = r.Header.Get(types.ProxiedHeader)
+		CreateWebhookHandler(leaderDeps).ServeHTTP(w, r)
+	}))
+	defer leader.Close()
+
+	var standbySent int32
+	deps := newLeaderTestDeps(config.LeaderElectionModeProxy, &FakeElector{status: kube.LeaderStatus{
+		Enabled:       true,
+		Identity:      "pod-b",
+		Leader:        "pod-a",
+		LeaderAddress: leader.URL,
+	}}, &standbySent)
+
+	rr := httptest.NewRecorder()
+	CreateWebhookHandler(deps).ServeHTTP(rr, newWebhookRequest())
+
+	if rr.Code != http.StatusOK || rr.Body.String() != string(types.ResponseOK) {
+		t.Errorf("Expected leader's response, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	if leaderSent != 1 || standbySent != 0 {
+		t.Errorf("Expected only the leader to send, got leader=%d standby=%d", leaderSent, standbySent)
+	}
+
+	if proxiedBy != "pod-b" {
+		t.Errorf("Expected proxied header from pod-b, got %q", proxiedBy)
+	}
+}
+
+func TestCreateWebhookHandler_ProxyFailure(t *testing.T) {
+	leader := httptest.NewServer(http.NotFoundHandler())
+	leader.Close()
+
+	var sent int32
+	deps := newLeaderTestDeps(config.LeaderElectionModeProxy, &FakeElector{status: kube.LeaderStatus{
+		Enabled:       true,
+		Leader:        "pod-a",
+		LeaderAddress: leader.URL,
+	}}, &sent)
+
+	rr := httptest.NewRecorder()
+	CreateWebhookHandler(deps).ServeHTTP(rr, newWebhookRequest())
+
+	if rr.Code != http.StatusBadGateway || rr.Body.String() != string(types.ResponseProxyError) {
+		t.Errorf("Expected 502, got %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestCreateStatusHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		elector  kube.LeaderElector
+		expected kube.LeaderStatus
+	}{
+		{"no elector", nil, kube.LeaderStatus{IsLeader: true}},
+		{
+			"standby",
+			&FakeElector{status: kube.LeaderStatus{Enabled: true, Identity: "pod-b", Leader: "pod-a", Lease: "flux-provider-pushover"}},
+			kube.LeaderStatus{Enabled: true, Identity: "pod-b", Leader: "pod-a", Lease: "flux-provider-pushover"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := &HandlerDependencies{Config: &config.Config{}, Logger: &MockLogger{}, Elector: tt.elector}
+
+			rr := httptest.NewRecorder()
+			CreateStatusHandler(deps).ServeHTTP(rr, httptest.NewRequest("GET", "/status", nil))
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d", rr.Code)
+			}
+
+			var response statusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode status: %v", err)
+			}
+
+			if response.LeaderElection != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, response.LeaderElection)
+			}
+		})
+	}
+}
+
+func TestHandlerDependencies_StartDrain(t *testing.T) {
+	elector := &FakeElector{}
+	deps := &HandlerDependencies{Elector: elector}
+
+	deps.Start()
+	if err := deps.Drain(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !elector.started || !elector.stopped {
+		t.Errorf("Expected elector to be started and stopped, got %+v", elector)
+	}
+}
+
+func TestPodAddress(t *testing.T) {
+	tests := []struct {
+		podIP    string
+		port     string
+		expected string
+	}{
+		{"", ":8080", ""},
+		{"10.0.0.5", ":8080", "http://10.0.0.5:8080"},
+		{"fd00::5", ":9000", "http://[fd00::5]:9000"},
+	}
+
+	for _, tt := range tests {
+		if got := podAddress(tt.podIP, tt.port); got != tt.expected {
+			t.Errorf("podAddress(%q, %q) = %q, want %q", tt.podIP, tt.port, got, tt.expected)
+		}
+	}
+}
@@ -1,28 +1,274 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"runtime/debug"
+	"slices"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/notify"
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
 	"github.com/zhorvath83/flux-provider-pushover/internal/types"
 )
 
-// MessageBuilder is a functional type for building messages
+// MessageBuilder is a functional type for building messages. Builders
+// capture their config-driven options, see NewMessageBuilder.
 type MessageBuilder func(*types.FluxAlert) string
 
-// BuildPushoverMessage creates a formatted message from FluxAlert (pure function)
+// MessageOptions controls config-driven message formatting
+type MessageOptions struct {
+	PreserveKindCase bool   // Keep the original involvedObject.kind casing
+	ClusterName      string // Appended as a footer line when set
+	Prefix           string // Starts the body when set, e.g. "[PROD]"
+	RevisionFormat   string // REVISION_FORMAT, empty keeps revisions untouched
+	ShortenRevision  bool   // SHORTEN_REVISION, overrides RevisionFormat
+	ShowUID          bool   // Add an involvedObject.uid line for correlation
+	MaxLines         int    // MAX_MESSAGE_LINES, 0 renders every metadata line
+}
+
+// MessageOptionsFromConfig extracts message options from config (pure function)
+func MessageOptionsFromConfig(cfg *config.Config) MessageOptions {
+	return MessageOptions{
+		PreserveKindCase: cfg.PreserveKindCase,
+		ClusterName:      cfg.ClusterName,
+		Prefix:           cfg.MessagePrefix,
+		RevisionFormat:   cfg.RevisionFormat,
+		ShortenRevision:  cfg.ShortenRevision,
+		ShowUID:          cfg.ShowUID,
+		MaxLines:         cfg.MaxMessageLines,
+	}
+}
+
+// revisionFormat is the format revisions are rendered in, SHORTEN_REVISION
+// being an alias for the short SHA format
+func (opts MessageOptions) revisionFormat() string {
+	if opts.ShortenRevision {
+		return revisionFormatSHA
+	}
+	return opts.RevisionFormat
+}
+
+// NewMessageBuilder creates a MessageBuilder using the given options
+func NewMessageBuilder(opts MessageOptions) MessageBuilder {
+	return func(alert *types.FluxAlert) string {
+		return buildMessage(alert, opts)
+	}
+}
+
+// errMessagePanic is returned by buildMessageSafely for a panicking message builder
+var errMessagePanic = errors.New("message builder panicked")
+
+// buildMessageSafely runs the message builder, recovering from its panics,
+// e.g. of a template bug. The alert then gets a minimalMessage with
+// MESSAGE_FALLBACK, else errMessagePanic is returned.
+func buildMessageSafely(deps *HandlerDependencies, alert *types.FluxAlert) (message string, err error) {
+	defer func() {
+		rec := recover()
+		if rec == nil {
+			return
+		}
+		deps.Logger.Printf("Message builder panicked for %s/%s/%s: %v\n%s", alertKind(alert), alert.InvolvedObject.Namespace, alertName(alert), rec, debug.Stack())
+		deps.Metrics.Counter("message_build_panics_total", "Messages whose builder panicked").Inc()
+		if deps.Config.MessageFallback {
+			message, err = minimalMessage(alert), nil
+			return
+		}
+		message, err = "", fmt.Errorf("%w: %v", errMessagePanic, rec)
+	}()
+	return deps.MessageBuilder(alert), nil
+}
+
+// minimalMessage formats an alert without options or templates (pure function)
+func minimalMessage(alert *types.FluxAlert) string {
+	body := alertKind(alert) + "/" + alertName(alert) + ": " + defaultIfEmpty(alert.Message, defaultIfEmpty(alert.Reason, types.DefaultValue))
+	return truncateMessage(body, "", types.MaxMessageLength)
+}
+
+// BuildPushoverMessage creates a formatted message from FluxAlert with default options (pure function)
 func BuildPushoverMessage(alert *types.FluxAlert) string {
-	severity := normalizeString(alert.Severity, types.DefaultSeverity, strings.ToUpper)
-	reason := defaultIfEmpty(alert.Reason, types.DefaultValue)
-	controller := defaultIfEmpty(alert.ReportingController, types.DefaultValue)
-	revision := defaultIfEmpty(alert.Metadata.Revision, types.DefaultValue)
-	kind := normalizeString(alert.InvolvedObject.Kind, types.DefaultValue, strings.ToLower)
-	objectName := defaultIfEmpty(alert.InvolvedObject.Name, types.DefaultValue)
-	message := defaultIfEmpty(alert.Message, types.NoMessage)
+	return buildMessage(alert, MessageOptions{})
+}
+
+// Message buffers start large enough for a typical alert, buffers grown
+// past the limit by huge alerts are not kept
+const (
+	messageBufferSize     = 512
+	maxPooledMessageBytes = 16 << 10
+)
+
+// messageBufferPool reuses the buffers messages are formatted into, so that
+// building a message allocates only the resulting string
+var messageBufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, messageBufferSize)
+		return &buf
+	},
+}
+
+// buildMessage creates a formatted message from FluxAlert (pure function)
+func buildMessage(alert *types.FluxAlert, opts MessageOptions) string {
+	bufp := messageBufferPool.Get().(*[]byte)
+	buf := (*bufp)[:0]
+
+	if opts.Prefix != "" {
+		buf = append(buf, opts.Prefix...)
+		buf = append(buf, ' ')
+	}
+	buf = append(buf, defaultIfEmpty(alert.Reason, types.DefaultValue)...)
+	buf = append(buf, " ["...)
+	severity, _ := NormalizeSeverity(alert.Severity)
+	buf = appendUpper(buf, severity)
+	buf = append(buf, "]\n"...)
+	buf = append(buf, defaultIfEmpty(alert.Message, types.NoMessage)...)
+	buf = append(buf, "\n\n"...)
+
+	// The metadata lines below the message are cut to MAX_MESSAGE_LINES
+	lines := 0
+	fits := func() bool {
+		lines++
+		return opts.MaxLines == 0 || lines <= opts.MaxLines
+	}
+	if fits() {
+		buf = append(buf, "Controller: "...)
+		buf = append(buf, defaultIfEmpty(alert.ReportingController, types.DefaultValue)...)
+		buf = append(buf, '\n')
+	}
+	if fits() {
+		buf = append(buf, "Object: "...)
+		if kind := defaultIfEmpty(alert.InvolvedObject.Kind, types.DefaultValue); opts.PreserveKindCase {
+			buf = append(buf, kind...)
+		} else {
+			buf = appendLower(buf, kind)
+		}
+		buf = append(buf, '/')
+		buf = append(buf, defaultIfEmpty(alert.InvolvedObject.Name, types.DefaultValue)...)
+		buf = append(buf, '\n')
+	}
+	if fits() {
+		buf = append(buf, "Revision: "...)
+		buf = append(buf, displayRevision(alert, opts)...)
+		buf = append(buf, '\n')
+	}
+	// Commit details only exist for git-backed sources, absent ones are omitted
+	if summary := alert.Metadata[types.MetadataSummary]; summary != "" && fits() {
+		buf = append(buf, "Summary: "...)
+		buf = append(buf, summary...)
+		buf = append(buf, '\n')
+	}
+	if commitStatus := alert.Metadata[types.MetadataCommitStatus]; commitStatus != "" && fits() {
+		buf = append(buf, "Commit status: "...)
+		buf = append(buf, commitStatus...)
+		buf = append(buf, '\n')
+	}
+	if uid := alert.InvolvedObject.UID; opts.ShowUID && uid != "" && fits() {
+		buf = append(buf, "UID: "...)
+		buf = append(buf, uid...)
+		buf = append(buf, '\n')
+	}
+	if opts.MaxLines > 0 && lines > opts.MaxLines {
+		buf = append(buf, types.LinesDroppedMarker...)
+		buf = append(buf, '\n')
+	}
+
+	footer := clusterFooter(opts, alert)
+
+	var message string
+	if utf8.RuneCount(buf)+utf8.RuneCountInString(footer) <= types.MaxMessageLength {
+		buf = append(buf, footer...)
+		message = string(buf)
+	} else {
+		message = truncateMessage(string(buf), footer, types.MaxMessageLength)
+	}
+
+	if cap(buf) <= maxPooledMessageBytes {
+		*bufp = buf[:0]
+		messageBufferPool.Put(bufp)
+	}
+	return message
+}
+
+// clusterFooter returns the footer line identifying the cluster of alert,
+// CLUSTER_NAME unless its webhook named another, if any (pure function)
+func clusterFooter(opts MessageOptions, alert *types.FluxAlert) string {
+	cluster := defaultIfEmpty(alert.Cluster, opts.ClusterName)
+	if cluster == "" {
+		return ""
+	}
+	return types.ClusterFooterPrefix + cluster
+}
+
+// appendUpper appends s in upper case, without allocating for ASCII (pure function)
+func appendUpper(buf []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			return append(buf[:len(buf)-i], strings.ToUpper(s)...)
+		}
+		if 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
+
+// appendLower appends s in lower case, without allocating for ASCII (pure function)
+func appendLower(buf []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			return append(buf[:len(buf)-i], strings.ToLower(s)...)
+		}
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
+
+// truncateMessage fits body and footer within limit characters, shortening
+// the body so that the footer is always kept (pure function)
+func truncateMessage(body, footer string, limit int) string {
+	bodyLen, footerLen := utf8.RuneCountInString(body), utf8.RuneCountInString(footer)
+	if bodyLen+footerLen <= limit {
+		return body + footer
+	}
+
+	if footerLen >= limit {
+		return string([]rune(footer)[:limit])
+	}
+
+	suffix := types.TruncationMarker
+	if footer != "" {
+		suffix += "\n" + footer
+	}
+
+	keep := max(0, limit-utf8.RuneCountInString(suffix))
+	return string([]rune(body)[:keep]) + suffix
+}
 
-	return fmt.Sprintf("%s [%s]\n%s\n\nController: %s\nObject: %s/%s\nRevision: %s\n",
-		reason, severity, message, controller, kind, objectName, revision)
+// NormalizeSeverity maps a Flux severity to info, warning or error, case
+// insensitively and with "warn" as an alias of warning. Missing and unknown
+// severities map to info, known is false for unknown ones (pure function).
+func NormalizeSeverity(severity string) (normalized string, known bool) {
+	switch {
+	case severity == "":
+		return types.SeverityInfo, true
+	case strings.EqualFold(severity, types.SeverityInfo):
+		return types.SeverityInfo, true
+	case strings.EqualFold(severity, types.SeverityWarning), strings.EqualFold(severity, "warn"):
+		return types.SeverityWarning, true
+	case strings.EqualFold(severity, types.SeverityError):
+		return types.SeverityError, true
+	default:
+		return types.SeverityInfo, false
+	}
 }
 
 // defaultIfEmpty returns default value if string is empty (pure function)
@@ -41,35 +287,237 @@ func normalizeString(value, defaultValue string, transform func(string) string)
 	return transform(value)
 }
 
-// CreatePushoverMessage creates a PushoverMessage struct (pure function)
-func CreatePushoverMessage(cfg *config.Config, message string) *types.PushoverMessage {
+// CreatePushoverMessage creates a PushoverMessage struct sent with the
+// credentials and title of severity (pure function)
+func CreatePushoverMessage(cfg *config.Config, message, severity string) *types.PushoverMessage {
+	severity, _ = NormalizeSeverity(severity)
+	credentials := cfg.Credentials(severity)
 	return &types.PushoverMessage{
-		Token:   cfg.PushoverAPIToken,
-		User:    cfg.PushoverUserKey,
-		Title:   types.AppTitle,
+		Token:   credentials.Token,
+		User:    credentials.User,
+		Title:   truncateTitle(cfg.Title(severity), cfg.MaxTitleLength),
 		Message: message,
 	}
 }
 
+// alertToken returns the Pushover application token alert is sent with (pure function)
+func alertToken(cfg *config.Config, alert *types.FluxAlert) string {
+	severity, _ := NormalizeSeverity(alert.Severity)
+	return cfg.Credentials(severity).Token
+}
+
+// OverflowAttachment returns the full event message when body was truncated
+// and left part of it out, cut to maxBytes on a character boundary, else nil
+// (pure function)
+func OverflowAttachment(body, full string, maxBytes int) []byte {
+	if full == "" || !strings.Contains(body, types.TruncationMarker) || strings.Contains(body, full) {
+		return nil
+	}
+	if len(full) <= maxBytes {
+		return []byte(full)
+	}
+
+	marker := types.TruncationMarker
+	if maxBytes < len(marker) {
+		marker = ""
+	}
+	keep := maxBytes - len(marker)
+	for keep > 0 && !utf8.RuneStart(full[keep]) {
+		keep--
+	}
+	return []byte(full[:keep] + marker)
+}
+
+// SelectSound picks the Pushover sound of an alert: by its reason, else by
+// its severity, else empty for the user's default sound (pure function)
+func SelectSound(cfg *config.Config, reason, severity string) string {
+	if sound, ok := cfg.ReasonSounds[strings.ToLower(reason)]; ok && reason != "" {
+		return sound
+	}
+	severity, _ = NormalizeSeverity(severity)
+	return cfg.SeveritySounds[severity]
+}
+
+// truncateTitle shortens title to limit characters with an ellipsis, a limit
+// of 0 is the Pushover limit (pure function)
+func truncateTitle(title string, limit int) string {
+	if limit <= 0 || limit > types.MaxTitleLength {
+		limit = types.MaxTitleLength
+	}
+	return truncateMessage(title, "", limit)
+}
+
+// CreateNotification creates a provider-neutral notification (pure function)
+func CreateNotification(alert *types.FluxAlert, message string) *notify.Notification {
+	severity, _ := NormalizeSeverity(alert.Severity)
+	return &notify.Notification{
+		Title:    types.AppTitle,
+		Body:     message,
+		Severity: severity,
+		Event:    alert,
+	}
+}
+
 // ValidateAlert validates a FluxAlert (pure function)
 func ValidateAlert(alert *types.FluxAlert) error {
+	return validateAlert(alert, alertRules{})
+}
+
+// ValidateStrictAlert validates a FluxAlert like ValidateAlert and also
+// requires the severity and involvedObject.kind, for STRICT_ALERTS (pure function)
+func ValidateStrictAlert(alert *types.FluxAlert) error {
+	return validateAlert(alert, alertRules{strict: true})
+}
+
+// alertValidator returns the validation STRICT_ALERTS and EXTRA_SEVERITIES
+// select. VALIDATION_MODE=strict also rejects unknown severities, alerts
+// without a message or reason and fields over their length caps. The default
+// lenient mode logs those and delivers the alert anyway, an unknown severity
+// as info.
+func alertValidator(cfg *config.Config, logger server.Logger) func(*types.FluxAlert) error {
+	rules := alertRules{strict: cfg.StrictAlerts, extraSeverities: cfg.ExtraSeverities}
+	if cfg.ValidationMode == config.ValidationModeStrict {
+		rules.limits = true
+		return func(alert *types.FluxAlert) error { return validateAlert(alert, rules) }
+	}
+
+	rules.unknownSeverities = true
+	return func(alert *types.FluxAlert) error {
+		if err := validateAlert(alert, rules); err != nil {
+			return err
+		}
+		if !knownSeverity(alert.Severity, rules.extraSeverities) {
+			logger.Printf("Delivering %s/%s with unknown severity %q as %s", alertKind(alert), alertName(alert), alert.Severity, types.SeverityInfo)
+		}
+		if fields := limitViolations(alert); len(fields) > 0 {
+			logger.Printf("Delivering %s/%s despite VALIDATION_MODE=lenient: %v", alertKind(alert), alertName(alert), &ValidationError{Fields: fields})
+		}
+		return nil
+	}
+}
+
+// alertRules are the checks of validateAlert
+type alertRules struct {
+	strict            bool     // Also require fields that are optional for Flux
+	limits            bool     // Also apply limitViolations, for VALIDATION_MODE=strict
+	unknownSeverities bool     // Accept unknown severities, which deliver as info
+	extraSeverities   []string // Accepted besides info, warning and error, lower case
+}
+
+// Maximum lengths of alert fields in bytes, far above what Flux sends
+const (
+	maxMessageFieldBytes  = 32 << 10
+	maxMetadataValueBytes = 4 << 10
+)
+
+// validateAlert reports every invalid field of alert, strict also reports
+// missing ones that are optional for Flux (pure function)
+func validateAlert(alert *types.FluxAlert, rules alertRules) error {
 	if alert == nil {
 		return fmt.Errorf("alert is nil")
 	}
-	// Add more validation if needed
+
+	var fields []FieldError
+	if rules.strict && alert.Severity == "" {
+		fields = append(fields, FieldError{Field: "severity", Reason: "is required"})
+	} else if !rules.unknownSeverities && !knownSeverity(alert.Severity, rules.extraSeverities) {
+		reason := fmt.Sprintf("must be %q, %q or %q", types.SeverityInfo, types.SeverityWarning, types.SeverityError)
+		if len(rules.extraSeverities) > 0 {
+			reason = fmt.Sprintf("must be %q, %q, %q or one of EXTRA_SEVERITIES", types.SeverityInfo, types.SeverityWarning, types.SeverityError)
+		}
+		fields = append(fields, FieldError{Field: "severity", Reason: reason})
+	}
+	if rules.strict && strings.TrimSpace(alert.InvolvedObject.Kind) == "" {
+		fields = append(fields, FieldError{Field: "involvedObject.kind", Reason: "is required"})
+	}
+	if alert.Timestamp != "" {
+		if _, err := time.Parse(time.RFC3339, alert.Timestamp); err != nil {
+			fields = append(fields, FieldError{Field: "timestamp", Reason: "must be an RFC 3339 time"})
+		}
+	}
+	if rules.limits {
+		fields = append(fields, limitViolations(alert)...)
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
 	return nil
 }
 
-// ExtractAlertInfo extracts key information from alert (pure function)
+// knownSeverity tells whether NormalizeSeverity knows severity or it is one of
+// extras, which are lower case (pure function)
+func knownSeverity(severity string, extras []string) bool {
+	_, known := NormalizeSeverity(severity)
+	return known || slices.Contains(extras, strings.ToLower(severity))
+}
+
+// limitViolations reports an alert with neither message nor reason and every
+// field over its length cap (pure function)
+func limitViolations(alert *types.FluxAlert) []FieldError {
+	var fields []FieldError
+	if strings.TrimSpace(alert.Message) == "" && strings.TrimSpace(alert.Reason) == "" {
+		fields = append(fields, FieldError{Field: "message", Reason: "is required when reason is empty"})
+	}
+
+	// Kubernetes limits the names, the rest are bounded generously
+	for _, field := range []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"involvedObject.kind", alert.InvolvedObject.Kind, 63},
+		{"involvedObject.namespace", alert.InvolvedObject.Namespace, 63},
+		{"involvedObject.name", alert.InvolvedObject.Name, 253},
+		{"reason", alert.Reason, 256},
+		{"reportingController", alert.ReportingController, 253},
+		{"message", alert.Message, maxMessageFieldBytes},
+	} {
+		if len(field.value) > field.max {
+			fields = append(fields, FieldError{Field: field.name, Reason: fmt.Sprintf("must be at most %d bytes", field.max)})
+		}
+	}
+	keys := make([]string, 0, len(alert.Metadata))
+	for key := range alert.Metadata {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if len(alert.Metadata[key]) > maxMetadataValueBytes {
+			fields = append(fields, FieldError{Field: "metadata." + key, Reason: fmt.Sprintf("must be at most %d bytes", maxMetadataValueBytes)})
+		}
+	}
+	return fields
+}
+
+// alertKind returns the involved object's kind for logging (pure function)
+func alertKind(alert *types.FluxAlert) string {
+	return defaultIfEmpty(alert.InvolvedObject.Kind, types.DefaultValue)
+}
+
+// alertName returns the involved object's name for logging (pure function)
+func alertName(alert *types.FluxAlert) string {
+	return defaultIfEmpty(alert.InvolvedObject.Name, types.DefaultValue)
+}
+
+// ExtractAlertInfo extracts key information from alert, including the
+// summary and commit status only when present (pure function)
 func ExtractAlertInfo(alert *types.FluxAlert) map[string]string {
-	return map[string]string{
+	info := map[string]string{
 		"severity":   defaultIfEmpty(alert.Severity, types.DefaultSeverity),
 		"reason":     defaultIfEmpty(alert.Reason, types.DefaultValue),
 		"controller": defaultIfEmpty(alert.ReportingController, types.DefaultValue),
-		"revision":   defaultIfEmpty(alert.Metadata.Revision, types.DefaultValue),
+		"revision":   defaultIfEmpty(alert.Metadata[types.MetadataRevision], types.DefaultValue),
 		"kind":       defaultIfEmpty(alert.InvolvedObject.Kind, types.DefaultValue),
 		"name":       defaultIfEmpty(alert.InvolvedObject.Name, types.DefaultValue),
 		"namespace":  defaultIfEmpty(alert.InvolvedObject.Namespace, "default"),
 		"message":    defaultIfEmpty(alert.Message, types.NoMessage),
 	}
+	if summary := alert.Metadata[types.MetadataSummary]; summary != "" {
+		info["summary"] = summary
+	}
+	if commitStatus := alert.Metadata[types.MetadataCommitStatus]; commitStatus != "" {
+		info["commit_status"] = commitStatus
+	}
+	return info
 }
@@ -1,10 +1,18 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
 	"github.com/zhorvath83/flux-provider-pushover/internal/types"
 )
 
@@ -32,15 +40,33 @@ func TestBuildPushoverMessage(t *testing.T) {
 					Kind: "Deployment",
 					Name: "test-deployment",
 				},
-				Metadata: struct {
-					CommitStatus string `json:"commit_status"`
-					Revision     string `json:"revision"`
-					Summary      string `json:"summary"`
-				}{
-					Revision: "abc123",
+				Metadata: map[string]string{
+					"revision":      "abc123",
+					"summary":       "Bump podinfo to 6.5.0",
+					"commit_status": "update",
+				},
+			},
+			expected: "TestReason [ERROR]\nTest message\n\nController: test-controller\nObject: deployment/test-deployment\nRevision: abc123\nSummary: Bump podinfo to 6.5.0\nCommit status: update\n",
+		},
+		{
+			name: "summary only",
+			alert: &types.FluxAlert{
+				Message: "Applied",
+				Metadata: map[string]string{
+					"summary": "Bump podinfo to 6.5.0",
+				},
+			},
+			expected: "Unknown [INFO]\nApplied\n\nController: Unknown\nObject: unknown/Unknown\nRevision: Unknown\nSummary: Bump podinfo to 6.5.0\n",
+		},
+		{
+			name: "commit status only",
+			alert: &types.FluxAlert{
+				Message: "Applied",
+				Metadata: map[string]string{
+					"commit_status": "update",
 				},
 			},
-			expected: "TestReason [ERROR]\nTest message\n\nController: test-controller\nObject: deployment/test-deployment\nRevision: abc123\n",
+			expected: "Unknown [INFO]\nApplied\n\nController: Unknown\nObject: unknown/Unknown\nRevision: Unknown\nCommit status: update\n",
 		},
 		{
 			name:     "empty alert",
@@ -67,6 +93,362 @@ func TestBuildPushoverMessage(t *testing.T) {
 	}
 }
 
+func TestNewMessageBuilder_KindCase(t *testing.T) {
+	alert := &types.FluxAlert{Severity: "info", Reason: "ReconciliationSucceeded", Message: "ok"}
+	alert.InvolvedObject.Kind = "HelmRelease"
+	alert.InvolvedObject.Name = "podinfo"
+
+	tests := []struct {
+		name           string
+		opts           MessageOptions
+		expectedObject string
+	}{
+		{"default lowercases kind", MessageOptions{}, "Object: helmrelease/podinfo\n"},
+		{"preserve kind case", MessageOptions{PreserveKindCase: true}, "Object: HelmRelease/podinfo\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NewMessageBuilder(tt.opts)(alert)
+			if !strings.Contains(result, tt.expectedObject) {
+				t.Errorf("Expected message to contain %q, got:\n%s", tt.expectedObject, result)
+			}
+		})
+	}
+
+	// Missing kind keeps the default placeholder casing
+	empty := NewMessageBuilder(MessageOptions{PreserveKindCase: true})(&types.FluxAlert{})
+	if !strings.Contains(empty, "Object: Unknown/Unknown\n") {
+		t.Errorf("Expected default kind placeholder, got:\n%s", empty)
+	}
+}
+
+func TestMessageOptionsFromConfig(t *testing.T) {
+	opts := MessageOptionsFromConfig(&config.Config{
+		PreserveKindCase: true,
+		ClusterName:      "prod-eu",
+		MessagePrefix:    "[PROD]",
+		RevisionFormat:   config.RevisionFormatShort,
+		ShortenRevision:  true,
+		ShowUID:          true,
+		MaxMessageLines:  4,
+	})
+	if !opts.PreserveKindCase {
+		t.Error("Expected PreserveKindCase to be taken from config")
+	}
+
+	if opts.ClusterName != "prod-eu" {
+		t.Errorf("Expected ClusterName prod-eu, got %q", opts.ClusterName)
+	}
+
+	if opts.Prefix != "[PROD]" {
+		t.Errorf("Expected Prefix [PROD], got %q", opts.Prefix)
+	}
+
+	if opts.RevisionFormat != config.RevisionFormatShort {
+		t.Errorf("Expected RevisionFormat short, got %q", opts.RevisionFormat)
+	}
+
+	if !opts.ShortenRevision {
+		t.Error("Expected ShortenRevision to be taken from config")
+	}
+
+	if !opts.ShowUID {
+		t.Error("Expected ShowUID to be taken from config")
+	}
+
+	if opts.MaxLines != 4 {
+		t.Errorf("Expected MaxLines 4, got %d", opts.MaxLines)
+	}
+}
+
+func TestNewMessageBuilder_ShowUID(t *testing.T) {
+	withUID := &types.FluxAlert{Severity: "info", Reason: "ReconciliationSucceeded", Message: "ok"}
+	withUID.InvolvedObject.UID = "3c2e5f1a-8b7d-4e6f-9a0b-1c2d3e4f5a6b"
+	withoutUID := &types.FluxAlert{Severity: "info", Reason: "ReconciliationSucceeded", Message: "ok"}
+
+	tests := []struct {
+		name     string
+		alert    *types.FluxAlert
+		showUID  bool
+		expected string
+	}{
+		{"present", withUID, true, "Revision: Unknown\nUID: 3c2e5f1a-8b7d-4e6f-9a0b-1c2d3e4f5a6b\n"},
+		{"absent", withoutUID, true, ""},
+		{"flag off", withUID, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := NewMessageBuilder(MessageOptions{ShowUID: tt.showUID})(tt.alert)
+			if tt.expected == "" {
+				if strings.Contains(message, "UID:") {
+					t.Errorf("Expected no UID line, got:\n%s", message)
+				}
+				return
+			}
+			if !strings.HasSuffix(message, tt.expected) {
+				t.Errorf("Expected message to end with %q, got:\n%s", tt.expected, message)
+			}
+		})
+	}
+}
+
+func TestNewMessageBuilder_MaxLines(t *testing.T) {
+	alert := &types.FluxAlert{Severity: "error", Reason: "HealthCheckFailed", Message: "timeout", ReportingController: "kustomize-controller"}
+	alert.InvolvedObject.Kind = "Kustomization"
+	alert.InvolvedObject.Name = "apps"
+	alert.InvolvedObject.UID = "3c2e5f1a"
+	alert.Metadata = map[string]string{"revision": "main@sha1:abc", "summary": "Deploy", "commit_status": "update"}
+
+	tests := []struct {
+		name     string
+		maxLines int
+		expected string
+	}{
+		{"unlimited", 0, "HealthCheckFailed [ERROR]\ntimeout\n\nController: kustomize-controller\nObject: kustomization/apps\nRevision: main@sha1:abc\nSummary: Deploy\nCommit status: update\nUID: 3c2e5f1a\n"},
+		{"all fit", 6, "HealthCheckFailed [ERROR]\ntimeout\n\nController: kustomize-controller\nObject: kustomization/apps\nRevision: main@sha1:abc\nSummary: Deploy\nCommit status: update\nUID: 3c2e5f1a\n"},
+		{"optional lines dropped", 3, "HealthCheckFailed [ERROR]\ntimeout\n\nController: kustomize-controller\nObject: kustomization/apps\nRevision: main@sha1:abc\n… (truncated)\n"},
+		{"message always kept", 1, "HealthCheckFailed [ERROR]\ntimeout\n\nController: kustomize-controller\n… (truncated)\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := NewMessageBuilder(MessageOptions{ShowUID: true, MaxLines: tt.maxLines})(alert)
+			if message != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, message)
+			}
+		})
+	}
+}
+
+func TestNewMessageBuilder_RevisionFormat(t *testing.T) {
+	alert := &types.FluxAlert{Severity: "info", Reason: "ReconciliationSucceeded", Message: "ok"}
+	alert.Metadata = map[string]string{"revision": "refs/heads/main@sha1:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b"}
+
+	message := NewMessageBuilder(MessageOptions{RevisionFormat: config.RevisionFormatBranchShort})(alert)
+	if !strings.Contains(message, "Revision: main @ 9f86d081\n") {
+		t.Errorf("Expected shortened revision, got:\n%s", message)
+	}
+}
+
+func TestNewMessageBuilder_ShortenRevision(t *testing.T) {
+	alert := &types.FluxAlert{Severity: "info", Reason: "ReconciliationSucceeded", Message: "ok"}
+	alert.Metadata = map[string]string{"revision": "main@sha1:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b"}
+
+	message := NewMessageBuilder(MessageOptions{ShortenRevision: true, RevisionFormat: config.RevisionFormatBranchShort})(alert)
+	if !strings.Contains(message, "Revision: 9f86d08\n") {
+		t.Errorf("Expected the short SHA, got:\n%s", message)
+	}
+
+	alert.Metadata = map[string]string{"revision": "20240101"}
+	if message := NewMessageBuilder(MessageOptions{ShortenRevision: true})(alert); !strings.Contains(message, "Revision: 20240101\n") {
+		t.Errorf("Expected a non-SHA revision left unchanged, got:\n%s", message)
+	}
+
+	alert.Metadata = nil
+	if message := NewMessageBuilder(MessageOptions{ShortenRevision: true})(alert); !strings.Contains(message, "Revision: "+types.DefaultValue+"\n") {
+		t.Errorf("Expected a missing revision left unchanged, got:\n%s", message)
+	}
+}
+
+func TestNewMessageBuilder_Prefix(t *testing.T) {
+	alert := &types.FluxAlert{Severity: "error", Reason: "HealthCheckFailed", Message: "timeout"}
+
+	tests := []struct {
+		name     string
+		prefix   string
+		expected string
+	}{
+		{"without prefix", "", "HealthCheckFailed [ERROR]\ntimeout\n"},
+		{"with prefix", "[PROD]", "[PROD] HealthCheckFailed [ERROR]\ntimeout\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := NewMessageBuilder(MessageOptions{Prefix: tt.prefix})(alert)
+			if !strings.HasPrefix(message, tt.expected) {
+				t.Errorf("Expected message to start with %q, got:\n%s", tt.expected, message)
+			}
+		})
+	}
+
+	// The prefix is kept and counted when the body is shortened
+	long := &types.FluxAlert{Severity: "error", Message: strings.Repeat("x", 2000)}
+	message := NewMessageBuilder(MessageOptions{Prefix: "[PROD]", ClusterName: "prod-eu"})(long)
+	if n := utf8.RuneCountInString(message); n != types.MaxMessageLength {
+		t.Errorf("Expected %d characters, got %d", types.MaxMessageLength, n)
+	}
+	if !strings.HasPrefix(message, "[PROD] Unknown [ERROR]") || !strings.HasSuffix(message, "…\n— cluster: prod-eu") {
+		t.Errorf("Expected prefix and footer to survive truncation, got %q…%q", message[:30], message[len(message)-30:])
+	}
+}
+
+func TestNewMessageBuilder_ClusterFooter(t *testing.T) {
+	alert := &types.FluxAlert{Severity: "info", Reason: "ReconciliationSucceeded", Message: "ok"}
+
+	with := NewMessageBuilder(MessageOptions{ClusterName: "prod-eu"})(alert)
+	if !strings.HasSuffix(with, "Revision: Unknown\n— cluster: prod-eu") {
+		t.Errorf("Expected cluster footer as last line, got:\n%s", with)
+	}
+
+	without := NewMessageBuilder(MessageOptions{})(alert)
+	if strings.Contains(without, "cluster:") {
+		t.Errorf("Expected no footer without cluster name, got:\n%s", without)
+	}
+}
+
+func TestNewMessageBuilder_FooterCountsAgainstLimit(t *testing.T) {
+	alert := &types.FluxAlert{Severity: "error", Message: strings.Repeat("é", 2000)}
+
+	tests := []struct {
+		name        string
+		clusterName string
+	}{
+		{"without footer", ""},
+		{"with footer", "prod-eu"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := NewMessageBuilder(MessageOptions{ClusterName: tt.clusterName})(alert)
+
+			if n := utf8.RuneCountInString(message); n != types.MaxMessageLength {
+				t.Errorf("Expected %d characters, got %d", types.MaxMessageLength, n)
+			}
+
+			if !strings.Contains(message, types.TruncationMarker) {
+				t.Error("Expected truncation marker")
+			}
+
+			if tt.clusterName != "" && !strings.HasSuffix(message, "…\n— cluster: prod-eu") {
+				t.Errorf("Expected footer to survive truncation, got suffix %q", message[len(message)-40:])
+			}
+		})
+	}
+}
+
+func TestTruncateMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		footer   string
+		limit    int
+		expected string
+	}{
+		{"fits", "body\n", "footer", 20, "body\nfooter"},
+		{"exactly at limit", "body\n", "footer", 11, "body\nfooter"},
+		{"body shortened for footer", "abcdefghij\n", "foot", 10, "abcd…\nfoot"},
+		{"no footer", "abcdefghij", "", 5, "abcd…"},
+		{"footer longer than limit", "body", "abcdefgh", 4, "abcd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateMessage(tt.body, tt.footer, tt.limit); got != tt.expected {
+				t.Errorf("truncateMessage() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAppendCase(t *testing.T) {
+	tests := []struct {
+		input string
+		upper string
+		lower string
+	}{
+		{"", "", ""},
+		{"error", "ERROR", "error"},
+		{"HelmRelease", "HELMRELEASE", "helmrelease"},
+		{"Kustomization-2", "KUSTOMIZATION-2", "kustomization-2"},
+		{"Ärger", "ÄRGER", "ärger"},
+		{"abcΣ", "ABCΣ", "abcσ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			prefix := []byte("x:")
+			if got := string(appendUpper(prefix, tt.input)); got != "x:"+tt.upper {
+				t.Errorf("appendUpper(%q) = %q, want %q", tt.input, got, "x:"+tt.upper)
+			}
+			if got := string(appendLower(prefix, tt.input)); got != "x:"+tt.lower {
+				t.Errorf("appendLower(%q) = %q, want %q", tt.input, got, "x:"+tt.lower)
+			}
+		})
+	}
+}
+
+func TestBuildMessage_Allocations(t *testing.T) {
+	alert := &types.FluxAlert{
+		Severity:            "error",
+		Reason:              "ReconciliationFailed",
+		Message:             "Health check failed",
+		ReportingController: "kustomize-controller",
+	}
+	alert.InvolvedObject.Kind = "Kustomization"
+	alert.InvolvedObject.Name = "apps"
+	alert.Metadata = map[string]string{"revision": "main@sha1:abc123"}
+	build := NewMessageBuilder(MessageOptions{ClusterName: "prod"})
+
+	expected := build(alert)
+	allocs := testing.AllocsPerRun(100, func() {
+		if got := build(alert); got != expected {
+			t.Fatalf("pooled build differs from the first:\n%s\n%s", got, expected)
+		}
+	})
+	// The footer concatenation and the resulting string
+	if allocs > 2 {
+		t.Errorf("Expected at most 2 allocations per message, got %.1f", allocs)
+	}
+}
+
+func TestNormalizeSeverity(t *testing.T) {
+	tests := []struct {
+		severity   string
+		normalized string
+		known      bool
+	}{
+		{"", types.SeverityInfo, true},
+		{"info", types.SeverityInfo, true},
+		{"INFO", types.SeverityInfo, true},
+		{"warn", types.SeverityWarning, true},
+		{"Warning", types.SeverityWarning, true},
+		{"error", types.SeverityError, true},
+		{"ERROR", types.SeverityError, true},
+		{"critical", types.SeverityInfo, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.severity, func(t *testing.T) {
+			normalized, known := NormalizeSeverity(tt.severity)
+			if normalized != tt.normalized || known != tt.known {
+				t.Errorf("NormalizeSeverity(%q) = %q, %v, want %q, %v", tt.severity, normalized, known, tt.normalized, tt.known)
+			}
+		})
+	}
+}
+
+func TestBuildPushoverMessage_Severity(t *testing.T) {
+	tests := []struct {
+		severity string
+		expected string
+	}{
+		{"warn", "[WARNING]"},
+		{"Error", "[ERROR]"},
+		{"critical", "[INFO]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.severity, func(t *testing.T) {
+			message := BuildPushoverMessage(&types.FluxAlert{Severity: tt.severity})
+			if !strings.Contains(message, tt.expected) {
+				t.Errorf("Expected %s in message, got:\n%s", tt.expected, message)
+			}
+		})
+	}
+}
+
 func TestDefaultIfEmpty(t *testing.T) {
 	tests := []struct {
 		value        string
@@ -135,7 +517,7 @@ func TestCreatePushoverMessage(t *testing.T) {
 	}
 	message := "Test message content"
 
-	result := CreatePushoverMessage(cfg, message)
+	result := CreatePushoverMessage(cfg, message, "error")
 
 	if result.Token != "test_token" {
 		t.Errorf("Expected token 'test_token', got '%s'", result.Token)
@@ -154,11 +536,251 @@ func TestCreatePushoverMessage(t *testing.T) {
 	}
 }
 
+func TestCreatePushoverMessage_SeverityCredentials(t *testing.T) {
+	cfg := &config.Config{
+		PushoverAPIToken: "default_token",
+		PushoverUserKey:  "default_user",
+		SeverityCredentials: map[string]config.PushoverCredentials{
+			types.SeverityError:   {Token: "error_token", User: "error_user"},
+			types.SeverityWarning: {Token: "warning_token"},
+		},
+	}
+
+	tests := []struct {
+		severity  string
+		wantToken string
+		wantUser  string
+	}{
+		{"ERROR", "error_token", "error_user"},
+		{"warn", "warning_token", "default_user"},
+		{"info", "default_token", "default_user"},
+		{"", "default_token", "default_user"},
+		{"unknown", "default_token", "default_user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.severity, func(t *testing.T) {
+			result := CreatePushoverMessage(cfg, "message", tt.severity)
+			if result.Token != tt.wantToken || result.User != tt.wantUser {
+				t.Errorf("Expected %s/%s, got %s/%s", tt.wantToken, tt.wantUser, result.Token, result.User)
+			}
+		})
+	}
+}
+
+func TestCreatePushoverMessage_SeverityTitles(t *testing.T) {
+	cfg := &config.Config{
+		PushoverAPIToken: "token",
+		PushoverUserKey:  "user",
+		TitleError:       "FluxCD ‼️",
+		TitleInfo:        "FluxCD ℹ️",
+		MaxTitleLength:   7,
+	}
+
+	tests := []struct {
+		severity string
+		expected string
+	}{
+		{"error", "FluxCD…"},
+		{"info", "FluxCD…"},
+		{"warning", types.AppTitle},
+		{"", "FluxCD…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.severity, func(t *testing.T) {
+			if got := CreatePushoverMessage(cfg, "message", tt.severity).Title; got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	cfg.MaxTitleLength = 0
+	if got := CreatePushoverMessage(cfg, "message", "error").Title; got != "FluxCD ‼️" {
+		t.Errorf("Expected the error title, got %q", got)
+	}
+	cfg.TitleError = ""
+	if got := CreatePushoverMessage(cfg, "message", "error").Title; got != types.AppTitle {
+		t.Errorf("Expected the app title as fallback, got %q", got)
+	}
+}
+
+func TestTruncateTitle(t *testing.T) {
+	long := strings.Repeat("é", 300)
+
+	tests := []struct {
+		name     string
+		title    string
+		limit    int
+		expected string
+	}{
+		{"short title unchanged", types.AppTitle, 250, types.AppTitle},
+		{"long title shortened", long, 250, strings.Repeat("é", 249) + "…"},
+		{"zero limit is the Pushover limit", long, 0, strings.Repeat("é", 249) + "…"},
+		{"limit above Pushover's is capped", long, 1000, strings.Repeat("é", 249) + "…"},
+		{"custom limit", "production cluster", 10, "productio…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateTitle(tt.title, tt.limit); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewPushoverSender_LongTitle(t *testing.T) {
+	var sent *types.PushoverMessage
+	sender := newPushoverSender(&config.Config{MetadataPrefix: "pushover."}, &MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			sent = msg
+			return nil
+		},
+	}, nil, &MockLogger{})
+
+	alert := &types.FluxAlert{Metadata: map[string]string{"pushover.title": strings.Repeat("x", 300)}}
+	if err := sender.Send(context.Background(), CreateNotification(alert, "message")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if n := utf8.RuneCountInString(sent.Title); n > types.MaxTitleLength {
+		t.Errorf("Expected at most %d characters, got %d", types.MaxTitleLength, n)
+	}
+	if !strings.HasSuffix(sent.Title, types.TruncationMarker) {
+		t.Errorf("Expected the title to end with an ellipsis, got %q", sent.Title)
+	}
+}
+
+func TestSelectSound(t *testing.T) {
+	cfg := &config.Config{
+		ReasonSounds:   map[string]string{"imagepullbackoff": "siren"},
+		SeveritySounds: map[string]string{types.SeverityError: "persistent", types.SeverityWarning: "tugboat"},
+	}
+
+	tests := []struct {
+		name     string
+		reason   string
+		severity string
+		expected string
+	}{
+		{"reason match", "ImagePullBackOff", types.SeverityInfo, "siren"},
+		{"reason over severity", "ImagePullBackOff", types.SeverityError, "siren"},
+		{"severity fallback", "ReconciliationFailed", types.SeverityError, "persistent"},
+		{"no reason", "", types.SeverityWarning, "tugboat"},
+		{"default", "ReconciliationSucceeded", types.SeverityInfo, ""},
+		{"upper-case severity", "ReconciliationFailed", "ERROR", "persistent"},
+		{"mixed-case alias", "", "Warn", "tugboat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SelectSound(cfg, tt.reason, tt.severity); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	if got := SelectSound(&config.Config{}, "ImagePullBackOff", types.SeverityError); got != "" {
+		t.Errorf("Expected the default sound without mappings, got %q", got)
+	}
+}
+
+func TestNewPushoverSender_Sound(t *testing.T) {
+	var sent *types.PushoverMessage
+	cfg := &config.Config{
+		MetadataPrefix: "pushover.",
+		ReasonSounds:   map[string]string{"imagepullbackoff": "siren"},
+		SeveritySounds: map[string]string{types.SeverityError: "persistent"},
+	}
+	sender := newPushoverSender(cfg, &MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			sent = msg
+			return nil
+		},
+	}, nil, &MockLogger{})
+
+	tests := []struct {
+		name     string
+		alert    *types.FluxAlert
+		expected string
+	}{
+		{"by reason", &types.FluxAlert{Severity: "error", Reason: "ImagePullBackOff"}, "siren"},
+		{"by severity", &types.FluxAlert{Severity: "ERROR", Reason: "ReconciliationFailed"}, "persistent"},
+		{"metadata override wins", &types.FluxAlert{Severity: "error", Reason: "ImagePullBackOff", Metadata: map[string]string{"pushover.sound": "bike"}}, "bike"},
+		{"default", &types.FluxAlert{Severity: "info"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := sender.Send(context.Background(), CreateNotification(tt.alert, "message")); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sent.Sound != tt.expected {
+				t.Errorf("Expected sound %q, got %q", tt.expected, sent.Sound)
+			}
+		})
+	}
+}
+
+func TestOverflowAttachment(t *testing.T) {
+	long := strings.Repeat("é", 600) // 1200 bytes
+
+	tests := []struct {
+		name     string
+		body     string
+		full     string
+		maxBytes int
+		expected string
+	}{
+		{"whole message in the body", "Failed\nshort error", "short error", 100, ""},
+		{"no event message", "Failed…", "", 100, ""},
+		{"body not truncated", "Failed", long, 2000, ""},
+		{"overflow attached", "Failed\n" + long[:100] + "…", long, 2000, long},
+		{"cut on a character boundary", "Failed…", long, 102, strings.Repeat("é", 49) + "…"},
+		{"cap below the marker", "Failed…", long, 2, "é"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attachment := OverflowAttachment(tt.body, tt.full, tt.maxBytes)
+			if string(attachment) != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, attachment)
+			}
+			if len(attachment) > tt.maxBytes || !utf8.Valid(attachment) {
+				t.Errorf("Expected valid UTF-8 within %d bytes, got %d bytes", tt.maxBytes, len(attachment))
+			}
+		})
+	}
+}
+
+func TestNewPushoverSender_AttachOverflow(t *testing.T) {
+	var sent *types.PushoverMessage
+	client := &MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			sent = msg
+			return nil
+		},
+	}
+	alert := &types.FluxAlert{Severity: "error", Reason: "UpgradeFailed", Message: "Helm upgrade failed: " + strings.Repeat("x", 2000)}
+	notification := CreateNotification(alert, BuildPushoverMessage(alert))
+
+	for _, attach := range []bool{false, true} {
+		cfg := &config.Config{AttachOverflow: attach, AttachmentMaxBytes: types.DefaultAttachmentBytes}
+		if err := newPushoverSender(cfg, client, nil, &MockLogger{}).Send(context.Background(), notification); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if attached := string(sent.Attachment) == alert.Message && sent.AttachmentName == types.OverflowAttachmentName; attached != attach {
+			t.Errorf("ATTACH_OVERFLOW=%v: expected attached %v, got %d bytes named %q", attach, attach, len(sent.Attachment), sent.AttachmentName)
+		}
+	}
+}
+
 func TestValidateAlert(t *testing.T) {
 	tests := []struct {
-		name      string
-		alert     *types.FluxAlert
-		wantError bool
+		name           string
+		alert          *types.FluxAlert
+		wantError      bool
+		expectedFields []FieldError
 	}{
 		{
 			name:      "nil alert",
@@ -173,11 +795,39 @@ func TestValidateAlert(t *testing.T) {
 		{
 			name: "alert with data",
 			alert: &types.FluxAlert{
-				Severity: "error",
-				Message:  "Test",
+				Severity:  "error",
+				Message:   "Test",
+				Timestamp: "2024-01-02T03:04:05Z",
 			},
 			wantError: false,
 		},
+		{
+			name:      "severity is case insensitive",
+			alert:     &types.FluxAlert{Severity: "INFO"},
+			wantError: false,
+		},
+		{
+			name:      "unknown severity",
+			alert:     &types.FluxAlert{Severity: "critical"},
+			wantError: true,
+			expectedFields: []FieldError{
+				{Field: "severity", Reason: `must be "info", "warning" or "error"`},
+			},
+		},
+		{
+			name:      "warn is an alias of warning",
+			alert:     &types.FluxAlert{Severity: "warn"},
+			wantError: false,
+		},
+		{
+			name:      "all invalid fields are reported",
+			alert:     &types.FluxAlert{Severity: "fatal", Timestamp: "yesterday"},
+			wantError: true,
+			expectedFields: []FieldError{
+				{Field: "severity", Reason: `must be "info", "warning" or "error"`},
+				{Field: "timestamp", Reason: "must be an RFC 3339 time"},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -186,8 +836,250 @@ func TestValidateAlert(t *testing.T) {
 			if (err != nil) != tt.wantError {
 				t.Errorf("ValidateAlert() error = %v, wantError %v", err, tt.wantError)
 			}
+			if tt.expectedFields == nil {
+				return
+			}
+
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("Expected *ValidationError, got %T", err)
+			}
+			if !reflect.DeepEqual(invalid.Fields, tt.expectedFields) {
+				t.Errorf("Expected fields %+v, got %+v", tt.expectedFields, invalid.Fields)
+			}
+		})
+	}
+}
+
+func TestLimitViolations(t *testing.T) {
+	tests := []struct {
+		name           string
+		alert          *types.FluxAlert
+		expectedFields []FieldError
+	}{
+		{
+			name:  "alert with a reason",
+			alert: &types.FluxAlert{Reason: "ReconciliationSucceeded"},
+		},
+		{
+			name:  "empty alert",
+			alert: &types.FluxAlert{},
+			expectedFields: []FieldError{
+				{Field: "message", Reason: "is required when reason is empty"},
+			},
+		},
+		{
+			name:  "blank message and reason",
+			alert: &types.FluxAlert{Message: " ", Reason: "\n"},
+			expectedFields: []FieldError{
+				{Field: "message", Reason: "is required when reason is empty"},
+			},
+		},
+		{
+			name:  "absurdly long fields",
+			alert: longFieldsAlert(),
+			expectedFields: []FieldError{
+				{Field: "involvedObject.kind", Reason: "must be at most 63 bytes"},
+				{Field: "involvedObject.namespace", Reason: "must be at most 63 bytes"},
+				{Field: "involvedObject.name", Reason: "must be at most 253 bytes"},
+				{Field: "reportingController", Reason: "must be at most 253 bytes"},
+				{Field: "message", Reason: "must be at most 32768 bytes"},
+				{Field: "metadata.a", Reason: "must be at most 4096 bytes"},
+				{Field: "metadata.b", Reason: "must be at most 4096 bytes"},
+			},
+		},
+		{
+			name:           "long reason",
+			alert:          &types.FluxAlert{Reason: strings.Repeat("r", 257)},
+			expectedFields: []FieldError{{Field: "reason", Reason: "must be at most 256 bytes"}},
+		},
+		{
+			name: "fields at their limits",
+			alert: &types.FluxAlert{
+				Reason:   strings.Repeat("r", 256),
+				Message:  strings.Repeat("m", 32<<10),
+				Metadata: map[string]string{"revision": strings.Repeat("v", 4<<10)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if fields := limitViolations(tt.alert); !reflect.DeepEqual(fields, tt.expectedFields) {
+				t.Errorf("Expected fields %+v, got %+v", tt.expectedFields, fields)
+			}
+		})
+	}
+}
+
+// longFieldsAlert returns an alert with every length-capped field too long
+func longFieldsAlert() *types.FluxAlert {
+	alert := &types.FluxAlert{
+		Message:             strings.Repeat("m", 32<<10+1),
+		ReportingController: strings.Repeat("c", 254),
+		Metadata:            map[string]string{"b": strings.Repeat("v", 4<<10+1), "a": strings.Repeat("v", 4<<10+1), "c": "ok"},
+	}
+	alert.InvolvedObject.Kind = strings.Repeat("k", 64)
+	alert.InvolvedObject.Namespace = strings.Repeat("n", 64)
+	alert.InvolvedObject.Name = strings.Repeat("x", 254)
+	return alert
+}
+
+// strictTestAlert returns an alert with the given severity and kind
+func strictTestAlert(severity, kind string) *types.FluxAlert {
+	alert := &types.FluxAlert{Severity: severity}
+	alert.InvolvedObject.Kind = kind
+	return alert
+}
+
+func TestValidateStrictAlert(t *testing.T) {
+	tests := []struct {
+		name           string
+		alert          *types.FluxAlert
+		expectedFields []FieldError
+	}{
+		{
+			name:  "complete alert",
+			alert: strictTestAlert("info", "Kustomization"),
+		},
+		{
+			name:           "missing kind",
+			alert:          &types.FluxAlert{Severity: "info"},
+			expectedFields: []FieldError{{Field: "involvedObject.kind", Reason: "is required"}},
+		},
+		{
+			name:           "blank kind",
+			alert:          strictTestAlert("info", "  "),
+			expectedFields: []FieldError{{Field: "involvedObject.kind", Reason: "is required"}},
+		},
+		{
+			name:           "missing severity",
+			alert:          strictTestAlert("", "HelmRelease"),
+			expectedFields: []FieldError{{Field: "severity", Reason: "is required"}},
+		},
+		{
+			name:  "lenient checks still apply",
+			alert: &types.FluxAlert{Severity: "fatal", Timestamp: "yesterday"},
+			expectedFields: []FieldError{
+				{Field: "severity", Reason: `must be "info", "warning" or "error"`},
+				{Field: "involvedObject.kind", Reason: "is required"},
+				{Field: "timestamp", Reason: "must be an RFC 3339 time"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStrictAlert(tt.alert)
+			if tt.expectedFields == nil {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("Expected *ValidationError, got %T", err)
+			}
+			if !reflect.DeepEqual(invalid.Fields, tt.expectedFields) {
+				t.Errorf("Expected fields %+v, got %+v", tt.expectedFields, invalid.Fields)
+			}
+
+		})
+	}
+}
+
+func TestAlertValidator(t *testing.T) {
+	tests := []struct {
+		name           string
+		cfg            *config.Config
+		alert          *types.FluxAlert
+		expectedFields []FieldError
+		expectedLog    bool
+	}{
+		{
+			name:  "strict accepts valid alerts",
+			cfg:   &config.Config{ValidationMode: config.ValidationModeStrict},
+			alert: &types.FluxAlert{Severity: "error", Message: "m"},
+		},
+		{
+			name:           "strict rejects invalid alerts",
+			cfg:            &config.Config{ValidationMode: config.ValidationModeStrict},
+			alert:          &types.FluxAlert{Severity: "critical"},
+			expectedFields: []FieldError{{Field: "severity", Reason: `must be "info", "warning" or "error"`}, {Field: "message", Reason: "is required when reason is empty"}},
+		},
+		{
+			name:        "lenient logs and delivers alerts over the limits",
+			cfg:         &config.Config{ValidationMode: config.ValidationModeLenient},
+			alert:       &types.FluxAlert{Severity: "error", Reason: strings.Repeat("r", 257)},
+			expectedLog: true,
+		},
+		{
+			name:        "lenient by default",
+			cfg:         &config.Config{},
+			alert:       &types.FluxAlert{Severity: "error"},
+			expectedLog: true,
+		},
+		{
+			name:        "lenient logs and delivers unknown severities",
+			cfg:         &config.Config{ValidationMode: config.ValidationModeLenient},
+			alert:       &types.FluxAlert{Severity: "critical", Message: "m"},
+			expectedLog: true,
+		},
+		{
+			name:  "extra severities are accepted",
+			cfg:   &config.Config{ExtraSeverities: []string{"critical"}},
+			alert: &types.FluxAlert{Severity: "CRITICAL", Message: "m"},
+		},
+		{
+			name:  "mixed-case severities are accepted",
+			cfg:   &config.Config{},
+			alert: &types.FluxAlert{Severity: "Error", Message: "m"},
+		},
+		{
+			name:  "upper-case alias is accepted",
+			cfg:   &config.Config{},
+			alert: &types.FluxAlert{Severity: "WARN", Message: "m"},
+		},
+		{
+			name:           "other severities with extras",
+			cfg:            &config.Config{ExtraSeverities: []string{"critical"}, ValidationMode: config.ValidationModeStrict},
+			alert:          &types.FluxAlert{Severity: "fatal", Message: "m"},
+			expectedFields: []FieldError{{Field: "severity", Reason: `must be "info", "warning", "error" or one of EXTRA_SEVERITIES`}},
+		},
+		{
+			name:           "STRICT_ALERTS with lenient validation",
+			cfg:            &config.Config{StrictAlerts: true, ValidationMode: config.ValidationModeLenient},
+			alert:          &types.FluxAlert{Message: "m"},
+			expectedFields: []FieldError{{Field: "severity", Reason: "is required"}, {Field: "involvedObject.kind", Reason: "is required"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &RecordingLogger{}
+			err := alertValidator(tt.cfg, logger)(tt.alert)
+
+			if tt.expectedFields == nil && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.expectedFields != nil {
+				var invalid *ValidationError
+				if !errors.As(err, &invalid) || !reflect.DeepEqual(invalid.Fields, tt.expectedFields) {
+					t.Errorf("Expected fields %+v, got %v", tt.expectedFields, err)
+				}
+			}
+			if logged := len(logger.lines) > 0; logged != tt.expectedLog {
+				t.Errorf("Expected logged %v, got %v", tt.expectedLog, logger.lines)
+			}
 		})
 	}
+
+	// Lenient validation still rejects a missing alert
+	if err := alertValidator(&config.Config{ValidationMode: config.ValidationModeLenient}, &RecordingLogger{})(nil); err == nil {
+		t.Error("Expected a nil alert rejected")
+	}
 }
 
 func TestExtractAlertInfo(t *testing.T) {
@@ -208,12 +1100,10 @@ func TestExtractAlertInfo(t *testing.T) {
 			Name:      "test-deployment",
 			Namespace: "test-namespace",
 		},
-		Metadata: struct {
-			CommitStatus string `json:"commit_status"`
-			Revision     string `json:"revision"`
-			Summary      string `json:"summary"`
-		}{
-			Revision: "abc123",
+		Metadata: map[string]string{
+			"revision":      "abc123",
+			"summary":       "Bump podinfo",
+			"commit_status": "update",
 		},
 	}
 
@@ -231,6 +1121,8 @@ func TestExtractAlertInfo(t *testing.T) {
 		{"name", "test-deployment"},
 		{"namespace", "test-namespace"},
 		{"message", "Test message"},
+		{"summary", "Bump podinfo"},
+		{"commit_status", "update"},
 	}
 
 	for _, tt := range tests {
@@ -265,6 +1157,12 @@ func TestExtractAlertInfo_EmptyAlert(t *testing.T) {
 				tt.key, info[tt.key], tt.expected)
 		}
 	}
+
+	for _, key := range []string{"summary", "commit_status"} {
+		if value, ok := info[key]; ok {
+			t.Errorf("ExtractAlertInfo()[%s] = %s, want absent", key, value)
+		}
+	}
 }
 
 // Benchmark tests
@@ -285,12 +1183,8 @@ func BenchmarkBuildPushoverMessage(b *testing.B) {
 			Kind: "Deployment",
 			Name: "benchmark-deployment",
 		},
-		Metadata: struct {
-			CommitStatus string `json:"commit_status"`
-			Revision     string `json:"revision"`
-			Summary      string `json:"summary"`
-		}{
-			Revision: "abc123def456",
+		Metadata: map[string]string{
+			"revision": "abc123def456",
 		},
 	}
 
@@ -299,3 +1193,58 @@ func BenchmarkBuildPushoverMessage(b *testing.B) {
 		_ = BuildPushoverMessage(alert)
 	}
 }
+
+func TestCreateWebhookHandler_MessageBuilderPanics(t *testing.T) {
+	tests := []struct {
+		name         string
+		fallback     bool
+		expectedCode int
+		expectedSent []string
+	}{
+		{name: "fails the alert", expectedCode: http.StatusInternalServerError},
+		{name: "falls back", fallback: true, expectedCode: http.StatusOK, expectedSent: []string{"Kustomization/apps: deployed"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []string
+			registry := metrics.NewRegistry()
+			logger := &RecordingLogger{}
+			handler := CreateWebhookHandler(&HandlerDependencies{
+				Config: &config.Config{
+					PushoverAPIToken: "token",
+					BearerToken:      "Bearer token",
+					MessageFallback:  tt.fallback,
+				},
+				PushoverClient: &MockPushoverClient{
+					SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+						sent = append(sent, msg.Message)
+						return nil
+					},
+				},
+				Logger:         logger,
+				Metrics:        registry,
+				MessageBuilder: func(alert *types.FluxAlert) string { panic("template bug") },
+			})
+
+			body := `{"severity":"info","message":"deployed","involvedObject":{"kind":"Kustomization","namespace":"flux-system","name":"apps"}}`
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer token")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+			if fmt.Sprint(sent) != fmt.Sprint(tt.expectedSent) {
+				t.Errorf("Expected %v sent, got %v", tt.expectedSent, sent)
+			}
+			if logs := strings.Join(logger.lines, "\n"); !strings.Contains(logs, "Message builder panicked for Kustomization/flux-system/apps: template bug") {
+				t.Errorf("Expected the panic logged, got %s", logs)
+			}
+			if got := registry.Counter("message_build_panics_total", "").Value(); got != 1 {
+				t.Errorf("Expected 1 panic counted, got %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"net"
+	"net/http"
+	"net/netip"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// WithRecovery wraps a handler so panics are logged with the request id,
+// reported to panics and answered with a 500
+func WithRecovery(next http.Handler, logger server.Logger, panics *PanicReporter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// Let net/http handle deliberate aborts
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			requestID := defaultIfEmpty(r.Header.Get(RequestIDHeader), "-")
+			logger.Printf("Panic serving %s %s (request %s): %v\n%s", r.Method, r.URL.Path, requestID, rec, debug.Stack())
+			panics.Report(r, requestID, rec)
+			writeJSONResponse(w, http.StatusInternalServerError, types.ResponseInternalError)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WithAccessLog wraps a handler so every request is logged with its method,
+// path, status, duration and remote address
+func WithAccessLog(next http.Handler, logger server.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r)
+
+		logger.Printf("Access: %s %s %d %s %s", r.Method, r.URL.Path, recorder.status, time.Since(start), r.RemoteAddr)
+	})
+}
+
+// WithForwardedHTTPS wraps a handler so requests that did not reach a trusted
+// proxy over HTTPS are refused with 403. X-Forwarded-Proto is only believed
+// from trusted peers, anyone else could set it. The exempt paths are the
+// health checks, kubelet probes the pod directly over plain HTTP.
+func WithForwardedHTTPS(next http.Handler, trusted []netip.Prefix, logger server.Logger, exempt ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(exempt, r.URL.Path) || IsForwardedHTTPS(r, trusted) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		logger.Printf("Refused plaintext request %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+		writeJSONResponse(w, http.StatusForbidden, types.ResponseHTTPSRequired)
+	})
+}
+
+// IsForwardedHTTPS reports whether r arrived over TLS, directly or through a
+// trusted proxy. The first X-Forwarded-Proto value was set by the proxy
+// closest to the client. (pure function)
+func IsForwardedHTTPS(r *http.Request, trusted []netip.Prefix) bool {
+	if r.TLS != nil {
+		return true
+	}
+	if !isTrustedPeer(r.RemoteAddr, trusted) {
+		return false
+	}
+
+	proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+	return strings.EqualFold(strings.TrimSpace(proto), "https")
+}
+
+// isTrustedPeer reports whether remoteAddr is within one of trusted
+func isTrustedPeer(remoteAddr string, trusted []netip.Prefix) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithGzip wraps a handler so its responses are gzip-compressed for clients
+// sending Accept-Encoding: gzip, e.g. Prometheus scraping /metrics
+func WithGzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !AcceptsGzip(r.Header.Get("Accept-Encoding")) || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		next.ServeHTTP(&gzipResponseWriter{ResponseWriter: w, writer: gz}, r)
+	})
+}
+
+// AcceptsGzip reports whether an Accept-Encoding header allows gzip, named
+// or through "*", unless refused with q=0 (pure function)
+func AcceptsGzip(acceptEncoding string) bool {
+	gzipOK, starOK := false, false
+	for _, coding := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		accepted := true
+		if quality, found := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); found {
+			q, err := strconv.ParseFloat(quality, 64)
+			accepted = err == nil && q > 0
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "gzip":
+			if !accepted {
+				return false
+			}
+			gzipOK = true
+		case "*":
+			starOK = accepted
+		}
+	}
+	return gzipOK || starOK
+}
+
+// gzipResponseWriter compresses the body written through it
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	writer *gzip.Writer
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	// The length of the uncompressed body no longer applies
+	g.Header().Del("Content-Length")
+	g.ResponseWriter.WriteHeader(status)
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	g.Header().Del("Content-Length")
+	return g.writer.Write(p)
+}
+
+// statusRecorder remembers the status code written through it
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
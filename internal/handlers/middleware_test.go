@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// RecordingLogger stores formatted log lines
+type RecordingLogger struct {
+	lines []string
+}
+
+func (l *RecordingLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *RecordingLogger) Println(v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprint(v...))
+}
+
+func TestWithRecovery_Panic(t *testing.T) {
+	logger := &RecordingLogger{}
+	handler := WithRecovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("template exploded")
+	}), logger, nil)
+
+	req := httptest.NewRequest("POST", "/webhook", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, rr.Code)
+	}
+
+	if !bytes.Equal(rr.Body.Bytes(), types.ResponseInternalError) {
+		t.Errorf("Expected body %s, got %s", types.ResponseInternalError, rr.Body.String())
+	}
+
+	if len(logger.lines) != 1 {
+		t.Fatalf("Expected 1 log entry, got %d", len(logger.lines))
+	}
+
+	if !strings.Contains(logger.lines[0], "template exploded") || !strings.Contains(logger.lines[0], "goroutine") {
+		t.Errorf("Expected panic value and stack trace in log, got %s", logger.lines[0])
+	}
+}
+
+func TestWithRecovery_NoPanic(t *testing.T) {
+	logger := &RecordingLogger{}
+	handler := WithRecovery(CreateHealthHandler(nil), logger, nil)
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	if len(logger.lines) != 0 {
+		t.Errorf("Expected no log entries, got %v", logger.lines)
+	}
+}
+
+func TestWithRecovery_AbortHandler(t *testing.T) {
+	handler := WithRecovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}), &RecordingLogger{}, nil)
+
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("Expected ErrAbortHandler to be re-panicked, got %v", rec)
+		}
+	}()
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestWithAccessLog(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  http.Handler
+		method   string
+		path     string
+		expected string
+	}{
+		{"explicit status", CreateRootHandler(false), "GET", "/", "Access: GET / 400 "},
+		{"implicit status", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("ok"))
+		}), "POST", "/webhook", "Access: POST /webhook 200 "},
+		{"recovered panic", WithRecovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}), &RecordingLogger{}, nil), "POST", "/webhook", "Access: POST /webhook 500 "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &RecordingLogger{}
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.RemoteAddr = "10.0.0.7:51234"
+
+			WithAccessLog(tt.handler, logger).ServeHTTP(httptest.NewRecorder(), req)
+
+			if len(logger.lines) != 1 {
+				t.Fatalf("Expected 1 log entry, got %v", logger.lines)
+			}
+			line := logger.lines[0]
+			if !strings.HasPrefix(line, tt.expected) || !strings.HasSuffix(line, " 10.0.0.7:51234") {
+				t.Errorf("Expected access log starting with %q, got %q", tt.expected, line)
+			}
+		})
+	}
+}
+
+func TestCreateRouter_AccessLog(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		logger := &RecordingLogger{}
+		deps := &HandlerDependencies{
+			Config:         &config.Config{BearerToken: "Bearer test_token", AccessLog: enabled},
+			Logger:         logger,
+			MessageBuilder: BuildPushoverMessage,
+		}
+
+		CreateRouter(deps).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+
+		logged := len(logger.lines) == 1 && strings.HasPrefix(logger.lines[0], "Access: GET /health 200 ")
+		if logged != enabled {
+			t.Errorf("ACCESS_LOG=%v: unexpected log entries %v", enabled, logger.lines)
+		}
+	}
+}
+
+func TestWithForwardedHTTPS(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")}
+
+	tests := []struct {
+		name           string
+		path           string
+		remoteAddr     string
+		proto          string
+		expectedStatus int
+	}{
+		{"trusted proxy https", "/webhook", "10.1.2.3:4567", "https", http.StatusOK},
+		{"trusted proxy https first of chain", "/webhook", "10.1.2.3:4567", "HTTPS, http", http.StatusOK},
+		{"trusted ipv6 proxy https", "/webhook", "[fd00::1]:4567", "https", http.StatusOK},
+		{"trusted proxy http", "/webhook", "10.1.2.3:4567", "http", http.StatusForbidden},
+		{"trusted proxy without header", "/webhook", "10.1.2.3:4567", "", http.StatusForbidden},
+		{"untrusted peer with header", "/webhook", "192.168.1.5:4567", "https", http.StatusForbidden},
+		{"health exempt", "/health", "192.168.1.5:4567", "", http.StatusOK},
+		{"ready exempt", "/ready", "192.168.1.5:4567", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &RecordingLogger{}
+			handler := WithForwardedHTTPS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}), trusted, logger, "/health", "/ready")
+
+			req := httptest.NewRequest("POST", tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if tt.expectedStatus == http.StatusForbidden {
+				if !bytes.Equal(rr.Body.Bytes(), types.ResponseHTTPSRequired) {
+					t.Errorf("Expected body %s, got %s", types.ResponseHTTPSRequired, rr.Body.String())
+				}
+				if len(logger.lines) != 1 || !strings.Contains(logger.lines[0], tt.remoteAddr) {
+					t.Errorf("Expected the refused peer to be logged, got %v", logger.lines)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateRouter_ForwardedHTTPS(t *testing.T) {
+	tests := []struct {
+		name           string
+		require        bool
+		expectedStatus int
+	}{
+		{"disabled by default", false, http.StatusUnauthorized},
+		{"enabled", true, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := CreateRouter(&HandlerDependencies{
+				Config: &config.Config{
+					BearerToken:           "Bearer token",
+					RequireForwardedHTTPS: tt.require,
+					TrustedProxies:        []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+				},
+				PushoverClient: &MockPushoverClient{},
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+			})
+
+			// A plaintext request from outside the trusted proxies
+			req := httptest.NewRequest("POST", "/webhook", strings.NewReader("{}"))
+			req.RemoteAddr = "192.168.1.5:4567"
+			req.Header.Set("X-Forwarded-Proto", "https")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=1.0, *;q=0.5", true},
+		{"GZIP", true},
+		{"identity", false},
+		{"gzip;q=0", false},
+		{"gzip; q=0.0, *", false},
+		{"*", true},
+		{"*;q=0", false},
+		{"br, deflate", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := AcceptsGzip(tt.header); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCreateRouter_MetricsGzip(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.Counter("alerts_received_total", "Alerts received").Inc()
+	deps := &HandlerDependencies{
+		Config:         &config.Config{BearerToken: "Bearer test_token"},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+		Metrics:        registry,
+	}
+	router := CreateRouter(deps)
+
+	for _, acceptGzip := range []bool{false, true} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if acceptGzip {
+			req.Header.Set("Accept-Encoding", "gzip")
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("gzip=%v: expected status 200, got %d", acceptGzip, rr.Code)
+		}
+		if vary := rr.Header().Get("Vary"); vary != "Accept-Encoding" {
+			t.Errorf("gzip=%v: expected Vary: Accept-Encoding, got %q", acceptGzip, vary)
+		}
+
+		body := rr.Body.Bytes()
+		encoding := rr.Header().Get("Content-Encoding")
+		if acceptGzip {
+			if encoding != "gzip" {
+				t.Fatalf("Expected a gzip response, got Content-Encoding %q", encoding)
+			}
+			reader, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("Expected a valid gzip stream: %v", err)
+			}
+			if body, err = io.ReadAll(reader); err != nil {
+				t.Fatalf("Failed to decompress: %v", err)
+			}
+		} else if encoding != "" {
+			t.Errorf("Expected an uncompressed response, got Content-Encoding %q", encoding)
+		}
+
+		if !strings.Contains(string(body), "# TYPE alerts_received_total counter\nalerts_received_total 1\n") {
+			t.Errorf("gzip=%v: expected metrics text, got %q", acceptGzip, body)
+		}
+	}
+}
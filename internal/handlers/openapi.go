@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/openapi"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// APIVersion is the version of the webhook API in the OpenAPI document
+const APIVersion = "1"
+
+// NewAPIDocument describes the endpoints CreateRouter serves for cfg, the
+// metrics endpoint only when it is served (pure function)
+func NewAPIDocument(cfg *config.Config, metrics bool) *openapi.Document {
+	routes := NewRoutes(cfg)
+	security := []map[string][]string{{"bearer": {}}}
+	schemes := map[string]*openapi.SecurityScheme{
+		"bearer": {Type: "http", Scheme: "bearer", Description: "WEBHOOK_TOKEN or one of WEBHOOK_TOKENS"},
+	}
+	if cfg.WebhookBasicUser != "" {
+		security = append(security, map[string][]string{"basic": {}})
+		schemes["basic"] = &openapi.SecurityScheme{Type: "http", Scheme: "basic", Description: "WEBHOOK_BASIC_USER and WEBHOOK_BASIC_PASSWORD"}
+	}
+
+	errorResponse := func(description string) *openapi.Response {
+		return &openapi.Response{Description: description, Content: openapi.JSON(types.ErrorResponse{})}
+	}
+	sendError := func(description string) *openapi.Response {
+		return &openapi.Response{Description: description, Content: openapi.JSON(types.SendErrorResponse{})}
+	}
+
+	alerts := &openapi.Schema{OneOf: []*openapi.Schema{
+		openapi.SchemaOf(types.FluxAlert{}),
+		openapi.SchemaOf([]types.FluxAlert{}),
+	}}
+	accepted := &openapi.Schema{OneOf: []*openapi.Schema{
+		openapi.SchemaOf(types.StatusResponse{}),
+		openapi.SchemaOf(Decision{}),
+		openapi.SchemaOf(batchResponse{}),
+	}}
+
+	paths := map[string]openapi.PathItem{
+		routes.Webhook: {"post": {
+			Summary:     "Deliver Flux alerts",
+			Description: "Accepts a single alert or a JSON array of alerts, each delivered on its own.",
+			Security:    security,
+			RequestBody: &openapi.RequestBody{
+				Required: true,
+				Content:  map[string]openapi.MediaType{types.ContentTypeJSON: {Schema: alerts}},
+			},
+			Responses: map[string]*openapi.Response{
+				"200": {Description: "Alerts delivered, or dropped by the setting the status names", Content: map[string]openapi.MediaType{types.ContentTypeJSON: {Schema: accepted}}},
+				"202": {Description: "Alert held back by COALESCE_WINDOW", Content: openapi.JSON(types.StatusResponse{})},
+				"400": errorResponse("Malformed JSON, or a stale or future timestamp"),
+				"401": errorResponse("Missing or invalid credentials"),
+				"405": errorResponse("Method other than POST"),
+				"409": errorResponse("Request with the same idempotency key in progress"),
+				"413": errorResponse("Body over 1MB"),
+				"422": {Description: "Well-formed JSON that is not a valid alert", Content: openapi.JSON(validationResponse{})},
+				"500": sendError("Notification failed, or an alert of the batch failed"),
+				"502": sendError("Pushover rejected the notification or the credentials"),
+				"503": sendError("Pushover unavailable or rate limiting, see Retry-After"),
+			},
+		}},
+		routes.Test: {"post": {
+			Summary:  "Send a test notification",
+			Security: security,
+			Responses: map[string]*openapi.Response{
+				"200": {Description: "Test notification sent", Content: openapi.JSON(testSendResponse{})},
+				"401": errorResponse("Missing or invalid credentials"),
+				"405": errorResponse("Method other than POST"),
+				"502": sendError("Pushover rejected the notification or the credentials"),
+				"503": sendError("Pushover unavailable or rate limiting"),
+			},
+		}},
+		routes.Status: {"get": {
+			Summary:   "Report runtime state such as leadership",
+			Responses: map[string]*openapi.Response{"200": {Description: "Runtime state", Content: openapi.JSON(statusResponse{})}},
+		}},
+		routes.Health: {"get": {
+			Summary: "Liveness probe",
+			Responses: map[string]*openapi.Response{
+				"200": {Description: "Serving", Content: openapi.Text()},
+				"503": {Description: "Draining before shutdown", Content: openapi.Text()},
+			},
+		}},
+		routes.Ready: {"get": {
+			Summary: "Readiness probe, failing while the latest Pushover send failed or deliveries back up",
+			Responses: map[string]*openapi.Response{
+				"200": {Description: "Ready", Content: openapi.JSON(types.StatusResponse{})},
+				"503": {Description: "Starting, credentials invalid, a send failed within SEND_FAILURE_UNREADY_WINDOW, or over QUEUE_UNHEALTHY_DEPTH deliveries wait", Content: openapi.JSON(readinessResponse{})},
+			},
+		}},
+		routes.OpenAPI: {"get": {
+			Summary:   "This document",
+			Responses: map[string]*openapi.Response{"200": {Description: "OpenAPI document"}},
+		}},
+		routes.Docs: {"get": {
+			Summary:   "HTML index of the endpoints",
+			Responses: map[string]*openapi.Response{"200": {Description: "Endpoint index"}},
+		}},
+	}
+	if cfg.PublicURL != "" {
+		paths[routes.Callback] = openapi.PathItem{"post": {
+			Summary:     "Acknowledgement callback of emergency notifications, called by Pushover",
+			Description: "Authenticated by the token in the query of the callback URL.",
+			Responses: map[string]*openapi.Response{
+				"200": {Description: "Acknowledgement recorded", Content: openapi.JSON(types.StatusResponse{})},
+				"400": errorResponse("Invalid form"),
+				"401": errorResponse("Invalid callback token"),
+				"405": errorResponse("Method other than POST"),
+			},
+		}}
+	}
+	if metrics {
+		paths[routes.Metrics] = openapi.PathItem{"get": {
+			Summary:   "Prometheus metrics",
+			Responses: map[string]*openapi.Response{"200": {Description: "Metrics in the Prometheus text format", Content: openapi.Text()}},
+		}}
+	}
+
+	return &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       "flux-provider-pushover",
+			Version:     APIVersion,
+			Description: "Forwards FluxCD alerts to Pushover.",
+		},
+		Paths:      paths,
+		Components: &openapi.Components{SecuritySchemes: schemes},
+	}
+}
+
+// CreateOpenAPIHandler creates a handler serving doc as JSON
+func CreateOpenAPIHandler(doc *openapi.Document) http.HandlerFunc {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		body = types.ResponseInternalError
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeJSONResponse(w, http.StatusMethodNotAllowed, types.ResponseMethodNotAllowed)
+			return
+		}
+		if err != nil {
+			writeJSONResponse(w, http.StatusInternalServerError, body)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, body)
+	}
+}
+
+// docsPage lists the endpoints of the OpenAPI document
+var docsPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Description}} The machine-readable contract is at <a href="{{.OpenAPI}}">{{.OpenAPI}}</a>.</p>
+<table>
+<tr><th>Method</th><th>Path</th><th>Summary</th></tr>
+{{range .Endpoints}}<tr><td>{{.Method}}</td><td><code>{{.Path}}</code></td><td>{{.Summary}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// docsEndpoint is a row of the docs page
+type docsEndpoint struct {
+	Method  string
+	Path    string
+	Summary string
+}
+
+// CreateDocsHandler creates a handler serving an HTML index of the
+// endpoints of doc, whose JSON is served at openAPIPath
+func CreateDocsHandler(doc *openapi.Document, openAPIPath string) http.HandlerFunc {
+	var endpoints []docsEndpoint
+	for path, item := range doc.Paths {
+		for method, operation := range item {
+			endpoints = append(endpoints, docsEndpoint{Method: strings.ToUpper(method), Path: path, Summary: operation.Summary})
+		}
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].Path != endpoints[j].Path {
+			return endpoints[i].Path < endpoints[j].Path
+		}
+		return endpoints[i].Method < endpoints[j].Method
+	})
+
+	var page strings.Builder
+	err := docsPage.Execute(&page, struct {
+		Title       string
+		Description string
+		OpenAPI     string
+		Endpoints   []docsEndpoint
+	}{doc.Info.Title, doc.Info.Description, openAPIPath, endpoints})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeJSONResponse(w, http.StatusMethodNotAllowed, types.ResponseMethodNotAllowed)
+			return
+		}
+		if err != nil {
+			writeJSONResponse(w, http.StatusInternalServerError, types.ResponseInternalError)
+			return
+		}
+		writeResponse(w, "text/html; charset=utf-8", http.StatusOK, []byte(page.String()))
+	}
+}
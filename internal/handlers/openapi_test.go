@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/openapi"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+func TestCreateRouter_OpenAPI(t *testing.T) {
+	router := CreateRouter(&HandlerDependencies{
+		Config:  &config.Config{PublicURL: "https://flux.example.com", WebhookBasicUser: "flux"},
+		Logger:  &MockLogger{},
+		Metrics: metrics.NewRegistry(),
+	})
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	if err := openapi.Validate(rr.Body.Bytes()); err != nil {
+		t.Fatalf("Expected a valid OpenAPI document, got %v", err)
+	}
+
+	var doc openapi.Document
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var paths []string
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	expectedPaths := []string{"/docs", "/health", "/metrics", "/openapi.json", "/pushover-callback", "/ready", "/status", "/test", "/webhook"}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("Expected the paths %v, got %v", expectedPaths, paths)
+	}
+	if doc.Components.SecuritySchemes["basic"] == nil {
+		t.Error("Expected basic authentication described with WEBHOOK_BASIC_USER")
+	}
+
+	// The request schema lists every field of the alert, so that the
+	// document follows changes of types.FluxAlert
+	webhook := doc.Paths["/webhook"]["post"]
+	if webhook == nil || webhook.RequestBody == nil {
+		t.Fatalf("Expected the webhook request described, got %+v", doc.Paths["/webhook"])
+	}
+	oneOf := webhook.RequestBody.Content[types.ContentTypeJSON].Schema.OneOf
+	if len(oneOf) != 2 || oneOf[1].Type != "array" {
+		t.Fatalf("Expected a single alert or an array of alerts, got %+v", oneOf)
+	}
+	alert := reflect.TypeOf(types.FluxAlert{})
+	for i := 0; i < alert.NumField(); i++ {
+		name, _, _ := strings.Cut(alert.Field(i).Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		for _, schema := range []*openapi.Schema{oneOf[0], oneOf[1].Items} {
+			if schema.Properties[name] == nil {
+				t.Errorf("Expected the alert field %s in the request schema", name)
+			}
+		}
+	}
+	involved := oneOf[0].Properties["involvedObject"]
+	for _, name := range []string{"kind", "namespace", "name", "uid", "apiVersion", "resourceVersion"} {
+		if involved == nil || involved.Properties[name] == nil {
+			t.Errorf("Expected involvedObject.%s in the request schema", name)
+		}
+	}
+	if oneOf[0].Properties["Cluster"] != nil {
+		t.Error("Expected fields not part of the payload left out")
+	}
+}
+
+func TestCreateRouter_Docs(t *testing.T) {
+	router := CreateRouter(&HandlerDependencies{
+		Config: &config.Config{BasePath: "/hooks"},
+		Logger: &MockLogger{},
+	})
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/hooks/docs", nil))
+
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("Expected an HTML page, got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	body := rr.Body.String()
+	for _, expected := range []string{
+		`<a href="/hooks/openapi.json">`,
+		"<td>POST</td><td><code>/hooks/webhook</code></td><td>Deliver Flux alerts</td>",
+		"<td>GET</td><td><code>/hooks/health</code></td>",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected %q in the page, got %s", expected, body)
+		}
+	}
+	if strings.Contains(body, "/metrics") || strings.Contains(body, "pushover-callback") {
+		t.Errorf("Expected endpoints not served left out, got %s", body)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/hooks/docs", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rr.Code)
+	}
+}
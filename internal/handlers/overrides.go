@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// Pushover options an Alert's eventMetadata may override, after the prefix
+const (
+	overridePriority = "priority"
+	overrideSound    = "sound"
+	overrideDevice   = "device"
+	overrideTitle    = "title"
+)
+
+// Pushover limits names of sounds and devices to 25 characters
+const maxOptionNameLength = 25
+
+// ApplyMetadataOverrides applies the recognised prefix-keyed options in
+// metadata, e.g. "pushover.priority", to msg. Unknown prefixed keys are
+// ignored. Invalid values leave msg unchanged and are returned as errors.
+func ApplyMetadataOverrides(msg *types.PushoverMessage, metadata map[string]string, prefix string) []error {
+	if prefix == "" {
+		return nil
+	}
+
+	// Sorted so that errors are reported in a stable order
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		value := metadata[key]
+		var err error
+		switch strings.TrimPrefix(key, prefix) {
+		case overridePriority:
+			var priority int
+			if priority, err = parsePriority(value); err == nil {
+				msg.Priority = &priority
+			}
+		case overrideSound:
+			if err = validateOptionName(value); err == nil {
+				msg.Sound = value
+			}
+		case overrideDevice:
+			if err = validateDevices(value); err == nil {
+				msg.Device = value
+			}
+		case overrideTitle:
+			if err = validateTitle(value); err == nil {
+				msg.Title = value
+			}
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return errs
+}
+
+// parsePriority parses a Pushover priority between -2 and 2 (pure function)
+func parsePriority(value string) (int, error) {
+	priority, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("priority %q is not a number", value)
+	}
+	if priority < types.MinPriority || priority > types.MaxPriority {
+		return 0, fmt.Errorf("priority %d is outside %d to %d", priority, types.MinPriority, types.MaxPriority)
+	}
+	return priority, nil
+}
+
+// validateDevices validates a comma-separated list of device names (pure function)
+func validateDevices(value string) error {
+	for _, device := range strings.Split(value, ",") {
+		if err := validateOptionName(device); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateOptionName validates a sound or device name: up to 25 letters,
+// digits, underscores and dashes (pure function)
+func validateOptionName(name string) error {
+	if name == "" || len(name) > maxOptionNameLength {
+		return fmt.Errorf("%q must be 1 to %d characters", name, maxOptionNameLength)
+	}
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '_' && c != '-' {
+			return fmt.Errorf("%q may only contain letters, digits, underscores and dashes", name)
+		}
+	}
+	return nil
+}
+
+// validateTitle validates a message title, long ones are shortened to
+// MAX_TITLE_LENGTH when sending (pure function)
+func validateTitle(title string) error {
+	if strings.TrimSpace(title) == "" {
+		return fmt.Errorf("title is empty")
+	}
+	return nil
+}
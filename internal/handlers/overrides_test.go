@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+func TestApplyMetadataOverrides(t *testing.T) {
+	tests := []struct {
+		name             string
+		metadata         map[string]string
+		prefix           string
+		expectedPriority string
+		expectedSound    string
+		expectedDevice   string
+		expectedTitle    string
+		expectedErrors   []string
+	}{
+		{
+			name:             "valid overrides",
+			metadata:         map[string]string{"pushover.priority": "2", "pushover.sound": "siren", "pushover.device": "phone,tablet", "pushover.title": "Prod"},
+			prefix:           "pushover.",
+			expectedPriority: "2",
+			expectedSound:    "siren",
+			expectedDevice:   "phone,tablet",
+			expectedTitle:    "Prod",
+		},
+		{
+			name:             "lowest priority",
+			metadata:         map[string]string{"pushover.priority": " -2 "},
+			prefix:           "pushover.",
+			expectedPriority: "-2",
+			expectedTitle:    types.AppTitle,
+		},
+		{
+			name:           "out of range priority",
+			metadata:       map[string]string{"pushover.priority": "3"},
+			prefix:         "pushover.",
+			expectedTitle:  types.AppTitle,
+			expectedErrors: []string{"pushover.priority: priority 3 is outside -2 to 2"},
+		},
+		{
+			name:           "non-numeric priority",
+			metadata:       map[string]string{"pushover.priority": "high"},
+			prefix:         "pushover.",
+			expectedTitle:  types.AppTitle,
+			expectedErrors: []string{`pushover.priority: priority "high" is not a number`},
+		},
+		{
+			name:     "invalid values keep the defaults",
+			metadata: map[string]string{"pushover.sound": "air horn", "pushover.device": "phone,", "pushover.title": " "},
+			prefix:   "pushover.",
+			expectedErrors: []string{
+				`pushover.device: "" must be 1 to 25 characters`,
+				`pushover.sound: "air horn" may only contain letters, digits, underscores and dashes`,
+				"pushover.title: title is empty",
+			},
+			expectedTitle: types.AppTitle,
+		},
+		{
+			name:          "unknown prefixed and unprefixed keys are ignored",
+			metadata:      map[string]string{"pushover.url": "https://example.com", "sound": "siren", "revision": "main@sha1:abc"},
+			prefix:        "pushover.",
+			expectedTitle: types.AppTitle,
+		},
+		{
+			name:          "custom prefix",
+			metadata:      map[string]string{"pushover.sound": "siren", "alerts/sound": "bugle"},
+			prefix:        "alerts/",
+			expectedSound: "bugle",
+			expectedTitle: types.AppTitle,
+		},
+		{
+			name:          "nil metadata",
+			prefix:        "pushover.",
+			expectedTitle: types.AppTitle,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := CreatePushoverMessage(&config.Config{}, "message", "")
+			errs := ApplyMetadataOverrides(msg, tt.metadata, tt.prefix)
+
+			priority := ""
+			if msg.Priority != nil {
+				priority = fmt.Sprint(*msg.Priority)
+			}
+			if priority != tt.expectedPriority {
+				t.Errorf("Expected priority %q, got %q", tt.expectedPriority, priority)
+			}
+			if msg.Sound != tt.expectedSound || msg.Device != tt.expectedDevice || msg.Title != tt.expectedTitle {
+				t.Errorf("Expected sound %q, device %q, title %q, got %q, %q, %q",
+					tt.expectedSound, tt.expectedDevice, tt.expectedTitle, msg.Sound, msg.Device, msg.Title)
+			}
+
+			got := make([]string, len(errs))
+			for i, err := range errs {
+				got[i] = err.Error()
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.expectedErrors) {
+				t.Errorf("Expected errors %q, got %q", tt.expectedErrors, got)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_MetadataOverrides(t *testing.T) {
+	var sent *types.PushoverMessage
+	logger := &MockLogger{}
+	deps := &HandlerDependencies{
+		Config: &config.Config{
+			PushoverAPIToken: "test_token",
+			BearerToken:      "Bearer test_token",
+			MetadataPrefix:   "pushover.",
+		},
+		PushoverClient: &MockPushoverClient{
+			SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+				sent = msg
+				return nil
+			},
+		},
+		Logger:         logger,
+		MessageBuilder: BuildPushoverMessage,
+	}
+
+	body := `{"message":"down","metadata":{"revision":"main@sha1:abc","pushover.sound":"siren","pushover.priority":"9"}}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test_token")
+	rr := httptest.NewRecorder()
+	CreateWebhookHandler(deps).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if sent == nil || sent.Sound != "siren" || sent.Priority != nil {
+		t.Errorf("Expected sound override and default priority, got %+v", sent)
+	}
+	if !contains(strings.Join(logger.messages, "\n"), "Ignoring Pushover override") {
+		t.Errorf("Expected the invalid priority to be logged, got %v", logger.messages)
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// PanicReporter counts recovered panics and notifies the Pushover user of
+// them, at most once per cool-down so that a panic on every request does
+// not flood the device. A nil PanicReporter only lets panics be logged.
+type PanicReporter struct {
+	sender   PushoverSender // nil only counts panics
+	cfg      *config.Config
+	cooldown time.Duration
+	logger   server.Logger
+	ctx      context.Context // Cancelled on shutdown, abandoning notifications
+	clock    clock.Clock
+
+	mu       sync.Mutex
+	notified time.Time // Last notification, zero before the first
+	sends    sync.WaitGroup
+
+	panics *metrics.Counter
+}
+
+// NewPanicReporter creates a reporter notifying through sender, nil
+// disables the notifications. Cancelling ctx abandons notifications in flight.
+func NewPanicReporter(ctx context.Context, cfg *config.Config, sender PushoverSender, cooldown time.Duration, logger server.Logger, registry *metrics.Registry) *PanicReporter {
+	return &PanicReporter{
+		sender:   sender,
+		cfg:      cfg,
+		cooldown: cooldown,
+		logger:   logger,
+		ctx:      ctx,
+		clock:    clock.Real{},
+		panics:   registry.Counter("panics_total", "Panics recovered while serving requests"),
+	}
+}
+
+// Report counts a panic recovered while serving r and notifies about it
+// unless a notification was sent within the cool-down. The notification is
+// sent in the background, the request has already failed.
+func (p *PanicReporter) Report(r *http.Request, requestID string, rec interface{}) {
+	if p == nil {
+		return
+	}
+	p.panics.Inc()
+	if p.sender == nil || !p.claim() {
+		return
+	}
+
+	message := CreatePushoverMessage(p.cfg, fmt.Sprintf("Recovered from a panic serving %s %s (request %s): %v", r.Method, r.URL.Path, requestID, rec), types.SeverityError)
+	p.sends.Add(1)
+	go func() {
+		defer p.sends.Done()
+		ctx, cancel := context.WithTimeout(p.ctx, 10*time.Second)
+		defer cancel()
+		if err := p.sender.SendMessage(ctx, message); err != nil {
+			p.logger.Printf("Failed to send panic notification: %v", err)
+		}
+	}()
+}
+
+// claim reports whether the cool-down has passed and starts a new one
+func (p *PanicReporter) claim() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.clock.Now()
+	if !p.notified.IsZero() && now.Sub(p.notified) < p.cooldown {
+		return false
+	}
+	p.notified = now
+	return true
+}
+
+// Drain waits for pending panic notifications
+func (p *PanicReporter) Drain(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.sends.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+func TestWithRecovery_ReportsPanics(t *testing.T) {
+	var mu sync.Mutex
+	var sent []*types.PushoverMessage
+	client := &MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			mu.Lock()
+			defer mu.Unlock()
+			sent = append(sent, msg)
+			return nil
+		},
+	}
+
+	registry := metrics.NewRegistry()
+	logger := &RecordingLogger{}
+	cfg := &config.Config{PushoverAPIToken: "api-token", PushoverUserKey: "user-key"}
+	reporter := NewPanicReporter(context.Background(), cfg, client, 15*time.Minute, logger, registry)
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	reporter.clock = fake
+
+	handler := WithRecovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("template exploded")
+	}), logger, reporter)
+
+	// A hot panic loop notifies once per cool-down
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+		req.Header.Set(RequestIDHeader, "req-1")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusInternalServerError || !bytes.Equal(rr.Body.Bytes(), types.ResponseInternalError) {
+			t.Fatalf("Expected 500 with %s, got %d %s", types.ResponseInternalError, rr.Code, rr.Body.String())
+		}
+	}
+	if err := reporter.Drain(context.Background()); err != nil {
+		t.Fatalf("Unexpected drain error: %v", err)
+	}
+
+	if count := registry.Counter("panics_total", "").Value(); count != 3 {
+		t.Errorf("Expected 3 panics counted, got %d", count)
+	}
+	if len(sent) != 1 {
+		t.Fatalf("Expected a single self-notification, got %d", len(sent))
+	}
+	expected := "Recovered from a panic serving POST /webhook (request req-1): template exploded"
+	if sent[0].Message != expected || sent[0].Token != "api-token" || sent[0].User != "user-key" {
+		t.Errorf("Expected notification %q, got %+v", expected, sent[0])
+	}
+	if !strings.Contains(logger.lines[0], "(request req-1)") {
+		t.Errorf("Expected the request id in the log, got %s", logger.lines[0])
+	}
+
+	// The next panic after the cool-down notifies again
+	fake.Advance(15 * time.Minute)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/webhook", nil))
+	if err := reporter.Drain(context.Background()); err != nil {
+		t.Fatalf("Unexpected drain error: %v", err)
+	}
+	if len(sent) != 2 || !strings.Contains(sent[1].Message, "(request -)") {
+		t.Errorf("Expected a second notification after the cool-down, got %d", len(sent))
+	}
+}
+
+func TestPanicReporter_CountsWithoutSender(t *testing.T) {
+	registry := metrics.NewRegistry()
+	reporter := NewPanicReporter(context.Background(), &config.Config{}, nil, time.Minute, &RecordingLogger{}, registry)
+
+	reporter.Report(httptest.NewRequest(http.MethodGet, "/", nil), "-", "boom")
+	if count := registry.Counter("panics_total", "").Value(); count != 1 {
+		t.Errorf("Expected 1 panic counted, got %d", count)
+	}
+
+	var disabled *PanicReporter
+	disabled.Report(httptest.NewRequest(http.MethodGet, "/", nil), "-", "boom")
+	if err := disabled.Drain(context.Background()); err != nil {
+		t.Errorf("Expected nil reporter to drain cleanly, got %v", err)
+	}
+}
+
+func TestPanicReporter_SendFailureLogged(t *testing.T) {
+	logger := &MockLogger{}
+	client := &MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			return context.DeadlineExceeded
+		},
+	}
+	reporter := NewPanicReporter(context.Background(), &config.Config{}, client, time.Minute, logger, nil)
+
+	reporter.Report(httptest.NewRequest(http.MethodGet, "/", nil), "-", "boom")
+	if err := reporter.Drain(context.Background()); err != nil {
+		t.Fatalf("Unexpected drain error: %v", err)
+	}
+	if !contains(strings.Join(logger.messages, "\n"), "Failed to send panic notification") {
+		t.Errorf("Expected the failed notification to be logged, got %v", logger.messages)
+	}
+}
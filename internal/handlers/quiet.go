@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// QuietHours quiets alerts other than errors and emergencies during a daily
+// window. A nil QuietHours is never quiet.
+type QuietHours struct {
+	window   string        // As configured, for drop details
+	start    time.Duration // Offsets from midnight, end is before start
+	end      time.Duration // for windows crossing midnight
+	location *time.Location
+	suppress bool   // Drop quiet alerts instead of sending them silently
+	prefix   string // Metadata prefix of the priority override
+	clock    clock.Clock
+}
+
+// NewQuietHours creates the QUIET_HOURS window, nil when it is not set
+func NewQuietHours(cfg *config.Config) (*QuietHours, error) {
+	if cfg.QuietHours == "" {
+		return nil, nil
+	}
+
+	start, end, err := config.ParseQuietHours(cfg.QuietHours)
+	if err != nil {
+		return nil, fmt.Errorf("invalid QUIET_HOURS: %w", err)
+	}
+	location, err := time.LoadLocation(cfg.QuietHoursTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid QUIET_HOURS_TIMEZONE: %w", err)
+	}
+
+	return &QuietHours{
+		window:   cfg.QuietHours,
+		start:    start,
+		end:      end,
+		location: location,
+		suppress: cfg.QuietHoursMode == config.QuietHoursSuppress,
+		prefix:   cfg.MetadataPrefix,
+		clock:    clock.Real{},
+	}, nil
+}
+
+// WithClock makes the window tell the time by clk. It is a no-op on a nil
+// QuietHours.
+func (q *QuietHours) WithClock(clk clock.Clock) *QuietHours {
+	if q != nil {
+		q.clock = clk
+	}
+	return q
+}
+
+// Contains reports whether t falls within the window, in its time zone
+func (q *QuietHours) Contains(t time.Time) bool {
+	t = t.In(q.location)
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if q.start < q.end {
+		return offset >= q.start && offset < q.end
+	}
+	return offset >= q.start || offset < q.end
+}
+
+// Quiets reports whether alert is quieted now. Errors and alerts overriding
+// the priority to emergency always notify normally.
+func (q *QuietHours) Quiets(alert *types.FluxAlert) bool {
+	if q == nil || !q.Contains(q.clock.Now()) {
+		return false
+	}
+	if severity, _ := NormalizeSeverity(alert.Severity); severity == types.SeverityError {
+		return false
+	}
+	return !q.isEmergency(alert)
+}
+
+// Suppresses reports whether quieted alerts are dropped rather than sent silently
+func (q *QuietHours) Suppresses() bool {
+	return q != nil && q.suppress
+}
+
+// Detail describes the window for dropped alerts
+func (q *QuietHours) Detail() string {
+	return fmt.Sprintf("non-error alert during quiet hours %s %s", q.window, q.location)
+}
+
+// isEmergency reports whether alert's metadata overrides the Pushover
+// priority to emergency
+func (q *QuietHours) isEmergency(alert *types.FluxAlert) bool {
+	if q.prefix == "" {
+		return false
+	}
+	priority, err := strconv.Atoi(strings.TrimSpace(alert.Metadata[q.prefix+overridePriority]))
+	return err == nil && priority == types.EmergencyPriority
+}
+
+// ApplyQuietTTL lets the devices delete msg, sent silently during quiet
+// hours, after ttl so that such messages do not pile up overnight.
+// Emergency messages and a zero ttl leave msg unchanged (pure function).
+func ApplyQuietTTL(msg *types.PushoverMessage, ttl time.Duration) {
+	if ttl <= 0 || (msg.Priority != nil && *msg.Priority == types.EmergencyPriority) {
+		return
+	}
+	msg.TTL = int(ttl / time.Second)
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/maintenance"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// newTestQuietHours returns quiet hours for window whose clock reads wall
+// on 2024-03-10 in timezone
+func newTestQuietHours(t *testing.T, window, timezone, mode, wall string) *QuietHours {
+	t.Helper()
+	q, err := NewQuietHours(&config.Config{
+		QuietHours:         window,
+		QuietHoursTimezone: timezone,
+		QuietHoursMode:     mode,
+		MetadataPrefix:     "pushover.",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	now, err := time.ParseInLocation("2006-01-02 15:04", "2024-03-10 "+wall, q.location)
+	if err != nil {
+		t.Fatalf("Invalid clock %q: %v", wall, err)
+	}
+	q.clock = clock.NewFake(now)
+	return q
+}
+
+func TestQuietHours_Contains(t *testing.T) {
+	tests := []struct {
+		window   string
+		clock    string
+		expected bool
+	}{
+		// Crossing midnight
+		{"22:00-07:00", "21:59", false},
+		{"22:00-07:00", "22:00", true},
+		{"22:00-07:00", "23:59", true},
+		{"22:00-07:00", "00:00", true},
+		{"22:00-07:00", "06:59", true},
+		{"22:00-07:00", "07:00", false},
+		{"22:00-07:00", "12:00", false},
+		// Within one day
+		{"01:00-05:30", "00:59", false},
+		{"01:00-05:30", "01:00", true},
+		{"01:00-05:30", "05:29", true},
+		{"01:00-05:30", "05:30", false},
+		{"01:00-05:30", "23:00", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window+" at "+tt.clock, func(t *testing.T) {
+			q := newTestQuietHours(t, tt.window, "UTC", config.QuietHoursSilent, tt.clock)
+			if got := q.Contains(q.clock.Now()); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestQuietHours_Timezone(t *testing.T) {
+	q := newTestQuietHours(t, "22:00-07:00", "Europe/Budapest", config.QuietHoursSilent, "12:00")
+
+	// 21:30 UTC is 22:30 in Budapest in winter
+	if !q.Contains(time.Date(2024, 1, 15, 21, 30, 0, 0, time.UTC)) {
+		t.Error("Expected 21:30 UTC to be within quiet hours in Europe/Budapest")
+	}
+	if q.Contains(time.Date(2024, 1, 15, 6, 30, 0, 0, time.UTC)) {
+		t.Error("Expected 06:30 UTC to be outside quiet hours in Europe/Budapest")
+	}
+}
+
+func TestQuietHours_Quiets(t *testing.T) {
+	tests := []struct {
+		name     string
+		clock    string
+		alert    *types.FluxAlert
+		expected bool
+	}{
+		{"info at night", "23:00", &types.FluxAlert{Severity: "info"}, true},
+		{"warning at night", "03:00", &types.FluxAlert{Severity: "warning"}, true},
+		{"info in the day", "12:00", &types.FluxAlert{Severity: "info"}, false},
+		{"error at night", "23:00", &types.FluxAlert{Severity: "error"}, false},
+		{"uppercase error at night", "23:00", &types.FluxAlert{Severity: "ERROR"}, false},
+		{"emergency at night", "23:00", &types.FluxAlert{Severity: "info", Metadata: map[string]string{"pushover.priority": "2"}}, false},
+		{"high priority at night", "23:00", &types.FluxAlert{Severity: "info", Metadata: map[string]string{"pushover.priority": "1"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newTestQuietHours(t, "22:00-07:00", "UTC", config.QuietHoursSilent, tt.clock)
+			if got := q.Quiets(tt.alert); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	var disabled *QuietHours
+	if disabled.Quiets(&types.FluxAlert{}) || disabled.Suppresses() {
+		t.Error("Expected nil quiet hours to never quiet alerts")
+	}
+}
+
+func TestNewQuietHours(t *testing.T) {
+	if q, err := NewQuietHours(&config.Config{}); q != nil || err != nil {
+		t.Errorf("Expected nil quiet hours without QUIET_HOURS, got %v, %v", q, err)
+	}
+	if _, err := NewQuietHours(&config.Config{QuietHours: "22:00-07:00", QuietHoursTimezone: "Mars/Olympus"}); err == nil {
+		t.Error("Expected error for unknown time zone")
+	}
+}
+
+func TestCreateWebhookHandler_QuietHours(t *testing.T) {
+	silentPriority := types.MinPriority
+	tests := []struct {
+		name             string
+		mode             string
+		clock            string
+		severity         string
+		expectedSent     bool
+		expectedPriority *int
+		expectedTTL      int
+	}{
+		{"silent info at night", config.QuietHoursSilent, "23:30", "info", true, &silentPriority, 28800},
+		{"silent error at night", config.QuietHoursSilent, "23:30", "error", true, nil, 0},
+		{"silent info in the day", config.QuietHoursSilent, "09:00", "info", true, nil, 0},
+		{"suppressed warning at night", config.QuietHoursSuppress, "02:00", "warning", false, nil, 0},
+		{"suppress mode error at night", config.QuietHoursSuppress, "02:00", "error", true, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent *types.PushoverMessage
+			client := &MockPushoverClient{
+				SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+					sent = msg
+					return nil
+				},
+			}
+			cfg := &config.Config{PushoverAPIToken: "api-token", BearerToken: "Bearer api-token", QuietTTL: 8 * time.Hour}
+			handler := CreateWebhookHandler(&HandlerDependencies{
+				Config:         cfg,
+				PushoverClient: client,
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+				QuietHours:     newTestQuietHours(t, "22:00-07:00", "UTC", tt.mode, tt.clock),
+			})
+
+			body := `{"severity":"` + tt.severity + `","message":"m","reason":"r","involvedObject":{"kind":"Kustomization","name":"apps"}}`
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer api-token")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d", rr.Code)
+			}
+			if (sent != nil) != tt.expectedSent {
+				t.Fatalf("Expected sent %v, got %v", tt.expectedSent, sent != nil)
+			}
+			if !tt.expectedSent {
+				if !contains(rr.Body.String(), `"rule":"QUIET_HOURS"`) {
+					t.Errorf("Expected the quiet hours rule in the response, got %s", rr.Body.String())
+				}
+				return
+			}
+			if (sent.Priority == nil) != (tt.expectedPriority == nil) || (sent.Priority != nil && *sent.Priority != *tt.expectedPriority) {
+				t.Errorf("Expected priority %v, got %v", tt.expectedPriority, sent.Priority)
+			}
+			if sent.TTL != tt.expectedTTL {
+				t.Errorf("Expected TTL %d, got %d", tt.expectedTTL, sent.TTL)
+			}
+		})
+	}
+}
+
+func TestApplyQuietTTL(t *testing.T) {
+	silent, emergency := types.MinPriority, types.EmergencyPriority
+	tests := []struct {
+		name     string
+		priority *int
+		ttl      time.Duration
+		expected int
+	}{
+		{"silent", &silent, 8 * time.Hour, 28800},
+		{"default priority", nil, 90 * time.Second, 90},
+		{"partial seconds dropped", &silent, 1500 * time.Millisecond, 1},
+		{"emergency", &emergency, 8 * time.Hour, 0},
+		{"disabled", &silent, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &types.PushoverMessage{Priority: tt.priority}
+			ApplyQuietTTL(msg, tt.ttl)
+			if msg.TTL != tt.expected {
+				t.Errorf("Expected TTL %d, got %d", tt.expected, msg.TTL)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookHandler_Maintenance(t *testing.T) {
+	silentPriority := types.MinPriority
+	tests := []struct {
+		name             string
+		mode             string
+		namespace        string
+		severity         string
+		quietClock       string // Wall clock of quiet hours 22:00-07:00, empty without
+		expectedSent     bool
+		expectedPriority *int
+		expectedTTL      int
+	}{
+		{"suppressed during the window", config.MaintenanceSuppress, "apps", "error", "", false, nil, 0},
+		{"default mode suppresses", "", "apps", "error", "", false, nil, 0},
+		{"downgraded during the window", config.MaintenanceDowngrade, "apps", "error", "", true, &silentPriority, 0},
+		{"other namespace", config.MaintenanceSuppress, "infra", "error", "", true, nil, 0},
+		{"downgraded outside quiet hours does not expire", config.MaintenanceDowngrade, "apps", "info", "09:00", true, &silentPriority, 0},
+		{"downgraded during quiet hours expires", config.MaintenanceDowngrade, "apps", "info", "23:30", true, &silentPriority, 28800},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			windows, err := maintenance.ParseWindows("0 2 * * 6 4h namespace=apps")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			// Saturday 03:00, within the window
+			clk := clock.NewFake(time.Date(2024, 1, 6, 3, 0, 0, 0, time.UTC))
+
+			var sent *types.PushoverMessage
+			client := &MockPushoverClient{
+				SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+					sent = msg
+					return nil
+				},
+			}
+			var quietHours *QuietHours
+			if tt.quietClock != "" {
+				quietHours = newTestQuietHours(t, "22:00-07:00", "UTC", config.QuietHoursSilent, tt.quietClock)
+			}
+			handler := CreateWebhookHandler(&HandlerDependencies{
+				Config:         &config.Config{PushoverAPIToken: "api-token", BearerToken: "Bearer api-token", MaintenanceMode: tt.mode, QuietTTL: 8 * time.Hour},
+				PushoverClient: client,
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+				QuietHours:     quietHours,
+				Maintenance:    maintenance.NewWindowsWithClock(windows, time.UTC, clk),
+			})
+
+			body := `{"severity":"` + tt.severity + `","message":"m","reason":"r","involvedObject":{"kind":"Kustomization","namespace":"` + tt.namespace + `","name":"apps"}}`
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer api-token")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d", rr.Code)
+			}
+			if (sent != nil) != tt.expectedSent {
+				t.Fatalf("Expected sent %v, got %v", tt.expectedSent, sent != nil)
+			}
+			if !tt.expectedSent {
+				expected := `{"status":"suppressed","rule":"MAINTENANCE_WINDOWS","detail":"maintenance window until 2024-01-06T06:00:00Z"}`
+				if rr.Body.String() != expected {
+					t.Errorf("Expected %s, got %s", expected, rr.Body.String())
+				}
+				return
+			}
+			if (sent.Priority == nil) != (tt.expectedPriority == nil) || (sent.Priority != nil && *sent.Priority != *tt.expectedPriority) {
+				t.Errorf("Expected priority %v, got %v", tt.expectedPriority, sent.Priority)
+			}
+			if sent.TTL != tt.expectedTTL {
+				t.Errorf("Expected TTL %d, got %d", tt.expectedTTL, sent.TTL)
+			}
+		})
+	}
+}
+
+func TestCreateStatusHandler_Maintenance(t *testing.T) {
+	windows, err := maintenance.ParseWindows("0 23 * * * 3h")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	clk := clock.NewFake(time.Date(2024, 1, 1, 23, 30, 0, 0, time.UTC))
+	deps := &HandlerDependencies{
+		Config:      &config.Config{},
+		Logger:      &MockLogger{},
+		Maintenance: maintenance.NewWindowsWithClock(windows, time.UTC, clk),
+	}
+
+	rr := httptest.NewRecorder()
+	CreateStatusHandler(deps).ServeHTTP(rr, httptest.NewRequest("GET", "/status", nil))
+	expected := `"maintenance_windows":[{"window":"0 23 * * * 3h","active":true,"until":"2024-01-02T02:00:00Z","next_start":"2024-01-02T23:00:00Z"}]`
+	if !strings.Contains(rr.Body.String(), expected) {
+		t.Errorf("Expected %s in %s", expected, rr.Body.String())
+	}
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/pushover"
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// maxReceiptPollBackoff caps how far polling backs off while the receipts
+// API keeps failing
+const maxReceiptPollBackoff = 10 * time.Minute
+
+// ReceiptGetter polls the acknowledgement state of an emergency message
+type ReceiptGetter interface {
+	GetReceipt(ctx context.Context, token, receipt string) (*pushover.ReceiptStatus, error)
+}
+
+// ReceiptPoller polls the receipts of the unacknowledged emergency messages
+// of an EmergencyTracker until they are acknowledged or expire, for when
+// Pushover cannot call back. Failed polls double the interval up to
+// maxReceiptPollBackoff. A nil ReceiptPoller polls nothing.
+type ReceiptPoller struct {
+	tracker  *EmergencyTracker
+	client   ReceiptGetter
+	token    string // Of receipts without a known application
+	interval time.Duration
+	logger   server.Logger
+	clock    clock.Clock
+
+	started  atomic.Bool
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+
+	failures *metrics.Counter
+}
+
+// NewReceiptPoller creates a poller of tracker's receipts every interval
+func NewReceiptPoller(tracker *EmergencyTracker, client ReceiptGetter, token string, interval time.Duration, logger server.Logger, registry *metrics.Registry) *ReceiptPoller {
+	return &ReceiptPoller{
+		tracker:  tracker,
+		client:   client,
+		token:    token,
+		interval: interval,
+		logger:   logger,
+		clock:    clock.Real{},
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		failures: registry.Counter("pushover_receipt_poll_failures_total", "Receipt polls of emergency messages that failed"),
+	}
+}
+
+// Start polls in the background until Stop or ctx is cancelled
+func (p *ReceiptPoller) Start(ctx context.Context) {
+	if p == nil || p.started.Swap(true) {
+		return
+	}
+	go p.run(ctx)
+}
+
+// Stop ends polling and waits for an ongoing poll to finish
+func (p *ReceiptPoller) Stop(ctx context.Context) error {
+	if p == nil || !p.started.Load() {
+		return nil
+	}
+	p.stopOnce.Do(func() { close(p.stop) })
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run polls every interval, backing off while polls fail
+func (p *ReceiptPoller) run(ctx context.Context) {
+	defer close(p.done)
+
+	// Polls in flight are cancelled by Stop too
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	delay := p.interval
+	for sleep(ctx, p.clock, delay) {
+		delay = nextPollDelay(delay, p.interval, p.Poll(ctx))
+	}
+}
+
+// nextPollDelay returns the delay before the next poll: the interval after
+// a successful poll, else the last delay doubled up to maxReceiptPollBackoff
+// (pure function)
+func nextPollDelay(delay, interval time.Duration, ok bool) time.Duration {
+	if ok {
+		return interval
+	}
+	return max(interval, min(2*delay, maxReceiptPollBackoff))
+}
+
+// sleep waits for d on clk, false when ctx ended first
+func sleep(ctx context.Context, clk clock.Clock, d time.Duration) bool {
+	select {
+	case <-clk.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Poll checks every pending receipt once and reports whether all polls succeeded
+func (p *ReceiptPoller) Poll(ctx context.Context) bool {
+	ok := true
+	for _, receipt := range p.tracker.Receipts() {
+		if ctx.Err() != nil {
+			return ok
+		}
+
+		status, err := p.client.GetReceipt(ctx, p.tracker.Token(receipt, p.token), receipt)
+		if err != nil {
+			p.failures.Inc()
+			p.logger.Printf("Failed to poll receipt %s: %v", receipt, err)
+			ok = false
+			continue
+		}
+
+		info, resolved := p.tracker.Update(receipt, status)
+		if !resolved {
+			continue
+		}
+		if info.Status == ReceiptAcknowledged {
+			p.logger.Printf("Emergency alert for %s acknowledged after %s: receipt %s on device %q",
+				info.Object, info.AcknowledgedAt.Sub(info.SentAt).Round(time.Second), receipt, info.Device)
+		} else {
+			p.logger.Printf("Emergency alert for %s expired without an acknowledgement: receipt %s", info.Object, receipt)
+		}
+	}
+	return ok
+}
+
+// Attempts and first backoff of cancelling an emergency message, which is
+// safe to repeat
+const (
+	cancelAttempts = 3
+	cancelBackoff  = time.Second
+)
+
+// ReceiptCanceller stops Pushover repeating an emergency message
+type ReceiptCanceller interface {
+	CancelReceipt(ctx context.Context, token, receipt string) error
+}
+
+// EmergencyCanceller cancels the unacknowledged emergency messages about an
+// object once it recovers, for AUTO_CANCEL_EMERGENCY. A nil
+// EmergencyCanceller cancels nothing.
+type EmergencyCanceller struct {
+	tracker *EmergencyTracker
+	client  ReceiptCanceller
+	token   string // Of receipts without a known application
+	logger  server.Logger
+	backoff time.Duration
+	clock   clock.Clock
+
+	wg sync.WaitGroup
+
+	cancelled *metrics.Counter
+	failures  *metrics.Counter
+}
+
+// NewEmergencyCanceller creates a canceller of tracker's emergency messages
+func NewEmergencyCanceller(tracker *EmergencyTracker, client ReceiptCanceller, token string, logger server.Logger, registry *metrics.Registry) *EmergencyCanceller {
+	return &EmergencyCanceller{
+		tracker:   tracker,
+		client:    client,
+		token:     token,
+		logger:    logger,
+		backoff:   cancelBackoff,
+		clock:     clock.Real{},
+		cancelled: registry.Counter("pushover_emergencies_cancelled_total", "Emergency messages cancelled because their object recovered"),
+		failures:  registry.Counter("pushover_emergency_cancel_failures_total", "Emergency messages that could not be cancelled after every attempt"),
+	}
+}
+
+// Recovered cancels in the background the emergency messages about the
+// object of a delivered info alert, which reports its recovery. The alert is
+// pooled by the webhook handler, the cancellations outliving the request
+// only keep the name of its object.
+func (c *EmergencyCanceller) Recovered(ctx context.Context, alert *types.FluxAlert) {
+	if c == nil {
+		return
+	}
+	if severity, _ := NormalizeSeverity(alert.Severity); severity != types.SeverityInfo {
+		return
+	}
+
+	object := emergencyObject(alert)
+	for _, receipt := range c.tracker.ClaimRecovered(alert) {
+		c.wg.Add(1)
+		go func(receipt string) {
+			defer c.wg.Done()
+			c.cancel(ctx, receipt, object)
+		}(receipt)
+	}
+}
+
+// Drain waits for ongoing cancellations to finish
+func (c *EmergencyCanceller) Drain(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// cancel cancels receipt, retrying failures that may pass
+func (c *EmergencyCanceller) cancel(ctx context.Context, receipt, object string) {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = c.client.CancelReceipt(ctx, c.tracker.Token(receipt, c.token), receipt); err == nil {
+			c.cancelled.Inc()
+			c.tracker.Cancelled(receipt, true)
+			c.logger.Printf("Cancelled emergency alert for %s after recovery: receipt %s", object, receipt)
+			return
+		}
+		if attempt >= cancelAttempts || !pushover.IsRetryable(err) || !sleep(ctx, c.clock, c.backoff<<(attempt-1)) {
+			break
+		}
+	}
+
+	c.failures.Inc()
+	c.tracker.Cancelled(receipt, false)
+	c.logger.Printf("Failed to cancel emergency alert for %s, it repeats until acknowledged: receipt %s: %v", object, receipt, err)
+}
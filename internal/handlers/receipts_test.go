@@ -0,0 +1,484 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/pushover"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// receiptResponses answers receipt polls with the next of a sequence of
+// bodies per receipt, repeating the last one
+type receiptResponses struct {
+	mu     sync.Mutex
+	bodies map[string][]string
+	polls  []string
+}
+
+func (r *receiptResponses) Do(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	receipt := strings.TrimSuffix(req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:], ".json")
+	r.polls = append(r.polls, receipt)
+	bodies := r.bodies[receipt]
+	if len(bodies) == 0 {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(`{"receipt":"not found","errors":["receipt not found; may be invalid or expired"],"status":0}`))}, nil
+	}
+	body := bodies[0]
+	if len(bodies) > 1 {
+		r.bodies[receipt] = bodies[1:]
+	}
+	if body == "error" {
+		return &http.Response{StatusCode: http.StatusInternalServerError, Body: io.NopCloser(strings.NewReader("Internal Server Error"))}, nil
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+func newTestTracker(clk clock.Clock, registry *metrics.Registry, receipts ...string) *EmergencyTracker {
+	tracker := NewEmergencyTracker(registry)
+	tracker.clock = clk
+
+	alert := &types.FluxAlert{}
+	alert.InvolvedObject.Kind = "HelmRelease"
+	alert.InvolvedObject.Namespace = "apps"
+	alert.InvolvedObject.Name = "podinfo"
+	for _, receipt := range receipts {
+		tracker.Track(receipt, "", alert)
+	}
+	return tracker
+}
+
+func TestReceiptPoller_Poll(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0).UTC())
+	registry := metrics.NewRegistry()
+	tracker := newTestTracker(fake, registry, "acked", "expired")
+	pending := `{"status":1,"acknowledged":0,"expired":0,"expires_at":1700003600,"request":"req"}`
+	responses := &receiptResponses{bodies: map[string][]string{
+		"acked":   {pending, `{"status":1,"acknowledged":1,"acknowledged_at":1700000090,"acknowledged_by":"u","acknowledged_by_device":"phone","expired":0,"expires_at":1700003600}`},
+		"expired": {pending, "error", `{"status":1,"acknowledged":0,"expired":1,"expires_at":1700003600}`},
+	}}
+	client := pushover.NewPushoverClient(responses, "http://pushover.test/1/messages.json")
+	logger := &RecordingLogger{}
+	poller := NewReceiptPoller(tracker, client, "token", time.Minute, logger, registry)
+
+	// Both pending
+	if !poller.Poll(context.Background()) {
+		t.Fatal("Expected the first poll to succeed")
+	}
+	if tracker.Pending() != 2 {
+		t.Fatalf("Expected 2 pending, got %d", tracker.Pending())
+	}
+
+	// Acknowledged, and a failed poll of the other
+	if poller.Poll(context.Background()) {
+		t.Error("Expected the second poll to report the failure")
+	}
+	if receipts := tracker.Receipts(); len(receipts) != 1 || receipts[0] != "expired" {
+		t.Fatalf("Expected only the expiring receipt pending, got %v", receipts)
+	}
+
+	// Expired
+	if !poller.Poll(context.Background()) {
+		t.Error("Expected the third poll to succeed")
+	}
+	if tracker.Pending() != 0 {
+		t.Errorf("Expected nothing pending, got %d", tracker.Pending())
+	}
+
+	// Resolved receipts are no longer polled
+	polls := len(responses.polls)
+	poller.Poll(context.Background())
+	if len(responses.polls) != polls {
+		t.Errorf("Expected no polls without pending receipts, got %v", responses.polls[polls:])
+	}
+
+	snapshot := tracker.Snapshot()
+	if len(snapshot) != 2 || snapshot[0].Receipt != "expired" || snapshot[0].Status != ReceiptExpired ||
+		snapshot[1].Receipt != "acked" || snapshot[1].Status != ReceiptAcknowledged || snapshot[1].Device != "phone" ||
+		snapshot[1].AcknowledgedAt == nil || !snapshot[1].AcknowledgedAt.Equal(fake.Now().Add(90*time.Second)) {
+		t.Errorf("Unexpected snapshot %+v", snapshot)
+	}
+
+	if acks := registry.Counter("pushover_acknowledgements_total", "").Value(); acks != 1 {
+		t.Errorf("Expected 1 acknowledgement, got %d", acks)
+	}
+	if seconds := registry.Counter("pushover_acknowledgement_seconds_total", "").Value(); seconds != 90 {
+		t.Errorf("Expected 90 seconds to acknowledgement, got %d", seconds)
+	}
+	if expired := registry.Counter("pushover_emergencies_expired_total", "").Value(); expired != 1 {
+		t.Errorf("Expected 1 expired emergency, got %d", expired)
+	}
+	if failures := registry.Counter("pushover_receipt_poll_failures_total", "").Value(); failures != 1 {
+		t.Errorf("Expected 1 failed poll, got %d", failures)
+	}
+
+	logged := strings.Join(logger.lines, "\n")
+	for _, expected := range []string{
+		`Emergency alert for HelmRelease/apps/podinfo acknowledged after 1m30s: receipt acked on device "phone"`,
+		"Failed to poll receipt expired: pushover API returned status 500: Internal Server Error",
+		"Emergency alert for HelmRelease/apps/podinfo expired without an acknowledgement: receipt expired",
+	} {
+		if !strings.Contains(logged, expected) {
+			t.Errorf("Expected log %q, got:\n%s", expected, logged)
+		}
+	}
+}
+
+func TestReceiptPoller_Request(t *testing.T) {
+	var polled *http.Request
+	client := pushover.NewPushoverClient(&MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			polled = req
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"status":1,"acknowledged":0}`))}, nil
+		},
+	}, "http://pushover.test/1/messages.json")
+	fake := clock.NewFake(time.Now())
+	poller := NewReceiptPoller(newTestTracker(fake, nil, "r1"), client, "app-token", time.Minute, &MockLogger{}, nil)
+
+	poller.Poll(context.Background())
+	if polled == nil || polled.Method != http.MethodGet || polled.URL.Path != "/1/receipts/r1.json" || polled.URL.Query().Get("token") != "app-token" {
+		t.Errorf("Expected GET /1/receipts/r1.json with the token, got %v", polled)
+	}
+
+	// Receipts are polled with the token of the application that sent them
+	tracker := newTestTracker(fake, nil)
+	tracker.Track("r2", "error-token", &types.FluxAlert{})
+	NewReceiptPoller(tracker, client, "app-token", time.Minute, &MockLogger{}, nil).Poll(context.Background())
+	if polled == nil || polled.URL.Query().Get("token") != "error-token" {
+		t.Errorf("Expected the receipt polled with the token it was sent with, got %v", polled)
+	}
+}
+
+func TestNextPollDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		delay    time.Duration
+		ok       bool
+		expected time.Duration
+	}{
+		{"success", 4 * time.Minute, true, time.Minute},
+		{"first failure", time.Minute, false, 2 * time.Minute},
+		{"repeated failure", 4 * time.Minute, false, 8 * time.Minute},
+		{"capped", 8 * time.Minute, false, maxReceiptPollBackoff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextPollDelay(tt.delay, time.Minute, tt.ok); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	// Intervals above the cap are not shortened by a failure
+	if got := nextPollDelay(time.Hour, time.Hour, false); got != time.Hour {
+		t.Errorf("Expected the interval, got %v", got)
+	}
+}
+
+func TestReceiptPoller_Stop(t *testing.T) {
+	// The poll blocks until its context is cancelled, like a hanging API
+	polling := make(chan struct{})
+	client := pushover.NewPushoverClient(&MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			close(polling)
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		},
+	}, "http://pushover.test/1/messages.json")
+	fake := clock.NewFake(time.Now())
+	poller := NewReceiptPoller(newTestTracker(fake, nil, "r1"), client, "token", time.Millisecond, &MockLogger{}, nil)
+
+	poller.Start(context.Background())
+	<-polling
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := poller.Stop(ctx); err != nil {
+		t.Fatalf("Expected Stop to cancel the ongoing poll, got %v", err)
+	}
+	if err := poller.Stop(ctx); err != nil {
+		t.Errorf("Expected a second Stop to return, got %v", err)
+	}
+
+	var nilPoller *ReceiptPoller
+	nilPoller.Start(context.Background())
+	if err := nilPoller.Stop(ctx); err != nil {
+		t.Errorf("Expected a nil poller to stop, got %v", err)
+	}
+	if err := NewReceiptPoller(nil, client, "token", time.Minute, &MockLogger{}, nil).Stop(ctx); err != nil {
+		t.Errorf("Expected a poller that never started to stop, got %v", err)
+	}
+}
+
+func TestEmergencyTracker_Snapshot(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0).UTC())
+	tracker := newTestTracker(fake, nil, "r1")
+	fake.Advance(time.Minute)
+	tracker.Track("r2", "", &types.FluxAlert{})
+	for i := 0; i < maxResolvedReceipts+5; i++ {
+		receipt := fmt.Sprintf("old%d", i)
+		tracker.Track(receipt, "", &types.FluxAlert{})
+		tracker.Acknowledge(receipt)
+	}
+
+	snapshot := tracker.Snapshot()
+	if len(snapshot) != 2+maxResolvedReceipts {
+		t.Fatalf("Expected 2 pending and %d resolved, got %d", maxResolvedReceipts, len(snapshot))
+	}
+	if snapshot[0].Receipt != "r1" || snapshot[1].Receipt != "r2" || snapshot[0].Status != ReceiptPending {
+		t.Errorf("Expected the pending receipts oldest first, got %+v", snapshot[:2])
+	}
+	if last := fmt.Sprintf("old%d", maxResolvedReceipts+4); snapshot[2].Receipt != last {
+		t.Errorf("Expected the newest resolved receipt %s next, got %s", last, snapshot[2].Receipt)
+	}
+
+	// Pushover stops repeating after EmergencyExpire
+	fake.Advance(types.EmergencyExpire * time.Second)
+	snapshot = tracker.Snapshot()
+	if snapshot[0].Receipt != "r2" || snapshot[0].Status != ReceiptPending {
+		t.Errorf("Expected r2 still pending, got %+v", snapshot[0])
+	}
+	if snapshot[1].Receipt != "r1" || snapshot[1].Status != ReceiptExpired {
+		t.Errorf("Expected r1 expired, got %+v", snapshot[1])
+	}
+}
+
+func TestCreateStatusHandler_Emergencies(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0).UTC())
+	deps := &HandlerDependencies{
+		Config:      &config.Config{},
+		Logger:      &MockLogger{},
+		Emergencies: newTestTracker(fake, nil, "r1"),
+	}
+
+	rr := httptest.NewRecorder()
+	CreateStatusHandler(deps).ServeHTTP(rr, httptest.NewRequest("GET", "/status", nil))
+
+	var response struct {
+		Emergencies []map[string]interface{} `json:"emergencies"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode status: %v", err)
+	}
+	if len(response.Emergencies) != 1 || response.Emergencies[0]["receipt"] != "r1" ||
+		response.Emergencies[0]["status"] != "pending" || response.Emergencies[0]["object"] != "HelmRelease/apps/podinfo" {
+		t.Errorf("Unexpected emergencies %v", response.Emergencies)
+	}
+
+	rr = httptest.NewRecorder()
+	CreateStatusHandler(&HandlerDependencies{Config: &config.Config{}, Logger: &MockLogger{}}).ServeHTTP(rr, httptest.NewRequest("GET", "/status", nil))
+	if strings.Contains(rr.Body.String(), "emergencies") {
+		t.Errorf("Expected no emergencies without tracking, got %s", rr.Body.String())
+	}
+}
+
+// pushoverAPI answers messages with a receipt for emergencies and records
+// cancellations, failing the first failCancels of them
+type pushoverAPI struct {
+	mu          sync.Mutex
+	sent        []url.Values
+	cancelled   []string
+	failCancels int
+}
+
+func (a *pushoverAPI) Do(req *http.Request) (*http.Response, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	body, _ := io.ReadAll(req.Body)
+	form, _ := url.ParseQuery(string(body))
+	respond := func(status int, body string) (*http.Response, error) {
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}, nil
+	}
+
+	if receipt, ok := strings.CutSuffix(strings.TrimPrefix(req.URL.Path, "/1/receipts/"), "/cancel.json"); ok {
+		if a.failCancels > 0 {
+			a.failCancels--
+			return respond(http.StatusServiceUnavailable, "Service Unavailable")
+		}
+		a.cancelled = append(a.cancelled, receipt+" "+form.Get("token"))
+		return respond(http.StatusOK, `{"status":1,"request":"req"}`)
+	}
+
+	a.sent = append(a.sent, form)
+	if form.Get("priority") == "2" {
+		return respond(http.StatusOK, fmt.Sprintf(`{"status":1,"request":"req","receipt":"r%d"}`, len(a.sent)))
+	}
+	return respond(http.StatusOK, `{"status":1,"request":"req"}`)
+}
+
+func TestCreateWebhookHandler_CancelsEmergencyOnRecovery(t *testing.T) {
+	tests := []struct {
+		name              string
+		failCancels       int
+		expectedCancelled int
+		expectedStatus    string
+	}{
+		{"cancelled", 0, 1, ReceiptCancelled},
+		{"cancelled after a retry", 1, 1, ReceiptCancelled},
+		{"every attempt failed", cancelAttempts, 0, ReceiptPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &pushoverAPI{failCancels: tt.failCancels}
+			client := pushover.NewPushoverClient(api, "http://pushover.test/1/messages.json")
+			registry := metrics.NewRegistry()
+			tracker := NewEmergencyTracker(registry)
+			canceller := NewEmergencyCanceller(tracker, client, "token", &MockLogger{}, registry)
+			canceller.backoff = time.Millisecond
+			deps := &HandlerDependencies{
+				Config:         &config.Config{PushoverAPIToken: "token", BearerToken: "Bearer token", MetadataPrefix: "pushover."},
+				PushoverClient: client,
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+				Emergencies:    tracker,
+				Canceller:      canceller,
+			}
+			handler := CreateWebhookHandler(deps)
+			post := func(body string) {
+				t.Helper()
+				req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+				req.Header.Set("Authorization", "Bearer token")
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+				if rr.Code != http.StatusOK {
+					t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+				}
+			}
+			object := `"involvedObject":{"kind":"HelmRelease","namespace":"apps","name":"podinfo"}`
+
+			// Page
+			post(`{"severity":"error","reason":"UpgradeFailed","metadata":{"pushover.priority":"2"},` + object + `}`)
+			// Another object recovering leaves the page alone
+			post(`{"severity":"info","reason":"UpgradeSucceeded","involvedObject":{"kind":"HelmRelease","namespace":"apps","name":"other"}}`)
+			// Still failing
+			post(`{"severity":"error","reason":"UpgradeFailed",` + object + `}`)
+			if err := deps.Drain(context.Background()); err != nil {
+				t.Fatalf("Unexpected drain error: %v", err)
+			}
+			if len(api.cancelled) != 0 || tracker.Pending() != 1 {
+				t.Fatalf("Expected the page pending before the recovery, got %v cancelled", api.cancelled)
+			}
+
+			// Recover
+			post(`{"severity":"info","reason":"UpgradeSucceeded",` + object + `}`)
+			if err := deps.Drain(context.Background()); err != nil {
+				t.Fatalf("Unexpected drain error: %v", err)
+			}
+
+			if len(api.cancelled) != tt.expectedCancelled || (tt.expectedCancelled > 0 && api.cancelled[0] != "r1 token") {
+				t.Errorf("Expected %d cancellation of r1, got %v", tt.expectedCancelled, api.cancelled)
+			}
+			if snapshot := tracker.Snapshot(); len(snapshot) != 1 || snapshot[0].Receipt != "r1" || snapshot[0].Status != tt.expectedStatus {
+				t.Errorf("Expected r1 %s, got %+v", tt.expectedStatus, snapshot)
+			}
+			// The recovery itself is a normal message
+			if recovery := api.sent[len(api.sent)-1]; recovery.Get("priority") != "" {
+				t.Errorf("Expected a normal priority recovery message, got priority %q", recovery.Get("priority"))
+			}
+
+			cancelled := registry.Counter("pushover_emergencies_cancelled_total", "").Value()
+			failures := registry.Counter("pushover_emergency_cancel_failures_total", "").Value()
+			if int(cancelled) != tt.expectedCancelled || int(failures) != 1-tt.expectedCancelled {
+				t.Errorf("Expected %d cancelled and %d failures counted, got %d and %d", tt.expectedCancelled, 1-tt.expectedCancelled, cancelled, failures)
+			}
+		})
+	}
+}
+
+// blockingCanceller holds every cancellation until released
+type blockingCanceller struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingCanceller) CancelReceipt(ctx context.Context, token, receipt string) error {
+	b.started <- struct{}{}
+	<-b.release
+	return nil
+}
+
+func TestEmergencyCanceller_AlertReusedDuringCancel(t *testing.T) {
+	tracker := newTestTracker(clock.Real{}, nil, "r1")
+	client := &blockingCanceller{started: make(chan struct{}, 1), release: make(chan struct{})}
+	logger := &RecordingLogger{}
+	canceller := NewEmergencyCanceller(tracker, client, "token", logger, nil)
+
+	alert := alertPool.Get().(*types.FluxAlert)
+	*alert = types.FluxAlert{Severity: "info"}
+	alert.InvolvedObject.Kind = "HelmRelease"
+	alert.InvolvedObject.Namespace = "apps"
+	alert.InvolvedObject.Name = "podinfo"
+	canceller.Recovered(context.Background(), alert)
+
+	// The handler returns the alert to the pool while the cancellation
+	// starts, the next request reuses it
+	*alert = types.FluxAlert{}
+	alertPool.Put(alert)
+	next := alertPool.Get().(*types.FluxAlert)
+	next.InvolvedObject.Name = "other"
+	alertPool.Put(next)
+
+	<-client.started
+	close(client.release)
+	if err := canceller.Drain(context.Background()); err != nil {
+		t.Fatalf("Unexpected drain error: %v", err)
+	}
+	expected := "Cancelled emergency alert for HelmRelease/apps/podinfo after recovery: receipt r1"
+	if len(logger.lines) != 1 || logger.lines[0] != expected {
+		t.Errorf("Expected %q, got %v", expected, logger.lines)
+	}
+}
+
+func TestEmergencyTracker_ClaimRecovered(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0).UTC())
+	tracker := newTestTracker(fake, nil, "r1", "r2")
+	alert := &types.FluxAlert{Severity: "info"}
+	alert.InvolvedObject.Kind = "HelmRelease"
+	alert.InvolvedObject.Namespace = "apps"
+	alert.InvolvedObject.Name = "podinfo"
+
+	if receipts := tracker.ClaimRecovered(alert); strings.Join(receipts, ",") != "r1,r2" {
+		t.Fatalf("Expected both receipts claimed, got %v", receipts)
+	}
+	if receipts := tracker.ClaimRecovered(alert); len(receipts) != 0 {
+		t.Errorf("Expected claimed receipts not claimed twice, got %v", receipts)
+	}
+
+	tracker.Cancelled("r1", true)
+	tracker.Cancelled("r2", false)
+	if receipts := tracker.ClaimRecovered(alert); strings.Join(receipts, ",") != "r2" {
+		t.Errorf("Expected the failed receipt claimable again, got %v", receipts)
+	}
+	if tracker.Pending() != 1 {
+		t.Errorf("Expected only r2 pending, got %d", tracker.Pending())
+	}
+
+	var nilTracker *EmergencyTracker
+	if nilTracker.ClaimRecovered(alert) != nil {
+		t.Error("Expected a nil tracker to claim nothing")
+	}
+	nilTracker.Cancelled("r1", true)
+
+	var nilCanceller *EmergencyCanceller
+	nilCanceller.Recovered(context.Background(), alert)
+	if err := nilCanceller.Drain(context.Background()); err != nil {
+		t.Errorf("Expected a nil canceller to drain, got %v", err)
+	}
+}
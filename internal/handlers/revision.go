@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// shortDigestLength is the number of hex characters kept of a shortened digest
+const shortDigestLength = 8
+
+// shortSHALength is the number of hex characters SHORTEN_REVISION keeps,
+// matching git's abbreviated commit SHAs
+const shortSHALength = 7
+
+// revisionFormatSHA renders only the shortened commit SHA of a revision,
+// the format SHORTEN_REVISION selects
+const revisionFormatSHA = "sha"
+
+// digestAlgorithms are the digest prefixes Flux controllers emit
+var digestAlgorithms = []string{"sha1", "sha256", "sha384", "sha512", "blake3"}
+
+// Revision is a Flux revision split into its reference and digest, e.g.
+// "refs/heads/main@sha1:9f86d081..." or "latest@sha256:...". Ref is empty for
+// a bare digest.
+type Revision struct {
+	Ref       string
+	Algorithm string
+	Digest    string
+	Legacy    bool // "<branch>/<sha1>" format of Flux 0.x
+}
+
+// ParseRevision splits the revision formats of source-controller: the
+// "<ref>@<algo>:<digest>" and "<algo>:<digest>" formats of Flux 2.x and the
+// legacy "<branch>/<sha1>" format. Anything else, such as a Helm chart
+// version, is not a known format (pure function).
+func ParseRevision(revision string) (Revision, bool) {
+	ref, digest := "", revision
+	if at := strings.LastIndexByte(revision, '@'); at >= 0 {
+		ref, digest = revision[:at], revision[at+1:]
+		if ref == "" {
+			return Revision{}, false
+		}
+	}
+
+	if algorithm, hex, ok := strings.Cut(digest, ":"); ok {
+		if !isDigestAlgorithm(algorithm) || !isHexDigest(hex) {
+			return Revision{}, false
+		}
+		return Revision{Ref: ref, Algorithm: algorithm, Digest: hex}, true
+	}
+
+	// Legacy GitRepository revisions end in a 40 character commit SHA
+	if ref != "" {
+		return Revision{}, false
+	}
+	slash := strings.LastIndexByte(revision, '/')
+	if slash <= 0 || len(revision)-slash-1 != 40 || !isHexDigest(revision[slash+1:]) {
+		return Revision{}, false
+	}
+	return Revision{Ref: revision[:slash], Algorithm: "sha1", Digest: revision[slash+1:], Legacy: true}, true
+}
+
+// FormatRevision renders revision in the given REVISION_FORMAT: "short"
+// keeps the structure with an 8 character digest, "branch-short" renders
+// "main @ 9f86d081" and "sha", used by SHORTEN_REVISION, only "9f86d08".
+// Full and unknown formats, as well as revisions that do not parse, are
+// returned untouched (pure function).
+func FormatRevision(revision, format string) string {
+	switch format {
+	case config.RevisionFormatShort, config.RevisionFormatBranchShort:
+	case revisionFormatSHA:
+		if sha, ok := commitSHA(revision); ok {
+			return sha[:shortSHALength]
+		}
+		return revision
+	default:
+		return revision
+	}
+
+	parsed, ok := ParseRevision(revision)
+	if !ok {
+		return revision
+	}
+	digest := parsed.Digest[:min(len(parsed.Digest), shortDigestLength)]
+
+	if format == config.RevisionFormatShort {
+		if parsed.Legacy {
+			return parsed.Ref + "/" + digest
+		}
+		short := parsed.Algorithm + ":" + digest
+		if parsed.Ref != "" {
+			short = parsed.Ref + "@" + short
+		}
+		return short
+	}
+
+	if parsed.Ref == "" {
+		return digest
+	}
+	return shortRef(parsed.Ref) + " @ " + digest
+}
+
+// ShortSHA returns the commit SHA of revision cut to 7 characters, dropping
+// its reference and algorithm: "main@sha1:9f86d081..." becomes "9f86d08".
+// Only full SHA-1 and SHA-256 digests are cut, anything else, such as a
+// date-like "20240101", is returned untouched (pure function).
+func ShortSHA(revision string) string {
+	return FormatRevision(revision, revisionFormatSHA)
+}
+
+// commitSHA returns the full SHA-1 or SHA-256 digest of revision, either a
+// Flux revision or a bare 40 or 64 character hex SHA (pure function)
+func commitSHA(revision string) (string, bool) {
+	digest := revision
+	if parsed, ok := ParseRevision(revision); ok {
+		if parsed.Algorithm != "sha1" && parsed.Algorithm != "sha256" {
+			return "", false
+		}
+		digest = parsed.Digest
+	}
+	if (len(digest) != 40 && len(digest) != 64) || !isHexDigest(digest) {
+		return "", false
+	}
+	return digest, true
+}
+
+// displayRevision renders the revision of alert as configured by
+// SHORTEN_REVISION and REVISION_FORMAT (pure function)
+func displayRevision(alert *types.FluxAlert, opts MessageOptions) string {
+	return FormatRevision(defaultIfEmpty(alert.Metadata[types.MetadataRevision], types.DefaultValue), opts.revisionFormat())
+}
+
+// shortRef strips the refs/heads/ and refs/tags/ prefixes of a Git reference (pure function)
+func shortRef(ref string) string {
+	for _, prefix := range []string{"refs/heads/", "refs/tags/"} {
+		if short, ok := strings.CutPrefix(ref, prefix); ok && short != "" {
+			return short
+		}
+	}
+	return ref
+}
+
+// isDigestAlgorithm reports whether algorithm is a known digest prefix (pure function)
+func isDigestAlgorithm(algorithm string) bool {
+	for _, known := range digestAlgorithms {
+		if algorithm == known {
+			return true
+		}
+	}
+	return false
+}
+
+// isHexDigest reports whether s is a non-empty lower case hex string (pure function)
+func isHexDigest(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+)
+
+func TestFormatRevision(t *testing.T) {
+	const (
+		sha1   = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b"
+		sha256 = "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+	)
+
+	tests := []struct {
+		name        string
+		revision    string
+		short       string
+		branchShort string
+	}{
+		// source-controller GitRepository, forwarded as-is by kustomize-controller
+		{"git branch", "main@sha1:" + sha1, "main@sha1:9f86d081", "main @ 9f86d081"},
+		{"git named branch", "refs/heads/main@sha1:" + sha1, "refs/heads/main@sha1:9f86d081", "main @ 9f86d081"},
+		{"git nested branch", "refs/heads/feature/login@sha1:" + sha1, "refs/heads/feature/login@sha1:9f86d081", "feature/login @ 9f86d081"},
+		{"git tag", "v1.2.3@sha1:" + sha1, "v1.2.3@sha1:9f86d081", "v1.2.3 @ 9f86d081"},
+		{"git named tag", "refs/tags/v1.2.3@sha1:" + sha1, "refs/tags/v1.2.3@sha1:9f86d081", "v1.2.3 @ 9f86d081"},
+		{"git pull request", "refs/pull/420/head@sha1:" + sha1, "refs/pull/420/head@sha1:9f86d081", "refs/pull/420/head @ 9f86d081"},
+		{"git commit only", "sha1:" + sha1, "sha1:9f86d081", "9f86d081"},
+		{"git sha256 object format", "main@sha256:" + sha256, "main@sha256:2c26b46b", "main @ 2c26b46b"},
+		{"git legacy", "main/" + sha1, "main/9f86d081", "main @ 9f86d081"},
+
+		// source-controller OCIRepository and Bucket, HelmRepository artifacts
+		{"oci tag", "latest@sha256:" + sha256, "latest@sha256:2c26b46b", "latest @ 2c26b46b"},
+		{"oci semver tag", "6.5.0@sha256:" + sha256, "6.5.0@sha256:2c26b46b", "6.5.0 @ 2c26b46b"},
+		{"oci digest only", "sha256:" + sha256, "sha256:2c26b46b", "2c26b46b"},
+		{"bucket digest", "sha256:" + sha256, "sha256:2c26b46b", "2c26b46b"},
+
+		// helm-controller and HelmChart versions are not digests
+		{"chart version", "6.5.0", "6.5.0", "6.5.0"},
+		{"chart version with build metadata", "6.5.0+9f86d081884c", "6.5.0+9f86d081884c", "6.5.0+9f86d081884c"},
+
+		// Unknown shapes pass through untouched
+		{"placeholder", "Unknown", "Unknown", "Unknown"},
+		{"empty", "", "", ""},
+		{"unknown algorithm", "main@md5:" + sha1, "main@md5:" + sha1, "main@md5:" + sha1},
+		{"non-hex digest", "main@sha1:not-a-sha", "main@sha1:not-a-sha", "main@sha1:not-a-sha"},
+		{"upper case digest", "main@sha1:9F86D081884C", "main@sha1:9F86D081884C", "main@sha1:9F86D081884C"},
+		{"missing ref", "@sha1:" + sha1, "@sha1:" + sha1, "@sha1:" + sha1},
+		{"ref without digest", "main@" + sha1, "main@" + sha1, "main@" + sha1},
+		{"short legacy sha", "main/9f86d081", "main/9f86d081", "main/9f86d081"},
+		{"bare sha", sha1, sha1, sha1},
+		{"short digest", "main@sha1:9f86", "main@sha1:9f86", "main @ 9f86"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatRevision(tt.revision, config.RevisionFormatFull); got != tt.revision {
+				t.Errorf("full: expected %q untouched, got %q", tt.revision, got)
+			}
+			if got := FormatRevision(tt.revision, ""); got != tt.revision {
+				t.Errorf("empty format: expected %q untouched, got %q", tt.revision, got)
+			}
+			if got := FormatRevision(tt.revision, config.RevisionFormatShort); got != tt.short {
+				t.Errorf("short: expected %q, got %q", tt.short, got)
+			}
+			if got := FormatRevision(tt.revision, config.RevisionFormatBranchShort); got != tt.branchShort {
+				t.Errorf("branch-short: expected %q, got %q", tt.branchShort, got)
+			}
+		})
+	}
+}
+
+func TestShortSHA(t *testing.T) {
+	const sha1 = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b"
+
+	tests := []struct {
+		name     string
+		revision string
+		expected string
+	}{
+		{"flux branch", "main@sha1:" + sha1, "9f86d08"},
+		{"flux named branch", "refs/heads/main@sha1:" + sha1, "9f86d08"},
+		{"flux digest only", "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae", "2c26b46"},
+		{"flux oci tag", "latest@sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae", "2c26b46"},
+		{"flux legacy", "main/" + sha1, "9f86d08"},
+		{"plain sha", sha1, "9f86d08"},
+		{"plain sha256", "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae", "2c26b46"},
+		{"already short sha", "9f86d08", "9f86d08"},
+		{"date", "20240101", "20240101"},
+		{"numeric chart version", "12345678", "12345678"},
+		{"partial hex", "9f86d081884c", "9f86d081884c"},
+		{"short digest", "main@sha1:9f86", "main@sha1:9f86"},
+		{"other algorithm", "main@sha512:" + sha1 + sha1, "main@sha512:" + sha1 + sha1},
+		{"chart version", "6.5.0", "6.5.0"},
+		{"placeholder", "Unknown", "Unknown"},
+		{"short hex word", "cafe", "cafe"},
+		{"upper case sha", "9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B", "9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShortSHA(tt.revision); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseRevision(t *testing.T) {
+	revision, ok := ParseRevision("refs/heads/main@sha1:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b")
+	if !ok {
+		t.Fatal("Expected revision to parse")
+	}
+
+	expected := Revision{Ref: "refs/heads/main", Algorithm: "sha1", Digest: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b"}
+	if revision != expected {
+		t.Errorf("Expected %+v, got %+v", expected, revision)
+	}
+
+	if _, ok := ParseRevision("6.5.0"); ok {
+		t.Error("Expected chart version not to parse")
+	}
+}
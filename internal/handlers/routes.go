@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+)
+
+// defaultWebhookPath is used by configs that leave WEBHOOK_PATH unset
+const defaultWebhookPath = "/webhook"
+
+// Routes are the paths the router serves, after BASE_PATH, WEBHOOK_PATH
+// and HEALTH_AT_ROOT were applied
+type Routes struct {
+	Base     string // BASE_PATH, empty at the root
+	Health   string
+	Ready    string
+	Status   string
+	Webhook  string
+	Test     string
+	Callback string
+	Metrics  string
+	OpenAPI  string
+	Docs     string
+}
+
+// NewRoutes resolves the routes of cfg (pure function)
+func NewRoutes(cfg *config.Config) Routes {
+	webhook := cfg.WebhookPath
+	if webhook == "" {
+		webhook = defaultWebhookPath
+	}
+	probes := cfg.BasePath
+	if cfg.HealthAtRoot {
+		probes = ""
+	}
+
+	return Routes{
+		Base:     cfg.BasePath,
+		Health:   probes + "/health",
+		Ready:    probes + "/ready",
+		Status:   cfg.BasePath + "/status",
+		Webhook:  cfg.BasePath + webhook,
+		Test:     cfg.BasePath + "/test",
+		Callback: cfg.BasePath + CallbackPath,
+		Metrics:  cfg.BasePath + "/metrics",
+		OpenAPI:  cfg.BasePath + "/openapi.json",
+		Docs:     cfg.BasePath + "/docs",
+	}
+}
+
+// Legacy maps the unprefixed default routes to the routes that replaced
+// them, leaving out the ones that did not move. The callback and metrics
+// routes are only included when they are served (pure function).
+func (r Routes) Legacy(callback, metrics bool) map[string]string {
+	legacy := map[string]string{
+		"/health":          r.Health,
+		"/ready":           r.Ready,
+		"/status":          r.Status,
+		defaultWebhookPath: r.Webhook,
+	}
+	if callback {
+		legacy[CallbackPath] = r.Callback
+	}
+	if metrics {
+		legacy["/metrics"] = r.Metrics
+	}
+
+	for old, route := range legacy {
+		if old == route {
+			delete(legacy, old)
+		}
+	}
+	return legacy
+}
+
+// redirectTo answers with a 308 to route, which keeps the method and body
+// so that POSTed alerts are resent, and the query for callback tokens
+func redirectTo(route string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := route
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
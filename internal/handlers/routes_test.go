@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+)
+
+func TestNewRoutes(t *testing.T) {
+	routes := NewRoutes(&config.Config{BasePath: "/hooks/pushover", WebhookPath: "/flux", HealthAtRoot: true})
+	expected := Routes{
+		Base:     "/hooks/pushover",
+		Health:   "/health",
+		Ready:    "/ready",
+		Status:   "/hooks/pushover/status",
+		Webhook:  "/hooks/pushover/flux",
+		Test:     "/hooks/pushover/test",
+		Callback: "/hooks/pushover/pushover-callback",
+		Metrics:  "/hooks/pushover/metrics",
+		OpenAPI:  "/hooks/pushover/openapi.json",
+		Docs:     "/hooks/pushover/docs",
+	}
+	if routes != expected {
+		t.Errorf("Expected %+v, got %+v", expected, routes)
+	}
+
+	if routes := NewRoutes(&config.Config{}); routes.Webhook != "/webhook" || routes.Health != "/health" {
+		t.Errorf("Expected the default routes without paths configured, got %+v", routes)
+	}
+}
+
+func TestRoutes_Legacy(t *testing.T) {
+	legacy := NewRoutes(&config.Config{BasePath: "/p", HealthAtRoot: true}).Legacy(false, true)
+	expected := map[string]string{"/status": "/p/status", "/webhook": "/p/webhook", "/metrics": "/p/metrics"}
+	if len(legacy) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, legacy)
+	}
+	for old, route := range expected {
+		if legacy[old] != route {
+			t.Errorf("Expected %s to move to %s, got %q", old, route, legacy[old])
+		}
+	}
+
+	if legacy := NewRoutes(&config.Config{WebhookPath: "/webhook"}).Legacy(true, true); len(legacy) != 0 {
+		t.Errorf("Expected no moved routes by default, got %v", legacy)
+	}
+}
+
+func TestCreateRouter_BasePath(t *testing.T) {
+	tests := []struct {
+		name           string
+		cfg            config.Config
+		method         string
+		path           string
+		expectedStatus int
+		expectedTarget string // Location of redirects
+	}{
+		{"prefixed health", config.Config{BasePath: "/hooks/pushover"}, "GET", "/hooks/pushover/health", http.StatusOK, ""},
+		{"prefixed ready", config.Config{BasePath: "/hooks/pushover"}, "GET", "/hooks/pushover/ready", http.StatusOK, ""},
+		{"prefixed status", config.Config{BasePath: "/hooks/pushover"}, "GET", "/hooks/pushover/status", http.StatusOK, ""},
+		{"prefixed webhook", config.Config{BasePath: "/hooks/pushover"}, "POST", "/hooks/pushover/webhook", http.StatusUnauthorized, ""},
+		{"prefixed test", config.Config{BasePath: "/hooks/pushover"}, "POST", "/hooks/pushover/test", http.StatusUnauthorized, ""},
+		{"prefixed metrics", config.Config{BasePath: "/hooks/pushover"}, "GET", "/hooks/pushover/metrics", http.StatusOK, ""},
+		{"prefixed openapi", config.Config{BasePath: "/hooks/pushover"}, "GET", "/hooks/pushover/openapi.json", http.StatusOK, ""},
+		{"prefixed docs", config.Config{BasePath: "/hooks/pushover"}, "GET", "/hooks/pushover/docs", http.StatusOK, ""},
+		{"prefixed root", config.Config{BasePath: "/hooks/pushover"}, "GET", "/hooks/pushover/", http.StatusBadRequest, ""},
+		{"prefixed debug", config.Config{BasePath: "/hooks/pushover"}, "GET", "/hooks/pushover/debug/pprof/", http.StatusNotFound, ""},
+		{"old webhook", config.Config{BasePath: "/hooks/pushover"}, "POST", "/webhook", http.StatusNotFound, ""},
+		{"old health", config.Config{BasePath: "/hooks/pushover"}, "GET", "/health", http.StatusNotFound, ""},
+		{"old root", config.Config{BasePath: "/hooks/pushover"}, "GET", "/", http.StatusNotFound, ""},
+		{"health at root", config.Config{BasePath: "/hooks/pushover", HealthAtRoot: true}, "GET", "/health", http.StatusOK, ""},
+		{"ready at root", config.Config{BasePath: "/hooks/pushover", HealthAtRoot: true}, "GET", "/ready", http.StatusOK, ""},
+		{"no prefixed health at root", config.Config{BasePath: "/hooks/pushover", HealthAtRoot: true}, "GET", "/hooks/pushover/health", http.StatusBadRequest, ""},
+		{"renamed webhook", config.Config{WebhookPath: "/flux"}, "POST", "/flux", http.StatusUnauthorized, ""},
+		{"renamed webhook old path", config.Config{WebhookPath: "/flux"}, "POST", "/webhook", http.StatusBadRequest, ""},
+		{"redirected webhook", config.Config{BasePath: "/hooks/pushover", RedirectLegacyPaths: true}, "POST", "/webhook?x=1", http.StatusPermanentRedirect, "/hooks/pushover/webhook?x=1"},
+		{"redirected renamed webhook", config.Config{WebhookPath: "/flux", RedirectLegacyPaths: true}, "POST", "/webhook", http.StatusPermanentRedirect, "/flux"},
+		{"redirected metrics", config.Config{BasePath: "/hooks/pushover", RedirectLegacyPaths: true}, "GET", "/metrics", http.StatusPermanentRedirect, "/hooks/pushover/metrics"},
+		{"unknown path not redirected", config.Config{BasePath: "/hooks/pushover", RedirectLegacyPaths: true}, "GET", "/other", http.StatusNotFound, ""},
+		{"health at root not redirected", config.Config{BasePath: "/hooks/pushover", HealthAtRoot: true, RedirectLegacyPaths: true}, "GET", "/health", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.BearerToken = "Bearer test_token"
+			router := CreateRouter(&HandlerDependencies{
+				Config:         &cfg,
+				PushoverClient: &MockPushoverClient{},
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+				Metrics:        metrics.NewRegistry(),
+			})
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if location := rr.Header().Get("Location"); location != tt.expectedTarget {
+				t.Errorf("Expected Location %q, got %q", tt.expectedTarget, location)
+			}
+		})
+	}
+}
+
+func TestCreateRouter_BasePathForwardedHTTPS(t *testing.T) {
+	cfg := &config.Config{
+		BearerToken:           "Bearer test_token",
+		BasePath:              "/hooks/pushover",
+		RequireForwardedHTTPS: true,
+		TrustedProxies:        []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+	}
+	router := CreateRouter(&HandlerDependencies{Config: cfg, Logger: &RecordingLogger{}, MessageBuilder: BuildPushoverMessage})
+
+	for path, expected := range map[string]int{
+		"/hooks/pushover/health": http.StatusOK,
+		"/hooks/pushover/ready":  http.StatusOK,
+		"/hooks/pushover/status": http.StatusForbidden,
+	} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		if rr.Code != expected {
+			t.Errorf("Path %s: expected status %d, got %d", path, expected, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// Event payload schemas of Flux's notification API. The v1beta2 payloads of
+// Flux 2.1 and older prefix the metadata keys of controllers with their
+// API group, e.g. "kustomize.toolkit.fluxcd.io/revision", later ones do not.
+const (
+	SchemaV1Beta2 = "v1beta2"
+	SchemaV1      = "v1"
+)
+
+// fluxGroupSuffix ends the API groups of Flux controllers
+const fluxGroupSuffix = ".toolkit.fluxcd.io"
+
+// detectSchema tells which payload schema a decoded alert was sent in (pure function)
+func detectSchema(alert *types.FluxAlert) string {
+	for key := range alert.Metadata {
+		if _, _, prefixed := cutGroupPrefix(key); prefixed {
+			return SchemaV1Beta2
+		}
+	}
+	return SchemaV1
+}
+
+// normalizeAlert brings an alert of either schema into the shape of v1,
+// stripping the API group from its metadata keys. A key also sent without
+// the prefix keeps that value. Returns the schema detected.
+func normalizeAlert(alert *types.FluxAlert) string {
+	schema := detectSchema(alert)
+	if schema == SchemaV1 {
+		return schema
+	}
+
+	metadata := make(map[string]string, len(alert.Metadata))
+	for key, value := range alert.Metadata {
+		if _, _, prefixed := cutGroupPrefix(key); !prefixed {
+			metadata[key] = value
+		}
+	}
+	for key, value := range alert.Metadata {
+		if _, name, prefixed := cutGroupPrefix(key); prefixed {
+			if _, exists := metadata[name]; !exists {
+				metadata[name] = value
+			}
+		}
+	}
+	alert.Metadata = metadata
+	return schema
+}
+
+// cutGroupPrefix splits a metadata key prefixed with the API group of a Flux
+// controller, e.g. "kustomize.toolkit.fluxcd.io/revision" (pure function)
+func cutGroupPrefix(key string) (group, name string, ok bool) {
+	group, name, ok = strings.Cut(key, "/")
+	if !ok || name == "" || !strings.HasSuffix(group, fluxGroupSuffix) {
+		return "", key, false
+	}
+	return group, name, true
+}
+
+// schemaDecoder wraps decode to normalize the alerts it decodes, counting
+// them by schema and logging the first alert of each schema, so that
+// clusters on older Flux versions show up in the logs
+func schemaDecoder(decode func([]byte, *types.FluxAlert) error, logger server.Logger, registry *metrics.Registry) func([]byte, *types.FluxAlert) error {
+	decoded := registry.CounterVec("event_schemas_total", "Decoded webhook events, by the Flux payload schema detected", "schema")
+	var seen sync.Map
+
+	return func(data []byte, alert *types.FluxAlert) error {
+		if err := decode(data, alert); err != nil {
+			return err
+		}
+		schema := normalizeAlert(alert)
+		decoded.WithLabelValues(schema).Inc()
+		if _, logged := seen.LoadOrStore(schema, true); !logged {
+			logger.Printf("Receiving Flux %s event payloads, first from %s %s", schema, alertKind(alert), alertName(alert))
+		}
+		return nil
+	}
+}
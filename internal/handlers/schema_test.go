@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+func TestSchemaDecoder_Fixtures(t *testing.T) {
+	golden, err := os.ReadFile(filepath.Join("testdata", "events", "normalized.golden.json"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		fixture string
+		schema  string
+	}{
+		{"flux-v2.1.json", SchemaV1Beta2},
+		{"flux-v2.3.json", SchemaV1},
+		{"flux-current.json", SchemaV1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "events", tt.fixture))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			registry := metrics.NewRegistry()
+			decode := schemaDecoder(alertDecoder(&config.Config{}), &MockLogger{}, registry)
+			var alert types.FluxAlert
+			if err := decode(data, &alert); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := registry.CounterVec("event_schemas_total", "").WithLabelValues(tt.schema).Value(); got != 1 {
+				t.Errorf("Expected the event counted as %s, got %d", tt.schema, got)
+			}
+
+			// reportingInstance, which later versions leave out, aside
+			alert.ReportingInstance = ""
+			normalized, err := json.Marshal(alert)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !bytes.Equal(normalized, bytes.TrimSpace(golden)) {
+				t.Errorf("Expected %s, got %s", golden, normalized)
+			}
+		})
+	}
+}
+
+func TestNormalizeAlert(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		schema   string
+		expected map[string]string
+	}{
+		{
+			name:     "unprefixed",
+			metadata: map[string]string{"revision": "main@sha1:abc", "summary": "prod"},
+			schema:   SchemaV1,
+			expected: map[string]string{"revision": "main@sha1:abc", "summary": "prod"},
+		},
+		{
+			name:     "no metadata",
+			schema:   SchemaV1,
+			expected: nil,
+		},
+		{
+			name:     "prefixed",
+			metadata: map[string]string{"helm.toolkit.fluxcd.io/revision": "1.2.3", "helm.toolkit.fluxcd.io/token": "t"},
+			schema:   SchemaV1Beta2,
+			expected: map[string]string{"revision": "1.2.3", "token": "t"},
+		},
+		{
+			name:     "unprefixed wins",
+			metadata: map[string]string{"kustomize.toolkit.fluxcd.io/revision": "old", "revision": "new"},
+			schema:   SchemaV1Beta2,
+			expected: map[string]string{"revision": "new"},
+		},
+		{
+			name:     "other groups kept",
+			metadata: map[string]string{"example.com/owner": "team-a", "kustomize.toolkit.fluxcd.io/": "empty name"},
+			schema:   SchemaV1,
+			expected: map[string]string{"example.com/owner": "team-a", "kustomize.toolkit.fluxcd.io/": "empty name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := &types.FluxAlert{Metadata: tt.metadata}
+			if schema := normalizeAlert(alert); schema != tt.schema {
+				t.Errorf("Expected schema %s, got %s", tt.schema, schema)
+			}
+			if !reflect.DeepEqual(alert.Metadata, tt.expected) {
+				t.Errorf("Expected metadata %v, got %v", tt.expected, alert.Metadata)
+			}
+		})
+	}
+}
+
+func TestSchemaDecoder_LogsFirstOfEachSchema(t *testing.T) {
+	logger := &RecordingLogger{}
+	decode := schemaDecoder(alertDecoder(&config.Config{}), logger, nil)
+
+	for _, body := range []string{
+		`{"involvedObject":{"kind":"Kustomization","name":"apps"},"metadata":{"revision":"a"}}`,
+		`{"involvedObject":{"kind":"Kustomization","name":"infra"},"metadata":{"revision":"b"}}`,
+		`{"involvedObject":{"kind":"HelmRelease","name":"podinfo"},"metadata":{"helm.toolkit.fluxcd.io/revision":"1.2.3"}}`,
+	} {
+		var alert types.FluxAlert
+		if err := decode([]byte(body), &alert); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	expected := "Receiving Flux v1 event payloads, first from Kustomization apps\n" +
+		"Receiving Flux v1beta2 event payloads, first from HelmRelease podinfo"
+	if got := strings.Join(logger.lines, "\n"); got != expected {
+		t.Errorf("Expected logs %q, got %q", expected, got)
+	}
+}
+
+func TestCreateWebhookHandler_LegacySchemaRevision(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "events", "flux-v2.1.json"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var sent *types.PushoverMessage
+	handler := CreateWebhookHandler(&HandlerDependencies{
+		Config: &config.Config{PushoverAPIToken: "api-token", BearerToken: "Bearer api-token"},
+		PushoverClient: &MockPushoverClient{
+			SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+				sent = msg
+				return nil
+			},
+		},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(data))
+	req.Header.Set("Authorization", "Bearer api-token")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || sent == nil {
+		t.Fatalf("Expected the alert sent, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(sent.Message, "main@sha1:5394cb7f") {
+		t.Errorf("Expected the prefixed revision in the message, got %q", sent.Message)
+	}
+}
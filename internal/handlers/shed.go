@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// errShed is returned by LoadShedder.Acquire for alerts dropped under pressure
+var errShed = errors.New("alert shed")
+
+// LoadShedder bounds the deliveries sent at once, letting the others wait
+// in two queues: error alerts, which are always admitted first, and the
+// rest. Non-error alerts arriving while highWater alerts wait are shed
+// instead of queued, so that error alerts get through an incident flooding
+// the provider. A nil LoadShedder admits every alert at once.
+type LoadShedder struct {
+	maxConcurrent int
+	highWater     int
+
+	mu     sync.Mutex
+	active int
+	errors []chan struct{} // Waiting error alerts, oldest first
+	others []chan struct{} // Waiting other alerts, oldest first
+
+	depth         *metrics.Gauge
+	shed          *metrics.Counter
+	onDepthChange func() // Called without mu held, nil when unset
+}
+
+// NewLoadShedder creates a shedder sending maxConcurrent deliveries at once
+func NewLoadShedder(maxConcurrent, highWater int, registry *metrics.Registry) *LoadShedder {
+	return &LoadShedder{
+		maxConcurrent: maxConcurrent,
+		highWater:     highWater,
+		depth:         registry.Gauge("delivery_queue_depth", "Alerts waiting for a delivery slot of SHED_MAX_CONCURRENT"),
+		shed:          registry.Counter("alerts_shed_total", "Non-error alerts dropped because SHED_HIGH_WATER alerts waited for delivery"),
+	}
+}
+
+// Acquire waits for a delivery slot for alert and returns its release. It
+// fails with errShed for non-error alerts arriving while highWater alerts
+// wait, and with the context's error when ctx ends first.
+func (s *LoadShedder) Acquire(ctx context.Context, alert *types.FluxAlert) (func(), error) {
+	if s == nil {
+		return func() {}, nil
+	}
+
+	severity, _ := NormalizeSeverity(alert.Severity)
+	isError := severity == types.SeverityError
+
+	s.mu.Lock()
+	if s.active < s.maxConcurrent && s.waiting() == 0 {
+		s.active++
+		s.mu.Unlock()
+		return s.release, nil
+	}
+	if !isError && s.waiting() >= s.highWater {
+		s.mu.Unlock()
+		s.shed.Inc()
+		return nil, errShed
+	}
+
+	ready := make(chan struct{})
+	if isError {
+		s.errors = append(s.errors, ready)
+	} else {
+		s.others = append(s.others, ready)
+	}
+	s.depth.Set(int64(s.waiting()))
+	s.mu.Unlock()
+	s.depthChanged()
+
+	select {
+	case <-ready:
+		return s.release, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		if s.remove(ready) {
+			s.depth.Set(int64(s.waiting()))
+		} else {
+			// The slot was handed over meanwhile, pass it on
+			s.handOver()
+		}
+		s.mu.Unlock()
+		s.depthChanged()
+		return nil, ctx.Err()
+	}
+}
+
+// OnDepthChange registers fn to be called whenever an alert starts or stops
+// waiting for a delivery slot. It must be registered before the first Acquire.
+func (s *LoadShedder) OnDepthChange(fn func()) {
+	if s != nil {
+		s.onDepthChange = fn
+	}
+}
+
+// depthChanged calls the OnDepthChange function, without mu held
+func (s *LoadShedder) depthChanged() {
+	if s.onDepthChange != nil {
+		s.onDepthChange()
+	}
+}
+
+// Waiting returns the number of alerts waiting for a delivery slot
+func (s *LoadShedder) Waiting() int {
+	if s == nil {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waiting()
+}
+
+// Saturated reports whether non-error alerts are being shed, highWater
+// alerts waiting for a delivery slot
+func (s *LoadShedder) Saturated() bool {
+	if s == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waiting() >= s.highWater
+}
+
+// Decision describes shed alerts
+func (s *LoadShedder) Decision() Decision {
+	return Decision{
+		Outcome: OutcomeShed,
+		Rule:    "SHED_HIGH_WATER",
+		Detail:  fmt.Sprintf("%d alerts waiting for delivery, only error alerts are queued", s.highWater),
+	}
+}
+
+// release ends a delivery, handing its slot to the next waiting alert
+func (s *LoadShedder) release() {
+	s.mu.Lock()
+	s.handOver()
+	s.mu.Unlock()
+	s.depthChanged()
+}
+
+// handOver passes a slot to the oldest waiting error alert, else to the
+// oldest other alert, else frees it
+func (s *LoadShedder) handOver() {
+	var next chan struct{}
+	switch {
+	case len(s.errors) > 0:
+		next, s.errors = s.errors[0], s.errors[1:]
+	case len(s.others) > 0:
+		next, s.others = s.others[0], s.others[1:]
+	default:
+		s.active--
+		return
+	}
+	s.depth.Set(int64(s.waiting()))
+	close(next)
+}
+
+// remove takes ready out of its queue, reporting whether it was still waiting
+func (s *LoadShedder) remove(ready chan struct{}) bool {
+	for _, queue := range []*[]chan struct{}{&s.errors, &s.others} {
+		for i, waiting := range *queue {
+			if waiting == ready {
+				*queue = append((*queue)[:i], (*queue)[i+1:]...)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// waiting returns the number of queued alerts, with mu held
+func (s *LoadShedder) waiting() int {
+	return len(s.errors) + len(s.others)
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+func TestLoadShedder_ErrorsFirst(t *testing.T) {
+	shedder := NewLoadShedder(1, 10, nil)
+	release, err := shedder.Acquire(context.Background(), &types.FluxAlert{Severity: "info"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var mu sync.Mutex
+	var admitted []string
+	var wg sync.WaitGroup
+	acquire := func(name, severity string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := shedder.Acquire(context.Background(), &types.FluxAlert{Severity: severity})
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", name, err)
+				return
+			}
+			mu.Lock()
+			admitted = append(admitted, name)
+			mu.Unlock()
+			release()
+		}()
+	}
+
+	// Queued one at a time to fix their order in each queue
+	for i, alert := range []struct{ name, severity string }{
+		{"info-1", "info"}, {"error-1", "error"}, {"warning-1", "warning"}, {"error-2", "ERROR"},
+	} {
+		acquire(alert.name, alert.severity)
+		waitFor(t, func() bool { return shedder.Waiting() == i+1 })
+	}
+
+	release()
+	wg.Wait()
+
+	expected := []string{"error-1", "error-2", "info-1", "warning-1"}
+	if fmt.Sprint(admitted) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, admitted)
+	}
+	if shedder.Waiting() != 0 {
+		t.Errorf("Expected no alerts waiting, got %d", shedder.Waiting())
+	}
+	if release, err := shedder.Acquire(context.Background(), &types.FluxAlert{}); err != nil {
+		t.Errorf("Expected the slot free again, got %v", err)
+	} else {
+		release()
+	}
+}
+
+func TestLoadShedder_Shed(t *testing.T) {
+	registry := metrics.NewRegistry()
+	shedder := NewLoadShedder(1, 1, registry)
+	release, _ := shedder.Acquire(context.Background(), &types.FluxAlert{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waited := make(chan error, 1)
+	go func() {
+		_, err := shedder.Acquire(ctx, &types.FluxAlert{Severity: "warning"})
+		waited <- err
+	}()
+	waitFor(t, func() bool { return shedder.Waiting() == 1 })
+
+	// At the high-water mark only errors are queued
+	if _, err := shedder.Acquire(context.Background(), &types.FluxAlert{Severity: "info"}); !errors.Is(err, errShed) {
+		t.Errorf("Expected the info alert shed, got %v", err)
+	}
+	if got := registry.Counter("alerts_shed_total", "").Value(); got != 1 {
+		t.Errorf("Expected 1 shed alert counted, got %v", got)
+	}
+	errorAdmitted := make(chan struct{})
+	go func() {
+		if release, err := shedder.Acquire(context.Background(), &types.FluxAlert{Severity: "error"}); err == nil {
+			release()
+			close(errorAdmitted)
+		}
+	}()
+	waitFor(t, func() bool { return shedder.Waiting() == 2 })
+
+	// A waiting alert whose request ends leaves the queue
+	cancel()
+	if err := <-waited; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the cancellation, got %v", err)
+	}
+	if shedder.Waiting() != 1 {
+		t.Errorf("Expected the error alert left waiting, got %d", shedder.Waiting())
+	}
+
+	release()
+	<-errorAdmitted
+
+	var nilShedder *LoadShedder
+	if release, err := nilShedder.Acquire(context.Background(), &types.FluxAlert{}); err != nil || nilShedder.Waiting() != 0 {
+		t.Errorf("Expected a nil shedder to admit at once, got %v", err)
+	} else {
+		release()
+	}
+}
+
+func TestCreateWebhookHandler_ShedsUnderFlood(t *testing.T) {
+	const (
+		errorAlerts = 20
+		infoAlerts  = 60
+		highWater   = 5
+	)
+
+	// The slow sender holds every delivery until the flood is queued or shed
+	gate := make(chan struct{})
+	var mu sync.Mutex
+	var sent []string
+	client := &MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			<-gate
+			mu.Lock()
+			defer mu.Unlock()
+			sent = append(sent, msg.Message)
+			return nil
+		},
+	}
+	shedder := NewLoadShedder(1, highWater, nil)
+	handler := CreateWebhookHandler(&HandlerDependencies{
+		Config:         &config.Config{PushoverAPIToken: "token", BearerToken: "Bearer token"},
+		PushoverClient: client,
+		Logger:         &MockLogger{},
+		MessageBuilder: func(alert *types.FluxAlert) string { return alert.Severity + ":" + alert.Message },
+		Shedder:        shedder,
+	})
+
+	post := func(severity string, i int) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"severity":%q,"message":"%d","involvedObject":{"kind":"Kustomization","name":"apps-%d"}}`, severity, i, i)
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer token")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The first delivery takes the only slot
+	first := make(chan *httptest.ResponseRecorder, 1)
+	go func() { first <- post("info", -1) }()
+	waitFor(t, func() bool {
+		shedder.mu.Lock()
+		defer shedder.mu.Unlock()
+		return shedder.active == 1
+	})
+
+	var wg sync.WaitGroup
+	var shed sync.Map
+	results := make([]*httptest.ResponseRecorder, errorAlerts+infoAlerts)
+	for i := range results {
+		severity := "info"
+		if i%4 == 0 {
+			severity = "error"
+		}
+		wg.Add(1)
+		go func(i int, severity string) {
+			defer wg.Done()
+			results[i] = post(severity, i)
+			if strings.Contains(results[i].Body.String(), `"status":"shed"`) {
+				shed.Store(i, true)
+			}
+		}(i, severity)
+	}
+
+	// Wait until each alert of the flood is either queued or shed
+	waitFor(t, func() bool {
+		count := 0
+		shed.Range(func(any, any) bool { count++; return true })
+		return shedder.Waiting()+count == len(results)
+	})
+	close(gate)
+	wg.Wait()
+	<-first
+
+	shedInfo := 0
+	for i, rr := range results {
+		if rr.Code != http.StatusOK {
+			t.Errorf("Alert %d: expected 200, got %d %s", i, rr.Code, rr.Body.String())
+		}
+		if strings.Contains(rr.Body.String(), `"status":"shed"`) {
+			if i%4 == 0 {
+				t.Errorf("Error alert %d was shed", i)
+			}
+			shedInfo++
+		}
+	}
+	if shedInfo == 0 {
+		t.Error("Expected info alerts shed under the flood")
+	}
+
+	// Every error alert was sent, all before the queued info alerts
+	mu.Lock()
+	defer mu.Unlock()
+	errorsSent := 0
+	for i, message := range sent[1:] {
+		if strings.HasPrefix(message, "error:") {
+			errorsSent++
+			if errorsSent != i+1 {
+				t.Errorf("Expected the error alerts sent first, got %v", sent)
+				break
+			}
+		}
+	}
+	if errorsSent != errorAlerts {
+		t.Errorf("Expected all %d error alerts sent, got %d", errorAlerts, errorsSent)
+	}
+	if len(sent) != 1+len(results)-shedInfo {
+		t.Errorf("Expected every alert not shed sent, got %d", len(sent))
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/pushover"
+	"github.com/zhorvath83/flux-provider-pushover/internal/telemetry"
+)
+
+func TestWebhookTracing(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiStatus  int
+		apiBody    string
+		wantStatus int
+		wantError  bool
+	}{
+		{"delivered", http.StatusOK, `{"status":1,"request":"req-123"}`, http.StatusOK, false},
+		{"rejected", http.StatusBadRequest, `{"user":"invalid","status":0,"request":"req-123"}`, http.StatusBadGateway, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := pushover.NewPushoverClient(&MockHTTPClient{
+				DoFunc: func(req *http.Request) (*http.Response, error) {
+					return &http.Response{StatusCode: tt.apiStatus, Body: io.NopCloser(strings.NewReader(tt.apiBody))}, nil
+				},
+			}, "https://api.pushover.net/1/messages.json")
+
+			recorder := telemetry.NewRecorder()
+			deps := &HandlerDependencies{
+				Config: &config.Config{
+					PushoverAPIToken: "test_token",
+					BearerToken:      "Bearer test_token",
+				},
+				PushoverClient: client,
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+				Tracer:         telemetry.NewTracer(recorder),
+			}
+
+			body := `{"involvedObject":{"kind":"Kustomization","namespace":"flux-system","name":"apps"},"severity":"error","message":"failed"}`
+			req := httptest.NewRequest("POST", "/webhook", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer test_token")
+			req.Header.Set(telemetry.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			rr := httptest.NewRecorder()
+			CreateRouter(deps).ServeHTTP(rr, req)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+
+			spans := recorder.Spans()
+			if len(spans) != 2 {
+				t.Fatalf("Expected 2 spans, got %d", len(spans))
+			}
+			clientSpan, serverSpan := spans[0], spans[1]
+
+			if serverSpan.Kind != telemetry.SpanKindServer || serverSpan.Name != "POST /webhook" {
+				t.Errorf("Unexpected server span %q kind %d", serverSpan.Name, serverSpan.Kind)
+			}
+			if serverSpan.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("Expected incoming trace context, got %s", serverSpan.TraceID)
+			}
+			if clientSpan.Kind != telemetry.SpanKindClient || clientSpan.ParentSpanID != serverSpan.SpanID {
+				t.Error("Expected the Pushover call as a client span under the server span")
+			}
+			if clientSpan.Error != tt.wantError {
+				t.Errorf("Expected client span error %v, got %v", tt.wantError, clientSpan.Error)
+			}
+
+			expected := map[string]interface{}{
+				"alert.severity":            "error",
+				"alert.object.kind":         "Kustomization",
+				"alert.object.name":         "apps",
+				"http.response.status_code": int64(tt.apiStatus),
+				"pushover.request_id":       "req-123",
+			}
+			for key, want := range expected {
+				if got, _ := clientSpan.Attribute(key); got != want {
+					t.Errorf("Expected %s=%v, got %v", key, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookTracing_Disabled(t *testing.T) {
+	deps := newIdempotencyTestDeps(nil, func() error { return nil })
+	rr := postAlert(CreateRouter(deps), `{"involvedObject":{"kind":"Kustomization","name":"apps"},"message":"ok"}`, "")
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+}
+
+func TestRouterTracing_SpanNames(t *testing.T) {
+	tests := []struct {
+		method   string
+		path     string
+		wantName string
+	}{
+		{"POST", "/webhook", "POST /webhook"},
+		{"GET", "/health", "GET /health"},
+		{"GET", "/wp-login.php", "GET /"},
+		{"GET", "/debug/pprof/heap", "GET /debug/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			recorder := telemetry.NewRecorder()
+			deps := &HandlerDependencies{
+				Config: &config.Config{},
+				Logger: &MockLogger{},
+				Tracer: telemetry.NewTracer(recorder),
+			}
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			CreateRouter(deps).ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := recorder.Spans()
+			if len(spans) == 0 {
+				t.Fatal("Expected a server span")
+			}
+			server := spans[len(spans)-1]
+			if server.Name != tt.wantName {
+				t.Errorf("Expected span %q, got %q", tt.wantName, server.Name)
+			}
+			if v, _ := server.Attribute("url.path"); v != tt.path {
+				t.Errorf("Expected url.path %q, got %v", tt.path, v)
+			}
+		})
+	}
+}
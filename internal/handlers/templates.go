@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// TemplateData is the value message templates are executed with. Missing
+// fields hold the same placeholders as the built-in message.
+type TemplateData struct {
+	Severity     string // INFO, WARNING or ERROR
+	Reason       string
+	Message      string
+	Controller   string
+	Kind         string // Lower case unless PRESERVE_KIND_CASE is set
+	Name         string
+	Namespace    string
+	Revision     string // Formatted by REVISION_FORMAT
+	Summary      string
+	CommitStatus string
+	Timestamp    string
+	Cluster      string
+	Alert        *types.FluxAlert
+}
+
+// templateFuncs are available to every message template
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// templateFile is the format of MESSAGE_TEMPLATES_FILE, in JSON or YAML
+type templateFile struct {
+	Default   string         `json:"default" yaml:"default"`
+	Templates []templateRule `json:"templates" yaml:"templates"`
+}
+
+// templateRule selects a template by severity and kind, an empty selector
+// matches everything
+type templateRule struct {
+	Severity string `json:"severity" yaml:"severity"`
+	Kind     string `json:"kind" yaml:"kind"`
+	Template string `json:"template" yaml:"template"`
+}
+
+// selectedTemplate is a parsed rule with its normalized selectors
+type selectedTemplate struct {
+	severity    string
+	kind        string
+	specificity int
+	template    *template.Template
+}
+
+// TemplateSet builds messages from the most specific template matching an
+// alert's severity and kind, falling back to the default template or the
+// built-in message
+type TemplateSet struct {
+	rules    []selectedTemplate // Most specific first
+	fallback *template.Template // nil means the built-in message
+	opts     MessageOptions
+	logger   server.Logger
+	failures *metrics.CounterVec
+}
+
+// LoadTemplateSet reads and validates a template file, parsed as YAML for a
+// .yaml or .yml extension and as JSON otherwise
+func LoadTemplateSet(filename string, opts MessageOptions, logger server.Logger, registry *metrics.Registry) (*TemplateSet, error) {
+	data, err := os.ReadFile(filename) //gosec:disable G304 -- path comes from operator configuration.
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message templates: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return ParseYAMLTemplateSet(data, opts, logger, registry)
+	default:
+		return ParseTemplateSet(data, opts, logger, registry)
+	}
+}
+
+// ParseTemplateSet parses and validates a JSON template document
+func ParseTemplateSet(data []byte, opts MessageOptions, logger server.Logger, registry *metrics.Registry) (*TemplateSet, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var file templateFile
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid message templates: %w", err)
+	}
+	return newTemplateSet(file, opts, logger, registry)
+}
+
+// ParseYAMLTemplateSet parses and validates a YAML template document
+func ParseYAMLTemplateSet(data []byte, opts MessageOptions, logger server.Logger, registry *metrics.Registry) (*TemplateSet, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var file templateFile
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid message templates: %w", err)
+	}
+	return newTemplateSet(file, opts, logger, registry)
+}
+
+// newTemplateSet validates a decoded template document. Every template is
+// executed once against a sample alert, and two equally specific rules that
+// can match the same alert are a conflict, since only file order would
+// decide between them.
+func newTemplateSet(file templateFile, opts MessageOptions, logger server.Logger, registry *metrics.Registry) (*TemplateSet, error) {
+	set := &TemplateSet{
+		opts:     opts,
+		logger:   logger,
+		failures: registry.CounterVec("message_template_errors_total", "Message templates that failed to execute, by template", "template"),
+	}
+	if file.Default != "" {
+		fallback, err := parseMessageTemplate("default", file.Default, opts)
+		if err != nil {
+			return nil, err
+		}
+		set.fallback = fallback
+	}
+
+	for i, rule := range file.Templates {
+		name := fmt.Sprintf("templates[%d]", i)
+		severity, kind := normalizeSelector(rule.Severity), normalizeSelector(rule.Kind)
+		for _, pattern := range []string{severity, kind} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("%s: invalid selector %q: %w", name, pattern, err)
+			}
+		}
+
+		specificity := 3*selectorSpecificity(kind) + selectorSpecificity(severity)
+		for previous, other := range set.rules {
+			if other.specificity == specificity && selectorsOverlap(other.severity, severity) && selectorsOverlap(other.kind, kind) {
+				return nil, fmt.Errorf("%s: selector severity=%q kind=%q conflicts with templates[%d] (severity=%q kind=%q)",
+					name, severity, kind, previous, other.severity, other.kind)
+			}
+		}
+
+		parsed, err := parseMessageTemplate(name, rule.Template, opts)
+		if err != nil {
+			return nil, err
+		}
+		set.rules = append(set.rules, selectedTemplate{
+			severity:    severity,
+			kind:        kind,
+			specificity: specificity,
+			template:    parsed,
+		})
+	}
+
+	// Ties keep file order
+	sort.SliceStable(set.rules, func(i, j int) bool {
+		return set.rules[i].specificity > set.rules[j].specificity
+	})
+	return set, nil
+}
+
+// Build renders the message for alert, using the built-in message when the
+// selected template fails. Failures are logged and counted in
+// message_template_errors_total.
+func (s *TemplateSet) Build(alert *types.FluxAlert) string {
+	tmpl := s.fallback
+	severity, _ := NormalizeSeverity(alert.Severity)
+	kind := strings.ToLower(alert.InvolvedObject.Kind)
+	for _, rule := range s.rules {
+		if selectorMatches(rule.severity, severity) && selectorMatches(rule.kind, kind) {
+			tmpl = rule.template
+			break
+		}
+	}
+	if tmpl == nil {
+		return buildMessage(alert, s.opts)
+	}
+
+	var body strings.Builder
+	if s.opts.Prefix != "" {
+		body.WriteString(s.opts.Prefix)
+		body.WriteByte(' ')
+	}
+	if err := tmpl.Execute(&body, newTemplateData(alert, s.opts)); err != nil {
+		s.failures.WithLabelValues(tmpl.Name()).Inc()
+		s.logger.Printf("Message template %s failed for %s/%s, using the built-in message: %v",
+			tmpl.Name(), alert.InvolvedObject.Kind, alert.InvolvedObject.Name, err)
+		return buildMessage(alert, s.opts)
+	}
+	return truncateMessage(body.String(), clusterFooter(s.opts, alert), types.MaxMessageLength)
+}
+
+// parseMessageTemplate parses a template and executes it against a sample
+// alert, so that unknown fields fail at startup
+func parseMessageTemplate(name, text string, opts MessageOptions) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("%s: template is empty", name)
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	sample := &types.FluxAlert{Severity: types.SeverityError, Reason: "Sample", Message: "sample"}
+	if err := tmpl.Execute(&strings.Builder{}, newTemplateData(sample, opts)); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// newTemplateData fills the template fields of alert (pure function)
+func newTemplateData(alert *types.FluxAlert, opts MessageOptions) *TemplateData {
+	kind := defaultIfEmpty(alert.InvolvedObject.Kind, types.DefaultValue)
+	if !opts.PreserveKindCase {
+		kind = strings.ToLower(kind)
+	}
+
+	severity, _ := NormalizeSeverity(alert.Severity)
+	return &TemplateData{
+		Severity:     strings.ToUpper(severity),
+		Reason:       defaultIfEmpty(alert.Reason, types.DefaultValue),
+		Message:      defaultIfEmpty(alert.Message, types.NoMessage),
+		Controller:   defaultIfEmpty(alert.ReportingController, types.DefaultValue),
+		Kind:         kind,
+		Name:         defaultIfEmpty(alert.InvolvedObject.Name, types.DefaultValue),
+		Namespace:    alert.InvolvedObject.Namespace,
+		Revision:     displayRevision(alert, opts),
+		Summary:      alert.Metadata[types.MetadataSummary],
+		CommitStatus: alert.Metadata[types.MetadataCommitStatus],
+		Timestamp:    alert.Timestamp,
+		Cluster:      defaultIfEmpty(alert.Cluster, opts.ClusterName),
+		Alert:        alert,
+	}
+}
+
+// normalizeSelector lower-cases a selector, empty matches everything (pure function)
+func normalizeSelector(selector string) string {
+	selector = strings.ToLower(strings.TrimSpace(selector))
+	if selector == "" {
+		return "*"
+	}
+	return selector
+}
+
+// selectorSpecificity ranks exact selectors above patterns above "*" (pure function)
+func selectorSpecificity(selector string) int {
+	switch {
+	case selector == "*":
+		return 0
+	case strings.ContainsAny(selector, "*?["):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// selectorMatches reports whether a normalized selector matches value (pure function)
+func selectorMatches(selector, value string) bool {
+	matched, err := path.Match(selector, value)
+	return err == nil && matched
+}
+
+// selectorsOverlap reports whether some value matches both normalized
+// selectors (pure function)
+func selectorsOverlap(a, b string) bool {
+	return patternsIntersect(selectorTokens(a), selectorTokens(b))
+}
+
+// selectorTokens splits a valid selector into single character tokens and
+// "*" (pure function)
+func selectorTokens(selector string) []string {
+	var tokens []string
+	for i := 0; i < len(selector); {
+		end := i + 1
+		switch selector[i] {
+		case '\\':
+			end = min(i+2, len(selector))
+		case '[':
+			for end < len(selector) && selector[end] != ']' {
+				if selector[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(selector))
+		}
+		tokens = append(tokens, selector[i:end])
+		i = end
+	}
+	return tokens
+}
+
+// patternsIntersect reports whether some string matches both token lists,
+// "*" standing for any run of characters (pure function)
+func patternsIntersect(a, b []string) bool {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return true
+	case len(a) > 0 && a[0] == "*":
+		return patternsIntersect(a[1:], b) || (len(b) > 0 && patternsIntersect(a, b[1:]))
+	case len(b) > 0 && b[0] == "*":
+		return patternsIntersect(a, b[1:]) || (len(a) > 0 && patternsIntersect(a[1:], b))
+	case len(a) == 0 || len(b) == 0:
+		return false
+	default:
+		return tokensIntersect(a[0], b[0]) && patternsIntersect(a[1:], b[1:])
+	}
+}
+
+// tokensIntersect reports whether a printable ASCII character matches both
+// single character tokens (pure function)
+func tokensIntersect(a, b string) bool {
+	for c := byte(' '); c <= '~'; c++ {
+		if c != '/' && selectorMatches(a, string(c)) && selectorMatches(b, string(c)) {
+			return true
+		}
+	}
+	return false
+}
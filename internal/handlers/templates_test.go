@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+const testTemplates = `{
+	"default": "default: {{.Reason}}",
+	"templates": [
+		{"kind": "GitRepository", "template": "git: {{.Summary}} @ {{.Revision}}"},
+		{"severity": "error", "kind": "HelmRelease", "template": "helm error: {{.Message}} ({{.Revision}})"},
+		{"kind": "helm*", "template": "helm: {{.Name}}"},
+		{"severity": "ERROR", "template": "error: {{.Message}}"},
+		{"severity": "info", "kind": "*", "template": "info: {{.Kind}}/{{.Name}}"}
+	]
+}`
+
+func newTemplateAlert(severity, kind string) *types.FluxAlert {
+	alert := &types.FluxAlert{Severity: severity, Reason: "Progressing", Message: "it happened"}
+	alert.InvolvedObject.Kind = kind
+	alert.InvolvedObject.Name = "podinfo"
+	alert.Metadata = map[string]string{"revision": "6.5.0", "summary": "bump podinfo"}
+	return alert
+}
+
+func TestTemplateSet_Build(t *testing.T) {
+	set, err := ParseTemplateSet([]byte(testTemplates), MessageOptions{}, &MockLogger{}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		alert    *types.FluxAlert
+		expected string
+	}{
+		{"exact kind beats severity", newTemplateAlert("error", "GitRepository"), "git: bump podinfo @ 6.5.0"},
+		{"kind and severity", newTemplateAlert("error", "HelmRelease"), "helm error: it happened (6.5.0)"},
+		{"case-insensitive match", newTemplateAlert("Error", "helmrelease"), "helm error: it happened (6.5.0)"},
+		{"kind wildcard", newTemplateAlert("info", "HelmRelease"), "helm: podinfo"},
+		{"kind wildcard beats severity", newTemplateAlert("error", "HelmRepository"), "helm: podinfo"},
+		{"severity only", newTemplateAlert("error", "Kustomization"), "error: it happened"},
+		{"missing severity is info", newTemplateAlert("", "Kustomization"), "info: kustomization/podinfo"},
+		{"unknown severity is info", newTemplateAlert("trace", "Kustomization"), "info: kustomization/podinfo"},
+		{"fallback", newTemplateAlert("warn", "Kustomization"), "default: Progressing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := set.Build(tt.alert); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTemplateSet_BuiltInFallback(t *testing.T) {
+	opts := MessageOptions{ClusterName: "prod"}
+	set, err := ParseTemplateSet([]byte(`{"templates":[{"kind":"GitRepository","template":"git: {{.Revision}}"}]}`), opts, &MockLogger{}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	alert := newTemplateAlert("info", "Kustomization")
+	if got, expected := set.Build(alert), buildMessage(alert, opts); got != expected {
+		t.Errorf("Expected the built-in message %q, got %q", expected, got)
+	}
+
+	// Templates get the cluster footer and length limit of the built-in message
+	git := newTemplateAlert("info", "GitRepository")
+	git.Metadata[types.MetadataRevision] = strings.Repeat("x", 2*types.MaxMessageLength)
+	got := set.Build(git)
+	if !strings.HasSuffix(got, "\n— cluster: prod") || len([]rune(got)) != types.MaxMessageLength {
+		t.Errorf("Expected truncated message with footer, got %d runes ending %q", len([]rune(got)), got[len(got)-30:])
+	}
+}
+
+func TestTemplateSet_Prefix(t *testing.T) {
+	set, err := ParseTemplateSet([]byte(`{"default":"{{.Reason}}"}`), MessageOptions{Prefix: "[PROD]"}, &MockLogger{}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := set.Build(newTemplateAlert("info", "Kustomization")); got != "[PROD] Progressing" {
+		t.Errorf("Expected prefixed template output, got %q", got)
+	}
+}
+
+func TestParseTemplateSet_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"not json", `default: x`, "invalid message templates"},
+		{"unknown key", `{"templates":[{"kind":"A","tmpl":"x"}]}`, `unknown field "tmpl"`},
+		{"empty template", `{"templates":[{"kind":"A","template":" "}]}`, "templates[0]: template is empty"},
+		{"syntax error", `{"default":"{{.Reason"}`, "default:"},
+		{"unknown field", `{"templates":[{"kind":"A","template":"{{.Chart}}"}]}`, "templates[0]:"},
+		{"bad pattern", `{"templates":[{"kind":"[a","template":"x"}]}`, `templates[0]: invalid selector "[a"`},
+		{"conflict", `{"templates":[{"kind":"HelmRelease","template":"a"},{"kind":"helmrelease","severity":"*","template":"b"}]}`, `templates[1]: selector severity="*" kind="helmrelease" conflicts with templates[0]`},
+		{"overlapping kind patterns", `{"templates":[{"kind":"helm*","template":"a"},{"kind":"*release","template":"b"}]}`, `templates[1]: selector severity="*" kind="*release" conflicts with templates[0] (severity="*" kind="helm*")`},
+		{"overlapping severity patterns", `{"templates":[{"severity":"err*","kind":"A","template":"a"},{"severity":"?rror","kind":"a","template":"b"}]}`, `templates[1]: selector severity="?rror" kind="a" conflicts with templates[0]`},
+		{"overlapping classes", `{"templates":[{"kind":"[gh]elm*","template":"a"},{"kind":"h?lm[a-r]*","template":"b"}]}`, `templates[1]: selector severity="*" kind="h?lm[a-r]*" conflicts with templates[0]`},
+		{"overlapping severities", `{"templates":[{"severity":"*","template":"a"},{"template":"b"}]}`, `templates[1]: selector severity="*" kind="*" conflicts with templates[0]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTemplateSet([]byte(tt.data), MessageOptions{}, &MockLogger{}, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestParseTemplateSet_NoConflict(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"more specific overlap", `{"templates":[{"kind":"HelmRelease","template":"a"},{"kind":"helm*","template":"b"},{"template":"c"}]}`},
+		{"disjoint patterns", `{"templates":[{"kind":"helm*","template":"a"},{"kind":"git*","template":"b"}]}`},
+		{"disjoint classes", `{"templates":[{"kind":"[a-f]*","template":"a"},{"kind":"[g-z]*","template":"b"}]}`},
+		{"disjoint severities", `{"templates":[{"severity":"error","template":"a"},{"severity":"info","template":"b"}]}`},
+		{"length mismatch", `{"templates":[{"kind":"?","template":"a"},{"kind":"??","template":"b"}]}`},
+		{"escaped wildcard", `{"templates":[{"kind":"a\\*","template":"a"},{"kind":"a?b","template":"b"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseTemplateSet([]byte(tt.data), MessageOptions{}, &MockLogger{}, nil); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseYAMLTemplateSet(t *testing.T) {
+	const data = `
+default: "default: {{.Reason}}"
+templates:
+  - kind: GitRepository
+    template: |-
+      git: {{.Summary}}
+      {{.Revision}}
+  - kind: HelmRelease
+    severity: error
+    template: "helm error: {{.Message}}"
+`
+	set, err := ParseYAMLTemplateSet([]byte(data), MessageOptions{}, &MockLogger{}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := set.Build(newTemplateAlert("info", "GitRepository")); got != "git: bump podinfo\n6.5.0" {
+		t.Errorf("Expected git template, got %q", got)
+	}
+	if got := set.Build(newTemplateAlert("error", "HelmRelease")); got != "helm error: it happened" {
+		t.Errorf("Expected helm template, got %q", got)
+	}
+	if got := set.Build(newTemplateAlert("info", "Kustomization")); got != "default: Progressing" {
+		t.Errorf("Expected default template, got %q", got)
+	}
+
+	for _, invalid := range []string{"templates: [", "templates:\n  - kind: A\n    tmpl: x\n", "templates:\n  - kind: helm*\n    template: a\n  - kind: '*release'\n    template: b\n"} {
+		if _, err := ParseYAMLTemplateSet([]byte(invalid), MessageOptions{}, &MockLogger{}, nil); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestTemplateSet_ExecutionError(t *testing.T) {
+	// The sample alert is an error, so the broken branch passes validation
+	data := `{"templates":[{"kind":"GitRepository","template":"{{if eq .Severity \"INFO\"}}{{.Alert.Chart}}{{end}}git"}]}`
+	logger := &RecordingLogger{}
+	registry := metrics.NewRegistry()
+	set, err := ParseTemplateSet([]byte(data), MessageOptions{}, logger, registry)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	alert := newTemplateAlert("info", "GitRepository")
+	if got, expected := set.Build(alert), buildMessage(alert, MessageOptions{}); got != expected {
+		t.Errorf("Expected the built-in message %q, got %q", expected, got)
+	}
+	if len(logger.lines) != 1 || !strings.Contains(logger.lines[0], "Message template templates[0] failed for GitRepository/podinfo") {
+		t.Errorf("Expected the failure logged, got %v", logger.lines)
+	}
+	if count := registry.CounterVec("message_template_errors_total", "", "template").WithLabelValues("templates[0]").Value(); count != 1 {
+		t.Errorf("Expected 1 template error, got %v", count)
+	}
+}
+
+func TestCreateServerDependencies_Templates(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "templates.json")
+	if err := os.WriteFile(valid, []byte(testTemplates), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	yamlFile := filepath.Join(dir, "templates.yaml")
+	if err := os.WriteFile(yamlFile, []byte("templates:\n  - kind: GitRepository\n    template: 'yaml: {{.Summary}}'\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"default":"{{.Nope}}"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{PushoverUserKey: "user", PushoverAPIToken: "token", TemplatesFile: valid}
+	deps, err := CreateServerDependencies(context.Background(), cfg, &MockLogger{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := deps.MessageBuilder(newTemplateAlert("error", "GitRepository")); got != "git: bump podinfo @ 6.5.0" {
+		t.Errorf("Expected templated message, got %q", got)
+	}
+
+	cfg.TemplatesFile = yamlFile
+	deps, err = CreateServerDependencies(context.Background(), cfg, &MockLogger{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := deps.MessageBuilder(newTemplateAlert("error", "GitRepository")); got != "yaml: bump podinfo" {
+		t.Errorf("Expected YAML templated message, got %q", got)
+	}
+
+	for _, file := range []string{invalid, filepath.Join(dir, "missing.json")} {
+		cfg.TemplatesFile = file
+		if _, err := CreateServerDependencies(context.Background(), cfg, &MockLogger{}); err == nil {
+			t.Errorf("Expected startup to fail for %s", filepath.Base(file))
+		}
+	}
+}
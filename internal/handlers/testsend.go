@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/pushover"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// TestNotificationMessage is the body of the notification sent by the test endpoint
+const TestNotificationMessage = "Test notification from flux-provider-pushover"
+
+// testSendResponse is the body of a successful test send
+type testSendResponse struct {
+	Status  string `json:"status"`
+	Request string `json:"request,omitempty"` // Pushover's request id
+}
+
+// CreateTestHandler creates a handler sending a fixed info notification
+// through the configured providers, confirming the wiring end to end without
+// crafting a Flux payload. It authenticates like the webhook and sends
+// nothing in test mode.
+func CreateTestHandler(deps *HandlerDependencies) http.HandlerFunc {
+	notifier := deps.notifier()
+	auth := NewAuthenticator(deps.Config, deps.Metrics)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSONResponse(w, http.StatusMethodNotAllowed, types.ResponseMethodNotAllowed)
+			return
+		}
+		if _, ok := auth.Authenticate(r); !ok {
+			deps.Logger.Printf("Unauthorized test request from %s", r.RemoteAddr)
+			writeJSONResponse(w, http.StatusUnauthorized, types.ResponseUnauthorized)
+			return
+		}
+
+		if deps.Config.PushoverAPIToken == "test_api_token" {
+			deps.Logger.Println("Test mode: not sending the test notification to Pushover")
+			writeJSONResponse(w, http.StatusOK, decisionResponse(Decision{Outcome: OutcomeTestMode}))
+			return
+		}
+
+		alert := &types.FluxAlert{
+			Severity:            types.SeverityInfo,
+			Reason:              "Test",
+			Message:             TestNotificationMessage,
+			ReportingController: "flux-provider-pushover",
+		}
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), deps.Config.SendTimeout())
+		defer cancel()
+		ctx, pushoverIDs := pushover.WithRequestIDs(ctx)
+
+		if _, err := notifier.Send(ctx, CreateNotification(alert, TestNotificationMessage)); err != nil {
+			logFailure(deps, "test send", err, "Failed to send the test notification: %v", err)
+			status, code := sendFailure(err)
+			writeJSONResponse(w, status, sendErrorResponse(code, err))
+			return
+		}
+
+		deps.Logger.Printf("Sent the test notification requested by %s", r.RemoteAddr)
+		body, err := json.Marshal(testSendResponse{Status: "ok", Request: pushoverIDs.Last()})
+		if err != nil {
+			writeJSONResponse(w, http.StatusInternalServerError, types.ResponseInternalError)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, body)
+	}
+}
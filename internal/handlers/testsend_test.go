@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/pushover"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+func TestCreateTestHandler(t *testing.T) {
+	respond := func(status int, body string) PushoverSender {
+		return pushover.NewPushoverClient(&MockHTTPClient{
+			DoFunc: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}, nil
+			},
+		}, "http://pushover.test/1/messages.json")
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		authorization  string
+		apiToken       string
+		client         PushoverSender
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "sent",
+			method:         http.MethodPost,
+			authorization:  "Bearer webhook_token",
+			client:         respond(http.StatusOK, `{"status":1,"request":"pushover-1"}`),
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"ok","request":"pushover-1"}`,
+		},
+		{
+			name:           "rejected by Pushover",
+			method:         http.MethodPost,
+			authorization:  "Bearer webhook_token",
+			client:         respond(http.StatusBadRequest, `{"status":0,"errors":["user identifier is invalid"],"request":"pushover-2"}`),
+			expectedStatus: http.StatusBadGateway,
+			expectedBody:   `{"error":"Failed to send to Pushover","code":"api_rejected","details":"pushover API returned status 400: user identifier is invalid","errors":["user identifier is invalid"],"request":"pushover-2"}`,
+		},
+		{
+			name:          "send failed",
+			method:        http.MethodPost,
+			authorization: "Bearer webhook_token",
+			client: &MockPushoverClient{SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+				return errors.New("connection reset")
+			}},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"Failed to send to Pushover","code":"send_failed","details":"connection reset"}`,
+		},
+		{
+			name:           "test mode",
+			method:         http.MethodPost,
+			authorization:  "Bearer webhook_token",
+			apiToken:       "test_api_token",
+			client:         &MockPushoverClient{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"test_mode"}`,
+		},
+		{
+			name:           "unauthorized",
+			method:         http.MethodPost,
+			authorization:  "Bearer api_token",
+			client:         &MockPushoverClient{},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   string(types.ResponseUnauthorized),
+		},
+		{
+			name:           "wrong method",
+			method:         http.MethodGet,
+			authorization:  "Bearer webhook_token",
+			client:         &MockPushoverClient{},
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedBody:   string(types.ResponseMethodNotAllowed),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Cases with a bare mock must not send
+			var sent []*types.PushoverMessage
+			client := tt.client
+			if mock, ok := client.(*MockPushoverClient); ok && mock.SendMessageFunc == nil {
+				mock.SendMessageFunc = func(ctx context.Context, msg *types.PushoverMessage) error {
+					sent = append(sent, msg)
+					return nil
+				}
+			}
+			apiToken := tt.apiToken
+			if apiToken == "" {
+				apiToken = "api_token"
+			}
+			handler := CreateTestHandler(&HandlerDependencies{
+				Config:         &config.Config{PushoverAPIToken: apiToken, PushoverUserKey: "user", WebhookToken: "webhook_token", BearerToken: "Bearer webhook_token"},
+				PushoverClient: client,
+				Logger:         &MockLogger{},
+				MessageBuilder: BuildPushoverMessage,
+			})
+
+			req := httptest.NewRequest(tt.method, "/test", nil)
+			req.Header.Set("Authorization", tt.authorization)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("Expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+			if len(sent) != 0 {
+				t.Errorf("Expected nothing sent, got %d messages", len(sent))
+			}
+		})
+	}
+}
+
+func TestCreateTestHandler_Message(t *testing.T) {
+	var sent *types.PushoverMessage
+	handler := CreateTestHandler(&HandlerDependencies{
+		Config: &config.Config{PushoverAPIToken: "api_token", PushoverUserKey: "user", BearerToken: "Bearer api_token"},
+		PushoverClient: &MockPushoverClient{SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			sent = msg
+			return nil
+		}},
+		Logger:         &MockLogger{},
+		MessageBuilder: BuildPushoverMessage,
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/test", nil)
+	req.Header.Set("Authorization", "Bearer api_token")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || rr.Body.String() != `{"status":"ok"}` {
+		t.Fatalf("Expected 200 without a request id from the mock, got %d %s", rr.Code, rr.Body.String())
+	}
+	if sent == nil || sent.Message != TestNotificationMessage || sent.Token != "api_token" || sent.User != "user" {
+		t.Errorf("Expected the test notification sent with the configured credentials, got %+v", sent)
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// DefaultMaxTransitionObjects bounds the objects a TransitionTracker remembers
+const DefaultMaxTransitionObjects = 10000
+
+// transitionEntry is the last delivered severity of an object
+type transitionEntry struct {
+	uid      string
+	severity string
+	expires  time.Time // Zero never expires
+}
+
+// TransitionTracker remembers the last delivered severity of each object,
+// keyed by its uid, for STATEFUL_NOTIFY. Alerts repeating it are dropped, so
+// that only transitions such as a recovery notify. It is bounded to maxKeys,
+// evicting the least recently delivered object, and forgets objects quiet
+// for ttl. A nil TransitionTracker lets every alert through.
+type TransitionTracker struct {
+	ttl     time.Duration // Zero never forgets
+	maxKeys int
+	clock   clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Front is most recently delivered
+
+	evictions *metrics.Counter
+}
+
+// NewTransitionTracker creates a tracker forgetting objects quiet for ttl
+func NewTransitionTracker(ttl time.Duration, maxKeys int, registry *metrics.Registry) *TransitionTracker {
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxTransitionObjects
+	}
+
+	return &TransitionTracker{
+		ttl:       ttl,
+		maxKeys:   maxKeys,
+		clock:     clock.Real{},
+		entries:   make(map[string]*list.Element),
+		order:     list.New(),
+		evictions: registry.Counter("stateful_notify_evictions_total", "Objects forgotten by STATEFUL_NOTIFY to stay within its bound"),
+	}
+}
+
+// WithClock makes the tracker expire objects on clk
+func (t *TransitionTracker) WithClock(clk clock.Clock) *TransitionTracker {
+	t.clock = clk
+	return t
+}
+
+// ObjectUID identifies the object of alert by its uid, or by kind, namespace
+// and name when Flux sent none (pure function)
+func ObjectUID(alert *types.FluxAlert) string {
+	if alert.InvolvedObject.UID != "" {
+		return alert.InvolvedObject.UID
+	}
+	return emergencyObject(alert)
+}
+
+// Transitions reports whether alert changes its object's last delivered
+// severity and returns that severity, empty when the object is unknown.
+// Successes of unknown objects are no transition, there is nothing they
+// recover from.
+func (t *TransitionTracker) Transitions(alert *types.FluxAlert) (string, bool) {
+	if t == nil {
+		return "", true
+	}
+
+	severity, _ := NormalizeSeverity(alert.Severity)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	last, ok := t.lookup(ObjectUID(alert))
+	if !ok {
+		return "", severity != types.SeverityInfo
+	}
+	return last, severity != last
+}
+
+// Record remembers the severity of a delivered alert
+func (t *TransitionTracker) Record(alert *types.FluxAlert) {
+	if t == nil {
+		return
+	}
+
+	uid := ObjectUID(alert)
+	severity, _ := NormalizeSeverity(alert.Severity)
+	var expires time.Time
+	if t.ttl > 0 {
+		expires = t.clock.Now().Add(t.ttl)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if element, ok := t.entries[uid]; ok {
+		t.order.Remove(element)
+	}
+	t.entries[uid] = t.order.PushFront(&transitionEntry{uid: uid, severity: severity, expires: expires})
+
+	for t.order.Len() > t.maxKeys {
+		oldest := t.order.Back()
+		t.order.Remove(oldest)
+		delete(t.entries, oldest.Value.(*transitionEntry).uid)
+		t.evictions.Inc()
+	}
+}
+
+// Len returns the number of objects remembered
+func (t *TransitionTracker) Len() int {
+	if t == nil {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.order.Len()
+}
+
+// lookup returns the last severity of uid, removing it once expired
+func (t *TransitionTracker) lookup(uid string) (string, bool) {
+	element, ok := t.entries[uid]
+	if !ok {
+		return "", false
+	}
+
+	entry := element.Value.(*transitionEntry)
+	if !entry.expires.IsZero() && !t.clock.Now().Before(entry.expires) {
+		t.order.Remove(element)
+		delete(t.entries, uid)
+		return "", false
+	}
+	return entry.severity, true
+}
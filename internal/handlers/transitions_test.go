@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// transitionAlert returns an alert about the object with uid
+func transitionAlert(uid, severity string) *types.FluxAlert {
+	alert := &types.FluxAlert{Severity: severity, Reason: "ReconciliationSucceeded"}
+	alert.InvolvedObject.Kind = "Kustomization"
+	alert.InvolvedObject.Namespace = "flux-system"
+	alert.InvolvedObject.Name = "apps"
+	alert.InvolvedObject.UID = uid
+	return alert
+}
+
+func TestObjectUID(t *testing.T) {
+	if uid := ObjectUID(transitionAlert("0b6e-uid", "info")); uid != "0b6e-uid" {
+		t.Errorf("Expected the uid, got %s", uid)
+	}
+	if uid := ObjectUID(transitionAlert("", "info")); uid != "Kustomization/flux-system/apps" {
+		t.Errorf("Expected the object without a uid, got %s", uid)
+	}
+}
+
+func TestTransitionTracker(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	tracker := NewTransitionTracker(time.Hour, 10, nil)
+	tracker.clock = fake
+
+	steps := []struct {
+		advance    time.Duration
+		uid        string
+		severity   string
+		transition bool
+	}{
+		{0, "a", "info", false}, // Nothing to recover from
+		{time.Minute, "a", "info", false},
+		{time.Minute, "a", "error", true},
+		{time.Minute, "a", "error", false},
+		{time.Minute, "a", "warning", true},
+		{time.Minute, "a", "info", true}, // Recovery
+		{time.Minute, "a", "info", false},
+		{time.Minute, "b", "error", true}, // Other objects are separate
+		{time.Hour, "b", "error", true},   // Forgotten after an hour
+	}
+
+	for i, step := range steps {
+		fake.Advance(step.advance)
+		alert := transitionAlert(step.uid, step.severity)
+		_, transition := tracker.Transitions(alert)
+		if transition != step.transition {
+			t.Errorf("Step %d: expected transition %v, got %v", i, step.transition, transition)
+		}
+		if transition {
+			tracker.Record(alert)
+		}
+	}
+
+	var nilTracker *TransitionTracker
+	if _, transition := nilTracker.Transitions(transitionAlert("a", "info")); !transition || nilTracker.Len() != 0 {
+		t.Error("Expected a nil tracker to let every alert through")
+	}
+}
+
+func TestTransitionTracker_Bounded(t *testing.T) {
+	registry := metrics.NewRegistry()
+	tracker := NewTransitionTracker(0, 2, registry)
+
+	for _, uid := range []string{"a", "b", "c"} {
+		tracker.Record(transitionAlert(uid, "error"))
+	}
+
+	if tracker.Len() != 2 {
+		t.Errorf("Expected 2 objects, got %d", tracker.Len())
+	}
+	if _, transition := tracker.Transitions(transitionAlert("a", "error")); !transition {
+		t.Error("Expected the least recently delivered object evicted")
+	}
+	if _, transition := tracker.Transitions(transitionAlert("c", "error")); transition {
+		t.Error("Expected the latest object remembered")
+	}
+	if evictions := tracker.evictions.Value(); evictions != 1 {
+		t.Errorf("Expected 1 eviction, got %d", evictions)
+	}
+}
+
+func TestTransitionTracker_Concurrent(t *testing.T) {
+	tracker := NewTransitionTracker(time.Minute, 50, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				alert := transitionAlert(fmt.Sprintf("uid-%d", j%100), []string{"info", "error"}[(i+j)%2])
+				if _, transition := tracker.Transitions(alert); transition {
+					tracker.Record(alert)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if tracker.Len() > 50 {
+		t.Errorf("Expected at most 50 objects, got %d", tracker.Len())
+	}
+}
+
+func TestCreateWebhookHandler_StatefulNotify(t *testing.T) {
+	var sent []string
+	deps := &HandlerDependencies{
+		Config: &config.Config{PushoverAPIToken: "test_token", BearerToken: "Bearer test_token"},
+		PushoverClient: &MockPushoverClient{
+			SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+				sent = append(sent, msg.Message)
+				return nil
+			},
+		},
+		Logger:         &MockLogger{},
+		MessageBuilder: func(alert *types.FluxAlert) string { return alert.Reason },
+		Transitions:    NewTransitionTracker(time.Hour, 10, nil),
+	}
+	handler := CreateWebhookHandler(deps)
+
+	steps := []struct {
+		severity string
+		reason   string
+		expected string // Decision of dropped alerts
+	}{
+		{"info", "ReconciliationSucceeded", `{"status":"unchanged","rule":"STATEFUL_NOTIFY","detail":"success of an object not known to fail"}`},
+		{"info", "ReconciliationSucceeded", `{"status":"unchanged","rule":"STATEFUL_NOTIFY","detail":"success of an object not known to fail"}`},
+		{"error", "HealthCheckFailed", ""},
+		{"error", "HealthCheckFailed", `{"status":"unchanged","rule":"STATEFUL_NOTIFY","detail":"severity still error since the last notification"}`},
+		{"info", "ReconciliationSucceeded", ""},
+		{"info", "ReconciliationSucceeded", `{"status":"unchanged","rule":"STATEFUL_NOTIFY","detail":"severity still info since the last notification"}`},
+	}
+
+	for i, step := range steps {
+		body := fmt.Sprintf(`{"severity":%q,"reason":%q,"involvedObject":{"kind":"Kustomization","namespace":"flux-system","name":"apps","uid":"0b6e-uid"}}`, step.severity, step.reason)
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test_token")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Step %d: expected 200, got %d", i, rr.Code)
+		}
+		if step.expected != "" && strings.TrimSpace(rr.Body.String()) != step.expected {
+			t.Errorf("Step %d: expected %s, got %s", i, step.expected, rr.Body.String())
+		}
+	}
+
+	if fmt.Sprint(sent) != "[HealthCheckFailed ReconciliationSucceeded]" {
+		t.Errorf("Expected the failure and the recovery sent, got %v", sent)
+	}
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// watchdogInterval is how often the watchdog checks the conditions that
+// change without a send, the delivery queue and expiring readiness flaps
+const watchdogInterval = 5 * time.Second
+
+// watchdogPriority is the Pushover priority of self-notifications, high
+// but not emergency so that they never repeat
+const watchdogPriority = 1
+
+// Watchdog notifies about the provider itself being degraded: a streak of
+// failed Pushover sends, the delivery queue staying saturated, or readiness
+// flapping. It wraps the send pipeline to watch outcomes. Entering a
+// degraded episode logs a critical line and sends a single high-priority
+// notification through notifier, which should be the bare API client so
+// that retries and the credential guard cannot hold it back. The episode
+// ends once every condition cleared. A nil Watchdog watches nothing.
+type Watchdog struct {
+	next      PushoverSender
+	notifier  PushoverSender
+	cfg       *config.Config
+	saturated func() bool // Reports whether the delivery queue is saturated
+	logger    server.Logger
+	ctx       context.Context // Cancelled on shutdown, abandoning notifications
+	clock     clock.Clock
+
+	mu             sync.Mutex
+	streak         int         // Consecutive failed sends
+	failing        bool        // Outcome of the latest send, what readiness reports
+	flaps          []time.Time // Readiness changes within the flap window, oldest first
+	saturatedSince time.Time   // Zero while the queue is not saturated
+	degraded       bool
+
+	started  atomic.Bool
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+	sends    sync.WaitGroup
+
+	state    *metrics.Gauge
+	episodes *metrics.Counter
+}
+
+// NewWatchdog wraps next, watching its outcomes and shedder's queue against
+// the WATCHDOG_* thresholds of cfg and notifying through notifier.
+// Cancelling ctx abandons notifications in flight.
+func NewWatchdog(ctx context.Context, cfg *config.Config, next, notifier PushoverSender, shedder *LoadShedder, logger server.Logger, registry *metrics.Registry) *Watchdog {
+	return &Watchdog{
+		next:      next,
+		notifier:  notifier,
+		cfg:       cfg,
+		saturated: shedder.Saturated,
+		logger:    logger,
+		ctx:       ctx,
+		clock:     clock.Real{},
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		state:     registry.Gauge("watchdog_degraded", "Whether the watchdog considers the provider degraded"),
+		episodes:  registry.Counter("watchdog_degraded_episodes_total", "Degraded episodes of the provider, each notified once"),
+	}
+}
+
+// SendMessage sends through the wrapped sender and records the outcome
+func (w *Watchdog) SendMessage(ctx context.Context, msg *types.PushoverMessage) error {
+	err := w.next.SendMessage(ctx, msg)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.clock.Now()
+	if err != nil {
+		w.streak++
+	} else {
+		w.streak = 0
+	}
+	if failing := err != nil; failing != w.failing {
+		w.failing = failing
+		w.flaps = append(w.flaps, now)
+	}
+	w.evaluate(now)
+	return err
+}
+
+// Check evaluates the conditions that change without a send
+func (w *Watchdog) Check() {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.evaluate(w.clock.Now())
+}
+
+// Degraded reports whether a degraded episode is ongoing
+func (w *Watchdog) Degraded() bool {
+	if w == nil {
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.degraded
+}
+
+// evaluate starts or ends a degraded episode, with mu held
+func (w *Watchdog) evaluate(now time.Time) {
+	reasons := w.reasons(now)
+	switch {
+	case len(reasons) > 0 && !w.degraded:
+		w.degraded = true
+		w.state.Set(1)
+		w.episodes.Inc()
+		w.logger.Printf("CRITICAL: watchdog=degraded reasons=%q consecutive_failures=%d readiness_flaps=%d", strings.Join(reasons, "; "), w.streak, len(w.flaps))
+		w.notify("flux-provider-pushover degraded: " + strings.Join(reasons, ", "))
+	case len(reasons) == 0 && w.degraded:
+		w.degraded = false
+		w.state.Set(0)
+		w.logger.Println("Watchdog: recovered, every degradation threshold cleared")
+	}
+}
+
+// reasons describes the exceeded thresholds, with mu held
+func (w *Watchdog) reasons(now time.Time) []string {
+	var reasons []string
+	if limit := w.cfg.WatchdogFailureStreak; limit > 0 && w.streak >= limit {
+		reasons = append(reasons, fmt.Sprintf("%d consecutive send failures", w.streak))
+	}
+
+	if w.saturated() {
+		if w.saturatedSince.IsZero() {
+			w.saturatedSince = now
+		}
+	} else {
+		w.saturatedSince = time.Time{}
+	}
+	if limit := w.cfg.WatchdogSaturation; limit > 0 && !w.saturatedSince.IsZero() && now.Sub(w.saturatedSince) >= limit {
+		reasons = append(reasons, "delivery queue saturated for "+now.Sub(w.saturatedSince).Round(time.Second).String())
+	}
+
+	expired := 0
+	for expired < len(w.flaps) && now.Sub(w.flaps[expired]) >= w.cfg.WatchdogFlapWindow {
+		expired++
+	}
+	w.flaps = w.flaps[expired:]
+	if limit := w.cfg.WatchdogFlaps; limit > 0 && len(w.flaps) >= limit {
+		reasons = append(reasons, fmt.Sprintf("readiness changed %d times in %s", len(w.flaps), w.cfg.WatchdogFlapWindow))
+	}
+	return reasons
+}
+
+// notify sends message in the background, the send that degraded the
+// provider must not wait for it
+func (w *Watchdog) notify(message string) {
+	if w.notifier == nil {
+		return
+	}
+
+	msg := CreatePushoverMessage(w.cfg, message, types.SeverityError)
+	priority := watchdogPriority
+	msg.Priority = &priority
+
+	w.sends.Add(1)
+	go func() {
+		defer w.sends.Done()
+		ctx, cancel := context.WithTimeout(w.ctx, w.cfg.SendTimeout())
+		defer cancel()
+		if err := w.notifier.SendMessage(ctx, msg); err != nil {
+			w.logger.Printf("Failed to send watchdog notification: %v", err)
+		}
+	}()
+}
+
+// Start checks every watchdogInterval in the background until Stop or ctx
+// is cancelled
+func (w *Watchdog) Start(ctx context.Context) {
+	if w == nil || w.started.Swap(true) {
+		return
+	}
+	go func() {
+		defer close(w.done)
+		for {
+			select {
+			case <-w.clock.After(watchdogInterval):
+				w.Check()
+			case <-w.stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends checking and waits for pending notifications
+func (w *Watchdog) Stop(ctx context.Context) error {
+	if w == nil {
+		return nil
+	}
+	w.stopOnce.Do(func() { close(w.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		if w.started.Load() {
+			<-w.done
+		}
+		w.sends.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// SelfAlerts records the notifications of a Watchdog
+type SelfAlerts struct {
+	mu       sync.Mutex
+	messages []*types.PushoverMessage
+}
+
+func (s *SelfAlerts) SendMessage(ctx context.Context, msg *types.PushoverMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+func (s *SelfAlerts) Messages() []*types.PushoverMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.messages
+}
+
+func newTestWatchdog(cfg *config.Config, fail *bool, registry *metrics.Registry) (*Watchdog, *SelfAlerts, *RecordingLogger, *clock.Fake) {
+	next := &MockPushoverClient{
+		SendMessageFunc: func(ctx context.Context, msg *types.PushoverMessage) error {
+			if *fail {
+				return errors.New("pushover unavailable")
+			}
+			return nil
+		},
+	}
+	alerts := &SelfAlerts{}
+	logger := &RecordingLogger{}
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	watchdog := NewWatchdog(context.Background(), cfg, next, alerts, nil, logger, registry)
+	watchdog.clock = clk
+	return watchdog, alerts, logger, clk
+}
+
+func sendTimes(t *testing.T, watchdog *Watchdog, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		_ = watchdog.SendMessage(context.Background(), &types.PushoverMessage{Message: "alert"})
+	}
+}
+
+func TestWatchdog_OneSelfAlertPerFailureEpisode(t *testing.T) {
+	cfg := &config.Config{PushoverAPIToken: "token", PushoverUserKey: "user", WatchdogFailureStreak: 25}
+	registry := metrics.NewRegistry()
+	fail := true
+	watchdog, alerts, logger, _ := newTestWatchdog(cfg, &fail, registry)
+
+	sendTimes(t, watchdog, 24)
+	if watchdog.Degraded() {
+		t.Fatal("Expected 24 failures to stay below the threshold")
+	}
+
+	// The streak keeps growing, but the episode is notified once
+	sendTimes(t, watchdog, 40)
+	if err := watchdog.Stop(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	messages := alerts.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("Expected exactly one self-alert, got %d", len(messages))
+	}
+	if messages[0].Message != "flux-provider-pushover degraded: 25 consecutive send failures" {
+		t.Errorf("Unexpected self-alert %q", messages[0].Message)
+	}
+	if messages[0].Priority == nil || *messages[0].Priority != 1 || messages[0].Token != "token" {
+		t.Errorf("Expected a high-priority self-alert with the configured token, got %+v", messages[0])
+	}
+	if len(logger.lines) != 1 || !strings.HasPrefix(logger.lines[0], `CRITICAL: watchdog=degraded reasons="25 consecutive send failures" consecutive_failures=25`) {
+		t.Errorf("Expected one critical log line, got %q", logger.lines)
+	}
+
+	// A successful send ends the episode, the next streak is a new one
+	fail = false
+	sendTimes(t, watchdog, 1)
+	if watchdog.Degraded() {
+		t.Fatal("Expected a successful send to end the episode")
+	}
+	fail = true
+	sendTimes(t, watchdog, 30)
+	if err := watchdog.Stop(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(alerts.Messages()) != 2 {
+		t.Errorf("Expected a second self-alert for the second episode, got %d", len(alerts.Messages()))
+	}
+
+	snapshot := registry.Snapshot()
+	if snapshot["watchdog_degraded_episodes_total"] != uint64(2) || snapshot["watchdog_degraded"] != int64(1) {
+		t.Errorf("Unexpected watchdog metrics %v %v", snapshot["watchdog_degraded_episodes_total"], snapshot["watchdog_degraded"])
+	}
+}
+
+func TestWatchdog_Saturation(t *testing.T) {
+	cfg := &config.Config{WatchdogSaturation: time.Minute}
+	fail := false
+	watchdog, alerts, _, clk := newTestWatchdog(cfg, &fail, nil)
+	saturated := true
+	watchdog.saturated = func() bool { return saturated }
+
+	watchdog.Check()
+	clk.Advance(59 * time.Second)
+	watchdog.Check()
+	if watchdog.Degraded() {
+		t.Fatal("Expected a queue saturated for 59s to stay below the threshold")
+	}
+
+	for i := 0; i < 3; i++ {
+		clk.Advance(time.Minute)
+		watchdog.Check()
+	}
+	if err := watchdog.Stop(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if messages := alerts.Messages(); len(messages) != 1 || messages[0].Message != "flux-provider-pushover degraded: delivery queue saturated for 1m59s" {
+		t.Fatalf("Expected one saturation self-alert, got %d", len(messages))
+	}
+
+	// Draining the queue restarts the clock
+	saturated = false
+	watchdog.Check()
+	saturated = true
+	watchdog.Check()
+	if watchdog.Degraded() {
+		t.Error("Expected the episode to end once the queue drained")
+	}
+}
+
+func TestWatchdog_ReadinessFlaps(t *testing.T) {
+	cfg := &config.Config{WatchdogFlaps: 4, WatchdogFlapWindow: 10 * time.Minute}
+	fail := false
+	watchdog, alerts, _, clk := newTestWatchdog(cfg, &fail, nil)
+
+	// Alternating outcomes flip readiness on every send
+	for i := 0; i < 8; i++ {
+		fail = i%2 == 0
+		sendTimes(t, watchdog, 1)
+		clk.Advance(time.Second)
+	}
+	if err := watchdog.Stop(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if messages := alerts.Messages(); len(messages) != 1 || !strings.Contains(messages[0].Message, "readiness changed 4 times in 10m0s") {
+		t.Fatalf("Expected one flapping self-alert, got %d", len(messages))
+	}
+
+	// The episode ends once the flaps left the window
+	clk.Advance(10 * time.Minute)
+	watchdog.Check()
+	if watchdog.Degraded() {
+		t.Error("Expected the episode to end once the flaps expired")
+	}
+}
+
+func TestWatchdog_StartChecksPeriodically(t *testing.T) {
+	cfg := &config.Config{WatchdogSaturation: time.Second}
+	fail := false
+	watchdog, alerts, _, clk := newTestWatchdog(cfg, &fail, nil)
+	watchdog.saturated = func() bool { return true }
+
+	watchdog.Start(context.Background())
+	for i := 0; i < 2; i++ {
+		waitFor(t, func() bool { return clk.Waiters() == 1 })
+		clk.Advance(watchdogInterval)
+	}
+	waitFor(t, watchdog.Degraded)
+	if err := watchdog.Stop(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(alerts.Messages()) != 1 {
+		t.Errorf("Expected one self-alert, got %d", len(alerts.Messages()))
+	}
+}
+
+func TestWatchdog_Nil(t *testing.T) {
+	var watchdog *Watchdog
+	watchdog.Start(context.Background())
+	watchdog.Check()
+	if watchdog.Degraded() {
+		t.Error("Expected nil watchdog never to be degraded")
+	}
+	if err := watchdog.Stop(context.Background()); err != nil {
+		t.Errorf("Expected nil watchdog to stop cleanly, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package idempotency
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+)
+
+// Cache defaults
+const (
+	DefaultTTL        = 10 * time.Minute
+	DefaultMaxEntries = 10000
+	DefaultWait       = 5 * time.Second
+)
+
+// ErrInFlight is returned when a request with the same key is still being
+// processed and did not finish within the wait time
+var ErrInFlight = errors.New("request with the same idempotency key is in progress")
+
+// Response is a completed response kept for replays
+type Response struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// cacheEntry is a completed key in the LRU list
+type cacheEntry struct {
+	key      string
+	response *Response
+	expires  time.Time
+}
+
+// Cache remembers completed responses by idempotency key. It is bounded to
+// maxEntries, evicting the least recently used key, and tracks keys that are
+// in flight so that concurrent replays never run twice.
+type Cache struct {
+	ttl        time.Duration
+	maxEntries int
+	wait       time.Duration
+	clock      clock.Clock
+
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	order    *list.List // Front is most recently used
+	inflight map[string]chan struct{}
+
+	hits      *metrics.Counter
+	conflicts *metrics.Counter
+}
+
+// NewCache creates a cache keeping responses for ttl
+func NewCache(ttl time.Duration, maxEntries int, registry *metrics.Registry) *Cache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+
+	return &Cache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		wait:       DefaultWait,
+		clock:      clock.Real{},
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		inflight:   make(map[string]chan struct{}),
+		hits:       registry.Counter("idempotency_hits_total", "Webhooks answered from the idempotency cache"),
+		conflicts:  registry.Counter("idempotency_conflicts_total", "Webhooks rejected because the same key was in flight"),
+	}
+}
+
+// WithClock makes the cache expire responses on clk
+func (c *Cache) WithClock(clk clock.Clock) *Cache {
+	c.clock = clk
+	return c
+}
+
+// WithWait overrides how long a replay waits for an in-flight request
+func (c *Cache) WithWait(wait time.Duration) *Cache {
+	c.wait = wait
+	return c
+}
+
+// Begin returns the cached response for key if there is one. Otherwise it
+// claims key and returns nil, and the caller must call Complete. A key that
+// is in flight is waited for, up to the wait time, and ErrInFlight is
+// returned if it does not complete.
+func (c *Cache) Begin(ctx context.Context, key string) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.wait)
+	defer cancel()
+
+	for {
+		c.mu.Lock()
+		if response := c.lookup(key); response != nil {
+			c.mu.Unlock()
+			c.hits.Inc()
+			return response, nil
+		}
+
+		done, busy := c.inflight[key]
+		if !busy {
+			c.inflight[key] = make(chan struct{})
+			c.mu.Unlock()
+			return nil, nil
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-done:
+			// Completed or released, look again
+		case <-ctx.Done():
+			c.conflicts.Inc()
+			return nil, ErrInFlight
+		}
+	}
+}
+
+// Complete stores response for a key claimed by Begin and wakes up waiting
+// replays. A nil response releases the key without caching, so that the next
+// request with it is processed again.
+func (c *Cache) Complete(key string, response *Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if done, ok := c.inflight[key]; ok {
+		close(done)
+		delete(c.inflight, key)
+	}
+
+	if response == nil || c.ttl <= 0 {
+		return
+	}
+
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, response: response, expires: c.clock.Now().Add(c.ttl)})
+
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Len returns the number of cached responses
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// lookup returns the live response for key, caller must hold the lock
+func (c *Cache) lookup(key string) *Response {
+	element, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !c.clock.Now().Before(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil
+	}
+
+	c.order.MoveToFront(element)
+	return entry.response
+}
+
+// Cacheable reports whether a response status may be replayed. Failures are
+// not cached so that retries can succeed (pure function).
+func Cacheable(status int) bool {
+	return status >= http.StatusOK && status < http.StatusMultipleChoices
+}
@@ -0,0 +1,187 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+)
+
+func TestCache_ReplaysCompletedResponse(t *testing.T) {
+	registry := metrics.NewRegistry()
+	cache := NewCache(time.Minute, 10, registry)
+	ctx := context.Background()
+
+	response, err := cache.Begin(ctx, "key")
+	if err != nil || response != nil {
+		t.Fatalf("Expected first request to claim the key, got %v %v", response, err)
+	}
+
+	cache.Complete("key", &Response{Status: http.StatusOK, Body: []byte("ok")})
+
+	response, err = cache.Begin(ctx, "key")
+	if err != nil || response == nil || string(response.Body) != "ok" {
+		t.Fatalf("Expected cached response, got %v %v", response, err)
+	}
+
+	if hits := registry.Counter("idempotency_hits_total", "").Value(); hits != 1 {
+		t.Errorf("Expected 1 hit, got %d", hits)
+	}
+}
+
+func TestCache_Expiry(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	cache := NewCache(time.Minute, 10, metrics.NewRegistry())
+	cache.clock = fake
+	ctx := context.Background()
+
+	if _, err := cache.Begin(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cache.Complete("key", &Response{Status: http.StatusOK})
+
+	fake.Advance(time.Minute)
+	response, err := cache.Begin(ctx, "key")
+	if err != nil || response != nil {
+		t.Errorf("Expected expired key to be claimable, got %v %v", response, err)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Expected expired entry to be removed, got %d", cache.Len())
+	}
+}
+
+func TestCache_SizeBounded(t *testing.T) {
+	cache := NewCache(time.Minute, 3, metrics.NewRegistry())
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if _, err := cache.Begin(ctx, key); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		cache.Complete(key, &Response{Status: http.StatusOK})
+
+		// Keep key-0 recently used
+		if i > 0 {
+			if response, _ := cache.Begin(ctx, "key-0"); response == nil {
+				t.Fatalf("Expected key-0 to stay cached at step %d", i)
+			}
+		}
+	}
+
+	if cache.Len() != 3 {
+		t.Errorf("Expected 3 entries, got %d", cache.Len())
+	}
+
+	// key-1 and key-2 were least recently used
+	for key, cached := range map[string]bool{"key-0": true, "key-1": false, "key-2": false, "key-4": true} {
+		response, _ := cache.Begin(ctx, key)
+		if (response != nil) != cached {
+			t.Errorf("%s: expected cached %v", key, cached)
+		}
+		if response == nil {
+			cache.Complete(key, nil)
+		}
+	}
+}
+
+func TestCache_ReleasedKeyIsProcessedAgain(t *testing.T) {
+	cache := NewCache(time.Minute, 10, metrics.NewRegistry())
+	ctx := context.Background()
+
+	if _, err := cache.Begin(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cache.Complete("key", nil)
+
+	response, err := cache.Begin(ctx, "key")
+	if err != nil || response != nil {
+		t.Errorf("Expected released key to be claimable, got %v %v", response, err)
+	}
+}
+
+func TestCache_WaitsForInFlight(t *testing.T) {
+	cache := NewCache(time.Minute, 10, metrics.NewRegistry())
+	ctx := context.Background()
+
+	if _, err := cache.Begin(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	result := make(chan *Response)
+	go func() {
+		response, err := cache.Begin(ctx, "key")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		result <- response
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cache.Complete("key", &Response{Status: http.StatusOK, Body: []byte("first")})
+
+	if response := <-result; response == nil || string(response.Body) != "first" {
+		t.Errorf("Expected waiting request to get the first response, got %v", response)
+	}
+}
+
+func TestCache_InFlightTimeout(t *testing.T) {
+	registry := metrics.NewRegistry()
+	cache := NewCache(time.Minute, 10, registry).WithWait(10 * time.Millisecond)
+	ctx := context.Background()
+
+	if _, err := cache.Begin(ctx, "key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := cache.Begin(ctx, "key"); !errors.Is(err, ErrInFlight) {
+		t.Errorf("Expected ErrInFlight, got %v", err)
+	}
+
+	if conflicts := registry.Counter("idempotency_conflicts_total", "").Value(); conflicts != 1 {
+		t.Errorf("Expected 1 conflict, got %d", conflicts)
+	}
+}
+
+func TestCache_ConcurrentClaims(t *testing.T) {
+	cache := NewCache(time.Minute, 10, metrics.NewRegistry())
+
+	var claims atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := cache.Begin(context.Background(), "key")
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			if response == nil {
+				claims.Add(1)
+				time.Sleep(time.Millisecond)
+				cache.Complete("key", &Response{Status: http.StatusOK})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if claims.Load() != 1 {
+		t.Errorf("Expected exactly one claim, got %d", claims.Load())
+	}
+}
+
+func TestCacheable(t *testing.T) {
+	for status, expected := range map[int]bool{200: true, 204: true, 400: false, 409: false, 500: false} {
+		if Cacheable(status) != expected {
+			t.Errorf("Cacheable(%d) = %v, want %v", status, !expected, expected)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+)
+
+// Default leader election settings, matching client-go's recommendations
+const (
+	DefaultLeaseName     = "flux-provider-pushover"
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+)
+
+// LeaderStatus describes the replica's view of the election
+type LeaderStatus struct {
+	Enabled       bool   `json:"enabled"`
+	IsLeader      bool   `json:"is_leader"`
+	Identity      string `json:"identity,omitempty"`
+	Leader        string `json:"leader,omitempty"`
+	LeaderAddress string `json:"leader_address,omitempty"`
+	Lease         string `json:"lease,omitempty"`
+}
+
+// LeaderElector decides which replica delivers notifications
+type LeaderElector interface {
+	Start()
+	Stop(ctx context.Context) error
+	IsLeader() bool
+	Status() LeaderStatus
+}
+
+// StandaloneElector is used when leader election is disabled, the only replica always leads
+type StandaloneElector struct{}
+
+// Start does nothing
+func (StandaloneElector) Start() {}
+
+// Stop does nothing
+func (StandaloneElector) Stop(ctx context.Context) error {
+	return nil
+}
+
+// IsLeader always returns true
+func (StandaloneElector) IsLeader() bool {
+	return true
+}
+
+// Status reports leader election as disabled
+func (StandaloneElector) Status() LeaderStatus {
+	return LeaderStatus{IsLeader: true}
+}
+
+// ElectorOptions configures a LeaseElector
+type ElectorOptions struct {
+	LeaseName     string
+	Identity      string // Unique per replica, usually the pod name
+	Address       string // URL other replicas use to reach this one, empty disables proxying to it
+	LeaseDuration time.Duration
+	RetryPeriod   time.Duration
+}
+
+// LeaseElector elects a leader through a coordination.k8s.io/v1 Lease
+type LeaseElector struct {
+	client LeaseClient
+	opts   ElectorOptions
+	logger server.Logger
+	clock  clock.Clock
+
+	mu         sync.RWMutex
+	leader     bool
+	observed   *LeaseRecord
+	observedAt time.Time
+	renewedAt  time.Time
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewLeaseElector creates an elector, applying defaults for unset options
+func NewLeaseElector(client LeaseClient, opts ElectorOptions, logger server.Logger) *LeaseElector {
+	if opts.LeaseName == "" {
+		opts.LeaseName = DefaultLeaseName
+	}
+	if opts.LeaseDuration <= 0 {
+		opts.LeaseDuration = DefaultLeaseDuration
+	}
+	if opts.RetryPeriod <= 0 {
+		opts.RetryPeriod = DefaultRetryPeriod
+	}
+
+	return &LeaseElector{
+		client: client,
+		opts:   opts,
+		logger: logger,
+		clock:  clock.Real{},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// NewElector returns a Lease based elector, or a StandaloneElector when
+// disabled or not running inside a cluster
+func NewElector(enabled bool, getEnv func(string) string, saDir string, opts ElectorOptions, logger server.Logger) LeaderElector {
+	if !enabled {
+		return StandaloneElector{}
+	}
+
+	client, err := NewInClusterClient(getEnv, saDir)
+	if err != nil {
+		logger.Printf("Leader election disabled: %v", err)
+		return StandaloneElector{}
+	}
+
+	if opts.Identity == "" {
+		opts.Identity = client.pod
+	}
+
+	return NewLeaseElector(client, opts, logger)
+}
+
+// Start runs the election loop in the background
+func (e *LeaseElector) Start() {
+	e.startOnce.Do(func() {
+		go e.run()
+	})
+}
+
+// Stop ends the election loop and releases the Lease if held, so that
+// another replica can take over without waiting for it to expire
+func (e *LeaseElector) Stop(ctx context.Context) error {
+	started := true
+	e.startOnce.Do(func() {
+		started = false
+	})
+
+	e.stopOnce.Do(func() {
+		close(e.stop)
+	})
+
+	if started {
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return e.release(ctx)
+}
+
+// IsLeader reports whether this replica currently holds the Lease
+func (e *LeaseElector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// Status returns the current election state
+func (e *LeaseElector) Status() LeaderStatus {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	status := LeaderStatus{
+		Enabled:  true,
+		IsLeader: e.leader,
+		Identity: e.opts.Identity,
+		Lease:    e.opts.LeaseName,
+	}
+	if e.observed != nil && e.observed.HolderIdentity != "" {
+		status.Leader = e.observed.HolderIdentity
+		status.LeaderAddress = e.observed.HolderAddress
+	}
+	return status
+}
+
+// run acquires or renews the Lease every retry period until stopped
+func (e *LeaseElector) run() {
+	defer close(e.done)
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), e.opts.RetryPeriod)
+		e.tryAcquireOrRenew(ctx)
+		cancel()
+
+		select {
+		case <-e.stop:
+			return
+		case <-e.clock.After(e.opts.RetryPeriod):
+		}
+	}
+}
+
+// tryAcquireOrRenew takes the Lease if it is free, expired or already ours
+func (e *LeaseElector) tryAcquireOrRenew(ctx context.Context) {
+	now := e.clock.Now()
+
+	record, err := e.client.GetLease(ctx, e.opts.LeaseName)
+	if errors.Is(err, ErrLeaseNotFound) {
+		created, err := e.client.CreateLease(ctx, e.opts.LeaseName, e.newRecord(nil, now))
+		if err != nil {
+			e.renewFailed(now, err)
+			return
+		}
+		e.observe(created, now, true)
+		return
+	}
+	if err != nil {
+		e.renewFailed(now, err)
+		return
+	}
+
+	e.observe(record, now, false)
+	if e.heldByOther(record, now) {
+		return
+	}
+
+	updated, err := e.client.UpdateLease(ctx, e.opts.LeaseName, e.newRecord(record, now))
+	if err != nil {
+		e.renewFailed(now, err)
+		return
+	}
+	e.observe(updated, now, true)
+}
+
+// heldByOther reports whether another replica holds an unexpired Lease. Expiry
+// is measured from when this replica last saw the Lease change, not from the
+// recorded renew time, so clock skew between nodes does not matter.
+func (e *LeaseElector) heldByOther(record *LeaseRecord, now time.Time) bool {
+	if record.HolderIdentity == "" || record.HolderIdentity == e.opts.Identity {
+		return false
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return now.Before(e.observedAt.Add(record.LeaseDuration))
+}
+
+// newRecord builds the record written when acquiring or renewing the Lease
+func (e *LeaseElector) newRecord(current *LeaseRecord, now time.Time) *LeaseRecord {
+	record := &LeaseRecord{
+		HolderIdentity: e.opts.Identity,
+		HolderAddress:  e.opts.Address,
+		LeaseDuration:  e.opts.LeaseDuration,
+		AcquireTime:    now,
+		RenewTime:      now,
+	}
+
+	if current != nil {
+		record.ResourceVersion = current.ResourceVersion
+		record.Transitions = current.Transitions
+		if current.HolderIdentity == e.opts.Identity {
+			record.AcquireTime = current.AcquireTime
+		} else {
+			record.Transitions++
+		}
+	}
+	return record
+}
+
+// observe records the latest Lease state and logs leadership changes
+func (e *LeaseElector) observe(record *LeaseRecord, now time.Time, renewed bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	previous := ""
+	if e.observed != nil {
+		previous = e.observed.HolderIdentity
+	}
+	if e.observed == nil || e.observed.ResourceVersion != record.ResourceVersion {
+		e.observedAt = now
+	}
+	e.observed = record
+
+	wasLeader := e.leader
+	e.leader = record.HolderIdentity == e.opts.Identity
+	if renewed && e.leader {
+		e.renewedAt = now
+	}
+
+	switch {
+	case e.leader && !wasLeader:
+		e.logger.Printf("Leader election: %s acquired lease %s", e.opts.Identity, e.opts.LeaseName)
+	case !e.leader && wasLeader:
+		e.logger.Printf("Leader election: %s lost lease %s to %q", e.opts.Identity, e.opts.LeaseName, record.HolderIdentity)
+	case record.HolderIdentity != previous && !e.leader:
+		e.logger.Printf("Leader election: current leader is %q", record.HolderIdentity)
+	}
+}
+
+// renewFailed steps down once the Lease could not be renewed for two thirds
+// of its duration, before other replicas may consider it expired
+func (e *LeaseElector) renewFailed(now time.Time, err error) {
+	e.logger.Printf("Leader election: failed to acquire or renew lease %s: %v", e.opts.LeaseName, err)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	renewDeadline := e.opts.LeaseDuration * 2 / 3
+	if e.leader && now.Sub(e.renewedAt) >= renewDeadline {
+		e.leader = false
+		e.logger.Printf("Leader election: %s stepped down, lease %s not renewed in %s", e.opts.Identity, e.opts.LeaseName, renewDeadline)
+	}
+}
+
+// release gives up the Lease if this replica holds it. The update is sent
+// without mu held, so that Status and IsLeader answer meanwhile.
+func (e *LeaseElector) release(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.leader || e.observed == nil {
+		e.mu.Unlock()
+		return nil
+	}
+	e.leader = false
+	record := *e.observed
+	e.mu.Unlock()
+
+	record.HolderIdentity = ""
+	record.HolderAddress = ""
+	record.LeaseDuration = time.Second
+	record.RenewTime = e.clock.Now()
+
+	if _, err := e.client.UpdateLease(ctx, e.opts.LeaseName, &record); err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	e.logger.Printf("Leader election: %s released lease %s", e.opts.Identity, e.opts.LeaseName)
+	return nil
+}
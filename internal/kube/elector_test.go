@@ -0,0 +1,412 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+)
+
+// FakeLeaseClient is an in-memory LeaseClient with optimistic concurrency
+type FakeLeaseClient struct {
+	mu      sync.Mutex
+	lease   *LeaseRecord
+	version int
+	err     error
+}
+
+func (f *FakeLeaseClient) GetLease(ctx context.Context, name string) (*LeaseRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.lease == nil {
+		return nil, ErrLeaseNotFound
+	}
+	record := *f.lease
+	return &record, nil
+}
+
+func (f *FakeLeaseClient) CreateLease(ctx context.Context, name string, record *LeaseRecord) (*LeaseRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.lease != nil {
+		return nil, ErrLeaseConflict
+	}
+	return f.store(record), nil
+}
+
+func (f *FakeLeaseClient) UpdateLease(ctx context.Context, name string, record *LeaseRecord) (*LeaseRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.lease == nil || f.lease.ResourceVersion != record.ResourceVersion {
+		return nil, ErrLeaseConflict
+	}
+	return f.store(record), nil
+}
+
+func (f *FakeLeaseClient) store(record *LeaseRecord) *LeaseRecord {
+	f.version++
+	stored := *record
+	stored.ResourceVersion = strconv.Itoa(f.version)
+	f.lease = &stored
+
+	result := stored
+	return &result
+}
+
+func (f *FakeLeaseClient) holder() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.lease == nil {
+		return ""
+	}
+	return f.lease.HolderIdentity
+}
+
+func newTestElector(client LeaseClient, identity string, clk clock.Clock) (*LeaseElector, *MockLogger) {
+	logger := &MockLogger{}
+	elector := NewLeaseElector(client, ElectorOptions{
+		Identity: identity,
+		Address:  "http://" + identity + ":8080",
+	}, logger)
+	elector.clock = clk
+	return elector, logger
+}
+
+func TestLeaseElector_SingleLeader(t *testing.T) {
+	client := &FakeLeaseClient{}
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	a, logger := newTestElector(client, "pod-a", fake)
+	b, _ := newTestElector(client, "pod-b", fake)
+
+	ctx := context.Background()
+	a.tryAcquireOrRenew(ctx)
+	b.tryAcquireOrRenew(ctx)
+
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatalf("Expected pod-a to lead, got a=%v b=%v", a.IsLeader(), b.IsLeader())
+	}
+
+	status := b.Status()
+	if !status.Enabled || status.Leader != "pod-a" || status.LeaderAddress != "http://pod-a:8080" || status.Identity != "pod-b" {
+		t.Errorf("Unexpected standby status: %+v", status)
+	}
+
+	// Renewals keep pod-a in charge well past the lease duration
+	for i := 0; i < 20; i++ {
+		fake.Advance(DefaultRetryPeriod)
+		a.tryAcquireOrRenew(ctx)
+		b.tryAcquireOrRenew(ctx)
+	}
+
+	if !a.IsLeader() || b.IsLeader() {
+		t.Errorf("Expected pod-a to keep leading, got a=%v b=%v", a.IsLeader(), b.IsLeader())
+	}
+
+	if !logger.Contains("pod-a acquired lease flux-provider-pushover") {
+		t.Errorf("Expected acquisition to be logged, got %v", logger.Messages())
+	}
+}
+
+func TestLeaseElector_TakeoverAfterExpiry(t *testing.T) {
+	client := &FakeLeaseClient{}
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	a, _ := newTestElector(client, "pod-a", fake)
+	b, logger := newTestElector(client, "pod-b", fake)
+
+	ctx := context.Background()
+	a.tryAcquireOrRenew(ctx)
+	b.tryAcquireOrRenew(ctx)
+
+	// pod-a stops renewing, pod-b waits out the lease before taking over
+	fake.Advance(DefaultLeaseDuration - time.Second)
+	b.tryAcquireOrRenew(ctx)
+	if b.IsLeader() {
+		t.Fatal("Expected pod-b to wait for the lease to expire")
+	}
+
+	fake.Advance(2 * time.Second)
+	b.tryAcquireOrRenew(ctx)
+	if !b.IsLeader() {
+		t.Fatal("Expected pod-b to take over an expired lease")
+	}
+
+	if client.lease.Transitions != 1 {
+		t.Errorf("Expected 1 transition, got %d", client.lease.Transitions)
+	}
+
+	// pod-a notices on its next attempt
+	a.tryAcquireOrRenew(ctx)
+	if a.IsLeader() {
+		t.Error("Expected pod-a to lose leadership")
+	}
+
+	if !logger.Contains("pod-b acquired lease") {
+		t.Errorf("Expected takeover to be logged, got %v", logger.Messages())
+	}
+}
+
+func TestLeaseElector_StepsDownWhenRenewFails(t *testing.T) {
+	client := &FakeLeaseClient{}
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	a, logger := newTestElector(client, "pod-a", fake)
+
+	ctx := context.Background()
+	a.tryAcquireOrRenew(ctx)
+
+	client.err = fmt.Errorf("apiserver unavailable")
+	fake.Advance(5 * time.Second)
+	a.tryAcquireOrRenew(ctx)
+	if !a.IsLeader() {
+		t.Fatal("Expected a single failed renewal to be tolerated")
+	}
+
+	fake.Advance(5 * time.Second)
+	a.tryAcquireOrRenew(ctx)
+	if a.IsLeader() {
+		t.Fatal("Expected pod-a to step down after the renew deadline")
+	}
+
+	if !logger.Contains("stepped down") {
+		t.Errorf("Expected step down to be logged, got %v", logger.Messages())
+	}
+}
+
+func TestLeaseElector_StopReleasesLease(t *testing.T) {
+	client := &FakeLeaseClient{}
+	a := NewLeaseElector(client, ElectorOptions{Identity: "pod-a", RetryPeriod: time.Millisecond}, &MockLogger{})
+	b := NewLeaseElector(client, ElectorOptions{Identity: "pod-b", RetryPeriod: time.Millisecond}, &MockLogger{})
+
+	a.Start()
+	waitFor(t, a.IsLeader)
+
+	b.Start()
+	defer func() {
+		if err := b.Stop(context.Background()); err != nil {
+			t.Errorf("Unexpected error stopping pod-b: %v", err)
+		}
+	}()
+
+	if err := a.Stop(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if a.IsLeader() {
+		t.Error("Expected pod-a to give up leadership on stop")
+	}
+
+	// A released lease can be taken immediately
+	waitFor(t, b.IsLeader)
+	if client.holder() != "pod-b" {
+		t.Errorf("Expected pod-b to hold the lease, got %q", client.holder())
+	}
+}
+
+// countingLeaseClient counts the Lease reads of a FakeLeaseClient
+type countingLeaseClient struct {
+	*FakeLeaseClient
+	gets atomic.Int32
+}
+
+func (c *countingLeaseClient) GetLease(ctx context.Context, name string) (*LeaseRecord, error) {
+	c.gets.Add(1)
+	return c.FakeLeaseClient.GetLease(ctx, name)
+}
+
+func TestLeaseElector_RetriesOnClock(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	client := &countingLeaseClient{FakeLeaseClient: &FakeLeaseClient{}}
+	elector, _ := newTestElector(client, "pod-a", clk)
+
+	elector.Start()
+	waitFor(t, func() bool { return client.gets.Load() == 1 && clk.Waiters() == 1 })
+	if !elector.IsLeader() {
+		t.Fatal("Expected pod-a to acquire the lease")
+	}
+
+	// The next attempt waits for the retry period on the elector's clock
+	clk.Advance(DefaultRetryPeriod - time.Millisecond)
+	if got := client.gets.Load(); got != 1 {
+		t.Errorf("Expected no renewal before the retry period, got %d reads", got)
+	}
+	clk.Advance(time.Millisecond)
+	waitFor(t, func() bool { return client.gets.Load() == 2 })
+
+	if err := elector.Stop(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+// blockingLeaseClient holds releasing updates of a FakeLeaseClient until unblocked
+type blockingLeaseClient struct {
+	*FakeLeaseClient
+	releasing chan struct{}
+	unblock   chan struct{}
+}
+
+func (c *blockingLeaseClient) UpdateLease(ctx context.Context, name string, record *LeaseRecord) (*LeaseRecord, error) {
+	if record.HolderIdentity == "" {
+		close(c.releasing)
+		<-c.unblock
+	}
+	return c.FakeLeaseClient.UpdateLease(ctx, name, record)
+}
+
+func TestLeaseElector_ReleaseDoesNotBlockStatus(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	client := &blockingLeaseClient{FakeLeaseClient: &FakeLeaseClient{}, releasing: make(chan struct{}), unblock: make(chan struct{})}
+	elector, _ := newTestElector(client, "pod-a", clk)
+	elector.tryAcquireOrRenew(context.Background())
+	if !elector.IsLeader() {
+		t.Fatal("Expected pod-a to acquire the lease")
+	}
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- elector.Stop(context.Background()) }()
+	<-client.releasing
+
+	answered := make(chan LeaderStatus, 1)
+	go func() { answered <- elector.Status() }()
+	select {
+	case status := <-answered:
+		if status.IsLeader {
+			t.Error("Expected leadership given up while releasing")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Status to answer while the lease is released")
+	}
+
+	close(client.unblock)
+	if err := <-stopped; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if client.holder() != "" {
+		t.Errorf("Expected the lease released, got holder %q", client.holder())
+	}
+}
+
+func TestLeaseElector_StopWithoutStart(t *testing.T) {
+	elector := NewLeaseElector(&FakeLeaseClient{}, ElectorOptions{Identity: "pod-a"}, &MockLogger{})
+	if err := elector.Stop(context.Background()); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestStandaloneElector(t *testing.T) {
+	elector := StandaloneElector{}
+	elector.Start()
+
+	if !elector.IsLeader() {
+		t.Error("Expected standalone replica to lead")
+	}
+
+	if status := elector.Status(); status.Enabled || !status.IsLeader {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	if err := elector.Stop(context.Background()); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestNewElector(t *testing.T) {
+	env := map[string]string{
+		"KUBERNETES_SERVICE_HOST": "10.0.0.1",
+		"KUBERNETES_SERVICE_PORT": "443",
+		"POD_NAME":                "flux-provider-pushover-abc",
+	}
+	inCluster := func(key string) string { return env[key] }
+	outOfCluster := func(string) string { return "" }
+
+	saDir := t.TempDir()
+	writeFile(t, filepath.Join(saDir, "namespace"), "flux-system\n")
+	writeFile(t, filepath.Join(saDir, "token"), "token")
+	writeFile(t, filepath.Join(saDir, "ca.crt"), testCACert)
+
+	tests := []struct {
+		name           string
+		enabled        bool
+		getEnv         func(string) string
+		wantStandalone bool
+	}{
+		{"disabled", false, inCluster, true},
+		{"not in cluster", true, outOfCluster, true},
+		{"in cluster", true, inCluster, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			elector := NewElector(tt.enabled, tt.getEnv, saDir, ElectorOptions{}, &MockLogger{})
+			_, standalone := elector.(StandaloneElector)
+			if standalone != tt.wantStandalone {
+				t.Errorf("Expected standalone %v, got %T", tt.wantStandalone, elector)
+			}
+
+			if lease, ok := elector.(*LeaseElector); ok {
+				if lease.opts.Identity != "flux-provider-pushover-abc" || lease.opts.LeaseName != DefaultLeaseName {
+					t.Errorf("Unexpected options: %+v", lease.opts)
+				}
+			}
+		})
+	}
+}
+
+// MockLogger records log messages
+type MockLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (m *MockLogger) Printf(format string, v ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, fmt.Sprintf(format, v...))
+}
+
+func (m *MockLogger) Println(v ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, fmt.Sprintln(v...))
+}
+
+func (m *MockLogger) Messages() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.messages...)
+}
+
+func (m *MockLogger) Contains(substr string) bool {
+	for _, msg := range m.Messages() {
+		if strings.Contains(msg, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
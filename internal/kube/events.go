@@ -0,0 +1,279 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// In-cluster service account paths
+const (
+	ServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	Component         = "flux-provider-pushover"
+)
+
+// Event is a Kubernetes Event about a Flux object
+type Event struct {
+	Namespace  string
+	Kind       string
+	Name       string
+	UID        string
+	APIVersion string
+	Reason     string
+	Message    string
+	Warning    bool
+}
+
+// EventEmitter creates Kubernetes Events
+type EventEmitter interface {
+	Emit(ctx context.Context, event Event) error
+}
+
+// NoopEmitter discards all events
+type NoopEmitter struct{}
+
+// Emit does nothing
+func (NoopEmitter) Emit(ctx context.Context, event Event) error {
+	return nil
+}
+
+// HTTPClient interface for dependency injection
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client talks to the Kubernetes REST API
+type Client struct {
+	client    HTTPClient
+	baseURL   string
+	namespace string
+	pod       string // Involved object of the Events, empty when unknown
+	token     func() (string, error)
+	clock     clock.Clock
+}
+
+// NewClient creates a new Kubernetes API client
+func NewClient(client HTTPClient, baseURL, namespace string, token func() (string, error)) *Client {
+	return &Client{
+		client:    client,
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		namespace: namespace,
+		token:     token,
+		clock:     clock.Real{},
+	}
+}
+
+// WithPod sets the provider's pod, which Events are recorded on
+func (c *Client) WithPod(name string) *Client {
+	c.pod = name
+	return c
+}
+
+// NewEmitter returns an in-cluster Events client, or a no-op emitter when
+// disabled or not running inside a cluster
+func NewEmitter(enabled bool, getEnv func(string) string, saDir string) EventEmitter {
+	if !enabled {
+		return NoopEmitter{}
+	}
+
+	client, err := NewInClusterClient(getEnv, saDir)
+	if err != nil {
+		return NoopEmitter{}
+	}
+	return client
+}
+
+// NewInClusterClient creates a client from the pod's service account
+func NewInClusterClient(getEnv func(string) string, saDir string) (*Client, error) {
+	host, port := getEnv("KUBERNETES_SERVICE_HOST"), getEnv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running inside a cluster")
+	}
+
+	namespace, err := os.ReadFile(saDir + "/namespace")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read namespace: %w", err)
+	}
+
+	caCert, err := os.ReadFile(saDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("no certificates found in %s/ca.crt", saDir)
+	}
+
+	httpClient := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+	}
+
+	// Projected service account tokens rotate, so read the token per request
+	token := func() (string, error) {
+		data, err := os.ReadFile(saDir + "/token")
+		return strings.TrimSpace(string(data)), err
+	}
+
+	return NewClient(httpClient, "https://"+net.JoinHostPort(host, port), strings.TrimSpace(string(namespace)), token).
+		WithPod(podName(getEnv)), nil
+}
+
+// podName returns the name of the pod the provider runs in, from POD_NAME
+// or else the hostname Kubernetes sets to it
+func podName(getEnv func(string) string) string {
+	if name := getEnv("POD_NAME"); name != "" {
+		return name
+	}
+	name, _ := os.Hostname()
+	return name
+}
+
+// eventObject is the core/v1 Event payload
+type eventObject struct {
+	APIVersion     string           `json:"apiVersion"`
+	Kind           string           `json:"kind"`
+	Metadata       eventMetadata    `json:"metadata"`
+	InvolvedObject objectReference  `json:"involvedObject"`
+	Related        *objectReference `json:"related,omitempty"`
+	Reason         string           `json:"reason"`
+	Message        string           `json:"message"`
+	Type           string           `json:"type"`
+	Source         eventSource      `json:"source"`
+	FirstTimestamp string           `json:"firstTimestamp"`
+	LastTimestamp  string           `json:"lastTimestamp"`
+	Count          int              `json:"count"`
+}
+
+type eventMetadata struct {
+	GenerateName string `json:"generateName"`
+	Namespace    string `json:"namespace"`
+}
+
+type objectReference struct {
+	Kind       string `json:"kind,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+	UID        string `json:"uid,omitempty"`
+	APIVersion string `json:"apiVersion,omitempty"`
+}
+
+type eventSource struct {
+	Component string `json:"component"`
+}
+
+// Emit creates the Event in the provider's own namespace, recorded on its
+// pod with the Flux object as the related object, so that the provider
+// needs no access to the namespaces of the Flux objects
+func (c *Client) Emit(ctx context.Context, event Event) error {
+	namespace := c.namespace
+
+	eventType := "Normal"
+	if event.Warning {
+		eventType = "Warning"
+	}
+
+	timestamp := c.clock.Now().UTC().Format(time.RFC3339)
+	body, err := json.Marshal(eventObject{
+		APIVersion: "v1",
+		Kind:       "Event",
+		Metadata: eventMetadata{
+			GenerateName: strings.ToLower(defaultString(event.Name, Component)) + ".",
+			Namespace:    namespace,
+		},
+		InvolvedObject: objectReference{
+			Kind:       "Pod",
+			Namespace:  namespace,
+			Name:       c.pod,
+			APIVersion: "v1",
+		},
+		Related:        event.related(),
+		Reason:         event.Reason,
+		Message:        event.Message,
+		Type:           eventType,
+		Source:         eventSource{Component: Component},
+		FirstTimestamp: timestamp,
+		LastTimestamp:  timestamp,
+		Count:          1,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	token, err := c.token()
+	if err != nil {
+		return fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/v1/namespaces/%s/events", c.baseURL, namespace)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", types.ContentTypeJSON)
+	req.Header.Set("Authorization", types.BearerPrefix+token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kubernetes API returned status %d: %s", resp.StatusCode, string(data))
+	}
+
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return fmt.Errorf("failed to discard response body: %w", err)
+	}
+	return nil
+}
+
+// related returns the reference to the Flux object, nil when unknown
+func (e Event) related() *objectReference {
+	if e.Kind == "" && e.Name == "" {
+		return nil
+	}
+	return &objectReference{Kind: e.Kind, Namespace: e.Namespace, Name: e.Name, UID: e.UID, APIVersion: e.APIVersion}
+}
+
+// DeliveryFailureEvent builds the Event recorded when a notification for alert could not be delivered
+func DeliveryFailureEvent(alert *types.FluxAlert, err error) Event {
+	obj := alert.InvolvedObject
+	return Event{
+		Namespace:  obj.Namespace,
+		Kind:       obj.Kind,
+		Name:       obj.Name,
+		UID:        obj.UID,
+		APIVersion: obj.APIVersion,
+		Reason:     "NotificationFailed",
+		Message: fmt.Sprintf("Failed to deliver notification for %s/%s/%s: %v",
+			defaultString(obj.Namespace, "-"), defaultString(obj.Kind, "-"), defaultString(obj.Name, "-"), err),
+		Warning: true,
+	}
+}
+
+// defaultString returns def if value is empty (pure function)
+func defaultString(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
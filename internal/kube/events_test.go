@@ -0,0 +1,237 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// MockHTTPClient is a mock implementation of HTTPClient
+type MockHTTPClient struct {
+	DoFunc func(req *http.Request) (*http.Response, error)
+}
+
+func (m *MockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return m.DoFunc(req)
+}
+
+func testAlert() *types.FluxAlert {
+	alert := &types.FluxAlert{}
+	alert.InvolvedObject.Kind = "Kustomization"
+	alert.InvolvedObject.Namespace = "apps"
+	alert.InvolvedObject.Name = "podinfo"
+	alert.InvolvedObject.UID = "1234"
+	alert.InvolvedObject.APIVersion = "kustomize.toolkit.fluxcd.io/v1"
+	return alert
+}
+
+func TestClient_Emit(t *testing.T) {
+	var captured *http.Request
+	var payload eventObject
+
+	client := NewClient(&MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			captured = req
+			if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+				t.Errorf("Failed to decode event: %v", err)
+			}
+			return &http.Response{
+				StatusCode: http.StatusCreated,
+				Body:       io.NopCloser(strings.NewReader("{}")),
+			}, nil
+		},
+	}, "https://10.0.0.1:443/", "flux-system", func() (string, error) { return "sa-token", nil }).WithPod("flux-provider-pushover-abc")
+	client.clock = clock.NewFake(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	event := DeliveryFailureEvent(testAlert(), fmt.Errorf("pushover API returned status 500"))
+	if err := client.Emit(context.Background(), event); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if captured.Method != "POST" {
+		t.Errorf("Expected POST, got %s", captured.Method)
+	}
+
+	// Recorded in the provider's namespace, not the Flux object's
+	if captured.URL.String() != "https://10.0.0.1:443/api/v1/namespaces/flux-system/events" {
+		t.Errorf("Unexpected URL: %s", captured.URL)
+	}
+
+	if captured.Header.Get("Authorization") != "Bearer sa-token" {
+		t.Errorf("Unexpected Authorization: %s", captured.Header.Get("Authorization"))
+	}
+
+	if payload.Metadata.Namespace != "flux-system" {
+		t.Errorf("Expected the event in the provider's namespace, got %s", payload.Metadata.Namespace)
+	}
+
+	if payload.InvolvedObject != (objectReference{Kind: "Pod", Namespace: "flux-system", Name: "flux-provider-pushover-abc", APIVersion: "v1"}) {
+		t.Errorf("Expected the provider's pod as involved object, got %+v", payload.InvolvedObject)
+	}
+
+	if payload.Related == nil || payload.Related.Kind != "Kustomization" || payload.Related.Name != "podinfo" ||
+		payload.Related.Namespace != "apps" || payload.Related.UID != "1234" {
+		t.Errorf("Unexpected related object: %+v", payload.Related)
+	}
+
+	if payload.Type != "Warning" || payload.Reason != "NotificationFailed" {
+		t.Errorf("Unexpected type/reason: %s/%s", payload.Type, payload.Reason)
+	}
+
+	if !strings.Contains(payload.Message, "apps/Kustomization/podinfo") {
+		t.Errorf("Expected message to reference the object, got %s", payload.Message)
+	}
+
+	if payload.FirstTimestamp != "2024-01-02T03:04:05Z" {
+		t.Errorf("Unexpected timestamp: %s", payload.FirstTimestamp)
+	}
+}
+
+func TestClient_Emit_WithoutObject(t *testing.T) {
+	var url string
+	var payload map[string]any
+	client := NewClient(&MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			url = req.URL.String()
+			if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+				t.Errorf("Failed to decode event: %v", err)
+			}
+			return &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader(""))}, nil
+		},
+	}, "https://k8s", "flux-system", func() (string, error) { return "t", nil })
+
+	if err := client.Emit(context.Background(), Event{Reason: "NotificationFailed"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if url != "https://k8s/api/v1/namespaces/flux-system/events" {
+		t.Errorf("Expected provider namespace, got %s", url)
+	}
+	if _, ok := payload["related"]; ok {
+		t.Errorf("Expected no related object, got %v", payload["related"])
+	}
+}
+
+func TestClient_Emit_Errors(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         func() (string, error)
+		response      *http.Response
+		err           error
+		errorContains string
+	}{
+		{
+			name:          "token error",
+			token:         func() (string, error) { return "", fmt.Errorf("missing") },
+			errorContains: "failed to read service account token",
+		},
+		{
+			name:          "forbidden",
+			token:         func() (string, error) { return "t", nil },
+			response:      &http.Response{StatusCode: http.StatusForbidden, Body: io.NopCloser(strings.NewReader("forbidden"))},
+			errorContains: "kubernetes API returned status 403",
+		},
+		{
+			name:          "network error",
+			token:         func() (string, error) { return "t", nil },
+			err:           fmt.Errorf("refused"),
+			errorContains: "failed to send request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(&MockHTTPClient{
+				DoFunc: func(req *http.Request) (*http.Response, error) {
+					return tt.response, tt.err
+				},
+			}, "https://k8s", "flux-system", tt.token)
+
+			err := client.Emit(context.Background(), Event{})
+			if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+				t.Errorf("Expected error containing %q, got %v", tt.errorContains, err)
+			}
+		})
+	}
+}
+
+func TestNewEmitter(t *testing.T) {
+	inCluster := func(key string) string {
+		return map[string]string{
+			"KUBERNETES_SERVICE_HOST": "10.0.0.1",
+			"KUBERNETES_SERVICE_PORT": "443",
+			"POD_NAME":                "flux-provider-pushover-abc",
+		}[key]
+	}
+	outOfCluster := func(string) string { return "" }
+
+	// Service account directory with a throwaway CA certificate
+	saDir := t.TempDir()
+	writeFile(t, filepath.Join(saDir, "namespace"), "flux-system\n")
+	writeFile(t, filepath.Join(saDir, "token"), "token")
+	writeFile(t, filepath.Join(saDir, "ca.crt"), testCACert)
+
+	tests := []struct {
+		name     string
+		enabled  bool
+		getEnv   func(string) string
+		saDir    string
+		wantNoop bool
+	}{
+		{"disabled", false, inCluster, saDir, true},
+		{"not in cluster", true, outOfCluster, saDir, true},
+		{"missing service account", true, inCluster, t.TempDir(), true},
+		{"in cluster", true, inCluster, saDir, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emitter := NewEmitter(tt.enabled, tt.getEnv, tt.saDir)
+			_, isNoop := emitter.(NoopEmitter)
+			if isNoop != tt.wantNoop {
+				t.Errorf("Expected noop %v, got %T", tt.wantNoop, emitter)
+			}
+
+			if client, ok := emitter.(*Client); ok {
+				if client.namespace != "flux-system" || client.baseURL != "https://10.0.0.1:443" || client.pod != "flux-provider-pushover-abc" {
+					t.Errorf("Unexpected client settings: %s %s %s", client.namespace, client.baseURL, client.pod)
+				}
+			}
+		})
+	}
+}
+
+func TestNoopEmitter(t *testing.T) {
+	if err := (NoopEmitter{}).Emit(context.Background(), Event{}); err != nil {
+		t.Errorf("Expected nil error, got %v", err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// testCACert is a throwaway self-signed certificate used as a fake cluster CA
+const testCACert = `-----BEGIN CERTIFICATE-----
+MIIBfDCCASGgAwIBAgIUF4geTZQYBAMx97qx52ao68QE4SgwCgYIKoZIzj0EAwIw
+EjEQMA4GA1UEAwwHdGVzdC1jYTAgFw0yNjEwMTUxMTEyMDdaGA8yMTI2MDkyMTEx
+MTIwN1owEjEQMA4GA1UEAwwHdGVzdC1jYTBZMBMGByqGSM49AgEGCCqGSM49AwEH
+A0IABISGVcZkTj2bjUybO53Hl9iBUzlQVfEnnZWYb+Jacoepcm3G/ZK5QWAjDxDh
++BIZ1BlX1x68BJrrOtEGqMgJRBSjUzBRMB0GA1UdDgQWBBScR3/LFHkCIO4UdmEP
+Q5AGQLrN7TAfBgNVHSMEGDAWgBScR3/LFHkCIO4UdmEPQ5AGQLrN7TAPBgNVHRMB
+Af8EBTADAQH/MAoGCCqGSM49BAMCA0kAMEYCIQCwqGR6gPaNlMvwZ0K6q/XSeWIN
+/Cs9euNwsPIzxAKHzQIhAI1I4JXlFAlX3FiVNM55pRjoz/+2m3xL5smg1rtXOVpe
+-----END CERTIFICATE-----`
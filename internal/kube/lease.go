@@ -0,0 +1,194 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// Lease API errors
+var (
+	ErrLeaseNotFound = errors.New("lease not found")
+	ErrLeaseConflict = errors.New("lease was modified concurrently")
+)
+
+// LeaderAddressAnnotation carries the leader's reachable URL for proxying
+const LeaderAddressAnnotation = "flux-provider-pushover/leader-address"
+
+// microTimeFormat is the wire format of metav1.MicroTime
+const microTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// LeaseRecord is the election state stored in a coordination.k8s.io/v1 Lease
+type LeaseRecord struct {
+	HolderIdentity  string
+	HolderAddress   string
+	LeaseDuration   time.Duration
+	AcquireTime     time.Time
+	RenewTime       time.Time
+	Transitions     int
+	ResourceVersion string
+}
+
+// LeaseClient reads and writes Leases in the provider's namespace
+type LeaseClient interface {
+	GetLease(ctx context.Context, name string) (*LeaseRecord, error)
+	CreateLease(ctx context.Context, name string, record *LeaseRecord) (*LeaseRecord, error)
+	UpdateLease(ctx context.Context, name string, record *LeaseRecord) (*LeaseRecord, error)
+}
+
+// leaseObject is the coordination.k8s.io/v1 Lease payload
+type leaseObject struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   leaseMetadata `json:"metadata"`
+	Spec       leaseSpec     `json:"spec"`
+}
+
+type leaseMetadata struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions"`
+}
+
+// GetLease fetches the named Lease
+func (c *Client) GetLease(ctx context.Context, name string) (*LeaseRecord, error) {
+	return c.doLease(ctx, "GET", c.leaseURL(name), nil)
+}
+
+// CreateLease creates the named Lease
+func (c *Client) CreateLease(ctx context.Context, name string, record *LeaseRecord) (*LeaseRecord, error) {
+	return c.doLease(ctx, "POST", c.leaseURL(""), c.toLeaseObject(name, record))
+}
+
+// UpdateLease replaces the named Lease, failing with ErrLeaseConflict if it
+// changed since record was read
+func (c *Client) UpdateLease(ctx context.Context, name string, record *LeaseRecord) (*LeaseRecord, error) {
+	return c.doLease(ctx, "PUT", c.leaseURL(name), c.toLeaseObject(name, record))
+}
+
+// leaseURL returns the Leases collection URL, or a single Lease URL when name is set
+func (c *Client) leaseURL(name string) string {
+	url := fmt.Sprintf("%s/apis/coordination.k8s.io/v1/namespaces/%s/leases", c.baseURL, c.namespace)
+	if name != "" {
+		url += "/" + name
+	}
+	return url
+}
+
+// toLeaseObject converts a record to its API representation
+func (c *Client) toLeaseObject(name string, record *LeaseRecord) *leaseObject {
+	lease := &leaseObject{
+		APIVersion: "coordination.k8s.io/v1",
+		Kind:       "Lease",
+		Metadata: leaseMetadata{
+			Name:            name,
+			Namespace:       c.namespace,
+			ResourceVersion: record.ResourceVersion,
+		},
+		Spec: leaseSpec{
+			HolderIdentity:       record.HolderIdentity,
+			LeaseDurationSeconds: int(record.LeaseDuration / time.Second),
+			AcquireTime:          formatMicroTime(record.AcquireTime),
+			RenewTime:            formatMicroTime(record.RenewTime),
+			LeaseTransitions:     record.Transitions,
+		},
+	}
+	if record.HolderAddress != "" {
+		lease.Metadata.Annotations = map[string]string{LeaderAddressAnnotation: record.HolderAddress}
+	}
+	return lease
+}
+
+// doLease sends a Lease request and decodes the returned Lease
+func (c *Client) doLease(ctx context.Context, method, url string, lease *leaseObject) (*LeaseRecord, error) {
+	var body io.Reader
+	if lease != nil {
+		data, err := json.Marshal(lease)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode lease: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	token, err := c.token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", types.ContentTypeJSON)
+	req.Header.Set("Authorization", types.BearerPrefix+token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+	case http.StatusNotFound:
+		return nil, ErrLeaseNotFound
+	case http.StatusConflict:
+		return nil, ErrLeaseConflict
+	default:
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("kubernetes API returned status %d: %s", resp.StatusCode, string(data))
+	}
+
+	var result leaseObject
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode lease: %w", err)
+	}
+	return fromLeaseObject(&result), nil
+}
+
+// fromLeaseObject converts an API Lease to a record (pure function)
+func fromLeaseObject(lease *leaseObject) *LeaseRecord {
+	return &LeaseRecord{
+		HolderIdentity:  lease.Spec.HolderIdentity,
+		HolderAddress:   lease.Metadata.Annotations[LeaderAddressAnnotation],
+		LeaseDuration:   time.Duration(lease.Spec.LeaseDurationSeconds) * time.Second,
+		AcquireTime:     parseMicroTime(lease.Spec.AcquireTime),
+		RenewTime:       parseMicroTime(lease.Spec.RenewTime),
+		Transitions:     lease.Spec.LeaseTransitions,
+		ResourceVersion: lease.Metadata.ResourceVersion,
+	}
+}
+
+// formatMicroTime formats t as metav1.MicroTime, empty for the zero time (pure function)
+func formatMicroTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(microTimeFormat)
+}
+
+// parseMicroTime parses a metav1.MicroTime, returning the zero time when unset or invalid (pure function)
+func parseMicroTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
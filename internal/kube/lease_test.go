@@ -0,0 +1,122 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_LeaseRoundTrip(t *testing.T) {
+	var captured *http.Request
+	var sent leaseObject
+
+	client := NewClient(&MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			captured = req
+			if err := json.NewDecoder(req.Body).Decode(&sent); err != nil {
+				t.Errorf("Failed to decode lease: %v", err)
+			}
+			sent.Metadata.ResourceVersion = "2"
+			body, _ := json.Marshal(sent)
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(body)))}, nil
+		},
+	}, "https://k8s", "flux-system", func() (string, error) { return "sa-token", nil })
+
+	acquired := time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC)
+	record, err := client.UpdateLease(context.Background(), "flux-provider-pushover", &LeaseRecord{
+		HolderIdentity:  "pod-a",
+		HolderAddress:   "http://10.0.0.5:8080",
+		LeaseDuration:   15 * time.Second,
+		AcquireTime:     acquired,
+		RenewTime:       acquired,
+		Transitions:     3,
+		ResourceVersion: "1",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if captured.Method != "PUT" {
+		t.Errorf("Expected PUT, got %s", captured.Method)
+	}
+
+	if captured.URL.String() != "https://k8s/apis/coordination.k8s.io/v1/namespaces/flux-system/leases/flux-provider-pushover" {
+		t.Errorf("Unexpected URL: %s", captured.URL)
+	}
+
+	if captured.Header.Get("Authorization") != "Bearer sa-token" {
+		t.Errorf("Unexpected Authorization: %s", captured.Header.Get("Authorization"))
+	}
+
+	if sent.Spec.RenewTime != "2024-01-02T03:04:05.123456Z" || sent.Spec.LeaseDurationSeconds != 15 {
+		t.Errorf("Unexpected spec: %+v", sent.Spec)
+	}
+
+	if sent.Metadata.ResourceVersion != "2" || sent.Metadata.Annotations[LeaderAddressAnnotation] != "http://10.0.0.5:8080" {
+		t.Errorf("Unexpected metadata: %+v", sent.Metadata)
+	}
+
+	if record.HolderIdentity != "pod-a" || record.HolderAddress != "http://10.0.0.5:8080" ||
+		!record.RenewTime.Equal(acquired) || record.Transitions != 3 || record.ResourceVersion != "2" {
+		t.Errorf("Unexpected record: %+v", record)
+	}
+}
+
+func TestClient_CreateLease(t *testing.T) {
+	var captured *http.Request
+	client := NewClient(&MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			captured = req
+			return &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader(`{"metadata":{"resourceVersion":"1"}}`))}, nil
+		},
+	}, "https://k8s", "flux-system", func() (string, error) { return "t", nil })
+
+	record, err := client.CreateLease(context.Background(), "lease", &LeaseRecord{HolderIdentity: "pod-a"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if captured.Method != "POST" || captured.URL.String() != "https://k8s/apis/coordination.k8s.io/v1/namespaces/flux-system/leases" {
+		t.Errorf("Unexpected request: %s %s", captured.Method, captured.URL)
+	}
+
+	if record.ResourceVersion != "1" {
+		t.Errorf("Expected resource version 1, got %q", record.ResourceVersion)
+	}
+}
+
+func TestClient_LeaseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		expected error
+		contains string
+	}{
+		{"not found", http.StatusNotFound, ErrLeaseNotFound, ""},
+		{"conflict", http.StatusConflict, ErrLeaseConflict, ""},
+		{"forbidden", http.StatusForbidden, nil, "kubernetes API returned status 403"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(&MockHTTPClient{
+				DoFunc: func(req *http.Request) (*http.Response, error) {
+					return &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader("denied"))}, nil
+				},
+			}, "https://k8s", "flux-system", func() (string, error) { return "t", nil })
+
+			_, err := client.GetLease(context.Background(), "lease")
+			if tt.expected != nil && !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+			if tt.contains != "" && (err == nil || !strings.Contains(err.Error(), tt.contains)) {
+				t.Errorf("Expected error containing %q, got %v", tt.contains, err)
+			}
+		})
+	}
+}
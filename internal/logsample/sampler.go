@@ -0,0 +1,158 @@
+// Package logsample keeps repeated identical failures from flooding the logs
+package logsample
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+)
+
+// window counts the occurrences of one failure signature
+type window struct {
+	endpoint string
+	err      string
+	start    time.Time
+	count    int
+}
+
+// Sampler logs a failure in full only the first burst times its signature,
+// the endpoint and error, occurs within a window. Later occurrences are
+// counted and summarised once the window ends. Other log lines never pass
+// through it.
+type Sampler struct {
+	logger server.Logger
+	window time.Duration
+	burst  int
+	clock  clock.Clock
+
+	mu      sync.Mutex
+	windows map[string]*window
+	stop    chan struct{}
+	done    chan struct{}
+	closed  bool
+
+	sampled *metrics.Counter
+}
+
+// NewSampler creates a sampler and starts summarising ended windows
+func NewSampler(logger server.Logger, interval time.Duration, burst int, registry *metrics.Registry) *Sampler {
+	s := &Sampler{
+		logger:  logger,
+		window:  interval,
+		burst:   burst,
+		clock:   clock.Real{},
+		windows: make(map[string]*window),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		sampled: registry.Counter("log_lines_sampled_total", "Failure log lines left out by LOG_SAMPLE_WINDOW"),
+	}
+	go s.summaryLoop()
+	return s
+}
+
+// Signature identifies failures that are logged together (pure function)
+func Signature(endpoint string, err error) string {
+	sum := sha256.Sum256([]byte(endpoint + "\x00" + err.Error()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Failuref logs a failure of endpoint unless its signature was already
+// logged burst times in the current window
+func (s *Sampler) Failuref(endpoint string, err error, format string, v ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	key := Signature(endpoint, err)
+	w := s.windows[key]
+	if w != nil && now.Sub(w.start) >= s.window {
+		s.summarize(key, w, s.window)
+		w = nil
+	}
+	if w == nil {
+		w = &window{endpoint: endpoint, err: err.Error(), start: now}
+		s.windows[key] = w
+	}
+
+	w.count++
+	if w.count > s.burst {
+		s.sampled.Inc()
+		return
+	}
+	s.logger.Printf(format, v...)
+}
+
+// Flush summarises the windows that have ended
+func (s *Sampler) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	for key, w := range s.windows {
+		if now.Sub(w.start) >= s.window {
+			s.summarize(key, w, s.window)
+		}
+	}
+}
+
+// Close stops the periodic summaries and summarises every open window
+func (s *Sampler) Close(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.stop)
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	for key, w := range s.windows {
+		s.summarize(key, w, min(now.Sub(w.start), s.window).Round(time.Second))
+	}
+	return nil
+}
+
+// summaryLoop flushes once per window until Close
+func (s *Sampler) summaryLoop() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.Flush()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// summarize logs how often w's failure was left out and forgets w, s.mu
+// must be held
+func (s *Sampler) summarize(key string, w *window, elapsed time.Duration) {
+	delete(s.windows, key)
+	if skipped := w.count - s.burst; skipped > 0 {
+		s.logger.Printf("%s failed %d more times in the last %s: %s", w.endpoint, skipped, elapsed, w.err)
+	}
+}
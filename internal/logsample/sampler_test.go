@@ -0,0 +1,172 @@
+package logsample
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+)
+
+// MockLogger for testing (thread-safe)
+type MockLogger struct {
+	mu       sync.Mutex
+	Messages []string
+}
+
+func (m *MockLogger) Printf(format string, v ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Messages = append(m.Messages, fmt.Sprintf(format, v...))
+}
+
+func (m *MockLogger) Println(v ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Messages = append(m.Messages, fmt.Sprint(v...))
+}
+
+// newTestSampler returns a sampler on a fake clock, whose periodic summary
+// never fires during a test
+func newTestSampler(burst int, registry *metrics.Registry) (*Sampler, *MockLogger, *clock.Fake) {
+	logger := &MockLogger{}
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	s := NewSampler(logger, time.Hour, burst, registry)
+	s.clock = fake
+	return s, logger, fake
+}
+
+func TestSampler_LogsBurstThenSummarises(t *testing.T) {
+	registry := metrics.NewRegistry()
+	s, logger, fake := newTestSampler(2, registry)
+	defer s.Close(context.Background())
+
+	err := errors.New("pushover API returned status 503")
+	for i := 0; i < 5; i++ {
+		s.Failuref("pushover send", err, "Failed to send to Pushover: %v", err)
+	}
+
+	if len(logger.Messages) != 2 {
+		t.Fatalf("Expected 2 full lines, got %v", logger.Messages)
+	}
+	if sampled := registry.Counter("log_lines_sampled_total", "").Value(); sampled != 3 {
+		t.Errorf("Expected 3 sampled lines, got %d", sampled)
+	}
+
+	// Nothing is summarised before the window ends
+	fake.Advance(59 * time.Minute)
+	s.Flush()
+	if len(logger.Messages) != 2 {
+		t.Fatalf("Expected no summary within the window, got %v", logger.Messages)
+	}
+
+	fake.Advance(time.Minute)
+	s.Flush()
+	expected := "pushover send failed 3 more times in the last 1h0m0s: pushover API returned status 503"
+	if len(logger.Messages) != 3 || logger.Messages[2] != expected {
+		t.Fatalf("Expected summary %q, got %v", expected, logger.Messages)
+	}
+
+	// A new window logs in full again
+	s.Failuref("pushover send", err, "Failed to send to Pushover: %v", err)
+	if len(logger.Messages) != 4 || !strings.HasPrefix(logger.Messages[3], "Failed to send") {
+		t.Errorf("Expected a full line in the new window, got %v", logger.Messages)
+	}
+}
+
+func TestSampler_SummaryOnNextFailure(t *testing.T) {
+	s, logger, fake := newTestSampler(1, nil)
+	defer s.Close(context.Background())
+
+	err := errors.New("timeout")
+	s.Failuref("pushover send", err, "Failed: %v", err)
+	s.Failuref("pushover send", err, "Failed: %v", err)
+
+	// The ended window is summarised before the failure opens a new one
+	fake.Advance(2 * time.Hour)
+	s.Failuref("pushover send", err, "Failed: %v", err)
+
+	expected := []string{"Failed: timeout", "pushover send failed 1 more times in the last 1h0m0s: timeout", "Failed: timeout"}
+	if fmt.Sprint(logger.Messages) != fmt.Sprint(expected) {
+		t.Errorf("Expected %q, got %q", expected, logger.Messages)
+	}
+}
+
+func TestSampler_SignaturesAreSeparate(t *testing.T) {
+	s, logger, _ := newTestSampler(1, nil)
+	defer s.Close(context.Background())
+
+	unavailable, timeout := errors.New("status 503"), errors.New("timeout")
+	for i := 0; i < 3; i++ {
+		s.Failuref("pushover send", unavailable, "Failed: %v", unavailable)
+		s.Failuref("pushover send", timeout, "Failed: %v", timeout)
+		s.Failuref("notification send", unavailable, "Failed: %v", unavailable)
+	}
+
+	// Each endpoint and error combination is logged in full once
+	if len(logger.Messages) != 3 {
+		t.Errorf("Expected one line per signature, got %v", logger.Messages)
+	}
+
+	if Signature("pushover send", unavailable) == Signature("pushover send", timeout) ||
+		Signature("pushover send", unavailable) == Signature("notification send", unavailable) {
+		t.Error("Expected different signatures for different endpoints and errors")
+	}
+	if Signature("pushover send", unavailable) != Signature("pushover send", errors.New("status 503")) {
+		t.Error("Expected identical failures to share a signature")
+	}
+}
+
+func TestSampler_CloseSummarisesOpenWindows(t *testing.T) {
+	s, logger, fake := newTestSampler(1, nil)
+
+	err := errors.New("status 503")
+	for i := 0; i < 4; i++ {
+		s.Failuref("pushover send", err, "Failed: %v", err)
+	}
+	fake.Advance(90 * time.Second)
+
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "pushover send failed 3 more times in the last 1m30s: status 503"
+	if len(logger.Messages) != 2 || logger.Messages[1] != expected {
+		t.Errorf("Expected summary %q on close, got %v", expected, logger.Messages)
+	}
+
+	if err := s.Close(context.Background()); err != nil {
+		t.Errorf("Expected a second close to be a no-op, got %v", err)
+	}
+
+	var nilSampler *Sampler
+	if err := nilSampler.Close(context.Background()); err != nil {
+		t.Errorf("Expected nil sampler to close cleanly, got %v", err)
+	}
+}
+
+func TestSampler_PeriodicSummary(t *testing.T) {
+	logger := &MockLogger{}
+	s := NewSampler(logger, 20*time.Millisecond, 1, nil)
+	defer s.Close(context.Background())
+
+	err := errors.New("status 503")
+	s.Failuref("pushover send", err, "Failed: %v", err)
+	s.Failuref("pushover send", err, "Failed: %v", err)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		logger.mu.Lock()
+		n := len(logger.Messages)
+		logger.mu.Unlock()
+		if n == 2 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("Expected the summary without further failures")
+}
@@ -0,0 +1,213 @@
+// Package maintenance tells whether planned maintenance windows, recurring
+// on cron schedules, are in progress
+package maintenance
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+)
+
+// composeHorizon bounds how far ahead composed windows are followed, so
+// that windows always covering each other still have an end
+const composeHorizon = 7 * 24 * time.Hour
+
+// cronParser accepts the standard five fields and descriptors like @weekly
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Schedule is a cron expression telling when windows start
+type Schedule struct {
+	expr     string
+	schedule cron.Schedule
+}
+
+// ParseSchedule parses a five field cron expression, e.g. "0 2 * * 6", or a
+// descriptor, e.g. "@daily". Like in cron, a restricted day of month and
+// day of week match days matching either.
+func ParseSchedule(expr string) (*Schedule, error) {
+	schedule, err := cronParser.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+	}
+	return &Schedule{expr: expr, schedule: schedule}, nil
+}
+
+// Next returns the first start after t, in t's time zone
+func (s *Schedule) Next(t time.Time) time.Time {
+	return s.schedule.Next(t)
+}
+
+// String returns the expression the schedule was parsed from
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// Window is a maintenance window starting on a schedule and lasting for a
+// duration, applying to the alerts of a namespace or kind when set
+type Window struct {
+	Spec      string // As configured
+	Schedule  *Schedule
+	Duration  time.Duration
+	Namespace string // Empty matches every namespace
+	Kind      string // Lower case, empty matches every kind
+}
+
+// Matches reports whether the window applies to an object (pure function)
+func (w Window) Matches(namespace, kind string) bool {
+	return (w.Namespace == "" || w.Namespace == namespace) && (w.Kind == "" || strings.EqualFold(w.Kind, kind))
+}
+
+// ActiveAt returns the end of the occurrence in progress at t, ok false
+// when none is. Occurrences overlapping each other extend the end.
+func (w Window) ActiveAt(t time.Time) (end time.Time, ok bool) {
+	// The first start within the last Duration, later ones extend its end
+	for start := w.Schedule.Next(t.Add(-w.Duration)); !start.After(t); start = w.Schedule.Next(start) {
+		end, ok = start.Add(w.Duration), true
+	}
+	return end, ok
+}
+
+// ParseWindows parses MAINTENANCE_WINDOWS: windows separated by semicolons,
+// each a cron expression, a duration and optional namespace= and kind=
+// matchers, e.g. "0 2 * * 6 4h namespace=apps; @daily 30m kind=HelmRelease"
+func ParseWindows(spec string) ([]Window, error) {
+	var windows []Window
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		window, err := parseWindow(item)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// parseWindow parses one window of MAINTENANCE_WINDOWS
+func parseWindow(item string) (Window, error) {
+	fields := strings.Fields(item)
+	cronFields := 5
+	if strings.HasPrefix(fields[0], "@") {
+		cronFields = 1
+	}
+	if len(fields) < cronFields+1 {
+		return Window{}, fmt.Errorf("invalid window %q, expected a cron expression and a duration", item)
+	}
+
+	schedule, err := ParseSchedule(strings.Join(fields[:cronFields], " "))
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %w", item, err)
+	}
+	duration, err := time.ParseDuration(fields[cronFields])
+	if err != nil || duration <= 0 {
+		return Window{}, fmt.Errorf("invalid window %q, the duration must be positive, e.g. 4h", item)
+	}
+
+	window := Window{Spec: item, Schedule: schedule, Duration: duration}
+	for _, matcher := range fields[cronFields+1:] {
+		key, value, _ := strings.Cut(matcher, "=")
+		switch {
+		case value == "":
+			return Window{}, fmt.Errorf("invalid window %q, expected namespace=<name> or kind=<kind>, got %q", item, matcher)
+		case key == "namespace":
+			window.Namespace = value
+		case key == "kind":
+			window.Kind = strings.ToLower(value)
+		default:
+			return Window{}, fmt.Errorf("invalid window %q, unknown matcher %q", item, key)
+		}
+	}
+	return window, nil
+}
+
+// Status is the state of a window, reported on /status
+type Status struct {
+	Window    string     `json:"window"`
+	Active    bool       `json:"active"`
+	Until     *time.Time `json:"until,omitempty"` // End of the occurrence in progress
+	NextStart time.Time  `json:"next_start"`
+}
+
+// Windows are the maintenance windows of the provider, in one time zone. A
+// nil Windows is never in maintenance.
+type Windows struct {
+	windows  []Window
+	location *time.Location
+	clock    clock.Clock
+}
+
+// NewWindows creates the windows of spec, nil when it has none, starting in
+// the time zone location names
+func NewWindows(spec, location string) (*Windows, error) {
+	windows, err := ParseWindows(spec)
+	if err != nil || len(windows) == 0 {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAINTENANCE_TIMEZONE: %w", err)
+	}
+	return NewWindowsWithClock(windows, loc, clock.Real{}), nil
+}
+
+// NewWindowsWithClock creates windows telling the time by clk
+func NewWindowsWithClock(windows []Window, location *time.Location, clk clock.Clock) *Windows {
+	return &Windows{windows: windows, location: location, clock: clk}
+}
+
+// WithClock makes the windows tell the time by clk. It is a no-op on nil
+// Windows.
+func (w *Windows) WithClock(clk clock.Clock) *Windows {
+	if w != nil {
+		w.clock = clk
+	}
+	return w
+}
+
+// ActiveFor returns when maintenance of an object ends, ok false when none
+// of its windows is in progress. Matching windows overlapping or following
+// each other without a gap compose into one, followed up to a week ahead.
+func (w *Windows) ActiveFor(namespace, kind string) (until time.Time, ok bool) {
+	if w == nil {
+		return time.Time{}, false
+	}
+
+	until = w.clock.Now().In(w.location)
+	horizon := until.Add(composeHorizon)
+	for extended := true; extended && until.Before(horizon); {
+		extended = false
+		for _, window := range w.windows {
+			if !window.Matches(namespace, kind) {
+				continue
+			}
+			if end, active := window.ActiveAt(until); active && end.After(until) {
+				until, ok, extended = end, true, true
+			}
+		}
+	}
+	return until, ok
+}
+
+// Status returns the state of every window
+func (w *Windows) Status() []Status {
+	if w == nil {
+		return nil
+	}
+
+	now := w.clock.Now().In(w.location)
+	statuses := make([]Status, len(w.windows))
+	for i, window := range w.windows {
+		statuses[i] = Status{Window: window.Spec, NextStart: window.Schedule.Next(now)}
+		if end, active := window.ActiveAt(now); active {
+			statuses[i].Active, statuses[i].Until = true, &end
+		}
+	}
+	return statuses
+}
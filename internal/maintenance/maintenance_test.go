@@ -0,0 +1,200 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+)
+
+func newTestWindows(t *testing.T, spec string, start time.Time) (*Windows, *clock.Fake) {
+	t.Helper()
+	windows, err := ParseWindows(spec)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	clk := clock.NewFake(start)
+	return NewWindowsWithClock(windows, time.UTC, clk), clk
+}
+
+func TestWindows_EntryAndExit(t *testing.T) {
+	// Saturday 2024-01-06, the window runs 02:00-06:00
+	windows, clk := newTestWindows(t, "0 2 * * 6 4h", time.Date(2024, 1, 6, 1, 59, 0, 0, time.UTC))
+
+	steps := []struct {
+		advance time.Duration
+		active  bool
+		until   string
+	}{
+		{0, false, ""},
+		{time.Minute, true, "2024-01-06T06:00:00Z"},                  // 02:00, entry
+		{3*time.Hour + 59*time.Minute, true, "2024-01-06T06:00:00Z"}, // 05:59
+		{time.Minute, false, ""},                                     // 06:00, exit
+		{7*24*time.Hour - 2*time.Hour, true, "2024-01-13T06:00:00Z"}, // 04:00 a week later, in the next one
+	}
+	for i, step := range steps {
+		clk.Advance(step.advance)
+		until, active := windows.ActiveFor("apps", "Kustomization")
+		if active != step.active {
+			t.Fatalf("Step %d at %s: expected active %v", i, clk.Now(), step.active)
+		}
+		if active && until.Format(time.RFC3339) != step.until {
+			t.Errorf("Step %d at %s: expected until %s, got %s", i, clk.Now(), step.until, until.Format(time.RFC3339))
+		}
+	}
+}
+
+func TestWindows_Overnight(t *testing.T) {
+	// Daily 23:00 for 3 hours spans midnight
+	windows, clk := newTestWindows(t, "0 23 * * * 3h", time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC))
+
+	for _, step := range []struct {
+		at     string
+		active bool
+	}{
+		{"2024-01-01T22:59:59Z", false},
+		{"2024-01-01T23:00:00Z", true},
+		{"2024-01-02T00:30:00Z", true},
+		{"2024-01-02T01:59:59Z", true},
+		{"2024-01-02T02:00:00Z", false},
+		{"2024-01-02T12:00:00Z", false},
+	} {
+		at, _ := time.Parse(time.RFC3339, step.at)
+		clk.Advance(at.Sub(clk.Now()))
+		if _, active := windows.ActiveFor("apps", "HelmRelease"); active != step.active {
+			t.Errorf("At %s: expected active %v", step.at, step.active)
+		}
+	}
+}
+
+func TestWindows_Timezone(t *testing.T) {
+	windows, err := ParseWindows("0 2 * * * 1h")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	budapest, err := time.LoadLocation("Europe/Budapest")
+	if err != nil {
+		t.Skipf("No time zone database: %v", err)
+	}
+
+	// 02:30 in Budapest is 01:30 UTC in winter
+	set := NewWindowsWithClock(windows, budapest, clock.NewFake(time.Date(2024, 1, 1, 1, 30, 0, 0, time.UTC)))
+	if _, active := set.ActiveFor("apps", "Kustomization"); !active {
+		t.Error("Expected the schedule to follow MAINTENANCE_TIMEZONE")
+	}
+}
+
+func TestWindows_Compose(t *testing.T) {
+	// 01:00-03:00 overlaps 02:00-04:00, which 04:00-05:00 follows without a gap
+	spec := "0 1 * * * 2h; 0 2 * * * 2h; 0 4 * * * 1h; 0 6 * * * 1h"
+	windows, clk := newTestWindows(t, spec, time.Date(2024, 1, 1, 1, 30, 0, 0, time.UTC))
+
+	until, active := windows.ActiveFor("apps", "Kustomization")
+	if !active || until.Format(time.RFC3339) != "2024-01-01T05:00:00Z" {
+		t.Errorf("Expected the windows composed until 05:00, got %v %s", active, until)
+	}
+
+	// Leaving the first window keeps the composed end
+	clk.Advance(2 * time.Hour)
+	if until, _ := windows.ActiveFor("apps", "Kustomization"); until.Format(time.RFC3339) != "2024-01-01T05:00:00Z" {
+		t.Errorf("Expected the composed end to stay 05:00, got %s", until)
+	}
+
+	// The 06:00 window is separate
+	clk.Advance(2 * time.Hour)
+	if _, active := windows.ActiveFor("apps", "Kustomization"); active {
+		t.Error("Expected no window at 05:30")
+	}
+}
+
+func TestWindows_AlwaysCoveredEnds(t *testing.T) {
+	windows, _ := newTestWindows(t, "* * * * * 2m", time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC))
+	until, active := windows.ActiveFor("apps", "Kustomization")
+	if !active || until.Sub(time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC)) < composeHorizon {
+		t.Errorf("Expected a window covering itself to compose up to the horizon, got %v %s", active, until)
+	}
+}
+
+func TestWindows_Matchers(t *testing.T) {
+	spec := "@hourly 30m namespace=apps; @hourly 30m kind=HelmRelease"
+	windows, _ := newTestWindows(t, spec, time.Date(2024, 1, 1, 0, 10, 0, 0, time.UTC))
+
+	tests := []struct {
+		namespace, kind string
+		expected        bool
+	}{
+		{"apps", "Kustomization", true},
+		{"infra", "helmrelease", true},
+		{"infra", "Kustomization", false},
+	}
+	for _, tt := range tests {
+		if _, active := windows.ActiveFor(tt.namespace, tt.kind); active != tt.expected {
+			t.Errorf("%s/%s: expected active %v", tt.namespace, tt.kind, tt.expected)
+		}
+	}
+}
+
+func TestWindows_Status(t *testing.T) {
+	windows, _ := newTestWindows(t, "0 2 * * 6 4h namespace=apps; @daily 30m", time.Date(2024, 1, 6, 3, 0, 0, 0, time.UTC))
+
+	body, err := json.Marshal(windows.Status())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `[{"window":"0 2 * * 6 4h namespace=apps","active":true,"until":"2024-01-06T06:00:00Z","next_start":"2024-01-13T02:00:00Z"},` +
+		`{"window":"@daily 30m","active":false,"next_start":"2024-01-07T00:00:00Z"}]`
+	if string(body) != expected {
+		t.Errorf("Expected %s, got %s", expected, body)
+	}
+
+	var nilWindows *Windows
+	if nilWindows.Status() != nil {
+		t.Error("Expected nil windows to report nothing")
+	}
+	if _, active := nilWindows.ActiveFor("apps", "Kustomization"); active {
+		t.Error("Expected nil windows never active")
+	}
+}
+
+func TestParseWindows(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		windows  int
+		expected string
+	}{
+		{name: "empty", spec: " ; "},
+		{name: "several", spec: "0 2 * * 6 4h namespace=apps kind=HelmRelease; @weekly 1h", windows: 2},
+		{name: "missing duration", spec: "0 2 * * 6", expected: "expected a cron expression and a duration"},
+		{name: "bad cron", spec: "0 25 * * * 1h", expected: `invalid schedule "0 25 * * *"`},
+		{name: "bad duration", spec: "0 2 * * * forever", expected: "the duration must be positive"},
+		{name: "zero duration", spec: "0 2 * * * 0s", expected: "the duration must be positive"},
+		{name: "unknown matcher", spec: "@daily 1h name=podinfo", expected: `unknown matcher "name"`},
+		{name: "empty matcher", spec: "@daily 1h kind=", expected: `expected namespace=<name> or kind=<kind>, got "kind="`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			windows, err := ParseWindows(tt.spec)
+			if tt.expected != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expected) {
+					t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(windows) != tt.windows {
+				t.Errorf("Expected %d windows, got %d", tt.windows, len(windows))
+			}
+		})
+	}
+
+	windows, _ := ParseWindows("0 2 * * 6 4h namespace=apps kind=HelmRelease")
+	if w := windows[0]; w.Schedule.String() != "0 2 * * 6" || w.Duration != 4*time.Hour || w.Namespace != "apps" || w.Kind != "helmrelease" {
+		t.Errorf("Unexpected window %+v", w)
+	}
+}
@@ -0,0 +1,323 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter is a monotonically increasing value. A nil Counter is a no-op.
+type Counter struct {
+	value atomic.Uint64
+}
+
+// Inc increments the counter by one
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add increments the counter by n
+func (c *Counter) Add(n uint64) {
+	if c == nil {
+		return
+	}
+	c.value.Add(n)
+}
+
+// Value returns the current counter value
+func (c *Counter) Value() uint64 {
+	if c == nil {
+		return 0
+	}
+	return c.value.Load()
+}
+
+// Gauge is a value that can go up and down. A nil Gauge is a no-op.
+type Gauge struct {
+	value atomic.Int64
+}
+
+// Set sets the gauge value
+func (g *Gauge) Set(v int64) {
+	if g == nil {
+		return
+	}
+	g.value.Store(v)
+}
+
+// Add adds delta to the gauge value
+func (g *Gauge) Add(delta int64) {
+	if g == nil {
+		return
+	}
+	g.value.Add(delta)
+}
+
+// Value returns the current gauge value
+func (g *Gauge) Value() int64 {
+	if g == nil {
+		return 0
+	}
+	return g.value.Load()
+}
+
+// CounterVec is a set of counters partitioned by label values. A nil CounterVec is a no-op.
+type CounterVec struct {
+	labels   []string
+	mu       sync.Mutex
+	counters map[string]*Counter
+}
+
+// WithLabelValues returns the counter for the given label values
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	if v == nil {
+		return nil
+	}
+
+	key := strings.Join(values, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	counter, ok := v.counters[key]
+	if !ok {
+		counter = &Counter{}
+		v.counters[key] = counter
+	}
+	return counter
+}
+
+// Delete removes the counter for the given label values, so that it is no
+// longer exported
+func (v *CounterVec) Delete(values ...string) {
+	if v == nil {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.counters, strings.Join(values, "\xff"))
+}
+
+// GaugeVec is a set of gauges partitioned by label values. A nil GaugeVec is a no-op.
+type GaugeVec struct {
+	labels []string
+	mu     sync.Mutex
+	gauges map[string]*Gauge
+}
+
+// WithLabelValues returns the gauge for the given label values
+func (v *GaugeVec) WithLabelValues(values ...string) *Gauge {
+	if v == nil {
+		return nil
+	}
+
+	key := strings.Join(values, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	gauge, ok := v.gauges[key]
+	if !ok {
+		gauge = &Gauge{}
+		v.gauges[key] = gauge
+	}
+	return gauge
+}
+
+// metric is a registered metric family
+type metric struct {
+	name     string
+	help     string
+	kind     string
+	counter  *Counter
+	gauge    *Gauge
+	vec      *CounterVec
+	gaugeVec *GaugeVec
+}
+
+// Registry holds metrics and renders them in the Prometheus text format.
+// A nil Registry hands out nil (no-op) metrics.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]*metric
+}
+
+// NewRegistry creates a new metrics registry
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]*metric)}
+}
+
+// Counter returns the counter with the given name, registering it if needed
+func (r *Registry) Counter(name, help string) *Counter {
+	if r == nil {
+		return nil
+	}
+	return r.register(name, help, "counter", func(m *metric) { m.counter = &Counter{} }).counter
+}
+
+// Gauge returns the gauge with the given name, registering it if needed
+func (r *Registry) Gauge(name, help string) *Gauge {
+	if r == nil {
+		return nil
+	}
+	return r.register(name, help, "gauge", func(m *metric) { m.gauge = &Gauge{} }).gauge
+}
+
+// CounterVec returns the labeled counter with the given name, registering it if needed
+func (r *Registry) CounterVec(name, help string, labels ...string) *CounterVec {
+	if r == nil {
+		return nil
+	}
+	return r.register(name, help, "counter", func(m *metric) {
+		m.vec = &CounterVec{labels: labels, counters: make(map[string]*Counter)}
+	}).vec
+}
+
+// GaugeVec returns the labeled gauge with the given name, registering it if needed
+func (r *Registry) GaugeVec(name, help string, labels ...string) *GaugeVec {
+	if r == nil {
+		return nil
+	}
+	return r.register(name, help, "gauge", func(m *metric) {
+		m.gaugeVec = &GaugeVec{labels: labels, gauges: make(map[string]*Gauge)}
+	}).gaugeVec
+}
+
+// register returns an existing metric or creates it with init
+func (r *Registry) register(name, help, kind string, init func(*metric)) *metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if m, ok := r.metrics[name]; ok {
+		return m
+	}
+
+	m := &metric{name: name, help: help, kind: kind}
+	init(m)
+	r.metrics[name] = m
+	return m
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	r.mu.Unlock()
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		r.mu.Lock()
+		m := r.metrics[name]
+		r.mu.Unlock()
+
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		switch {
+		case m.counter != nil:
+			fmt.Fprintf(&b, "%s %d\n", m.name, m.counter.Value())
+		case m.gauge != nil:
+			fmt.Fprintf(&b, "%s %d\n", m.name, m.gauge.Value())
+		case m.vec != nil:
+			m.vec.mu.Lock()
+			values := make(map[string]string, len(m.vec.counters))
+			for key, counter := range m.vec.counters {
+				values[key] = strconv.FormatUint(counter.Value(), 10)
+			}
+			m.vec.mu.Unlock()
+			writeVec(&b, m.name, m.vec.labels, values)
+		case m.gaugeVec != nil:
+			m.gaugeVec.mu.Lock()
+			values := make(map[string]string, len(m.gaugeVec.gauges))
+			for key, gauge := range m.gaugeVec.gauges {
+				values[key] = strconv.FormatInt(gauge.Value(), 10)
+			}
+			m.gaugeVec.mu.Unlock()
+			writeVec(&b, m.name, m.gaugeVec.labels, values)
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// Snapshot returns the current metric values keyed by name, labeled
+// series are keyed by their comma separated label values
+func (r *Registry) Snapshot() map[string]interface{} {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	values := make(map[string]interface{}, len(r.metrics))
+	for name, m := range r.metrics {
+		switch {
+		case m.counter != nil:
+			values[name] = m.counter.Value()
+		case m.gauge != nil:
+			values[name] = m.gauge.Value()
+		case m.vec != nil:
+			m.vec.mu.Lock()
+			series := make(map[string]uint64, len(m.vec.counters))
+			for key, counter := range m.vec.counters {
+				series[strings.ReplaceAll(key, "\xff", ",")] = counter.Value()
+			}
+			m.vec.mu.Unlock()
+			values[name] = series
+		case m.gaugeVec != nil:
+			m.gaugeVec.mu.Lock()
+			series := make(map[string]int64, len(m.gaugeVec.gauges))
+			for key, gauge := range m.gaugeVec.gauges {
+				series[strings.ReplaceAll(key, "\xff", ",")] = gauge.Value()
+			}
+			m.gaugeVec.mu.Unlock()
+			values[name] = series
+		}
+	}
+	return values
+}
+
+// writeVec writes each labeled series of a vector in a stable order, values
+// being keyed like the vector's series
+func writeVec(b *strings.Builder, name string, labels []string, values map[string]string) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		parts := strings.Split(key, "\xff")
+		pairs := make([]string, 0, len(labels))
+		for i, label := range labels {
+			value := ""
+			if i < len(parts) {
+				value = parts[i]
+			}
+			pairs = append(pairs, fmt.Sprintf("%s=%q", label, value))
+		}
+		fmt.Fprintf(b, "%s{%s} %s\n", name, strings.Join(pairs, ","), values[key])
+	}
+}
+
+// Handler returns an HTTP handler serving the registry
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if _, err := r.WriteTo(w); err != nil {
+			// Response header already written, can't do much more
+			return
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRegistry_Counter(t *testing.T) {
+	registry := NewRegistry()
+
+	counter := registry.Counter("events_total", "Total events")
+	counter.Inc()
+	counter.Add(2)
+
+	if counter.Value() != 3 {
+		t.Errorf("Expected 3, got %d", counter.Value())
+	}
+
+	// Same name returns the same counter
+	if registry.Counter("events_total", "Total events") != counter {
+		t.Error("Expected the registered counter to be reused")
+	}
+}
+
+func TestRegistry_Gauge(t *testing.T) {
+	gauge := NewRegistry().Gauge("queue_depth", "Queue depth")
+	gauge.Set(5)
+	gauge.Add(-2)
+
+	if gauge.Value() != 3 {
+		t.Errorf("Expected 3, got %d", gauge.Value())
+	}
+}
+
+func TestRegistry_CounterVec(t *testing.T) {
+	vec := NewRegistry().CounterVec("decisions_total", "Decisions", "outcome")
+
+	vec.WithLabelValues("sent").Inc()
+	vec.WithLabelValues("sent").Inc()
+	vec.WithLabelValues("filtered").Inc()
+
+	if vec.WithLabelValues("sent").Value() != 2 {
+		t.Errorf("Expected 2, got %d", vec.WithLabelValues("sent").Value())
+	}
+}
+
+func TestCounterVec_Delete(t *testing.T) {
+	registry := NewRegistry()
+	vec := registry.CounterVec("limited_total", "Limited", "namespace")
+	vec.WithLabelValues("apps").Inc()
+	vec.WithLabelValues("infra").Inc()
+
+	vec.Delete("apps")
+
+	var out strings.Builder
+	registry.WriteTo(&out)
+	if strings.Contains(out.String(), `namespace="apps"`) || !strings.Contains(out.String(), `limited_total{namespace="infra"} 1`) {
+		t.Errorf("Expected only the infra series, got:\n%s", out.String())
+	}
+	if vec.WithLabelValues("apps").Value() != 0 {
+		t.Error("Expected a deleted counter to start again from 0")
+	}
+
+	var nilVec *CounterVec
+	nilVec.Delete("apps")
+}
+
+func TestRegistry_NilIsNoop(t *testing.T) {
+	var registry *Registry
+
+	counter := registry.Counter("x", "x")
+	counter.Inc()
+	registry.Gauge("y", "y").Set(1)
+	registry.CounterVec("z", "z", "label").WithLabelValues("a").Inc()
+
+	if counter.Value() != 0 {
+		t.Errorf("Expected nil counter to report 0, got %d", counter.Value())
+	}
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	registry := NewRegistry()
+	registry.Counter("b_total", "Second metric").Add(7)
+	registry.Gauge("a_depth", "First metric").Set(2)
+	registry.CounterVec("c_total", "Labeled", "rule").WithLabelValues("dedup").Inc()
+
+	rr := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	expected := strings.Join([]string{
+		"# HELP a_depth First metric",
+		"# TYPE a_depth gauge",
+		"a_depth 2",
+		"# HELP b_total Second metric",
+		"# TYPE b_total counter",
+		"b_total 7",
+		"# HELP c_total Labeled",
+		"# TYPE c_total counter",
+		`c_total{rule="dedup"} 1`,
+		"",
+	}, "\n")
+
+	if rr.Body.String() != expected {
+		t.Errorf("Unexpected output:\n%s\nExpected:\n%s", rr.Body.String(), expected)
+	}
+}
+
+func TestCounter_Concurrent(t *testing.T) {
+	counter := NewRegistry().Counter("concurrent_total", "Concurrent")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counter.Inc()
+		}()
+	}
+	wg.Wait()
+
+	if counter.Value() != 50 {
+		t.Errorf("Expected 50, got %d", counter.Value())
+	}
+}
+
+func TestRegistry_Snapshot(t *testing.T) {
+	registry := NewRegistry()
+	registry.Counter("sent_total", "Sent").Add(3)
+	registry.Gauge("queue_depth", "Queue").Set(-2)
+	registry.CounterVec("dropped_total", "Dropped", "rule", "reason").WithLabelValues("dedup", "window").Inc()
+
+	snapshot := registry.Snapshot()
+	if snapshot["sent_total"] != uint64(3) {
+		t.Errorf("Expected sent_total 3, got %v", snapshot["sent_total"])
+	}
+	if snapshot["queue_depth"] != int64(-2) {
+		t.Errorf("Expected queue_depth -2, got %v", snapshot["queue_depth"])
+	}
+	series, ok := snapshot["dropped_total"].(map[string]uint64)
+	if !ok || series["dedup,window"] != 1 {
+		t.Errorf("Expected labeled series, got %v", snapshot["dropped_total"])
+	}
+
+	var nilRegistry *Registry
+	if nilRegistry.Snapshot() != nil {
+		t.Error("Expected nil snapshot from nil registry")
+	}
+}
+
+func TestRegistry_GaugeVec(t *testing.T) {
+	registry := NewRegistry()
+	vec := registry.GaugeVec("queue_depth", "Queued", "worker")
+
+	vec.WithLabelValues("0").Add(3)
+	vec.WithLabelValues("0").Add(-1)
+	vec.WithLabelValues("1").Set(5)
+
+	if vec.WithLabelValues("0").Value() != 2 {
+		t.Errorf("Expected 2, got %d", vec.WithLabelValues("0").Value())
+	}
+
+	var out strings.Builder
+	if _, err := registry.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	expected := "# HELP queue_depth Queued\n# TYPE queue_depth gauge\nqueue_depth{worker=\"0\"} 2\nqueue_depth{worker=\"1\"} 5\n"
+	if out.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	series, ok := registry.Snapshot()["queue_depth"].(map[string]int64)
+	if !ok || series["1"] != 5 {
+		t.Errorf("Expected labeled series, got %v", registry.Snapshot()["queue_depth"])
+	}
+
+	var nilRegistry *Registry
+	nilRegistry.GaugeVec("x", "x", "label").WithLabelValues("a").Add(1)
+}
@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// Mode selects how the Coordinator dispatches to multiple senders
+type Mode string
+
+const (
+	// ModeFanOut sends to every sender
+	ModeFanOut Mode = "fanout"
+	// ModeFailover sends to the senders in order until one succeeds
+	ModeFailover Mode = "failover"
+)
+
+// Notification is a provider-neutral notification
+type Notification struct {
+	Title    string
+	Body     string
+	Severity string
+	Link     string
+	Silent   bool // Delivered without sound or vibration, e.g. during quiet hours
+	Quiet    bool // Silent because of quiet hours, whose messages may expire
+
+	// Event is the source alert, if any, for provider-specific enrichment
+	Event *types.FluxAlert
+}
+
+// NotificationSender delivers notifications to a single backend
+type NotificationSender interface {
+	Name() string
+	Send(ctx context.Context, n *Notification) error
+}
+
+// HTTPClient interface for dependency injection
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Result is the outcome of a single sender
+type Result struct {
+	Provider string
+	Err      error
+}
+
+// Coordinator dispatches notifications to a set of senders
+type Coordinator struct {
+	mode    Mode
+	senders []NotificationSender
+}
+
+// NewCoordinator creates a new coordinator
+func NewCoordinator(mode Mode, senders ...NotificationSender) *Coordinator {
+	return &Coordinator{
+		mode:    mode,
+		senders: senders,
+	}
+}
+
+// Providers returns the names of the configured senders
+func (c *Coordinator) Providers() []string {
+	names := make([]string, len(c.senders))
+	for i, sender := range c.senders {
+		names[i] = sender.Name()
+	}
+	return names
+}
+
+// Send dispatches the notification according to the coordinator mode.
+// It returns the per-sender results and an error if no sender succeeded.
+func (c *Coordinator) Send(ctx context.Context, n *Notification) ([]Result, error) {
+	if len(c.senders) == 0 {
+		return nil, fmt.Errorf("no notification senders configured")
+	}
+
+	var results []Result
+	if c.mode == ModeFailover {
+		results = c.failover(ctx, n)
+	} else {
+		results = c.fanOut(ctx, n)
+	}
+
+	var errs []error
+	for _, result := range results {
+		if result.Err == nil {
+			return results, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", result.Provider, result.Err))
+	}
+
+	if len(errs) == 1 {
+		return results, results[0].Err
+	}
+	return results, errors.Join(errs...)
+}
+
+// fanOut sends to all senders concurrently
+func (c *Coordinator) fanOut(ctx context.Context, n *Notification) []Result {
+	results := make([]Result, len(c.senders))
+
+	// A single provider needs no goroutine
+	if len(c.senders) == 1 {
+		results[0] = Result{Provider: c.senders[0].Name(), Err: c.senders[0].Send(ctx, n)}
+		return results
+	}
+
+	var wg sync.WaitGroup
+	for i, sender := range c.senders {
+		wg.Add(1)
+		go func(i int, sender NotificationSender) {
+			defer wg.Done()
+			results[i] = Result{Provider: sender.Name(), Err: sender.Send(ctx, n)}
+		}(i, sender)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// failover sends to senders in order, stopping at the first success
+func (c *Coordinator) failover(ctx context.Context, n *Notification) []Result {
+	results := make([]Result, 0, len(c.senders))
+
+	for _, sender := range c.senders {
+		err := sender.Send(ctx, n)
+		results = append(results, Result{Provider: sender.Name(), Err: err})
+		if err == nil {
+			break
+		}
+	}
+
+	return results
+}
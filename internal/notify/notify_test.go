@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// MockHTTPClient is a mock implementation of HTTPClient
+type MockHTTPClient struct {
+	DoFunc func(req *http.Request) (*http.Response, error)
+}
+
+func (m *MockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return m.DoFunc(req)
+}
+
+// MockSender records calls and returns a fixed error
+type MockSender struct {
+	name  string
+	err   error
+	mu    sync.Mutex
+	calls int
+}
+
+func (m *MockSender) Name() string {
+	return m.name
+}
+
+func (m *MockSender) Send(ctx context.Context, n *Notification) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	return m.err
+}
+
+func TestCoordinator_FanOut(t *testing.T) {
+	tests := []struct {
+		name        string
+		errs        []error
+		expectError bool
+	}{
+		{"all succeed", []error{nil, nil}, false},
+		{"one fails", []error{fmt.Errorf("down"), nil}, false},
+		{"all fail", []error{fmt.Errorf("down"), fmt.Errorf("also down")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			senders := []NotificationSender{
+				&MockSender{name: "first", err: tt.errs[0]},
+				&MockSender{name: "second", err: tt.errs[1]},
+			}
+
+			coordinator := NewCoordinator(ModeFanOut, senders...)
+			results, err := coordinator.Send(context.Background(), &Notification{Body: "test"})
+
+			if (err != nil) != tt.expectError {
+				t.Errorf("Expected error %v, got %v", tt.expectError, err)
+			}
+
+			if len(results) != 2 {
+				t.Fatalf("Expected 2 results, got %d", len(results))
+			}
+
+			for i, sender := range senders {
+				mock := sender.(*MockSender)
+				if mock.calls != 1 {
+					t.Errorf("Sender %s: expected 1 call, got %d", mock.name, mock.calls)
+				}
+				if results[i].Provider != mock.name {
+					t.Errorf("Result %d: expected provider %s, got %s", i, mock.name, results[i].Provider)
+				}
+				if results[i].Err != tt.errs[i] {
+					t.Errorf("Result %d: expected error %v, got %v", i, tt.errs[i], results[i].Err)
+				}
+			}
+		})
+	}
+}
+
+func TestCoordinator_Failover(t *testing.T) {
+	tests := []struct {
+		name          string
+		errs          []error
+		expectedCalls []int
+		expectResults int
+		expectError   bool
+	}{
+		{"first succeeds", []error{nil, nil}, []int{1, 0}, 1, false},
+		{"fails over to second", []error{fmt.Errorf("down"), nil}, []int{1, 1}, 2, false},
+		{"all fail", []error{fmt.Errorf("down"), fmt.Errorf("also down")}, []int{1, 1}, 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := &MockSender{name: "first", err: tt.errs[0]}
+			second := &MockSender{name: "second", err: tt.errs[1]}
+
+			coordinator := NewCoordinator(ModeFailover, first, second)
+			results, err := coordinator.Send(context.Background(), &Notification{Body: "test"})
+
+			if (err != nil) != tt.expectError {
+				t.Errorf("Expected error %v, got %v", tt.expectError, err)
+			}
+
+			if len(results) != tt.expectResults {
+				t.Errorf("Expected %d results, got %d", tt.expectResults, len(results))
+			}
+
+			if first.calls != tt.expectedCalls[0] || second.calls != tt.expectedCalls[1] {
+				t.Errorf("Expected calls %v, got [%d %d]", tt.expectedCalls, first.calls, second.calls)
+			}
+		})
+	}
+}
+
+func TestCoordinator_ErrorIncludesProviders(t *testing.T) {
+	coordinator := NewCoordinator(ModeFanOut,
+		&MockSender{name: "first", err: fmt.Errorf("timeout")},
+		&MockSender{name: "second", err: fmt.Errorf("refused")},
+	)
+
+	_, err := coordinator.Send(context.Background(), &Notification{})
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+
+	for _, want := range []string{"first: timeout", "second: refused"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got %q", want, err.Error())
+		}
+	}
+}
+
+func TestCoordinator_NoSenders(t *testing.T) {
+	coordinator := NewCoordinator(ModeFanOut)
+
+	if _, err := coordinator.Send(context.Background(), &Notification{}); err == nil {
+		t.Error("Expected error with no senders")
+	}
+}
+
+func TestCoordinator_Providers(t *testing.T) {
+	coordinator := NewCoordinator(ModeFanOut, &MockSender{name: "pushover"}, &MockSender{name: "ntfy"})
+
+	providers := coordinator.Providers()
+	if len(providers) != 2 || providers[0] != "pushover" || providers[1] != "ntfy" {
+		t.Errorf("Unexpected providers: %v", providers)
+	}
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// NtfySender sends notifications to an ntfy topic
+type NtfySender struct {
+	client HTTPClient
+	url    string
+	token  string
+}
+
+// NewNtfySender creates a new ntfy sender for the given server and topic
+func NewNtfySender(client HTTPClient, serverURL, topic, token string) *NtfySender {
+	return &NtfySender{
+		client: client,
+		url:    strings.TrimRight(serverURL, "/") + "/" + topic,
+		token:  token,
+	}
+}
+
+// Name returns the provider name
+func (s *NtfySender) Name() string {
+	return "ntfy"
+}
+
+// Send posts the notification to the ntfy topic
+func (s *NtfySender) Send(ctx context.Context, n *Notification) error {
+	if n == nil {
+		return fmt.Errorf("notification is nil")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.url, strings.NewReader(n.Body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("Title", n.Title)
+	if n.Silent {
+		req.Header.Set("Priority", "1")
+	} else {
+		req.Header.Set("Priority", NtfyPriority(n.Severity))
+	}
+	if n.Link != "" {
+		req.Header.Set("Click", n.Link)
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("ntfy returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return fmt.Errorf("failed to discard response body: %w", err)
+	}
+	return nil
+}
+
+// NtfyPriority maps a Flux severity to an ntfy priority (pure function)
+func NtfyPriority(severity string) string {
+	switch strings.ToLower(severity) {
+	case "error":
+		return "5"
+	case "warning", "warn":
+		return "4"
+	default:
+		return "3"
+	}
+}
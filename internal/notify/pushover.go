@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/telemetry"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// PushoverMessageSender is implemented by pushover.PushoverClient
+type PushoverMessageSender interface {
+	SendMessage(ctx context.Context, msg *types.PushoverMessage) error
+}
+
+// PushoverMessageFactory converts a Notification into a Pushover message,
+// ctx bounding any request it needs, e.g. to fetch an attachment
+type PushoverMessageFactory func(ctx context.Context, n *Notification) *types.PushoverMessage
+
+// PushoverSender adapts a Pushover client to NotificationSender
+type PushoverSender struct {
+	client PushoverMessageSender
+	build  PushoverMessageFactory
+}
+
+// NewPushoverSender creates a new Pushover sender
+func NewPushoverSender(client PushoverMessageSender, build PushoverMessageFactory) *PushoverSender {
+	return &PushoverSender{
+		client: client,
+		build:  build,
+	}
+}
+
+// Name returns the provider name
+func (p *PushoverSender) Name() string {
+	return "pushover"
+}
+
+// Send sends the notification through the Pushover client, traced as a
+// client span when ctx carries a span
+func (p *PushoverSender) Send(ctx context.Context, n *Notification) error {
+	if telemetry.SpanFromContext(ctx) == nil {
+		return p.client.SendMessage(ctx, p.build(ctx, n))
+	}
+
+	ctx, span := telemetry.StartSpan(ctx, "pushover.send", telemetry.SpanKindClient, spanAttributes(n)...)
+	defer span.End()
+
+	err := p.client.SendMessage(ctx, p.build(ctx, n))
+	span.RecordError(err)
+	return err
+}
+
+// spanAttributes describes the notification for tracing (pure function)
+func spanAttributes(n *Notification) []telemetry.Attribute {
+	attrs := []telemetry.Attribute{telemetry.String("alert.severity", n.Severity)}
+	if n.Event != nil {
+		attrs = append(attrs,
+			telemetry.String("alert.object.kind", n.Event.InvolvedObject.Kind),
+			telemetry.String("alert.object.name", n.Event.InvolvedObject.Name),
+		)
+	}
+	return attrs
+}
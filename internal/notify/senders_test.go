@@ -0,0 +1,303 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// MockPushoverClient for testing
+type MockPushoverClient struct {
+	msg *types.PushoverMessage
+	err error
+}
+
+func (m *MockPushoverClient) SendMessage(ctx context.Context, msg *types.PushoverMessage) error {
+	m.msg = msg
+	return m.err
+}
+
+func okResponse() *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader("{}")),
+	}
+}
+
+func TestPushoverSender_Send(t *testing.T) {
+	client := &MockPushoverClient{}
+	sender := NewPushoverSender(client, func(ctx context.Context, n *Notification) *types.PushoverMessage {
+		return &types.PushoverMessage{Title: n.Title, Message: n.Body}
+	})
+
+	if sender.Name() != "pushover" {
+		t.Errorf("Expected name pushover, got %s", sender.Name())
+	}
+
+	err := sender.Send(context.Background(), &Notification{Title: "FluxCD", Body: "hello"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if client.msg == nil || client.msg.Title != "FluxCD" || client.msg.Message != "hello" {
+		t.Errorf("Unexpected Pushover message: %+v", client.msg)
+	}
+}
+
+func TestNtfySender_Send(t *testing.T) {
+	var captured *http.Request
+	var body string
+
+	client := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			captured = req
+			data, _ := io.ReadAll(req.Body)
+			body = string(data)
+			return okResponse(), nil
+		},
+	}
+
+	sender := NewNtfySender(client, "https://ntfy.example.com/", "flux", "secret")
+	err := sender.Send(context.Background(), &Notification{
+		Title:    "FluxCD",
+		Body:     "Reconciliation failed",
+		Severity: "error",
+		Link:     "https://example.com",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if captured.Method != "POST" {
+		t.Errorf("Expected POST method, got %s", captured.Method)
+	}
+
+	if captured.URL.String() != "https://ntfy.example.com/flux" {
+		t.Errorf("Unexpected URL: %s", captured.URL)
+	}
+
+	if body != "Reconciliation failed" {
+		t.Errorf("Unexpected body: %s", body)
+	}
+
+	expectedHeaders := map[string]string{
+		"Title":         "FluxCD",
+		"Priority":      "5",
+		"Click":         "https://example.com",
+		"Authorization": "Bearer secret",
+	}
+	for header, expected := range expectedHeaders {
+		if got := captured.Header.Get(header); got != expected {
+			t.Errorf("Header %s: expected %q, got %q", header, expected, got)
+		}
+	}
+}
+
+func TestNtfySender_Silent(t *testing.T) {
+	var priority string
+	client := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			priority = req.Header.Get("Priority")
+			return okResponse(), nil
+		},
+	}
+
+	sender := NewNtfySender(client, "https://ntfy.example.com", "flux", "")
+	if err := sender.Send(context.Background(), &Notification{Body: "m", Severity: "warning", Silent: true}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if priority != "1" {
+		t.Errorf("Expected minimum priority for a silent notification, got %q", priority)
+	}
+}
+
+func TestNtfySender_Errors(t *testing.T) {
+	tests := []struct {
+		name          string
+		response      *http.Response
+		err           error
+		errorContains string
+	}{
+		{
+			name:          "network error",
+			err:           fmt.Errorf("connection refused"),
+			errorContains: "failed to send request",
+		},
+		{
+			name: "bad status",
+			response: &http.Response{
+				StatusCode: http.StatusForbidden,
+				Body:       io.NopCloser(strings.NewReader("forbidden")),
+			},
+			errorContains: "ntfy returned status 403",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &MockHTTPClient{
+				DoFunc: func(req *http.Request) (*http.Response, error) {
+					return tt.response, tt.err
+				},
+			}
+
+			sender := NewNtfySender(client, "https://ntfy.sh", "flux", "")
+			err := sender.Send(context.Background(), &Notification{Body: "test"})
+			if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+				t.Errorf("Expected error containing %q, got %v", tt.errorContains, err)
+			}
+		})
+	}
+}
+
+func TestNtfyPriority(t *testing.T) {
+	tests := map[string]string{
+		"error":   "5",
+		"ERROR":   "5",
+		"warning": "4",
+		"info":    "3",
+		"":        "3",
+	}
+
+	for severity, expected := range tests {
+		if got := NtfyPriority(severity); got != expected {
+			t.Errorf("NtfyPriority(%q) = %s, want %s", severity, got, expected)
+		}
+	}
+}
+
+func TestWebhookSender_Send(t *testing.T) {
+	var captured *http.Request
+	var payload webhookPayload
+
+	client := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			captured = req
+			if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+				t.Errorf("Failed to decode payload: %v", err)
+			}
+			return &http.Response{
+				StatusCode: http.StatusAccepted,
+				Body:       io.NopCloser(strings.NewReader("")),
+			}, nil
+		},
+	}
+
+	sender := NewWebhookSender(client, "https://hooks.example.com/flux", "secret")
+	err := sender.Send(context.Background(), &Notification{
+		Title:    "FluxCD",
+		Body:     "Reconciliation failed",
+		Severity: "error",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if captured.Header.Get("Content-Type") != types.ContentTypeJSON {
+		t.Errorf("Unexpected Content-Type: %s", captured.Header.Get("Content-Type"))
+	}
+
+	if captured.Header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("Unexpected Authorization: %s", captured.Header.Get("Authorization"))
+	}
+
+	if payload.Title != "FluxCD" || payload.Body != "Reconciliation failed" || payload.Severity != "error" {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+}
+
+func TestWebhookSender_BadStatus(t *testing.T) {
+	client := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusInternalServerError,
+				Body:       io.NopCloser(strings.NewReader("boom")),
+			}, nil
+		},
+	}
+
+	sender := NewWebhookSender(client, "https://hooks.example.com/flux", "")
+	err := sender.Send(context.Background(), &Notification{Body: "test"})
+	if err == nil || !strings.Contains(err.Error(), "webhook returned status 500") {
+		t.Errorf("Expected status error, got %v", err)
+	}
+}
+
+func TestSlackSender_Send(t *testing.T) {
+	var captured *http.Request
+	var payload slackPayload
+
+	client := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			captured = req
+			if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+				t.Errorf("Failed to decode payload: %v", err)
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+		},
+	}
+
+	sender := NewSlackSender(client, "https://hooks.slack.com/services/T/B/X")
+	err := sender.Send(context.Background(), &Notification{
+		Title:    "FluxCD",
+		Body:     "HealthCheckFailed [ERROR]\n<apps> & more",
+		Severity: "error",
+		Link:     "https://flux.example.com",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if captured.URL.String() != "https://hooks.slack.com/services/T/B/X" || captured.Header.Get("Content-Type") != types.ContentTypeJSON {
+		t.Errorf("Unexpected request %s with Content-Type %s", captured.URL, captured.Header.Get("Content-Type"))
+	}
+
+	expected := slackPayload{
+		Text: "FluxCD: HealthCheckFailed [ERROR]",
+		Blocks: []slackBlock{
+			{Type: "header", Text: &slackText{Type: "plain_text", Text: "FluxCD"}},
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: "HealthCheckFailed [ERROR]\n&lt;apps&gt; &amp; more"}},
+			{Type: "context", Elements: []slackText{{Type: "mrkdwn", Text: "Severity: *error* | <https://flux.example.com|Details>"}}},
+		},
+	}
+	got, _ := json.Marshal(payload)
+	want, _ := json.Marshal(expected)
+	if string(got) != string(want) {
+		t.Errorf("Expected payload %s, got %s", want, got)
+	}
+}
+
+func TestSlackSender_Limits(t *testing.T) {
+	payload := newSlackPayload(&Notification{Title: strings.Repeat("t", 200), Body: strings.Repeat("b", 4000)})
+
+	if n := len([]rune(payload.Blocks[0].Text.Text)); n != slackMaxHeaderLength {
+		t.Errorf("Expected header of %d characters, got %d", slackMaxHeaderLength, n)
+	}
+	if n := len([]rune(payload.Blocks[1].Text.Text)); n != slackMaxSectionLength {
+		t.Errorf("Expected section of %d characters, got %d", slackMaxSectionLength, n)
+	}
+}
+
+func TestSlackSender_BadStatus(t *testing.T) {
+	client := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader("no_service"))}, nil
+		},
+	}
+
+	sender := NewSlackSender(client, "https://hooks.slack.com/services/T/B/X")
+	err := sender.Send(context.Background(), &Notification{Body: "test"})
+	if err == nil || err.Error() != "slack returned status 404: no_service" {
+		t.Errorf("Expected status error, got %v", err)
+	}
+
+	if err := sender.Send(context.Background(), nil); err == nil {
+		t.Error("Expected error for nil notification")
+	}
+}
@@ -0,0 +1,129 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// Slack Block Kit limits, in characters
+const (
+	slackMaxHeaderLength  = 150
+	slackMaxSectionLength = 3000
+)
+
+// slackText is a Block Kit text object
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackBlock is a Block Kit layout block
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+// slackPayload is the body posted to an incoming webhook. Text is the
+// fallback shown in notifications, which do not render blocks.
+type slackPayload struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+// SlackSender posts notifications as Block Kit messages to a Slack-compatible
+// incoming webhook
+type SlackSender struct {
+	client HTTPClient
+	url    string
+}
+
+// NewSlackSender creates a new Slack incoming webhook sender
+func NewSlackSender(client HTTPClient, url string) *SlackSender {
+	return &SlackSender{
+		client: client,
+		url:    url,
+	}
+}
+
+// Name returns the provider name
+func (s *SlackSender) Name() string {
+	return "slack"
+}
+
+// Send posts the notification to the incoming webhook
+func (s *SlackSender) Send(ctx context.Context, n *Notification) error {
+	if n == nil {
+		return fmt.Errorf("notification is nil")
+	}
+
+	body, err := json.Marshal(newSlackPayload(n))
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", types.ContentTypeJSON)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("slack returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return fmt.Errorf("failed to discard response body: %w", err)
+	}
+	return nil
+}
+
+// newSlackPayload builds the Block Kit message for n: a header with the
+// title, the body as a section and the severity as context (pure function)
+func newSlackPayload(n *Notification) slackPayload {
+	footer := "Severity: *" + SlackEscape(n.Severity) + "*"
+	if n.Link != "" {
+		footer += " | <" + n.Link + "|Details>"
+	}
+
+	firstLine, _, _ := strings.Cut(n.Body, "\n")
+	return slackPayload{
+		Text: truncate(n.Title+": "+firstLine, slackMaxSectionLength),
+		Blocks: []slackBlock{
+			{Type: "header", Text: &slackText{Type: "plain_text", Text: truncate(n.Title, slackMaxHeaderLength)}},
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: truncate(SlackEscape(n.Body), slackMaxSectionLength)}},
+			{Type: "context", Elements: []slackText{{Type: "mrkdwn", Text: footer}}},
+		},
+	}
+}
+
+// slackEscaper escapes the characters Slack treats as control sequences
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// SlackEscape escapes text for Slack mrkdwn (pure function)
+func SlackEscape(text string) string {
+	return slackEscaper.Replace(text)
+}
+
+// truncate shortens s to limit characters with an ellipsis (pure function)
+func truncate(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	return string([]rune(s)[:limit-1]) + types.TruncationMarker
+}
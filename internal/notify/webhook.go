@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// webhookPayload is the JSON body posted by WebhookSender
+type webhookPayload struct {
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+	Severity string `json:"severity"`
+	Link     string `json:"link,omitempty"`
+}
+
+// WebhookSender posts notifications as JSON to an arbitrary URL
+type WebhookSender struct {
+	client HTTPClient
+	url    string
+	token  string
+}
+
+// NewWebhookSender creates a new outgoing webhook sender
+func NewWebhookSender(client HTTPClient, url, token string) *WebhookSender {
+	return &WebhookSender{
+		client: client,
+		url:    url,
+		token:  token,
+	}
+}
+
+// Name returns the provider name
+func (s *WebhookSender) Name() string {
+	return "webhook"
+}
+
+// Send posts the notification as JSON
+func (s *WebhookSender) Send(ctx context.Context, n *Notification) error {
+	if n == nil {
+		return fmt.Errorf("notification is nil")
+	}
+
+	body, err := json.Marshal(webhookPayload{
+		Title:    n.Title,
+		Body:     n.Body,
+		Severity: n.Severity,
+		Link:     n.Link,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", types.ContentTypeJSON)
+	if s.token != "" {
+		req.Header.Set("Authorization", types.BearerPrefix+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return fmt.Errorf("failed to discard response body: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchemaOf(t *testing.T) {
+	type inner struct {
+		Count int `json:"count"`
+	}
+	type sample struct {
+		Name     string            `json:"name" doc:"Name of the sample"`
+		Enabled  bool              `json:"enabled,omitempty"`
+		Ratio    float64           `json:"ratio"`
+		Labels   map[string]string `json:"labels"`
+		Items    []inner           `json:"items"`
+		Data     []byte            `json:"data"`
+		At       time.Time         `json:"at"`
+		Optional *inner            `json:"optional"`
+		Untagged string
+		Skipped  string `json:"-"`
+		private  string
+	}
+
+	schema, err := json.Marshal(SchemaOf(sample{private: "unused"}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{"type":"object","properties":{` +
+		`"Untagged":{"type":"string"},` +
+		`"at":{"type":"string","format":"date-time"},` +
+		`"data":{"type":"string","format":"byte"},` +
+		`"enabled":{"type":"boolean"},` +
+		`"items":{"type":"array","items":{"type":"object","properties":{"count":{"type":"integer"}}}},` +
+		`"labels":{"type":"object","additionalProperties":{"type":"string"}},` +
+		`"name":{"type":"string","description":"Name of the sample"},` +
+		`"optional":{"type":"object","nullable":true,"properties":{"count":{"type":"integer"}}},` +
+		`"ratio":{"type":"number"}}}`
+	if string(schema) != expected {
+		t.Errorf("Expected %s, got %s", expected, schema)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		document    string
+		expectedErr string
+	}{
+		{
+			name:     "valid",
+			document: `{"openapi":"3.0.3","info":{"title":"t","version":"1"},"paths":{"/webhook":{"post":{"responses":{"200":{"description":"ok","content":{"application/json":{"schema":{"type":"object","properties":{"status":{"type":"string"}}}}}}}}}}}`,
+		},
+		{
+			name:        "missing info",
+			document:    `{"openapi":"3.0.3","paths":{}}`,
+			expectedErr: `/: missing required property "info"`,
+		},
+		{
+			name:        "wrong version",
+			document:    `{"openapi":"2.0","info":{"title":"t","version":"1"},"paths":{}}`,
+			expectedErr: `/openapi: must match`,
+		},
+		{
+			name:        "relative path",
+			document:    `{"openapi":"3.0.3","info":{"title":"t","version":"1"},"paths":{"webhook":{}}}`,
+			expectedErr: `/paths/webhook: is not allowed`,
+		},
+		{
+			name:        "response without description",
+			document:    `{"openapi":"3.0.3","info":{"title":"t","version":"1"},"paths":{"/webhook":{"post":{"responses":{"200":{}}}}}}`,
+			expectedErr: `/paths/~1webhook/post/responses/200: missing required property "description"`,
+		},
+		{
+			name:        "no responses",
+			document:    `{"openapi":"3.0.3","info":{"title":"t","version":"1"},"paths":{"/webhook":{"post":{"responses":{}}}}}`,
+			expectedErr: `/paths/~1webhook/post/responses: must have at least 1 properties`,
+		},
+		{
+			name:        "unknown schema type",
+			document:    `{"openapi":"3.0.3","info":{"title":"t","version":"1"},"paths":{"/webhook":{"post":{"responses":{"200":{"description":"ok","content":{"application/json":{"schema":{"type":"text"}}}}}}}}}`,
+			expectedErr: `/paths/~1webhook/post/responses/200/content/application~1json/schema/type: must be one of`,
+		},
+		{
+			name:        "mistyped",
+			document:    `{"openapi":"3.0.3","info":{"title":1,"version":"1"},"paths":{}}`,
+			expectedErr: `/info/title: must be string, not integer`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate([]byte(tt.document))
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Expected %q, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
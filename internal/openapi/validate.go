@@ -0,0 +1,194 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// metaSchema is the subset of the OpenAPI 3.0 JSON Schema the rendered
+// documents are checked against
+//
+//go:embed schema.json
+var metaSchema []byte
+
+// Validate checks a JSON document against the OpenAPI 3.0 meta-schema,
+// returning every violation
+func Validate(document []byte) error {
+	var meta map[string]any
+	if err := json.Unmarshal(metaSchema, &meta); err != nil {
+		return fmt.Errorf("invalid meta-schema: %w", err)
+	}
+	var value any
+	if err := json.Unmarshal(document, &value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	definitions, _ := meta["definitions"].(map[string]any)
+	v := &validator{definitions: definitions}
+	v.validate(meta, value, "")
+	return errors.Join(v.errs...)
+}
+
+// validator applies the JSON Schema keywords the meta-schema uses: $ref,
+// type, enum, pattern, required, minProperties, properties,
+// patternProperties, additionalProperties and items
+type validator struct {
+	definitions map[string]any
+	errs        []error
+}
+
+// validate checks value at path against schema, recording violations
+func (v *validator) validate(schema map[string]any, value any, path string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		definition, ok := v.definitions[strings.TrimPrefix(ref, "#/definitions/")].(map[string]any)
+		if !ok {
+			v.fail(path, "unresolved $ref %s", ref)
+			return
+		}
+		schema = definition
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !matchesType(types, value) {
+		v.fail(path, "must be %s, not %s", strings.Join(types, " or "), jsonType(value))
+		return
+	}
+	if enum, ok := schema["enum"].([]any); ok && !containsValue(enum, value) {
+		v.fail(path, "must be one of %v", enum)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		if s, isString := value.(string); isString && !regexp.MustCompile(pattern).MatchString(s) {
+			v.fail(path, "must match %s", pattern)
+		}
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		v.validateObject(schema, value, path)
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range value {
+				v.validate(items, item, fmt.Sprintf("%s/%d", path, i))
+			}
+		}
+	}
+}
+
+// validateObject checks the properties of object at path against schema
+func (v *validator) validateObject(schema map[string]any, object map[string]any, path string) {
+	required, _ := schema["required"].([]any)
+	for _, name := range required {
+		if _, ok := object[name.(string)]; !ok {
+			v.fail(path, "missing required property %q", name)
+		}
+	}
+	if minimum, ok := schema["minProperties"].(float64); ok && len(object) < int(minimum) {
+		v.fail(path, "must have at least %d properties", int(minimum))
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	patterns, _ := schema["patternProperties"].(map[string]any)
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		child := path + "/" + strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+		matched := false
+		if property, ok := properties[key].(map[string]any); ok {
+			v.validate(property, object[key], child)
+			matched = true
+		}
+		for pattern, property := range patterns {
+			if regexp.MustCompile(pattern).MatchString(key) {
+				v.validate(property.(map[string]any), object[key], child)
+				matched = true
+			}
+		}
+		if matched {
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.fail(child, "is not allowed")
+			}
+		case map[string]any:
+			v.validate(additional, object[key], child)
+		}
+	}
+}
+
+// fail records a violation at path
+func (v *validator) fail(path, format string, args ...any) {
+	if path == "" {
+		path = "/"
+	}
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+// schemaTypes returns the types a type keyword allows (pure function)
+func schemaTypes(keyword any) []string {
+	switch keyword := keyword.(type) {
+	case string:
+		return []string{keyword}
+	case []any:
+		types := make([]string, 0, len(keyword))
+		for _, t := range keyword {
+			types = append(types, t.(string))
+		}
+		return types
+	}
+	return nil
+}
+
+// matchesType reports whether value is of one of types, integers being
+// numbers too (pure function)
+func matchesType(types []string, value any) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON type of a decoded value (pure function)
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// containsValue reports whether enum holds value (pure function)
+func containsValue(enum []any, value any) bool {
+	for _, allowed := range enum {
+		if reflect.DeepEqual(allowed, value) {
+			return true
+		}
+	}
+	return false
+}
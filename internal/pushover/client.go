@@ -1,35 +1,267 @@
 package pushover
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/telemetry"
 	"github.com/zhorvath83/flux-provider-pushover/internal/types"
 )
 
+// ErrAttachmentTooLarge is returned for attachments over Pushover's limit,
+// without sending them
+var ErrAttachmentTooLarge = errors.New("attachment exceeds Pushover's size limit")
+
 // HTTPClient interface for dependency injection
 type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// errorSnippetLength caps how much of a non-JSON error body is reported
+const errorSnippetLength = 120
+
+// maxResponseBytes bounds how much of a response body is read, enough for
+// Pushover's whole "errors" array
+const maxResponseBytes = 16 << 10
+
+// APIError is returned when the Pushover API responds with a non-200 status
+type APIError struct {
+	Status  int
+	Errors  []string // Pushover's "errors" array
+	Request string   // Pushover's request id, empty for bodies that are not Pushover JSON
+	Body    string   // Short single-line snippet of bodies that are not Pushover JSON
+}
+
+func (e *APIError) Error() string {
+	detail := strings.Join(e.Errors, "; ")
+	if detail == "" {
+		detail = e.Body
+	}
+	if detail == "" {
+		return fmt.Sprintf("pushover API returned status %d", e.Status)
+	}
+	return fmt.Sprintf("pushover API returned status %d: %s", e.Status, detail)
+}
+
+// ParseAPIError describes an error response from its status and body. The
+// body of proxy error pages is kept as a snippet, see ErrorDetail (pure function).
+func ParseAPIError(status int, body []byte) *APIError {
+	var parsed struct {
+		Errors  []string `json:"errors"`
+		Request string   `json:"request"`
+	}
+	if err := json.Unmarshal(body, &parsed); err == nil && len(parsed.Errors) > 0 {
+		return &APIError{Status: status, Errors: parsed.Errors, Request: parsed.Request}
+	}
+	return &APIError{Status: status, Request: parsed.Request, Body: ErrorDetail(body)}
+}
+
+// RateLimitError is returned when Pushover answers 429 because the
+// application's message limit is used up. RetryAfter is when it accepts
+// messages again, zero when it did not tell.
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Err        *APIError
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter <= 0 {
+		return fmt.Sprintf("pushover rate limit reached: %v", e.Err)
+	}
+	return fmt.Sprintf("pushover rate limit reached, retry after %s: %v", e.RetryAfter, e.Err)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// RateLimitReset returns how long until a rate limited application may send
+// again, from Retry-After seconds or Pushover's X-Limit-App-Reset Unix time,
+// zero when neither is set (pure function)
+func RateLimitReset(header http.Header, now time.Time) time.Duration {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if reset, err := strconv.ParseInt(header.Get("X-Limit-App-Reset"), 10, 64); err == nil {
+		if until := time.Unix(reset, 0).Sub(now); until > 0 {
+			return until
+		}
+	}
+	return 0
+}
+
+// TransportError is returned when a request got no response. Written reports
+// whether the request was fully written first, Pushover may then have
+// accepted it.
+type TransportError struct {
+	Err     error
+	Written bool
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("failed to send request: %v", e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
 // PushoverClient handles communication with Pushover API
 type PushoverClient struct {
-	client HTTPClient
-	url    string
+	client      HTTPClient
+	url         string
+	glancesURL  string
+	validateURL string
+	receiptsURL string
+
+	connections *metrics.CounterVec // nil without WithMetrics
+	dnsFailures *metrics.Counter
+
+	inflight chan struct{} // Slots of concurrent requests, nil is unlimited
+}
+
+// Endpoints are the Pushover API endpoints a client posts to
+type Endpoints struct {
+	Messages string
+	Glances  string
+	Validate string
+	Receipts string // Directory of the receipts of emergency messages
+}
+
+// BaseEndpoints returns the endpoints below an API base URL, e.g.
+// https://api.pushover.net/1 or a relay mimicking it at another path
+// (pure function)
+func BaseEndpoints(baseURL string) Endpoints {
+	baseURL = strings.TrimRight(baseURL, "/")
+	return Endpoints{
+		Messages: baseURL + "/messages.json",
+		Glances:  baseURL + "/glances.json",
+		Validate: baseURL + "/users/validate.json",
+		Receipts: baseURL + "/receipts",
+	}
+}
+
+// MessagesEndpoints returns the endpoints next to a messages endpoint, see
+// GlancesURL and ValidateURL (pure function)
+func MessagesEndpoints(messagesURL string) Endpoints {
+	return Endpoints{
+		Messages: messagesURL,
+		Glances:  GlancesURL(messagesURL),
+		Validate: ValidateURL(messagesURL),
+		Receipts: ReceiptsURL(messagesURL),
+	}
+}
+
+// ResolveEndpoints returns the endpoints below baseURL with messagesURL as
+// the messages endpoint. Without a base URL the endpoints are found next to
+// messagesURL, as PUSHOVER_URL always did (pure function).
+func ResolveEndpoints(baseURL, messagesURL string) Endpoints {
+	if baseURL == "" {
+		return MessagesEndpoints(messagesURL)
+	}
+	endpoints := BaseEndpoints(baseURL)
+	if messagesURL != "" {
+		endpoints.Messages = messagesURL
+	}
+	return endpoints
 }
 
-// NewPushoverClient creates a new Pushover client
+// NewPushoverClient creates a new Pushover client. Glances are posted next
+// to the messages endpoint, see GlancesURL.
 func NewPushoverClient(client HTTPClient, url string) *PushoverClient {
+	return NewPushoverClientWithEndpoints(client, MessagesEndpoints(url))
+}
+
+// NewPushoverClientWithEndpoints creates a Pushover client posting to endpoints
+func NewPushoverClientWithEndpoints(client HTTPClient, endpoints Endpoints) *PushoverClient {
 	return &PushoverClient{
-		client: client,
-		url:    url,
+		client:      client,
+		url:         endpoints.Messages,
+		glancesURL:  endpoints.Glances,
+		validateURL: endpoints.Validate,
+		receiptsURL: endpoints.Receipts,
+	}
+}
+
+// GlancesURL returns the Glances endpoint next to a messages endpoint, e.g.
+// https://api.pushover.net/1/glances.json, or the URL itself when it does
+// not end in messages.json (pure function)
+func GlancesURL(messagesURL string) string {
+	base, ok := strings.CutSuffix(messagesURL, "messages.json")
+	if !ok {
+		return messagesURL
+	}
+	return base + "glances.json"
+}
+
+// ValidateURL returns the user validation endpoint next to a messages
+// endpoint, e.g. https://api.pushover.net/1/users/validate.json, or the URL
+// itself when it does not end in messages.json (pure function)
+func ValidateURL(messagesURL string) string {
+	base, ok := strings.CutSuffix(messagesURL, "messages.json")
+	if !ok {
+		return messagesURL
+	}
+	return base + "users/validate.json"
+}
+
+// ReceiptsURL returns the receipts directory next to a messages endpoint,
+// e.g. https://api.pushover.net/1/receipts, or the URL itself when it does
+// not end in messages.json (pure function)
+func ReceiptsURL(messagesURL string) string {
+	base, ok := strings.CutSuffix(messagesURL, "messages.json")
+	if !ok {
+		return messagesURL
 	}
+	return base + "receipts"
+}
+
+// WithMetrics counts the connections requests got, new or reused, to
+// verify that keep-alive works through egress proxies, and the requests
+// failing to resolve the host
+func (p *PushoverClient) WithMetrics(registry *metrics.Registry) *PushoverClient {
+	p.connections = registry.CounterVec("pushover_connections_total", "Connections Pushover requests were sent on, by whether they were new or reused", "connection")
+	p.dnsFailures = registry.Counter("pushover_dns_failures_total", "Pushover requests that failed because the host could not be resolved")
+	return p
+}
+
+// WithMaxInFlight bounds the requests sent at once to max, further ones
+// wait for a slot as long as their context allows. Zero is unlimited.
+func (p *PushoverClient) WithMaxInFlight(max int) *PushoverClient {
+	p.inflight = nil
+	if max > 0 {
+		p.inflight = make(chan struct{}, max)
+	}
+	return p
+}
+
+// ValidateUser checks that token is a valid application token and user a
+// valid user or group key, without sending a message
+func (p *PushoverClient) ValidateUser(ctx context.Context, token, user string) error {
+	data := url.Values{}
+	data.Set("token", token)
+	data.Set("user", user)
+
+	return p.post(ctx, p.validateURL, data)
 }
 
 // SendMessage sends a message to Pushover API
@@ -43,47 +275,410 @@ func (p *PushoverClient) SendMessage(ctx context.Context, msg *types.PushoverMes
 	data.Set("user", msg.User)
 	data.Set("message", msg.Message)
 	data.Set("title", msg.Title)
+	if msg.Priority != nil {
+		data.Set("priority", strconv.Itoa(*msg.Priority))
+		if *msg.Priority == types.EmergencyPriority {
+			data.Set("retry", strconv.Itoa(types.EmergencyRetry))
+			data.Set("expire", strconv.Itoa(types.EmergencyExpire))
+			if msg.Callback != "" {
+				data.Set("callback", msg.Callback)
+			}
+		}
+	}
+	if msg.TTL > 0 && (msg.Priority == nil || *msg.Priority != types.EmergencyPriority) {
+		data.Set("ttl", strconv.Itoa(msg.TTL))
+	}
+	if msg.Sound != "" {
+		data.Set("sound", msg.Sound)
+	}
+	if msg.Device != "" {
+		data.Set("device", msg.Device)
+	}
+
+	if len(msg.Attachment) > 0 {
+		if len(msg.Attachment) > types.MaxAttachmentBytes {
+			return fmt.Errorf("%w: %d bytes over %d", ErrAttachmentTooLarge, len(msg.Attachment), types.MaxAttachmentBytes)
+		}
+		return p.postMultipart(ctx, p.url, data, msg.AttachmentName, msg.Attachment)
+	}
+	return p.post(ctx, p.url, data)
+}
+
+// SendGlance updates the user's Glances widget
+func (p *PushoverClient) SendGlance(ctx context.Context, glance *types.PushoverGlance) error {
+	if glance == nil {
+		return fmt.Errorf("glance is nil")
+	}
+
+	data := url.Values{}
+	data.Set("token", glance.Token)
+	data.Set("user", glance.User)
+	data.Set("title", glance.Title)
+	data.Set("text", glance.Text)
+	data.Set("subtext", glance.Subtext)
+	if glance.Count != nil {
+		data.Set("count", strconv.Itoa(*glance.Count))
+	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", p.url, strings.NewReader(data.Encode()))
+	return p.post(ctx, p.glancesURL, data)
+}
+
+// ReceiptStatus is the acknowledgement state of an emergency message
+type ReceiptStatus struct {
+	Acknowledged   bool
+	AcknowledgedAt time.Time // Zero until acknowledged
+	AcknowledgedBy string    // User key of the acknowledging user
+	Device         string    // Device the message was acknowledged on
+	Expired        bool      // Pushover stopped repeating the message
+	ExpiresAt      time.Time
+}
+
+// GetReceipt polls the acknowledgement state of the emergency message with
+// receipt
+func (p *PushoverClient) GetReceipt(ctx context.Context, token, receipt string) (*ReceiptStatus, error) {
+	endpoint := p.receiptsURL + "/" + url.PathEscape(receipt) + ".json?" + url.Values{"token": {token}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	body, err := p.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return ParseReceiptStatus(body)
+}
+
+// CancelReceipt stops Pushover repeating the emergency message with receipt
+func (p *PushoverClient) CancelReceipt(ctx context.Context, token, receipt string) error {
+	data := url.Values{}
+	data.Set("token", token)
+
+	return p.post(ctx, p.receiptsURL+"/"+url.PathEscape(receipt)+"/cancel.json", data)
+}
+
+// ParseReceiptStatus parses the response of the receipts API (pure function)
+func ParseReceiptStatus(body []byte) (*ReceiptStatus, error) {
+	var parsed struct {
+		Acknowledged         int    `json:"acknowledged"`
+		AcknowledgedAt       int64  `json:"acknowledged_at"`
+		AcknowledgedBy       string `json:"acknowledged_by"`
+		AcknowledgedByDevice string `json:"acknowledged_by_device"`
+		Expired              int    `json:"expired"`
+		ExpiresAt            int64  `json:"expires_at"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse receipt: %w", err)
+	}
+
+	status := &ReceiptStatus{
+		Acknowledged:   parsed.Acknowledged == 1,
+		AcknowledgedBy: parsed.AcknowledgedBy,
+		Device:         parsed.AcknowledgedByDevice,
+		Expired:        parsed.Expired == 1,
+	}
+	if parsed.AcknowledgedAt > 0 {
+		status.AcknowledgedAt = time.Unix(parsed.AcknowledgedAt, 0).UTC()
+	}
+	if parsed.ExpiresAt > 0 {
+		status.ExpiresAt = time.Unix(parsed.ExpiresAt, 0).UTC()
+	}
+	return status, nil
+}
+
+// post submits a form to the Pushover API and checks the response
+func (p *PushoverClient) post(ctx context.Context, endpoint string, data url.Values) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(data.Encode()))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Content-Type", types.ContentTypeForm)
+	req.Header.Set("Content-Type", types.ContentTypeForm+types.CharsetUTF8)
+	_, err = p.do(ctx, req)
+	return err
+}
+
+// postMultipart posts data to a Pushover API endpoint as multipart/form-data,
+// with attachment as the file part
+func (p *PushoverClient) postMultipart(ctx context.Context, endpoint string, data url.Values, name string, attachment []byte) error {
+	body, contentType, err := EncodeMultipart(data, name, attachment)
+	if err != nil {
+		return fmt.Errorf("failed to encode attachment: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	_, err = p.do(ctx, req)
+	return err
+}
+
+// EncodeMultipart encodes the fields of data in key order and attachment as
+// the "attachment" file part named name, typed by its content. It returns
+// the body and its Content-Type (pure function).
+func EncodeMultipart(data url.Values, name string, attachment []byte) ([]byte, string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		for _, value := range data[key] {
+			if err := writer.WriteField(key, value); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": "attachment", "filename": name}))
+	header.Set("Content-Type", http.DetectContentType(attachment))
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := part.Write(attachment); err != nil {
+		return nil, "", err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return body.Bytes(), writer.FormDataContentType(), nil
+}
+
+// do sends a request to the Pushover API and returns the body of a
+// successful response
+func (p *PushoverClient) do(ctx context.Context, req *http.Request) ([]byte, error) {
+	if p.inflight != nil {
+		select {
+		case p.inflight <- struct{}{}:
+			defer func() { <-p.inflight }()
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for a PUSHOVER_MAX_INFLIGHT slot: %w", ctx.Err())
+		}
+	}
+
+	// The transport reports the write from its own goroutine, possibly
+	// after a timeout already returned
+	var written atomic.Bool
+	req = req.WithContext(httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			connection := "new"
+			if info.Reused {
+				connection = "reused"
+			}
+			p.connections.WithLabelValues(connection).Inc()
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			if info.Err == nil {
+				written.Store(true)
+			}
+		},
+	}))
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		var dnsErr *DNSError
+		if errors.As(err, &dnsErr) {
+			p.dnsFailures.Inc()
+		}
+		return nil, &TransportError{Err: err, Written: written.Load()}
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, err := io.ReadAll(io.LimitReader(resp.Body, 512))
-		if err != nil {
-			return fmt.Errorf("pushover API returned status %d (failed to read body: %w)", resp.StatusCode, err)
+	span := telemetry.SpanFromContext(ctx)
+	span.SetAttributes(telemetry.Int("http.response.status_code", resp.StatusCode))
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, &APIError{Status: resp.StatusCode, Body: fmt.Sprintf("failed to read body: %v", err)}
 		}
-		return fmt.Errorf("pushover API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if id := RequestID(body); id != "" {
+		span.SetAttributes(telemetry.String("pushover.request_id", id))
+		requestIDsFromContext(ctx).add(id)
+	}
+	if receipt := Receipt(body); receipt != "" {
+		requestIDsFromContext(ctx).setReceipt(receipt)
 	}
 
-	// Discard response body
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &RateLimitError{RetryAfter: RateLimitReset(resp.Header, time.Now()), Err: ParseAPIError(resp.StatusCode, body)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, ParseAPIError(resp.StatusCode, body)
+	}
+
+	// Discard the rest of the response body
 	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
-		// Log the error but don't fail the request - response was successful
-		return fmt.Errorf("failed to discard response body: %w", err)
+		return nil, fmt.Errorf("failed to discard response body: %w", err)
+	}
+	return body, nil
+}
+
+// requestIDsKey is the context key of RequestIDs
+type requestIDsKey struct{}
+
+// RequestIDs collects the request identifiers of the Pushover API responses
+// to sends made with a context from WithRequestIDs, and the receipt of an
+// emergency message
+type RequestIDs struct {
+	mu      sync.Mutex
+	ids     []string
+	receipt string
+}
+
+// WithRequestIDs returns a context collecting Pushover request identifiers
+func WithRequestIDs(ctx context.Context) (context.Context, *RequestIDs) {
+	ids := &RequestIDs{}
+	return context.WithValue(ctx, requestIDsKey{}, ids), ids
+}
+
+// requestIDsFromContext returns the collector of ctx, nil if there is none
+func requestIDsFromContext(ctx context.Context) *RequestIDs {
+	ids, _ := ctx.Value(requestIDsKey{}).(*RequestIDs)
+	return ids
+}
+
+// add records id, a nil RequestIDs records nothing
+func (r *RequestIDs) add(id string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ids = append(r.ids, id)
+}
+
+// setReceipt records the receipt of an emergency message
+func (r *RequestIDs) setReceipt(receipt string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.receipt = receipt
+}
+
+// Receipt returns the receipt of an emergency message, empty if none
+func (r *RequestIDs) Receipt() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.receipt
+}
+
+// Last returns the identifier of the latest response, empty if none
+func (r *RequestIDs) Last() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.ids) == 0 {
+		return ""
+	}
+	return r.ids[len(r.ids)-1]
+}
+
+// RequestID returns the request identifier from a Pushover API response
+// body, or an empty string (pure function)
+func RequestID(body []byte) string {
+	var parsed struct {
+		Request string `json:"request"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return ""
+	}
+	return parsed.Request
+}
+
+// Receipt returns the receipt of an emergency message from a Pushover API
+// response body, or an empty string (pure function)
+func Receipt(body []byte) string {
+	var parsed struct {
+		Receipt string `json:"receipt"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return ""
+	}
+	return parsed.Receipt
+}
+
+// ErrorDetail summarizes an error response body: the messages of Pushover's
+// "errors" array when the body is JSON, otherwise a short single-line
+// snippet, so that proxy error pages stay readable in logs (pure function)
+func ErrorDetail(body []byte) string {
+	var parsed struct {
+		Errors []string `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &parsed); err == nil && len(parsed.Errors) > 0 {
+		return strings.Join(parsed.Errors, "; ")
+	}
+
+	snippet := strings.Join(strings.Fields(string(body)), " ")
+	if utf8.RuneCountInString(snippet) <= errorSnippetLength {
+		return snippet
+	}
+	runes := []rune(snippet)
+	return string(runes[:errorSnippetLength]) + "…"
+}
+
+// TransportOptions tunes the connection pool and protocols of an HTTP client
+type TransportOptions struct {
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	IdleConnTimeout       time.Duration
+	TLSHandshakeTimeout   time.Duration // Zero waits for the client timeout
+	ExpectContinueTimeout time.Duration
+	HTTP2                 bool // Negotiate HTTP/2 over TLS
+	Compression           bool // Ask for gzip responses
+
+	Resolve ResolveOptions // How the Pushover host is resolved, empty Host uses plain DNS
+}
+
+// DefaultTransportOptions returns the transport settings of CreateOptimizedHTTPClient
+func DefaultTransportOptions() TransportOptions {
+	return TransportOptions{
+		MaxIdleConns:          10,
+		MaxIdleConnsPerHost:   2,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		HTTP2:                 true,
 	}
-	return nil
 }
 
 // CreateOptimizedHTTPClient creates an optimized HTTP client
 func CreateOptimizedHTTPClient(timeout time.Duration) *http.Client {
+	return NewHTTPClient(timeout, DefaultTransportOptions())
+}
+
+// NewHTTPClient creates an optimized HTTP client with the given transport
+// settings. A custom DialContext disables HTTP/2 unless it is forced, so
+// ForceAttemptHTTP2 follows opts.HTTP2.
+func NewHTTPClient(timeout time.Duration, opts TransportOptions) *http.Client {
+	dial := (&net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	if opts.Resolve.Host != "" {
+		dial = NewHostDialer(opts.Resolve, dial, net.DefaultResolver).DialContext
+	}
+
 	transport := &http.Transport{
-		MaxIdleConns:        10,
-		MaxIdleConnsPerHost: 2,
-		IdleConnTimeout:     90 * time.Second,
-		DisableCompression:  true,
-		DialContext: (&net.Dialer{
-			Timeout:   5 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
+		MaxIdleConns:          opts.MaxIdleConns,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		TLSHandshakeTimeout:   opts.TLSHandshakeTimeout,
+		ExpectContinueTimeout: opts.ExpectContinueTimeout,
+		ForceAttemptHTTP2:     opts.HTTP2,
+		DisableCompression:    !opts.Compression,
+		DialContext:           dial,
 	}
 
 	return &http.Client{
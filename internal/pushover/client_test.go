@@ -1,14 +1,22 @@
 package pushover
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
 	"github.com/zhorvath83/flux-provider-pushover/internal/types"
 )
 
@@ -107,9 +115,9 @@ func TestPushoverClient_SendMessage(t *testing.T) {
 						t.Errorf("Expected POST method, got %s", req.Method)
 					}
 
-					if req.Header.Get("Content-Type") != types.ContentTypeForm {
+					if req.Header.Get("Content-Type") != "application/x-www-form-urlencoded; charset=utf-8" {
 						t.Errorf("Expected Content-Type %s, got %s",
-							types.ContentTypeForm, req.Header.Get("Content-Type"))
+							"application/x-www-form-urlencoded; charset=utf-8", req.Header.Get("Content-Type"))
 					}
 
 					// Parse form data if message is not nil
@@ -208,6 +216,14 @@ func TestCreateOptimizedHTTPClient(t *testing.T) {
 	if transport.DisableCompression != true {
 		t.Error("Expected DisableCompression to be true")
 	}
+
+	if !transport.ForceAttemptHTTP2 {
+		t.Error("Expected HTTP/2 to be attempted despite the custom dialer")
+	}
+
+	if transport.TLSHandshakeTimeout != 10*time.Second || transport.ExpectContinueTimeout != time.Second {
+		t.Errorf("Expected the default protocol timeouts, got %v %v", transport.TLSHandshakeTimeout, transport.ExpectContinueTimeout)
+	}
 }
 
 // Benchmark tests
@@ -236,3 +252,848 @@ func BenchmarkPushoverClient_SendMessage(b *testing.B) {
 		_ = client.SendMessage(ctx, msg)
 	}
 }
+
+func TestNewHTTPClient(t *testing.T) {
+	timeout := 5 * time.Second
+	client := NewHTTPClient(timeout, TransportOptions{
+		MaxIdleConns:          64,
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       30 * time.Second,
+		TLSHandshakeTimeout:   3 * time.Second,
+		ExpectContinueTimeout: 2 * time.Second,
+	})
+
+	if client.Timeout != timeout {
+		t.Errorf("Expected timeout %v, got %v", timeout, client.Timeout)
+	}
+
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatal("Expected http.Transport")
+	}
+
+	if transport.MaxIdleConns != 64 {
+		t.Errorf("Expected MaxIdleConns 64, got %d", transport.MaxIdleConns)
+	}
+
+	if transport.MaxIdleConnsPerHost != 16 {
+		t.Errorf("Expected MaxIdleConnsPerHost 16, got %d", transport.MaxIdleConnsPerHost)
+	}
+
+	if transport.IdleConnTimeout != 30*time.Second {
+		t.Errorf("Expected IdleConnTimeout 30s, got %v", transport.IdleConnTimeout)
+	}
+
+	if transport.DisableCompression != true {
+		t.Error("Expected DisableCompression to be true")
+	}
+
+	if transport.TLSHandshakeTimeout != 3*time.Second || transport.ExpectContinueTimeout != 2*time.Second {
+		t.Errorf("Expected the configured protocol timeouts, got %v %v", transport.TLSHandshakeTimeout, transport.ExpectContinueTimeout)
+	}
+
+	if transport.ForceAttemptHTTP2 {
+		t.Error("Expected HTTP/2 off unless enabled")
+	}
+
+	compressed := NewHTTPClient(timeout, TransportOptions{Compression: true}).Transport.(*http.Transport)
+	if compressed.DisableCompression {
+		t.Error("Expected compression with Compression set")
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"success", `{"status":1,"request":"647d2300-702c-4b38-8b2f-d56326ae460b"}`, "647d2300-702c-4b38-8b2f-d56326ae460b"},
+		{"error", `{"user":"invalid","errors":["user identifier is invalid"],"status":0,"request":"5042853c"}`, "5042853c"},
+		{"missing", `{"status":1}`, ""},
+		{"not json", `Bad Gateway`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RequestID([]byte(tt.body)); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestErrorDetail(t *testing.T) {
+	html := "<html>\r\n<head><title>502 Bad Gateway</title></head>\r\n<body>\r\n<center><h1>502 Bad Gateway</h1></center>\r\n<hr><center>nginx</center>\r\n</body>\r\n</html>\r\n" +
+		strings.Repeat("<!-- a padding to disable MSIE and Chrome friendly error page -->\r\n", 6)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"pushover errors", `{"token":"invalid","errors":["application token is invalid"],"status":0,"request":"5042853c"}`, "application token is invalid"},
+		{"several errors", `{"errors":["user identifier is invalid","message cannot be blank"],"status":0}`, "user identifier is invalid; message cannot be blank"},
+		{"json without errors", `{"error":"Invalid token"}`, `{"error":"Invalid token"}`},
+		{"plain text", "Bad Gateway\n", "Bad Gateway"},
+		{"empty", "", ""},
+		{"html page", html, "<html> <head><title>502 Bad Gateway</title></head> <body> <center><h1>502 Bad Gateway</h1></center> <hr><center>nginx</c…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorDetail([]byte(tt.body)); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPushoverClient_SendMessage_ErrorBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    string
+	}{
+		{
+			name:        "json error body",
+			contentType: "application/json",
+			body:        `{"user":"invalid","errors":["user identifier is not a valid user, group, or subscribed user key"],"status":0,"request":"5042853c"}`,
+			expected:    "pushover API returned status 400: user identifier is not a valid user, group, or subscribed user key",
+		},
+		{
+			name:        "html error body",
+			contentType: "text/html",
+			body:        "<html>\n<head><title>400 Bad Request</title></head>\n<body>\n" + strings.Repeat("<p>proxy error</p>\n", 100) + "</body>\n</html>\n",
+			expected:    "pushover API returned status 400: <html> <head><title>400 Bad Request</title></head> <body> <p>proxy error</p> <p>proxy error</p> <p>proxy error</p> <p>pr…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockHTTPClient{
+				DoFunc: func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusBadRequest,
+						Header:     http.Header{"Content-Type": []string{tt.contentType}},
+						Body:       io.NopCloser(strings.NewReader(tt.body)),
+					}, nil
+				},
+			}
+
+			err := NewPushoverClient(mockClient, "http://test.example.com").SendMessage(context.Background(), &types.PushoverMessage{})
+			if err == nil {
+				t.Fatal("Expected error but got nil")
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("Expected error %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseAPIError(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected APIError
+	}{
+		{"pushover errors", `{"user":"invalid","errors":["user identifier is invalid","message cannot be blank"],"status":0,"request":"5042853c"}`,
+			APIError{Status: 400, Errors: []string{"user identifier is invalid", "message cannot be blank"}, Request: "5042853c"}},
+		{"json without errors", `{"error":"Invalid token"}`, APIError{Status: 400, Body: `{"error":"Invalid token"}`}},
+		{"plain text", "Bad Gateway\n", APIError{Status: 400, Body: "Bad Gateway"}},
+		{"empty", "", APIError{Status: 400}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseAPIError(400, []byte(tt.body))
+			if got.Status != tt.expected.Status || strings.Join(got.Errors, "|") != strings.Join(tt.expected.Errors, "|") ||
+				got.Request != tt.expected.Request || got.Body != tt.expected.Body {
+				t.Errorf("Expected %+v, got %+v", tt.expected, *got)
+			}
+		})
+	}
+}
+
+func TestRateLimitReset(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name     string
+		header   http.Header
+		expected time.Duration
+	}{
+		{"retry after", http.Header{"Retry-After": []string{"120"}}, 2 * time.Minute},
+		{"app reset", http.Header{"X-Limit-App-Reset": []string{"1700000600"}}, 10 * time.Minute},
+		{"retry after wins", http.Header{"Retry-After": []string{"5"}, "X-Limit-App-Reset": []string{"1700000600"}}, 5 * time.Second},
+		{"reset in the past", http.Header{"X-Limit-App-Reset": []string{"1699999000"}}, 0},
+		{"http date", http.Header{"Retry-After": []string{"Wed, 21 Oct 2015 07:28:00 GMT"}}, 0},
+		{"none", http.Header{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RateLimitReset(tt.header, now); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPushoverClient_SendMessage_RateLimited(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Unix()
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusTooManyRequests,
+				Header:     http.Header{"X-Limit-App-Reset": []string{fmt.Sprint(reset)}},
+				Body:       io.NopCloser(strings.NewReader(`{"errors":["application is over its message limit"],"status":0,"request":"r-1"}`)),
+			}, nil
+		},
+	}
+
+	err := NewPushoverClient(mockClient, "http://test.example.com").SendMessage(context.Background(), &types.PushoverMessage{})
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("Expected a rate limit error, got %v", err)
+	}
+	if rateLimitErr.RetryAfter <= 59*time.Minute || rateLimitErr.RetryAfter > time.Hour {
+		t.Errorf("Expected a retry after about an hour, got %v", rateLimitErr.RetryAfter)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusTooManyRequests || apiErr.Request != "r-1" {
+		t.Errorf("Expected the API error unwrapped, got %+v", apiErr)
+	}
+	if !IsRetryable(err) {
+		t.Error("Expected a rate limit error to be retryable")
+	}
+}
+
+func TestPushoverClient_SendMessage_LargeErrorBody(t *testing.T) {
+	errs := make([]string, 200)
+	for i := range errs {
+		errs[i] = fmt.Sprintf(`"error number %d"`, i)
+	}
+	body := `{"errors":[` + strings.Join(errs, ",") + `],"status":0,"request":"r-2"}`
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusBadRequest,
+				Body:       io.NopCloser(strings.NewReader(body + strings.Repeat(" ", 1<<20))),
+			}, nil
+		},
+	}
+
+	err := NewPushoverClient(mockClient, "http://test.example.com").SendMessage(context.Background(), &types.PushoverMessage{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an API error, got %v", err)
+	}
+	if len(apiErr.Errors) != 200 || apiErr.Request != "r-2" {
+		t.Errorf("Expected every error of a long body, got %d errors and request %q", len(apiErr.Errors), apiErr.Request)
+	}
+}
+
+func TestPushoverClient_SendMessage_Options(t *testing.T) {
+	emergency, low := types.EmergencyPriority, -1
+
+	tests := []struct {
+		name     string
+		msg      *types.PushoverMessage
+		expected url.Values
+	}{
+		{
+			name:     "defaults omit options",
+			msg:      &types.PushoverMessage{Message: "m"},
+			expected: url.Values{"priority": nil, "sound": nil, "device": nil, "retry": nil, "ttl": nil},
+		},
+		{
+			name:     "priority, sound and device",
+			msg:      &types.PushoverMessage{Message: "m", Priority: &low, Sound: "siren", Device: "phone"},
+			expected: url.Values{"priority": {"-1"}, "sound": {"siren"}, "device": {"phone"}, "retry": nil},
+		},
+		{
+			name:     "emergency priority sets retry and expire",
+			msg:      &types.PushoverMessage{Message: "m", Priority: &emergency},
+			expected: url.Values{"priority": {"2"}, "retry": {"60"}, "expire": {"3600"}},
+		},
+		{
+			name:     "emergency priority sets callback",
+			msg:      &types.PushoverMessage{Message: "m", Priority: &emergency, Callback: "https://flux.example.com/pushover-callback"},
+			expected: url.Values{"priority": {"2"}, "callback": {"https://flux.example.com/pushover-callback"}},
+		},
+		{
+			name:     "callback only for emergency priority",
+			msg:      &types.PushoverMessage{Message: "m", Priority: &low, Callback: "https://flux.example.com/pushover-callback"},
+			expected: url.Values{"priority": {"-1"}, "callback": nil},
+		},
+		{
+			name:     "ttl",
+			msg:      &types.PushoverMessage{Message: "m", Priority: &low, TTL: 3600},
+			expected: url.Values{"ttl": {"3600"}},
+		},
+		{
+			name:     "no ttl for emergency priority",
+			msg:      &types.PushoverMessage{Message: "m", Priority: &emergency, TTL: 3600},
+			expected: url.Values{"priority": {"2"}, "ttl": nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var form url.Values
+			client := NewPushoverClient(&MockHTTPClient{
+				DoFunc: func(req *http.Request) (*http.Response, error) {
+					body, _ := io.ReadAll(req.Body)
+					form, _ = url.ParseQuery(string(body))
+					return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"status":1}`))}, nil
+				},
+			}, "http://test.example.com")
+
+			if err := client.SendMessage(context.Background(), tt.msg); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			for key, values := range tt.expected {
+				if fmt.Sprint(form[key]) != fmt.Sprint(values) {
+					t.Errorf("Expected %s=%v, got %v", key, values, form[key])
+				}
+			}
+		})
+	}
+}
+
+func TestGlancesURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://api.pushover.net/1/messages.json", "https://api.pushover.net/1/glances.json"},
+		{"http://127.0.0.1:8081/1/messages.json", "http://127.0.0.1:8081/1/glances.json"},
+		{"http://test.example.com", "http://test.example.com"},
+	}
+
+	for _, tt := range tests {
+		if got := GlancesURL(tt.url); got != tt.expected {
+			t.Errorf("GlancesURL(%q) = %q, expected %q", tt.url, got, tt.expected)
+		}
+	}
+}
+
+func TestPushoverClient_SendGlance(t *testing.T) {
+	count := 3
+	tests := []struct {
+		name     string
+		glance   *types.PushoverGlance
+		expected url.Values
+	}{
+		{
+			name: "all fields",
+			glance: &types.PushoverGlance{
+				Token:   "test_token",
+				User:    "test_user",
+				Title:   "FluxCD",
+				Text:    "HealthCheckFailed [ERROR]",
+				Subtext: "kustomization/apps",
+				Count:   &count,
+			},
+			expected: url.Values{
+				"token":   {"test_token"},
+				"user":    {"test_user"},
+				"title":   {"FluxCD"},
+				"text":    {"HealthCheckFailed [ERROR]"},
+				"subtext": {"kustomization/apps"},
+				"count":   {"3"},
+			},
+		},
+		{
+			name:     "nil count leaves the count unchanged",
+			glance:   &types.PushoverGlance{Token: "test_token", User: "test_user", Text: "ok"},
+			expected: url.Values{"text": {"ok"}, "count": nil, "message": nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *http.Request
+			var form url.Values
+			client := NewPushoverClient(&MockHTTPClient{
+				DoFunc: func(r *http.Request) (*http.Response, error) {
+					req = r
+					body, _ := io.ReadAll(r.Body)
+					form, _ = url.ParseQuery(string(body))
+					return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"status":1}`))}, nil
+				},
+			}, "https://api.pushover.net/1/messages.json")
+
+			if err := client.SendGlance(context.Background(), tt.glance); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if req.Method != http.MethodPost || req.URL.String() != "https://api.pushover.net/1/glances.json" {
+				t.Errorf("Expected POST to glances.json, got %s %s", req.Method, req.URL)
+			}
+			if ct := req.Header.Get("Content-Type"); ct != types.ContentTypeForm+types.CharsetUTF8 {
+				t.Errorf("Expected form content type, got %q", ct)
+			}
+			for key, values := range tt.expected {
+				if fmt.Sprint(form[key]) != fmt.Sprint(values) {
+					t.Errorf("Expected %s=%v, got %v", key, values, form[key])
+				}
+			}
+		})
+	}
+}
+
+func TestPushoverClient_SendGlance_Errors(t *testing.T) {
+	client := NewPushoverClient(&MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusBadRequest,
+				Body:       io.NopCloser(strings.NewReader(`{"errors":["count is invalid"],"status":0}`)),
+			}, nil
+		},
+	}, "http://test.example.com/1/messages.json")
+
+	if err := client.SendGlance(context.Background(), nil); err == nil || err.Error() != "glance is nil" {
+		t.Errorf("Expected nil glance error, got %v", err)
+	}
+
+	err := client.SendGlance(context.Background(), &types.PushoverGlance{})
+	if err == nil || err.Error() != "pushover API returned status 400: count is invalid" {
+		t.Errorf("Expected API error, got %v", err)
+	}
+}
+
+func TestPushoverClient_RequestIDs(t *testing.T) {
+	client := NewPushoverClient(&MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"status":1,"request":"5042853c"}`))}, nil
+		},
+	}, "http://test.example.com")
+
+	// Sends without a collector are unaffected
+	if err := client.SendMessage(context.Background(), &types.PushoverMessage{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, ids := WithRequestIDs(context.Background())
+	if ids.Last() != "" {
+		t.Errorf("Expected no request id before sending, got %q", ids.Last())
+	}
+	if err := client.SendMessage(ctx, &types.PushoverMessage{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ids.Last() != "5042853c" {
+		t.Errorf("Expected the response's request id, got %q", ids.Last())
+	}
+}
+
+func TestPushoverClient_Receipt(t *testing.T) {
+	client := NewPushoverClient(&MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"status":1,"request":"5042853c","receipt":"rLqVuqTRh62UzxtmqiaLzQmVcPgiCy"}`))}, nil
+		},
+	}, "http://test.example.com")
+
+	ctx, ids := WithRequestIDs(context.Background())
+	if err := client.SendMessage(ctx, &types.PushoverMessage{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ids.Receipt() != "rLqVuqTRh62UzxtmqiaLzQmVcPgiCy" {
+		t.Errorf("Expected the emergency receipt, got %q", ids.Receipt())
+	}
+}
+
+func TestParseReceiptStatus(t *testing.T) {
+	status, err := ParseReceiptStatus([]byte(`{"status":1,"acknowledged":1,"acknowledged_at":1700000090,"acknowledged_by":"uQiRzpo4DXghDmr9QzzfQu27cmVRsG","acknowledged_by_device":"phone","last_delivered_at":1700000060,"expired":0,"expires_at":1700003600,"called_back":0,"called_back_at":0,"request":"req"}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !status.Acknowledged || !status.AcknowledgedAt.Equal(time.Unix(1700000090, 0)) || status.AcknowledgedBy != "uQiRzpo4DXghDmr9QzzfQu27cmVRsG" ||
+		status.Device != "phone" || status.Expired || !status.ExpiresAt.Equal(time.Unix(1700003600, 0)) {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	status, err = ParseReceiptStatus([]byte(`{"status":1,"acknowledged":0,"acknowledged_at":0,"expired":1,"expires_at":1700003600}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if status.Acknowledged || !status.AcknowledgedAt.IsZero() || !status.Expired {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	if _, err := ParseReceiptStatus([]byte("<html>")); err == nil {
+		t.Error("Expected an error for a body that is not JSON")
+	}
+}
+
+func TestPushoverClient_CancelReceipt(t *testing.T) {
+	var cancelled *http.Request
+	var form url.Values
+	client := NewPushoverClient(&MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			cancelled = req
+			body, _ := io.ReadAll(req.Body)
+			form, _ = url.ParseQuery(string(body))
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"status":1,"request":"req"}`))}, nil
+		},
+	}, "https://api.pushover.net/1/messages.json")
+
+	if err := client.CancelReceipt(context.Background(), "app-token", "r1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cancelled.Method != http.MethodPost || cancelled.URL.String() != "https://api.pushover.net/1/receipts/r1/cancel.json" || form.Get("token") != "app-token" {
+		t.Errorf("Expected the token posted to the cancel endpoint, got %s %s %v", cancelled.Method, cancelled.URL, form)
+	}
+}
+
+func TestReceiptsURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://api.pushover.net/1/messages.json", "https://api.pushover.net/1/receipts"},
+		{"http://test.example.com", "http://test.example.com"},
+	}
+
+	for _, tt := range tests {
+		if got := ReceiptsURL(tt.url); got != tt.expected {
+			t.Errorf("ReceiptsURL(%q) = %q, expected %q", tt.url, got, tt.expected)
+		}
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://api.pushover.net/1/messages.json", "https://api.pushover.net/1/users/validate.json"},
+		{"http://127.0.0.1:8081/1/messages.json", "http://127.0.0.1:8081/1/users/validate.json"},
+		{"http://test.example.com", "http://test.example.com"},
+	}
+
+	for _, tt := range tests {
+		if got := ValidateURL(tt.url); got != tt.expected {
+			t.Errorf("ValidateURL(%q) = %q, expected %q", tt.url, got, tt.expected)
+		}
+	}
+}
+
+func TestPushoverClient_ValidateUser(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		body          string
+		expectedError string
+	}{
+		{"valid", http.StatusOK, `{"status":1,"devices":["phone"]}`, ""},
+		{"invalid user", http.StatusBadRequest, `{"user":"invalid","errors":["user key is invalid"],"status":0}`, "pushover API returned status 400: user key is invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *http.Request
+			var form url.Values
+			client := NewPushoverClient(&MockHTTPClient{
+				DoFunc: func(r *http.Request) (*http.Response, error) {
+					req = r
+					body, _ := io.ReadAll(r.Body)
+					form, _ = url.ParseQuery(string(body))
+					return &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body))}, nil
+				},
+			}, "https://api.pushover.net/1/messages.json")
+
+			err := client.ValidateUser(context.Background(), "test_token", "test_user")
+			if tt.expectedError == "" && err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tt.expectedError != "" && (err == nil || err.Error() != tt.expectedError) {
+				t.Fatalf("Expected %q, got %v", tt.expectedError, err)
+			}
+
+			if req.URL.String() != "https://api.pushover.net/1/users/validate.json" {
+				t.Errorf("Expected POST to users/validate.json, got %s", req.URL)
+			}
+			if form.Get("token") != "test_token" || form.Get("user") != "test_user" || form.Has("message") {
+				t.Errorf("Expected only the credentials, got %v", form)
+			}
+		})
+	}
+}
+
+func TestResolveEndpoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseURL  string
+		override string
+		expected Endpoints
+	}{
+		{
+			"default",
+			"",
+			"https://api.pushover.net/1/messages.json",
+			Endpoints{"https://api.pushover.net/1/messages.json", "https://api.pushover.net/1/glances.json", "https://api.pushover.net/1/users/validate.json", "https://api.pushover.net/1/receipts"},
+		},
+		{
+			"relay base URL",
+			"https://relay.example.com/pushover/v1/",
+			"",
+			Endpoints{"https://relay.example.com/pushover/v1/messages.json", "https://relay.example.com/pushover/v1/glances.json", "https://relay.example.com/pushover/v1/users/validate.json", "https://relay.example.com/pushover/v1/receipts"},
+		},
+		{
+			"messages override below base URL",
+			"https://relay.example.com/pushover/v1",
+			"https://relay.example.com/send",
+			Endpoints{"https://relay.example.com/send", "https://relay.example.com/pushover/v1/glances.json", "https://relay.example.com/pushover/v1/users/validate.json", "https://relay.example.com/pushover/v1/receipts"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveEndpoints(tt.baseURL, tt.override); got != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewPushoverClientWithEndpoints(t *testing.T) {
+	var paths []string
+	client := NewPushoverClientWithEndpoints(&MockHTTPClient{
+		DoFunc: func(r *http.Request) (*http.Response, error) {
+			paths = append(paths, r.URL.Path)
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"status":1}`))}, nil
+		},
+	}, BaseEndpoints("https://relay.example.com/pushover/v1"))
+
+	ctx := context.Background()
+	if err := client.SendMessage(ctx, &types.PushoverMessage{Token: "t", User: "u", Message: "m"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := client.SendGlance(ctx, &types.PushoverGlance{Token: "t", User: "u"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := client.ValidateUser(ctx, "t", "u"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"/pushover/v1/messages.json", "/pushover/v1/glances.json", "/pushover/v1/users/validate.json"}
+	if strings.Join(paths, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected posts to %v, got %v", expected, paths)
+	}
+}
+
+func TestPushoverClient_TransportError(t *testing.T) {
+	// The server reads the request and never answers, like a Pushover that
+	// accepted the message before the response was lost
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewPushoverClient(&http.Client{Timeout: 100 * time.Millisecond}, server.URL+"/1/messages.json")
+	err := client.SendMessage(context.Background(), &types.PushoverMessage{Token: "t", User: "u", Message: "m"})
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) || !transportErr.Written || !IsAmbiguous(err) {
+		t.Errorf("Expected a timeout after the request was written, got %v", err)
+	}
+
+	// Nothing listens on a closed server, the request is never written
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	client = NewPushoverClient(&http.Client{Timeout: time.Second}, closed.URL+"/1/messages.json")
+	err = client.SendMessage(context.Background(), &types.PushoverMessage{Token: "t", User: "u", Message: "m"})
+	if !errors.As(err, &transportErr) || transportErr.Written || !ShouldRetry(err, false) {
+		t.Errorf("Expected a retryable error before the write, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "failed to send request: ") {
+		t.Errorf("Expected the send failure message, got %v", err)
+	}
+}
+
+func TestPushoverClient_ConnectionReuse(t *testing.T) {
+	var protos []int
+	var mu sync.Mutex
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		protos = append(protos, r.ProtoMajor)
+		mu.Unlock()
+		w.Write([]byte(`{"status":1}`))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	httpClient := NewHTTPClient(5*time.Second, DefaultTransportOptions())
+	httpClient.Transport.(*http.Transport).TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+
+	registry := metrics.NewRegistry()
+	client := NewPushoverClient(httpClient, server.URL+"/1/messages.json").WithMetrics(registry)
+	for i := 0; i < 2; i++ {
+		if err := client.SendMessage(context.Background(), &types.PushoverMessage{Token: "t", User: "u", Message: "m"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	connections := registry.CounterVec("pushover_connections_total", "", "connection")
+	if connections.WithLabelValues("new").Value() != 1 || connections.WithLabelValues("reused").Value() != 1 {
+		t.Errorf("Expected one new and one reused connection, got %v new and %v reused",
+			connections.WithLabelValues("new").Value(), connections.WithLabelValues("reused").Value())
+	}
+	if len(protos) != 2 || protos[0] != 2 || protos[1] != 2 {
+		t.Errorf("Expected both sends over HTTP/2, got %v", protos)
+	}
+}
+
+func TestPushoverClient_SendMessage_Attachment(t *testing.T) {
+	var contentType string
+	var form *multipart.Form
+	client := NewPushoverClient(&MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			contentType = req.Header.Get("Content-Type")
+			mediaType, params, err := mime.ParseMediaType(contentType)
+			if err != nil || mediaType != "multipart/form-data" {
+				t.Fatalf("Expected multipart/form-data, got %q", contentType)
+			}
+			form, err = multipart.NewReader(req.Body, params["boundary"]).ReadForm(1 << 20)
+			if err != nil {
+				t.Fatalf("Invalid multipart body: %v", err)
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"status":1}`))}, nil
+		},
+	}, "http://test.example.com")
+
+	priority := 1
+	attachment := []byte("Helm upgrade failed: " + strings.Repeat("error detail ", 200))
+	err := client.SendMessage(context.Background(), &types.PushoverMessage{
+		Token:          "token",
+		User:           "user",
+		Title:          "FluxCD",
+		Message:        "HelmRelease failed…",
+		Priority:       &priority,
+		Attachment:     attachment,
+		AttachmentName: "message.txt",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string][]string{
+		"token":    {"token"},
+		"user":     {"user"},
+		"title":    {"FluxCD"},
+		"message":  {"HelmRelease failed…"},
+		"priority": {"1"},
+	}
+	if fmt.Sprint(form.Value) != fmt.Sprint(expected) {
+		t.Errorf("Expected fields %v, got %v", expected, form.Value)
+	}
+
+	files := form.File["attachment"]
+	if len(files) != 1 {
+		t.Fatalf("Expected one attachment, got %v", form.File)
+	}
+	if files[0].Filename != "message.txt" || files[0].Header.Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("Unexpected attachment %s of type %s", files[0].Filename, files[0].Header.Get("Content-Type"))
+	}
+	file, err := files[0].Open()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer file.Close()
+	if content, _ := io.ReadAll(file); !bytes.Equal(content, attachment) {
+		t.Errorf("Expected the attachment content, got %d bytes", len(content))
+	}
+}
+
+func TestPushoverClient_SendMessage_AttachmentTooLarge(t *testing.T) {
+	requests := 0
+	client := NewPushoverClient(&MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			requests++
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"status":1}`))}, nil
+		},
+	}, "http://test.example.com")
+
+	err := client.SendMessage(context.Background(), &types.PushoverMessage{
+		Message:        "m",
+		Attachment:     make([]byte, types.MaxAttachmentBytes+1),
+		AttachmentName: "message.txt",
+	})
+	if !errors.Is(err, ErrAttachmentTooLarge) || IsRetryable(err) {
+		t.Errorf("Expected a final ErrAttachmentTooLarge, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected nothing sent, got %d requests", requests)
+	}
+}
+
+func TestPushoverClient_MaxInFlight(t *testing.T) {
+	const (
+		limit = 3
+		sends = 30
+	)
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"status":1}`))}, nil
+		},
+	}
+	client := NewPushoverClient(mockClient, "http://test.example.com").WithMaxInFlight(limit)
+
+	var wg sync.WaitGroup
+	for i := 0; i < sends; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.SendMessage(context.Background(), &types.PushoverMessage{Token: "token", User: "user", Message: "m"}); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight > limit {
+		t.Errorf("Expected at most %d requests in flight, got %d", limit, maxInFlight)
+	}
+	if maxInFlight < limit {
+		t.Errorf("Expected the %d slots used, got %d", limit, maxInFlight)
+	}
+
+	// Sends waiting for a slot give up with their context
+	holding := make(chan struct{})
+	client = NewPushoverClient(&MockHTTPClient{DoFunc: func(req *http.Request) (*http.Response, error) {
+		close(holding)
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}}, "http://test.example.com").WithMaxInFlight(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	go func() {
+		_ = client.SendMessage(ctx, &types.PushoverMessage{Token: "token", User: "user", Message: "m"})
+	}()
+	<-holding
+	err := client.SendMessage(ctx, &types.PushoverMessage{Token: "token", User: "user", Message: "m"})
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "PUSHOVER_MAX_INFLIGHT") {
+		t.Errorf("Expected the deadline while waiting for a slot, got %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+package pushover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/conditions"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// ErrCredentialsInvalid is returned without sending for messages carrying
+// credentials Pushover already rejected as invalid
+var ErrCredentialsInvalid = errors.New("pushover credentials are invalid")
+
+// credentialErrors are the Pushover errors of tokens and user keys that are
+// invalid, e.g. of a deleted application, which no resend fixes
+var credentialErrors = []string{
+	"application token is invalid",
+	"user identifier is invalid",
+	"user key is invalid",
+}
+
+// IsCredentialError reports whether err is Pushover rejecting the
+// application token or user key of a message (pure function)
+func IsCredentialError(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest {
+		return false
+	}
+	for _, message := range apiErr.Errors {
+		message = strings.ToLower(message)
+		for _, credentialError := range credentialErrors {
+			if strings.Contains(message, credentialError) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CredentialsState describes why a CredentialGuard considers the
+// credentials invalid
+type CredentialsState struct {
+	Reason string    `json:"reason"`
+	Since  time.Time `json:"since"`
+}
+
+// credentialPair identifies the credentials of a message
+type credentialPair struct {
+	token string
+	user  string
+}
+
+// CredentialValidator checks credentials without sending a message
+type CredentialValidator interface {
+	ValidateUser(ctx context.Context, token, user string) error
+}
+
+// CredentialGuard stops sending with credentials Pushover rejected as
+// invalid, failing such sends fast with ErrCredentialsInvalid instead of
+// calling the API for every alert. The first rejection runs the callback
+// set by OnInvalid. Rejected credentials are validated again every
+// interval set by WithRecheck, e.g. until a reactivated application is
+// accepted, and cleared once Pushover accepts them.
+type CredentialGuard struct {
+	next      MessageSender
+	clock     clock.Clock
+	onInvalid func(ctx context.Context, state CredentialsState)
+
+	conditions *conditions.Set // nil maintains no CredentialsValid condition
+
+	validator CredentialValidator
+	interval  time.Duration // 0 never validates rejected credentials again
+	started   atomic.Bool
+	stop      chan struct{}
+	stopOnce  sync.Once
+	done      chan struct{}
+
+	mu      sync.Mutex
+	revoked map[credentialPair]bool
+	state   *CredentialsState // nil while valid
+}
+
+// NewCredentialGuard wraps next, stopping sends with rejected credentials
+func NewCredentialGuard(next MessageSender) *CredentialGuard {
+	return &CredentialGuard{
+		next:    next,
+		clock:   clock.Real{},
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		revoked: make(map[credentialPair]bool),
+	}
+}
+
+// WithRecheck validates rejected credentials with validator every interval
+// once started
+func (g *CredentialGuard) WithRecheck(validator CredentialValidator, interval time.Duration) *CredentialGuard {
+	g.validator = validator
+	g.interval = interval
+	return g
+}
+
+// OnInvalid sets fn to run once when credentials are first rejected, with
+// the context of the rejected send
+func (g *CredentialGuard) OnInvalid(fn func(ctx context.Context, state CredentialsState)) *CredentialGuard {
+	g.onInvalid = fn
+	return g
+}
+
+// WithConditions maintains the CredentialsValid condition of set
+func (g *CredentialGuard) WithConditions(set *conditions.Set) *CredentialGuard {
+	g.conditions = set
+	return g
+}
+
+// SendMessage sends msg unless its credentials were rejected before
+func (g *CredentialGuard) SendMessage(ctx context.Context, msg *types.PushoverMessage) error {
+	if msg == nil {
+		return g.next.SendMessage(ctx, msg)
+	}
+
+	pair := credentialPair{token: msg.Token, user: msg.User}
+	g.mu.Lock()
+	if g.revoked[pair] {
+		reason := g.state.Reason
+		g.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrCredentialsInvalid, reason)
+	}
+	g.mu.Unlock()
+
+	err := g.next.SendMessage(ctx, msg)
+	switch {
+	case err == nil:
+		g.accepted(pair, "Accepted")
+	case IsCredentialError(err):
+		g.mu.Lock()
+		g.revoked[pair] = true
+		first := g.state == nil
+		if first {
+			g.state = &CredentialsState{Reason: err.Error(), Since: g.clock.Now()}
+		}
+		state := *g.state
+		g.mu.Unlock()
+		g.conditions.Set(conditions.CredentialsValid, conditions.StatusFalse, "Rejected", err.Error())
+
+		if first && g.onInvalid != nil {
+			g.onInvalid(ctx, state)
+		}
+	}
+	return err
+}
+
+// accepted clears the rejection of pair, and the invalid state once no
+// rejected credentials are left
+func (g *CredentialGuard) accepted(pair credentialPair, reason string) {
+	g.mu.Lock()
+	delete(g.revoked, pair)
+	valid := len(g.revoked) == 0
+	if valid {
+		g.state = nil
+	}
+	g.mu.Unlock()
+
+	if valid {
+		g.conditions.Set(conditions.CredentialsValid, conditions.StatusTrue, reason, "")
+	}
+}
+
+// Recheck validates every rejected pair of credentials once, clearing the
+// ones Pushover accepts again. Returns how many are still rejected, pairs
+// that could not be checked, e.g. during an outage, included.
+func (g *CredentialGuard) Recheck(ctx context.Context) int {
+	g.mu.Lock()
+	pairs := make([]credentialPair, 0, len(g.revoked))
+	for pair := range g.revoked {
+		pairs = append(pairs, pair)
+	}
+	g.mu.Unlock()
+
+	rejected := 0
+	for _, pair := range pairs {
+		if err := g.validator.ValidateUser(ctx, pair.token, pair.user); err != nil {
+			rejected++
+			continue
+		}
+		g.accepted(pair, "Revalidated")
+	}
+	return rejected
+}
+
+// Start validates rejected credentials in the background every interval
+// until Stop or ctx is cancelled. Without WithRecheck it does nothing.
+func (g *CredentialGuard) Start(ctx context.Context) {
+	if g == nil || g.validator == nil || g.interval <= 0 || g.started.Swap(true) {
+		return
+	}
+	go g.run(ctx)
+}
+
+// Stop ends rechecking and waits for an ongoing one to finish
+func (g *CredentialGuard) Stop(ctx context.Context) error {
+	if g == nil || !g.started.Load() {
+		return nil
+	}
+	g.stopOnce.Do(func() { close(g.stop) })
+
+	select {
+	case <-g.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run rechecks every interval while credentials are rejected
+func (g *CredentialGuard) run(ctx context.Context) {
+	defer close(g.done)
+
+	// Checks in flight are cancelled by Stop too
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-g.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		select {
+		case <-g.clock.After(g.interval):
+			g.Recheck(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Invalid returns why the credentials are invalid, or nil while they are not
+func (g *CredentialGuard) Invalid() *CredentialsState {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.state == nil {
+		return nil
+	}
+	state := *g.state
+	return &state
+}
@@ -0,0 +1,229 @@
+package pushover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/conditions"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+func TestIsCredentialError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"invalid token", &APIError{Status: http.StatusBadRequest, Errors: []string{"application token is invalid"}}, true},
+		{"invalid user", &APIError{Status: http.StatusBadRequest, Errors: []string{"user identifier is invalid"}}, true},
+		{"among other errors", &APIError{Status: http.StatusBadRequest, Errors: []string{"message cannot be blank", "Application token is invalid"}}, true},
+		{"wrapped", fmt.Errorf("send: %w", &APIError{Status: http.StatusBadRequest, Errors: []string{"application token is invalid"}}), true},
+		{"other rejection", &APIError{Status: http.StatusBadRequest, Errors: []string{"message cannot be blank"}}, false},
+		{"server error", &APIError{Status: http.StatusInternalServerError, Errors: []string{"application token is invalid"}}, false},
+		{"not an API error", errors.New("application token is invalid"), false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsCredentialError(tt.err); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// tokenSender rejects the messages of one token as invalid
+type tokenSender struct {
+	revoked string
+	err     error
+	sent    []string
+}
+
+func (s *tokenSender) SendMessage(ctx context.Context, msg *types.PushoverMessage) error {
+	s.sent = append(s.sent, msg.Token)
+	if msg.Token == s.revoked {
+		return s.err
+	}
+	return nil
+}
+
+func TestCredentialGuard(t *testing.T) {
+	revoked := &APIError{Status: http.StatusBadRequest, Errors: []string{"application token is invalid"}}
+	next := &tokenSender{revoked: "old-token", err: revoked}
+
+	var notices []CredentialsState
+	set := conditions.NewSet(conditions.CredentialsValid)
+	guard := NewCredentialGuard(next).OnInvalid(func(ctx context.Context, state CredentialsState) {
+		notices = append(notices, state)
+	}).WithConditions(set)
+	fake := clock.NewFake(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	guard.clock = fake
+
+	old := &types.PushoverMessage{Token: "old-token", User: "user"}
+	if err := guard.SendMessage(context.Background(), old); !errors.Is(err, revoked) {
+		t.Fatalf("Expected the rejection returned, got %v", err)
+	}
+	state := guard.Invalid()
+	if state == nil || state.Reason != revoked.Error() || !state.Since.Equal(fake.Now()) {
+		t.Fatalf("Expected the credentials invalid, got %+v", state)
+	}
+	if len(notices) != 1 || notices[0] != *state {
+		t.Errorf("Expected one notice of the state, got %+v", notices)
+	}
+	if condition, _ := set.Get(conditions.CredentialsValid); condition.Status != conditions.StatusFalse || condition.Message != revoked.Error() {
+		t.Errorf("Expected CredentialsValid False with the rejection, got %+v", condition)
+	}
+
+	// Sends with the rejected credentials fail fast
+	for i := 0; i < 3; i++ {
+		err := guard.SendMessage(context.Background(), old)
+		if !errors.Is(err, ErrCredentialsInvalid) {
+			t.Fatalf("Expected ErrCredentialsInvalid, got %v", err)
+		}
+	}
+	if len(next.sent) != 1 {
+		t.Errorf("Expected a single attempt with the rejected credentials, got %d", len(next.sent))
+	}
+	if len(notices) != 1 {
+		t.Errorf("Expected the notice once, got %d", len(notices))
+	}
+
+	// Other credentials are sent with, the rejected ones stay rejected
+	if err := guard.SendMessage(context.Background(), &types.PushoverMessage{Token: "new-token", User: "user"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if guard.Invalid() == nil {
+		t.Error("Expected a send with other credentials to keep the state")
+	}
+	if err := guard.SendMessage(context.Background(), old); !errors.Is(err, ErrCredentialsInvalid) {
+		t.Errorf("Expected the rejected credentials to keep failing fast, got %v", err)
+	}
+
+	var nilGuard *CredentialGuard
+	if nilGuard.Invalid() != nil {
+		t.Error("Expected a nil guard never invalid")
+	}
+}
+
+// credentialValidator accepts the credentials of accepted tokens
+type credentialValidator struct {
+	mu       sync.Mutex
+	accepted map[string]bool
+	checked  []string
+}
+
+func (v *credentialValidator) accept(token string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.accepted[token] = true
+}
+
+func (v *credentialValidator) ValidateUser(ctx context.Context, token, user string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.checked = append(v.checked, token)
+	if !v.accepted[token] {
+		return &APIError{Status: http.StatusBadRequest, Errors: []string{"application token is invalid"}}
+	}
+	return nil
+}
+
+func TestCredentialGuard_Recheck(t *testing.T) {
+	revoked := &APIError{Status: http.StatusBadRequest, Errors: []string{"application token is invalid"}}
+	next := &tokenSender{revoked: "error-token", err: revoked}
+	validator := &credentialValidator{accepted: map[string]bool{}}
+	set := conditions.NewSet(conditions.CredentialsValid)
+	guard := NewCredentialGuard(next).WithConditions(set).WithRecheck(validator, time.Minute)
+
+	for _, token := range []string{"error-token", "other-token"} {
+		next.revoked = token
+		_ = guard.SendMessage(context.Background(), &types.PushoverMessage{Token: token, User: "user"})
+	}
+
+	// Still rejected
+	if rejected := guard.Recheck(context.Background()); rejected != 2 || guard.Invalid() == nil {
+		t.Fatalf("Expected both pairs still rejected, got %d and %+v", rejected, guard.Invalid())
+	}
+
+	// One pair accepted again is cleared on its own
+	validator.accept("error-token")
+	if rejected := guard.Recheck(context.Background()); rejected != 1 || guard.Invalid() == nil {
+		t.Fatalf("Expected one pair still rejected, got %d and %+v", rejected, guard.Invalid())
+	}
+	next.revoked = ""
+	if err := guard.SendMessage(context.Background(), &types.PushoverMessage{Token: "error-token", User: "user"}); err != nil {
+		t.Errorf("Expected the revalidated credentials sent with, got %v", err)
+	}
+	if err := guard.SendMessage(context.Background(), &types.PushoverMessage{Token: "other-token", User: "user"}); !errors.Is(err, ErrCredentialsInvalid) {
+		t.Errorf("Expected the other pair to keep failing fast, got %v", err)
+	}
+
+	// The last pair accepted again makes the credentials valid
+	validator.accept("other-token")
+	if rejected := guard.Recheck(context.Background()); rejected != 0 || guard.Invalid() != nil {
+		t.Fatalf("Expected the credentials valid, got %d and %+v", rejected, guard.Invalid())
+	}
+	if condition, _ := set.Get(conditions.CredentialsValid); condition.Status != conditions.StatusTrue || condition.Reason != "Revalidated" {
+		t.Errorf("Expected CredentialsValid True, got %+v", condition)
+	}
+}
+
+func TestCredentialGuard_RecheckInBackground(t *testing.T) {
+	revoked := &APIError{Status: http.StatusBadRequest, Errors: []string{"application token is invalid"}}
+	validator := &credentialValidator{accepted: map[string]bool{}}
+	guard := NewCredentialGuard(&tokenSender{revoked: "token", err: revoked}).WithRecheck(validator, time.Minute)
+	fake := clock.NewFake(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	guard.clock = fake
+	_ = guard.SendMessage(context.Background(), &types.PushoverMessage{Token: "token", User: "user"})
+
+	guard.Start(context.Background())
+	waitForWaiters := func() {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for fake.Waiters() == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("Timed out waiting for the recheck loop")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	waitForWaiters()
+	validator.accept("token")
+	fake.Advance(time.Minute)
+	waitForWaiters()
+	if guard.Invalid() != nil {
+		t.Errorf("Expected the background recheck to clear the state, got %+v", guard.Invalid())
+	}
+
+	if err := guard.Stop(context.Background()); err != nil {
+		t.Fatalf("Unexpected stop error: %v", err)
+	}
+	var nilGuard *CredentialGuard
+	nilGuard.Start(context.Background())
+	if err := nilGuard.Stop(context.Background()); err != nil {
+		t.Errorf("Expected a nil guard to stop, got %v", err)
+	}
+}
+
+func TestCredentialGuard_OtherErrors(t *testing.T) {
+	outage := &APIError{Status: http.StatusInternalServerError}
+	mock := &MockSender{errFn: func(int) error { return outage }}
+	guard := NewCredentialGuard(mock)
+
+	for i := 0; i < 2; i++ {
+		if err := guard.SendMessage(context.Background(), &types.PushoverMessage{Token: "token"}); err != outage {
+			t.Errorf("Expected the outage returned, got %v", err)
+		}
+	}
+	if mock.calls != 2 || guard.Invalid() != nil {
+		t.Errorf("Expected other errors to keep sending, got %d calls and %+v", mock.calls, guard.Invalid())
+	}
+}
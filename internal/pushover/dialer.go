@@ -0,0 +1,134 @@
+package pushover
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+)
+
+// DialFunc opens a network connection, like net.Dialer.DialContext
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// Resolver looks up the addresses of a host, *net.Resolver satisfies it
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// DNSError is returned when the Pushover host could not be resolved, to
+// tell DNS outages apart from failures of the API itself
+type DNSError struct {
+	Host string
+	Err  error
+}
+
+func (e *DNSError) Error() string {
+	return fmt.Sprintf("failed to resolve %s: %v", e.Host, e.Err)
+}
+
+func (e *DNSError) Unwrap() error {
+	return e.Err
+}
+
+// ResolveOptions hardens how the Pushover host is resolved
+type ResolveOptions struct {
+	Host          string        // Hostname the options apply to, other hosts are dialed directly
+	Override      string        // IP:port dialed instead of resolving Host, empty resolves it
+	CacheTTL      time.Duration // How long resolved addresses are reused, zero disables the cache
+	LookupTimeout time.Duration // Limit on a single lookup, zero leaves it to the dial
+}
+
+// cachedAddrs are the addresses of a lookup and when they go stale
+type cachedAddrs struct {
+	addrs   []string
+	expires time.Time
+}
+
+// HostDialer dials the Pushover host through a static override or a
+// positive DNS cache. Stale addresses are still used when a lookup fails,
+// so that a cluster DNS outage does not also silence the alerts about it.
+type HostDialer struct {
+	opts     ResolveOptions
+	dial     DialFunc
+	resolver Resolver
+	clock    clock.Clock
+
+	mu    sync.Mutex
+	cache *cachedAddrs
+}
+
+// NewHostDialer creates a dialer resolving opts.Host through resolver and
+// connecting with dial
+func NewHostDialer(opts ResolveOptions, dial DialFunc, resolver Resolver) *HostDialer {
+	return &HostDialer{
+		opts:     opts,
+		dial:     dial,
+		resolver: resolver,
+		clock:    clock.Real{},
+	}
+}
+
+// DialContext connects to address, resolving the Pushover host itself
+func (d *HostDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || host != d.opts.Host {
+		return d.dial(ctx, network, address)
+	}
+	if d.opts.Override != "" {
+		return d.dial(ctx, network, d.opts.Override)
+	}
+
+	addrs, err := d.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialErr error
+	for _, addr := range addrs {
+		conn, err := d.dial(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+		dialErr = err
+	}
+	return nil, dialErr
+}
+
+// lookup returns the cached addresses of host while they are fresh, and
+// resolves them again otherwise
+func (d *HostDialer) lookup(ctx context.Context, host string) ([]string, error) {
+	d.mu.Lock()
+	cached := d.cache
+	d.mu.Unlock()
+	if cached != nil && d.clock.Now().Before(cached.expires) {
+		return cached.addrs, nil
+	}
+
+	lookupCtx := ctx
+	if d.opts.LookupTimeout > 0 {
+		var cancel context.CancelFunc
+		lookupCtx, cancel = context.WithTimeout(ctx, d.opts.LookupTimeout)
+		defer cancel()
+	}
+
+	addrs, err := d.resolver.LookupHost(lookupCtx, host)
+	if err != nil || len(addrs) == 0 {
+		if cached != nil {
+			return cached.addrs, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("no addresses")
+		}
+		return nil, &DNSError{Host: host, Err: err}
+	}
+
+	if d.opts.CacheTTL > 0 {
+		d.mu.Lock()
+		d.cache = &cachedAddrs{addrs: addrs, expires: d.clock.Now().Add(d.opts.CacheTTL)}
+		d.mu.Unlock()
+	}
+	return addrs, nil
+}
@@ -0,0 +1,163 @@
+package pushover
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// FakeResolver answers lookups from a function and counts them
+type FakeResolver struct {
+	lookups  int
+	lookupFn func(ctx context.Context, host string) ([]string, error)
+}
+
+func (f *FakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	f.lookups++
+	return f.lookupFn(ctx, host)
+}
+
+// FakeDialer records the dialed addresses and fails those listed in refuse
+type FakeDialer struct {
+	dialed []string
+	refuse map[string]bool
+}
+
+func (f *FakeDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	f.dialed = append(f.dialed, address)
+	if f.refuse[address] {
+		return nil, errors.New("connection refused")
+	}
+	client, server := net.Pipe()
+	server.Close()
+	return client, nil
+}
+
+func TestHostDialer_Override(t *testing.T) {
+	resolver := &FakeResolver{lookupFn: func(context.Context, string) ([]string, error) {
+		return nil, errors.New("dns down")
+	}}
+	dialer := &FakeDialer{}
+	d := NewHostDialer(ResolveOptions{Host: "api.pushover.net", Override: "203.0.113.7:443"}, dialer.DialContext, resolver)
+
+	conn, err := d.DialContext(context.Background(), "tcp", "api.pushover.net:443")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	conn.Close()
+
+	if resolver.lookups != 0 || len(dialer.dialed) != 1 || dialer.dialed[0] != "203.0.113.7:443" {
+		t.Errorf("Expected the override dialed without a lookup, got %d lookups and %v", resolver.lookups, dialer.dialed)
+	}
+}
+
+func TestHostDialer_OtherHosts(t *testing.T) {
+	resolver := &FakeResolver{}
+	dialer := &FakeDialer{}
+	d := NewHostDialer(ResolveOptions{Host: "api.pushover.net", Override: "203.0.113.7:443"}, dialer.DialContext, resolver)
+
+	conn, err := d.DialContext(context.Background(), "tcp", "ntfy.sh:443")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	conn.Close()
+
+	if resolver.lookups != 0 || len(dialer.dialed) != 1 || dialer.dialed[0] != "ntfy.sh:443" {
+		t.Errorf("Expected other hosts dialed as they are, got %d lookups and %v", resolver.lookups, dialer.dialed)
+	}
+}
+
+func TestHostDialer_Cache(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	failing := false
+	resolver := &FakeResolver{lookupFn: func(context.Context, string) ([]string, error) {
+		if failing {
+			return nil, errors.New("dns down")
+		}
+		return []string{"198.51.100.1", "198.51.100.2"}, nil
+	}}
+	dialer := &FakeDialer{refuse: map[string]bool{"198.51.100.1:443": true}}
+	d := NewHostDialer(ResolveOptions{Host: "api.pushover.net", CacheTTL: time.Minute}, dialer.DialContext, resolver)
+	d.clock = fake
+
+	dial := func() {
+		t.Helper()
+		conn, err := d.DialContext(context.Background(), "tcp", "api.pushover.net:443")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		conn.Close()
+	}
+
+	dial()
+	dial()
+	if resolver.lookups != 1 {
+		t.Errorf("Expected a cache hit within the TTL, got %d lookups", resolver.lookups)
+	}
+	if len(dialer.dialed) != 4 || dialer.dialed[1] != "198.51.100.2:443" {
+		t.Errorf("Expected the next address after a refused one, got %v", dialer.dialed)
+	}
+
+	fake.Advance(2 * time.Minute)
+	dial()
+	if resolver.lookups != 2 {
+		t.Errorf("Expected a new lookup after the TTL, got %d lookups", resolver.lookups)
+	}
+
+	// Stale addresses keep alerts flowing through a DNS outage
+	fake.Advance(2 * time.Minute)
+	failing = true
+	dial()
+	if resolver.lookups != 3 {
+		t.Errorf("Expected a lookup attempt, got %d lookups", resolver.lookups)
+	}
+}
+
+func TestHostDialer_DNSError(t *testing.T) {
+	resolver := &FakeResolver{lookupFn: func(ctx context.Context, host string) ([]string, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+	dialer := &FakeDialer{}
+	d := NewHostDialer(ResolveOptions{Host: "api.pushover.net", CacheTTL: time.Minute, LookupTimeout: 10 * time.Millisecond}, dialer.DialContext, resolver)
+
+	start := time.Now()
+	_, err := d.DialContext(context.Background(), "tcp", "api.pushover.net:443")
+	var dnsErr *DNSError
+	if !errors.As(err, &dnsErr) || dnsErr.Host != "api.pushover.net" || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected a DNS error after the lookup timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the lookup timeout to end the lookup, took %v", elapsed)
+	}
+	if len(dialer.dialed) != 0 {
+		t.Errorf("Expected nothing dialed, got %v", dialer.dialed)
+	}
+}
+
+func TestPushoverClient_CountsDNSFailures(t *testing.T) {
+	resolver := &FakeResolver{lookupFn: func(context.Context, string) ([]string, error) {
+		return nil, errors.New("dns down")
+	}}
+	d := NewHostDialer(ResolveOptions{Host: "api.pushover.net"}, (&FakeDialer{}).DialContext, resolver)
+
+	registry := metrics.NewRegistry()
+	httpClient := &http.Client{Transport: &http.Transport{DialContext: d.DialContext}}
+	client := NewPushoverClient(httpClient, "http://api.pushover.net/1/messages.json").WithMetrics(registry)
+
+	err := client.SendMessage(context.Background(), &types.PushoverMessage{Token: "t", User: "u", Message: "m"})
+	var dnsErr *DNSError
+	if !errors.As(err, &dnsErr) {
+		t.Fatalf("Expected a DNS error, got %v", err)
+	}
+	if failures := registry.Counter("pushover_dns_failures_total", "").Value(); failures != 1 {
+		t.Errorf("Expected 1 DNS failure counted, got %d", failures)
+	}
+}
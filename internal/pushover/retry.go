@@ -0,0 +1,191 @@
+package pushover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// Retry defaults
+const (
+	DefaultRetryBackoff      = 500 * time.Millisecond
+	DefaultRetryBudgetTokens = 10
+	DefaultRetryBudgetRatio  = 0.1
+)
+
+// ErrRetryBudgetExhausted is returned when the shared retry budget forbids another attempt
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// RetriesExhaustedError is returned when every attempt allowed by the retry
+// configuration failed with a retryable error
+type RetriesExhaustedError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetriesExhaustedError) Error() string {
+	return fmt.Sprintf("retries exhausted after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *RetriesExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// MessageSender sends a single Pushover message
+type MessageSender interface {
+	SendMessage(ctx context.Context, msg *types.PushoverMessage) error
+}
+
+// RetryBudget is a process-wide token bucket limiting retries, modelled on
+// gRPC retry throttling: every failure costs one token, every success earns
+// ratio tokens, and retries are only allowed while more than half of the
+// tokens are left.
+type RetryBudget struct {
+	mu        sync.Mutex
+	tokens    float64
+	maxTokens float64
+	ratio     float64
+}
+
+// NewRetryBudget creates a full retry budget
+func NewRetryBudget(maxTokens, ratio float64) *RetryBudget {
+	return &RetryBudget{
+		tokens:    maxTokens,
+		maxTokens: maxTokens,
+		ratio:     ratio,
+	}
+}
+
+// OnSuccess refills the budget after a successful send
+func (b *RetryBudget) OnSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.maxTokens, b.tokens+b.ratio)
+}
+
+// OnFailure drains the budget after a failed send
+func (b *RetryBudget) OnFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = max(0, b.tokens-1)
+}
+
+// AllowRetry reports whether a retry may be attempted
+func (b *RetryBudget) AllowRetry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens > b.maxTokens/2
+}
+
+// Tokens returns the current number of tokens
+func (b *RetryBudget) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens
+}
+
+// RetryingSender retries failed sends within a shared retry budget
+type RetryingSender struct {
+	next           MessageSender
+	maxAttempts    int
+	backoff        time.Duration
+	budget         *RetryBudget
+	retryOnTimeout bool // Also retry timeouts after the request was written
+	clock          clock.Clock
+}
+
+// NewRetryingSender wraps next with retries
+func NewRetryingSender(next MessageSender, maxAttempts int, backoff time.Duration, budget *RetryBudget) *RetryingSender {
+	return &RetryingSender{
+		next:        next,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		budget:      budget,
+		clock:       clock.Real{},
+	}
+}
+
+// WithRetryOnTimeout also retries requests that timed out after being
+// written, which may deliver a message Pushover already accepted twice
+func (r *RetryingSender) WithRetryOnTimeout(enabled bool) *RetryingSender {
+	r.retryOnTimeout = enabled
+	return r
+}
+
+// SendMessage sends msg, retrying retryable failures while attempts and budget remain
+func (r *RetryingSender) SendMessage(ctx context.Context, msg *types.PushoverMessage) error {
+	for attempt := 1; ; attempt++ {
+		err := r.next.SendMessage(ctx, msg)
+		if err == nil {
+			r.budget.OnSuccess()
+			return nil
+		}
+		r.budget.OnFailure()
+
+		if !ShouldRetry(err, r.retryOnTimeout) {
+			if attempt < r.maxAttempts && IsAmbiguous(err) {
+				return fmt.Errorf("not retried, Pushover may have accepted the message: %w", err)
+			}
+			return err
+		}
+		if attempt >= r.maxAttempts {
+			if attempt > 1 {
+				return &RetriesExhaustedError{Attempts: attempt, Err: err}
+			}
+			return err
+		}
+		if !r.budget.AllowRetry() {
+			return fmt.Errorf("%w: %w", ErrRetryBudgetExhausted, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-r.clock.After(r.backoff << (attempt - 1)):
+		}
+	}
+}
+
+// ShouldRetry decides whether a failed send is retried. Failures before the
+// request was written are safe to retry. A timeout after it was written may
+// hide a message Pushover accepted, it is only retried with retryOnTimeout
+// (pure function).
+func ShouldRetry(err error, retryOnTimeout bool) bool {
+	if IsAmbiguous(err) {
+		return retryOnTimeout
+	}
+	return IsRetryable(err)
+}
+
+// IsAmbiguous reports whether err timed out after the request was written,
+// leaving it unknown whether Pushover accepted it (pure function)
+func IsAmbiguous(err error) bool {
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) || !transportErr.Written {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// IsRetryable reports whether a send error is worth retrying (pure function)
+func IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrAttachmentTooLarge) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		// Client errors (bad token, invalid user) will not succeed on retry
+		return apiErr.Status == http.StatusTooManyRequests || apiErr.Status >= 500
+	}
+
+	return true
+}
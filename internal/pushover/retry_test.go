@@ -0,0 +1,256 @@
+package pushover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// MockSender counts SendMessage calls and returns errors from a function
+type MockSender struct {
+	calls int
+	errFn func(call int) error
+}
+
+func (m *MockSender) SendMessage(ctx context.Context, msg *types.PushoverMessage) error {
+	m.calls++
+	return m.errFn(m.calls)
+}
+
+func TestRetryBudget(t *testing.T) {
+	budget := NewRetryBudget(10, 0.5)
+
+	if !budget.AllowRetry() {
+		t.Error("Expected full budget to allow retries")
+	}
+
+	for i := 0; i < 5; i++ {
+		budget.OnFailure()
+	}
+	if budget.AllowRetry() {
+		t.Errorf("Expected budget at %v tokens to forbid retries", budget.Tokens())
+	}
+
+	budget.OnSuccess()
+	if !budget.AllowRetry() {
+		t.Errorf("Expected budget at %v tokens to allow retries", budget.Tokens())
+	}
+
+	for i := 0; i < 100; i++ {
+		budget.OnSuccess()
+	}
+	if budget.Tokens() != 10 {
+		t.Errorf("Expected tokens capped at 10, got %v", budget.Tokens())
+	}
+
+	for i := 0; i < 100; i++ {
+		budget.OnFailure()
+	}
+	if budget.Tokens() != 0 {
+		t.Errorf("Expected tokens floored at 0, got %v", budget.Tokens())
+	}
+}
+
+func TestRetryingSender_RetriesUntilSuccess(t *testing.T) {
+	mock := &MockSender{errFn: func(call int) error {
+		if call < 3 {
+			return &APIError{Status: http.StatusBadGateway}
+		}
+		return nil
+	}}
+
+	sender := NewRetryingSender(mock, 3, time.Millisecond, NewRetryBudget(10, 0.1))
+	if err := sender.SendMessage(context.Background(), &types.PushoverMessage{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if mock.calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", mock.calls)
+	}
+}
+
+func TestRetryingSender_NonRetryable(t *testing.T) {
+	mock := &MockSender{errFn: func(int) error {
+		return &APIError{Status: http.StatusBadRequest, Body: `{"token":"invalid"}`}
+	}}
+
+	sender := NewRetryingSender(mock, 3, time.Millisecond, NewRetryBudget(10, 0.1))
+	err := sender.SendMessage(context.Background(), &types.PushoverMessage{})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest {
+		t.Errorf("Expected APIError 400, got %v", err)
+	}
+
+	if mock.calls != 1 {
+		t.Errorf("Expected no retries for 4xx, got %d attempts", mock.calls)
+	}
+}
+
+func TestRetryingSender_BudgetExhausted(t *testing.T) {
+	mock := &MockSender{errFn: func(int) error {
+		return fmt.Errorf("failed to send request: connection refused")
+	}}
+
+	budget := NewRetryBudget(10, 0.1)
+	sender := NewRetryingSender(mock, 3, 0, budget)
+
+	// Sustained failures drain the budget until retries stop entirely
+	var attempts []int
+	for i := 0; i < 6; i++ {
+		before := mock.calls
+		err := sender.SendMessage(context.Background(), &types.PushoverMessage{})
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		attempts = append(attempts, mock.calls-before)
+	}
+
+	expected := []int{3, 2, 1, 1, 1, 1}
+	if fmt.Sprint(attempts) != fmt.Sprint(expected) {
+		t.Errorf("Expected attempts per send %v, got %v", expected, attempts)
+	}
+
+	err := sender.SendMessage(context.Background(), &types.PushoverMessage{})
+	if !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Errorf("Expected ErrRetryBudgetExhausted, got %v", err)
+	}
+}
+
+func TestRetryingSender_ContextCancelled(t *testing.T) {
+	mock := &MockSender{errFn: func(int) error { return fmt.Errorf("timeout") }}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	sender := NewRetryingSender(mock, 5, time.Hour, NewRetryBudget(10, 0.1))
+	if err := sender.SendMessage(ctx, &types.PushoverMessage{}); err == nil {
+		t.Error("Expected error")
+	}
+
+	if mock.calls != 1 {
+		t.Errorf("Expected cancelled context to stop retries, got %d attempts", mock.calls)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"transport error", fmt.Errorf("connection refused"), true},
+		{"server error", &APIError{Status: 503}, true},
+		{"rate limited", &APIError{Status: 429}, true},
+		{"client error", &APIError{Status: 400}, false},
+		{"wrapped client error", fmt.Errorf("send: %w", &APIError{Status: 401}), false},
+		{"context cancelled", context.Canceled, false},
+		{"deadline", fmt.Errorf("send: %w", context.DeadlineExceeded), false},
+		{"attachment too large", fmt.Errorf("%w: 3000000 bytes", ErrAttachmentTooLarge), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.expected {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRetryingSender_RetriesExhausted(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxAttempts   int
+		err           error
+		wantExhausted bool
+		wantAttempts  int
+	}{
+		{"all attempts fail", 3, &APIError{Status: http.StatusBadGateway}, true, 3},
+		{"single attempt", 1, &APIError{Status: http.StatusBadGateway}, false, 1},
+		{"non-retryable", 3, &APIError{Status: http.StatusBadRequest}, false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockSender{errFn: func(int) error { return tt.err }}
+			sender := NewRetryingSender(mock, tt.maxAttempts, 0, NewRetryBudget(10, 0.1))
+			err := sender.SendMessage(context.Background(), &types.PushoverMessage{})
+
+			var exhausted *RetriesExhaustedError
+			if errors.As(err, &exhausted) != tt.wantExhausted {
+				t.Fatalf("Expected RetriesExhaustedError %v, got %v", tt.wantExhausted, err)
+			}
+			if tt.wantExhausted && exhausted.Attempts != tt.wantAttempts {
+				t.Errorf("Expected %d attempts in error, got %d", tt.wantAttempts, exhausted.Attempts)
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Errorf("Expected the last APIError to be wrapped, got %v", err)
+			}
+			if mock.calls != tt.wantAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.wantAttempts, mock.calls)
+			}
+		})
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	refused := &TransportError{Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}
+	timeoutBeforeWrite := &TransportError{Err: &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}}
+	timeoutAfterWrite := &TransportError{Err: &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, Written: true}
+	resetAfterWrite := &TransportError{Err: &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, Written: true}
+
+	tests := []struct {
+		name           string
+		err            error
+		retryOnTimeout bool
+		expected       bool
+	}{
+		{"refused connection", refused, false, true},
+		{"timeout before write", timeoutBeforeWrite, false, true},
+		{"timeout after write", timeoutAfterWrite, false, false},
+		{"timeout after write opted in", timeoutAfterWrite, true, true},
+		{"wrapped timeout after write", fmt.Errorf("send: %w", timeoutAfterWrite), false, false},
+		{"reset after write", resetAfterWrite, false, true},
+		{"server error", &APIError{Status: 503}, false, true},
+		{"client error", &APIError{Status: 400}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShouldRetry(tt.err, tt.retryOnTimeout); got != tt.expected {
+				t.Errorf("ShouldRetry(%v, %v) = %v, want %v", tt.err, tt.retryOnTimeout, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRetryingSender_TimeoutAfterWrite(t *testing.T) {
+	timeout := &TransportError{Err: &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, Written: true}
+
+	mock := &MockSender{errFn: func(int) error { return timeout }}
+	err := NewRetryingSender(mock, 3, 0, NewRetryBudget(10, 0.1)).SendMessage(context.Background(), &types.PushoverMessage{})
+	if mock.calls != 1 {
+		t.Errorf("Expected no retry after a timeout, got %d attempts", mock.calls)
+	}
+	if !errors.Is(err, timeout) || !strings.Contains(err.Error(), "not retried, Pushover may have accepted the message") {
+		t.Errorf("Expected the timeout explained, got %v", err)
+	}
+
+	mock = &MockSender{errFn: func(int) error { return timeout }}
+	NewRetryingSender(mock, 3, 0, NewRetryBudget(10, 0.1)).WithRetryOnTimeout(true).SendMessage(context.Background(), &types.PushoverMessage{})
+	if mock.calls != 3 {
+		t.Errorf("Expected retries with RETRY_ON_TIMEOUT, got %d attempts", mock.calls)
+	}
+}
@@ -0,0 +1,89 @@
+package pushover
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/conditions"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+// SendError is the most recent failed send
+type SendError struct {
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// StatusTracker records the outcome of the latest send. A failure is kept
+// until the next successful send clears it.
+type StatusTracker struct {
+	next       MessageSender
+	lastError  atomic.Pointer[SendError]
+	clock      clock.Clock
+	conditions *conditions.Set // nil maintains no PushoverReachable condition
+}
+
+// NewStatusTracker wraps next, tracking its send outcomes
+func NewStatusTracker(next MessageSender) *StatusTracker {
+	return &StatusTracker{
+		next:  next,
+		clock: clock.Real{},
+	}
+}
+
+// WithConditions maintains the PushoverReachable condition of set
+func (t *StatusTracker) WithConditions(set *conditions.Set) *StatusTracker {
+	t.conditions = set
+	return t
+}
+
+// WithClock makes the tracker time failed sends by clk
+func (t *StatusTracker) WithClock(clk clock.Clock) *StatusTracker {
+	t.clock = clk
+	return t
+}
+
+// SendMessage sends through the wrapped sender and records the outcome
+func (t *StatusTracker) SendMessage(ctx context.Context, msg *types.PushoverMessage) error {
+	err := t.next.SendMessage(ctx, msg)
+	if err != nil {
+		t.lastError.Store(&SendError{Message: err.Error(), Time: t.clock.Now()})
+	} else {
+		t.lastError.Store(nil)
+	}
+	if status, reason, ok := reachability(err); ok {
+		message := ""
+		if err != nil {
+			message = err.Error()
+		}
+		t.conditions.Set(conditions.PushoverReachable, status, reason, message)
+	}
+	return err
+}
+
+// reachability tells from the outcome of a send whether Pushover could be
+// reached. Errors raised before calling Pushover tell nothing (pure function).
+func reachability(err error) (conditions.Status, string, bool) {
+	var apiErr *APIError
+	switch {
+	case err == nil:
+		return conditions.StatusTrue, "Sent", true
+	case errors.Is(err, ErrCredentialsInvalid), errors.Is(err, ErrAttachmentTooLarge), errors.Is(err, context.Canceled):
+		return "", "", false
+	case errors.As(err, &apiErr) && apiErr.Status < http.StatusInternalServerError && apiErr.Status != http.StatusTooManyRequests:
+		return conditions.StatusTrue, "Rejected", true
+	case errors.As(err, &apiErr):
+		return conditions.StatusFalse, "APIUnavailable", true
+	default:
+		return conditions.StatusFalse, "RequestFailed", true
+	}
+}
+
+// LastError returns the error of the latest send, or nil if it succeeded
+func (t *StatusTracker) LastError() *SendError {
+	return t.lastError.Load()
+}
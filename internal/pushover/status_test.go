@@ -0,0 +1,76 @@
+package pushover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/conditions"
+	"github.com/zhorvath83/flux-provider-pushover/internal/types"
+)
+
+func TestStatusTracker(t *testing.T) {
+	var sendErr error
+	mock := &MockSender{errFn: func(int) error { return sendErr }}
+
+	tracker := NewStatusTracker(mock)
+	tracker.clock = clock.NewFake(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	if tracker.LastError() != nil {
+		t.Fatal("Expected no error before the first send")
+	}
+
+	sendErr = fmt.Errorf("pushover API returned status 400: invalid token")
+	if err := tracker.SendMessage(context.Background(), &types.PushoverMessage{}); err != sendErr {
+		t.Errorf("Expected send error to be returned, got %v", err)
+	}
+
+	lastError := tracker.LastError()
+	if lastError == nil || lastError.Message != sendErr.Error() || !lastError.Time.Equal(tracker.clock.Now()) {
+		t.Errorf("Unexpected last error: %+v", lastError)
+	}
+
+	sendErr = nil
+	if err := tracker.SendMessage(context.Background(), &types.PushoverMessage{}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if tracker.LastError() != nil {
+		t.Errorf("Expected success to clear the last error, got %+v", tracker.LastError())
+	}
+}
+
+func TestStatusTracker_Conditions(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status conditions.Status
+		reason string
+	}{
+		{"sent", nil, conditions.StatusTrue, "Sent"},
+		{"rejected message", &APIError{Status: http.StatusBadRequest, Errors: []string{"message cannot be blank"}}, conditions.StatusTrue, "Rejected"},
+		{"rate limited", &APIError{Status: http.StatusTooManyRequests}, conditions.StatusFalse, "APIUnavailable"},
+		{"server error", &APIError{Status: http.StatusBadGateway}, conditions.StatusFalse, "APIUnavailable"},
+		{"unreachable", errors.New("dial tcp: connection refused"), conditions.StatusFalse, "RequestFailed"},
+		{"not sent", ErrCredentialsInvalid, conditions.StatusUnknown, conditions.ReasonUnknown},
+		{"too large", fmt.Errorf("%w: 1 bytes over 0", ErrAttachmentTooLarge), conditions.StatusUnknown, conditions.ReasonUnknown},
+		{"cancelled", context.Canceled, conditions.StatusUnknown, conditions.ReasonUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := conditions.NewSet(conditions.PushoverReachable)
+			tracker := NewStatusTracker(&MockSender{errFn: func(int) error { return tt.err }}).WithConditions(set)
+			_ = tracker.SendMessage(context.Background(), &types.PushoverMessage{})
+
+			condition, _ := set.Get(conditions.PushoverReachable)
+			if condition.Status != tt.status || condition.Reason != tt.reason {
+				t.Errorf("Expected %s %s, got %+v", tt.status, tt.reason, condition)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+)
+
+// DefaultMaxKeys bounds the number of buckets a KeyedLimiter keeps
+const DefaultMaxKeys = 1000
+
+// bucket is the token bucket of one key in the LRU list
+type bucket struct {
+	key     string
+	tokens  float64
+	updated time.Time
+}
+
+// KeyedLimiter gives every key, e.g. a namespace, its own token bucket
+// refilled at perMinute tokens a minute and holding up to perMinute tokens.
+// It is bounded to maxKeys buckets, evicting the least recently used one,
+// whose key then starts again with a full bucket. Rate limited events are
+// counted per key in alerts_rate_limited_total, whose series are dropped
+// with their bucket, so that the metric is bounded by maxKeys too.
+type KeyedLimiter struct {
+	perMinute float64
+	rate      float64 // Tokens per second
+	burst     float64
+	maxKeys   int
+	clock     clock.Clock
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	order   *list.List // Front is most recently used
+
+	limited *metrics.CounterVec
+}
+
+// NewKeyedLimiter creates a limiter allowing perMinute events a minute per key
+func NewKeyedLimiter(perMinute float64, maxKeys int, registry *metrics.Registry) *KeyedLimiter {
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxKeys
+	}
+
+	return &KeyedLimiter{
+		perMinute: perMinute,
+		rate:      perMinute / 60,
+		burst:     max(1, perMinute),
+		maxKeys:   maxKeys,
+		clock:     clock.Real{},
+		buckets:   make(map[string]*list.Element),
+		order:     list.New(),
+		limited:   registry.CounterVec("alerts_rate_limited_total", "Alerts dropped by the per-namespace rate limit", "namespace"),
+	}
+}
+
+// WithClock makes the limiter refill its buckets on clk
+func (l *KeyedLimiter) WithClock(clk clock.Clock) *KeyedLimiter {
+	l.clock = clk
+	return l
+}
+
+// Allow takes a token from key's bucket, reporting false and counting the
+// event when the bucket is empty. A nil KeyedLimiter allows everything.
+func (l *KeyedLimiter) Allow(key string) bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Counted under the lock, so that an evicted key cannot leave a series behind
+	allowed := l.take(key)
+	if !allowed {
+		l.limited.WithLabelValues(key).Inc()
+	}
+	return allowed
+}
+
+// PerMinute returns the events a minute allowed per key
+func (l *KeyedLimiter) PerMinute() float64 {
+	return l.perMinute
+}
+
+// Len returns the number of buckets kept
+func (l *KeyedLimiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+// take refills and takes a token from key's bucket, caller must hold the lock
+func (l *KeyedLimiter) take(key string) bool {
+	now := l.clock.Now()
+
+	element, ok := l.buckets[key]
+	if !ok {
+		element = l.order.PushFront(&bucket{key: key, tokens: l.burst, updated: now})
+		l.buckets[key] = element
+		for l.order.Len() > l.maxKeys {
+			oldest := l.order.Back()
+			l.order.Remove(oldest)
+			evicted := oldest.Value.(*bucket).key
+			delete(l.buckets, evicted)
+			l.limited.Delete(evicted)
+		}
+	} else {
+		l.order.MoveToFront(element)
+	}
+
+	b := element.Value.(*bucket)
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = min(l.burst, b.tokens+elapsed.Seconds()*l.rate)
+		b.updated = now
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
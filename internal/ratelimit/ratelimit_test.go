@@ -0,0 +1,160 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/metrics"
+)
+
+func newTestLimiter(perMinute float64, maxKeys int, registry *metrics.Registry) (*KeyedLimiter, *clock.Fake) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	limiter := NewKeyedLimiter(perMinute, maxKeys, registry)
+	limiter.clock = fake
+	return limiter, fake
+}
+
+func TestKeyedLimiter_ThrottlesPerKey(t *testing.T) {
+	registry := metrics.NewRegistry()
+	limiter, _ := newTestLimiter(3, 10, registry)
+
+	var noisy []bool
+	for i := 0; i < 5; i++ {
+		noisy = append(noisy, limiter.Allow("noisy"))
+	}
+	if fmt.Sprint(noisy) != "[true true true false false]" {
+		t.Errorf("Expected the noisy namespace to be throttled after 3 alerts, got %v", noisy)
+	}
+
+	for i := 0; i < 3; i++ {
+		if !limiter.Allow("quiet") {
+			t.Errorf("Expected alert %d of the quiet namespace to flow freely", i)
+		}
+	}
+
+	if limited := registry.CounterVec("alerts_rate_limited_total", "", "namespace").WithLabelValues("noisy").Value(); limited != 2 {
+		t.Errorf("Expected 2 rate limited alerts for noisy, got %d", limited)
+	}
+	if limited := registry.CounterVec("alerts_rate_limited_total", "", "namespace").WithLabelValues("quiet").Value(); limited != 0 {
+		t.Errorf("Expected no rate limited alerts for quiet, got %d", limited)
+	}
+	if limiter.PerMinute() != 3 {
+		t.Errorf("Expected 3 a minute, got %v", limiter.PerMinute())
+	}
+}
+
+func TestKeyedLimiter_Refills(t *testing.T) {
+	limiter, fake := newTestLimiter(60, 10, nil)
+
+	for i := 0; i < 60; i++ {
+		limiter.Allow("apps")
+	}
+	if limiter.Allow("apps") {
+		t.Fatal("Expected empty bucket")
+	}
+
+	fake.Advance(time.Second)
+	if !limiter.Allow("apps") {
+		t.Error("Expected one token after a second at 60 a minute")
+	}
+	if limiter.Allow("apps") {
+		t.Error("Expected a single refilled token")
+	}
+
+	// Refills never exceed the burst
+	fake.Advance(time.Hour)
+	allowed := 0
+	for i := 0; i < 100; i++ {
+		if limiter.Allow("apps") {
+			allowed++
+		}
+	}
+	if allowed != 60 {
+		t.Errorf("Expected 60 alerts after a long pause, got %d", allowed)
+	}
+}
+
+func TestKeyedLimiter_SlowRateAllowsOne(t *testing.T) {
+	limiter, _ := newTestLimiter(0.5, 10, nil)
+
+	if !limiter.Allow("apps") || limiter.Allow("apps") {
+		t.Error("Expected a rate below one a minute to allow a single alert")
+	}
+}
+
+func TestKeyedLimiter_Bounded(t *testing.T) {
+	limiter, _ := newTestLimiter(1, 2, nil)
+
+	limiter.Allow("a")
+	limiter.Allow("b")
+	limiter.Allow("c")
+
+	if limiter.Len() != 2 {
+		t.Errorf("Expected 2 buckets, got %d", limiter.Len())
+	}
+
+	// "a" was evicted and starts with a full bucket, "c" is still empty
+	if !limiter.Allow("a") {
+		t.Error("Expected evicted key to start with a full bucket")
+	}
+	if limiter.Allow("c") {
+		t.Error("Expected recent key to keep its empty bucket")
+	}
+}
+
+func TestKeyedLimiter_BoundedMetric(t *testing.T) {
+	registry := metrics.NewRegistry()
+	limiter, _ := newTestLimiter(1, 2, registry)
+	limited := registry.CounterVec("alerts_rate_limited_total", "", "namespace")
+
+	for i := 0; i < 100; i++ {
+		namespace := fmt.Sprintf("ns-%d", i)
+		limiter.Allow(namespace)
+		limiter.Allow(namespace)
+	}
+
+	var out strings.Builder
+	registry.WriteTo(&out)
+	if series := strings.Count(out.String(), "alerts_rate_limited_total{"); series != 2 {
+		t.Errorf("Expected a series for each of the 2 buckets kept, got %d:\n%s", series, out.String())
+	}
+	if limited.WithLabelValues("ns-99").Value() != 1 {
+		t.Errorf("Expected the most recent namespace to keep its count, got %d", limited.WithLabelValues("ns-99").Value())
+	}
+}
+
+func TestKeyedLimiter_Nil(t *testing.T) {
+	var limiter *KeyedLimiter
+	if !limiter.Allow("apps") {
+		t.Error("Expected nil limiter to allow everything")
+	}
+}
+
+func TestKeyedLimiter_Concurrent(t *testing.T) {
+	limiter := NewKeyedLimiter(100, 10, metrics.NewRegistry())
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if limiter.Allow("apps") {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The test may take long enough for a token or two to be refilled
+	if allowed < 100 || allowed > 102 {
+		t.Errorf("Expected about 100 allowed alerts, got %d", allowed)
+	}
+}
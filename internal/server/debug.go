@@ -0,0 +1,43 @@
+package server
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+)
+
+// NewDebugHandler serves net/http/pprof and expvar, with vars published
+// next to the process-wide ones. It is only meant for the debug listener,
+// never for the public router.
+func NewDebugHandler(vars map[string]expvar.Var) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", varsHandler(vars))
+	return mux
+}
+
+// varsHandler renders the expvar registry plus vars as one JSON object.
+// Keeping vars out of the global registry lets every server instance
+// publish its own values.
+func varsHandler(vars map[string]expvar.Var) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		values := make(map[string]json.RawMessage)
+		expvar.Do(func(kv expvar.KeyValue) {
+			values[kv.Key] = json.RawMessage(kv.Value.String())
+		})
+		for name, v := range vars {
+			values[name] = json.RawMessage(v.String())
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err := json.NewEncoder(w).Encode(values); err != nil {
+			// Response header already written, can't do much more
+			return
+		}
+	}
+}
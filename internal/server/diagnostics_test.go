@@ -0,0 +1,204 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/config"
+)
+
+func TestServer_WaitForSignals(t *testing.T) {
+	tests := []struct {
+		name        string
+		diagnostics DiagnosticsFunc
+		expected    string
+	}{
+		{
+			name: "dump",
+			diagnostics: func(logger Logger) {
+				logger.Printf("Diagnostics runtime: %s", `{"goroutines":4}`)
+			},
+			expected: `Diagnostics runtime: {"goroutines":4}`,
+		},
+		{
+			name:     "nothing registered",
+			expected: "No diagnostics registered",
+		},
+		{
+			name: "panicking dump",
+			diagnostics: func(logger Logger) {
+				panic("half initialised")
+			},
+			expected: "Diagnostics dump failed: half initialised",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &MockLogger{}
+			srv := NewServer(&config.Config{Port: "127.0.0.1:0"}, http.NotFoundHandler(), logger)
+			if tt.diagnostics != nil {
+				srv.RegisterDiagnostics(tt.diagnostics)
+			}
+			if err := srv.Start(); err != nil {
+				t.Fatalf("Failed to start server: %v", err)
+			}
+
+			signals := make(chan os.Signal, 1)
+			done := make(chan error, 1)
+			go func() {
+				done <- srv.waitForSignals(signals)
+			}()
+
+			signals <- syscall.SIGUSR1
+			deadline := time.Now().Add(2 * time.Second)
+			for !logged(logger, tt.expected) && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if !logged(logger, tt.expected) {
+				t.Fatalf("Expected %q to be logged, got %v", tt.expected, logger.Messages)
+			}
+
+			// SIGUSR1 must not stop the server
+			select {
+			case err := <-done:
+				t.Fatalf("Server stopped on SIGUSR1: %v", err)
+			case <-time.After(50 * time.Millisecond):
+			}
+			if logged(logger, "Shutting down server...") {
+				t.Fatal("Expected no shutdown on SIGUSR1")
+			}
+
+			signals <- syscall.SIGTERM
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("Unexpected shutdown error: %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Server did not shut down on SIGTERM")
+			}
+			if !logged(logger, "Server exited") {
+				t.Error("Expected graceful shutdown after SIGTERM")
+			}
+		})
+	}
+}
+
+// logged reports whether a message containing substr was logged
+func logged(logger *MockLogger, substr string) bool {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	for _, message := range logger.Messages {
+		if strings.Contains(message, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestServer_PreShutdownDelay(t *testing.T) {
+	tests := []struct {
+		name  string
+		delay time.Duration
+	}{
+		{"drains for the delay", 5 * time.Second},
+		{"disabled", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := &HealthState{}
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if health.Draining() {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			logger := &MockLogger{}
+			srv := NewServer(&config.Config{Port: "127.0.0.1:0", PreShutdownDelay: tt.delay}, handler, logger)
+			srv.RegisterHealthState(health)
+			if err := srv.Start(); err != nil {
+				t.Fatalf("Failed to start server: %v", err)
+			}
+			deadline := time.Now().Add(2 * time.Second)
+			for srv.Addr() == nil && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			get := func() int {
+				resp, err := http.Get("http://" + srv.Addr().String() + "/health")
+				if err != nil {
+					t.Fatalf("Expected the server to keep serving, got %v", err)
+				}
+				resp.Body.Close()
+				return resp.StatusCode
+			}
+
+			if status := get(); status != http.StatusOK {
+				t.Fatalf("Expected healthy server before the signal, got %d", status)
+			}
+
+			// The fake sleep checks health during the drain window
+			var slept time.Duration
+			var drainingStatus int
+			srv.sleep = func(d time.Duration) {
+				slept = d
+				drainingStatus = get()
+			}
+
+			signals := make(chan os.Signal, 1)
+			signals <- syscall.SIGTERM
+			if err := srv.waitForSignals(signals); err != nil {
+				t.Fatalf("Unexpected shutdown error: %v", err)
+			}
+
+			if tt.delay == 0 {
+				if slept != 0 || health.Draining() {
+					t.Errorf("Expected no drain window, slept %s", slept)
+				}
+				return
+			}
+			if slept != tt.delay {
+				t.Errorf("Expected to drain for %s, slept %s", tt.delay, slept)
+			}
+			if drainingStatus != http.StatusServiceUnavailable {
+				t.Errorf("Expected health to fail with 503 while draining, got %d", drainingStatus)
+			}
+			if !logged(logger, "Draining for 5s before shutdown") {
+				t.Errorf("Expected the drain to be logged, got %v", logger.Messages)
+			}
+		})
+	}
+}
+
+func TestHealthState_Nil(t *testing.T) {
+	var health *HealthState
+	health.StartDraining()
+	if health.Draining() {
+		t.Error("Expected nil health state to never drain")
+	}
+}
+
+func TestHealthState_Started(t *testing.T) {
+	health := &HealthState{}
+	if health.Started() {
+		t.Error("Expected a new health state to not be started")
+	}
+	health.MarkStarted()
+	if !health.Started() {
+		t.Error("Expected the health state to be started")
+	}
+
+	var disabled *HealthState
+	disabled.MarkStarted()
+	if !disabled.Started() {
+		t.Error("Expected nil health state to always be started")
+	}
+}
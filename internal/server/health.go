@@ -0,0 +1,37 @@
+package server
+
+import "sync/atomic"
+
+// HealthState is shared between the server and the health handlers, so that
+// /ready fails until startup completed and /health fails while the server
+// drains before shutdown. The zero value is healthy but not yet started, a
+// nil HealthState is always healthy and started.
+type HealthState struct {
+	started  atomic.Bool
+	draining atomic.Bool
+}
+
+// MarkStarted marks the server as accepting requests with every startup
+// check passed
+func (h *HealthState) MarkStarted() {
+	if h != nil {
+		h.started.Store(true)
+	}
+}
+
+// Started reports whether startup completed
+func (h *HealthState) Started() bool {
+	return h == nil || h.started.Load()
+}
+
+// StartDraining marks the server as about to shut down
+func (h *HealthState) StartDraining() {
+	if h != nil {
+		h.draining.Store(true)
+	}
+}
+
+// Draining reports whether the server is about to shut down
+func (h *HealthState) Draining() bool {
+	return h != nil && h.draining.Load()
+}
@@ -2,10 +2,13 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -19,10 +22,35 @@ type Logger interface {
 	Println(v ...interface{})
 }
 
+// ShutdownHook is run after the HTTP server stopped accepting requests
+type ShutdownHook func(ctx context.Context) error
+
+// DiagnosticsFunc logs a snapshot of the application state
+type DiagnosticsFunc func(logger Logger)
+
 // Server represents the HTTP server with dependencies
 type Server struct {
-	httpServer *http.Server
-	logger     Logger
+	httpServer    *http.Server
+	debugServer   *http.Server // nil unless EnableDebug was called
+	logger        Logger
+	shutdownHooks []ShutdownHook
+	diagnostics   DiagnosticsFunc
+
+	// Load balancers keep routing to a terminating pod for a few seconds,
+	// health fails for preShutdownDelay before the server stops accepting
+	health           *HealthState
+	preShutdownDelay time.Duration
+	sleep            func(d time.Duration)
+
+	// Bounds shutting down, shutdown hooks included
+	shutdownTimeout time.Duration
+
+	// Errors of a listener failing after it started, ending WaitForShutdown
+	serveErrs chan error
+
+	mu        sync.Mutex
+	addr      net.Addr
+	debugAddr net.Addr
 }
 
 // NewServer creates a new server instance
@@ -32,30 +60,98 @@ func NewServer(cfg *config.Config, handler http.Handler, logger Logger) *Server
 			Addr:           cfg.Port,
 			Handler:        handler,
 			ReadTimeout:    time.Duration(types.ReadTimeout) * time.Second,
-			WriteTimeout:   time.Duration(types.WriteTimeout) * time.Second,
+			WriteTimeout:   cfg.RequestDeadline + time.Duration(types.WriteTimeout)*time.Second,
 			MaxHeaderBytes: types.MaxBodySize,
 		},
-		logger: logger,
+		logger:           logger,
+		preShutdownDelay: cfg.PreShutdownDelay,
+		sleep:            time.Sleep,
+		shutdownTimeout:  cfg.ShutdownTimeout,
+		serveErrs:        make(chan error, 2),
 	}
 }
 
-// Start starts the server (non-blocking)
+// EnableDebug serves handler on a separate listener at addr, started and
+// shut down together with the main server
+func (s *Server) EnableDebug(addr string, handler http.Handler) {
+	s.debugServer = &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: time.Duration(types.ReadTimeout) * time.Second,
+	}
+}
+
+// Start binds the listeners and serves in the background. Binding is done
+// before returning, so that an address in use fails startup instead of
+// leaving a running process that never accepts requests.
 func (s *Server) Start() error {
-	s.logger.Printf("Starting server on %s", s.httpServer.Addr)
-
-	go func() {
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			s.logger.Printf("Server failed to start: %v", err)
-			// Don't exit in tests
-			if os.Getenv("GO_TEST") != "1" {
-				os.Exit(1)
-			}
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+
+	var debugListener net.Listener
+	if s.debugServer != nil {
+		debugListener, err = net.Listen("tcp", s.debugServer.Addr)
+		if err != nil {
+			_ = listener.Close()
+			return fmt.Errorf("failed to start debug server: %w", err)
 		}
-	}()
+	}
+
+	s.mu.Lock()
+	s.addr = listener.Addr()
+	if debugListener != nil {
+		s.debugAddr = debugListener.Addr()
+	}
+	s.mu.Unlock()
+
+	if debugListener != nil {
+		s.logger.Printf("Starting debug server on %s", debugListener.Addr())
+		go s.serve(s.debugServer, debugListener, "debug server")
+	}
+	s.logger.Printf("Starting server on %s", listener.Addr())
+	go s.serve(s.httpServer, listener, "server")
 
 	return nil
 }
 
+// serve runs srv on listener, reporting failures other than a shutdown
+func (s *Server) serve(srv *http.Server, listener net.Listener, name string) {
+	if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+		s.serveErrs <- fmt.Errorf("%s failed: %w", name, err)
+	}
+}
+
+// Addr returns the address the main server listens on, nil before it started
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addr
+}
+
+// DebugAddr returns the address the debug server listens on, nil when disabled
+func (s *Server) DebugAddr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.debugAddr
+}
+
+// RegisterShutdownHook registers a hook to drain background work during shutdown
+func (s *Server) RegisterShutdownHook(hook ShutdownHook) {
+	s.shutdownHooks = append(s.shutdownHooks, hook)
+}
+
+// RegisterHealthState sets the health state failed during PRESHUTDOWN_DELAY
+func (s *Server) RegisterHealthState(health *HealthState) {
+	s.health = health
+}
+
+// RegisterDiagnostics sets the dump logged on SIGUSR1
+func (s *Server) RegisterDiagnostics(fn DiagnosticsFunc) {
+	s.diagnostics = fn
+}
+
 // Shutdown performs graceful shutdown
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Println("Shutting down server...")
@@ -64,21 +160,97 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		return fmt.Errorf("server forced to shutdown: %w", err)
 	}
 
+	// Long running profiles must not hold up shutdown
+	if s.debugServer != nil {
+		if err := s.debugServer.Shutdown(ctx); err != nil {
+			s.logger.Printf("Debug server forced to shutdown: %v", err)
+			_ = s.debugServer.Close()
+		}
+	}
+
+	for _, hook := range s.shutdownHooks {
+		if err := hook(ctx); err != nil {
+			s.logger.Printf("Shutdown hook failed: %v", err)
+		}
+	}
+
 	s.logger.Println("Server exited")
 	return nil
 }
 
-// WaitForShutdown waits for interrupt signal and performs graceful shutdown
+// WaitForShutdown waits for interrupt signal and performs graceful shutdown.
+// SIGUSR1 logs a diagnostics dump and keeps serving. A listener failing
+// shuts the server down too, returning its error.
 func (s *Server) WaitForShutdown() error {
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGUSR1)
+	defer signal.Stop(signals)
+
+	return s.waitForSignals(signals)
+}
+
+// waitForSignals dumps diagnostics on SIGUSR1 until any other signal
+// arrives, then shuts down
+func (s *Server) waitForSignals(signals <-chan os.Signal) error {
+	var serveErr error
+wait:
+	for {
+		select {
+		case sig, ok := <-signals:
+			if !ok || sig != syscall.SIGUSR1 {
+				break wait
+			}
+			s.dumpDiagnostics()
+		case serveErr = <-s.serveErrs:
+			s.logger.Printf("Shutting down after failure: %v", serveErr)
+			break wait
+		}
+	}
 
-	<-stop
+	// A failed listener is not drained, it no longer receives traffic
+	if serveErr == nil {
+		s.drainBeforeShutdown()
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(types.ShutdownTimeout)*time.Second)
+	timeout := s.shutdownTimeout
+	if timeout <= 0 {
+		timeout = time.Duration(types.ShutdownTimeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	return s.Shutdown(ctx)
+	if err := s.Shutdown(ctx); err != nil {
+		return errors.Join(serveErr, err)
+	}
+	return serveErr
+}
+
+// drainBeforeShutdown fails health checks and keeps serving for
+// PRESHUTDOWN_DELAY, so that load balancers stop routing to the server
+func (s *Server) drainBeforeShutdown() {
+	if s.preShutdownDelay <= 0 {
+		return
+	}
+
+	s.health.StartDraining()
+	s.logger.Printf("Draining for %s before shutdown", s.preShutdownDelay)
+	s.sleep(s.preShutdownDelay)
+}
+
+// dumpDiagnostics runs the registered dump, a panicking dump must not
+// take the server down
+func (s *Server) dumpDiagnostics() {
+	if s.diagnostics == nil {
+		s.logger.Println("No diagnostics registered")
+		return
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			s.logger.Printf("Diagnostics dump failed: %v", recovered)
+		}
+	}()
+	s.diagnostics(s.logger)
 }
 
 // HealthCheck performs a health check (for Docker HEALTHCHECK)
@@ -2,9 +2,13 @@ package server
 
 import (
 	"context"
+	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -48,12 +52,9 @@ func TestServer_Start(t *testing.T) {
 	}
 }
 
-// TestServer_Start_WithInvalidPort tests Start with invalid port
+// TestServer_Start_WithInvalidPort tests that a listener that cannot bind
+// fails Start
 func TestServer_Start_WithInvalidPort(t *testing.T) {
-	// Set test environment to prevent os.Exit
-	os.Setenv("GO_TEST", "1")
-	defer os.Unsetenv("GO_TEST")
-
 	cfg := &config.Config{
 		Port: ":-1", // Invalid port
 	}
@@ -62,25 +63,136 @@ func TestServer_Start_WithInvalidPort(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	logger := &MockLogger{}
-	srv := NewServer(cfg, handler, logger)
+	srv := NewServer(cfg, handler, &MockLogger{})
 
-	// Start the server (should not crash due to GO_TEST env var)
 	err := srv.Start()
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to start server: ") {
+		t.Errorf("Expected start error, got %v", err)
+	}
+	if srv.Addr() != nil {
+		t.Errorf("Expected no address after a failed start, got %s", srv.Addr())
+	}
+}
+
+// TestServer_Start_AddressInUse tests that an address in use fails Start
+// and releases the debug listener
+func TestServer_Start_AddressInUse(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
-		t.Logf("Expected behavior: Start returned error: %v", err)
+		t.Fatalf("Failed to listen: %v", err)
 	}
+	defer taken.Close()
 
-	// Give goroutine time to attempt start
-	time.Sleep(100 * time.Millisecond)
+	srv := NewServer(&config.Config{Port: taken.Addr().String()}, http.NotFoundHandler(), &MockLogger{})
+	err = srv.Start()
+	if err == nil || !strings.Contains(err.Error(), "address already in use") {
+		t.Fatalf("Expected address in use error, got %v", err)
+	}
 
-	// Verify error was logged (thread-safe read)
-	logger.mu.Lock()
-	messagesLen := len(logger.Messages)
-	logger.mu.Unlock()
+	// The main listener failing leaves the debug address free
+	debug := NewServer(&config.Config{Port: "127.0.0.1:0"}, http.NotFoundHandler(), &MockLogger{})
+	debug.EnableDebug(taken.Addr().String(), http.NotFoundHandler())
+	if err := debug.Start(); err == nil || !strings.HasPrefix(err.Error(), "failed to start debug server: ") {
+		t.Fatalf("Expected debug start error, got %v", err)
+	}
+}
 
-	if messagesLen == 0 {
-		t.Error("Expected error message to be logged")
+// TestServer_Start_BindsBeforeReturning tests that requests are accepted as
+// soon as Start returns
+func TestServer_Start_BindsBeforeReturning(t *testing.T) {
+	srv := NewServer(&config.Config{Port: "127.0.0.1:0"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), &MockLogger{})
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+
+	resp, err := http.Get("http://" + srv.Addr().String() + "/")
+	if err != nil {
+		t.Fatalf("Expected the server to accept immediately, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d", resp.StatusCode)
+	}
+}
+
+// TestServer_WaitForSignals_ServeFailure tests that a failing listener ends
+// the wait with its error
+func TestServer_WaitForSignals_ServeFailure(t *testing.T) {
+	srv := NewServer(&config.Config{Port: "127.0.0.1:0"}, http.NotFoundHandler(), &MockLogger{})
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+
+	srv.serveErrs <- errors.New("server failed: accept: too many open files")
+	err := srv.waitForSignals(make(chan os.Signal))
+	if err == nil || err.Error() != "server failed: accept: too many open files" {
+		t.Errorf("Expected the serve error, got %v", err)
+	}
+}
+
+// TestServer_WaitForSignals_ShutdownTimeout tests that shutdown hooks, e.g.
+// the final flush of queued deliveries, are bounded by SHUTDOWN_TIMEOUT
+func TestServer_WaitForSignals_ShutdownTimeout(t *testing.T) {
+	srv := NewServer(&config.Config{Port: "127.0.0.1:0", ShutdownTimeout: 2 * time.Second}, http.NotFoundHandler(), &MockLogger{})
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+
+	var remaining time.Duration
+	srv.RegisterShutdownHook(func(ctx context.Context) error {
+		deadline, _ := ctx.Deadline()
+		remaining = time.Until(deadline)
+		return nil
+	})
+
+	signals := make(chan os.Signal, 1)
+	signals <- syscall.SIGTERM
+	if err := srv.waitForSignals(signals); err != nil {
+		t.Fatalf("Unexpected shutdown error: %v", err)
+	}
+	if remaining <= 0 || remaining > 2*time.Second {
+		t.Errorf("Expected the hooks bounded by SHUTDOWN_TIMEOUT, %s remained", remaining)
+	}
+}
+
+// TestServer_ShutdownHooks tests that hooks run after the HTTP server stops
+func TestServer_ShutdownHooks(t *testing.T) {
+	cfg := &config.Config{
+		Port: ":0",
+	}
+
+	logger := &MockLogger{}
+	srv := NewServer(cfg, http.NotFoundHandler(), logger)
+
+	var calls []string
+	srv.RegisterShutdownHook(func(ctx context.Context) error {
+		calls = append(calls, "first")
+		return nil
+	})
+	srv.RegisterShutdownHook(func(ctx context.Context) error {
+		calls = append(calls, "second")
+		return context.DeadlineExceeded
+	})
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	if len(calls) != 2 || calls[0] != "first" || calls[1] != "second" {
+		t.Errorf("Expected hooks to run in order, got %v", calls)
+	}
+
+	found := false
+	for _, msg := range logger.Messages {
+		if msg == "Shutdown hook failed: "+context.DeadlineExceeded.Error() {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected hook failure to be logged, got %v", logger.Messages)
 	}
 }
 
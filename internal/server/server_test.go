@@ -68,6 +68,15 @@ func TestNewServer(t *testing.T) {
 	}
 }
 
+func TestNewServer_WriteTimeoutAboveRequestDeadline(t *testing.T) {
+	server := NewServer(&config.Config{RequestDeadline: 20 * time.Second}, http.NotFoundHandler(), &MockLogger{})
+
+	expected := 20*time.Second + time.Duration(types.WriteTimeout)*time.Second
+	if server.httpServer.WriteTimeout != expected {
+		t.Errorf("Expected WriteTimeout %v, got %v", expected, server.httpServer.WriteTimeout)
+	}
+}
+
 func TestServer_StartAndShutdown(t *testing.T) {
 	cfg := &config.Config{
 		Port: ":0", // Random port
@@ -0,0 +1,92 @@
+package signing
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignVerify_RoundTrip(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	body := []byte(`{"severity":"error","message":"boom"}`)
+
+	tests := []struct {
+		name     string
+		tamper   func(secret *string, body *[]byte, now *time.Time)
+		expected error
+	}{
+		{name: "valid", tamper: func(*string, *[]byte, *time.Time) {}},
+		{name: "within tolerance", tamper: func(_ *string, _ *[]byte, now *time.Time) { *now = now.Add(DefaultTolerance) }},
+		{name: "clock behind", tamper: func(_ *string, _ *[]byte, now *time.Time) { *now = now.Add(-DefaultTolerance) }},
+		{name: "replayed later", tamper: func(_ *string, _ *[]byte, now *time.Time) { *now = now.Add(DefaultTolerance + time.Second) }, expected: ErrExpired},
+		{name: "other body", tamper: func(_ *string, body *[]byte, _ *time.Time) { *body = []byte(`{"severity":"info"}`) }, expected: ErrMismatch},
+		{name: "other secret", tamper: func(secret *string, _ *[]byte, _ *time.Time) { *secret = "other" }, expected: ErrMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", nil)
+			Sign(req, "secret", body, now)
+
+			secret, received, verifiedAt := "secret", body, now
+			tt.tamper(&secret, &received, &verifiedAt)
+			if err := Verify(req, secret, received, verifiedAt, DefaultTolerance); !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestSign_Headers(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", nil)
+	Sign(req, "secret", []byte("body"), time.Unix(1700000000, 0))
+
+	if got := req.Header.Get(TimestampHeader); got != "1700000000" {
+		t.Errorf("Expected the Unix time, got %q", got)
+	}
+	// HMAC-SHA256 of "1700000000.body" keyed with "secret"
+	if got := req.Header.Get(SignatureHeader); got != "sha256=42ac6f0448c1d9c3e1e82b9726248f58fef84afffcbad5188246e96070e0ea46" {
+		t.Errorf("Unexpected signature %q", got)
+	}
+}
+
+func TestVerify_Malformed(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		expected  string
+	}{
+		{"unsigned", "", "", ErrMissing.Error()},
+		{"no timestamp", "", "sha256=00", ErrMissing.Error()},
+		{"timestamp not a number", "yesterday", "sha256=00", `invalid timestamp "yesterday"`},
+		{"other algorithm", "1700000000", "sha1=00", `invalid signature "sha1=00"`},
+		{"not hex", "1700000000", "sha256=zz", `invalid signature "sha256=zz"`},
+		// The timestamp is part of the MAC, a swapped one fails
+		{"swapped timestamp", "1700000001", "sha256=42ac6f0448c1d9c3e1e82b9726248f58fef84afffcbad5188246e96070e0ea46", ErrMismatch.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", nil)
+			if tt.timestamp != "" {
+				req.Header.Set(TimestampHeader, tt.timestamp)
+			}
+			if tt.signature != "" {
+				req.Header.Set(SignatureHeader, tt.signature)
+			}
+			if err := Verify(req, "secret", []byte("body"), now, DefaultTolerance); err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	if got := string(canonical("1700000000", []byte(`{"a":1}`))); got != `1700000000.{"a":1}` {
+		t.Errorf("Unexpected canonical message %q", got)
+	}
+}
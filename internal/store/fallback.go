@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+)
+
+// DefaultRecheckInterval is how long the fallback store stays local-only
+// before trying the shared store again
+const DefaultRecheckInterval = 10 * time.Second
+
+// FallbackStore uses a shared store and degrades to a local one while the
+// shared store fails, so that alerts are never dropped because of it. While
+// degraded, replicas deduplicate independently.
+type FallbackStore struct {
+	shared Store
+	local  Store
+	logger server.Logger
+	clock  clock.Clock
+
+	mu       sync.Mutex
+	degraded bool
+	retryAt  time.Time
+}
+
+// NewFallbackStore creates a store preferring shared over local
+func NewFallbackStore(shared, local Store, logger server.Logger) *FallbackStore {
+	return &FallbackStore{
+		shared: shared,
+		local:  local,
+		logger: logger,
+		clock:  clock.Real{},
+	}
+}
+
+// Claim claims key in the shared store, or locally while it is unavailable
+func (s *FallbackStore) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if s.useShared() {
+		claimed, err := s.shared.Claim(ctx, key, ttl)
+		if s.record(err) {
+			return claimed, nil
+		}
+	}
+	return s.local.Claim(ctx, key, ttl)
+}
+
+// Get reads key from the shared store, or locally while it is unavailable
+func (s *FallbackStore) Get(ctx context.Context, key string) (string, bool, error) {
+	if s.useShared() {
+		value, ok, err := s.shared.Get(ctx, key)
+		if s.record(err) {
+			return value, ok, nil
+		}
+	}
+	return s.local.Get(ctx, key)
+}
+
+// Set writes key to the shared store, or locally while it is unavailable
+func (s *FallbackStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if s.useShared() {
+		if s.record(s.shared.Set(ctx, key, value, ttl)) {
+			return nil
+		}
+	}
+	return s.local.Set(ctx, key, value, ttl)
+}
+
+// Delete removes key from both stores
+func (s *FallbackStore) Delete(ctx context.Context, key string) error {
+	if s.useShared() {
+		s.record(s.shared.Delete(ctx, key))
+	}
+	return s.local.Delete(ctx, key)
+}
+
+// useShared reports whether the shared store should be tried
+func (s *FallbackStore) useShared() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.degraded || !s.clock.Now().Before(s.retryAt)
+}
+
+// record tracks the shared store's health from a call's error, logging
+// transitions, and reports whether the call succeeded
+func (s *FallbackStore) record(err error) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		if !s.degraded {
+			s.logger.Printf("Warning: shared store unavailable, falling back to local-only deduplication: %v", err)
+		}
+		s.degraded = true
+		s.retryAt = s.clock.Now().Add(DefaultRecheckInterval)
+		return false
+	}
+
+	if s.degraded {
+		s.logger.Println("Shared store available again")
+		s.degraded = false
+	}
+	return true
+}
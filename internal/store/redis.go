@@ -0,0 +1,212 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRedisTimeout bounds a Redis command when the context has no deadline
+const DefaultRedisTimeout = 2 * time.Second
+
+// errNil is the RESP null bulk string reply
+var errNil = errors.New("redis: nil")
+
+// RedisStore is a Store shared between replicas through Redis. It speaks the
+// RESP protocol over a single connection, which is redialled after errors.
+type RedisStore struct {
+	addr     string
+	password string
+	timeout  time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedisStore creates a Redis store, connecting lazily on first use
+func NewRedisStore(addr, password string) *RedisStore {
+	return &RedisStore{
+		addr:     addr,
+		password: password,
+		timeout:  DefaultRedisTimeout,
+	}
+}
+
+// Claim sets key with SET NX, so exactly one replica claims it per ttl
+func (s *RedisStore) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	_, err := s.do(ctx, setArgs(key, "1", ttl, true)...)
+	if errors.Is(err, errNil) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Get returns the value of key
+func (s *RedisStore) Get(ctx context.Context, key string) (string, bool, error) {
+	value, err := s.do(ctx, "GET", key)
+	if errors.Is(err, errNil) {
+		return "", false, nil
+	}
+	return value, err == nil, err
+}
+
+// Set stores value under key
+func (s *RedisStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	_, err := s.do(ctx, setArgs(key, value, ttl, false)...)
+	return err
+}
+
+// Delete removes key
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, "DEL", key)
+	return err
+}
+
+// Close closes the connection
+func (s *RedisStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn, s.reader = nil, nil
+	return err
+}
+
+// setArgs builds a SET command (pure function)
+func setArgs(key, value string, ttl time.Duration, onlyIfAbsent bool) []string {
+	args := []string{"SET", key, value}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	if onlyIfAbsent {
+		args = append(args, "NX")
+	}
+	return args
+}
+
+// do sends a command and reads its reply, dropping the connection on I/O errors
+func (s *RedisStore) do(ctx context.Context, args ...string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(s.timeout)
+	}
+
+	if err := s.connect(deadline); err != nil {
+		return "", err
+	}
+
+	reply, err := s.roundTrip(deadline, args)
+	var replyErr redisError
+	if err != nil && !errors.Is(err, errNil) && !errors.As(err, &replyErr) {
+		s.dropConn()
+	}
+	return reply, err
+}
+
+// connect dials and authenticates if there is no open connection, caller must hold the lock
+func (s *RedisStore) connect(deadline time.Time) error {
+	if s.conn != nil {
+		return nil
+	}
+
+	conn, err := net.DialTimeout("tcp", s.addr, time.Until(deadline))
+	if err != nil {
+		return fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	s.conn, s.reader = conn, bufio.NewReader(conn)
+
+	if s.password != "" {
+		if _, err := s.roundTrip(deadline, []string{"AUTH", s.password}); err != nil {
+			s.dropConn()
+			return fmt.Errorf("failed to authenticate to redis: %w", err)
+		}
+	}
+	return nil
+}
+
+// dropConn discards a broken connection so the next command redials, caller must hold the lock
+func (s *RedisStore) dropConn() {
+	// The connection is unusable either way, a close error adds nothing
+	_ = s.conn.Close()
+	s.conn, s.reader = nil, nil
+}
+
+// roundTrip writes a command and reads one reply, caller must hold the lock
+func (s *RedisStore) roundTrip(deadline time.Time, args []string) (string, error) {
+	if err := s.conn.SetDeadline(deadline); err != nil {
+		return "", fmt.Errorf("failed to set redis deadline: %w", err)
+	}
+
+	if _, err := io.WriteString(s.conn, encodeCommand(args)); err != nil {
+		return "", fmt.Errorf("failed to send redis command: %w", err)
+	}
+
+	return readReply(s.reader)
+}
+
+// redisError is an error reply sent by the server
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// encodeCommand encodes args as a RESP array of bulk strings (pure function)
+func encodeCommand(args []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return b.String()
+}
+
+// readReply reads a simple string, error, integer or bulk string reply
+func readReply(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read redis reply: %w", err)
+	}
+
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return "", fmt.Errorf("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", redisError(line[1:])
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", fmt.Errorf("invalid redis bulk length %q", line[1:])
+		}
+		if size < 0 {
+			return "", errNil
+		}
+
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return "", fmt.Errorf("failed to read redis reply: %w", err)
+		}
+		return string(data[:size]), nil
+	default:
+		return "", fmt.Errorf("unsupported redis reply %q", line)
+	}
+}
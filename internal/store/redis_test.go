@@ -0,0 +1,278 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+)
+
+// newRedis starts a miniredis server, requiring password when set
+func newRedis(t *testing.T, password string) *miniredis.Miniredis {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	if password != "" {
+		server.RequireAuth(password)
+	}
+	return server
+}
+
+func TestRedisStore_Operations(t *testing.T) {
+	server := newRedis(t, "secret")
+	s := NewRedisStore(server.Addr(), "secret")
+	defer s.Close()
+	ctx := context.Background()
+
+	if claimed, err := s.Claim(ctx, "alert", time.Minute); err != nil || !claimed {
+		t.Fatalf("Expected first claim to succeed, got %v %v", claimed, err)
+	}
+
+	if claimed, err := s.Claim(ctx, "alert", time.Minute); err != nil || claimed {
+		t.Fatalf("Expected second claim to fail, got %v %v", claimed, err)
+	}
+
+	if err := s.Set(ctx, "state", "Ready", time.Minute); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if value, ok, err := s.Get(ctx, "state"); err != nil || !ok || value != "Ready" {
+		t.Errorf("Expected Ready, got %q %v %v", value, ok, err)
+	}
+
+	if err := s.Delete(ctx, "state"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, ok, err := s.Get(ctx, "state"); err != nil || ok {
+		t.Errorf("Expected deleted key to be missing, got %v %v", ok, err)
+	}
+}
+
+func TestRedisStore_ClaimExpires(t *testing.T) {
+	server := newRedis(t, "")
+	s := NewRedisStore(server.Addr(), "")
+	defer s.Close()
+	ctx := context.Background()
+
+	if claimed, _ := s.Claim(ctx, "alert", 20*time.Millisecond); !claimed {
+		t.Fatal("Expected first claim to succeed")
+	}
+	if ttl := server.TTL("alert"); ttl != 20*time.Millisecond {
+		t.Errorf("Expected the claim to expire in 20ms, got %v", ttl)
+	}
+
+	server.FastForward(20 * time.Millisecond)
+	if claimed, err := s.Claim(ctx, "alert", time.Minute); err != nil || !claimed {
+		t.Errorf("Expected claim after ttl to succeed, got %v %v", claimed, err)
+	}
+}
+
+func TestRedisStore_ConcurrentReplicas(t *testing.T) {
+	server := newRedis(t, "")
+	replicas := []*RedisStore{NewRedisStore(server.Addr(), ""), NewRedisStore(server.Addr(), "")}
+	for _, replica := range replicas {
+		defer replica.Close()
+	}
+
+	const alerts = 20
+	var wins [alerts]atomic.Int32
+	var wg sync.WaitGroup
+	for _, replica := range replicas {
+		for i := 0; i < alerts; i++ {
+			// Each alert is delivered to both replicas, several times each
+			for j := 0; j < 3; j++ {
+				wg.Add(1)
+				go func(replica *RedisStore, alert int) {
+					defer wg.Done()
+					claimed, err := replica.Claim(context.Background(), fmt.Sprintf("alert-%d", alert), time.Minute)
+					if err != nil {
+						t.Errorf("Unexpected error: %v", err)
+					}
+					if claimed {
+						wins[alert].Add(1)
+					}
+				}(replica, i)
+			}
+		}
+	}
+	wg.Wait()
+
+	for i := range wins {
+		if n := wins[i].Load(); n != 1 {
+			t.Errorf("Alert %d: expected exactly one claim, got %d", i, n)
+		}
+	}
+}
+
+func TestRedisStore_Errors(t *testing.T) {
+	server := newRedis(t, "secret")
+
+	s := NewRedisStore(server.Addr(), "wrong")
+	defer s.Close()
+	if _, err := s.Claim(context.Background(), "alert", time.Minute); err == nil || !strings.Contains(err.Error(), "failed to authenticate") {
+		t.Errorf("Expected authentication error, got %v", err)
+	}
+
+	unauthenticated := NewRedisStore(server.Addr(), "")
+	defer unauthenticated.Close()
+	if _, err := unauthenticated.Claim(context.Background(), "alert", time.Minute); err == nil || !strings.Contains(err.Error(), "NOAUTH") {
+		t.Errorf("Expected NOAUTH error, got %v", err)
+	}
+
+	unreachable := NewRedisStore(closedAddr(t), "")
+	if _, err := unreachable.Claim(context.Background(), "alert", time.Minute); err == nil || !strings.Contains(err.Error(), "failed to connect") {
+		t.Errorf("Expected connection error, got %v", err)
+	}
+}
+
+func TestRedisStore_Reconnects(t *testing.T) {
+	server := newRedis(t, "")
+	s := NewRedisStore(server.Addr(), "")
+	defer s.Close()
+	ctx := context.Background()
+
+	if err := s.Set(ctx, "key", "value", 0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Drop the connection under the client
+	s.mu.Lock()
+	s.conn.Close()
+	s.mu.Unlock()
+
+	if _, _, err := s.Get(ctx, "key"); err == nil {
+		t.Fatal("Expected error on closed connection")
+	}
+
+	if value, ok, err := s.Get(ctx, "key"); err != nil || !ok || value != "value" {
+		t.Errorf("Expected reconnect to succeed, got %q %v %v", value, ok, err)
+	}
+}
+
+func TestFallbackStore(t *testing.T) {
+	logger := &MockLogger{}
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+
+	shared := &failingStore{Store: NewMemoryStore()}
+	local := NewMemoryStore()
+	s := NewFallbackStore(shared, local, logger)
+	s.clock = fake
+	ctx := context.Background()
+
+	if claimed, err := s.Claim(ctx, "a", time.Minute); err != nil || !claimed {
+		t.Fatalf("Expected shared claim, got %v %v", claimed, err)
+	}
+	if _, ok, _ := local.Get(ctx, "a"); ok {
+		t.Error("Expected healthy shared store to be used")
+	}
+
+	// Outage: claims still succeed locally and a warning is logged once
+	shared.fail.Store(true)
+	for i := 0; i < 3; i++ {
+		if claimed, err := s.Claim(ctx, fmt.Sprintf("b-%d", i), time.Minute); err != nil || !claimed {
+			t.Fatalf("Expected local claim during outage, got %v %v", claimed, err)
+		}
+	}
+	if claimed, _ := s.Claim(ctx, "b-0", time.Minute); claimed {
+		t.Error("Expected local store to deduplicate during outage")
+	}
+	if n := logger.Count("falling back to local-only"); n != 1 {
+		t.Errorf("Expected one warning, got %d: %v", n, logger.messages)
+	}
+	if shared.calls.Load() != 2 {
+		t.Errorf("Expected shared store to be skipped while degraded, got %d calls", shared.calls.Load())
+	}
+
+	// Recovery after the recheck interval
+	shared.fail.Store(false)
+	fake.Advance(DefaultRecheckInterval)
+	if claimed, err := s.Claim(ctx, "c", time.Minute); err != nil || !claimed {
+		t.Fatalf("Expected shared claim after recovery, got %v %v", claimed, err)
+	}
+	if _, ok, _ := local.Get(ctx, "c"); ok {
+		t.Error("Expected recovered shared store to be used")
+	}
+	if logger.Count("available again") != 1 {
+		t.Errorf("Expected recovery to be logged, got %v", logger.messages)
+	}
+}
+
+func TestFallbackStore_UnreachableRedis(t *testing.T) {
+	logger := &MockLogger{}
+	s := NewFallbackStore(NewRedisStore(closedAddr(t), ""), NewMemoryStore(), logger)
+
+	if claimed, err := s.Claim(context.Background(), "alert", time.Minute); err != nil || !claimed {
+		t.Fatalf("Expected alert not to be dropped, got %v %v", claimed, err)
+	}
+
+	if logger.Count("Warning") != 1 {
+		t.Errorf("Expected warning, got %v", logger.messages)
+	}
+}
+
+// failingStore wraps a Store, failing every call while fail is set
+type failingStore struct {
+	Store
+	fail  atomic.Bool
+	calls atomic.Int32
+}
+
+func (f *failingStore) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	f.calls.Add(1)
+	if f.fail.Load() {
+		return false, fmt.Errorf("connection refused")
+	}
+	return f.Store.Claim(ctx, key, ttl)
+}
+
+// MockLogger records log messages
+type MockLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (m *MockLogger) Printf(format string, v ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, fmt.Sprintf(format, v...))
+}
+
+func (m *MockLogger) Println(v ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, fmt.Sprintln(v...))
+}
+
+func (m *MockLogger) Count(substr string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, msg := range m.messages {
+		if strings.Contains(msg, substr) {
+			count++
+		}
+	}
+	return count
+}
+
+// closedAddr returns an address nothing listens on
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return addr
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+)
+
+// sweepInterval bounds how often expired in-memory entries are purged
+const sweepInterval = time.Minute
+
+// Store holds short-lived shared state such as deduplication claims and
+// per-object notification state
+type Store interface {
+	// Claim sets key for ttl only if it does not exist yet, and reports
+	// whether this call created it
+	Claim(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Get returns the value of key and whether it exists
+	Get(ctx context.Context, key string) (string, bool, error)
+	// Set stores value under key for ttl, zero ttl means no expiry
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// Delete removes key
+	Delete(ctx context.Context, key string) error
+}
+
+// memoryEntry is a value with an optional expiry
+type memoryEntry struct {
+	value   string
+	expires time.Time
+}
+
+// MemoryStore is a Store local to this process
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	nextSweep time.Time
+	clock     clock.Clock
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]memoryEntry),
+		clock:   clock.Real{},
+	}
+}
+
+// WithClock makes the store expire keys on clk, e.g. a fake clock in tests
+func (s *MemoryStore) WithClock(clk clock.Clock) *MemoryStore {
+	s.clock = clk
+	return s
+}
+
+// Claim sets key if it is absent or expired
+func (s *MemoryStore) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	s.sweep(now)
+
+	if _, ok := s.lookup(key, now); ok {
+		return false, nil
+	}
+	s.entries[key] = newMemoryEntry("1", now, ttl)
+	return true, nil
+}
+
+// Get returns the value of key if it is present and not expired
+func (s *MemoryStore) Get(ctx context.Context, key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.lookup(key, s.clock.Now())
+	return entry.value, ok, nil
+}
+
+// Set stores value under key
+func (s *MemoryStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	s.sweep(now)
+	s.entries[key] = newMemoryEntry(value, now, ttl)
+	return nil
+}
+
+// Delete removes key
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// lookup returns the live entry for key, caller must hold the lock
+func (s *MemoryStore) lookup(key string, now time.Time) (memoryEntry, bool) {
+	entry, ok := s.entries[key]
+	if !ok || entry.expired(now) {
+		return memoryEntry{}, false
+	}
+	return entry, true
+}
+
+// sweep purges expired entries at most once per sweepInterval, caller must hold the lock
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	s.nextSweep = now.Add(sweepInterval)
+
+	for key, entry := range s.entries {
+		if entry.expired(now) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// newMemoryEntry creates an entry expiring after ttl, never when ttl is zero (pure function)
+func newMemoryEntry(value string, now time.Time, ttl time.Duration) memoryEntry {
+	entry := memoryEntry{value: value}
+	if ttl > 0 {
+		entry.expires = now.Add(ttl)
+	}
+	return entry
+}
+
+// expired reports whether the entry has expired at now (pure function)
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
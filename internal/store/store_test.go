@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+)
+
+func TestMemoryStore_Claim(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	s := NewMemoryStore()
+	s.clock = fake
+	ctx := context.Background()
+
+	if claimed, _ := s.Claim(ctx, "alert", time.Minute); !claimed {
+		t.Fatal("Expected first claim to succeed")
+	}
+
+	if claimed, _ := s.Claim(ctx, "alert", time.Minute); claimed {
+		t.Fatal("Expected second claim within ttl to fail")
+	}
+
+	fake.Advance(time.Minute)
+	if claimed, _ := s.Claim(ctx, "alert", time.Minute); !claimed {
+		t.Fatal("Expected claim after ttl to succeed")
+	}
+
+	if err := s.Delete(ctx, "alert"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if claimed, _ := s.Claim(ctx, "alert", time.Minute); !claimed {
+		t.Fatal("Expected claim after delete to succeed")
+	}
+}
+
+func TestMemoryStore_GetSet(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	s := NewMemoryStore()
+	s.clock = fake
+	ctx := context.Background()
+
+	if _, ok, _ := s.Get(ctx, "state"); ok {
+		t.Fatal("Expected missing key")
+	}
+
+	if err := s.Set(ctx, "state", "Ready", time.Second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := s.Set(ctx, "forever", "yes", 0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if value, ok, _ := s.Get(ctx, "state"); !ok || value != "Ready" {
+		t.Errorf("Expected Ready, got %q %v", value, ok)
+	}
+
+	fake.Advance(2 * sweepInterval)
+	if _, ok, _ := s.Get(ctx, "state"); ok {
+		t.Error("Expected key to expire")
+	}
+
+	// Writes purge expired entries, entries without ttl are kept
+	if err := s.Set(ctx, "other", "x", time.Second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(s.entries) != 2 {
+		t.Errorf("Expected expired entries to be swept, got %d entries", len(s.entries))
+	}
+
+	if value, ok, _ := s.Get(ctx, "forever"); !ok || value != "yes" {
+		t.Errorf("Expected key without ttl to persist, got %q %v", value, ok)
+	}
+}
@@ -0,0 +1,59 @@
+package telemetry
+
+import (
+	"net/http"
+)
+
+// Middleware traces every request as a server span, continuing the trace
+// from an incoming traceparent header. Spans are named by the method and the
+// route pattern that route returns for the request, e.g. "POST /webhook", so
+// that arbitrary paths do not create new span names; requests without a
+// route are named by the method alone. With a nil tracer next is returned
+// unchanged.
+func Middleware(next http.Handler, tracer *Tracer, route func(*http.Request) string) http.Handler {
+	if tracer == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attrs := []Attribute{
+			String("http.request.method", r.Method),
+			String("url.path", r.URL.Path),
+		}
+		name := r.Method
+		if pattern := route(r); pattern != "" {
+			name += " " + pattern
+			attrs = append(attrs, String("http.route", pattern))
+		}
+
+		ctx := Extract(r.Context(), r.Header)
+		ctx, span := tracer.Start(ctx, name, SpanKindServer, attrs...)
+		defer span.End()
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(Int("http.response.status_code", recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.RecordError(httpStatusError(recorder.status))
+		}
+	})
+}
+
+// statusRecorder remembers the status code written through it
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// httpStatusError describes a server error status
+type httpStatusError int
+
+func (e httpStatusError) Error() string {
+	return http.StatusText(int(e))
+}
@@ -0,0 +1,336 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/server"
+)
+
+// Exporter defaults
+const (
+	DefaultServiceName   = "flux-provider-pushover"
+	DefaultBatchSize     = 512
+	DefaultQueueSize     = 2048
+	DefaultFlushInterval = 5 * time.Second
+)
+
+// HTTPClient interface for dependency injection
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// SpanExporter sends a batch of finished spans to a backend
+type SpanExporter interface {
+	ExportSpans(ctx context.Context, spans []SpanData) error
+}
+
+// NewTracerFromEnv returns a tracer exporting over OTLP/HTTP when
+// OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set,
+// or nil when tracing is disabled
+func NewTracerFromEnv(getEnv func(string) string, client HTTPClient, logger server.Logger) *Tracer {
+	if strings.EqualFold(strings.TrimSpace(getEnv("OTEL_SDK_DISABLED")), "true") {
+		return nil
+	}
+
+	endpoint := TracesEndpoint(getEnv("OTEL_EXPORTER_OTLP_ENDPOINT"), getEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"))
+	if endpoint == "" {
+		return nil
+	}
+
+	serviceName := strings.TrimSpace(getEnv("OTEL_SERVICE_NAME"))
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+
+	exporter := NewOTLPExporter(client, endpoint, serviceName, ParseHeaders(getEnv("OTEL_EXPORTER_OTLP_HEADERS")))
+	logger.Printf("Tracing enabled, exporting spans to %s", endpoint)
+	return NewTracer(NewBatchProcessor(exporter, DefaultFlushInterval, logger))
+}
+
+// TracesEndpoint returns the OTLP/HTTP traces URL, preferring the signal
+// specific endpoint, which is used as is (pure function)
+func TracesEndpoint(base, traces string) string {
+	if traces = strings.TrimSpace(traces); traces != "" {
+		return traces
+	}
+	if base = strings.TrimSpace(base); base == "" {
+		return ""
+	}
+	return strings.TrimRight(base, "/") + "/v1/traces"
+}
+
+// ParseHeaders parses the comma separated key=value list of
+// OTEL_EXPORTER_OTLP_HEADERS, values are URL decoded (pure function)
+func ParseHeaders(value string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		if decoded, err := url.QueryUnescape(strings.TrimSpace(val)); err == nil {
+			val = decoded
+		}
+		headers[key] = strings.TrimSpace(val)
+	}
+	return headers
+}
+
+// OTLPExporter exports spans as OTLP/HTTP JSON
+type OTLPExporter struct {
+	client      HTTPClient
+	endpoint    string
+	serviceName string
+	headers     map[string]string
+}
+
+// NewOTLPExporter creates an exporter posting to the traces endpoint
+func NewOTLPExporter(client HTTPClient, endpoint, serviceName string, headers map[string]string) *OTLPExporter {
+	return &OTLPExporter{
+		client:      client,
+		endpoint:    endpoint,
+		serviceName: serviceName,
+		headers:     headers,
+	}
+}
+
+// ExportSpans posts one batch of spans
+func (e *OTLPExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(encodeSpans(e.serviceName, spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("trace collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// OTLP/JSON wire types, see opentelemetry-proto trace/v1
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"` // 2 is STATUS_CODE_ERROR
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+// encodeSpans converts spans to an OTLP export request (pure function)
+func encodeSpans(serviceName string, spans []SpanData) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceID:           span.TraceID.String(),
+			SpanID:            span.SpanID.String(),
+			Name:              span.Name,
+			Kind:              int(span.Kind),
+			StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+			Attributes:        encodeAttributes(span.Attributes),
+		}
+		if span.ParentSpanID.IsValid() {
+			s.ParentSpanID = span.ParentSpanID.String()
+		}
+		if span.Error {
+			s.Status = otlpStatus{Code: 2, Message: span.StatusMessage}
+		}
+		encoded = append(encoded, s)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: encodeAttributes([]Attribute{String("service.name", serviceName)})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: DefaultServiceName}, Spans: encoded}},
+	}}}
+}
+
+// encodeAttributes converts attributes to OTLP key values, 64-bit
+// integers are strings in OTLP/JSON (pure function)
+func encodeAttributes(attrs []Attribute) []otlpKeyValue {
+	encoded := make([]otlpKeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		var value otlpValue
+		switch v := attr.Value.(type) {
+		case string:
+			value.StringValue = &v
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			value.IntValue = &s
+		case bool:
+			value.BoolValue = &v
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		encoded = append(encoded, otlpKeyValue{Key: attr.Key, Value: value})
+	}
+	return encoded
+}
+
+// BatchProcessor queues finished spans and exports them in batches from a
+// background goroutine. Spans are dropped when the queue is full.
+type BatchProcessor struct {
+	exporter SpanExporter
+	logger   server.Logger
+	interval time.Duration
+	queue    chan SpanData
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewBatchProcessor creates and starts a batch processor
+func NewBatchProcessor(exporter SpanExporter, interval time.Duration, logger server.Logger) *BatchProcessor {
+	b := &BatchProcessor{
+		exporter: exporter,
+		logger:   logger,
+		interval: interval,
+		queue:    make(chan SpanData, DefaultQueueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// OnEnd queues a span for export
+func (b *BatchProcessor) OnEnd(span SpanData) {
+	select {
+	case b.queue <- span:
+	default:
+	}
+}
+
+// Shutdown exports the queued spans and stops the processor
+func (b *BatchProcessor) Shutdown(ctx context.Context) error {
+	b.stopOnce.Do(func() { close(b.stop) })
+
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush spans: %w", ctx.Err())
+	}
+}
+
+func (b *BatchProcessor) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, DefaultBatchSize)
+	for {
+		select {
+		case span := <-b.queue:
+			batch = append(batch, span)
+			if len(batch) >= DefaultBatchSize {
+				batch = b.export(batch)
+			}
+		case <-ticker.C:
+			batch = b.export(batch)
+		case <-b.stop:
+			for {
+				select {
+				case span := <-b.queue:
+					batch = append(batch, span)
+					if len(batch) >= DefaultBatchSize {
+						batch = b.export(batch)
+					}
+				default:
+					b.export(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// export sends batch and returns it emptied for reuse
+func (b *BatchProcessor) export(batch []SpanData) []SpanData {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := b.exporter.ExportSpans(ctx, batch); err != nil {
+		b.logger.Printf("Failed to export %d spans: %v", len(batch), err)
+	}
+	return batch[:0]
+}
@@ -0,0 +1,245 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// MockHTTPClient is a mock implementation of HTTPClient
+type MockHTTPClient struct {
+	DoFunc func(req *http.Request) (*http.Response, error)
+}
+
+func (m *MockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return m.DoFunc(req)
+}
+
+// MockLogger for testing (thread-safe)
+type MockLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (m *MockLogger) Printf(format string, v ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, fmt.Sprintf(format, v...))
+}
+
+func (m *MockLogger) Println(v ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, fmt.Sprint(v...))
+}
+
+// MockExporter records exported batches
+type MockExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+	err   error
+}
+
+func (m *MockExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.spans = append(m.spans, spans...)
+	return m.err
+}
+
+func (m *MockExporter) Count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.spans)
+}
+
+func TestTracesEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string
+		traces   string
+		expected string
+	}{
+		{"disabled", "", "", ""},
+		{"base endpoint", "http://collector:4318", "", "http://collector:4318/v1/traces"},
+		{"base endpoint with slash", "http://collector:4318/", "", "http://collector:4318/v1/traces"},
+		{"traces endpoint used as is", "http://collector:4318", "http://other:4318/custom", "http://other:4318/custom"},
+		{"whitespace only", "  ", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TracesEndpoint(tt.base, tt.traces); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers := ParseHeaders("api-key=secret, x-tenant = team%20a,invalid,=empty")
+
+	if len(headers) != 2 {
+		t.Fatalf("Expected 2 headers, got %v", headers)
+	}
+	if headers["api-key"] != "secret" {
+		t.Errorf("Expected api-key secret, got %q", headers["api-key"])
+	}
+	if headers["x-tenant"] != "team a" {
+		t.Errorf("Expected decoded value, got %q", headers["x-tenant"])
+	}
+}
+
+func TestNewTracerFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		enabled bool
+	}{
+		{"no endpoint", map[string]string{}, false},
+		{"endpoint", map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318"}, true},
+		{"traces endpoint", map[string]string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://collector:4318/v1/traces"}, true},
+		{"sdk disabled", map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318", "OTEL_SDK_DISABLED": "true"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getEnv := func(key string) string { return tt.env[key] }
+			tracer := NewTracerFromEnv(getEnv, &MockHTTPClient{}, &MockLogger{})
+			if (tracer != nil) != tt.enabled {
+				t.Fatalf("Expected enabled %v, got tracer %v", tt.enabled, tracer)
+			}
+			if err := tracer.Shutdown(context.Background()); err != nil {
+				t.Errorf("Unexpected shutdown error: %v", err)
+			}
+		})
+	}
+}
+
+func TestOTLPExporter_ExportSpans(t *testing.T) {
+	var (
+		gotReq  *http.Request
+		gotBody []byte
+	)
+	client := &MockHTTPClient{DoFunc: func(req *http.Request) (*http.Response, error) {
+		gotReq = req
+		gotBody, _ = io.ReadAll(req.Body)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+	}}
+
+	exporter := NewOTLPExporter(client, "http://collector:4318/v1/traces", "my-service", map[string]string{"api-key": "secret"})
+
+	recorder := NewRecorder()
+	tracer := NewTracer(recorder)
+	ctx, parent := tracer.Start(context.Background(), "POST /webhook", SpanKindServer)
+	_, child := StartSpan(ctx, "pushover.send", SpanKindClient, Int("http.response.status_code", 200), String("pushover.request_id", "abc"))
+	child.RecordError(errors.New("failed"))
+	child.End()
+	parent.End()
+
+	if err := exporter.ExportSpans(context.Background(), recorder.Spans()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if gotReq.URL.String() != "http://collector:4318/v1/traces" {
+		t.Errorf("Unexpected URL %s", gotReq.URL)
+	}
+	if gotReq.Header.Get("Content-Type") != "application/json" || gotReq.Header.Get("api-key") != "secret" {
+		t.Errorf("Unexpected headers %v", gotReq.Header)
+	}
+
+	var payload otlpRequest
+	if err := json.Unmarshal(gotBody, &payload); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	resource := payload.ResourceSpans[0]
+	if *resource.Resource.Attributes[0].Value.StringValue != "my-service" {
+		t.Error("Expected service.name resource attribute")
+	}
+	spans := resource.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	client0 := spans[0]
+	if client0.Kind != int(SpanKindClient) || client0.ParentSpanID != spans[1].SpanID || client0.TraceID != spans[1].TraceID {
+		t.Errorf("Unexpected client span %+v", client0)
+	}
+	if spans[1].ParentSpanID != "" {
+		t.Error("Expected root span without parentSpanId")
+	}
+	if client0.Status.Code != 2 || client0.Status.Message != "failed" {
+		t.Errorf("Expected error status, got %+v", client0.Status)
+	}
+	if *client0.Attributes[0].Value.IntValue != "200" {
+		t.Error("Expected integer attribute encoded as string")
+	}
+}
+
+func TestOTLPExporter_ErrorStatus(t *testing.T) {
+	client := &MockHTTPClient{DoFunc: func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: io.NopCloser(strings.NewReader(""))}, nil
+	}}
+	exporter := NewOTLPExporter(client, "http://collector:4318/v1/traces", "svc", nil)
+
+	err := exporter.ExportSpans(context.Background(), []SpanData{{Name: "op"}})
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Expected status error, got %v", err)
+	}
+}
+
+func TestBatchProcessor_ShutdownFlushes(t *testing.T) {
+	exporter := &MockExporter{}
+	processor := NewBatchProcessor(exporter, time.Hour, &MockLogger{})
+	tracer := NewTracer(processor)
+
+	for i := 0; i < 3; i++ {
+		_, span := tracer.Start(context.Background(), "op", SpanKindInternal)
+		span.End()
+	}
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exporter.Count() != 3 {
+		t.Errorf("Expected 3 exported spans, got %d", exporter.Count())
+	}
+
+	// Spans ending after shutdown are dropped without blocking
+	_, span := tracer.Start(context.Background(), "late", SpanKindInternal)
+	span.End()
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Errorf("Unexpected error on second shutdown: %v", err)
+	}
+}
+
+func TestBatchProcessor_ExportOnInterval(t *testing.T) {
+	exporter := &MockExporter{err: errors.New("collector down")}
+	logger := &MockLogger{}
+	processor := NewBatchProcessor(exporter, 10*time.Millisecond, logger)
+
+	processor.OnEnd(SpanData{Name: "op"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for exporter.Count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if exporter.Count() != 1 {
+		t.Fatalf("Expected periodic export, got %d spans", exporter.Count())
+	}
+	if err := processor.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	if len(logger.messages) == 0 || !strings.Contains(logger.messages[0], "collector down") {
+		t.Errorf("Expected export failure to be logged, got %v", logger.messages)
+	}
+}
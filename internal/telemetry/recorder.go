@@ -0,0 +1,36 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+)
+
+// Recorder is an in-memory SpanProcessor for tests
+type Recorder struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// OnEnd records the span
+func (r *Recorder) OnEnd(span SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+// Shutdown does nothing
+func (r *Recorder) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// Spans returns the recorded spans in the order they ended
+func (r *Recorder) Spans() []SpanData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]SpanData(nil), r.spans...)
+}
@@ -0,0 +1,276 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/zhorvath83/flux-provider-pushover/internal/clock"
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// String returns the lowercase hex encoding
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsValid reports whether the ID is not all zeros
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+// String returns the lowercase hex encoding
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// IsValid reports whether the ID is not all zeros
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// SpanContext is the propagated part of a span
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether both IDs are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// SpanKind is the OTLP span kind
+type SpanKind int
+
+// Span kinds, numbered as in the OTLP protocol
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// Attribute is a span attribute, Value is a string, int64 or bool
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String creates a string attribute
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int creates an integer attribute
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: int64(value)}
+}
+
+// SpanData is a finished span handed to a SpanProcessor
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	TraceID       TraceID
+	SpanID        SpanID
+	ParentSpanID  SpanID // Zero for root spans
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    []Attribute
+	Error         bool
+	StatusMessage string
+}
+
+// Attribute returns the value of the attribute with key
+func (d SpanData) Attribute(key string) (interface{}, bool) {
+	for _, attr := range d.Attributes {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return nil, false
+}
+
+// SpanProcessor receives finished spans
+type SpanProcessor interface {
+	OnEnd(span SpanData)
+	Shutdown(ctx context.Context) error
+}
+
+// Tracer creates spans. A nil Tracer creates no spans, so that tracing
+// costs nothing when disabled.
+type Tracer struct {
+	processor SpanProcessor
+	clock     clock.Clock
+}
+
+// NewTracer creates a tracer sending finished spans to processor
+func NewTracer(processor SpanProcessor) *Tracer {
+	return &Tracer{
+		processor: processor,
+		clock:     clock.Real{},
+	}
+}
+
+// Start starts a span, as a child of the span or remote span context in ctx
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			Name:       name,
+			Kind:       kind,
+			StartTime:  t.clock.Now(),
+			Attributes: attrs,
+		},
+	}
+
+	parent := SpanFromContext(ctx).SpanContext()
+	if !parent.IsValid() {
+		parent = remoteSpanContext(ctx)
+	}
+
+	if parent.IsValid() {
+		if !parent.Sampled {
+			return ctx, nil
+		}
+		span.data.TraceID = parent.TraceID
+		span.data.ParentSpanID = parent.SpanID
+	} else {
+		span.data.TraceID = newTraceID()
+	}
+	span.data.SpanID = newSpanID()
+
+	return ContextWithSpan(ctx, span), span
+}
+
+// Shutdown flushes pending spans
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	return t.processor.Shutdown(ctx)
+}
+
+// Span is an operation being traced. All methods are no-ops on a nil Span.
+type Span struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// StartSpan starts a child of the span in ctx, or nothing if ctx has no span
+func StartSpan(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.Start(ctx, name, kind, attrs...)
+}
+
+// SpanContext returns the span's identity
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return SpanContext{TraceID: s.data.TraceID, SpanID: s.data.SpanID, Sampled: true}
+}
+
+// SetAttributes adds attributes, replacing existing ones with the same key
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, attr := range attrs {
+		replaced := false
+		for i := range s.data.Attributes {
+			if s.data.Attributes[i].Key == attr.Key {
+				s.data.Attributes[i] = attr
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.data.Attributes = append(s.data.Attributes, attr)
+		}
+	}
+}
+
+// RecordError marks the span as failed with err, nil errors are ignored
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Error = true
+	s.data.StatusMessage = err.Error()
+}
+
+// End finishes the span, later calls are ignored
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = s.tracer.clock.Now()
+	data := s.data
+	data.Attributes = append([]Attribute(nil), s.data.Attributes...)
+	s.mu.Unlock()
+
+	s.tracer.processor.OnEnd(data)
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns ctx carrying span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span in ctx, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// newTraceID returns a random trace ID
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+// newSpanID returns a random span ID
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
@@ -0,0 +1,253 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		valid   bool
+		sampled bool
+	}{
+		{"sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"future version with extra field", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, true},
+		{"empty", "", false, false},
+		{"version ff", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"zero span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"uppercase", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, false},
+		{"short trace id", "00-4bf92f3577b34da6-00f067aa0ba902b7-01", false, false},
+		{"extra field in version 00", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := ParseTraceparent(tt.value)
+			if ok != tt.valid {
+				t.Fatalf("Expected valid %v, got %v", tt.valid, ok)
+			}
+			if !ok {
+				return
+			}
+			if sc.Sampled != tt.sampled {
+				t.Errorf("Expected sampled %v, got %v", tt.sampled, sc.Sampled)
+			}
+			if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("Unexpected trace ID %s", sc.TraceID)
+			}
+		})
+	}
+}
+
+func TestFormatTraceparent_RoundTrip(t *testing.T) {
+	value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, ok := ParseTraceparent(value)
+	if !ok {
+		t.Fatal("Failed to parse traceparent")
+	}
+	if got := FormatTraceparent(sc); got != value {
+		t.Errorf("Expected %s, got %s", value, got)
+	}
+}
+
+func TestTracer_NilIsNoop(t *testing.T) {
+	var tracer *Tracer
+
+	ctx, span := tracer.Start(context.Background(), "op", SpanKindInternal)
+	if span != nil {
+		t.Fatal("Expected nil span from nil tracer")
+	}
+	if SpanFromContext(ctx) != nil {
+		t.Error("Expected no span in context")
+	}
+
+	// Nil spans accept every call
+	span.SetAttributes(String("key", "value"))
+	span.RecordError(errors.New("boom"))
+	span.End()
+
+	if _, child := StartSpan(ctx, "child", SpanKindClient); child != nil {
+		t.Error("Expected no child span without a parent")
+	}
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Errorf("Unexpected shutdown error: %v", err)
+	}
+}
+
+func TestTracer_ParentChild(t *testing.T) {
+	recorder := NewRecorder()
+	tracer := NewTracer(recorder)
+
+	ctx, parent := tracer.Start(context.Background(), "parent", SpanKindServer)
+	_, child := StartSpan(ctx, "child", SpanKindClient, String("a", "1"))
+	child.SetAttributes(String("a", "2"), Int("b", 3))
+	child.RecordError(errors.New("send failed"))
+	child.End()
+	child.End()
+	parent.End()
+
+	spans := recorder.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+
+	childData, parentData := spans[0], spans[1]
+	if parentData.ParentSpanID.IsValid() {
+		t.Error("Expected root span without parent")
+	}
+	if childData.TraceID != parentData.TraceID {
+		t.Error("Expected child in the parent's trace")
+	}
+	if childData.ParentSpanID != parentData.SpanID {
+		t.Error("Expected child to reference the parent span")
+	}
+	if v, _ := childData.Attribute("a"); v != "2" {
+		t.Errorf("Expected replaced attribute 2, got %v", v)
+	}
+	if v, _ := childData.Attribute("b"); v != int64(3) {
+		t.Errorf("Expected attribute 3, got %v", v)
+	}
+	if !childData.Error || childData.StatusMessage != "send failed" {
+		t.Errorf("Expected error status, got %v %q", childData.Error, childData.StatusMessage)
+	}
+}
+
+func TestTracer_RemoteParent(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		wantSpan  bool
+		wantTrace string
+	}{
+		{"sampled parent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"unsampled parent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", false, ""},
+		{"invalid header starts new trace", "garbage", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := NewRecorder()
+			tracer := NewTracer(recorder)
+
+			header := http.Header{}
+			header.Set(TraceparentHeader, tt.header)
+			_, span := tracer.Start(Extract(context.Background(), header), "op", SpanKindServer)
+			span.End()
+
+			spans := recorder.Spans()
+			if !tt.wantSpan {
+				if len(spans) != 0 {
+					t.Errorf("Expected no spans, got %d", len(spans))
+				}
+				return
+			}
+			if len(spans) != 1 {
+				t.Fatalf("Expected 1 span, got %d", len(spans))
+			}
+			if tt.wantTrace != "" && spans[0].TraceID.String() != tt.wantTrace {
+				t.Errorf("Expected trace %s, got %s", tt.wantTrace, spans[0].TraceID)
+			}
+			if tt.wantTrace != "" && spans[0].ParentSpanID.String() != "00f067aa0ba902b7" {
+				t.Errorf("Expected remote parent, got %s", spans[0].ParentSpanID)
+			}
+		})
+	}
+}
+
+func TestInject(t *testing.T) {
+	recorder := NewRecorder()
+	ctx, span := NewTracer(recorder).Start(context.Background(), "op", SpanKindClient)
+
+	header := http.Header{}
+	Inject(ctx, header)
+	span.End()
+
+	sc, ok := ParseTraceparent(header.Get(TraceparentHeader))
+	if !ok {
+		t.Fatalf("Expected valid traceparent, got %q", header.Get(TraceparentHeader))
+	}
+	if sc != span.SpanContext() {
+		t.Error("Expected injected context to match the span")
+	}
+
+	empty := http.Header{}
+	Inject(context.Background(), empty)
+	if empty.Get(TraceparentHeader) != "" {
+		t.Error("Expected no header without a span")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("nil tracer returns handler unchanged", func(t *testing.T) {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		wrapped := Middleware(handler, nil, nil)
+		if _, ok := wrapped.(http.HandlerFunc); !ok {
+			t.Error("Expected the original handler")
+		}
+	})
+
+	tests := []struct {
+		name      string
+		path      string
+		route     string
+		status    int
+		wantName  string
+		wantError bool
+	}{
+		{"success", "/webhook", "/webhook", http.StatusOK, "POST /webhook", false},
+		{"client error", "/webhook", "/webhook", http.StatusBadRequest, "POST /webhook", false},
+		{"server error", "/webhook", "/webhook", http.StatusInternalServerError, "POST /webhook", true},
+		{"path under a catch-all route", "/wp-login.php", "/", http.StatusBadRequest, "POST /", false},
+		{"no route", "/webhook", "", http.StatusNotFound, "POST", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := NewRecorder()
+			var inner *Span
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				inner = SpanFromContext(r.Context())
+				w.WriteHeader(tt.status)
+			}), NewTracer(recorder), func(*http.Request) string { return tt.route })
+
+			req := httptest.NewRequest("POST", tt.path, nil)
+			req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			spans := recorder.Spans()
+			if len(spans) != 1 {
+				t.Fatalf("Expected 1 span, got %d", len(spans))
+			}
+			span := spans[0]
+			if inner == nil || inner.SpanContext().SpanID != span.SpanID {
+				t.Error("Expected the server span in the request context")
+			}
+			if span.Name != tt.wantName || span.Kind != SpanKindServer {
+				t.Errorf("Unexpected span %q kind %d", span.Name, span.Kind)
+			}
+			if span.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("Expected incoming trace, got %s", span.TraceID)
+			}
+			if v, ok := span.Attribute("http.route"); v != tt.route && (ok || tt.route != "") {
+				t.Errorf("Expected route attribute %q, got %v", tt.route, v)
+			}
+			if v, _ := span.Attribute("url.path"); v != tt.path {
+				t.Errorf("Expected path attribute %q, got %v", tt.path, v)
+			}
+			if v, _ := span.Attribute("http.response.status_code"); v != int64(tt.status) {
+				t.Errorf("Expected status attribute %d, got %v", tt.status, v)
+			}
+			if span.Error != tt.wantError {
+				t.Errorf("Expected error %v, got %v", tt.wantError, span.Error)
+			}
+		})
+	}
+}
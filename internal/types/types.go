@@ -1,6 +1,9 @@
 package types
 
-// FluxAlert represents an alert from FluxCD
+import "net/http"
+
+// FluxAlert represents an alert from FluxCD. The doc tags describe the
+// fields in the OpenAPI document.
 type FluxAlert struct {
 	InvolvedObject struct {
 		Kind            string `json:"kind"`
@@ -9,44 +12,112 @@ type FluxAlert struct {
 		UID             string `json:"uid"`
 		APIVersion      string `json:"apiVersion"`
 		ResourceVersion string `json:"resourceVersion"`
-	} `json:"involvedObject"`
-	Severity  string `json:"severity"`
-	Timestamp string `json:"timestamp"`
-	Message   string `json:"message"`
-	Reason    string `json:"reason"`
-	Metadata  struct {
-		CommitStatus string `json:"commit_status"`
-		Revision     string `json:"revision"`
-		Summary      string `json:"summary"`
-	} `json:"metadata"`
-	ReportingController string `json:"reportingController"`
-	ReportingInstance   string `json:"reportingInstance"`
+	} `json:"involvedObject" doc:"Flux object the event is about"`
+	Severity            string            `json:"severity" doc:"info, warning (or warn), error, or one of EXTRA_SEVERITIES"`
+	Timestamp           string            `json:"timestamp" doc:"RFC 3339 time of the event"`
+	Message             string            `json:"message" doc:"Event message, required when reason is empty"`
+	Reason              string            `json:"reason" doc:"Short machine-readable cause, e.g. ReconciliationSucceeded"`
+	Metadata            map[string]string `json:"metadata" doc:"Event metadata, e.g. revision and summary"` // Well-known keys below, plus the Alert's eventMetadata
+	ReportingController string            `json:"reportingController" doc:"Controller that reported the event"`
+	ReportingInstance   string            `json:"reportingInstance" doc:"Controller instance that reported the event"`
+
+	// Cluster the webhook named, not part of Flux's payload. Empty falls
+	// back to CLUSTER_NAME.
+	Cluster string `json:"-"`
 }
 
+// Well-known keys of FluxAlert.Metadata
+const (
+	MetadataRevision     = "revision"
+	MetadataSummary      = "summary"
+	MetadataCommitStatus = "commit_status"
+)
+
 // PushoverMessage represents a message to be sent to Pushover
 type PushoverMessage struct {
+	Token    string
+	User     string
+	Title    string
+	Message  string
+	Priority *int   // nil leaves the Pushover default
+	Sound    string // Empty leaves the user's default sound
+	Device   string // Empty sends to all of the user's devices
+	Callback string // URL Pushover calls when an emergency message is acknowledged
+	TTL      int    // Seconds until devices delete the message, 0 keeps it; not for emergencies
+
+	// File sent along, the message is posted as multipart/form-data when set
+	Attachment     []byte
+	AttachmentName string
+}
+
+// pushoverSounds are the sounds Pushover offers every user
+var pushoverSounds = map[string]bool{
+	"pushover": true, "bike": true, "bugle": true, "cashregister": true,
+	"classical": true, "cosmic": true, "falling": true, "gamelan": true,
+	"incoming": true, "intermission": true, "magic": true, "mechanical": true,
+	"pianobar": true, "siren": true, "spacealarm": true, "tugboat": true,
+	"alien": true, "climb": true, "persistent": true, "echo": true,
+	"updown": true, "vibrate": true, "none": true,
+}
+
+// IsPushoverSound reports whether name is one of Pushover's built-in sounds (pure function)
+func IsPushoverSound(name string) bool {
+	return pushoverSounds[name]
+}
+
+// PushoverGlance represents an update of a Pushover Glances widget
+type PushoverGlance struct {
 	Token   string
 	User    string
 	Title   string
-	Message string
+	Text    string
+	Subtext string
+	Count   *int // nil leaves the widget's count unchanged
 }
 
 // Constants for default values
 const (
 	DefaultSeverity = "INFO"
+	SeverityInfo    = "info"
+	SeverityWarning = "warning" // Also sent as "warn"
+	SeverityError   = "error"
 	DefaultValue    = "Unknown"
 	NoMessage       = "No Message"
 	AppTitle        = "FluxCD"
 
+	InvalidAlertError = "Invalid alert" // Error of 422 responses, next to the rejected fields
+
+	// Message formatting
+	MaxMessageLength    = 1024 // Pushover limit, in characters
+	TruncationMarker    = "…"
+	LinesDroppedMarker  = TruncationMarker + " (truncated)" // Replaces the lines over MAX_MESSAGE_LINES
+	ClusterFooterPrefix = "— cluster: "
+
+	// Pushover message options
+	MinPriority       = -2
+	MaxPriority       = 2
+	EmergencyPriority = 2    // Repeats until acknowledged, needs retry and expire
+	EmergencyRetry    = 60   // seconds between repeats of emergency messages
+	EmergencyExpire   = 3600 // seconds emergency messages keep repeating
+	MaxTitleLength    = 250  // Pushover limit, in characters
+	MaxGlanceLength   = 100  // Pushover limit of each Glances text field, in characters
+
+	// Attachments
+	MaxAttachmentBytes     = 2621440 // Pushover limit, 2.5 MB
+	DefaultAttachmentBytes = 1 << 20 // ATTACHMENT_MAX_BYTES default
+	OverflowAttachmentName = "message.txt"
+
 	// HTTP related constants
 	ContentTypeJSON = "application/json"
 	ContentTypeForm = "application/x-www-form-urlencoded"
+	CharsetUTF8     = "; charset=utf-8" // Appended to outgoing forms, whose values are UTF-8
 	BearerPrefix    = "Bearer "
+	ProxiedHeader   = "X-Flux-Provider-Proxied" // Set on webhooks proxied to the leader replica
 
 	// Server constants
 	ServerPort      = ":8080"
 	ReadTimeout     = 10      // seconds
-	WriteTimeout    = 10      // seconds
+	WriteTimeout    = 10      // seconds, on top of REQUEST_DEADLINE
 	ShutdownTimeout = 30      // seconds
 	MaxBodySize     = 1 << 20 // 1MB
 )
@@ -56,7 +127,61 @@ var (
 	ResponseOK               = []byte(`{"status": "ok"}`)
 	ResponseUnauthorized     = []byte(`{"error": "Unauthorized"}`)
 	ResponseInvalidJSON      = []byte(`{"error": "Invalid JSON"}`)
+	ResponseInvalidForm      = []byte(`{"error": "Invalid form"}`)
 	ResponseMethodNotAllowed = []byte(`{"error": "Method not allowed"}`)
+	ResponseInternalError    = []byte(`{"error": "internal error"}`)
+	ResponseStandby          = []byte(`{"status":"standby"}`)
+	ResponseQueued           = []byte(`{"status":"queued"}`)
+	ResponseNotDelivered     = []byte(`{"error": "Request ended while waiting for delivery"}`)
+	ResponseProxyError       = []byte(`{"error": "Failed to proxy to leader"}`)
+	ResponseInFlight         = []byte(`{"error": "Request with this idempotency key is in progress"}`)
+	ResponsePayloadTooLarge  = []byte(`{"error": "Payload too large"}`)
+	ResponseHTTPSRequired    = []byte(`{"error": "HTTPS required, requests must arrive through the TLS-terminating proxy"}`)
 	ResponseRootError        = []byte("Requests need to be made to /webhook")
+	ResponseRootInfo         = []byte("flux-provider-pushover: send Flux alerts to /webhook")
 	ResponseHealthy          = []byte("healthy")
+	ResponseDraining         = []byte("draining")
+	ResponseReady            = []byte(`{"status":"ready"}`)
+	ResponseStarting         = []byte(`{"status":"starting"}`)
 )
+
+// StatusResponse is the shape of the pre-defined status bodies, e.g. ResponseOK
+type StatusResponse struct {
+	Status string `json:"status" doc:"ok, queued, standby, ready or starting"`
+}
+
+// ErrorResponse is the shape of the pre-defined error bodies, e.g. ResponseUnauthorized
+type ErrorResponse struct {
+	Error string `json:"error" doc:"Human-readable error"`
+}
+
+// SendErrorResponse is the body of webhooks whose notification failed
+type SendErrorResponse struct {
+	Error   string   `json:"error" doc:"Human-readable error"`
+	Code    string   `json:"code" doc:"Machine-readable cause, e.g. rate_limited or credentials_invalid"`
+	Details string   `json:"details" doc:"Error of the failed send"`
+	Errors  []string `json:"errors,omitempty" doc:"Errors Pushover reported"`
+	Request string   `json:"request,omitempty" doc:"Pushover request id of the failed send"`
+}
+
+// Responses holds the response bodies and content type written by the webhook handler
+type Responses struct {
+	ContentType      string
+	OKStatus         int // 200, or 202 for integrations expecting asynchronous acceptance
+	OK               []byte
+	Unauthorized     []byte
+	InvalidJSON      []byte
+	MethodNotAllowed []byte
+}
+
+// DefaultResponses returns the built-in response set
+func DefaultResponses() *Responses {
+	return &Responses{
+		ContentType:      ContentTypeJSON,
+		OKStatus:         http.StatusOK,
+		OK:               ResponseOK,
+		Unauthorized:     ResponseUnauthorized,
+		InvalidJSON:      ResponseInvalidJSON,
+		MethodNotAllowed: ResponseMethodNotAllowed,
+	}
+}